@@ -2,6 +2,7 @@ package quic
 
 import (
 	"sync"
+	"time"
 
 	"github.com/fkwhite/quic-go/internal/protocol"
 )
@@ -13,6 +14,10 @@ type packetBuffer struct {
 	// It doesn't support concurrent use.
 	// It is > 1 when used for coalesced packet.
 	refCount int
+
+	// onSent is called by the send queue with the time the packet was sent.
+	// It is only set when the connection is traced.
+	onSent func(time.Time)
 }
 
 // Split increases the refCount.
@@ -56,13 +61,18 @@ func (b *packetBuffer) Len() protocol.ByteCount {
 }
 
 func (b *packetBuffer) putBack() {
-	if cap(b.Data) != int(protocol.MaxPacketBufferSize) {
+	b.onSent = nil
+	switch cap(b.Data) {
+	case int(protocol.MaxPacketBufferSize):
+		bufferPool.Put(b)
+	case int(protocol.MaxLargePacketBufferSize):
+		largeBufferPool.Put(b)
+	default:
 		panic("putPacketBuffer called with packet of wrong size!")
 	}
-	bufferPool.Put(b)
 }
 
-var bufferPool sync.Pool
+var bufferPool, largeBufferPool sync.Pool
 
 func getPacketBuffer() *packetBuffer {
 	buf := bufferPool.Get().(*packetBuffer)
@@ -71,10 +81,32 @@ func getPacketBuffer() *packetBuffer {
 	return buf
 }
 
+// getLargePacketBuffer returns a buffer that can hold multiple coalesced UDP datagrams.
+func getLargePacketBuffer() *packetBuffer {
+	buf := largeBufferPool.Get().(*packetBuffer)
+	buf.refCount = 1
+	buf.Data = buf.Data[:0]
+	return buf
+}
+
+// getPacketBufferWithSize returns a buffer that can hold a packet of the given size.
+// Packets larger than protocol.MaxPacketBufferSize (i.e. jumbo packets) use a large buffer.
+func getPacketBufferWithSize(size protocol.ByteCount) *packetBuffer {
+	if size > protocol.MaxPacketBufferSize {
+		return getLargePacketBuffer()
+	}
+	return getPacketBuffer()
+}
+
 func init() {
 	bufferPool.New = func() interface{} {
 		return &packetBuffer{
 			Data: make([]byte, 0, protocol.MaxPacketBufferSize),
 		}
 	}
+	largeBufferPool.New = func() interface{} {
+		return &packetBuffer{
+			Data: make([]byte, 0, protocol.MaxLargePacketBufferSize),
+		}
+	}
 }
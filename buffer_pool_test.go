@@ -13,11 +13,26 @@ var _ = Describe("Buffer Pool", func() {
 		Expect(buf.Data).To(HaveCap(int(protocol.MaxPacketBufferSize)))
 	})
 
+	It("returns large buffers", func() {
+		buf := getLargePacketBuffer()
+		Expect(buf.Data).To(HaveCap(int(protocol.MaxLargePacketBufferSize)))
+	})
+
+	It("returns buffers depending on the packet size", func() {
+		Expect(getPacketBufferWithSize(protocol.MaxPacketBufferSize).Data).To(HaveCap(int(protocol.MaxPacketBufferSize)))
+		Expect(getPacketBufferWithSize(protocol.MaxPacketBufferSize + 1).Data).To(HaveCap(int(protocol.MaxLargePacketBufferSize)))
+	})
+
 	It("releases buffers", func() {
 		buf := getPacketBuffer()
 		buf.Release()
 	})
 
+	It("releases large buffers", func() {
+		buf := getLargePacketBuffer()
+		buf.Release()
+	})
+
 	It("gets the length", func() {
 		buf := getPacketBuffer()
 		buf.Data = append(buf.Data, []byte("foobar")...)
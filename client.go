@@ -47,6 +47,11 @@ var generateConnectionIDForInitial = protocol.GenerateConnectionIDForInitial
 
 // DialAddr establishes a new QUIC connection to a server.
 // It uses a new UDP connection and closes this connection when the QUIC connection is closed.
+// Where supported (currently on Linux), the UDP socket is connected to the server's address,
+// which saves the kernel a route lookup for every packet sent.
+// Only the send path makes use of this: packets are still sent one at a time (without GSO or sendmmsg),
+// and packets are received the same way as on an unconnected socket.
+// It is disconnected when the connection migrates to a different address, e.g. the server's preferred address.
 // The hostname for SNI is taken from the given address.
 // The tls.Config.CipherSuites allows setting of TLS 1.3 cipher suites.
 func DialAddr(
@@ -194,6 +199,9 @@ func dialContext(
 	if err != nil {
 		return nil, err
 	}
+	if config.usesLargePackets() {
+		packetHandlers.UseLargeBuffers()
+	}
 	c, err := newClient(pconn, remoteAddr, config, tlsConf, host, use0RTT, createdPacketConn)
 	if err != nil {
 		return nil, err
@@ -261,10 +269,24 @@ func newClient(
 	if err != nil {
 		return nil, err
 	}
+	sconn := newSendPconn(pconn, remoteAddr)
+	if createdPacketConn {
+		// The packet conn is only used for this connection.
+		// Connecting it to the server's address saves a route lookup for every packet sent.
+		udpConn, ok1 := pconn.(*net.UDPConn)
+		udpAddr, ok2 := remoteAddr.(*net.UDPAddr)
+		if ok1 && ok2 {
+			if cc, err := newConnectedPconn(udpConn, udpAddr); err == nil {
+				sconn = cc
+			} else {
+				utils.DefaultLogger.Debugf("Not using a connected UDP socket: %s", err)
+			}
+		}
+	}
 	c := &client{
 		srcConnID:         srcConnID,
 		destConnID:        destConnID,
-		sconn:             newSendPconn(pconn, remoteAddr),
+		sconn:             newSwitchableSendConn(sconn),
 		createdPacketConn: createdPacketConn,
 		use0RTT:           use0RTT,
 		tlsConf:           tlsConf,
@@ -3,6 +3,7 @@ package quic
 import (
 	"math/bits"
 	"net"
+	"time"
 
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/utils"
@@ -11,8 +12,11 @@ import (
 // A closedLocalConn is a connection that we closed locally.
 // When receiving packets for such a connection, we need to retransmit the packet containing the CONNECTION_CLOSE frame,
 // with an exponential backoff.
+// Additionally, the CONNECTION_CLOSE is retransmitted at most once per MinConnectionCloseRetransmissionInterval,
+// see section 10.2.1 of RFC 9000.
 type closedLocalConn struct {
 	counter     uint32
+	lastSent    time.Time
 	perspective protocol.Perspective
 	logger      utils.Logger
 
@@ -37,6 +41,11 @@ func (c *closedLocalConn) handlePacket(p *receivedPacket) {
 	if bits.OnesCount32(c.counter) != 1 {
 		return
 	}
+	// rate limit the CONNECTION_CLOSE retransmissions
+	if c.counter > 1 && p.rcvTime.Sub(c.lastSent) < protocol.MinConnectionCloseRetransmissionInterval {
+		return
+	}
+	c.lastSent = p.rcvTime
 	c.logger.Debugf("Received %d packets after sending CONNECTION_CLOSE. Retransmitting.", c.counter)
 	c.sendPacket(p.remoteAddr, p.info)
 }
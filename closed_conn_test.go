@@ -2,6 +2,7 @@ package quic
 
 import (
 	"net"
+	"time"
 
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/utils"
@@ -26,8 +27,10 @@ var _ = Describe("Closed local connection", func() {
 			utils.DefaultLogger,
 		)
 		addr := &net.UDPAddr{IP: net.IPv4(127, 1, 2, 3), Port: 1337}
+		now := time.Now()
 		for i := 1; i <= 20; i++ {
-			conn.handlePacket(&receivedPacket{remoteAddr: addr})
+			now = now.Add(protocol.MinConnectionCloseRetransmissionInterval)
+			conn.handlePacket(&receivedPacket{remoteAddr: addr, rcvTime: now})
 			if i == 1 || i == 2 || i == 4 || i == 8 || i == 16 {
 				Expect(written).To(Receive(Equal(addr))) // receive the CONNECTION_CLOSE
 			} else {
@@ -35,4 +38,40 @@ var _ = Describe("Closed local connection", func() {
 			}
 		}
 	})
+
+	It("rate limits the CONNECTION_CLOSE when flooded with packets", func() {
+		var written int
+		conn := newClosedLocalConn(
+			func(net.Addr, *packetInfo) { written++ },
+			protocol.PerspectiveServer,
+			utils.DefaultLogger,
+		)
+		addr := &net.UDPAddr{IP: net.IPv4(127, 1, 2, 3), Port: 1337}
+		// flood the connection with packets, 10 packets per millisecond, for one second
+		start := time.Now()
+		const numPackets = 10000
+		for i := 0; i < numPackets; i++ {
+			conn.handlePacket(&receivedPacket{
+				remoteAddr: addr,
+				rcvTime:    start.Add(time.Duration(i) * time.Second / numPackets),
+			})
+		}
+		Expect(written).To(BeNumerically(">=", 1))
+		Expect(written).To(BeNumerically("<=", int(time.Second/protocol.MinConnectionCloseRetransmissionInterval)+1))
+	})
+
+	It("only sends a single CONNECTION_CLOSE for a burst of packets", func() {
+		var written int
+		conn := newClosedLocalConn(
+			func(net.Addr, *packetInfo) { written++ },
+			protocol.PerspectiveServer,
+			utils.DefaultLogger,
+		)
+		addr := &net.UDPAddr{IP: net.IPv4(127, 1, 2, 3), Port: 1337}
+		now := time.Now()
+		for i := 0; i < 1000; i++ {
+			conn.handlePacket(&receivedPacket{remoteAddr: addr, rcvTime: now})
+		}
+		Expect(written).To(Equal(1))
+	})
 })
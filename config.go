@@ -2,11 +2,14 @@ package quic
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"time"
 
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/utils"
+	"github.com/fkwhite/quic-go/internal/wire"
+	"github.com/fkwhite/quic-go/quicvarint"
 )
 
 // Clone clones a Config
@@ -15,7 +18,16 @@ func (c *Config) Clone() *Config {
 	return &copy
 }
 
+// usesLargePackets says if packets might be larger than protocol.MaxPacketBufferSize.
+// These packets don't fit into the regular packet buffers.
+func (c *Config) usesLargePackets() bool {
+	return protocol.ByteCount(c.MaxPacketSize) > protocol.MaxPacketBufferSize
+}
+
 func (c *Config) handshakeTimeout() time.Duration {
+	if c.HandshakeTimeout > 0 {
+		return c.HandshakeTimeout
+	}
 	return utils.Max(protocol.DefaultHandshakeTimeout, 2*c.HandshakeIdleTimeout)
 }
 
@@ -29,6 +41,63 @@ func validateConfig(config *Config) error {
 	if config.MaxIncomingUniStreams > 1<<60 {
 		return errors.New("invalid value for Config.MaxIncomingUniStreams")
 	}
+	if config.InitialRTT < 0 || config.InitialRTT > protocol.MaxInitialRTT {
+		return errors.New("invalid value for Config.InitialRTT")
+	}
+	if config.AckDelayExponent > protocol.MaxAckDelayExponent {
+		return errors.New("invalid value for Config.AckDelayExponent")
+	}
+	if config.MaxAckDelay < 0 || config.MaxAckDelay > protocol.MaxMaxAckDelay-protocol.TimerGranularity {
+		return errors.New("invalid value for Config.MaxAckDelay")
+	}
+	maxAckDelay := config.MaxAckDelay
+	if maxAckDelay == 0 {
+		maxAckDelay = protocol.MaxAckDelay
+	}
+	if config.MinAckDelay < 0 || config.MinAckDelay > maxAckDelay {
+		return errors.New("invalid value for Config.MinAckDelay")
+	}
+	if config.HandshakeTimeout < 0 {
+		return errors.New("invalid value for Config.HandshakeTimeout")
+	}
+	if config.PathDegradingRTTThreshold < 0 {
+		return errors.New("invalid value for Config.PathDegradingRTTThreshold")
+	}
+	if config.MinCongestionWindow != 0 &&
+		(config.MinCongestionWindow < protocol.MinCongestionWindowPackets || config.MinCongestionWindow > protocol.MaxCongestionWindowPackets) {
+		return errors.New("invalid value for Config.MinCongestionWindow")
+	}
+	if config.InitialCongestionWindow != 0 &&
+		(config.InitialCongestionWindow < utils.Max(config.MinCongestionWindow, protocol.MinCongestionWindowPackets) ||
+			config.InitialCongestionWindow > protocol.MaxCongestionWindowPackets) {
+		return errors.New("invalid value for Config.InitialCongestionWindow")
+	}
+	if config.MaxPacketSize != 0 &&
+		(config.MaxPacketSize < protocol.MinInitialPacketSize || protocol.ByteCount(config.MaxPacketSize) > protocol.MaxUDPPayloadSize) {
+		return errors.New("invalid value for Config.MaxPacketSize")
+	}
+	maxPacketSize := protocol.MaxPacketBufferSize
+	if config.MaxPacketSize != 0 {
+		maxPacketSize = protocol.ByteCount(config.MaxPacketSize)
+	}
+	if config.InitialPacketSize != 0 &&
+		(config.InitialPacketSize < protocol.MinInitialPacketSize || protocol.ByteCount(config.InitialPacketSize) > maxPacketSize) {
+		return errors.New("invalid value for Config.InitialPacketSize")
+	}
+	if config.MaxDatagramFrameSize > quicvarint.Max {
+		return errors.New("invalid value for Config.MaxDatagramFrameSize")
+	}
+	if config.MaxHandshakesPerIP < 0 {
+		return errors.New("invalid value for Config.MaxHandshakesPerIP")
+	}
+	if config.MaxAcceptQueueSize < 0 {
+		return errors.New("invalid value for Config.MaxAcceptQueueSize")
+	}
+	for frameType := range config.ExtensionFrameTypes {
+		if wire.IsKnownFrameType(frameType) {
+			return fmt.Errorf("invalid frame type in Config.ExtensionFrameTypes: %#x", frameType)
+		}
+	}
 	return nil
 }
 
@@ -96,6 +165,36 @@ func populateConfig(config *Config, defaultConnIDLen int) *Config {
 	if maxConnectionReceiveWindow == 0 {
 		maxConnectionReceiveWindow = protocol.DefaultMaxReceiveConnectionFlowControlWindow
 	}
+	minCongestionWindow := config.MinCongestionWindow
+	if minCongestionWindow == 0 {
+		minCongestionWindow = protocol.MinCongestionWindowPackets
+	}
+	initialCongestionWindow := config.InitialCongestionWindow
+	if initialCongestionWindow == 0 {
+		initialCongestionWindow = utils.Max(protocol.DefaultInitialCongestionWindowPackets, minCongestionWindow)
+	}
+	pathDegradingThreshold := config.PathDegradingThreshold
+	if pathDegradingThreshold == 0 {
+		pathDegradingThreshold = protocol.DefaultPathDegradingThreshold
+	}
+	ackDelayExponent := config.AckDelayExponent
+	if ackDelayExponent == 0 {
+		ackDelayExponent = protocol.AckDelayExponent
+	} else if ackDelayExponent < 0 {
+		ackDelayExponent = 0
+	}
+	maxAckDelay := config.MaxAckDelay
+	if maxAckDelay == 0 {
+		maxAckDelay = protocol.MaxAckDelay
+	}
+	minAckDelay := config.MinAckDelay
+	if minAckDelay == 0 {
+		minAckDelay = protocol.MinAckDelay
+	}
+	maxDatagramFrameSize := config.MaxDatagramFrameSize
+	if maxDatagramFrameSize == 0 {
+		maxDatagramFrameSize = uint64(protocol.MaxDatagramFrameSize)
+	}
 	maxIncomingStreams := config.MaxIncomingStreams
 	if maxIncomingStreams == 0 {
 		maxIncomingStreams = protocol.DefaultMaxIncomingStreams
@@ -108,6 +207,10 @@ func populateConfig(config *Config, defaultConnIDLen int) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	maxAcceptQueueSize := config.MaxAcceptQueueSize
+	if maxAcceptQueueSize == 0 {
+		maxAcceptQueueSize = protocol.MaxAcceptQueueSize
+	}
 	connIDGenerator := config.ConnectionIDGenerator
 	if connIDGenerator == nil {
 		connIDGenerator = &protocol.DefaultConnectionIDGenerator{ConnLen: conIDLen}
@@ -116,11 +219,32 @@ func populateConfig(config *Config, defaultConnIDLen int) *Config {
 	return &Config{
 		Versions:                         versions,
 		HandshakeIdleTimeout:             handshakeIdleTimeout,
+		HandshakeTimeout:                 config.HandshakeTimeout,
 		MaxIdleTimeout:                   idleTimeout,
+		InitialRTT:                       config.InitialRTT,
+		AckDelayExponent:                 ackDelayExponent,
+		MaxAckDelay:                      maxAckDelay,
+		MinAckDelay:                      minAckDelay,
+		InitialCongestionWindow:          initialCongestionWindow,
+		MinCongestionWindow:              minCongestionWindow,
+		DisablePacing:                    config.DisablePacing,
+		PathDegradingThreshold:           pathDegradingThreshold,
+		PathDegradingCallback:            config.PathDegradingCallback,
+		PathDegradingRTTThreshold:        config.PathDegradingRTTThreshold,
+		PathDownCallback:                 config.PathDownCallback,
+		RemoteAddressChanged:             config.RemoteAddressChanged,
+		DisableActiveMigration:           config.DisableActiveMigration,
+		PreferredAddressIPv4:             config.PreferredAddressIPv4,
+		PreferredAddressIPv6:             config.PreferredAddressIPv6,
 		MaxTokenAge:                      config.MaxTokenAge,
 		MaxRetryTokenAge:                 config.MaxRetryTokenAge,
 		RequireAddressValidation:         config.RequireAddressValidation,
+		VerifyClient:                     config.VerifyClient,
+		MaxHandshakesPerIP:               config.MaxHandshakesPerIP,
+		MaxAcceptQueueSize:               maxAcceptQueueSize,
+		Allow0RTT:                        config.Allow0RTT,
 		KeepAlivePeriod:                  config.KeepAlivePeriod,
+		ExactKeepAlivePeriod:             config.ExactKeepAlivePeriod,
 		InitialStreamReceiveWindow:       initialStreamReceiveWindow,
 		MaxStreamReceiveWindow:           maxStreamReceiveWindow,
 		InitialConnectionReceiveWindow:   initialConnectionReceiveWindow,
@@ -133,8 +257,18 @@ func populateConfig(config *Config, defaultConnIDLen int) *Config {
 		StatelessResetKey:                config.StatelessResetKey,
 		TokenStore:                       config.TokenStore,
 		EnableDatagrams:                  config.EnableDatagrams,
+		MaxDatagramFrameSize:             maxDatagramFrameSize,
+		EnableStreamResetPartialDelivery: config.EnableStreamResetPartialDelivery,
+		EnableAckFrequency:               config.EnableAckFrequency,
 		DisablePathMTUDiscovery:          config.DisablePathMTUDiscovery,
+		InitialPacketSize:                config.InitialPacketSize,
+		MaxPacketSize:                    config.MaxPacketSize,
 		DisableVersionNegotiationPackets: config.DisableVersionNegotiationPackets,
+		DisableVersionNegotiation:        config.DisableVersionNegotiation,
+		DisableGSO:                       config.DisableGSO,
+		EnableTxTimestamps:               config.EnableTxTimestamps,
+		ExtensionFrameTypes:              config.ExtensionFrameTypes,
+		HandleExtensionFrame:             config.HandleExtensionFrame,
 		Tracer:                           config.Tracer,
 	}
 }
@@ -8,6 +8,7 @@ import (
 
 	mocklogging "github.com/fkwhite/quic-go/internal/mocks/logging"
 	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/fkwhite/quic-go/quicvarint"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -30,6 +31,92 @@ var _ = Describe("Config", func() {
 		It("errors on too large values for MaxIncomingUniStreams", func() {
 			Expect(validateConfig(&Config{MaxIncomingUniStreams: 1<<60 + 1})).To(MatchError("invalid value for Config.MaxIncomingUniStreams"))
 		})
+
+		It("errors on invalid values for InitialRTT", func() {
+			Expect(validateConfig(&Config{InitialRTT: -time.Second})).To(MatchError("invalid value for Config.InitialRTT"))
+			Expect(validateConfig(&Config{InitialRTT: protocol.MaxInitialRTT + 1})).To(MatchError("invalid value for Config.InitialRTT"))
+			Expect(validateConfig(&Config{InitialRTT: protocol.MaxInitialRTT})).To(Succeed())
+		})
+
+		It("errors on too large values for AckDelayExponent", func() {
+			Expect(validateConfig(&Config{AckDelayExponent: protocol.MaxAckDelayExponent + 1})).To(MatchError("invalid value for Config.AckDelayExponent"))
+			Expect(validateConfig(&Config{AckDelayExponent: protocol.MaxAckDelayExponent})).To(Succeed())
+			Expect(validateConfig(&Config{AckDelayExponent: -1})).To(Succeed())
+		})
+
+		It("errors on invalid values for MaxAckDelay", func() {
+			Expect(validateConfig(&Config{MaxAckDelay: -time.Millisecond})).To(MatchError("invalid value for Config.MaxAckDelay"))
+			Expect(validateConfig(&Config{MaxAckDelay: protocol.MaxMaxAckDelay})).To(MatchError("invalid value for Config.MaxAckDelay"))
+			Expect(validateConfig(&Config{MaxAckDelay: protocol.MaxMaxAckDelay - protocol.TimerGranularity})).To(Succeed())
+		})
+
+		It("errors on invalid values for MinAckDelay", func() {
+			Expect(validateConfig(&Config{MinAckDelay: -time.Millisecond})).To(MatchError("invalid value for Config.MinAckDelay"))
+			Expect(validateConfig(&Config{MinAckDelay: protocol.MaxAckDelay + 1})).To(MatchError("invalid value for Config.MinAckDelay"))
+			Expect(validateConfig(&Config{MinAckDelay: protocol.MaxAckDelay})).To(Succeed())
+			Expect(validateConfig(&Config{MinAckDelay: 6 * time.Millisecond, MaxAckDelay: 5 * time.Millisecond})).To(MatchError("invalid value for Config.MinAckDelay"))
+			Expect(validateConfig(&Config{MinAckDelay: 5 * time.Millisecond, MaxAckDelay: 5 * time.Millisecond})).To(Succeed())
+		})
+
+		It("errors on negative values for HandshakeTimeout", func() {
+			Expect(validateConfig(&Config{HandshakeTimeout: -time.Second})).To(MatchError("invalid value for Config.HandshakeTimeout"))
+			Expect(validateConfig(&Config{HandshakeTimeout: time.Second})).To(Succeed())
+		})
+
+		It("errors on negative values for PathDegradingRTTThreshold", func() {
+			Expect(validateConfig(&Config{PathDegradingRTTThreshold: -time.Millisecond})).To(MatchError("invalid value for Config.PathDegradingRTTThreshold"))
+			Expect(validateConfig(&Config{PathDegradingRTTThreshold: time.Millisecond})).To(Succeed())
+		})
+
+		It("errors on too large values for MaxDatagramFrameSize", func() {
+			Expect(validateConfig(&Config{MaxDatagramFrameSize: quicvarint.Max + 1})).To(MatchError("invalid value for Config.MaxDatagramFrameSize"))
+			Expect(validateConfig(&Config{MaxDatagramFrameSize: quicvarint.Max})).To(Succeed())
+		})
+
+		It("errors on invalid values for MinCongestionWindow", func() {
+			Expect(validateConfig(&Config{MinCongestionWindow: 1})).To(MatchError("invalid value for Config.MinCongestionWindow"))
+			Expect(validateConfig(&Config{MinCongestionWindow: protocol.MaxCongestionWindowPackets + 1})).To(MatchError("invalid value for Config.MinCongestionWindow"))
+			Expect(validateConfig(&Config{MinCongestionWindow: 2})).To(Succeed())
+		})
+
+		It("errors on invalid values for InitialCongestionWindow", func() {
+			Expect(validateConfig(&Config{InitialCongestionWindow: 1})).To(MatchError("invalid value for Config.InitialCongestionWindow"))
+			Expect(validateConfig(&Config{InitialCongestionWindow: protocol.MaxCongestionWindowPackets + 1})).To(MatchError("invalid value for Config.InitialCongestionWindow"))
+			Expect(validateConfig(&Config{InitialCongestionWindow: 10, MinCongestionWindow: 20})).To(MatchError("invalid value for Config.InitialCongestionWindow"))
+			Expect(validateConfig(&Config{InitialCongestionWindow: 10, MinCongestionWindow: 10})).To(Succeed())
+		})
+
+		It("errors on invalid values for MaxPacketSize", func() {
+			Expect(validateConfig(&Config{MaxPacketSize: 1199})).To(MatchError("invalid value for Config.MaxPacketSize"))
+			Expect(validateConfig(&Config{MaxPacketSize: uint16(protocol.MaxUDPPayloadSize) + 1})).To(MatchError("invalid value for Config.MaxPacketSize"))
+			Expect(validateConfig(&Config{MaxPacketSize: 1200})).To(Succeed())
+			Expect(validateConfig(&Config{MaxPacketSize: 9000})).To(Succeed())
+		})
+
+		It("errors on invalid values for InitialPacketSize", func() {
+			Expect(validateConfig(&Config{InitialPacketSize: 1199})).To(MatchError("invalid value for Config.InitialPacketSize"))
+			Expect(validateConfig(&Config{InitialPacketSize: uint16(protocol.MaxPacketBufferSize) + 1})).To(MatchError("invalid value for Config.InitialPacketSize"))
+			Expect(validateConfig(&Config{InitialPacketSize: 1400, MaxPacketSize: 1300})).To(MatchError("invalid value for Config.InitialPacketSize"))
+			Expect(validateConfig(&Config{InitialPacketSize: 1400, MaxPacketSize: 1400})).To(Succeed())
+			Expect(validateConfig(&Config{InitialPacketSize: 9000, MaxPacketSize: 9000})).To(Succeed())
+		})
+
+		It("errors on invalid values for MaxHandshakesPerIP", func() {
+			Expect(validateConfig(&Config{MaxHandshakesPerIP: -1})).To(MatchError("invalid value for Config.MaxHandshakesPerIP"))
+			Expect(validateConfig(&Config{MaxHandshakesPerIP: 10})).To(Succeed())
+		})
+
+		It("errors on invalid values for MaxAcceptQueueSize", func() {
+			Expect(validateConfig(&Config{MaxAcceptQueueSize: -1})).To(MatchError("invalid value for Config.MaxAcceptQueueSize"))
+			Expect(validateConfig(&Config{MaxAcceptQueueSize: 10})).To(Succeed())
+		})
+
+		It("errors on known frame types in ExtensionFrameTypes", func() {
+			parse := func([]byte) (int, error) { return 0, nil }
+			Expect(validateConfig(&Config{ExtensionFrameTypes: map[uint64]ExtensionFrameParser{0x1f: parse}})).To(MatchError("invalid frame type in Config.ExtensionFrameTypes: 0x1f"))
+			Expect(validateConfig(&Config{ExtensionFrameTypes: map[uint64]ExtensionFrameParser{0x30: parse}})).To(MatchError("invalid frame type in Config.ExtensionFrameTypes: 0x30"))
+			Expect(validateConfig(&Config{ExtensionFrameTypes: map[uint64]ExtensionFrameParser{0x2f5a8b: parse}})).To(Succeed())
+		})
 	})
 
 	configWithNonZeroNonFunctionFields := func() *Config {
@@ -45,7 +132,7 @@ var _ = Describe("Config", func() {
 			}
 
 			switch fn := typ.Field(i).Name; fn {
-			case "RequireAddressValidation", "GetLogWriter", "AllowConnectionWindowIncrease":
+			case "RequireAddressValidation", "GetLogWriter", "AllowConnectionWindowIncrease", "PathDegradingCallback", "PathDownCallback", "RemoteAddressChanged", "VerifyClient", "Allow0RTT", "ExtensionFrameTypes", "HandleExtensionFrame":
 				// Can't compare functions.
 			case "Versions":
 				f.Set(reflect.ValueOf([]VersionNumber{1, 2, 3}))
@@ -55,12 +142,30 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf(&protocol.DefaultConnectionIDGenerator{ConnLen: protocol.DefaultConnectionIDLength}))
 			case "HandshakeIdleTimeout":
 				f.Set(reflect.ValueOf(time.Second))
+			case "HandshakeTimeout":
+				f.Set(reflect.ValueOf(3 * time.Second))
 			case "MaxIdleTimeout":
 				f.Set(reflect.ValueOf(time.Hour))
+			case "InitialRTT":
+				f.Set(reflect.ValueOf(500 * time.Millisecond))
+			case "AckDelayExponent":
+				f.Set(reflect.ValueOf(5))
+			case "MaxAckDelay":
+				f.Set(reflect.ValueOf(10 * time.Millisecond))
+			case "MinAckDelay":
+				f.Set(reflect.ValueOf(2 * time.Millisecond))
+			case "InitialCongestionWindow":
+				f.Set(reflect.ValueOf(uint32(20)))
+			case "MinCongestionWindow":
+				f.Set(reflect.ValueOf(uint32(4)))
 			case "MaxTokenAge":
 				f.Set(reflect.ValueOf(2 * time.Hour))
 			case "MaxRetryTokenAge":
 				f.Set(reflect.ValueOf(2 * time.Minute))
+			case "MaxHandshakesPerIP":
+				f.Set(reflect.ValueOf(8))
+			case "MaxAcceptQueueSize":
+				f.Set(reflect.ValueOf(64))
 			case "TokenStore":
 				f.Set(reflect.ValueOf(NewLRUTokenStore(2, 3)))
 			case "InitialStreamReceiveWindow":
@@ -79,12 +184,42 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf([]byte{1, 2, 3, 4}))
 			case "KeepAlivePeriod":
 				f.Set(reflect.ValueOf(time.Second))
+			case "ExactKeepAlivePeriod":
+				f.Set(reflect.ValueOf(true))
 			case "EnableDatagrams":
 				f.Set(reflect.ValueOf(true))
+			case "MaxDatagramFrameSize":
+				f.Set(reflect.ValueOf(uint64(1000)))
+			case "EnableStreamResetPartialDelivery":
+				f.Set(reflect.ValueOf(true))
+			case "EnableAckFrequency":
+				f.Set(reflect.ValueOf(true))
 			case "DisableVersionNegotiationPackets":
 				f.Set(reflect.ValueOf(true))
+			case "DisableVersionNegotiation":
+				f.Set(reflect.ValueOf(true))
 			case "DisablePathMTUDiscovery":
 				f.Set(reflect.ValueOf(true))
+			case "InitialPacketSize":
+				f.Set(reflect.ValueOf(uint16(1300)))
+			case "MaxPacketSize":
+				f.Set(reflect.ValueOf(uint16(1400)))
+			case "DisableGSO":
+				f.Set(reflect.ValueOf(true))
+			case "EnableTxTimestamps":
+				f.Set(reflect.ValueOf(true))
+			case "DisablePacing":
+				f.Set(reflect.ValueOf(true))
+			case "PathDegradingRTTThreshold":
+				f.Set(reflect.ValueOf(time.Second))
+			case "PathDegradingThreshold":
+				f.Set(reflect.ValueOf(uint32(5)))
+			case "DisableActiveMigration":
+				f.Set(reflect.ValueOf(true))
+			case "PreferredAddressIPv4":
+				f.Set(reflect.ValueOf(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1234}))
+			case "PreferredAddressIPv6":
+				f.Set(reflect.ValueOf(&net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1234}))
 			case "Tracer":
 				f.Set(reflect.ValueOf(mocklogging.NewMockTracer(mockCtrl)))
 			default:
@@ -104,6 +239,11 @@ var _ = Describe("Config", func() {
 		Expect(c.handshakeTimeout()).To(Equal(11 * time.Second))
 	})
 
+	It("uses the configured handshake timeout", func() {
+		c := &Config{HandshakeIdleTimeout: 10 * time.Second, HandshakeTimeout: 3 * time.Second}
+		Expect(c.handshakeTimeout()).To(Equal(3 * time.Second))
+	})
+
 	Context("cloning", func() {
 		It("clones function fields", func() {
 			var calledAddrValidation, calledAllowConnectionWindowIncrease bool
@@ -139,12 +279,41 @@ var _ = Describe("Config", func() {
 
 	Context("populating", func() {
 		It("populates function fields", func() {
-			var calledAddrValidation bool
+			var calledAddrValidation, calledPathDegrading, calledPathDown, calledRemoteAddressChanged, calledVerifyClient, calledAllow0RTT bool
 			c1 := &Config{}
 			c1.RequireAddressValidation = func(net.Addr) bool { calledAddrValidation = true; return true }
+			c1.PathDegradingCallback = func(Connection) { calledPathDegrading = true }
+			c1.PathDownCallback = func(Connection) { calledPathDown = true }
+			c1.RemoteAddressChanged = func(Connection, net.Addr, net.Addr) { calledRemoteAddressChanged = true }
+			c1.VerifyClient = func(net.Addr, bool) bool { calledVerifyClient = true; return true }
+			c1.Allow0RTT = func(net.Addr) bool { calledAllow0RTT = true; return true }
 			c2 := populateConfig(c1, protocol.DefaultConnectionIDLength)
 			c2.RequireAddressValidation(&net.UDPAddr{})
 			Expect(calledAddrValidation).To(BeTrue())
+			c2.PathDegradingCallback(nil)
+			Expect(calledPathDegrading).To(BeTrue())
+			c2.PathDownCallback(nil)
+			Expect(calledPathDown).To(BeTrue())
+			c2.RemoteAddressChanged(nil, nil, nil)
+			Expect(calledRemoteAddressChanged).To(BeTrue())
+			c2.VerifyClient(&net.UDPAddr{}, true)
+			Expect(calledVerifyClient).To(BeTrue())
+			c2.Allow0RTT(&net.UDPAddr{})
+			Expect(calledAllow0RTT).To(BeTrue())
+		})
+
+		It("populates the extension frame types", func() {
+			var calledParser, calledHandler bool
+			c1 := &Config{
+				ExtensionFrameTypes:  map[uint64]ExtensionFrameParser{0x2f5a8b: func([]byte) (int, error) { calledParser = true; return 0, nil }},
+				HandleExtensionFrame: func(Connection, uint64, []byte) error { calledHandler = true; return nil },
+			}
+			c2 := populateConfig(c1, protocol.DefaultConnectionIDLength)
+			Expect(c2.ExtensionFrameTypes).To(HaveLen(1))
+			c2.ExtensionFrameTypes[0x2f5a8b](nil)
+			Expect(calledParser).To(BeTrue())
+			Expect(c2.HandleExtensionFrame(nil, 0x2f5a8b, nil)).To(Succeed())
+			Expect(calledHandler).To(BeTrue())
 		})
 
 		It("copies non-function fields", func() {
@@ -156,6 +325,7 @@ var _ = Describe("Config", func() {
 			c := populateConfig(&Config{}, protocol.DefaultConnectionIDLength)
 			Expect(c.Versions).To(Equal(protocol.SupportedVersions))
 			Expect(c.HandshakeIdleTimeout).To(Equal(protocol.DefaultHandshakeIdleTimeout))
+			Expect(c.MaxAcceptQueueSize).To(Equal(protocol.MaxAcceptQueueSize))
 			Expect(c.InitialStreamReceiveWindow).To(BeEquivalentTo(protocol.DefaultInitialMaxStreamData))
 			Expect(c.MaxStreamReceiveWindow).To(BeEquivalentTo(protocol.DefaultMaxReceiveStreamFlowControlWindow))
 			Expect(c.InitialConnectionReceiveWindow).To(BeEquivalentTo(protocol.DefaultInitialMaxData))
@@ -163,7 +333,25 @@ var _ = Describe("Config", func() {
 			Expect(c.MaxIncomingStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingStreams))
 			Expect(c.MaxIncomingUniStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingUniStreams))
 			Expect(c.DisableVersionNegotiationPackets).To(BeFalse())
+			Expect(c.DisableVersionNegotiation).To(BeFalse())
 			Expect(c.DisablePathMTUDiscovery).To(BeFalse())
+			Expect(c.InitialCongestionWindow).To(BeEquivalentTo(protocol.DefaultInitialCongestionWindowPackets))
+			Expect(c.MinCongestionWindow).To(BeEquivalentTo(protocol.MinCongestionWindowPackets))
+			Expect(c.PathDegradingThreshold).To(BeEquivalentTo(protocol.DefaultPathDegradingThreshold))
+			Expect(c.AckDelayExponent).To(Equal(protocol.AckDelayExponent))
+			Expect(c.MaxAckDelay).To(Equal(protocol.MaxAckDelay))
+			Expect(c.MinAckDelay).To(Equal(protocol.MinAckDelay))
+			Expect(c.MaxDatagramFrameSize).To(BeEquivalentTo(protocol.MaxDatagramFrameSize))
+		})
+
+		It("uses an ack delay exponent of 0 for negative values", func() {
+			c := populateConfig(&Config{AckDelayExponent: -1}, protocol.DefaultConnectionIDLength)
+			Expect(c.AckDelayExponent).To(BeZero())
+		})
+
+		It("doesn't use an initial congestion window smaller than the minimum congestion window", func() {
+			c := populateConfig(&Config{MinCongestionWindow: 50}, protocol.DefaultConnectionIDLength)
+			Expect(c.InitialCongestionWindow).To(BeEquivalentTo(50))
 		})
 
 		It("populates empty fields with default values, for the server", func() {
@@ -12,6 +12,8 @@ import (
 type connIDGenerator struct {
 	generator  ConnectionIDGenerator
 	highestSeq uint64
+	// set when the server is draining, no new connection IDs are issued after that
+	stoppedIssuing bool
 
 	activeSrcConnIDs        map[uint64]protocol.ConnectionID
 	initialClientDestConnID *protocol.ConnectionID // nil for the client
@@ -61,9 +63,7 @@ func (m *connIDGenerator) SetMaxActiveConnIDs(limit uint64) error {
 	// The active_connection_id_limit transport parameter is the number of
 	// connection IDs the peer will store. This limit includes the connection ID
 	// used during the handshake, and the one sent in the preferred_address
-	// transport parameter.
-	// We currently don't send the preferred_address transport parameter,
-	// so we can issue (limit - 1) connection IDs.
+	// transport parameter. Both of them are already contained in activeSrcConnIDs.
 	for i := uint64(len(m.activeSrcConnIDs)); i < utils.Min(limit, protocol.MaxIssuedConnectionIDs); i++ {
 		if err := m.issueNewConnID(); err != nil {
 			return err
@@ -100,6 +100,9 @@ func (m *connIDGenerator) Retire(seq uint64, sentWithDestConnID protocol.Connect
 }
 
 func (m *connIDGenerator) issueNewConnID() error {
+	if m.stoppedIssuing {
+		return nil
+	}
 	connID, err := m.generator.GenerateConnectionID()
 	if err != nil {
 		return err
@@ -115,6 +118,28 @@ func (m *connIDGenerator) issueNewConnID() error {
 	return nil
 }
 
+// StopIssuing stops issuing new connection IDs.
+// Connection IDs that were already issued remain valid.
+func (m *connIDGenerator) StopIssuing() {
+	m.stoppedIssuing = true
+}
+
+// IssuePreferredAddressConnID issues the connection ID that is sent in the preferred_address transport parameter.
+// This connection ID has the sequence number 1, so it must be called before any other connection ID is issued.
+func (m *connIDGenerator) IssuePreferredAddressConnID() (protocol.ConnectionID, protocol.StatelessResetToken, error) {
+	if m.highestSeq != 0 {
+		panic("expected the preferred address connection ID to have sequence number 1")
+	}
+	connID, err := m.generator.GenerateConnectionID()
+	if err != nil {
+		return protocol.ConnectionID{}, protocol.StatelessResetToken{}, err
+	}
+	m.highestSeq++
+	m.activeSrcConnIDs[m.highestSeq] = connID
+	m.addConnectionID(connID)
+	return connID, m.getStatelessResetToken(connID), nil
+}
+
 func (m *connIDGenerator) SetHandshakeComplete() {
 	if m.initialClientDestConnID != nil {
 		m.retireConnectionID(*m.initialClientDestConnID)
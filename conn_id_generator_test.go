@@ -68,6 +68,25 @@ var _ = Describe("Connection ID Generator", func() {
 		}
 	})
 
+	It("issues the connection ID for the preferred address", func() {
+		connID, token, err := g.IssuePreferredAddressConnID()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connID.Len()).To(Equal(7))
+		Expect(token).To(Equal(connIDToToken(connID)))
+		Expect(addedConnIDs).To(Equal([]protocol.ConnectionID{connID}))
+		// the connection ID is sent in the transport parameters, not in a NEW_CONNECTION_ID frame
+		Expect(queuedFrames).To(BeEmpty())
+		Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+		Expect(addedConnIDs).To(HaveLen(3))
+		Expect(queuedFrames).To(HaveLen(2))
+		for i, f := range queuedFrames {
+			Expect(f.(*wire.NewConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(i + 2))
+		}
+		// the preferred address connection ID can be retired like any other connection ID
+		Expect(g.Retire(1, protocol.ParseConnectionID([]byte{1}))).To(Succeed())
+		Expect(retiredConnIDs).To(Equal([]protocol.ConnectionID{connID}))
+	})
+
 	It("limits the number of connection IDs that it issues", func() {
 		Expect(g.SetMaxActiveConnIDs(9999999)).To(Succeed())
 		Expect(retiredConnIDs).To(BeEmpty())
@@ -136,6 +155,17 @@ var _ = Describe("Connection ID Generator", func() {
 		Expect(nf.ConnectionID.Len()).To(Equal(7))
 	})
 
+	It("doesn't issue new connection IDs after it stopped issuing", func() {
+		Expect(g.SetMaxActiveConnIDs(5)).To(Succeed())
+		queuedFrames = nil
+		addedConnIDs = nil
+		g.StopIssuing()
+		Expect(g.Retire(3, protocol.ConnectionID{})).To(Succeed())
+		Expect(retiredConnIDs).To(HaveLen(1))
+		Expect(queuedFrames).To(BeEmpty())
+		Expect(addedConnIDs).To(BeEmpty())
+	})
+
 	It("retires the initial connection ID", func() {
 		Expect(g.Retire(0, protocol.ConnectionID{})).To(Succeed())
 		Expect(removedConnIDs).To(BeEmpty())
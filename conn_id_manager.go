@@ -1,7 +1,9 @@
 package quic
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/qerr"
@@ -17,6 +19,10 @@ type newConnID struct {
 }
 
 type connIDManager struct {
+	// The mutex is only needed because the application can request a connection ID rotation.
+	// All other methods are called from the connection's run loop.
+	mutex sync.Mutex
+
 	queue list.List[newConnID]
 
 	handshakeComplete         bool
@@ -24,6 +30,9 @@ type connIDManager struct {
 	highestRetired            uint64
 	activeConnectionID        protocol.ConnectionID
 	activeStatelessResetToken *protocol.StatelessResetToken
+	// the connection ID sent in the server's preferred_address transport parameter,
+	// only used once the client migrates to the preferred address
+	preferredAddressConnID *newConnID
 
 	// We change the connection ID after sending on average
 	// protocol.PacketsPerConnectionID packets. The actual value is randomized
@@ -32,6 +41,8 @@ type connIDManager struct {
 	packetsSinceLastChange uint32
 	packetsPerConnectionID uint32
 
+	// The stateless reset tokens of all connection IDs that haven't been retired are registered,
+	// not only the token of the active connection ID (see section 10.3.1 of RFC 9000).
 	addStatelessResetToken    func(protocol.StatelessResetToken)
 	removeStatelessResetToken func(protocol.StatelessResetToken)
 	queueControlFrame         func(wire.Frame)
@@ -51,11 +62,58 @@ func newConnIDManager(
 	}
 }
 
-func (h *connIDManager) AddFromPreferredAddress(connID protocol.ConnectionID, resetToken protocol.StatelessResetToken) error {
-	return h.addConnectionID(1, connID, resetToken)
+// AddFromPreferredAddress stores the connection ID sent in the preferred_address transport parameter.
+// A connection ID must not be used on more than one path (see section 9.5 of RFC 9000),
+// so it is only used for the path to the preferred address.
+func (h *connIDManager) AddFromPreferredAddress(connID protocol.ConnectionID, resetToken protocol.StatelessResetToken) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.preferredAddressConnID = &newConnID{
+		SequenceNumber:      1,
+		ConnectionID:        connID,
+		StatelessResetToken: resetToken,
+	}
+	h.addStatelessResetToken(resetToken)
+}
+
+// PreferredAddressConnectionID returns the connection ID that is used to probe the path to the preferred address.
+func (h *connIDManager) PreferredAddressConnectionID() (protocol.ConnectionID, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.preferredAddressConnID == nil {
+		return protocol.ConnectionID{}, false
+	}
+	return h.preferredAddressConnID.ConnectionID, true
+}
+
+// UsePreferredAddressConnectionID is called when migrating to the preferred address.
+// It retires the connection ID currently in use, and switches to the preferred address connection ID.
+func (h *connIDManager) UsePreferredAddressConnectionID() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.preferredAddressConnID == nil {
+		return
+	}
+	h.queue.PushFront(*h.preferredAddressConnID)
+	h.preferredAddressConnID = nil
+	h.updateConnectionID()
 }
 
 func (h *connIDManager) Add(f *wire.NewConnectionIDFrame) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	// An endpoint that is sending packets with a zero-length Destination Connection ID
+	// must treat receipt of a NEW_CONNECTION_ID frame as a protocol violation (RFC 9000, section 19.15).
+	if h.activeConnectionID.Len() == 0 {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: "received NEW_CONNECTION_ID frame but zero-length connection IDs are in use",
+		}
+	}
 	if err := h.add(f); err != nil {
 		return err
 	}
@@ -87,8 +145,16 @@ func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 			h.queueControlFrame(&wire.RetireConnectionIDFrame{
 				SequenceNumber: el.Value.SequenceNumber,
 			})
+			h.removeStatelessResetToken(el.Value.StatelessResetToken)
 			h.queue.Remove(el)
 		}
+		if h.preferredAddressConnID != nil && h.preferredAddressConnID.SequenceNumber < f.RetirePriorTo {
+			h.queueControlFrame(&wire.RetireConnectionIDFrame{
+				SequenceNumber: h.preferredAddressConnID.SequenceNumber,
+			})
+			h.removeStatelessResetToken(h.preferredAddressConnID.StatelessResetToken)
+			h.preferredAddressConnID = nil
+		}
 		h.highestRetired = f.RetirePriorTo
 	}
 
@@ -116,6 +182,7 @@ func (h *connIDManager) addConnectionID(seq uint64, connID protocol.ConnectionID
 			ConnectionID:        connID,
 			StatelessResetToken: resetToken,
 		})
+		h.addStatelessResetToken(resetToken)
 		return nil
 	}
 	// insert a new element somewhere in the middle
@@ -135,6 +202,7 @@ func (h *connIDManager) addConnectionID(seq uint64, connID protocol.ConnectionID
 				ConnectionID:        connID,
 				StatelessResetToken: resetToken,
 			}, el)
+			h.addStatelessResetToken(resetToken)
 			break
 		}
 	}
@@ -156,18 +224,31 @@ func (h *connIDManager) updateConnectionID() {
 	h.activeStatelessResetToken = &front.StatelessResetToken
 	h.packetsSinceLastChange = 0
 	h.packetsPerConnectionID = protocol.PacketsPerConnectionID/2 + uint32(h.rand.Int31n(protocol.PacketsPerConnectionID))
-	h.addStatelessResetToken(*h.activeStatelessResetToken)
+	// The stateless reset token was already registered when the connection ID was added.
 }
 
+// Close removes the stateless reset tokens of all connection IDs that haven't been retired.
 func (h *connIDManager) Close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
 	if h.activeStatelessResetToken != nil {
 		h.removeStatelessResetToken(*h.activeStatelessResetToken)
 	}
+	for el := h.queue.Front(); el != nil; el = el.Next() {
+		h.removeStatelessResetToken(el.Value.StatelessResetToken)
+	}
+	if h.preferredAddressConnID != nil {
+		h.removeStatelessResetToken(h.preferredAddressConnID.StatelessResetToken)
+	}
 }
 
 // is called when the server performs a Retry
 // and when the server changes the connection ID in the first Initial sent
 func (h *connIDManager) ChangeInitialConnID(newConnID protocol.ConnectionID) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
 	if h.activeSequenceNumber != 0 {
 		panic("expected first connection ID to have sequence number 0")
 	}
@@ -176,6 +257,9 @@ func (h *connIDManager) ChangeInitialConnID(newConnID protocol.ConnectionID) {
 
 // is called when the server provides a stateless reset token in the transport parameters
 func (h *connIDManager) SetStatelessResetToken(token protocol.StatelessResetToken) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
 	if h.activeSequenceNumber != 0 {
 		panic("expected first connection ID to have sequence number 0")
 	}
@@ -184,6 +268,9 @@ func (h *connIDManager) SetStatelessResetToken(token protocol.StatelessResetToke
 }
 
 func (h *connIDManager) SentPacket() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
 	h.packetsSinceLastChange++
 }
 
@@ -203,12 +290,33 @@ func (h *connIDManager) shouldUpdateConnID() bool {
 }
 
 func (h *connIDManager) Get() protocol.ConnectionID {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
 	if h.shouldUpdateConnID() {
 		h.updateConnectionID()
 	}
 	return h.activeConnectionID
 }
 
+// Rotate retires the connection ID currently in use, and switches to the next connection ID issued by the peer.
+func (h *connIDManager) Rotate() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if !h.handshakeComplete {
+		return errors.New("can't rotate the connection ID before the handshake completes")
+	}
+	if h.queue.Len() == 0 {
+		return ErrNoConnectionIDAvailable
+	}
+	h.updateConnectionID()
+	return nil
+}
+
 func (h *connIDManager) SetHandshakeComplete() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
 	h.handshakeComplete = true
 }
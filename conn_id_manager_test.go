@@ -13,18 +13,18 @@ var _ = Describe("Connection ID Manager", func() {
 	var (
 		m             *connIDManager
 		frameQueue    []wire.Frame
-		tokenAdded    *protocol.StatelessResetToken
+		addedTokens   []protocol.StatelessResetToken
 		removedTokens []protocol.StatelessResetToken
 	)
 	initialConnID := protocol.ParseConnectionID([]byte{0, 0, 0, 0})
 
 	BeforeEach(func() {
 		frameQueue = nil
-		tokenAdded = nil
+		addedTokens = nil
 		removedTokens = nil
 		m = newConnIDManager(
 			initialConnID,
-			func(token protocol.StatelessResetToken) { tokenAdded = &token },
+			func(token protocol.StatelessResetToken) { addedTokens = append(addedTokens, token) },
 			func(token protocol.StatelessResetToken) { removedTokens = append(removedTokens, token) },
 			func(f wire.Frame,
 			) {
@@ -53,7 +53,7 @@ var _ = Describe("Connection ID Manager", func() {
 		token := protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
 		m.SetStatelessResetToken(token)
 		Expect(*m.activeStatelessResetToken).To(Equal(token))
-		Expect(*tokenAdded).To(Equal(token))
+		Expect(addedTokens).To(Equal([]protocol.StatelessResetToken{token}))
 	})
 
 	It("adds and gets connection IDs", func() {
@@ -90,6 +90,7 @@ var _ = Describe("Connection ID Manager", func() {
 		}
 		Expect(m.Add(f1)).To(Succeed())
 		Expect(m.Add(f2)).To(Succeed())
+		Expect(addedTokens).To(HaveLen(1))
 		c1, rt1 := get()
 		Expect(c1).To(Equal(protocol.ParseConnectionID([]byte{1, 2, 3, 4})))
 		Expect(rt1).To(Equal(protocol.StatelessResetToken{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 0xa, 0xb, 0xc, 0xd, 0xe}))
@@ -236,6 +237,140 @@ var _ = Describe("Connection ID Manager", func() {
 		})).To(MatchError(&qerr.TransportError{ErrorCode: qerr.ConnectionIDLimitError}))
 	})
 
+	It("accepts more connection IDs than allowed, if older ones are retired", func() {
+		for i := uint8(1); i < protocol.MaxActiveConnectionIDs; i++ {
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber:      uint64(i),
+				ConnectionID:        protocol.ParseConnectionID([]byte{i, i, i, i}),
+				StatelessResetToken: protocol.StatelessResetToken{i},
+			})).To(Succeed())
+		}
+		Expect(frameQueue).To(BeEmpty())
+		// retire the initial connection ID and the connection ID with sequence number 1
+		next := uint8(protocol.MaxActiveConnectionIDs)
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      uint64(next),
+			RetirePriorTo:       2,
+			ConnectionID:        protocol.ParseConnectionID([]byte{next, next, next, next}),
+			StatelessResetToken: protocol.StatelessResetToken{next},
+		})).To(Succeed())
+		Expect(frameQueue).To(HaveLen(2))
+		Expect(frameQueue[0].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(1))
+		Expect(frameQueue[1].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeZero())
+		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{2, 2, 2, 2})))
+		Expect(m.queue.Len()).To(Equal(protocol.MaxActiveConnectionIDs - 2))
+		// fill up the queue again
+		next++
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      uint64(next),
+			RetirePriorTo:       2,
+			ConnectionID:        protocol.ParseConnectionID([]byte{next, next, next, next}),
+			StatelessResetToken: protocol.StatelessResetToken{next},
+		})).To(Succeed())
+		next++
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      uint64(next),
+			RetirePriorTo:       2,
+			ConnectionID:        protocol.ParseConnectionID([]byte{next, next, next, next}),
+			StatelessResetToken: protocol.StatelessResetToken{next},
+		})).To(MatchError(&qerr.TransportError{ErrorCode: qerr.ConnectionIDLimitError}))
+	})
+
+	It("retires connection IDs when the peer advances retire_prior_to", func() {
+		for i := uint8(1); i <= 3; i++ {
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber:      uint64(i),
+				ConnectionID:        protocol.ParseConnectionID([]byte{i, i, i, i}),
+				StatelessResetToken: protocol.StatelessResetToken{i},
+			})).To(Succeed())
+		}
+		m.SetHandshakeComplete()
+		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{1, 1, 1, 1})))
+		Expect(addedTokens).To(Equal([]protocol.StatelessResetToken{{1}, {2}, {3}}))
+		addedTokens = nil
+		Expect(frameQueue).To(HaveLen(1))
+		Expect(frameQueue[0].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeZero())
+		frameQueue = nil
+
+		// retire the active connection ID
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      4,
+			RetirePriorTo:       2,
+			ConnectionID:        protocol.ParseConnectionID([]byte{4, 4, 4, 4}),
+			StatelessResetToken: protocol.StatelessResetToken{4},
+		})).To(Succeed())
+		Expect(frameQueue).To(HaveLen(1))
+		Expect(frameQueue[0].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(1))
+		Expect(removedTokens).To(Equal([]protocol.StatelessResetToken{{1}}))
+		Expect(addedTokens).To(Equal([]protocol.StatelessResetToken{{4}}))
+		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{2, 2, 2, 2})))
+		frameQueue = nil
+
+		// retire the active connection ID and a queued one
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      5,
+			RetirePriorTo:       4,
+			ConnectionID:        protocol.ParseConnectionID([]byte{5, 5, 5, 5}),
+			StatelessResetToken: protocol.StatelessResetToken{5},
+		})).To(Succeed())
+		Expect(frameQueue).To(HaveLen(2))
+		Expect(frameQueue[0].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(3))
+		Expect(frameQueue[1].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(2))
+		Expect(removedTokens).To(Equal([]protocol.StatelessResetToken{{1}, {3}, {2}}))
+		Expect(addedTokens).To(Equal([]protocol.StatelessResetToken{{4}, {5}}))
+		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{4, 4, 4, 4})))
+		Expect(m.queue.Len()).To(Equal(1))
+		frameQueue = nil
+
+		// a retransmission of an old NEW_CONNECTION_ID frame doesn't undo the retirement
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      3,
+			ConnectionID:        protocol.ParseConnectionID([]byte{3, 3, 3, 3}),
+			StatelessResetToken: protocol.StatelessResetToken{3},
+		})).To(Succeed())
+		Expect(frameQueue).To(HaveLen(1))
+		Expect(frameQueue[0].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(3))
+		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{4, 4, 4, 4})))
+		Expect(m.queue.Len()).To(Equal(1))
+	})
+
+	It("registers the stateless reset tokens of all connection IDs that haven't been retired", func() {
+		m.SetStatelessResetToken(protocol.StatelessResetToken{0})
+		for i := uint8(1); i <= 3; i++ {
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber:      uint64(i),
+				ConnectionID:        protocol.ParseConnectionID([]byte{i, i, i, i}),
+				StatelessResetToken: protocol.StatelessResetToken{i},
+			})).To(Succeed())
+		}
+		Expect(addedTokens).To(Equal([]protocol.StatelessResetToken{{0}, {1}, {2}, {3}}))
+		m.SetHandshakeComplete()
+		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{1, 1, 1, 1})))
+		// switching to a new connection ID only removes the token of the retired connection ID
+		Expect(removedTokens).To(Equal([]protocol.StatelessResetToken{{0}}))
+		Expect(addedTokens).To(HaveLen(4))
+		removedTokens = nil
+		m.Close()
+		Expect(removedTokens).To(ConsistOf(protocol.StatelessResetToken{1}, protocol.StatelessResetToken{2}, protocol.StatelessResetToken{3}))
+	})
+
+	It("errors when the peer sends a NEW_CONNECTION_ID frame while zero-length connection IDs are used", func() {
+		m = newConnIDManager(
+			protocol.ConnectionID{},
+			func(protocol.StatelessResetToken) {},
+			func(protocol.StatelessResetToken) {},
+			func(f wire.Frame) { frameQueue = append(frameQueue, f) },
+		)
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 1,
+			ConnectionID:   protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+		})).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: "received NEW_CONNECTION_ID frame but zero-length connection IDs are in use",
+		}))
+		Expect(frameQueue).To(BeEmpty())
+	})
+
 	It("initiates the first connection ID update as soon as possible", func() {
 		Expect(m.Get()).To(Equal(initialConnID))
 		m.SetHandshakeComplete()
@@ -347,6 +482,92 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(removedTokens[0]).To(Equal(protocol.StatelessResetToken{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
 	})
 
+	Context("rotating connection IDs", func() {
+		It("rotates the connection ID", func() {
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber:      1,
+				ConnectionID:        protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+				StatelessResetToken: protocol.StatelessResetToken{1},
+			})).To(Succeed())
+			m.SetHandshakeComplete()
+			Expect(m.Rotate()).To(Succeed())
+			Expect(frameQueue).To(HaveLen(1))
+			Expect(frameQueue[0].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeZero())
+			Expect(addedTokens).To(Equal([]protocol.StatelessResetToken{{1}}))
+			Expect(removedTokens).To(BeEmpty())
+			Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{1, 2, 3, 4})))
+			Expect(m.queue.Len()).To(BeZero())
+		})
+
+		It("errors when no connection ID is available", func() {
+			m.SetHandshakeComplete()
+			Expect(m.Rotate()).To(MatchError(ErrNoConnectionIDAvailable))
+			Expect(frameQueue).To(BeEmpty())
+			Expect(m.Get()).To(Equal(initialConnID))
+		})
+
+		It("errors when the last connection ID has already been used", func() {
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber: 1,
+				ConnectionID:   protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			})).To(Succeed())
+			m.SetHandshakeComplete()
+			Expect(m.Rotate()).To(Succeed())
+			Expect(m.Rotate()).To(MatchError(ErrNoConnectionIDAvailable))
+			Expect(frameQueue).To(HaveLen(1))
+			Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{1, 2, 3, 4})))
+		})
+
+		It("doesn't rotate the connection ID before the handshake completes", func() {
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber: 1,
+				ConnectionID:   protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			})).To(Succeed())
+			Expect(m.Rotate()).To(MatchError("can't rotate the connection ID before the handshake completes"))
+			Expect(frameQueue).To(BeEmpty())
+			Expect(m.Get()).To(Equal(initialConnID))
+		})
+	})
+
+	Context("using the preferred address connection ID", func() {
+		preferredConnID := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
+
+		It("only uses the preferred address connection ID after migrating", func() {
+			m.AddFromPreferredAddress(preferredConnID, protocol.StatelessResetToken{1})
+			m.SetHandshakeComplete()
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber: 2,
+				ConnectionID:   protocol.ParseConnectionID([]byte{2, 3, 4, 5}),
+			})).To(Succeed())
+			Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{2, 3, 4, 5})))
+			connID, ok := m.PreferredAddressConnectionID()
+			Expect(ok).To(BeTrue())
+			Expect(connID).To(Equal(preferredConnID))
+			frameQueue = nil
+			m.UsePreferredAddressConnectionID()
+			Expect(m.Get()).To(Equal(preferredConnID))
+			Expect(addedTokens).To(ContainElement(protocol.StatelessResetToken{1}))
+			Expect(frameQueue).To(Equal([]wire.Frame{&wire.RetireConnectionIDFrame{SequenceNumber: 2}}))
+			_, ok = m.PreferredAddressConnectionID()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("retires the preferred address connection ID when the peer advances retire_prior_to", func() {
+			m.AddFromPreferredAddress(preferredConnID, protocol.StatelessResetToken{1})
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber: 2,
+				ConnectionID:   protocol.ParseConnectionID([]byte{2, 3, 4, 5}),
+				RetirePriorTo:  2,
+			})).To(Succeed())
+			Expect(frameQueue).To(ContainElement(&wire.RetireConnectionIDFrame{SequenceNumber: 1}))
+			Expect(removedTokens).To(Equal([]protocol.StatelessResetToken{{1}}))
+			_, ok := m.PreferredAddressConnectionID()
+			Expect(ok).To(BeFalse())
+			m.UsePreferredAddressConnectionID()
+			Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{2, 3, 4, 5})))
+		})
+	})
+
 	It("removes the currently active stateless reset token when it is closed", func() {
 		m.Close()
 		Expect(removedTokens).To(BeEmpty())
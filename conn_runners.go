@@ -0,0 +1,142 @@
+package quic
+
+import (
+	"sync"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+)
+
+// connRunners is the connRunner used by client connections.
+// When a connection migrates to a new packet conn (see Connection.MigrateTo), it has to be registered
+// with the packet handler managers of both the old and the new packet conn.
+// All calls are forwarded to all runners.
+// Connection IDs and stateless reset tokens are tracked, such that they can be registered
+// with runners that are added later.
+type connRunners struct {
+	// The connection IDs are added and removed from the run loop,
+	// but the stateless reset token can be changed when the application rotates the connection ID.
+	mutex sync.Mutex
+
+	runners     []connRunner
+	handlers    map[protocol.ConnectionID]packetHandler
+	resetTokens map[protocol.StatelessResetToken]packetHandler
+}
+
+var _ connRunner = &connRunners{}
+
+func newConnRunners(r connRunner) *connRunners {
+	return &connRunners{
+		runners:     []connRunner{r},
+		handlers:    make(map[protocol.ConnectionID]packetHandler),
+		resetTokens: make(map[protocol.StatelessResetToken]packetHandler),
+	}
+}
+
+// AddRunner adds a runner, and registers all connection IDs and stateless reset tokens with it.
+func (r *connRunners) AddRunner(runner connRunner) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.runners = append(r.runners, runner)
+	for connID, handler := range r.handlers {
+		runner.Add(connID, handler)
+	}
+	for token, handler := range r.resetTokens {
+		runner.AddResetToken(token, handler)
+	}
+}
+
+// RemoveRunner removes a runner, and removes all connection IDs and stateless reset tokens from it.
+func (r *connRunners) RemoveRunner(runner connRunner) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, rr := range r.runners {
+		if rr != runner {
+			continue
+		}
+		r.runners = append(r.runners[:i], r.runners[i+1:]...)
+		for connID := range r.handlers {
+			runner.Remove(connID)
+		}
+		for token := range r.resetTokens {
+			runner.RemoveResetToken(token)
+		}
+		return
+	}
+}
+
+func (r *connRunners) Add(connID protocol.ConnectionID, handler packetHandler) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.handlers[connID] = handler
+	added := true
+	for _, runner := range r.runners {
+		if !runner.Add(connID, handler) {
+			added = false
+		}
+	}
+	return added
+}
+
+// GetStatelessResetToken returns the stateless reset token of the first runner.
+// All runners use the same stateless reset key.
+func (r *connRunners) GetStatelessResetToken(connID protocol.ConnectionID) protocol.StatelessResetToken {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.runners[0].GetStatelessResetToken(connID)
+}
+
+func (r *connRunners) Retire(connID protocol.ConnectionID) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.handlers, connID)
+	for _, runner := range r.runners {
+		runner.Retire(connID)
+	}
+}
+
+func (r *connRunners) Remove(connID protocol.ConnectionID) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.handlers, connID)
+	for _, runner := range r.runners {
+		runner.Remove(connID)
+	}
+}
+
+func (r *connRunners) ReplaceWithClosed(connIDs []protocol.ConnectionID, pers protocol.Perspective, connClosePacket []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, connID := range connIDs {
+		delete(r.handlers, connID)
+	}
+	for _, runner := range r.runners {
+		runner.ReplaceWithClosed(connIDs, pers, connClosePacket)
+	}
+}
+
+func (r *connRunners) AddResetToken(token protocol.StatelessResetToken, handler packetHandler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.resetTokens[token] = handler
+	for _, runner := range r.runners {
+		runner.AddResetToken(token, handler)
+	}
+}
+
+func (r *connRunners) RemoveResetToken(token protocol.StatelessResetToken) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.resetTokens, token)
+	for _, runner := range r.runners {
+		runner.RemoveResetToken(token)
+	}
+}
@@ -0,0 +1,84 @@
+package quic
+
+import (
+	"github.com/fkwhite/quic-go/internal/protocol"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection Runners", func() {
+	var (
+		runner1, runner2 *MockConnRunner
+		runners          *connRunners
+	)
+
+	BeforeEach(func() {
+		runner1 = NewMockConnRunner(mockCtrl)
+		runner2 = NewMockConnRunner(mockCtrl)
+		runners = newConnRunners(runner1)
+	})
+
+	It("forwards calls to all runners", func() {
+		runners.AddRunner(runner2)
+		connID := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
+		handler := NewMockPacketHandler(mockCtrl)
+		runner1.EXPECT().Add(connID, handler).Return(true)
+		runner2.EXPECT().Add(connID, handler).Return(true)
+		Expect(runners.Add(connID, handler)).To(BeTrue())
+		runner1.EXPECT().Retire(connID)
+		runner2.EXPECT().Retire(connID)
+		runners.Retire(connID)
+		token := protocol.StatelessResetToken{0xde, 0xad}
+		runner1.EXPECT().RemoveResetToken(token)
+		runner2.EXPECT().RemoveResetToken(token)
+		runners.RemoveResetToken(token)
+	})
+
+	It("removes runners", func() {
+		connID := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
+		token := protocol.StatelessResetToken{0xde, 0xad}
+		handler := NewMockPacketHandler(mockCtrl)
+		runners.AddRunner(runner2)
+		runner1.EXPECT().Add(connID, handler).Return(true)
+		runner2.EXPECT().Add(connID, handler).Return(true)
+		Expect(runners.Add(connID, handler)).To(BeTrue())
+		runner1.EXPECT().AddResetToken(token, handler)
+		runner2.EXPECT().AddResetToken(token, handler)
+		runners.AddResetToken(token, handler)
+
+		runner2.EXPECT().Remove(connID)
+		runner2.EXPECT().RemoveResetToken(token)
+		runners.RemoveRunner(runner2)
+		// calls are only forwarded to the remaining runner
+		runner1.EXPECT().Retire(connID)
+		runners.Retire(connID)
+	})
+
+	It("uses the first runner to generate stateless reset tokens", func() {
+		runners.AddRunner(runner2)
+		connID := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
+		runner1.EXPECT().GetStatelessResetToken(connID).Return(protocol.StatelessResetToken{0xde, 0xad})
+		Expect(runners.GetStatelessResetToken(connID)).To(Equal(protocol.StatelessResetToken{0xde, 0xad}))
+	})
+
+	It("registers connection IDs and reset tokens with runners added later", func() {
+		connID1 := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
+		connID2 := protocol.ParseConnectionID([]byte{5, 6, 7, 8})
+		token := protocol.StatelessResetToken{0xde, 0xad}
+		handler := NewMockPacketHandler(mockCtrl)
+		runner1.EXPECT().Add(gomock.Any(), handler).Return(true).Times(2)
+		Expect(runners.Add(connID1, handler)).To(BeTrue())
+		Expect(runners.Add(connID2, handler)).To(BeTrue())
+		runner1.EXPECT().Remove(connID1)
+		runners.Remove(connID1)
+		runner1.EXPECT().AddResetToken(token, handler)
+		runners.AddResetToken(token, handler)
+
+		runner2.EXPECT().Add(connID2, handler).Return(true)
+		runner2.EXPECT().AddResetToken(token, handler)
+		runners.AddRunner(runner2)
+	})
+})
@@ -46,6 +46,7 @@ type streamManager interface {
 	DeleteStream(protocol.StreamID) error
 	UpdateLimits(*wire.TransportParameters)
 	HandleMaxStreamsFrame(*wire.MaxStreamsFrame)
+	ResetAllStreams(StreamErrorCode)
 	CloseWithError(error)
 	ResetFor0RTT()
 	UseResetMaps()
@@ -76,6 +77,13 @@ type receivedPacket struct {
 	ecn protocol.ECN
 
 	info *packetInfo
+
+	// segmentSize is set when the kernel coalesced multiple UDP datagrams into this packet using UDP GRO.
+	// data then contains multiple UDP datagrams of this size, the last one may be shorter.
+	segmentSize protocol.ByteCount
+
+	// set when the packet was queued for later decryption
+	wasQueued bool
 }
 
 func (p *receivedPacket) Size() protocol.ByteCount { return protocol.ByteCount(len(p.data)) }
@@ -136,6 +144,8 @@ type connection struct {
 	// Destination connection ID used during the handshake.
 	// Used to check source connection ID on incoming packets.
 	handshakeDestConnID protocol.ConnectionID
+	// the source connection ID that we chose during the handshake
+	handshakeSrcConnID protocol.ConnectionID
 	// Set for the client. Destination connection ID used on the first Initial sent.
 	origDestConnID protocol.ConnectionID
 	retrySrcConnID *protocol.ConnectionID // only set for the client (and if a Retry was performed)
@@ -152,6 +162,33 @@ type connection struct {
 	streamsMap      streamManager
 	connIDManager   *connIDManager
 	connIDGenerator *connIDGenerator
+	pathManager     *pathManager
+
+	// remoteAddr is the peer's address on the last validated path.
+	// When the peer migrates, packets are sent to its new address right away,
+	// but remoteAddr is only updated once the new path has been validated.
+	// It is protected by a mutex, since RemoteAddr is called by the application.
+	remoteAddrMutex sync.Mutex
+	remoteAddr      net.Addr
+	// largestRcvdShortHeaderPN is used to detect peer migrations:
+	// Only packets with the largest packet number received so far can trigger a migration.
+	largestRcvdShortHeaderPN protocol.PacketNumber
+	// receivedConnectionClose is set when the peer closed the connection.
+	// A CONNECTION_CLOSE frame received from a new address doesn't initiate a migration.
+	receivedConnectionClose bool
+
+	// preferredAddr is the server's preferred address, while the client validates the path to it
+	preferredAddr                *net.UDPAddr
+	preferredAddressProbes       int
+	preferredAddressProbePending bool
+
+	// only set for the client, used to register the connection with the packet conns it migrates to
+	runners *connRunners
+	// set while the client validates the path after migrating to a new packet conn (see MigrateTo)
+	migration         *pathMigration
+	migrationRequests chan *migrationRequest // nil for the server
+	// the packet handler managers of the packet conns passed to MigrateTo, destroyed when the connection is closed
+	migrationManagers []packetHandlerManager
 
 	rttStats *utils.RTTStats
 
@@ -176,12 +213,21 @@ type connection struct {
 	receivedPackets  chan *receivedPacket
 	sendingScheduled chan struct{}
 
+	stopIssuingConnIDsOnce sync.Once
+	stopIssuingConnIDsChan chan struct{} // is closed when the server starts draining
+
 	closeOnce sync.Once
 	// closeChan is used to notify the run loop that it should terminate
 	closeChan chan closeError
 
+	gracefulCloseOnce sync.Once
+	// closingGracefully is set by CloseGracefully, after gracefulCloseErr was set
+	closingGracefully utils.AtomicBool
+	gracefulCloseErr  *qerr.ApplicationError
+
 	ctx                context.Context
-	ctxCancel          context.CancelFunc
+	ctxCancel          func(cause error)
+	closeErr           error // the error that closed the connection, set before ctx is cancelled
 	handshakeCtx       context.Context
 	handshakeCtxCancel context.CancelFunc
 
@@ -205,24 +251,68 @@ type connection struct {
 	lastPacketReceivedTime time.Time
 	// ... and the time we sent a new ack-eliciting packet after receiving a packet.
 	firstAckElicitingPacketAfterIdleSentTime time.Time
+
+	connStateMutex           sync.Mutex // protects the handshakeTimeline and the undecryptablePacketStats
+	handshakeTimeline        HandshakeTimeline
+	undecryptablePacketStats UndecryptablePacketStats
 	// pacingDeadline is the time when the next packet should be sent
 	pacingDeadline time.Time
 
 	peerParams *wire.TransportParameters
+	// zeroRTTParams are the transport parameters restored for 0-RTT (client only).
+	// They are set before the early connection is ready, and never modified afterwards.
+	zeroRTTParams *wire.TransportParameters
+	// received0RTTPacket is set when the server receives a 0-RTT packet, even if it can't be decrypted
+	received0RTTPacket bool
+	// peerSupportsResetStreamAt is accessed from the streams' goroutines (via queueControlFrame)
+	peerSupportsResetStreamAt utils.AtomicBool
+	// ackFrequencyNegotiated is set once the peer's transport parameters were applied,
+	// if both endpoints advertised the min_ack_delay transport parameter
+	ackFrequencyNegotiated utils.AtomicBool
+	// pathMTU is the current maximum packet size. It is accessed atomically.
+	pathMTU uint32
 
 	timer *utils.Timer
 	// keepAlivePingSent stores whether a keep alive PING is in flight.
 	// It is reset as soon as we receive a packet from the peer.
 	keepAlivePingSent bool
 	keepAliveInterval time.Duration
+	// pathDownNotified stores whether the PathDownCallback was called.
+	// It is reset as soon as we receive a packet from the peer.
+	pathDownNotified bool
+	// rttAbovePathDegradingThreshold stores whether the smoothed RTT exceeded the PathDegradingRTTThreshold.
+	rttAbovePathDegradingThreshold bool
 
 	datagramQueue *datagramQueue
 
+	// Tracing events that occur on other goroutines (e.g. on the streams' goroutines, or when the send time of a packet is reported)
+	// are queued, and passed to the tracer on the run loop.
+	traceQueueMutex  sync.Mutex
+	traceQueue       []func()
+	traceQueueClosed bool          // set when the run loop terminates
+	traceQueueChan   chan struct{} // notifies the run loop that events were queued
+
 	logID  string
 	tracer logging.ConnectionTracer
 	logger utils.Logger
 }
 
+var errClosingGracefully = errors.New("connection is closing gracefully")
+
+type migrationRequest struct {
+	conn   net.PacketConn
+	result chan error
+}
+
+// A pathMigration is a migration to a new packet conn, while the new path is being validated.
+type pathMigration struct {
+	local    net.Addr
+	manager  packetHandlerManager // handles the packets received on the new packet conn
+	oldConn  sendConn             // used again if the path validation fails
+	deadline time.Time            // the migration is aborted if the path isn't validated by then
+	result   chan<- error
+}
+
 var (
 	_                       Connection      = &connection{}
 	_                       EarlyConnection = &connection{}
@@ -253,6 +343,7 @@ var newConnection = func(
 		conn:                  conn,
 		config:                conf,
 		handshakeDestConnID:   destConnID,
+		handshakeSrcConnID:    srcConnID,
 		srcConnIDLen:          srcConnID.Len(),
 		tokenGenerator:        tokenGenerator,
 		oneRTTStream:          newCryptoStream(),
@@ -286,10 +377,15 @@ var newConnection = func(
 		s.version,
 	)
 	s.preSetup()
-	s.ctx, s.ctxCancel = context.WithCancel(context.WithValue(context.Background(), ConnectionTracingKey, tracingID))
+	s.ctx, s.ctxCancel = contextWithCancelCause(context.WithValue(context.Background(), ConnectionTracingKey, tracingID))
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
 		0,
-		getMaxPacketSize(s.conn.RemoteAddr()),
+		s.initialPacketSize(),
+		s.config.InitialCongestionWindow,
+		s.config.MinCongestionWindow,
+		s.config.DisablePacing,
+		uint8(s.config.AckDelayExponent),
+		s.config.MaxAckDelay,
 		s.rttStats,
 		clientAddressValidated,
 		s.perspective,
@@ -305,11 +401,12 @@ var newConnection = func(
 		InitialMaxStreamDataUni:         protocol.ByteCount(s.config.InitialStreamReceiveWindow),
 		InitialMaxData:                  protocol.ByteCount(s.config.InitialConnectionReceiveWindow),
 		MaxIdleTimeout:                  s.config.MaxIdleTimeout,
+		MaxUDPPayloadSize:               s.maxPacketSize(),
 		MaxBidiStreamNum:                protocol.StreamNum(s.config.MaxIncomingStreams),
 		MaxUniStreamNum:                 protocol.StreamNum(s.config.MaxIncomingUniStreams),
-		MaxAckDelay:                     protocol.MaxAckDelayInclGranularity,
-		AckDelayExponent:                protocol.AckDelayExponent,
-		DisableActiveMigration:          true,
+		MaxAckDelay:                     s.config.MaxAckDelay + protocol.TimerGranularity,
+		AckDelayExponent:                uint8(s.config.AckDelayExponent),
+		DisableActiveMigration:          s.config.DisableActiveMigration,
 		StatelessResetToken:             &statelessResetToken,
 		OriginalDestinationConnectionID: origDestConnID,
 		ActiveConnectionIDLimit:         protocol.MaxActiveConnectionIDs,
@@ -317,13 +414,31 @@ var newConnection = func(
 		RetrySourceConnectionID:         retrySrcConnID,
 	}
 	if s.config.EnableDatagrams {
-		params.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
+		params.MaxDatagramFrameSize = protocol.ByteCount(s.config.MaxDatagramFrameSize)
 	} else {
 		params.MaxDatagramFrameSize = protocol.InvalidByteCount
 	}
+	params.EnableResetStreamAt = s.config.EnableStreamResetPartialDelivery
+	if s.config.EnableAckFrequency {
+		minAckDelay := s.config.MinAckDelay
+		params.MinAckDelay = &minAckDelay
+	}
+	// A server that uses zero-length connection IDs can't send a preferred_address.
+	if (s.config.PreferredAddressIPv4 != nil || s.config.PreferredAddressIPv6 != nil) && s.config.ConnectionIDGenerator.ConnectionIDLen() > 0 {
+		pa, err := s.newPreferredAddress()
+		if err != nil {
+			s.logger.Errorf("Failed to issue connection ID for the preferred address: %s", err)
+		} else {
+			params.PreferredAddress = pa
+		}
+	}
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
 	}
+	var allow0RTT func() bool
+	if s.config.Allow0RTT != nil {
+		allow0RTT = func() bool { return s.config.Allow0RTT(conn.RemoteAddr()) }
+	}
 	cs := handshake.NewCryptoSetupServer(
 		initialStream,
 		handshakeStream,
@@ -342,6 +457,7 @@ var newConnection = func(
 		},
 		tlsConf,
 		enable0RTT,
+		allow0RTT,
 		s.rttStats,
 		tracer,
 		logger,
@@ -355,7 +471,7 @@ var newConnection = func(
 		handshakeStream,
 		s.sentPacketHandler,
 		s.retransmissionQueue,
-		s.RemoteAddr(),
+		s.initialPacketSize(),
 		cs,
 		s.framer,
 		s.receivedPacketHandler,
@@ -389,6 +505,7 @@ var newClientConnection = func(
 		config:                conf,
 		origDestConnID:        destConnID,
 		handshakeDestConnID:   destConnID,
+		handshakeSrcConnID:    srcConnID,
 		srcConnIDLen:          srcConnID.Len(),
 		perspective:           protocol.PerspectiveClient,
 		handshakeCompleteChan: make(chan struct{}),
@@ -398,29 +515,36 @@ var newClientConnection = func(
 		versionNegotiated:     hasNegotiatedVersion,
 		version:               v,
 	}
+	s.runners = newConnRunners(runner)
 	s.connIDManager = newConnIDManager(
 		destConnID,
-		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
-		runner.RemoveResetToken,
+		func(token protocol.StatelessResetToken) { s.runners.AddResetToken(token, s) },
+		s.runners.RemoveResetToken,
 		s.queueControlFrame,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
 		nil,
-		func(connID protocol.ConnectionID) { runner.Add(connID, s) },
-		runner.GetStatelessResetToken,
-		runner.Remove,
-		runner.Retire,
-		runner.ReplaceWithClosed,
+		func(connID protocol.ConnectionID) { s.runners.Add(connID, s) },
+		s.runners.GetStatelessResetToken,
+		s.runners.Remove,
+		s.runners.Retire,
+		s.runners.ReplaceWithClosed,
 		s.queueControlFrame,
 		s.config.ConnectionIDGenerator,
 		s.version,
 	)
 	s.preSetup()
-	s.ctx, s.ctxCancel = context.WithCancel(context.WithValue(context.Background(), ConnectionTracingKey, tracingID))
+	s.migrationRequests = make(chan *migrationRequest)
+	s.ctx, s.ctxCancel = contextWithCancelCause(context.WithValue(context.Background(), ConnectionTracingKey, tracingID))
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
 		initialPacketNumber,
-		getMaxPacketSize(s.conn.RemoteAddr()),
+		s.initialPacketSize(),
+		s.config.InitialCongestionWindow,
+		s.config.MinCongestionWindow,
+		s.config.DisablePacing,
+		uint8(s.config.AckDelayExponent),
+		s.config.MaxAckDelay,
 		s.rttStats,
 		false, /* has no effect */
 		s.perspective,
@@ -436,19 +560,25 @@ var newClientConnection = func(
 		InitialMaxStreamDataUni:        protocol.ByteCount(s.config.InitialStreamReceiveWindow),
 		InitialMaxData:                 protocol.ByteCount(s.config.InitialConnectionReceiveWindow),
 		MaxIdleTimeout:                 s.config.MaxIdleTimeout,
+		MaxUDPPayloadSize:              s.maxPacketSize(),
 		MaxBidiStreamNum:               protocol.StreamNum(s.config.MaxIncomingStreams),
 		MaxUniStreamNum:                protocol.StreamNum(s.config.MaxIncomingUniStreams),
-		MaxAckDelay:                    protocol.MaxAckDelayInclGranularity,
-		AckDelayExponent:               protocol.AckDelayExponent,
-		DisableActiveMigration:         true,
+		MaxAckDelay:                    s.config.MaxAckDelay + protocol.TimerGranularity,
+		AckDelayExponent:               uint8(s.config.AckDelayExponent),
+		DisableActiveMigration:         s.config.DisableActiveMigration,
 		ActiveConnectionIDLimit:        protocol.MaxActiveConnectionIDs,
 		InitialSourceConnectionID:      srcConnID,
 	}
 	if s.config.EnableDatagrams {
-		params.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
+		params.MaxDatagramFrameSize = protocol.ByteCount(s.config.MaxDatagramFrameSize)
 	} else {
 		params.MaxDatagramFrameSize = protocol.InvalidByteCount
 	}
+	params.EnableResetStreamAt = s.config.EnableStreamResetPartialDelivery
+	if s.config.EnableAckFrequency {
+		minAckDelay := s.config.MinAckDelay
+		params.MinAckDelay = &minAckDelay
+	}
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
 	}
@@ -483,7 +613,7 @@ var newClientConnection = func(
 		handshakeStream,
 		s.sentPacketHandler,
 		s.retransmissionQueue,
-		s.RemoteAddr(),
+		s.initialPacketSize(),
 		cs,
 		s.framer,
 		s.receivedPacketHandler,
@@ -506,9 +636,20 @@ var newClientConnection = func(
 
 func (s *connection) preSetup() {
 	s.sendQueue = newSendQueue(s.conn)
+	atomic.StoreUint32(&s.pathMTU, uint32(s.initialPacketSize()))
 	s.retransmissionQueue = newRetransmissionQueue(s.version)
-	s.frameParser = wire.NewFrameParser(s.config.EnableDatagrams, s.version)
+	s.frameParser = wire.NewFrameParserWithOptions(wire.FrameParserOptions{
+		SupportsDatagrams:     s.config.EnableDatagrams,
+		SupportsResetStreamAt: s.config.EnableStreamResetPartialDelivery,
+		SupportsAckFrequency:  s.config.EnableAckFrequency,
+	}, s.version)
+	for frameType, parse := range s.config.ExtensionFrameTypes {
+		s.frameParser.RegisterFrameType(frameType, parse)
+	}
 	s.rttStats = &utils.RTTStats{}
+	if s.config.InitialRTT > 0 {
+		s.rttStats.SetInitialRTTEstimate(s.config.InitialRTT)
+	}
 	s.connFlowController = flowcontrol.NewConnectionFlowController(
 		protocol.ByteCount(s.config.InitialConnectionReceiveWindow),
 		protocol.ByteCount(s.config.MaxConnectionReceiveWindow),
@@ -523,23 +664,30 @@ func (s *connection) preSetup() {
 		s.logger,
 	)
 	s.earlyConnReadyChan = make(chan struct{})
+	s.pathManager = newPathManager()
+	s.remoteAddr = s.conn.RemoteAddr()
+	s.largestRcvdShortHeaderPN = protocol.InvalidPacketNumber
 	s.streamsMap = newStreamsMap(
 		s,
 		s.newFlowController,
 		uint64(s.config.MaxIncomingStreams),
 		uint64(s.config.MaxIncomingUniStreams),
 		s.perspective,
+		s.tracer,
 		s.version,
 	)
 	s.framer = newFramer(s.streamsMap, s.version)
 	s.receivedPackets = make(chan *receivedPacket, protocol.MaxConnUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
 	s.sendingScheduled = make(chan struct{}, 1)
+	s.traceQueueChan = make(chan struct{}, 1)
+	s.stopIssuingConnIDsChan = make(chan struct{})
 	s.handshakeCtx, s.handshakeCtxCancel = context.WithCancel(context.Background())
 
 	now := time.Now()
 	s.lastPacketReceivedTime = now
 	s.creationTime = now
+	s.handshakeTimeline.Start = now
 
 	s.windowUpdateQueue = newWindowUpdateQueue(s.streamsMap, s.connFlowController, s.framer.QueueControlFrame)
 	s.datagramQueue = newDatagramQueue(s.scheduleSending, s.logger, s.version)
@@ -547,8 +695,6 @@ func (s *connection) preSetup() {
 
 // run the connection main loop
 func (s *connection) run() error {
-	defer s.ctxCancel()
-
 	s.timer = utils.NewTimer()
 
 	handshaking := make(chan struct{})
@@ -623,6 +769,8 @@ runLoop:
 				// We do all the interesting stuff after the switch statement, so
 				// nothing to see here.
 			case <-sendQueueAvailable:
+			case <-s.traceQueueChan:
+				s.traceQueuedEvents()
 			case firstPacket := <-s.receivedPackets:
 				wasProcessed := s.handlePacketImpl(firstPacket)
 				// Don't set timers and send packets if the packet made us close the connection.
@@ -661,6 +809,13 @@ runLoop:
 				}
 			case <-s.handshakeCompleteChan:
 				s.handleHandshakeComplete()
+			case <-s.stopIssuingConnIDsChan:
+				s.stopIssuingConnIDsChan = nil // prevent this case from ever being selected again
+				s.connIDGenerator.StopIssuing()
+			case req := <-s.migrationRequests:
+				if err := s.startMigration(req.conn, req.result); err != nil {
+					req.result <- err
+				}
 			}
 		}
 
@@ -668,10 +823,20 @@ runLoop:
 		if timeout := s.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && timeout.Before(now) {
 			// This could cause packets to be retransmitted.
 			// Check it before trying to send packets.
+			ptoCount := s.sentPacketHandler.PTOCount()
 			if err := s.sentPacketHandler.OnLossDetectionTimeout(); err != nil {
 				s.closeLocal(err)
+			} else if s.config.PathDegradingCallback != nil {
+				s.maybeNotifyPathDegrading(ptoCount)
 			}
 		}
+		if pathDownTime := s.pathDownTime(); !pathDownTime.IsZero() && !now.Before(pathDownTime) {
+			s.pathDownNotified = true
+			s.config.PathDownCallback(s)
+		}
+		if s.migration != nil && !now.Before(s.migration.deadline) {
+			s.abortMigration()
+		}
 
 		if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() && !now.Before(keepAliveTime) {
 			// send a PING frame since there is no activity in the connection
@@ -699,6 +864,10 @@ runLoop:
 		if err := s.sendPackets(); err != nil {
 			s.closeLocal(err)
 		}
+		if s.closingGracefully.Get() && !s.framer.HasData() && !s.sentPacketHandler.HasOutstandingPackets() {
+			// all stream data was sent and acknowledged
+			s.closeLocal(s.gracefulCloseErr)
+		}
 		if s.sendQueue.WouldBlock() {
 			sendQueueAvailable = s.sendQueue.Available()
 		} else {
@@ -708,13 +877,23 @@ runLoop:
 
 	s.cryptoStreamHandler.Close()
 	<-handshaking
-	s.handleCloseError(&closeErr)
+	s.traceQueueMutex.Lock()
+	s.traceQueueClosed = true
+	s.traceQueueMutex.Unlock()
+	s.traceQueuedEvents()
+	cause := s.handleCloseError(&closeErr)
 	if e := (&errCloseForRecreating{}); !errors.As(closeErr.err, &e) && s.tracer != nil {
 		s.tracer.Close()
 	}
 	s.logger.Infof("Connection %s closed.", s.logID)
 	s.sendQueue.Close()
 	s.timer.Stop()
+	s.closeErr = cause
+	s.ctxCancel(cause)
+	// Closing the packet conns that we migrated to removes them from the multiplexer.
+	for _, manager := range s.migrationManagers {
+		manager.Destroy()
+	}
 	return closeErr.err
 }
 
@@ -727,6 +906,17 @@ func (s *connection) HandshakeComplete() context.Context {
 	return s.handshakeCtx
 }
 
+func (s *connection) AwaitHandshake(ctx context.Context) error {
+	select {
+	case <-s.handshakeCtx.Done():
+		return nil
+	case <-s.ctx.Done():
+		return s.closeErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (s *connection) Context() context.Context {
 	return s.ctx
 }
@@ -736,10 +926,184 @@ func (s *connection) supportsDatagrams() bool {
 }
 
 func (s *connection) ConnectionState() ConnectionState {
+	s.connStateMutex.Lock()
+	timeline := s.handshakeTimeline
+	undecryptablePacketStats := s.undecryptablePacketStats
+	s.connStateMutex.Unlock()
+
 	return ConnectionState{
-		TLS:               s.cryptoStreamHandler.ConnectionState(),
-		SupportsDatagrams: s.supportsDatagrams(),
+		TLS:                  s.cryptoStreamHandler.ConnectionState(),
+		SupportsDatagrams:    s.supportsDatagrams(),
+		Handshake:            timeline,
+		UndecryptablePackets: undecryptablePacketStats,
+		ECNCongestionSignals: s.sentPacketHandler.ECNCongestionSignals(),
+		UsesPacketInfo:       s.conn.UsesPacketInfo(),
+	}
+}
+
+// recordHandshakeMilestone sets the time of a milestone in the handshake timeline,
+// unless it was already reached before.
+func (s *connection) recordHandshakeMilestone(milestone *time.Time, t time.Time) {
+	s.connStateMutex.Lock()
+	defer s.connStateMutex.Unlock()
+
+	if milestone.IsZero() {
+		*milestone = t
+	}
+}
+
+// updateUndecryptablePacketStats is used to update the counters for undecryptable packets.
+func (s *connection) updateUndecryptablePacketStats(f func(*UndecryptablePacketStats)) {
+	s.connStateMutex.Lock()
+	f(&s.undecryptablePacketStats)
+	s.connStateMutex.Unlock()
+}
+
+func (s *connection) PathMTU() uint16 {
+	return uint16(atomic.LoadUint32(&s.pathMTU))
+}
+
+func (s *connection) IdleTimeout() time.Duration {
+	select {
+	case <-s.handshakeCtx.Done():
+		return s.idleTimeout
+	case <-s.ctx.Done():
+		return 0
+	}
+}
+
+func (s *connection) Ping(ctx context.Context) (time.Duration, error) {
+	select {
+	case <-s.handshakeCtx.Done():
+	case <-s.ctx.Done():
+		return 0, s.closeErr
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	acked := make(chan time.Duration, 1)
+	if s.ackFrequencyNegotiated.Get() {
+		// ask the peer to acknowledge the PING frame without delay
+		s.framer.QueueControlFrame(&wire.ImmediateAckFrame{})
+	}
+	s.framer.QueuePing(func(rtt time.Duration) { acked <- rtt })
+	s.scheduleSending()
+	select {
+	case rtt := <-acked:
+		return rtt, nil
+	case <-s.ctx.Done():
+		return 0, s.closeErr
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func (s *connection) ResetAllStreams(code StreamErrorCode) {
+	s.streamsMap.ResetAllStreams(code)
+}
+
+func (s *connection) RotateConnectionID() error {
+	if err := s.connIDManager.Rotate(); err != nil {
+		return err
+	}
+	// send the RETIRE_CONNECTION_ID frame
+	s.scheduleSending()
+	return nil
+}
+
+func (s *connection) MigrateTo(ctx context.Context, conn net.PacketConn) error {
+	if s.perspective == protocol.PerspectiveServer {
+		return errors.New("only clients can migrate")
+	}
+	req := &migrationRequest{conn: conn, result: make(chan error, 1)}
+	select {
+	case s.migrationRequests <- req:
+	case <-s.ctx.Done():
+		return s.closeErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-req.result:
+		return err
+	case <-s.ctx.Done():
+		return s.closeErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startMigration switches to a new packet conn, and starts validating the path from the new local address.
+// Packets are sent on the new path right away (see section 9.2 of RFC 9000).
+// Once the path has been validated, nil is sent on the result channel.
+func (s *connection) startMigration(conn net.PacketConn, result chan<- error) error {
+	if !s.handshakeConfirmed {
+		return errors.New("can't migrate before the handshake is confirmed")
+	}
+	if s.peerParams.DisableActiveMigration {
+		return ErrActiveMigrationDisabled
+	}
+	if s.migration != nil {
+		return errors.New("migration already in progress")
+	}
+	sconn, ok := s.conn.(*switchableSendConn)
+	if !ok {
+		return errors.New("connection doesn't support migration")
 	}
+	manager, err := getMultiplexer().AddConn(conn, s.config.ConnectionIDGenerator.ConnectionIDLen(), s.config.StatelessResetKey, s.config.Tracer)
+	if err != nil {
+		return err
+	}
+	if s.config.usesLargePackets() {
+		manager.UseLargeBuffers()
+	}
+	s.runners.AddRunner(manager)
+	s.migrationManagers = append(s.migrationManagers, manager)
+	oldConn := sconn.get()
+	oldAddr := sconn.LocalAddr()
+	sconn.Switch(newSendPconn(conn, sconn.RemoteAddr()))
+	if s.logger.Debug() {
+		s.logger.Debugf("Migrating from local address %s to %s", oldAddr, conn.LocalAddr())
+	}
+	// Don't use the same connection ID from a different local address, to prevent linkability (see section 9.5 of RFC 9000).
+	if err := s.connIDManager.Rotate(); err != nil && s.logger.Debug() {
+		s.logger.Debugf("Not rotating the connection ID on migration: %s", err)
+	}
+	// The congestion state of the old path doesn't apply to the new path.
+	// There's no need to limit the amount of data sent to the server's address though.
+	s.sentPacketHandler.MigratedPath(0, true)
+	s.sentPacketHandler.PathValidated()
+	s.migration = &pathMigration{
+		local:    conn.LocalAddr(),
+		manager:  manager,
+		oldConn:  oldConn,
+		deadline: time.Now().Add(3 * s.rttStats.PTO(true)), // see section 8.2.4 of RFC 9000
+		result:   result,
+	}
+	s.queueControlFrame(s.pathManager.NewChallenge(conn.LocalAddr(), sconn.RemoteAddr()))
+	return nil
+}
+
+// abortMigration moves the connection back to the packet conn it used before the migration,
+// and closes the new packet conn.
+func (s *connection) abortMigration() {
+	m := s.migration
+	s.migration = nil
+	if s.logger.Debug() {
+		s.logger.Debugf("Validating the path from %s timed out. Moving back to %s.", m.local, m.oldConn.LocalAddr())
+	}
+	s.conn.(*switchableSendConn).Switch(m.oldConn)
+	s.sentPacketHandler.MigratedPath(0, true)
+	s.sentPacketHandler.PathValidated()
+	s.runners.RemoveRunner(m.manager)
+	for i, manager := range s.migrationManagers {
+		if manager == m.manager {
+			s.migrationManagers = append(s.migrationManagers[:i], s.migrationManagers[i+1:]...)
+			break
+		}
+	}
+	// Closing the packet conn removes it from the multiplexer.
+	go m.manager.Destroy()
+	m.result <- ErrPathValidationTimeout
 }
 
 // Time when the next keep-alive packet should be sent.
@@ -775,6 +1139,12 @@ func (s *connection) maybeResetTimer() {
 	if !s.pacingDeadline.IsZero() {
 		deadline = utils.MinTime(deadline, s.pacingDeadline)
 	}
+	if pathDownTime := s.pathDownTime(); !pathDownTime.IsZero() {
+		deadline = utils.MinTime(deadline, pathDownTime)
+	}
+	if s.migration != nil {
+		deadline = utils.MinTime(deadline, s.migration.deadline)
+	}
 
 	s.timer.Reset(deadline)
 }
@@ -783,17 +1153,45 @@ func (s *connection) idleTimeoutStartTime() time.Time {
 	return utils.MaxTime(s.lastPacketReceivedTime, s.firstAckElicitingPacketAfterIdleSentTime)
 }
 
+// pathDownTime returns the time when the PathDownCallback should be called:
+// 3 PTOs before the idle timeout expires, if the peer didn't respond to any of the packets we sent since then.
+// It returns the zero value if the callback is not set or was already called.
+func (s *connection) pathDownTime() time.Time {
+	if s.config.PathDownCallback == nil || s.pathDownNotified || !s.handshakeComplete || s.firstAckElicitingPacketAfterIdleSentTime.IsZero() {
+		return time.Time{}
+	}
+	return s.idleTimeoutStartTime().Add(s.idleTimeout - 3*s.rttStats.PTO(true))
+}
+
 func (s *connection) handleHandshakeComplete() {
 	s.handshakeComplete = true
+	s.recordHandshakeMilestone(&s.handshakeTimeline.HandshakeComplete, time.Now())
 	s.handshakeCompleteChan = nil // prevent this case from ever being selected again
 	defer s.handshakeCtxCancel()
 	// Once the handshake completes, we have derived 1-RTT keys.
 	// There's no point in queueing undecryptable packets for later decryption any more.
+	if len(s.undecryptablePackets) > 0 {
+		s.updateUndecryptablePacketStats(func(stats *UndecryptablePacketStats) { stats.Dropped += uint64(len(s.undecryptablePackets)) })
+		for _, p := range s.undecryptablePackets {
+			if s.tracer != nil {
+				s.tracer.DroppedPacket(logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropKeyUnavailable)
+			}
+		}
+	}
 	s.undecryptablePackets = nil
 
 	s.connIDManager.SetHandshakeComplete()
 	s.connIDGenerator.SetHandshakeComplete()
 
+	if s.tracer != nil {
+		accepted0RTT := s.cryptoStreamHandler.ConnectionState().Used0RTT
+		attempted0RTT := accepted0RTT || s.received0RTTPacket
+		if s.perspective == protocol.PerspectiveClient {
+			attempted0RTT = s.zeroRTTParams != nil
+		}
+		s.tracer.CompletedHandshake(attempted0RTT, accepted0RTT)
+	}
+
 	if s.perspective == protocol.PerspectiveClient {
 		s.applyTransportParameters()
 		return
@@ -821,24 +1219,38 @@ func (s *connection) handleHandshakeComplete() {
 
 func (s *connection) handleHandshakeConfirmed() {
 	s.handshakeConfirmed = true
+	s.recordHandshakeMilestone(&s.handshakeTimeline.HandshakeConfirmed, time.Now())
 	s.sentPacketHandler.SetHandshakeConfirmed()
 	s.cryptoStreamHandler.SetHandshakeConfirmed()
 
+	if s.perspective == protocol.PerspectiveClient && s.peerParams.PreferredAddress != nil {
+		s.startPreferredAddressValidation()
+	}
+
 	if !s.config.DisablePathMTUDiscovery {
 		maxPacketSize := s.peerParams.MaxUDPPayloadSize
 		if maxPacketSize == 0 {
 			maxPacketSize = protocol.MaxByteCount
 		}
-		maxPacketSize = utils.Min(maxPacketSize, protocol.MaxPacketBufferSize)
+		maxPacketSize = utils.Min(maxPacketSize, s.maxPacketSize())
 		s.mtuDiscoverer = newMTUDiscoverer(
 			s.rttStats,
-			getMaxPacketSize(s.conn.RemoteAddr()),
+			utils.Min(s.initialPacketSize(), maxPacketSize),
 			maxPacketSize,
 			func(size protocol.ByteCount) {
 				s.sentPacketHandler.SetMaxDatagramSize(size)
 				s.packer.SetMaxPacketSize(size)
+				atomic.StoreUint32(&s.pathMTU, uint32(size))
 			},
+			s.tracer,
 		)
+		s.sentPacketHandler.SetPacketFeedbackCallback(func(size protocol.ByteCount, lost bool) {
+			if lost {
+				s.mtuDiscoverer.OnPacketLost(size)
+			} else {
+				s.mtuDiscoverer.OnPacketAcked(size)
+			}
+		})
 	}
 }
 
@@ -941,11 +1353,24 @@ func (s *connection) handleShortHeaderPacket(p *receivedPacket, destConnID proto
 		}
 	}()
 
+	if s.isDisabledMigration(p) {
+		if s.logger.Debug() {
+			s.logger.Debugf("Dropping packet from %s, since active migration is disabled", p.remoteAddr)
+		}
+		if s.tracer != nil {
+			s.tracer.DroppedPacket(logging.PacketType1RTT, p.Size(), logging.PacketDropUnexpectedPacket)
+		}
+		return false
+	}
+
 	pn, pnLen, keyPhase, data, err := s.unpacker.UnpackShortHeader(p.rcvTime, p.data)
 	if err != nil {
 		wasQueued = s.handleUnpackError(err, p, logging.PacketType1RTT)
 		return false
 	}
+	if p.wasQueued {
+		s.updateUndecryptablePacketStats(func(stats *UndecryptablePacketStats) { stats.Decrypted++ })
+	}
 
 	if s.logger.Debug() {
 		s.logger.Debugf("<- Reading packet %d (%d bytes) for connection %s, 1-RTT", pn, p.Size(), destConnID)
@@ -975,13 +1400,80 @@ func (s *connection) handleShortHeaderPacket(p *receivedPacket, destConnID proto
 			)
 		}
 	}
-	if err := s.handleUnpackedShortHeaderPacket(destConnID, pn, data, p.ecn, p.rcvTime, log); err != nil {
+	isNonProbing, err := s.handleUnpackedShortHeaderPacket(destConnID, pn, data, p.ecn, p.rcvTime, log)
+	if err != nil {
 		s.closeLocal(err)
 		return false
 	}
+	s.checkPeerMigration(p, pn, isNonProbing)
 	return true
 }
 
+// checkPeerMigration detects if the peer migrated to a new address (see section 9.3 of RFC 9000).
+// Packets are sent to the new address right away, limited by the anti-amplification limit,
+// and a PATH_CHALLENGE is sent to validate the new path.
+// Only servers handle migrations, and only after the handshake was confirmed.
+func (s *connection) checkPeerMigration(p *receivedPacket, pn protocol.PacketNumber, isNonProbing bool) {
+	isLargest := pn > s.largestRcvdShortHeaderPN
+	if isLargest {
+		s.largestRcvdShortHeaderPN = pn
+	}
+	if s.perspective != protocol.PerspectiveServer || !s.handshakeConfirmed || s.receivedConnectionClose || p.remoteAddr == nil {
+		return
+	}
+	// Only packets with the largest packet number containing non-probing frames indicate a migration.
+	// This prevents reordered packets from switching back to an old address.
+	if !isNonProbing || !isLargest {
+		return
+	}
+	// The client might have started sending to a different local address, e.g. our preferred address.
+	// Packets are then sent from that address.
+	if p.info != nil {
+		s.conn.SetPacketInfo(p.info)
+	}
+	currentAddr := s.conn.RemoteAddr()
+	if p.remoteAddr.String() == currentAddr.String() {
+		return
+	}
+	if s.logger.Debug() {
+		s.logger.Debugf("Peer migrated from %s to %s", currentAddr, p.remoteAddr)
+	}
+	// We keep using the current connection ID. Since we're still sending from the same local address,
+	// this is allowed for a peer that migrated (see section 9.5 of RFC 9000).
+	s.conn.SetRemoteAddr(p.remoteAddr)
+	if p.remoteAddr.String() == s.RemoteAddr().String() {
+		// The peer returned to the last validated path.
+		s.sentPacketHandler.PathValidated()
+		return
+	}
+	s.sentPacketHandler.MigratedPath(p.Size(), !onlyPortChanged(currentAddr, p.remoteAddr))
+	s.queueControlFrame(s.pathManager.NewChallenge(s.conn.LocalAddr(), p.remoteAddr))
+}
+
+// isDisabledMigration says if a packet was received from a new client address, although we disabled active migration.
+// Packets received on a new path are dropped (without sending a stateless reset) instead of validating the path,
+// see section 9 of RFC 9000.
+func (s *connection) isDisabledMigration(p *receivedPacket) bool {
+	if !s.config.DisableActiveMigration || s.perspective != protocol.PerspectiveServer || !s.handshakeConfirmed || p.remoteAddr == nil {
+		return false
+	}
+	return p.remoteAddr.String() != s.conn.RemoteAddr().String()
+}
+
+// onlyPortChanged says if two addresses only differ in their port.
+// In that case, the congestion state is kept (see section 9.4 of RFC 9000).
+func onlyPortChanged(a, b net.Addr) bool {
+	udpA, ok := a.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	udpB, ok := b.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	return udpA.IP.Equal(udpB.IP) && udpA.Zone == udpB.Zone
+}
+
 func (s *connection) handleLongHeaderPacket(p *receivedPacket, hdr *wire.Header) bool /* was the packet successfully processed */ {
 	var wasQueued bool
 
@@ -1012,12 +1504,18 @@ func (s *connection) handleLongHeaderPacket(p *receivedPacket, hdr *wire.Header)
 		}
 		return false
 	}
+	if hdr.Type == protocol.PacketType0RTT {
+		s.received0RTTPacket = true
+	}
 
 	packet, err := s.unpacker.UnpackLongHeader(hdr, p.rcvTime, p.data)
 	if err != nil {
 		wasQueued = s.handleUnpackError(err, p, logging.PacketTypeFromHeader(hdr))
 		return false
 	}
+	if p.wasQueued {
+		s.updateUndecryptablePacketStats(func(stats *UndecryptablePacketStats) { stats.Decrypted++ })
+	}
 
 	if s.logger.Debug() {
 		s.logger.Debugf("<- Reading packet %d (%d bytes) for connection %s, %s", packet.hdr.PacketNumber, p.Size(), hdr.DestConnectionID, packet.encryptionLevel)
@@ -1042,6 +1540,7 @@ func (s *connection) handleLongHeaderPacket(p *receivedPacket, hdr *wire.Header)
 func (s *connection) handleUnpackError(err error, p *receivedPacket, pt logging.PacketType) (wasQueued bool) {
 	switch err {
 	case handshake.ErrKeysDropped:
+		s.updateUndecryptablePacketStats(func(stats *UndecryptablePacketStats) { stats.Dropped++ })
 		if s.tracer != nil {
 			s.tracer.DroppedPacket(pt, p.Size(), logging.PacketDropKeyUnavailable)
 		}
@@ -1058,6 +1557,7 @@ func (s *connection) handleUnpackError(err error, p *receivedPacket, pt logging.
 		})
 	case handshake.ErrDecryptionFailed:
 		// This might be a packet injected by an attacker. Drop it.
+		s.updateUndecryptablePacketStats(func(stats *UndecryptablePacketStats) { stats.Dropped++ })
 		if s.tracer != nil {
 			s.tracer.DroppedPacket(pt, p.Size(), logging.PacketDropPayloadDecryptError)
 		}
@@ -1174,6 +1674,14 @@ func (s *connection) handleVersionNegotiationPacket(p *receivedPacket) {
 	if s.tracer != nil {
 		s.tracer.ReceivedVersionNegotiationPacket(dest, src, supportedVersions)
 	}
+	if s.config.DisableVersionNegotiation {
+		s.destroyImpl(&VersionNegotiationError{
+			Ours:   s.config.Versions,
+			Theirs: supportedVersions,
+		})
+		s.logger.Infof("Version negotiation disabled, not switching to a different QUIC version.")
+		return
+	}
 	newVersion, ok := protocol.ChooseSupportedVersion(s.config.Versions, supportedVersions)
 	if !ok {
 		s.destroyImpl(&VersionNegotiationError{
@@ -1241,9 +1749,14 @@ func (s *connection) handleUnpackedPacket(
 		}
 	}
 
+	if packet.encryptionLevel == protocol.EncryptionHandshake {
+		s.recordHandshakeMilestone(&s.handshakeTimeline.HandshakeKeys, rcvTime)
+	}
+
 	s.lastPacketReceivedTime = rcvTime
 	s.firstAckElicitingPacketAfterIdleSentTime = time.Time{}
 	s.keepAlivePingSent = false
+	s.pathDownNotified = false
 
 	var log func([]logging.Frame)
 	if s.tracer != nil {
@@ -1251,7 +1764,7 @@ func (s *connection) handleUnpackedPacket(
 			s.tracer.ReceivedLongHeaderPacket(packet.hdr, packetSize, frames)
 		}
 	}
-	isAckEliciting, err := s.handleFrames(packet.data, packet.hdr.DestConnectionID, packet.encryptionLevel, log)
+	isAckEliciting, _, err := s.handleFrames(packet.data, packet.hdr.DestConnectionID, packet.encryptionLevel, log)
 	if err != nil {
 		return err
 	}
@@ -1265,16 +1778,17 @@ func (s *connection) handleUnpackedShortHeaderPacket(
 	ecn protocol.ECN,
 	rcvTime time.Time,
 	log func([]logging.Frame),
-) error {
+) (isNonProbing bool, _ error) {
 	s.lastPacketReceivedTime = rcvTime
 	s.firstAckElicitingPacketAfterIdleSentTime = time.Time{}
 	s.keepAlivePingSent = false
+	s.pathDownNotified = false
 
-	isAckEliciting, err := s.handleFrames(data, destConnID, protocol.Encryption1RTT, log)
+	isAckEliciting, isNonProbing, err := s.handleFrames(data, destConnID, protocol.Encryption1RTT, log)
 	if err != nil {
-		return err
+		return false, err
 	}
-	return s.receivedPacketHandler.ReceivedPacket(pn, ecn, protocol.Encryption1RTT, rcvTime, isAckEliciting)
+	return isNonProbing, s.receivedPacketHandler.ReceivedPacket(pn, ecn, protocol.Encryption1RTT, rcvTime, isAckEliciting)
 }
 
 func (s *connection) handleFrames(
@@ -1282,14 +1796,14 @@ func (s *connection) handleFrames(
 	destConnID protocol.ConnectionID,
 	encLevel protocol.EncryptionLevel,
 	log func([]logging.Frame),
-) (isAckEliciting bool, _ error) {
+) (isAckEliciting, isNonProbing bool, _ error) {
 	// Only used for tracing.
 	// If we're not tracing, this slice will always remain empty.
 	var frames []wire.Frame
 	for len(data) > 0 {
 		l, frame, err := s.frameParser.ParseNext(data, encLevel)
 		if err != nil {
-			return false, err
+			return false, false, err
 		}
 		data = data[l:]
 		if frame == nil {
@@ -1298,11 +1812,14 @@ func (s *connection) handleFrames(
 		if ackhandler.IsFrameAckEliciting(frame) {
 			isAckEliciting = true
 		}
+		if !isProbingFrame(frame) {
+			isNonProbing = true
+		}
 		// Only process frames now if we're not logging.
 		// If we're logging, we need to make sure that the packet_received event is logged first.
 		if log == nil {
 			if err := s.handleFrame(frame, encLevel, destConnID); err != nil {
-				return false, err
+				return false, false, err
 			}
 		} else {
 			frames = append(frames, frame)
@@ -1317,16 +1834,29 @@ func (s *connection) handleFrames(
 		log(fs)
 		for _, frame := range frames {
 			if err := s.handleFrame(frame, encLevel, destConnID); err != nil {
-				return false, err
+				return false, false, err
 			}
 		}
 	}
 	return
 }
 
+// isProbingFrame says if a frame is a probing frame, as defined in section 9.1 of RFC 9000.
+// PADDING frames are probing frames as well, but they are never returned by the frame parser.
+func isProbingFrame(f wire.Frame) bool {
+	switch f.(type) {
+	case *wire.PathChallengeFrame, *wire.PathResponseFrame, *wire.NewConnectionIDFrame:
+		return true
+	default:
+		return false
+	}
+}
+
 func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, destConnID protocol.ConnectionID) error {
 	var err error
 	wire.LogFrame(s.logger, f, false)
+	// Determine the frame type now, since some frames are returned to a pool when they are handled.
+	frameType := wire.FrameType(f)
 	switch frame := f.(type) {
 	case *wire.CryptoFrame:
 		err = s.handleCryptoFrame(frame, encLevel)
@@ -1334,28 +1864,29 @@ func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel
 		err = s.handleStreamFrame(frame)
 	case *wire.AckFrame:
 		err = s.handleAckFrame(frame, encLevel)
-		wire.PutAckFrame(frame)
 	case *wire.ConnectionCloseFrame:
 		s.handleConnectionCloseFrame(frame)
 	case *wire.ResetStreamFrame:
 		err = s.handleResetStreamFrame(frame)
+	case *wire.ResetStreamAtFrame:
+		err = s.handleResetStreamAtFrame(frame)
 	case *wire.MaxDataFrame:
 		s.handleMaxDataFrame(frame)
 	case *wire.MaxStreamDataFrame:
 		err = s.handleMaxStreamDataFrame(frame)
 	case *wire.MaxStreamsFrame:
 		s.handleMaxStreamsFrame(frame)
-	case *wire.DataBlockedFrame:
-	case *wire.StreamDataBlockedFrame:
-	case *wire.StreamsBlockedFrame:
+	case *wire.DataBlockedFrame, *wire.StreamDataBlockedFrame, *wire.StreamsBlockedFrame:
+		if s.tracer != nil {
+			s.tracer.Blocked(frame, true)
+		}
 	case *wire.StopSendingFrame:
 		err = s.handleStopSendingFrame(frame)
 	case *wire.PingFrame:
 	case *wire.PathChallengeFrame:
 		s.handlePathChallengeFrame(frame)
 	case *wire.PathResponseFrame:
-		// since we don't send PATH_CHALLENGEs, we don't expect PATH_RESPONSEs
-		err = errors.New("unexpected PATH_RESPONSE frame")
+		err = s.handlePathResponseFrame(frame)
 	case *wire.NewTokenFrame:
 		err = s.handleNewTokenFrame(frame)
 	case *wire.NewConnectionIDFrame:
@@ -1364,11 +1895,23 @@ func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel
 		err = s.handleRetireConnectionIDFrame(frame, destConnID)
 	case *wire.HandshakeDoneFrame:
 		err = s.handleHandshakeDoneFrame()
+	case *wire.ImmediateAckFrame:
+		s.receivedPacketHandler.QueueAck()
+	case *wire.AckFrequencyFrame:
+		err = s.handleAckFrequencyFrame(frame)
 	case *wire.DatagramFrame:
 		err = s.handleDatagramFrame(frame)
+	case *wire.ExtensionFrame:
+		err = s.handleExtensionFrame(frame)
 	default:
 		err = fmt.Errorf("unexpected frame type: %s", reflect.ValueOf(&frame).Elem().Type().Name())
 	}
+	s.frameParser.Release(f)
+	// Include the type of the frame that triggered the error in the CONNECTION_CLOSE frame.
+	var transportErr *qerr.TransportError
+	if err != nil && errors.As(err, &transportErr) && transportErr.FrameType == 0 {
+		transportErr.FrameType = frameType
+	}
 	return err
 }
 
@@ -1385,7 +1928,33 @@ func (s *connection) handlePacket(p *receivedPacket) {
 	}
 }
 
+func (s *connection) handleAckFrequencyFrame(frame *wire.AckFrequencyFrame) error {
+	// The peer must not request a max_ack_delay smaller than the min_ack_delay we advertised.
+	if frame.RequestMaxAckDelay < s.config.MinAckDelay {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: fmt.Sprintf("requested max_ack_delay (%s) smaller than min_ack_delay (%s)", frame.RequestMaxAckDelay, s.config.MinAckDelay),
+		}
+	}
+	s.receivedPacketHandler.HandleAckFrequencyFrame(frame)
+	return nil
+}
+
+func (s *connection) handleExtensionFrame(frame *wire.ExtensionFrame) error {
+	if s.config.HandleExtensionFrame == nil {
+		return nil
+	}
+	if err := s.config.HandleExtensionFrame(s, frame.FrameType, frame.Data); err != nil {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: err.Error(),
+		}
+	}
+	return nil
+}
+
 func (s *connection) handleConnectionCloseFrame(frame *wire.ConnectionCloseFrame) {
+	s.receivedConnectionClose = true
 	if frame.IsApplicationError {
 		s.closeRemote(&qerr.ApplicationError{
 			Remote:       true,
@@ -1461,6 +2030,18 @@ func (s *connection) handleResetStreamFrame(frame *wire.ResetStreamFrame) error
 	return str.handleResetStreamFrame(frame)
 }
 
+func (s *connection) handleResetStreamAtFrame(frame *wire.ResetStreamAtFrame) error {
+	str, err := s.streamsMap.GetOrOpenReceiveStream(frame.StreamID)
+	if err != nil {
+		return err
+	}
+	if str == nil {
+		// stream is closed and already garbage collected
+		return nil
+	}
+	return str.handleResetStreamAtFrame(frame)
+}
+
 func (s *connection) handleStopSendingFrame(frame *wire.StopSendingFrame) error {
 	str, err := s.streamsMap.GetOrOpenSendStream(frame.StreamID)
 	if err != nil {
@@ -1478,6 +2059,39 @@ func (s *connection) handlePathChallengeFrame(frame *wire.PathChallengeFrame) {
 	s.queueControlFrame(&wire.PathResponseFrame{Data: frame.Data})
 }
 
+func (s *connection) handlePathResponseFrame(frame *wire.PathResponseFrame) error {
+	local, remote, ok := s.pathManager.HandlePathResponse(frame)
+	if !ok {
+		// The peer responds to every PATH_CHALLENGE it receives, so a PATH_RESPONSE might be a duplicate.
+		// It might also be a response to a challenge that was already discarded.
+		s.logger.Debugf("Ignoring PATH_RESPONSE frame for unknown challenge %#x", frame.Data)
+		return nil
+	}
+	if s.logger.Debug() {
+		s.logger.Debugf("Validated path from %s to %s", local, remote)
+	}
+	if s.preferredAddr != nil && remote.String() == s.preferredAddr.String() {
+		s.migrateToPreferredAddress()
+	}
+	if s.migration != nil && local.String() == s.migration.local.String() {
+		s.migration.result <- nil
+		s.migration = nil
+	}
+	// The peer might already have moved on to yet another address.
+	if remote.String() != s.conn.RemoteAddr().String() {
+		return nil
+	}
+	s.sentPacketHandler.PathValidated()
+	s.remoteAddrMutex.Lock()
+	oldAddr := s.remoteAddr
+	s.remoteAddr = remote
+	s.remoteAddrMutex.Unlock()
+	if oldAddr.String() != remote.String() && s.config.RemoteAddressChanged != nil {
+		go s.config.RemoteAddressChanged(s, oldAddr, remote)
+	}
+	return nil
+}
+
 func (s *connection) handleNewTokenFrame(frame *wire.NewTokenFrame) error {
 	if s.perspective == protocol.PerspectiveServer {
 		return &qerr.TransportError{
@@ -1506,6 +2120,9 @@ func (s *connection) handleHandshakeDoneFrame() error {
 			ErrorMessage: "received a HANDSHAKE_DONE frame",
 		}
 	}
+	if s.tracer != nil {
+		s.tracer.ReceivedHandshakeDone()
+	}
 	if !s.handshakeConfirmed {
 		s.handleHandshakeConfirmed()
 	}
@@ -1517,6 +2134,9 @@ func (s *connection) handleAckFrame(frame *wire.AckFrame, encLevel protocol.Encr
 	if err != nil {
 		return err
 	}
+	if s.config.PathDegradingCallback != nil && s.config.PathDegradingRTTThreshold > 0 {
+		s.maybeNotifyPathDegradingRTT()
+	}
 	if !acked1RTTPacket {
 		return nil
 	}
@@ -1527,7 +2147,7 @@ func (s *connection) handleAckFrame(frame *wire.AckFrame, encLevel protocol.Encr
 }
 
 func (s *connection) handleDatagramFrame(f *wire.DatagramFrame) error {
-	if f.Length(s.version) > protocol.MaxDatagramFrameSize {
+	if f.Length(s.version) > protocol.ByteCount(s.config.MaxDatagramFrameSize) {
 		return &qerr.TransportError{
 			ErrorCode:    qerr.ProtocolViolation,
 			ErrorMessage: "DATAGRAM frame too large",
@@ -1573,6 +2193,12 @@ func (s *connection) closeRemote(e error) {
 	})
 }
 
+// stopIssuingConnectionIDs makes the connection stop issuing new connection IDs to the peer.
+// It is called by the server when it starts draining.
+func (s *connection) stopIssuingConnectionIDs() {
+	s.stopIssuingConnIDsOnce.Do(func() { close(s.stopIssuingConnIDsChan) })
+}
+
 // Close the connection. It sends a NO_ERROR application error.
 // It waits until the run loop has stopped before returning
 func (s *connection) shutdown() {
@@ -1589,7 +2215,27 @@ func (s *connection) CloseWithError(code ApplicationErrorCode, desc string) erro
 	return nil
 }
 
-func (s *connection) handleCloseError(closeErr *closeError) {
+func (s *connection) CloseGracefully(ctx context.Context, code ApplicationErrorCode, desc string) error {
+	s.gracefulCloseOnce.Do(func() {
+		s.gracefulCloseErr = &qerr.ApplicationError{
+			ErrorCode:    code,
+			ErrorMessage: desc,
+		}
+		s.closingGracefully.Set(true)
+		s.scheduleSending()
+	})
+	select {
+	case <-s.ctx.Done():
+		return nil
+	case <-ctx.Done():
+		s.CloseWithError(code, desc)
+		return ctx.Err()
+	}
+}
+
+// handleCloseError closes all streams and sends the CONNECTION_CLOSE, if necessary.
+// It returns the error that the streams were closed with.
+func (s *connection) handleCloseError(closeErr *closeError) error {
 	e := closeErr.err
 	if e == nil {
 		e = &qerr.ApplicationError{}
@@ -1634,23 +2280,24 @@ func (s *connection) handleCloseError(closeErr *closeError) {
 	// If this is a remote close we're done here
 	if closeErr.remote {
 		s.connIDGenerator.ReplaceWithClosed(s.perspective, nil)
-		return
+		return e
 	}
 	if closeErr.immediate {
 		s.connIDGenerator.RemoveAll()
-		return
+		return e
 	}
 	// Don't send out any CONNECTION_CLOSE if this is an error that occurred
 	// before we even sent out the first packet.
 	if s.perspective == protocol.PerspectiveClient && !s.sentFirstPacket {
 		s.connIDGenerator.RemoveAll()
-		return
+		return e
 	}
 	connClosePacket, err := s.sendConnectionClose(e)
 	if err != nil {
 		s.logger.Debugf("Error sending CONNECTION_CLOSE: %s", err)
 	}
 	s.connIDGenerator.ReplaceWithClosed(s.perspective, connClosePacket)
+	return e
 }
 
 func (s *connection) dropEncryptionLevel(encLevel protocol.EncryptionLevel) {
@@ -1677,6 +2324,8 @@ func (s *connection) restoreTransportParameters(params *wire.TransportParameters
 	}
 
 	s.peerParams = params
+	s.zeroRTTParams = params
+	s.peerSupportsResetStreamAt.Set(params.EnableResetStreamAt)
 	s.connIDGenerator.SetMaxActiveConnIDs(params.ActiveConnectionIDLimit)
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
 	s.streamsMap.UpdateLimits(params)
@@ -1737,9 +2386,19 @@ func (s *connection) applyTransportParameters() {
 	params := s.peerParams
 	// Our local idle timeout will always be > 0.
 	s.idleTimeout = utils.MinNonZeroDuration(s.config.MaxIdleTimeout, params.MaxIdleTimeout)
-	s.keepAliveInterval = utils.Min(s.config.KeepAlivePeriod, utils.Min(s.idleTimeout/2, protocol.MaxKeepAliveInterval))
+	if s.config.ExactKeepAlivePeriod {
+		s.keepAliveInterval = s.config.KeepAlivePeriod
+	} else {
+		s.keepAliveInterval = utils.Min(s.config.KeepAlivePeriod, utils.Min(s.idleTimeout/2, protocol.MaxKeepAliveInterval))
+	}
+	s.peerSupportsResetStreamAt.Set(params.EnableResetStreamAt)
+	// The ACK frequency extension is only used if both endpoints advertised the min_ack_delay.
+	s.ackFrequencyNegotiated.Set(s.config.EnableAckFrequency && params.MinAckDelay != nil)
 	s.streamsMap.UpdateLimits(params)
 	s.packer.HandleTransportParameters(params)
+	if params.MaxUDPPayloadSize != 0 && uint32(params.MaxUDPPayloadSize) < atomic.LoadUint32(&s.pathMTU) {
+		atomic.StoreUint32(&s.pathMTU, uint32(params.MaxUDPPayloadSize))
+	}
 	s.frameParser.SetAckDelayExponent(params.AckDelayExponent)
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
 	s.rttStats.SetMaxAckDelay(params.MaxAckDelay)
@@ -1747,15 +2406,149 @@ func (s *connection) applyTransportParameters() {
 	if params.StatelessResetToken != nil {
 		s.connIDManager.SetStatelessResetToken(*params.StatelessResetToken)
 	}
-	// We don't support connection migration yet, so we don't have any use for the preferred_address.
+	// The connection ID is only used on the path to the preferred address.
 	if params.PreferredAddress != nil {
-		// Retire the connection ID.
 		s.connIDManager.AddFromPreferredAddress(params.PreferredAddress.ConnectionID, params.PreferredAddress.StatelessResetToken)
 	}
 }
 
+// initialPacketSize is the size of the packets sent before Path MTU Discovery found a larger size.
+func (s *connection) initialPacketSize() protocol.ByteCount {
+	if s.config.InitialPacketSize != 0 {
+		return protocol.ByteCount(s.config.InitialPacketSize)
+	}
+	return getMaxPacketSize(s.conn.RemoteAddr())
+}
+
+// maxPacketSize is the upper bound for Path MTU Discovery.
+func (s *connection) maxPacketSize() protocol.ByteCount {
+	if s.config.MaxPacketSize != 0 {
+		return protocol.ByteCount(s.config.MaxPacketSize)
+	}
+	return protocol.MaxPacketBufferSize
+}
+
+func (s *connection) newPreferredAddress() (*wire.PreferredAddress, error) {
+	connID, token, err := s.connIDGenerator.IssuePreferredAddressConnID()
+	if err != nil {
+		return nil, err
+	}
+	pa := &wire.PreferredAddress{
+		ConnectionID:        connID,
+		StatelessResetToken: token,
+	}
+	if addr := s.config.PreferredAddressIPv4; addr != nil {
+		pa.IPv4 = addr.IP.To4()
+		pa.IPv4Port = uint16(addr.Port)
+	}
+	if addr := s.config.PreferredAddressIPv6; addr != nil {
+		pa.IPv6 = addr.IP.To16()
+		pa.IPv6Port = uint16(addr.Port)
+	}
+	return pa, nil
+}
+
+// startPreferredAddressValidation starts validating the path to the server's preferred address (see section 9.6 of RFC 9000).
+// Packets are sent to the original address until the path has been validated.
+func (s *connection) startPreferredAddressValidation() {
+	pa := s.peerParams.PreferredAddress
+	remoteAddr, ok := s.conn.RemoteAddr().(*net.UDPAddr)
+	if !ok {
+		return
+	}
+	if _, ok := s.connIDManager.PreferredAddressConnectionID(); !ok {
+		return
+	}
+	// Stay within the address family we used for the handshake.
+	var addr *net.UDPAddr
+	if remoteAddr.IP.To4() != nil {
+		if pa.IPv4Port != 0 && !pa.IPv4.IsUnspecified() {
+			addr = &net.UDPAddr{IP: pa.IPv4, Port: int(pa.IPv4Port)}
+		}
+	} else if pa.IPv6Port != 0 && !pa.IPv6.IsUnspecified() {
+		addr = &net.UDPAddr{IP: pa.IPv6, Port: int(pa.IPv6Port)}
+	}
+	if addr == nil {
+		return
+	}
+	if s.logger.Debug() {
+		s.logger.Debugf("Validating the path to the server's preferred address %s", addr)
+	}
+	s.preferredAddr = addr
+	s.preferredAddressProbePending = true
+	s.scheduleSending()
+}
+
+// sendPreferredAddressProbe sends a PATH_CHALLENGE to the server's preferred address.
+// The packet uses the connection ID from the preferred_address transport parameter,
+// and is padded to 1200 bytes (see section 8.2.1 of RFC 9000).
+func (s *connection) sendPreferredAddressProbe(now time.Time) error {
+	connID, ok := s.connIDManager.PreferredAddressConnectionID()
+	if !ok {
+		// The server retired the connection ID.
+		s.preferredAddr = nil
+		return nil
+	}
+	s.preferredAddressProbes++
+	challenge := ackhandler.Frame{
+		Frame:  s.pathManager.NewChallenge(s.conn.LocalAddr(), s.preferredAddr),
+		OnLost: s.onPreferredAddressProbeLost,
+	}
+	packet, err := s.packer.PackPathProbePacket(connID, challenge, protocol.MinInitialPacketSize)
+	if err != nil {
+		return err
+	}
+	s.logPacket(packet)
+	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(now, s.retransmissionQueue))
+	err = s.conn.WritePacketTo(packet.buffer.Data, s.preferredAddr)
+	packet.buffer.Release()
+	if err != nil {
+		s.logger.Debugf("Sending a PATH_CHALLENGE to the preferred address failed: %s", err)
+		s.preferredAddr = nil
+	}
+	return nil
+}
+
+func (s *connection) onPreferredAddressProbeLost(wire.Frame) {
+	if s.preferredAddr == nil {
+		return
+	}
+	if s.preferredAddressProbes >= protocol.MaxPreferredAddressProbes {
+		if s.logger.Debug() {
+			s.logger.Debugf("Path validation to the preferred address %s failed. Staying on the original path.", s.preferredAddr)
+		}
+		s.preferredAddr = nil
+		return
+	}
+	s.preferredAddressProbePending = true
+}
+
+// migrateToPreferredAddress is called once the path to the server's preferred address has been validated.
+// From now on, packets are sent to the preferred address, using the connection ID issued for it.
+func (s *connection) migrateToPreferredAddress() {
+	addr := s.preferredAddr
+	s.preferredAddr = nil
+	s.preferredAddressProbePending = false
+	if s.logger.Debug() {
+		s.logger.Debugf("Migrating to the server's preferred address %s", addr)
+	}
+	s.conn.SetRemoteAddr(addr)
+	s.connIDManager.UsePreferredAddressConnectionID()
+	// The path has already been validated, but the congestion state of the old path doesn't apply to the new path.
+	s.sentPacketHandler.MigratedPath(0, true)
+}
+
 func (s *connection) sendPackets() error {
 	s.pacingDeadline = time.Time{}
+	// Trace the queued events first, such that the tracer receives the Blocked events before the packets containing the frames.
+	s.traceQueuedEvents()
+
+	if s.preferredAddressProbePending {
+		s.preferredAddressProbePending = false
+		if err := s.sendPreferredAddressProbe(time.Now()); err != nil {
+			return err
+		}
+	}
 
 	var sentPacket bool // only used in for packets sent in send mode SendAny
 	for {
@@ -1834,6 +2627,7 @@ func (s *connection) maybeSendAckOnlyPacket() error {
 			s.sentPacketHandler.SentPacket(p.ToAckHandlerPacket(time.Now(), s.retransmissionQueue))
 		}
 		s.connIDManager.SentPacket()
+		s.traceSendTimestamp(packet.buffer, packet.packets...)
 		s.sendQueue.Send(packet.buffer)
 		return nil
 	}
@@ -1850,6 +2644,10 @@ func (s *connection) maybeSendAckOnlyPacket() error {
 }
 
 func (s *connection) sendProbePacket(encLevel protocol.EncryptionLevel) error {
+	// Ask the peer to acknowledge the probe packet right away, so that we can declare packets lost sooner.
+	if encLevel == protocol.Encryption1RTT && s.ackFrequencyNegotiated.Get() {
+		s.retransmissionQueue.AddAppData(&wire.ImmediateAckFrame{})
+	}
 	// Queue probe packets until we actually send out a packet,
 	// or until there are no more packets to queue.
 	var packet *packedPacket
@@ -1893,7 +2691,11 @@ func (s *connection) sendProbePacket(encLevel protocol.EncryptionLevel) error {
 
 func (s *connection) sendPacket() (bool, error) {
 	if isBlocked, offset := s.connFlowController.IsNewlyBlocked(); isBlocked {
-		s.framer.QueueControlFrame(&wire.DataBlockedFrame{MaximumData: offset})
+		f := &wire.DataBlockedFrame{MaximumData: offset}
+		s.framer.QueueControlFrame(f)
+		if s.tracer != nil {
+			s.tracer.Blocked(f, false)
+		}
 	}
 	s.windowUpdateQueue.QueueAll()
 
@@ -1903,7 +2705,10 @@ func (s *connection) sendPacket() (bool, error) {
 		if err != nil || packet == nil {
 			return false, err
 		}
-		s.sentFirstPacket = true
+		if !s.sentFirstPacket {
+			s.sentFirstPacket = true
+			s.recordHandshakeMilestone(&s.handshakeTimeline.FirstFlightSent, now)
+		}
 		s.logCoalescedPacket(packet)
 		for _, p := range packet.packets {
 			if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && p.IsAckEliciting() {
@@ -1912,6 +2717,7 @@ func (s *connection) sendPacket() (bool, error) {
 			s.sentPacketHandler.SentPacket(p.ToAckHandlerPacket(now, s.retransmissionQueue))
 		}
 		s.connIDManager.SentPacket()
+		s.traceSendTimestamp(packet.buffer, packet.packets...)
 		s.sendQueue.Send(packet.buffer)
 		return true, nil
 	}
@@ -1938,9 +2744,25 @@ func (s *connection) sendPackedPacket(packet *packedPacket, now time.Time) {
 	s.logPacket(packet)
 	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(now, s.retransmissionQueue))
 	s.connIDManager.SentPacket()
+	s.traceSendTimestamp(packet.buffer, packet.packetContents)
 	s.sendQueue.Send(packet.buffer)
 }
 
+// traceSendTimestamp makes the send queue report the time the packet was actually sent to the tracer.
+// This is only done if TX timestamps were enabled in the config.
+func (s *connection) traceSendTimestamp(buf *packetBuffer, packets ...*packetContents) {
+	if s.tracer == nil || !s.config.EnableTxTimestamps {
+		return
+	}
+	buf.onSent = func(t time.Time) {
+		s.queueTraceEvent(func() {
+			for _, p := range packets {
+				s.tracer.SentPacketTimestamp(p.EncryptionLevel(), p.header.PacketNumber, t)
+			}
+		})
+	}
+}
+
 func (s *connection) sendConnectionClose(e error) ([]byte, error) {
 	var packet *coalescedPacket
 	var err error
@@ -2021,18 +2843,30 @@ func (s *connection) AcceptUniStream(ctx context.Context) (ReceiveStream, error)
 
 // OpenStream opens a stream
 func (s *connection) OpenStream() (Stream, error) {
+	if s.closingGracefully.Get() {
+		return nil, errClosingGracefully
+	}
 	return s.streamsMap.OpenStream()
 }
 
 func (s *connection) OpenStreamSync(ctx context.Context) (Stream, error) {
+	if s.closingGracefully.Get() {
+		return nil, errClosingGracefully
+	}
 	return s.streamsMap.OpenStreamSync(ctx)
 }
 
 func (s *connection) OpenUniStream() (SendStream, error) {
+	if s.closingGracefully.Get() {
+		return nil, errClosingGracefully
+	}
 	return s.streamsMap.OpenUniStream()
 }
 
 func (s *connection) OpenUniStreamSync(ctx context.Context) (SendStream, error) {
+	if s.closingGracefully.Get() {
+		return nil, errClosingGracefully
+	}
 	return s.streamsMap.OpenUniStreamSync(ctx)
 }
 
@@ -2072,6 +2906,7 @@ func (s *connection) tryQueueingUndecryptablePacket(p *receivedPacket, pt loggin
 		panic("shouldn't queue undecryptable packets after handshake completion")
 	}
 	if len(s.undecryptablePackets)+1 > protocol.MaxUndecryptablePackets {
+		s.updateUndecryptablePacketStats(func(stats *UndecryptablePacketStats) { stats.Dropped++ })
 		if s.tracer != nil {
 			s.tracer.DroppedPacket(pt, p.Size(), logging.PacketDropDOSPrevention)
 		}
@@ -2082,19 +2917,81 @@ func (s *connection) tryQueueingUndecryptablePacket(p *receivedPacket, pt loggin
 	if s.tracer != nil {
 		s.tracer.BufferedPacket(pt)
 	}
+	if !p.wasQueued {
+		// packets might be queued multiple times, if the keys are still not available when they're processed again
+		p.wasQueued = true
+		s.updateUndecryptablePacketStats(func(stats *UndecryptablePacketStats) { stats.Buffered++ })
+	}
 	s.undecryptablePackets = append(s.undecryptablePackets, p)
 }
 
+func (s *connection) supportsResetStreamAt() bool {
+	return s.peerSupportsResetStreamAt.Get()
+}
+
 func (s *connection) queueControlFrame(f wire.Frame) {
 	s.framer.QueueControlFrame(f)
+	switch f.(type) {
+	case *wire.StreamDataBlockedFrame, *wire.StreamsBlockedFrame:
+		if s.tracer != nil {
+			s.queueTraceEvent(func() { s.tracer.Blocked(f, false) })
+		}
+	}
 	s.scheduleSending()
 }
 
+// queueTraceEvent queues a tracing event that occurred on a different goroutine.
+// The event is passed to the tracer on the run loop, such that the tracer is never called concurrently.
+// Events queued after the run loop terminated are dropped.
+func (s *connection) queueTraceEvent(f func()) {
+	s.traceQueueMutex.Lock()
+	if s.traceQueueClosed {
+		s.traceQueueMutex.Unlock()
+		return
+	}
+	s.traceQueue = append(s.traceQueue, f)
+	s.traceQueueMutex.Unlock()
+	select {
+	case s.traceQueueChan <- struct{}{}:
+	default:
+	}
+}
+
+// traceQueuedEvents passes the queued tracing events to the tracer.
+// It must only be called from the run loop.
+func (s *connection) traceQueuedEvents() {
+	s.traceQueueMutex.Lock()
+	queue := s.traceQueue
+	s.traceQueue = nil
+	s.traceQueueMutex.Unlock()
+	for _, f := range queue {
+		f()
+	}
+}
+
 func (s *connection) onHasStreamWindowUpdate(id protocol.StreamID) {
 	s.windowUpdateQueue.AddStream(id)
 	s.scheduleSending()
 }
 
+// maybeNotifyPathDegrading calls the PathDegradingCallback when a PTO made the PTO count reach the threshold.
+// The loss detection timer also fires for time-threshold losses, which don't change the PTO count.
+func (s *connection) maybeNotifyPathDegrading(previousPTOCount uint32) {
+	if ptoCount := s.sentPacketHandler.PTOCount(); ptoCount != previousPTOCount && ptoCount == s.config.PathDegradingThreshold {
+		s.config.PathDegradingCallback(s)
+	}
+}
+
+// maybeNotifyPathDegradingRTT calls the PathDegradingCallback when the smoothed RTT exceeds the PathDegradingRTTThreshold.
+// It is called again once the smoothed RTT dropped below the threshold and then exceeds it again.
+func (s *connection) maybeNotifyPathDegradingRTT() {
+	above := s.rttStats.SmoothedRTT() > s.config.PathDegradingRTTThreshold
+	if above && !s.rttAbovePathDegradingThreshold {
+		s.config.PathDegradingCallback(s)
+	}
+	s.rttAbovePathDegradingThreshold = above
+}
+
 func (s *connection) onHasConnectionWindowUpdate() {
 	s.windowUpdateQueue.AddConnection()
 	s.scheduleSending()
@@ -2111,10 +3008,19 @@ func (s *connection) onStreamCompleted(id protocol.StreamID) {
 	}
 }
 
+func (s *connection) SetMaxSendRate(bytesPerSecond uint64) {
+	s.sentPacketHandler.SetMaxSendRate(bytesPerSecond)
+	// the pacing deadline might have changed
+	s.scheduleSending()
+}
+
 func (s *connection) SendMessage(p []byte) error {
 	if !s.supportsDatagrams() {
 		return errors.New("datagram support disabled")
 	}
+	if s.closingGracefully.Get() {
+		return errClosingGracefully
+	}
 
 	f := &wire.DatagramFrame{DataLenPresent: true}
 	if protocol.ByteCount(len(p)) > f.MaxDataLen(s.peerParams.MaxDatagramFrameSize, s.version) {
@@ -2137,7 +3043,23 @@ func (s *connection) LocalAddr() net.Addr {
 }
 
 func (s *connection) RemoteAddr() net.Addr {
-	return s.conn.RemoteAddr()
+	s.remoteAddrMutex.Lock()
+	defer s.remoteAddrMutex.Unlock()
+	return s.remoteAddr
+}
+
+func (s *connection) LocalConnectionID() ConnectionID {
+	return s.handshakeSrcConnID
+}
+
+func (s *connection) RemoteConnectionID() ConnectionID {
+	// The handshakeDestConnID is only changed by the run loop before the handshake completes.
+	select {
+	case <-s.handshakeCtx.Done():
+		return s.handshakeDestConnID
+	case <-s.ctx.Done():
+		return protocol.ConnectionID{}
+	}
 }
 
 func (s *connection) getPerspective() protocol.Perspective {
@@ -2153,3 +3075,7 @@ func (s *connection) NextConnection() Connection {
 	s.streamsMap.UseResetMaps()
 	return s
 }
+
+func (s *connection) ZeroRTTTransportParameters() *wire.TransportParameters {
+	return s.zeroRTTParams
+}
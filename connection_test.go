@@ -28,6 +28,7 @@ import (
 	"github.com/golang/mock/gomock"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -164,6 +165,21 @@ var _ = Describe("Connection", func() {
 				err := conn.handleAckFrame(f, protocol.EncryptionHandshake)
 				Expect(err).ToNot(HaveOccurred())
 			})
+
+			It("closes with the frame type when the peer acknowledges an unsent packet", func() {
+				f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedAck(f, protocol.Encryption1RTT, gomock.Any()).Return(false, qerr.NewOptimisticAckError(protocol.Encryption1RTT, 3, false))
+				conn.sentPacketHandler = sph
+				err := conn.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})
+				var ackErr *OptimisticAckError
+				Expect(errors.As(err, &ackErr)).To(BeTrue())
+				Expect(ackErr.PacketNumber).To(Equal(protocol.PacketNumber(3)))
+				var transportErr *TransportError
+				Expect(errors.As(err, &transportErr)).To(BeTrue())
+				Expect(transportErr.ErrorCode).To(Equal(qerr.ProtocolViolation))
+				Expect(transportErr.FrameType).To(BeEquivalentTo(0x2))
+			})
 		})
 
 		Context("handling RESET_STREAM frames", func() {
@@ -193,6 +209,21 @@ var _ = Describe("Connection", func() {
 				Expect(err).To(MatchError(testErr))
 			})
 
+			It("sets the frame type on transport errors", func() {
+				f := &wire.ResetStreamFrame{
+					StreamID:  7,
+					FinalSize: 0x1337,
+				}
+				str := NewMockReceiveStreamI(mockCtrl)
+				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(7)).Return(str, nil)
+				str.EXPECT().handleResetStreamFrame(f).Return(&qerr.TransportError{ErrorCode: qerr.FinalSizeError})
+				err := conn.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})
+				Expect(err).To(MatchError(&qerr.TransportError{
+					ErrorCode: qerr.FinalSizeError,
+					FrameType: 0x4,
+				}))
+			})
+
 			It("ignores RESET_STREAM frames for closed streams", func() {
 				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(3)).Return(nil, nil)
 				Expect(conn.handleFrame(&wire.ResetStreamFrame{
@@ -202,6 +233,35 @@ var _ = Describe("Connection", func() {
 			})
 		})
 
+		Context("handling RESET_STREAM_AT frames", func() {
+			It("passes the frame to the stream", func() {
+				f := &wire.ResetStreamAtFrame{
+					StreamID:     555,
+					ErrorCode:    42,
+					FinalSize:    0x1337,
+					ReliableSize: 0x42,
+				}
+				str := NewMockReceiveStreamI(mockCtrl)
+				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(555)).Return(str, nil)
+				str.EXPECT().handleResetStreamAtFrame(f)
+				Expect(conn.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			})
+
+			It("ignores RESET_STREAM_AT frames for closed streams", func() {
+				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(3)).Return(nil, nil)
+				Expect(conn.handleFrame(&wire.ResetStreamAtFrame{
+					StreamID:  3,
+					ErrorCode: 42,
+				}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			})
+
+			It("says if the peer supports RESET_STREAM_AT", func() {
+				Expect(conn.supportsResetStreamAt()).To(BeFalse())
+				conn.peerSupportsResetStreamAt.Set(true)
+				Expect(conn.supportsResetStreamAt()).To(BeTrue())
+			})
+		})
+
 		Context("handling MAX_DATA and MAX_STREAM_DATA frames", func() {
 			var connFC *mocks.MockConnectionFlowController
 
@@ -271,6 +331,7 @@ var _ = Describe("Connection", func() {
 
 		It("handles NEW_CONNECTION_ID frames", func() {
 			connID := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
+			connRunner.EXPECT().AddResetToken(protocol.StatelessResetToken{}, conn)
 			Expect(conn.handleFrame(&wire.NewConnectionIDFrame{
 				SequenceNumber: 10,
 				ConnectionID:   connID,
@@ -278,14 +339,95 @@ var _ = Describe("Connection", func() {
 			Expect(conn.connIDManager.queue.Back().Value.ConnectionID).To(Equal(connID))
 		})
 
+		It("handles ACK_FREQUENCY frames", func() {
+			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+			conn.receivedPacketHandler = rph
+			f := &wire.AckFrequencyFrame{AckElicitingThreshold: 10, RequestMaxAckDelay: 50 * time.Millisecond}
+			rph.EXPECT().HandleAckFrequencyFrame(f)
+			Expect(conn.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+		})
+
+		It("rejects ACK_FREQUENCY frames requesting a max_ack_delay smaller than the min_ack_delay", func() {
+			f := &wire.AckFrequencyFrame{RequestMaxAckDelay: protocol.MinAckDelay - 1}
+			err := conn.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})
+			Expect(err).To(BeAssignableToTypeOf(&qerr.TransportError{}))
+			Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.ProtocolViolation))
+		})
+
+		It("uses the configured min_ack_delay when handling ACK_FREQUENCY frames", func() {
+			conn.config.MinAckDelay = 10 * time.Millisecond
+			f := &wire.AckFrequencyFrame{RequestMaxAckDelay: 9 * time.Millisecond}
+			err := conn.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})
+			Expect(err).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.ProtocolViolation,
+				FrameType:    0xaf,
+				ErrorMessage: "requested max_ack_delay (9ms) smaller than min_ack_delay (10ms)",
+			}))
+		})
+
+		Context("extension frames", func() {
+			It("passes extension frames to the application", func() {
+				var received []byte
+				conn.config.HandleExtensionFrame = func(c Connection, frameType uint64, frame []byte) error {
+					Expect(c).To(Equal(conn))
+					Expect(frameType).To(BeEquivalentTo(0x2f5a8b))
+					received = append([]byte{}, frame...)
+					return nil
+				}
+				f := &wire.ExtensionFrame{FrameType: 0x2f5a8b, Data: []byte("foobar")}
+				Expect(conn.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				Expect(received).To(Equal([]byte("foobar")))
+			})
+
+			It("ignores extension frames if no handler is set", func() {
+				conn.config.HandleExtensionFrame = nil
+				f := &wire.ExtensionFrame{FrameType: 0x2f5a8b, Data: []byte("foobar")}
+				Expect(conn.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			})
+
+			It("closes the connection if the application rejects an extension frame", func() {
+				conn.config.HandleExtensionFrame = func(Connection, uint64, []byte) error {
+					return errors.New("malformed frame")
+				}
+				f := &wire.ExtensionFrame{FrameType: 0x2f5a8b, Data: []byte("foobar")}
+				err := conn.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})
+				Expect(err).To(MatchError(&qerr.TransportError{
+					ErrorCode:    qerr.ProtocolViolation,
+					FrameType:    0x2f5a8b,
+					ErrorMessage: "malformed frame",
+				}))
+			})
+		})
+
 		It("handles PING frames", func() {
 			err := conn.handleFrame(&wire.PingFrame{}, protocol.Encryption1RTT, protocol.ConnectionID{})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("rejects PATH_RESPONSE frames", func() {
+		It("handles IMMEDIATE_ACK frames", func() {
+			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+			rph.EXPECT().QueueAck()
+			conn.receivedPacketHandler = rph
+			err := conn.handleFrame(&wire.ImmediateAckFrame{}, protocol.Encryption1RTT, protocol.ConnectionID{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("ignores PATH_RESPONSE frames that don't match a PATH_CHALLENGE", func() {
 			err := conn.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.Encryption1RTT, protocol.ConnectionID{})
-			Expect(err).To(MatchError("unexpected PATH_RESPONSE frame"))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("ignores duplicate PATH_RESPONSE frames", func() {
+			f := conn.pathManager.NewChallenge(conn.LocalAddr(), conn.RemoteAddr())
+			Expect(conn.handleFrame(&wire.PathResponseFrame{Data: f.Data}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Expect(conn.handleFrame(&wire.PathResponseFrame{Data: f.Data}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+		})
+
+		It("handles PATH_RESPONSE frames", func() {
+			f := conn.pathManager.NewChallenge(conn.LocalAddr(), conn.RemoteAddr())
+			err := conn.handleFrame(&wire.PathResponseFrame{Data: f.Data}, protocol.Encryption1RTT, protocol.ConnectionID{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conn.pathManager.HasOutstandingChallenge(conn.LocalAddr(), conn.RemoteAddr())).To(BeFalse())
 		})
 
 		It("handles PATH_CHALLENGE frames", func() {
@@ -304,24 +446,41 @@ var _ = Describe("Connection", func() {
 		})
 
 		It("handles BLOCKED frames", func() {
-			err := conn.handleFrame(&wire.DataBlockedFrame{}, protocol.Encryption1RTT, protocol.ConnectionID{})
+			f := &wire.DataBlockedFrame{MaximumData: 1337}
+			tracer.EXPECT().Blocked(f, true)
+			err := conn.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("handles STREAM_BLOCKED frames", func() {
-			err := conn.handleFrame(&wire.StreamDataBlockedFrame{}, protocol.Encryption1RTT, protocol.ConnectionID{})
+			f := &wire.StreamDataBlockedFrame{StreamID: 4, MaximumStreamData: 1337}
+			tracer.EXPECT().Blocked(f, true)
+			err := conn.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		It("handles STREAMS_BLOCKED frames", func() {
-			err := conn.handleFrame(&wire.StreamsBlockedFrame{}, protocol.Encryption1RTT, protocol.ConnectionID{})
+			f := &wire.StreamsBlockedFrame{Type: protocol.StreamTypeBidi, StreamLimit: 10}
+			tracer.EXPECT().Blocked(f, true)
+			err := conn.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("traces when a stream is blocked by flow control", func() {
+			f := &wire.StreamDataBlockedFrame{StreamID: 4, MaximumStreamData: 1337}
+			conn.queueControlFrame(f)
+			frames, _ := conn.framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(Equal([]ackhandler.Frame{{Frame: f}}))
+			// the event is traced on the run loop
+			tracer.EXPECT().Blocked(f, false)
+			conn.traceQueuedEvents()
+		})
+
 		It("handles CONNECTION_CLOSE frames, with a transport error code", func() {
 			expectedErr := &qerr.TransportError{
 				Remote:       true,
 				ErrorCode:    qerr.StreamLimitError,
+				FrameType:    0x13,
 				ErrorMessage: "foobar",
 			}
 			streamManager.EXPECT().CloseWithError(expectedErr)
@@ -341,6 +500,7 @@ var _ = Describe("Connection", func() {
 			}()
 			Expect(conn.handleFrame(&wire.ConnectionCloseFrame{
 				ErrorCode:    uint64(qerr.StreamLimitError),
+				FrameType:    0x13,
 				ReasonPhrase: "foobar",
 			}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
 			Eventually(conn.Context().Done()).Should(BeClosed())
@@ -374,6 +534,7 @@ var _ = Describe("Connection", func() {
 			}
 			Expect(conn.handleFrame(ccf, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
 			Eventually(conn.Context().Done()).Should(BeClosed())
+			Expect(ContextCause(conn.Context())).To(Equal(testErr))
 		})
 
 		It("errors on HANDSHAKE_DONE frames", func() {
@@ -445,6 +606,23 @@ var _ = Describe("Connection", func() {
 			Expect(conn.Context().Done()).To(BeClosed())
 		})
 
+		It("closes the packet conns it migrated to", func() {
+			manager := NewMockPacketHandlerManager(mockCtrl)
+			conn.migrationManagers = []packetHandlerManager{manager}
+			runConn()
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			mconn.EXPECT().Write(gomock.Any())
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			destroyed := make(chan struct{})
+			manager.EXPECT().Destroy().Do(func() { close(destroyed) })
+			conn.shutdown()
+			Eventually(destroyed).Should(BeClosed())
+		})
+
 		It("only closes once", func() {
 			runConn()
 			streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -478,6 +656,7 @@ var _ = Describe("Connection", func() {
 			conn.CloseWithError(0x1337, "test error")
 			Eventually(areConnsRunning).Should(BeFalse())
 			Expect(conn.Context().Done()).To(BeClosed())
+			Expect(ContextCause(conn.Context())).To(Equal(expectedErr))
 		})
 
 		It("includes the frame type in transport-level close frames", func() {
@@ -525,6 +704,60 @@ var _ = Describe("Connection", func() {
 			}
 		})
 
+		DescribeTable("closing streams with typed errors",
+			func(closeErr error, check func(error)) {
+				runConn()
+				streamManager.EXPECT().CloseWithError(gomock.Any()).Do(func(e error) {
+					defer GinkgoRecover()
+					check(e)
+				})
+				connRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
+				cryptoSetup.EXPECT().Close()
+				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(e error) {
+					defer GinkgoRecover()
+					check(e)
+				})
+				tracer.EXPECT().Close()
+				conn.destroy(closeErr)
+				Eventually(areConnsRunning).Should(BeFalse())
+			},
+			Entry("idle timeouts", qerr.ErrIdleTimeout, func(e error) {
+				var idleTimeoutErr *IdleTimeoutError
+				Expect(errors.As(e, &idleTimeoutErr)).To(BeTrue())
+			}),
+			Entry("handshake timeouts", qerr.ErrHandshakeTimeout, func(e error) {
+				var handshakeTimeoutErr *HandshakeTimeoutError
+				Expect(errors.As(e, &handshakeTimeoutErr)).To(BeTrue())
+			}),
+			Entry("stateless resets", &StatelessResetError{Token: protocol.StatelessResetToken{1, 2, 3}}, func(e error) {
+				var statelessResetErr *StatelessResetError
+				Expect(errors.As(e, &statelessResetErr)).To(BeTrue())
+				Expect(statelessResetErr.Token).To(Equal(protocol.StatelessResetToken{1, 2, 3}))
+			}),
+			Entry("failed version negotiation", &VersionNegotiationError{Theirs: []protocol.VersionNumber{1337}}, func(e error) {
+				var vnErr *VersionNegotiationError
+				Expect(errors.As(e, &vnErr)).To(BeTrue())
+				Expect(vnErr.Theirs).To(Equal([]protocol.VersionNumber{1337}))
+			}),
+			Entry("application errors", &ApplicationError{Remote: true, ErrorCode: 0x42}, func(e error) {
+				var appErr *ApplicationError
+				Expect(errors.As(e, &appErr)).To(BeTrue())
+				Expect(appErr.Remote).To(BeTrue())
+				Expect(appErr.ErrorCode).To(BeEquivalentTo(0x42))
+			}),
+			Entry("transport errors", &TransportError{ErrorCode: qerr.FlowControlError}, func(e error) {
+				var transportErr *TransportError
+				Expect(errors.As(e, &transportErr)).To(BeTrue())
+				Expect(transportErr.ErrorCode).To(Equal(qerr.FlowControlError))
+			}),
+			Entry("other errors", errors.New("foobar"), func(e error) {
+				var transportErr *TransportError
+				Expect(errors.As(e, &transportErr)).To(BeTrue())
+				Expect(transportErr.ErrorCode).To(Equal(qerr.InternalError))
+				Expect(transportErr.ErrorMessage).To(Equal("foobar"))
+			}),
+		)
+
 		It("cancels the context when the run loop exists", func() {
 			runConn()
 			streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -626,6 +859,91 @@ var _ = Describe("Connection", func() {
 			cryptoSetup.EXPECT().Close()
 			conn.destroy(&StatelessResetError{Token: token})
 		})
+
+		Context("closing gracefully", func() {
+			var sph *mockackhandler.MockSentPacketHandler
+			expectedErr := &qerr.ApplicationError{
+				ErrorCode:    0x1337,
+				ErrorMessage: "graceful close",
+			}
+
+			BeforeEach(func() {
+				sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				conn.sentPacketHandler = sph
+				sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+				sph.EXPECT().SendMode().Return(ackhandler.SendNone).AnyTimes()
+				streamManager.EXPECT().CloseWithError(expectedErr)
+				expectReplaceWithClosed()
+				cryptoSetup.EXPECT().Close()
+				packer.EXPECT().PackApplicationClose(expectedErr).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+				mconn.EXPECT().Write(gomock.Any())
+				gomock.InOrder(
+					tracer.EXPECT().ClosedConnection(expectedErr),
+					tracer.EXPECT().Close(),
+				)
+			})
+
+			It("closes once all packets were acknowledged", func() {
+				checked := make(chan struct{}, 1)
+				gomock.InOrder(
+					sph.EXPECT().HasOutstandingPackets().DoAndReturn(func() bool {
+						checked <- struct{}{}
+						return true
+					}),
+					sph.EXPECT().HasOutstandingPackets().Return(false),
+				)
+				runConn()
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					Expect(conn.CloseGracefully(context.Background(), 0x1337, "graceful close")).To(Succeed())
+					close(done)
+				}()
+				Eventually(checked).Should(Receive())
+				Consistently(done).ShouldNot(BeClosed())
+				// an ACK was received
+				conn.scheduleSending()
+				Eventually(done).Should(BeClosed())
+				Expect(conn.Context().Done()).To(BeClosed())
+			})
+
+			It("closes immediately when the context is canceled", func() {
+				sph.EXPECT().HasOutstandingPackets().Return(true).AnyTimes()
+				runConn()
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					Expect(conn.CloseGracefully(ctx, 0x1337, "graceful close")).To(MatchError(context.Canceled))
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				cancel()
+				Eventually(done).Should(BeClosed())
+				Expect(conn.Context().Done()).To(BeClosed())
+			})
+		})
+	})
+
+	It("sets the maximum send rate", func() {
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		conn.sentPacketHandler = sph
+		sph.EXPECT().SetMaxSendRate(uint64(1250000))
+		conn.SetMaxSendRate(1250000)
+	})
+
+	It("refuses to open new streams and send datagrams while closing gracefully", func() {
+		conn.peerParams = &wire.TransportParameters{MaxDatagramFrameSize: protocol.MaxDatagramFrameSize}
+		conn.closingGracefully.Set(true)
+		_, err := conn.OpenStream()
+		Expect(err).To(MatchError(errClosingGracefully))
+		_, err = conn.OpenStreamSync(context.Background())
+		Expect(err).To(MatchError(errClosingGracefully))
+		_, err = conn.OpenUniStream()
+		Expect(err).To(MatchError(errClosingGracefully))
+		_, err = conn.OpenUniStreamSync(context.Background())
+		Expect(err).To(MatchError(errClosingGracefully))
+		Expect(conn.SendMessage([]byte("foobar"))).To(MatchError(errClosingGracefully))
 	})
 
 	Context("receiving packets", func() {
@@ -1048,6 +1366,74 @@ var _ = Describe("Connection", func() {
 			Expect(conn.undecryptablePackets).To(Equal([]*receivedPacket{packet}))
 		})
 
+		It("counts undecryptable packets that are decrypted later", func() {
+			conn.handshakeComplete = false
+			hdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeHandshake,
+					DestConnectionID: destConnID,
+					SrcConnectionID:  srcConnID,
+					Length:           1,
+					Version:          conn.version,
+				},
+				PacketNumberLen: protocol.PacketNumberLen1,
+				PacketNumber:    1,
+			}
+			packet := getPacket(hdr, nil)
+			gomock.InOrder(
+				unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrKeysNotYetAvailable),
+				unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrKeysNotYetAvailable),
+				unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+					encryptionLevel: protocol.EncryptionHandshake,
+					data:            []byte{0}, // one PADDING frame
+					hdr:             hdr,
+				}, nil),
+			)
+			tracer.EXPECT().BufferedPacket(logging.PacketTypeHandshake).Times(2)
+			Expect(conn.handlePacketImpl(packet)).To(BeFalse())
+			Expect(conn.undecryptablePackets).To(HaveLen(1))
+			// try to decrypt the packet again, but the keys are still not available
+			conn.undecryptablePackets = nil
+			Expect(conn.handlePacketImpl(packet)).To(BeFalse())
+			Expect(conn.undecryptablePackets).To(HaveLen(1))
+			conn.undecryptablePackets = nil
+			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedLongHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any())
+			Expect(conn.handlePacketImpl(packet)).To(BeTrue())
+			cryptoSetup.EXPECT().ConnectionState()
+			mconn.EXPECT().UsesPacketInfo()
+			conn.peerParams = &wire.TransportParameters{}
+			Expect(conn.ConnectionState().UndecryptablePackets).To(Equal(UndecryptablePacketStats{Buffered: 1, Decrypted: 1}))
+		})
+
+		It("counts undecryptable packets that are dropped because the queue is full", func() {
+			conn.handshakeComplete = false
+			for i := 0; i < protocol.MaxUndecryptablePackets; i++ {
+				conn.undecryptablePackets = append(conn.undecryptablePackets, &receivedPacket{})
+			}
+			hdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeHandshake,
+					DestConnectionID: destConnID,
+					SrcConnectionID:  srcConnID,
+					Length:           1,
+					Version:          conn.version,
+				},
+				PacketNumberLen: protocol.PacketNumberLen1,
+				PacketNumber:    1,
+			}
+			unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrKeysNotYetAvailable)
+			packet := getPacket(hdr, nil)
+			tracer.EXPECT().DroppedPacket(logging.PacketTypeHandshake, packet.Size(), logging.PacketDropDOSPrevention)
+			Expect(conn.handlePacketImpl(packet)).To(BeFalse())
+			cryptoSetup.EXPECT().ConnectionState()
+			mconn.EXPECT().UsesPacketInfo()
+			conn.peerParams = &wire.TransportParameters{}
+			Expect(conn.ConnectionState().UndecryptablePackets).To(Equal(UndecryptablePacketStats{Dropped: 1}))
+		})
+
 		Context("updating the remote address", func() {
 			It("doesn't support connection migration", func() {
 				unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).Return(protocol.PacketNumber(10), protocol.PacketNumberLen2, protocol.KeyPhaseZero, []byte{0} /* one PADDING frame */, nil)
@@ -1281,6 +1667,7 @@ var _ = Describe("Connection", func() {
 			sent := make(chan struct{})
 			sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { close(sent) })
 			tracer.EXPECT().SentPacket(p.header, p.length, nil, []logging.Frame{})
+			tracer.EXPECT().Blocked(&logging.DataBlockedFrame{MaximumData: 1337}, false)
 			conn.scheduleSending()
 			Eventually(sent).Should(BeClosed())
 			frames, _ := conn.framer.AppendControlFrames(nil, 1000)
@@ -1364,6 +1751,29 @@ var _ = Describe("Connection", func() {
 					// We therefore need to test separately that the PING was actually queued.
 					Expect(getFrame(1000)).To(BeAssignableToTypeOf(&wire.PingFrame{}))
 				})
+
+				if encLevel == protocol.Encryption1RTT {
+					It("requests an immediate acknowledgement, if the peer supports it", func() {
+						conn.ackFrequencyNegotiated.Set(true)
+						sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+						sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+						sph.EXPECT().TimeUntilSend().AnyTimes()
+						sph.EXPECT().SendMode().Return(sendMode)
+						sph.EXPECT().SendMode().Return(ackhandler.SendNone)
+						sph.EXPECT().QueueProbePacket(encLevel)
+						p := getPacket(123)
+						packer.EXPECT().MaybePackProbePacket(encLevel).Return(p, nil)
+						sph.EXPECT().SentPacket(gomock.Any())
+						conn.sentPacketHandler = sph
+						runConn()
+						sent := make(chan struct{})
+						sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { close(sent) })
+						tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any())
+						conn.scheduleSending()
+						Eventually(sent).Should(BeClosed())
+						Expect(getFrame(1000)).To(BeAssignableToTypeOf(&wire.ImmediateAckFrame{}))
+					})
+				}
 			})
 		}
 	})
@@ -1728,6 +2138,41 @@ var _ = Describe("Connection", func() {
 		})
 	})
 
+	It("doesn't request send timestamps by default", func() {
+		buf := getPacketBuffer()
+		conn.traceSendTimestamp(buf, &packetContents{header: &wire.ExtendedHeader{PacketNumber: 42}})
+		Expect(buf.onSent).To(BeNil())
+	})
+
+	It("reports send timestamps to the tracer, if enabled", func() {
+		conn.config.EnableTxTimestamps = true
+		buf := getPacketBuffer()
+		conn.traceSendTimestamp(buf,
+			&packetContents{header: &wire.ExtendedHeader{Header: wire.Header{IsLongHeader: true, Type: protocol.PacketTypeHandshake}, PacketNumber: 41}},
+			&packetContents{header: &wire.ExtendedHeader{PacketNumber: 42}},
+		)
+		Expect(buf.onSent).ToNot(BeNil())
+		sendTime := time.Now().Add(-time.Millisecond)
+		gomock.InOrder(
+			tracer.EXPECT().SentPacketTimestamp(protocol.EncryptionHandshake, protocol.PacketNumber(41), sendTime),
+			tracer.EXPECT().SentPacketTimestamp(protocol.Encryption1RTT, protocol.PacketNumber(42), sendTime),
+		)
+		buf.onSent(sendTime)
+		// the timestamps are passed to the tracer on the run loop
+		Expect(conn.traceQueueChan).To(Receive())
+		conn.traceQueuedEvents()
+	})
+
+	It("drops send timestamps reported after the run loop terminated", func() {
+		conn.config.EnableTxTimestamps = true
+		buf := getPacketBuffer()
+		conn.traceSendTimestamp(buf, &packetContents{header: &wire.ExtendedHeader{PacketNumber: 42}})
+		conn.traceQueueClosed = true
+		buf.onSent(time.Now())
+		Expect(conn.traceQueueChan).ToNot(Receive())
+		conn.traceQueuedEvents() // no call to SentPacketTimestamp EXPECTed
+	})
+
 	It("sends coalesced packets before the handshake is confirmed", func() {
 		conn.handshakeComplete = false
 		conn.handshakeConfirmed = false
@@ -1797,6 +2242,13 @@ var _ = Describe("Connection", func() {
 
 		conn.scheduleSending()
 		Eventually(sent).Should(BeClosed())
+		conn.peerParams = &wire.TransportParameters{}
+		cryptoSetup.EXPECT().ConnectionState()
+		mconn.EXPECT().UsesPacketInfo()
+		sph.EXPECT().ECNCongestionSignals()
+		timeline := conn.ConnectionState().Handshake
+		Expect(timeline.FirstFlightSent).To(BeTemporally(">=", timeline.Start))
+		Expect(timeline.HandshakeComplete).To(BeZero())
 
 		// make sure the go routine returns
 		streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -1826,6 +2278,8 @@ var _ = Describe("Connection", func() {
 			cryptoSetup.EXPECT().RunHandshake()
 			cryptoSetup.EXPECT().SetHandshakeConfirmed()
 			cryptoSetup.EXPECT().GetSessionTicket()
+			cryptoSetup.EXPECT().ConnectionState()
+			tracer.EXPECT().CompletedHandshake(false, false)
 			close(conn.handshakeCompleteChan)
 			conn.run()
 		}()
@@ -1833,6 +2287,16 @@ var _ = Describe("Connection", func() {
 		Consistently(handshakeCtx.Done()).ShouldNot(BeClosed())
 		close(finishHandshake)
 		Eventually(handshakeCtx.Done()).Should(BeClosed())
+		conn.peerParams = &wire.TransportParameters{}
+		cryptoSetup.EXPECT().ConnectionState()
+		mconn.EXPECT().UsesPacketInfo()
+		sph.EXPECT().ECNCongestionSignals()
+		timeline := conn.ConnectionState().Handshake
+		Expect(timeline.Start).ToNot(BeZero())
+		Expect(timeline.HandshakeComplete).To(BeTemporally(">", timeline.Start))
+		// the server confirms the handshake as soon as it completes
+		Expect(timeline.HandshakeConfirmed).To(BeTemporally("~", timeline.HandshakeComplete, time.Millisecond))
+		Expect(timeline.HandshakeConfirmed).ToNot(BeZero())
 		// make sure the go routine returns
 		streamManager.EXPECT().CloseWithError(gomock.Any())
 		expectReplaceWithClosed()
@@ -1856,6 +2320,8 @@ var _ = Describe("Connection", func() {
 			cryptoSetup.EXPECT().RunHandshake()
 			cryptoSetup.EXPECT().SetHandshakeConfirmed()
 			cryptoSetup.EXPECT().GetSessionTicket().Return(make([]byte, size), nil)
+			cryptoSetup.EXPECT().ConnectionState()
+			tracer.EXPECT().CompletedHandshake(false, false)
 			close(conn.handshakeCompleteChan)
 			conn.run()
 		}()
@@ -1943,6 +2409,8 @@ var _ = Describe("Connection", func() {
 			cryptoSetup.EXPECT().SetHandshakeConfirmed()
 			cryptoSetup.EXPECT().GetSessionTicket()
 			mconn.EXPECT().Write(gomock.Any())
+			cryptoSetup.EXPECT().ConnectionState()
+			tracer.EXPECT().CompletedHandshake(false, false)
 			close(conn.handshakeCompleteChan)
 			conn.run()
 		}()
@@ -2022,6 +2490,39 @@ var _ = Describe("Connection", func() {
 			conn.handleTransportParameters(params)
 			Expect(conn.earlyConnReady()).To(BeClosed())
 		})
+
+		Context("negotiating the ACK frequency extension", func() {
+			receiveParams := func(minAckDelay *time.Duration) {
+				streamManager.EXPECT().UpdateLimits(gomock.Any())
+				packer.EXPECT().HandleTransportParameters(gomock.Any())
+				tracer.EXPECT().ReceivedTransportParameters(gomock.Any())
+				conn.handleTransportParameters(&wire.TransportParameters{
+					MaxAckDelay:               protocol.DefaultMaxAckDelay,
+					MinAckDelay:               minAckDelay,
+					InitialSourceConnectionID: destConnID,
+				})
+			}
+
+			It("uses the extension if both endpoints advertised the min_ack_delay", func() {
+				conn.config.EnableAckFrequency = true
+				minAckDelay := 5 * time.Millisecond
+				receiveParams(&minAckDelay)
+				Expect(conn.ackFrequencyNegotiated.Get()).To(BeTrue())
+			})
+
+			It("doesn't use the extension if the peer didn't advertise the min_ack_delay", func() {
+				conn.config.EnableAckFrequency = true
+				receiveParams(nil)
+				Expect(conn.ackFrequencyNegotiated.Get()).To(BeFalse())
+			})
+
+			It("doesn't use the extension if it is disabled locally", func() {
+				conn.config.EnableAckFrequency = false
+				minAckDelay := 5 * time.Millisecond
+				receiveParams(&minAckDelay)
+				Expect(conn.ackFrequencyNegotiated.Get()).To(BeFalse())
+			})
+		})
 	})
 
 	Context("keep-alives", func() {
@@ -2087,6 +2588,33 @@ var _ = Describe("Connection", func() {
 			Eventually(sent).Should(BeClosed())
 		})
 
+		It("sends a PING after exactly the KeepAlivePeriod, if configured", func() {
+			conn.config.MaxIdleTimeout = time.Hour
+			conn.config.KeepAlivePeriod = time.Minute
+			conn.config.ExactKeepAlivePeriod = true
+			setRemoteIdleTimeout(time.Hour)
+			Expect(conn.keepAliveInterval).To(Equal(time.Minute))
+			conn.lastPacketReceivedTime = time.Now().Add(-time.Minute).Add(-time.Millisecond)
+			sent := make(chan struct{})
+			packer.EXPECT().PackCoalescedPacket(false).Do(func(bool) (*packedPacket, error) {
+				close(sent)
+				return nil, nil
+			})
+			runConn()
+			Eventually(sent).Should(BeClosed())
+		})
+
+		It("doesn't send a PING before the KeepAlivePeriod, if configured", func() {
+			conn.config.MaxIdleTimeout = time.Hour
+			conn.config.KeepAlivePeriod = time.Minute
+			conn.config.ExactKeepAlivePeriod = true
+			setRemoteIdleTimeout(time.Hour)
+			conn.lastPacketReceivedTime = time.Now().Add(-protocol.MaxKeepAliveInterval).Add(-time.Millisecond)
+			runConn()
+			// don't EXPECT() any calls to packer.PackCoalescedPacket()
+			time.Sleep(50 * time.Millisecond)
+		})
+
 		It("doesn't send a PING packet if keep-alive is disabled", func() {
 			setRemoteIdleTimeout(5 * time.Second)
 			conn.config.KeepAlivePeriod = 0
@@ -2137,6 +2665,33 @@ var _ = Describe("Connection", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("calls the PathDownCallback before timing out, if the peer didn't respond", func() {
+			connRunner.EXPECT().Remove(gomock.Any()).Times(2)
+			conn.lastPacketReceivedTime = time.Now().Add(-time.Hour)
+			conn.firstAckElicitingPacketAfterIdleSentTime = time.Now().Add(-time.Hour)
+			var pathDownCalled bool
+			conn.config.PathDownCallback = func(c Connection) {
+				Expect(c).To(Equal(conn))
+				pathDownCalled = true
+			}
+			done := make(chan struct{})
+			cryptoSetup.EXPECT().Close()
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(e error) {
+					Expect(pathDownCalled).To(BeTrue())
+					Expect(e).To(MatchError(&qerr.IdleTimeoutError{}))
+				}),
+				tracer.EXPECT().Close(),
+			)
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				Expect(conn.run()).To(MatchError(qerr.ErrIdleTimeout))
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("times out due to non-completed handshake", func() {
 			conn.handshakeComplete = false
 			conn.creationTime = time.Now().Add(-protocol.DefaultHandshakeTimeout).Add(-time.Second)
@@ -2162,6 +2717,28 @@ var _ = Describe("Connection", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("times out due to the configured handshake timeout", func() {
+			conn.handshakeComplete = false
+			conn.config.HandshakeTimeout = time.Second
+			conn.creationTime = time.Now().Add(-2 * time.Second)
+			connRunner.EXPECT().Remove(gomock.Any()).Times(2)
+			cryptoSetup.EXPECT().Close()
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(e error) {
+					Expect(e).To(MatchError(&HandshakeTimeoutError{}))
+				}),
+				tracer.EXPECT().Close(),
+			)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				Expect(conn.run()).To(MatchError(qerr.ErrHandshakeTimeout))
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("does not use the idle timeout before the handshake complete", func() {
 			conn.handshakeComplete = false
 			conn.config.HandshakeIdleTimeout = 9999 * time.Second
@@ -2243,6 +2820,8 @@ var _ = Describe("Connection", func() {
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
 				cryptoSetup.EXPECT().GetSessionTicket().MaxTimes(1)
 				cryptoSetup.EXPECT().SetHandshakeConfirmed().MaxTimes(1)
+				cryptoSetup.EXPECT().ConnectionState()
+				tracer.EXPECT().CompletedHandshake(false, false)
 				close(conn.handshakeCompleteChan)
 				err := conn.run()
 				nerr, ok := err.(net.Error)
@@ -2276,28 +2855,491 @@ var _ = Describe("Connection", func() {
 		})
 	})
 
-	It("stores up to MaxConnUnprocessedPackets packets", func() {
-		done := make(chan struct{})
-		tracer.EXPECT().DroppedPacket(logging.PacketTypeNotDetermined, logging.ByteCount(6), logging.PacketDropDOSPrevention).Do(func(logging.PacketType, logging.ByteCount, logging.PacketDropReason) {
-			close(done)
-		})
-		// Nothing here should block
-		for i := protocol.PacketNumber(0); i < protocol.MaxConnUnprocessedPackets+1; i++ {
-			conn.handlePacket(&receivedPacket{data: []byte("foobar")})
-		}
-		Eventually(done).Should(BeClosed())
+	It("uses the configured packet sizes", func() {
+		Expect(conn.initialPacketSize()).To(BeEquivalentTo(protocol.InitialPacketSizeIPv4))
+		Expect(conn.maxPacketSize()).To(Equal(protocol.MaxPacketBufferSize))
+		conn.config.InitialPacketSize = 1300
+		conn.config.MaxPacketSize = 1400
+		Expect(conn.initialPacketSize()).To(Equal(protocol.ByteCount(1300)))
+		Expect(conn.maxPacketSize()).To(Equal(protocol.ByteCount(1400)))
 	})
 
-	Context("getting streams", func() {
-		It("opens streams", func() {
-			mstr := NewMockStreamI(mockCtrl)
-			streamManager.EXPECT().OpenStream().Return(mstr, nil)
-			str, err := conn.OpenStream()
-			Expect(err).ToNot(HaveOccurred())
-			Expect(str).To(Equal(mstr))
-		})
-
-		It("opens streams synchronously", func() {
+	It("reports the path MTU", func() {
+		Expect(conn.PathMTU()).To(BeEquivalentTo(protocol.InitialPacketSizeIPv4))
+		conn.config.DisablePathMTUDiscovery = false
+		conn.peerParams = &wire.TransportParameters{}
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		conn.sentPacketHandler = sph
+		sph.EXPECT().SetHandshakeConfirmed()
+		sph.EXPECT().SetPacketFeedbackCallback(gomock.Any())
+		cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		conn.handleHandshakeConfirmed()
+		Expect(conn.mtuDiscoverer).ToNot(BeNil())
+		ping, size := conn.mtuDiscoverer.GetPing()
+		sph.EXPECT().SetMaxDatagramSize(size)
+		packer.EXPECT().SetMaxPacketSize(size)
+		tracer.EXPECT().UpdatedMTU(size, false)
+		ping.OnAcked(ping.Frame)
+		Expect(conn.PathMTU()).To(BeEquivalentTo(size))
+	})
+
+	Context("pinging", func() {
+		It("measures the time until the PING frame is acknowledged", func() {
+			conn.handshakeCtxCancel()
+			type result struct {
+				rtt time.Duration
+				err error
+			}
+			done := make(chan result, 1)
+			go func() {
+				defer GinkgoRecover()
+				rtt, err := conn.Ping(context.Background())
+				done <- result{rtt: rtt, err: err}
+			}()
+			Eventually(conn.framer.HasData).Should(BeTrue())
+			frames, _ := conn.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
+			time.Sleep(scaleDuration(10 * time.Millisecond))
+			Consistently(done).ShouldNot(Receive())
+			frames[0].OnAcked(frames[0].Frame)
+			var res result
+			Eventually(done).Should(Receive(&res))
+			Expect(res.err).ToNot(HaveOccurred())
+			Expect(res.rtt).To(BeNumerically(">=", scaleDuration(10*time.Millisecond)))
+		})
+
+		It("requests an immediate acknowledgement, if the peer supports it", func() {
+			conn.handshakeCtxCancel()
+			conn.ackFrequencyNegotiated.Set(true)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := conn.Ping(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			Eventually(conn.framer.HasData).Should(BeTrue())
+			frames, _ := conn.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Frame).To(BeAssignableToTypeOf(&wire.ImmediateAckFrame{}))
+			Expect(frames[1].Frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
+			frames[1].OnAcked(frames[1].Frame)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("waits for the handshake to complete", func() {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := conn.Ping(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			Consistently(conn.framer.HasData).Should(BeFalse())
+			conn.handshakeCtxCancel()
+			Eventually(conn.framer.HasData).Should(BeTrue())
+			frames, _ := conn.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+			Expect(frames).To(HaveLen(1))
+			frames[0].OnAcked(frames[0].Frame)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("returns when the context is canceled", func() {
+			conn.handshakeCtxCancel()
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := conn.Ping(ctx)
+				Expect(err).To(MatchError(context.Canceled))
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			cancel()
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
+	Context("awaiting the handshake", func() {
+		It("returns once the handshake completes", func() {
+			done := make(chan error, 1)
+			go func() { done <- conn.AwaitHandshake(context.Background()) }()
+			Consistently(done).ShouldNot(Receive())
+			conn.handshakeCtxCancel()
+			var err error
+			Eventually(done).Should(Receive(&err))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the error that closed the connection", func() {
+			done := make(chan error, 1)
+			go func() { done <- conn.AwaitHandshake(context.Background()) }()
+			Consistently(done).ShouldNot(Receive())
+			testErr := errors.New("handshake failed")
+			conn.closeErr = testErr
+			conn.ctxCancel(testErr)
+			Eventually(done).Should(Receive(MatchError(testErr)))
+		})
+
+		It("returns when the context is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- conn.AwaitHandshake(ctx) }()
+			Consistently(done).ShouldNot(Receive())
+			cancel()
+			Eventually(done).Should(Receive(MatchError(context.Canceled)))
+		})
+	})
+
+	It("sends the preferred_address transport parameter", func() {
+		var params *wire.TransportParameters
+		tr := mocklogging.NewMockConnectionTracer(mockCtrl)
+		tr.EXPECT().NegotiatedVersion(gomock.Any(), gomock.Any(), gomock.Any()).MaxTimes(1)
+		tr.EXPECT().SentTransportParameters(gomock.Any()).Do(func(p *wire.TransportParameters) { params = p })
+		tr.EXPECT().UpdatedKeyFromTLS(gomock.Any(), gomock.Any()).AnyTimes()
+		tr.EXPECT().UpdatedCongestionState(gomock.Any())
+		var preferredConnID protocol.ConnectionID
+		connRunner.EXPECT().Add(gomock.Any(), gomock.Any()).Do(func(c protocol.ConnectionID, _ packetHandler) { preferredConnID = c })
+		connRunner.EXPECT().GetStatelessResetToken(gomock.Any()).Return(protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+		tokenGenerator, err := handshake.NewTokenGenerator(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		newConnection(
+			mconn,
+			connRunner,
+			protocol.ConnectionID{},
+			nil,
+			clientDestConnID,
+			destConnID,
+			srcConnID,
+			protocol.StatelessResetToken{},
+			populateServerConfig(&Config{
+				DisablePathMTUDiscovery: true,
+				PreferredAddressIPv4:    &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1234},
+				PreferredAddressIPv6:    &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 4321},
+			}),
+			nil, // tls.Config
+			tokenGenerator,
+			false,
+			false,
+			tr,
+			1234,
+			utils.DefaultLogger,
+			protocol.VersionTLS,
+		)
+		Expect(params).ToNot(BeNil())
+		Expect(params.PreferredAddress).ToNot(BeNil())
+		Expect(params.PreferredAddress.IPv4.String()).To(Equal("192.168.0.1"))
+		Expect(params.PreferredAddress.IPv4Port).To(BeEquivalentTo(1234))
+		Expect(params.PreferredAddress.IPv6.String()).To(Equal("2001:db8::1"))
+		Expect(params.PreferredAddress.IPv6Port).To(BeEquivalentTo(4321))
+		Expect(params.PreferredAddress.ConnectionID).To(Equal(preferredConnID))
+		Expect(params.PreferredAddress.StatelessResetToken).To(Equal(protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}))
+	})
+
+	Context("datagrams", func() {
+		It("rejects DATAGRAM frames larger than the configured max_datagram_frame_size", func() {
+			conn.config.MaxDatagramFrameSize = 100
+			f := &wire.DatagramFrame{Data: make([]byte, 100)}
+			Expect(f.Length(conn.version)).To(BeNumerically(">", 100))
+			Expect(conn.handleDatagramFrame(f)).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.ProtocolViolation,
+				ErrorMessage: "DATAGRAM frame too large",
+			}))
+			f = &wire.DatagramFrame{Data: make([]byte, 50)}
+			Expect(conn.handleDatagramFrame(f)).To(Succeed())
+		})
+
+		It("errors when sending a message that exceeds the peer's max_datagram_frame_size", func() {
+			conn.peerParams = &wire.TransportParameters{MaxDatagramFrameSize: 100}
+			f := &wire.DatagramFrame{DataLenPresent: true}
+			maxLen := f.MaxDataLen(100, conn.version)
+			Expect(conn.SendMessage(make([]byte, maxLen+1))).To(MatchError("message too large"))
+		})
+
+		It("errors when sending a message if the peer doesn't support datagrams", func() {
+			conn.peerParams = &wire.TransportParameters{MaxDatagramFrameSize: protocol.InvalidByteCount}
+			Expect(conn.SendMessage([]byte("foobar"))).To(MatchError("datagram support disabled"))
+		})
+	})
+
+	Context("peer migration", func() {
+		var (
+			sph          *mockackhandler.MockSentPacketHandler
+			oldAddr      net.Addr
+			newAddr      net.Addr
+			addrsChanged chan [2]net.Addr
+		)
+		migratedAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 4242}
+
+		packetFrom := func(addr net.Addr) *receivedPacket {
+			return &receivedPacket{remoteAddr: addr, data: make([]byte, 100)}
+		}
+
+		getPathChallenge := func() *wire.PathChallengeFrame {
+			frames, _ := conn.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+			ExpectWithOffset(1, frames).To(HaveLen(1))
+			ExpectWithOffset(1, frames[0].Frame).To(BeAssignableToTypeOf(&wire.PathChallengeFrame{}))
+			return frames[0].Frame.(*wire.PathChallengeFrame)
+		}
+
+		BeforeEach(func() {
+			addrsChanged = make(chan [2]net.Addr, 10)
+			packetConn := NewMockPacketConn(mockCtrl)
+			packetConn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
+			conn.conn = newSendPconn(packetConn, remoteAddr)
+			sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			conn.sentPacketHandler = sph
+			conn.handshakeConfirmed = true
+			conn.config.RemoteAddressChanged = func(_ Connection, from, to net.Addr) {
+				addrsChanged <- [2]net.Addr{from, to}
+			}
+			oldAddr = remoteAddr
+			newAddr = migratedAddr
+		})
+
+		It("migrates to a new address, and validates the new path", func() {
+			sph.EXPECT().MigratedPath(protocol.ByteCount(100), true)
+			conn.checkPeerMigration(packetFrom(newAddr), 10, true)
+			Expect(conn.conn.RemoteAddr()).To(Equal(newAddr))
+			// the new path hasn't been validated yet
+			Expect(conn.RemoteAddr()).To(Equal(oldAddr))
+			Expect(addrsChanged).ToNot(Receive())
+			challenge := getPathChallenge()
+
+			sph.EXPECT().PathValidated()
+			Expect(conn.handleFrame(&wire.PathResponseFrame{Data: challenge.Data}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Expect(conn.RemoteAddr()).To(Equal(newAddr))
+			Eventually(addrsChanged).Should(Receive(Equal([2]net.Addr{oldAddr, newAddr})))
+		})
+
+		It("keeps the congestion state if only the port changed", func() {
+			sph.EXPECT().MigratedPath(protocol.ByteCount(100), false)
+			conn.checkPeerMigration(packetFrom(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4242}), 10, true)
+			getPathChallenge()
+		})
+
+		It("keeps using the connection ID when the peer migrates", func() {
+			connRunner.EXPECT().AddResetToken(gomock.Any(), gomock.Any()).AnyTimes()
+			conn.connIDManager.SetHandshakeComplete()
+			Expect(conn.handleNewConnectionIDFrame(&wire.NewConnectionIDFrame{
+				SequenceNumber: 1,
+				ConnectionID:   protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			})).To(Succeed())
+			connID := conn.connIDManager.Get()
+			conn.framer.AppendControlFrames(nil, protocol.MaxByteCount) // drain the RETIRE_CONNECTION_ID frame for the original connection ID
+			sph.EXPECT().MigratedPath(protocol.ByteCount(100), true)
+			conn.checkPeerMigration(packetFrom(newAddr), 10, true)
+			Expect(conn.connIDManager.Get()).To(Equal(connID))
+			getPathChallenge()
+		})
+
+		It("doesn't migrate when receiving a CONNECTION_CLOSE from a new address", func() {
+			conn.receivedConnectionClose = true
+			conn.checkPeerMigration(packetFrom(newAddr), 10, true)
+			Expect(conn.conn.RemoteAddr()).To(Equal(oldAddr))
+		})
+
+		It("sends from the local address that the client sent the packet to", func() {
+			sconn := NewMockSendConn(mockCtrl)
+			conn.conn = sconn
+			info := &packetInfo{addr: net.IPv4(192, 168, 0, 1)}
+			sconn.EXPECT().SetPacketInfo(info)
+			sconn.EXPECT().RemoteAddr().Return(oldAddr).AnyTimes()
+			conn.checkPeerMigration(&receivedPacket{remoteAddr: oldAddr, info: info, data: make([]byte, 100)}, 10, true)
+		})
+
+		It("doesn't migrate on packets that only contain probing frames", func() {
+			conn.checkPeerMigration(packetFrom(newAddr), 10, false)
+			Expect(conn.conn.RemoteAddr()).To(Equal(oldAddr))
+		})
+
+		It("doesn't migrate on reordered packets", func() {
+			conn.checkPeerMigration(packetFrom(oldAddr), 10, true)
+			conn.checkPeerMigration(packetFrom(newAddr), 9, true)
+			Expect(conn.conn.RemoteAddr()).To(Equal(oldAddr))
+		})
+
+		It("doesn't migrate before the handshake is confirmed", func() {
+			conn.handshakeConfirmed = false
+			conn.checkPeerMigration(packetFrom(newAddr), 10, true)
+			Expect(conn.conn.RemoteAddr()).To(Equal(oldAddr))
+		})
+
+		Context("if active migration is disabled", func() {
+			BeforeEach(func() {
+				conn.config.DisableActiveMigration = true
+			})
+
+			It("drops packets from a new address", func() {
+				p := packetFrom(newAddr)
+				p.buffer = getPacketBuffer()
+				tracer.EXPECT().DroppedPacket(logging.PacketType1RTT, p.Size(), logging.PacketDropUnexpectedPacket)
+				// the packet is dropped before it is unpacked
+				Expect(conn.handleShortHeaderPacket(p, srcConnID)).To(BeFalse())
+				Expect(conn.conn.RemoteAddr()).To(Equal(oldAddr))
+				frames, _ := conn.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+				Expect(frames).To(BeEmpty())
+			})
+
+			It("accepts packets from the address used during the handshake", func() {
+				Expect(conn.isDisabledMigration(packetFrom(oldAddr))).To(BeFalse())
+			})
+
+			It("accepts packets from a new address before the handshake is confirmed", func() {
+				conn.handshakeConfirmed = false
+				Expect(conn.isDisabledMigration(packetFrom(newAddr))).To(BeFalse())
+			})
+		})
+
+		It("returns to the validated path without validating it again", func() {
+			sph.EXPECT().MigratedPath(protocol.ByteCount(100), true)
+			conn.checkPeerMigration(packetFrom(newAddr), 10, true)
+			Expect(conn.conn.RemoteAddr()).To(Equal(newAddr))
+			getPathChallenge()
+			sph.EXPECT().PathValidated()
+			conn.checkPeerMigration(packetFrom(oldAddr), 11, true)
+			Expect(conn.conn.RemoteAddr()).To(Equal(oldAddr))
+			Expect(conn.RemoteAddr()).To(Equal(oldAddr))
+			Consistently(addrsChanged).ShouldNot(Receive())
+		})
+
+		It("doesn't switch to a path that was validated after the peer moved on", func() {
+			sph.EXPECT().MigratedPath(protocol.ByteCount(100), true)
+			conn.checkPeerMigration(packetFrom(newAddr), 10, true)
+			challenge := getPathChallenge()
+			sph.EXPECT().PathValidated()
+			conn.checkPeerMigration(packetFrom(oldAddr), 11, true)
+			Expect(conn.handleFrame(&wire.PathResponseFrame{Data: challenge.Data}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Expect(conn.RemoteAddr()).To(Equal(oldAddr))
+			Consistently(addrsChanged).ShouldNot(Receive())
+		})
+	})
+
+	Context("path degradation", func() {
+		var (
+			sph    *mockackhandler.MockSentPacketHandler
+			called int
+		)
+
+		BeforeEach(func() {
+			called = 0
+			sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			conn.sentPacketHandler = sph
+			conn.config.PathDegradingThreshold = 3
+			conn.config.PathDegradingCallback = func(c Connection) {
+				Expect(c).To(Equal(conn))
+				called++
+			}
+		})
+
+		It("calls the callback when the PTO count reaches the threshold", func() {
+			sph.EXPECT().PTOCount().Return(uint32(2))
+			conn.maybeNotifyPathDegrading(1)
+			Expect(called).To(BeZero())
+			sph.EXPECT().PTOCount().Return(uint32(3))
+			conn.maybeNotifyPathDegrading(2)
+			Expect(called).To(Equal(1))
+			sph.EXPECT().PTOCount().Return(uint32(4))
+			conn.maybeNotifyPathDegrading(3)
+			Expect(called).To(Equal(1))
+		})
+
+		It("doesn't call the callback again if the PTO count didn't change", func() {
+			// this happens when the loss detection timer fires for a time-threshold loss
+			sph.EXPECT().PTOCount().Return(uint32(3))
+			conn.maybeNotifyPathDegrading(3)
+			Expect(called).To(BeZero())
+		})
+
+		It("calls the callback again after the peer responded", func() {
+			sph.EXPECT().PTOCount().Return(uint32(3))
+			conn.maybeNotifyPathDegrading(2)
+			Expect(called).To(Equal(1))
+			// an ACK reset the PTO count to 0
+			sph.EXPECT().PTOCount().Return(uint32(1))
+			conn.maybeNotifyPathDegrading(0)
+			sph.EXPECT().PTOCount().Return(uint32(2))
+			conn.maybeNotifyPathDegrading(1)
+			sph.EXPECT().PTOCount().Return(uint32(3))
+			conn.maybeNotifyPathDegrading(2)
+			Expect(called).To(Equal(2))
+		})
+
+		It("calls the callback when the smoothed RTT exceeds the threshold", func() {
+			conn.config.PathDegradingRTTThreshold = 100 * time.Millisecond
+			conn.rttStats.UpdateRTT(50*time.Millisecond, 0, time.Now())
+			conn.maybeNotifyPathDegradingRTT()
+			Expect(called).To(BeZero())
+			conn.rttStats.UpdateRTT(time.Second, 0, time.Now())
+			Expect(conn.rttStats.SmoothedRTT()).To(BeNumerically(">", 100*time.Millisecond))
+			conn.maybeNotifyPathDegradingRTT()
+			Expect(called).To(Equal(1))
+			// the callback is only called when crossing the threshold
+			conn.maybeNotifyPathDegradingRTT()
+			Expect(called).To(Equal(1))
+			for conn.rttStats.SmoothedRTT() > 100*time.Millisecond {
+				conn.rttStats.UpdateRTT(10*time.Millisecond, 0, time.Now())
+			}
+			conn.maybeNotifyPathDegradingRTT()
+			Expect(called).To(Equal(1))
+			conn.rttStats.UpdateRTT(5*time.Second, 0, time.Now())
+			conn.maybeNotifyPathDegradingRTT()
+			Expect(called).To(Equal(2))
+		})
+	})
+
+	Context("path down", func() {
+		BeforeEach(func() {
+			conn.handshakeComplete = true
+			conn.idleTimeout = 10 * time.Second
+			conn.rttStats.UpdateRTT(100*time.Millisecond, 0, time.Now())
+			conn.config.PathDownCallback = func(Connection) {}
+		})
+
+		It("doesn't set a path down time if the callback is not set", func() {
+			conn.config.PathDownCallback = nil
+			conn.firstAckElicitingPacketAfterIdleSentTime = time.Now()
+			Expect(conn.pathDownTime()).To(BeZero())
+		})
+
+		It("doesn't set a path down time if no packet is awaiting a response", func() {
+			conn.lastPacketReceivedTime = time.Now()
+			Expect(conn.pathDownTime()).To(BeZero())
+		})
+
+		It("sets the path down time 3 PTOs before the idle timeout", func() {
+			now := time.Now()
+			conn.lastPacketReceivedTime = now.Add(-time.Second)
+			conn.firstAckElicitingPacketAfterIdleSentTime = now
+			Expect(conn.pathDownTime()).To(Equal(now.Add(10*time.Second - 3*conn.rttStats.PTO(true))))
+			conn.pathDownNotified = true
+			Expect(conn.pathDownTime()).To(BeZero())
+		})
+	})
+
+	It("stores up to MaxConnUnprocessedPackets packets", func() {
+		done := make(chan struct{})
+		tracer.EXPECT().DroppedPacket(logging.PacketTypeNotDetermined, logging.ByteCount(6), logging.PacketDropDOSPrevention).Do(func(logging.PacketType, logging.ByteCount, logging.PacketDropReason) {
+			close(done)
+		})
+		// Nothing here should block
+		for i := protocol.PacketNumber(0); i < protocol.MaxConnUnprocessedPackets+1; i++ {
+			conn.handlePacket(&receivedPacket{data: []byte("foobar")})
+		}
+		Eventually(done).Should(BeClosed())
+	})
+
+	Context("getting streams", func() {
+		It("opens streams", func() {
+			mstr := NewMockStreamI(mockCtrl)
+			streamManager.EXPECT().OpenStream().Return(mstr, nil)
+			str, err := conn.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(Equal(mstr))
+		})
+
+		It("opens streams synchronously", func() {
 			mstr := NewMockStreamI(mockCtrl)
 			streamManager.EXPECT().OpenStreamSync(context.Background()).Return(mstr, nil)
 			str, err := conn.OpenStreamSync(context.Background())
@@ -2340,6 +3382,11 @@ var _ = Describe("Connection", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(str).To(Equal(mstr))
 		})
+
+		It("resets all streams", func() {
+			streamManager.EXPECT().ResetAllStreams(StreamErrorCode(1337))
+			conn.ResetAllStreams(1337)
+		})
 	})
 
 	It("returns the local address", func() {
@@ -2420,6 +3467,230 @@ var _ = Describe("Client Connection", func() {
 		conn.sentFirstPacket = true
 	})
 
+	It("returns the transport parameters restored for 0-RTT", func() {
+		Expect(conn.ZeroRTTTransportParameters()).To(BeNil())
+		params := &wire.TransportParameters{
+			InitialMaxData:          1337,
+			MaxBidiStreamNum:        10,
+			MaxUniStreamNum:         20,
+			ActiveConnectionIDLimit: 4,
+		}
+		conn.restoreTransportParameters(params)
+		Expect(conn.ZeroRTTTransportParameters()).To(Equal(params))
+		// the transport parameters received during the handshake don't overwrite the restored ones
+		tracer.EXPECT().ReceivedTransportParameters(gomock.Any())
+		conn.handleTransportParameters(&wire.TransportParameters{
+			OriginalDestinationConnectionID: destConnID,
+			InitialSourceConnectionID:       destConnID,
+			InitialMaxData:                  42,
+			ActiveConnectionIDLimit:         4,
+		})
+		Expect(conn.ZeroRTTTransportParameters()).To(Equal(params))
+	})
+
+	Context("migrating to the preferred address", func() {
+		var (
+			packetConn *MockPacketConn
+			sph        *mockackhandler.MockSentPacketHandler
+		)
+		serverAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443}
+		preferredIPv4 := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1234}
+		preferredConnID := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
+
+		BeforeEach(func() {
+			packetConn = NewMockPacketConn(mockCtrl)
+			sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		})
+
+		JustBeforeEach(func() {
+			packetConn.EXPECT().LocalAddr().Return(&net.UDPAddr{}).AnyTimes()
+			conn.conn = newSendPconn(packetConn, serverAddr)
+			conn.remoteAddr = serverAddr
+			conn.sentPacketHandler = sph
+			conn.peerParams = &wire.TransportParameters{
+				PreferredAddress: &wire.PreferredAddress{
+					IPv4:         preferredIPv4.IP,
+					IPv4Port:     uint16(preferredIPv4.Port),
+					IPv6:         net.ParseIP("2001:db8::1"),
+					IPv6Port:     4321,
+					ConnectionID: preferredConnID,
+				},
+			}
+			connRunner.EXPECT().AddResetToken(protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, gomock.Any())
+			conn.connIDManager.AddFromPreferredAddress(preferredConnID, protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1})
+		})
+
+		expectProbe := func() (challenge *ackhandler.Frame) {
+			challenge = &ackhandler.Frame{}
+			packer.EXPECT().PackPathProbePacket(preferredConnID, gomock.Any(), protocol.ByteCount(protocol.MinInitialPacketSize)).DoAndReturn(func(_ protocol.ConnectionID, f ackhandler.Frame, _ protocol.ByteCount) (*packedPacket, error) {
+				*challenge = f
+				return &packedPacket{
+					buffer: getPacketBuffer(),
+					packetContents: &packetContents{
+						header: &wire.ExtendedHeader{Header: wire.Header{DestConnectionID: preferredConnID}},
+						frames: []ackhandler.Frame{f},
+					},
+				}, nil
+			})
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().SentPacket(gomock.Any())
+			packetConn.EXPECT().WriteTo(gomock.Any(), preferredIPv4)
+			return challenge
+		}
+
+		It("migrates to the preferred address once the path is validated", func() {
+			addrsChanged := make(chan [2]net.Addr, 1)
+			conn.config.RemoteAddressChanged = func(_ Connection, from, to net.Addr) {
+				addrsChanged <- [2]net.Addr{from, to}
+			}
+			conn.startPreferredAddressValidation()
+			Expect(conn.preferredAddressProbePending).To(BeTrue())
+			challenge := expectProbe()
+			Expect(conn.sendPreferredAddressProbe(time.Now())).To(Succeed())
+			Expect(challenge.Frame).To(BeAssignableToTypeOf(&wire.PathChallengeFrame{}))
+			// packets are sent to the original address until the path has been validated
+			Expect(conn.conn.RemoteAddr()).To(Equal(serverAddr))
+			Expect(conn.RemoteAddr()).To(Equal(serverAddr))
+
+			gomock.InOrder(
+				sph.EXPECT().MigratedPath(protocol.ByteCount(0), true),
+				sph.EXPECT().PathValidated(),
+			)
+			response := &wire.PathResponseFrame{Data: challenge.Frame.(*wire.PathChallengeFrame).Data}
+			Expect(conn.handleFrame(response, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Expect(conn.conn.RemoteAddr()).To(Equal(preferredIPv4))
+			Expect(conn.RemoteAddr()).To(Equal(preferredIPv4))
+			Expect(conn.connIDManager.Get()).To(Equal(preferredConnID))
+			Eventually(addrsChanged).Should(Receive(Equal([2]net.Addr{serverAddr, preferredIPv4})))
+		})
+
+		It("probes the preferred address again if the PATH_CHALLENGE is lost", func() {
+			conn.startPreferredAddressValidation()
+			for i := 0; i < protocol.MaxPreferredAddressProbes; i++ {
+				Expect(conn.preferredAddressProbePending).To(BeTrue())
+				conn.preferredAddressProbePending = false
+				challenge := expectProbe()
+				Expect(conn.sendPreferredAddressProbe(time.Now())).To(Succeed())
+				challenge.OnLost(challenge.Frame)
+			}
+			// give up after the maximum number of probes
+			Expect(conn.preferredAddressProbePending).To(BeFalse())
+			Expect(conn.preferredAddr).To(BeNil())
+			Expect(conn.conn.RemoteAddr()).To(Equal(serverAddr))
+		})
+
+		It("doesn't migrate if the server didn't send an address of the same address family", func() {
+			conn.peerParams.PreferredAddress.IPv4 = net.IPv4zero
+			conn.peerParams.PreferredAddress.IPv4Port = 0
+			conn.startPreferredAddressValidation()
+			Expect(conn.preferredAddressProbePending).To(BeFalse())
+			Expect(conn.preferredAddr).To(BeNil())
+		})
+	})
+
+	Context("migrating to a new packet conn", func() {
+		var (
+			packetConn      *MockPacketConn
+			sph             *mockackhandler.MockSentPacketHandler
+			mockMultiplexer *MockMultiplexer
+			origMultiplexer multiplexer
+		)
+		serverAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443}
+		newLocalAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 1234}
+
+		BeforeEach(func() {
+			packetConn = NewMockPacketConn(mockCtrl)
+			packetConn.EXPECT().LocalAddr().Return(newLocalAddr).AnyTimes()
+			sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			getMultiplexer() // make the sync.Once execute
+			mockMultiplexer = NewMockMultiplexer(mockCtrl)
+			origMultiplexer = connMuxer
+			connMuxer = mockMultiplexer
+		})
+
+		AfterEach(func() {
+			connMuxer = origMultiplexer
+		})
+
+		JustBeforeEach(func() {
+			oldPacketConn := NewMockPacketConn(mockCtrl)
+			oldPacketConn.EXPECT().LocalAddr().Return(&net.UDPAddr{}).AnyTimes()
+			conn.conn = newSwitchableSendConn(newSendPconn(oldPacketConn, serverAddr))
+			conn.sentPacketHandler = sph
+			conn.peerParams = &wire.TransportParameters{}
+			conn.handshakeConfirmed = true
+		})
+
+		It("migrates, and reports when the new path is validated", func() {
+			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Add(gomock.Any(), gomock.Any()).Return(true).AnyTimes()
+			manager.EXPECT().AddResetToken(gomock.Any(), gomock.Any()).AnyTimes()
+			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
+			gomock.InOrder(
+				sph.EXPECT().MigratedPath(protocol.ByteCount(0), true),
+				sph.EXPECT().PathValidated(),
+			)
+			result := make(chan error, 1)
+			Expect(conn.startMigration(packetConn, result)).To(Succeed())
+			// packets are sent from the new packet conn right away
+			Expect(conn.conn.LocalAddr()).To(Equal(newLocalAddr))
+			Expect(conn.conn.RemoteAddr()).To(Equal(serverAddr))
+			frames, _ := conn.framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(BeAssignableToTypeOf(&wire.PathChallengeFrame{}))
+			Expect(result).ToNot(Receive())
+
+			sph.EXPECT().PathValidated()
+			response := &wire.PathResponseFrame{Data: frames[0].Frame.(*wire.PathChallengeFrame).Data}
+			Expect(conn.handleFrame(response, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Expect(result).To(Receive(BeNil()))
+			Expect(conn.migration).To(BeNil())
+		})
+
+		It("moves back to the old packet conn if the path validation times out", func() {
+			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Add(gomock.Any(), gomock.Any()).Return(true).AnyTimes()
+			manager.EXPECT().AddResetToken(gomock.Any(), gomock.Any()).AnyTimes()
+			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
+			sph.EXPECT().MigratedPath(protocol.ByteCount(0), true)
+			sph.EXPECT().PathValidated()
+			result := make(chan error, 1)
+			Expect(conn.startMigration(packetConn, result)).To(Succeed())
+			Expect(conn.conn.LocalAddr()).To(Equal(newLocalAddr))
+			Expect(conn.migration.deadline).To(BeTemporally("~", time.Now().Add(3*conn.rttStats.PTO(true)), scaleDuration(10*time.Millisecond)))
+			Expect(conn.migrationManagers).To(Equal([]packetHandlerManager{manager}))
+
+			manager.EXPECT().Remove(gomock.Any()).AnyTimes()
+			manager.EXPECT().RemoveResetToken(gomock.Any()).AnyTimes()
+			destroyed := make(chan struct{})
+			manager.EXPECT().Destroy().Do(func() { close(destroyed) })
+			sph.EXPECT().MigratedPath(protocol.ByteCount(0), true)
+			sph.EXPECT().PathValidated()
+			conn.abortMigration()
+			Expect(result).To(Receive(MatchError(ErrPathValidationTimeout)))
+			Expect(conn.migration).To(BeNil())
+			Expect(conn.migrationManagers).To(BeEmpty())
+			Expect(conn.conn.LocalAddr()).To(Equal(&net.UDPAddr{}))
+			Expect(conn.conn.RemoteAddr()).To(Equal(serverAddr))
+			Eventually(destroyed).Should(BeClosed())
+		})
+
+		It("doesn't migrate if the server disabled active migration", func() {
+			conn.peerParams.DisableActiveMigration = true
+			Expect(conn.startMigration(packetConn, make(chan error, 1))).To(MatchError(ErrActiveMigrationDisabled))
+		})
+
+		It("doesn't migrate before the handshake is confirmed", func() {
+			conn.handshakeConfirmed = false
+			Expect(conn.startMigration(packetConn, make(chan error, 1))).To(MatchError("can't migrate before the handshake is confirmed"))
+		})
+
+		It("doesn't migrate while another migration is in progress", func() {
+			conn.migration = &pathMigration{local: newLocalAddr}
+			Expect(conn.startMigration(packetConn, make(chan error, 1))).To(MatchError("migration already in progress"))
+		})
+	})
+
 	It("changes the connection ID when receiving the first packet from the server", func() {
 		unpacker := NewMockUnpacker(mockCtrl)
 		unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(hdr *wire.Header, _ time.Time, data []byte) (*unpackedPacket, error) {
@@ -2493,7 +3764,12 @@ var _ = Describe("Client Connection", func() {
 		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 		conn.sentPacketHandler = sph
 		sph.EXPECT().SetHandshakeConfirmed()
+		sph.EXPECT().SetPacketFeedbackCallback(gomock.Any())
 		cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		tracer.EXPECT().ReceivedHandshakeDone()
+		Expect(conn.handleHandshakeDoneFrame()).To(Succeed())
+		// retransmissions of the HANDSHAKE_DONE frame are traced as well
+		tracer.EXPECT().ReceivedHandshakeDone()
 		Expect(conn.handleHandshakeDoneFrame()).To(Succeed())
 	})
 
@@ -2504,6 +3780,7 @@ var _ = Describe("Client Connection", func() {
 		ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}}
 		sph.EXPECT().ReceivedAck(ack, protocol.Encryption1RTT, gomock.Any()).Return(true, nil)
 		sph.EXPECT().SetHandshakeConfirmed()
+		sph.EXPECT().SetPacketFeedbackCallback(gomock.Any())
 		cryptoSetup.EXPECT().SetLargest1RTTAcked(protocol.PacketNumber(3))
 		cryptoSetup.EXPECT().SetHandshakeConfirmed()
 		Expect(conn.handleAckFrame(ack, protocol.Encryption1RTT)).To(Succeed())
@@ -2614,6 +3891,30 @@ var _ = Describe("Client Connection", func() {
 			Expect(err.Error()).To(ContainSubstring("no compatible QUIC version found"))
 		})
 
+		It("closes when version negotiation is disabled", func() {
+			conn.config.Versions = []protocol.VersionNumber{1234, 4321}
+			conn.config.DisableVersionNegotiation = true
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				errChan <- conn.run()
+			}()
+			connRunner.EXPECT().Remove(srcConnID).MaxTimes(1)
+			gomock.InOrder(
+				tracer.EXPECT().ReceivedVersionNegotiationPacket(gomock.Any(), gomock.Any(), gomock.Any()),
+				tracer.EXPECT().ClosedConnection(gomock.Any()),
+				tracer.EXPECT().Close(),
+			)
+			cryptoSetup.EXPECT().Close()
+			Expect(conn.handlePacketImpl(getVNP(4321, 1337))).To(BeFalse())
+			var err error
+			Eventually(errChan).Should(Receive(&err))
+			var vnErr *VersionNegotiationError
+			Expect(errors.As(err, &vnErr)).To(BeTrue())
+			Expect(vnErr.Theirs).To(ContainElement(protocol.VersionNumber(4321)))
+		})
+
 		It("ignores Version Negotiation packets that offer the current version", func() {
 			p := getVNP(conn.version)
 			tracer.EXPECT().DroppedPacket(logging.PacketTypeVersionNegotiation, p.Size(), logging.PacketDropUnexpectedVersion)
@@ -2731,7 +4032,7 @@ var _ = Describe("Client Connection", func() {
 			Eventually(errChan).Should(BeClosed())
 		})
 
-		It("uses the preferred_address connection ID", func() {
+		It("stores the preferred_address connection ID", func() {
 			params := &wire.TransportParameters{
 				OriginalDestinationConnectionID: destConnID,
 				InitialSourceConnectionID:       destConnID,
@@ -2745,13 +4046,19 @@ var _ = Describe("Client Connection", func() {
 			packer.EXPECT().HandleTransportParameters(gomock.Any())
 			packer.EXPECT().PackCoalescedPacket(false).MaxTimes(1)
 			tracer.EXPECT().ReceivedTransportParameters(params)
+			connRunner.EXPECT().AddResetToken(protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, conn)
 			conn.handleTransportParameters(params)
+			cryptoSetup.EXPECT().ConnectionState()
+			tracer.EXPECT().CompletedHandshake(false, false)
 			conn.handleHandshakeComplete()
 			// make sure the connection ID is not retired
 			cf, _ := conn.framer.AppendControlFrames(nil, protocol.MaxByteCount)
 			Expect(cf).To(BeEmpty())
-			connRunner.EXPECT().AddResetToken(protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, conn)
-			Expect(conn.connIDManager.Get()).To(Equal(protocol.ParseConnectionID([]byte{1, 2, 3, 4})))
+			// the preferred_address connection ID is only used on the path to the preferred address
+			Expect(conn.connIDManager.Get()).To(Equal(destConnID))
+			connID, ok := conn.connIDManager.PreferredAddressConnectionID()
+			Expect(ok).To(BeTrue())
+			Expect(connID).To(Equal(protocol.ParseConnectionID([]byte{1, 2, 3, 4})))
 			// shut down
 			connRunner.EXPECT().RemoveResetToken(protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1})
 			expectClose(true)
@@ -2767,8 +4074,66 @@ var _ = Describe("Client Connection", func() {
 			packer.EXPECT().HandleTransportParameters(gomock.Any())
 			tracer.EXPECT().ReceivedTransportParameters(params)
 			conn.handleTransportParameters(params)
+			cryptoSetup.EXPECT().ConnectionState()
+			tracer.EXPECT().CompletedHandshake(false, false)
 			conn.handleHandshakeComplete()
 			Expect(conn.idleTimeout).To(Equal(18 * time.Second))
+			Expect(conn.IdleTimeout()).To(Equal(18 * time.Second))
+			expectClose(true)
+		})
+
+		It("traces if 0-RTT was used when the handshake completes", func() {
+			conn.zeroRTTParams = &wire.TransportParameters{}
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       destConnID,
+			}
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			conn.handleTransportParameters(params)
+			var state handshake.ConnectionState
+			state.Used0RTT = true
+			cryptoSetup.EXPECT().ConnectionState().Return(state)
+			tracer.EXPECT().CompletedHandshake(true, true)
+			conn.handleHandshakeComplete()
+			expectClose(true)
+		})
+
+		It("doesn't block in IdleTimeout if the connection is closed before the handshake completes", func() {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				Expect(conn.IdleTimeout()).To(BeZero())
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			expectClose(true)
+			conn.shutdown()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("returns the connection IDs chosen during the handshake", func() {
+			serverConnID := protocol.ParseConnectionID([]byte{0xde, 0xca, 0xfb, 0xad})
+			conn.handshakeDestConnID = serverConnID
+			Expect(conn.LocalConnectionID()).To(Equal(srcConnID))
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				Expect(conn.RemoteConnectionID()).To(Equal(serverConnID))
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       serverConnID,
+			}
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			conn.handleTransportParameters(params)
+			cryptoSetup.EXPECT().ConnectionState()
+			tracer.EXPECT().CompletedHandshake(false, false)
+			conn.handleHandshakeComplete()
+			Eventually(done).Should(BeClosed())
 			expectClose(true)
 		})
 
@@ -2946,6 +4311,7 @@ var _ = Describe("Client Connection", func() {
 			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
 			initialPacket := testutils.ComposeInitialPacket(destConnID, srcConnID, conn.version, destConnID, []wire.Frame{ack})
 			tracer.EXPECT().ReceivedLongHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedAckForUnsentPacket(protocol.EncryptionInitial, protocol.PacketNumber(2))
 			Expect(conn.handlePacketImpl(wrapPacket(initialPacket))).To(BeFalse())
 		})
 
@@ -0,0 +1,54 @@
+package quic
+
+import (
+	"context"
+	"sync"
+)
+
+type causeContextKey struct{}
+
+// A causeContext is a context that is canceled with an error.
+// It emulates context.WithCancelCause, which is only available starting with Go 1.20.
+type causeContext struct {
+	context.Context
+
+	mutex sync.Mutex
+	cause error
+}
+
+// contextWithCancelCause returns a context that can be canceled with an error.
+// The error can be retrieved using ContextCause.
+func contextWithCancelCause(parent context.Context) (context.Context, func(error)) {
+	ctx, cancel := context.WithCancel(parent)
+	c := &causeContext{Context: ctx}
+	return c, func(cause error) {
+		c.mutex.Lock()
+		if c.cause == nil {
+			c.cause = cause
+		}
+		c.mutex.Unlock()
+		cancel()
+	}
+}
+
+func (c *causeContext) Value(key interface{}) interface{} {
+	if key == (causeContextKey{}) {
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+// ContextCause returns the error that closed the connection,
+// if ctx is the context returned by Connection.Context, or a context derived from it.
+// This is the same error that is returned by stream Read and Write calls.
+// It returns nil if the connection is not closed yet, or if ctx is not derived from a connection's context.
+// It serves the same purpose as context.Cause, which is only available starting with Go 1.20.
+func ContextCause(ctx context.Context) error {
+	c, ok := ctx.Value(causeContextKey{}).(*causeContext)
+	if !ok {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.cause
+}
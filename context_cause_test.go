@@ -0,0 +1,45 @@
+package quic
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Context with cancellation cause", func() {
+	It("cancels the context with a cause", func() {
+		ctx, cancel := contextWithCancelCause(context.Background())
+		Expect(ctx.Done()).ToNot(BeClosed())
+		Expect(ContextCause(ctx)).To(BeNil())
+		testErr := errors.New("test error")
+		cancel(testErr)
+		Expect(ctx.Done()).To(BeClosed())
+		Expect(ctx.Err()).To(MatchError(context.Canceled))
+		Expect(ContextCause(ctx)).To(MatchError(testErr))
+	})
+
+	It("keeps the first cause", func() {
+		ctx, cancel := contextWithCancelCause(context.Background())
+		cancel(errors.New("first"))
+		cancel(errors.New("second"))
+		Expect(ContextCause(ctx)).To(MatchError("first"))
+	})
+
+	It("returns the cause for derived contexts", func() {
+		ctx, cancel := contextWithCancelCause(context.Background())
+		type key struct{}
+		derived, derivedCancel := context.WithCancel(context.WithValue(ctx, key{}, "bar"))
+		defer derivedCancel()
+		testErr := errors.New("test error")
+		cancel(testErr)
+		Expect(derived.Done()).To(BeClosed())
+		Expect(ContextCause(derived)).To(MatchError(testErr))
+		Expect(derived.Value(key{})).To(Equal("bar"))
+	})
+
+	It("returns nil for other contexts", func() {
+		Expect(ContextCause(context.Background())).To(BeNil())
+	})
+})
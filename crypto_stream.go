@@ -25,6 +25,8 @@ type cryptoStreamImpl struct {
 	queue  *frameSorter
 	msgBuf []byte
 
+	// the offset of the first byte that wasn't returned by GetCryptoData yet
+	readOffset    protocol.ByteCount
 	highestOffset protocol.ByteCount
 	finished      bool
 
@@ -38,10 +40,10 @@ func newCryptoStream() cryptoStream {
 
 func (s *cryptoStreamImpl) HandleCryptoFrame(f *wire.CryptoFrame) error {
 	highestOffset := f.Offset + protocol.ByteCount(len(f.Data))
-	if maxOffset := highestOffset; maxOffset > protocol.MaxCryptoStreamOffset {
+	if maxOffset := s.readOffset + protocol.MaxCryptoStreamBufferSize; highestOffset > maxOffset {
 		return &qerr.TransportError{
 			ErrorCode:    qerr.CryptoBufferExceeded,
-			ErrorMessage: fmt.Sprintf("received invalid offset %d on crypto stream, maximum allowed %d", maxOffset, protocol.MaxCryptoStreamOffset),
+			ErrorMessage: fmt.Sprintf("received invalid offset %d on crypto stream, maximum allowed %d", highestOffset, maxOffset),
 		}
 	}
 	if s.finished {
@@ -81,6 +83,7 @@ func (s *cryptoStreamImpl) GetCryptoData() []byte {
 	msg := make([]byte, msgLen)
 	copy(msg, s.msgBuf[:msgLen])
 	s.msgBuf = s.msgBuf[msgLen:]
+	s.readOffset += protocol.ByteCount(msgLen)
 	return msg
 }
 
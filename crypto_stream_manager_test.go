@@ -5,6 +5,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/fkwhite/quic-go/internal/qerr"
 	"github.com/fkwhite/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -116,4 +117,24 @@ var _ = Describe("Crypto Stream Manager", func() {
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("received CRYPTO frame with unexpected encryption level"))
 	})
+
+	It("limits the buffer size per encryption level", func() {
+		csm = newCryptoStreamManager(cs, newCryptoStream(), newCryptoStream(), newCryptoStream())
+		// out-of-order data, nothing can be passed to the crypto handler
+		for _, encLevel := range []protocol.EncryptionLevel{protocol.EncryptionInitial, protocol.EncryptionHandshake, protocol.Encryption1RTT} {
+			_, err := csm.HandleCryptoFrame(&wire.CryptoFrame{
+				Offset: protocol.MaxCryptoStreamBufferSize - 6,
+				Data:   []byte("foobar"),
+			}, encLevel)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		_, err := csm.HandleCryptoFrame(&wire.CryptoFrame{
+			Offset: protocol.MaxCryptoStreamBufferSize,
+			Data:   []byte("foobar"),
+		}, protocol.EncryptionHandshake)
+		Expect(err).To(HaveOccurred())
+		var transportErr *qerr.TransportError
+		Expect(errors.As(err, &transportErr)).To(BeTrue())
+		Expect(transportErr.ErrorCode).To(Equal(qerr.CryptoBufferExceeded))
+	})
 })
@@ -51,11 +51,47 @@ var _ = Describe("Crypto Stream", func() {
 
 		It("errors if the frame exceeds the maximum offset", func() {
 			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{
-				Offset: protocol.MaxCryptoStreamOffset - 5,
+				Offset: protocol.MaxCryptoStreamBufferSize - 5,
 				Data:   []byte("foobar"),
 			})).To(MatchError(&qerr.TransportError{
 				ErrorCode:    qerr.CryptoBufferExceeded,
-				ErrorMessage: fmt.Sprintf("received invalid offset %d on crypto stream, maximum allowed %d", protocol.MaxCryptoStreamOffset+1, protocol.MaxCryptoStreamOffset),
+				ErrorMessage: fmt.Sprintf("received invalid offset %d on crypto stream, maximum allowed %d", protocol.MaxCryptoStreamBufferSize+1, protocol.MaxCryptoStreamBufferSize),
+			}))
+		})
+
+		It("accepts data up to the maximum buffer size", func() {
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{
+				Offset: protocol.MaxCryptoStreamBufferSize - 6,
+				Data:   []byte("foobar"),
+			})).To(Succeed())
+		})
+
+		It("errors if an out-of-order frame exceeds the maximum buffer size", func() {
+			msg := createHandshakeMessage(6)
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{Offset: 4, Data: msg[4:]})).To(Succeed())
+			err := str.HandleCryptoFrame(&wire.CryptoFrame{
+				Offset: 1000 + protocol.MaxCryptoStreamBufferSize,
+				Data:   []byte("foobar"),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.CryptoBufferExceeded))
+		})
+
+		It("limits the buffer size relative to the data that was already read", func() {
+			msg := createHandshakeMessage(1000)
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{Data: msg})).To(Succeed())
+			Expect(str.GetCryptoData()).To(Equal(msg))
+			offset := protocol.ByteCount(len(msg))
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{
+				Offset: offset + protocol.MaxCryptoStreamBufferSize - 6,
+				Data:   []byte("foobar"),
+			})).To(Succeed())
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{
+				Offset: offset + protocol.MaxCryptoStreamBufferSize - 5,
+				Data:   []byte("foobar"),
+			})).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.CryptoBufferExceeded,
+				ErrorMessage: fmt.Sprintf("received invalid offset %d on crypto stream, maximum allowed %d", offset+protocol.MaxCryptoStreamBufferSize+1, offset+protocol.MaxCryptoStreamBufferSize),
 			}))
 		})
 
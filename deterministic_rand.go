@@ -0,0 +1,47 @@
+//go:build quic_testing
+
+package quic
+
+import (
+	"crypto/rand"
+	mrand "math/rand"
+	"sync"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+)
+
+// SetRandSource makes quic-go use src for all random values that are not security-critical:
+// connection IDs, skipped packet numbers, and greased versions and transport parameters.
+// Using a source with a fixed seed makes these values reproducible across runs, e.g. for benchmarks.
+// Note that the TLS handshake uses its own randomness, which is configured using tls.Config.Rand.
+// It must be called before any connection is established, and is only available when building with the quic_testing build tag.
+// Passing nil restores the default (crypto/rand).
+func SetRandSource(src mrand.Source) {
+	if src == nil {
+		protocol.RandReader = rand.Reader
+		return
+	}
+	protocol.RandReader = &randSourceReader{src: src}
+}
+
+// randSourceReader turns a math/rand.Source into an io.Reader.
+// Unlike the math/rand.Source, it is safe for concurrent use.
+type randSourceReader struct {
+	mutex sync.Mutex
+	src   mrand.Source
+}
+
+func (r *randSourceReader) Read(b []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := 0; i < len(b); i += 7 {
+		// Int63 returns 63 random bits, use the lower 7 bytes
+		v := r.src.Int63()
+		for j := i; j < i+7 && j < len(b); j++ {
+			b[j] = byte(v)
+			v >>= 8
+		}
+	}
+	return len(b), nil
+}
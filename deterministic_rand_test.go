@@ -0,0 +1,62 @@
+//go:build quic_testing
+
+package quic
+
+import (
+	"crypto/rand"
+	mrand "math/rand"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/fkwhite/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Deterministic Randomness", func() {
+	AfterEach(func() { SetRandSource(nil) })
+
+	generate := func() ([]protocol.ConnectionID, []int32) {
+		var connIDs []protocol.ConnectionID
+		for i := 0; i < 10; i++ {
+			connID, err := protocol.GenerateConnectionIDForInitial()
+			Expect(err).ToNot(HaveOccurred())
+			connIDs = append(connIDs, connID)
+		}
+		var r utils.Rand
+		var nums []int32
+		for i := 0; i < 10; i++ {
+			nums = append(nums, r.Int31n(1000))
+		}
+		return connIDs, nums
+	}
+
+	It("generates the same values when using the same seed", func() {
+		SetRandSource(mrand.NewSource(42))
+		connIDs1, nums1 := generate()
+		SetRandSource(mrand.NewSource(42))
+		connIDs2, nums2 := generate()
+		Expect(connIDs1).To(Equal(connIDs2))
+		Expect(nums1).To(Equal(nums2))
+
+		SetRandSource(mrand.NewSource(1337))
+		connIDs3, _ := generate()
+		Expect(connIDs3).ToNot(Equal(connIDs1))
+	})
+
+	It("fills buffers that are not a multiple of 7 bytes", func() {
+		SetRandSource(mrand.NewSource(42))
+		b := make([]byte, 100)
+		n, err := protocol.RandReader.Read(b[:99])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(99))
+		Expect(b[99]).To(BeZero())
+		Expect(b[:99]).ToNot(Equal(make([]byte, 99)))
+	})
+
+	It("restores crypto/rand", func() {
+		SetRandSource(mrand.NewSource(42))
+		SetRandSource(nil)
+		Expect(protocol.RandReader).To(Equal(rand.Reader))
+	})
+})
@@ -2,10 +2,26 @@ package quic
 
 import (
 	"fmt"
+	"net"
 
 	"github.com/fkwhite/quic-go/internal/qerr"
 )
 
+// When a connection is closed, all pending and future calls on the connection and its streams
+// (e.g. Accept{Uni}Stream, Open{Uni}Stream{Sync}, Stream.Read and Stream.Write) return an error of one of these types.
+// The same error is returned when dialing fails. Use errors.As to distinguish between them:
+//   - *ApplicationError: the connection was closed using CloseWithError, either locally or by the peer.
+//   - *TransportError: the connection was closed due to a QUIC protocol error, either locally or by the peer.
+//     Local failures that don't correspond to a QUIC error code are reported as an INTERNAL_ERROR.
+//   - *IdleTimeoutError: no network activity for the duration of the idle timeout.
+//   - *HandshakeTimeoutError: the handshake didn't complete in time.
+//   - *VersionNegotiationError: client and server don't support a common QUIC version.
+//   - *StatelessResetError: the peer sent a stateless reset.
+//
+// If the peer acknowledged a packet that was never sent (a likely optimistic ACK attack), the connection is closed
+// with an *OptimisticAckError. It wraps the *TransportError (a PROTOCOL_VIOLATION) that is sent to the peer.
+//
+// All of these errors match net.ErrClosed when using errors.Is.
 type (
 	TransportError          = qerr.TransportError
 	ApplicationError        = qerr.ApplicationError
@@ -13,6 +29,7 @@ type (
 	StatelessResetError     = qerr.StatelessResetError
 	IdleTimeoutError        = qerr.IdleTimeoutError
 	HandshakeTimeoutError   = qerr.HandshakeTimeoutError
+	OptimisticAckError      = qerr.OptimisticAckError
 )
 
 type (
@@ -41,11 +58,16 @@ const (
 	NoViablePathError         = qerr.NoViablePathError
 )
 
-// A StreamError is used for Stream.CancelRead and Stream.CancelWrite.
-// It is also returned from Stream.Read and Stream.Write if the peer canceled reading or writing.
+// A StreamError is returned from Stream.Read and Stream.Write if reading or writing was canceled,
+// either by the application (using Stream.CancelRead and Stream.CancelWrite), or by the peer
+// (by sending a RESET_STREAM or a STOP_SENDING frame).
 type StreamError struct {
 	StreamID  StreamID
 	ErrorCode StreamErrorCode
+	// Remote is true if the peer canceled the stream, and false if the stream was canceled locally.
+	// On the receive side, this distinguishes a RESET_STREAM received from the peer from a STOP_SENDING sent by us.
+	// On the send side, it distinguishes a STOP_SENDING received from the peer from a RESET_STREAM sent by us.
+	Remote bool
 }
 
 func (e *StreamError) Is(target error) bool {
@@ -54,5 +76,56 @@ func (e *StreamError) Is(target error) bool {
 }
 
 func (e *StreamError) Error() string {
-	return fmt.Sprintf("stream %d canceled with error code %d", e.StreamID, e.ErrorCode)
+	pers := "local"
+	if e.Remote {
+		pers = "remote"
+	}
+	return fmt.Sprintf("stream %d canceled by %s with error code %d", e.StreamID, pers, e.ErrorCode)
 }
+
+// A Stream0RTTRejectedError is returned from Stream.Write if the stream was opened in 0-RTT, and the server rejected 0-RTT.
+// It matches Err0RTTRejected when using errors.Is.
+//
+// When the server rejects 0-RTT, none of the data sent in 0-RTT is delivered to the server's application.
+// All streams opened before the rejection are closed, and calls to Open{Uni}Stream{Sync},
+// Accept{Uni}Stream, Stream.Read and Stream.Write on them return an error matching Err0RTTRejected.
+// quic-go doesn't retransmit any of this data in 1-RTT packets: it is up to the application to decide
+// whether it is safe to replay it (e.g. for idempotent requests).
+// To do so, the application waits for HandshakeComplete, obtains the new connection using NextConnection,
+// opens new streams on it, and writes the data again.
+// BytesWritten tells the application how many bytes it needs to resend.
+type Stream0RTTRejectedError struct {
+	StreamID StreamID
+	// BytesWritten is the number of bytes that were written to the stream,
+	// including the bytes that were written by the Write call that returned this error.
+	// All of these bytes need to be sent again on a new stream.
+	BytesWritten uint64
+}
+
+func (e *Stream0RTTRejectedError) Is(target error) bool {
+	return target == Err0RTTRejected
+}
+
+func (e *Stream0RTTRejectedError) Error() string {
+	return fmt.Sprintf("0-RTT rejected, %d bytes written on stream %d were not delivered", e.BytesWritten, e.StreamID)
+}
+
+// A StreamLimitReachedError is returned from OpenStream and OpenUniStream if opening a new stream
+// would exceed the stream limit announced by the peer (in its transport parameters and MAX_STREAMS frames),
+// or if there are OpenStreamSync / OpenUniStreamSync calls waiting for the limit to be raised.
+// Opening a stream is never blocked by flow control: flow control only limits the amount of data sent on a stream.
+// Use OpenStreamSync with a context.WithTimeout to wait for the peer to allow opening more streams.
+type StreamLimitReachedError struct {
+	// Unidirectional is set when the limit for unidirectional streams was reached.
+	Unidirectional bool
+	// MaxStreams is the current stream limit, i.e. the number of streams the peer allows us to open.
+	MaxStreams uint64
+}
+
+var _ net.Error = &StreamLimitReachedError{}
+
+func (e *StreamLimitReachedError) Error() string { return errTooManyOpenStreams.Error() }
+
+// Temporary says that opening a stream might succeed later, once the peer raised the stream limit.
+func (e *StreamLimitReachedError) Temporary() bool { return true }
+func (e *StreamLimitReachedError) Timeout() bool   { return false }
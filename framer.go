@@ -3,6 +3,7 @@ package quic
 import (
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/fkwhite/quic-go/internal/ackhandler"
 	"github.com/fkwhite/quic-go/internal/protocol"
@@ -14,6 +15,7 @@ type framer interface {
 	HasData() bool
 
 	QueueControlFrame(wire.Frame)
+	QueuePing(onAcked func(rtt time.Duration))
 	AppendControlFrames([]ackhandler.Frame, protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount)
 
 	AddActiveStream(protocol.StreamID)
@@ -33,6 +35,7 @@ type framerI struct {
 
 	controlFrameMutex sync.Mutex
 	controlFrames     []wire.Frame
+	pings             []func(time.Duration) // the callbacks of the queued PING frames
 }
 
 var _ framer = &framerI{}
@@ -56,7 +59,7 @@ func (f *framerI) HasData() bool {
 		return true
 	}
 	f.controlFrameMutex.Lock()
-	hasData = len(f.controlFrames) > 0
+	hasData = len(f.controlFrames) > 0 || len(f.pings) > 0
 	f.controlFrameMutex.Unlock()
 	return hasData
 }
@@ -67,6 +70,15 @@ func (f *framerI) QueueControlFrame(frame wire.Frame) {
 	f.controlFrameMutex.Unlock()
 }
 
+// QueuePing queues a PING frame.
+// onAcked is called with the time that elapsed between sending the PING frame and receiving the acknowledgement for it.
+// If the packet containing the PING frame is lost, a new PING frame is sent.
+func (f *framerI) QueuePing(onAcked func(rtt time.Duration)) {
+	f.controlFrameMutex.Lock()
+	f.pings = append(f.pings, onAcked)
+	f.controlFrameMutex.Unlock()
+}
+
 func (f *framerI) AppendControlFrames(frames []ackhandler.Frame, maxLen protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount) {
 	var length protocol.ByteCount
 	f.controlFrameMutex.Lock()
@@ -80,6 +92,22 @@ func (f *framerI) AppendControlFrames(frames []ackhandler.Frame, maxLen protocol
 		length += frameLen
 		f.controlFrames = f.controlFrames[:len(f.controlFrames)-1]
 	}
+	for len(f.pings) > 0 {
+		ping := &wire.PingFrame{}
+		frameLen := ping.Length(f.version)
+		if length+frameLen > maxLen {
+			break
+		}
+		onAcked := f.pings[0]
+		sent := time.Now()
+		frames = append(frames, ackhandler.Frame{
+			Frame:   ping,
+			OnLost:  func(wire.Frame) { f.QueuePing(onAcked) },
+			OnAcked: func(wire.Frame) { onAcked(time.Since(sent)) },
+		})
+		length += frameLen
+		f.pings = f.pings[1:]
+	}
 	f.controlFrameMutex.Unlock()
 	return frames, length
 }
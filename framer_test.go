@@ -3,6 +3,7 @@ package quic
 import (
 	"bytes"
 	"math/rand"
+	"time"
 
 	"github.com/fkwhite/quic-go/internal/ackhandler"
 	"github.com/fkwhite/quic-go/internal/protocol"
@@ -87,6 +88,47 @@ var _ = Describe("Framer", func() {
 			Expect(length).To(Equal(bfLen))
 		})
 
+		It("adds PING frames", func() {
+			var rtt time.Duration
+			framer.QueuePing(func(d time.Duration) { rtt = d })
+			Expect(framer.HasData()).To(BeTrue())
+			frames, length := framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(&wire.PingFrame{}))
+			Expect(length).To(Equal((&wire.PingFrame{}).Length(version)))
+			Expect(framer.HasData()).To(BeFalse())
+			time.Sleep(scaleDuration(10 * time.Millisecond))
+			frames[0].OnAcked(frames[0].Frame)
+			Expect(rtt).To(BeNumerically(">=", scaleDuration(10*time.Millisecond)))
+		})
+
+		It("queues a new PING frame when a PING frame is lost", func() {
+			var acked bool
+			framer.QueuePing(func(time.Duration) { acked = true })
+			frames, _ := framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(HaveLen(1))
+			frames[0].OnLost(frames[0].Frame)
+			Expect(acked).To(BeFalse())
+			Expect(framer.HasData()).To(BeTrue())
+			frames, _ = framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(HaveLen(1))
+			frames[0].OnAcked(frames[0].Frame)
+			Expect(acked).To(BeTrue())
+		})
+
+		It("doesn't add PING frames if there's no space left", func() {
+			mdf := &wire.MaxDataFrame{MaximumData: 0x42}
+			framer.QueueControlFrame(mdf)
+			framer.QueuePing(func(time.Duration) {})
+			frames, length := framer.AppendControlFrames(nil, mdf.Length(version))
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(mdf))
+			Expect(length).To(Equal(mdf.Length(version)))
+			frames, _ = framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(&wire.PingFrame{}))
+		})
+
 		It("drops *_BLOCKED frames when 0-RTT is rejected", func() {
 			ping := &wire.PingFrame{}
 			ncid := &wire.NewConnectionIDFrame{
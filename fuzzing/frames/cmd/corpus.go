@@ -121,6 +121,13 @@ func getFrames() []wire.Frame {
 			ECNCE:     getRandomNumber(),
 		},
 		&wire.PingFrame{},
+		&wire.ImmediateAckFrame{},
+		&wire.AckFrequencyFrame{
+			SequenceNumber:        getRandomNumber(),
+			AckElicitingThreshold: getRandomNumber(),
+			RequestMaxAckDelay:    time.Duration(getRandomNumber()) * time.Microsecond,
+			ReorderingThreshold:   getRandomNumber(),
+		},
 		&wire.ResetStreamFrame{
 			StreamID:  protocol.StreamID(getRandomNumber()),
 			ErrorCode: quic.StreamErrorCode(getRandomNumber()),
@@ -131,6 +138,12 @@ func getFrames() []wire.Frame {
 			ErrorCode: quic.StreamErrorCode(getRandomNumber()),
 			FinalSize: protocol.MaxByteCount,
 		},
+		&wire.ResetStreamAtFrame{
+			StreamID:     protocol.StreamID(getRandomNumber()),
+			ErrorCode:    quic.StreamErrorCode(getRandomNumber()),
+			FinalSize:    protocol.MaxByteCount,
+			ReliableSize: protocol.ByteCount(getRandomNumber()),
+		},
 		&wire.StopSendingFrame{
 			StreamID:  protocol.StreamID(getRandomNumber()),
 			ErrorCode: quic.StreamErrorCode(getRandomNumber()),
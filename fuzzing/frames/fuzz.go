@@ -33,7 +33,11 @@ func Fuzz(data []byte) int {
 	encLevel := toEncLevel(data[0])
 	data = data[PrefixLen:]
 
-	parser := wire.NewFrameParser(true, version)
+	parser := wire.NewFrameParserWithOptions(wire.FrameParserOptions{
+		SupportsDatagrams:     true,
+		SupportsResetStreamAt: true,
+		SupportsAckFrequency:  true,
+	}, version)
 	parser.SetAckDelayExponent(protocol.DefaultAckDelayExponent)
 
 	initialLen := len(data)
@@ -60,9 +64,9 @@ func Fuzz(data []byte) int {
 			b = append(b, 0)
 			continue
 		}
-		// We accept empty STREAM frames, but we don't write them.
+		// We accept empty STREAM frames, but we don't write them, unless they carry a FIN.
 		if sf, ok := f.(*wire.StreamFrame); ok {
-			if sf.DataLen() == 0 {
+			if sf.DataLen() == 0 && !sf.IsFinOnly() {
 				sf.PutBack()
 				continue
 			}
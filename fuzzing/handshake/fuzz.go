@@ -401,6 +401,7 @@ func runHandshake(runConfig [confLen]byte, messageConfig uint8, clientConf *tls.
 		runner,
 		serverConf,
 		enable0RTTServer,
+		nil,
 		utils.NewRTTStats(),
 		nil,
 		utils.DefaultLogger.WithPrefix("server"),
@@ -0,0 +1,450 @@
+package http3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fkwhite/quic-go"
+	"github.com/fkwhite/quic-go/internal/utils"
+)
+
+// defaultAltSvcMaxAge is the freshness lifetime of an alternative service
+// advertised without the ma parameter, see Section 3.1 of RFC 7838.
+const defaultAltSvcMaxAge = 24 * time.Hour
+
+// altSvcBrokenDuration is the time an origin's alternative services are not used
+// after dialing or using a connection to its alternative service failed.
+const altSvcBrokenDuration = 5 * time.Minute
+
+var errAltSvcRemoved = errors.New("http3: alternative service was removed")
+
+// An AltSvcTransport is a http.RoundTripper that upgrades to HTTP/3 once an origin advertises support for it.
+// Requests are sent using the Fallback (HTTP/1.1 or HTTP/2), until a response carries an Alt-Svc header field
+// announcing an HTTP/3 alternative service, see RFC 7838. Subsequent requests to that origin are then sent over QUIC.
+// If the connection to the alternative service can't be established or fails, the alternative service
+// is removed from the cache, and the origin's alternative services are ignored for the next 5 minutes.
+// A request that failed over HTTP/3 is retried using the Fallback if the server didn't process it,
+// or if it is idempotent, as long as the request body can be rewound.
+type AltSvcTransport struct {
+	// Fallback is used for requests to origins that didn't advertise HTTP/3,
+	// and when sending a request over HTTP/3 fails.
+	// If nil, http.DefaultTransport is used.
+	Fallback http.RoundTripper
+
+	// RoundTripper is used for sending requests over HTTP/3.
+	// Its TLSClientConfig, QuicConfig and Dial are used for dialing the alternative services.
+	// If nil, a RoundTripper with the default configuration is used.
+	RoundTripper *RoundTripper
+
+	initOnce sync.Once
+	h3       *RoundTripper // the RoundTripper, or the default RoundTripper if none was set
+
+	mutex    sync.Mutex
+	services map[string]*altService // keyed by the authority of the origin
+	broken   map[string]time.Time   // origins whose alternative services are not used until the given time
+}
+
+var (
+	_ http.RoundTripper = &AltSvcTransport{}
+	_ io.Closer         = &AltSvcTransport{}
+)
+
+// An altService is a cached HTTP/3 alternative service
+type altService struct {
+	authority string // host and port of the alternative service
+	expires   time.Time
+
+	dialMutex sync.Mutex // held while dialing
+	removed   bool
+	conn      quic.EarlyConnection
+}
+
+// RoundTrip sends the request over HTTP/3 if the origin advertised an HTTP/3 alternative service,
+// and using the Fallback otherwise.
+func (t *AltSvcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL == nil || req.URL.Scheme != "https" {
+		return t.fallback().RoundTrip(req)
+	}
+	origin := authorityAddr("https", hostnameFromRequest(req))
+	if svc, ok := t.getService(origin); ok {
+		rsp, processed, err := t.roundTripH3(req, origin, svc)
+		if err == nil {
+			t.handleAltSvc(origin, rsp.Header)
+			return rsp, nil
+		}
+		if req.Context().Err() != nil || (processed && !isReplayable(req)) {
+			return nil, err
+		}
+		// Retry the request using the fallback.
+		var rerr error
+		if req, rerr = rewindBody(req); rerr != nil {
+			return nil, err
+		}
+	}
+	rsp, err := t.fallback().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.handleAltSvc(origin, rsp.Header)
+	return rsp, nil
+}
+
+// roundTripH3 sends the request to the alternative service.
+// If the request fails, processed says if the server might have processed the request.
+// Stream errors don't affect other requests using the connection to the alternative service.
+func (t *AltSvcTransport) roundTripH3(req *http.Request, origin string, svc *altService) (rsp *http.Response, processed bool, err error) {
+	conn, err := t.dial(req.Context(), origin, svc)
+	if err != nil {
+		if !errors.Is(err, errAltSvcRemoved) && req.Context().Err() == nil {
+			t.markBroken(origin, svc)
+		}
+		return nil, false, err
+	}
+	var wroteHeaders bool
+	ctx := httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{WroteHeaders: func() { wroteHeaders = true }})
+	rsp, err = t.roundTripper().RoundTripOpt(req.WithContext(ctx), RoundTripOpt{Conn: conn})
+	if err == nil {
+		return rsp, true, nil
+	}
+	switch {
+	case errors.Is(err, ErrGoAway):
+		// The server is shutting down the connection, and didn't process the request.
+		// Use a new connection for the next request.
+		svc.forgetConn(conn)
+		return nil, false, err
+	case conn.Context().Err() != nil && req.Context().Err() == nil:
+		t.markBroken(origin, svc)
+	}
+	return nil, wroteHeaders && !isRequestRejected(err), err
+}
+
+// dial returns the connection to the alternative service, dialing a new connection if necessary
+func (t *AltSvcTransport) dial(ctx context.Context, origin string, svc *altService) (quic.EarlyConnection, error) {
+	svc.dialMutex.Lock()
+	defer svc.dialMutex.Unlock()
+
+	if svc.removed {
+		return nil, errAltSvcRemoved
+	}
+	if svc.conn != nil && svc.conn.Context().Err() == nil {
+		return svc.conn, nil
+	}
+	rt := t.roundTripper()
+	tlsConf, quicConf, err := dialConfigs(rt.TLSClientConfig, rt.QuicConfig, rt.opts())
+	if err != nil {
+		return nil, err
+	}
+	// The certificate has to be valid for the origin, not for the alternative service.
+	if tlsConf.ServerName == "" {
+		host, _, err := net.SplitHostPort(origin)
+		if err != nil {
+			return nil, err
+		}
+		tlsConf.ServerName = host
+	}
+	dial := rt.Dial
+	if dial == nil {
+		dial = dialAddr
+	}
+	conn, err := dial(ctx, svc.authority, tlsConf, quicConf)
+	if err != nil {
+		return nil, err
+	}
+	svc.conn = conn
+	return conn, nil
+}
+
+func (s *altService) forgetConn(conn quic.EarlyConnection) {
+	s.dialMutex.Lock()
+	defer s.dialMutex.Unlock()
+
+	if s.conn == conn {
+		s.conn = nil
+	}
+}
+
+// handleAltSvc updates the cache based on the Alt-Svc header fields of a response.
+// Alt-Svc header fields replace all alternative services cached for the origin, see Section 3 of RFC 7838.
+func (t *AltSvcTransport) handleAltSvc(origin string, hdr http.Header) {
+	values := hdr.Values("Alt-Svc")
+	if len(values) == 0 {
+		return
+	}
+	host, _, err := net.SplitHostPort(origin)
+	if err != nil {
+		return
+	}
+	authority, maxAge, ok := parseAltSvc(values, t.alpn(), host)
+	if !ok {
+		t.removeService(origin, nil)
+		return
+	}
+	if t.isBroken(origin) {
+		return
+	}
+	t.addService(origin, authority, maxAge)
+}
+
+// AddAltSvc adds an HTTP/3 alternative service for an origin to the cache, replacing the one cached before.
+// The origin and the alternative service are given as host and port, if the port is omitted, port 443 is used.
+// Requests to the origin are sent to the alternative service until maxAge has passed,
+// even if a previous alternative service of the origin failed recently.
+func (t *AltSvcTransport) AddAltSvc(origin, authority string, maxAge time.Duration) {
+	origin = authorityAddr("https", origin)
+	t.mutex.Lock()
+	delete(t.broken, origin)
+	t.mutex.Unlock()
+	t.addService(origin, authorityAddr("https", authority), maxAge)
+}
+
+// RemoveAltSvc removes the alternative service cached for an origin,
+// and closes the connection to it. The origin is given as host and port, as for AddAltSvc.
+func (t *AltSvcTransport) RemoveAltSvc(origin string) {
+	t.removeService(authorityAddr("https", origin), nil)
+}
+
+// ClearAltSvcCache removes all alternative services from the cache, and closes the connections to them.
+func (t *AltSvcTransport) ClearAltSvcCache() {
+	t.mutex.Lock()
+	services := t.services
+	t.services = nil
+	t.broken = nil
+	t.mutex.Unlock()
+
+	for _, svc := range services {
+		svc.close()
+	}
+}
+
+// Close clears the cache, and closes the HTTP/3 RoundTripper.
+// The Fallback is not closed.
+func (t *AltSvcTransport) Close() error {
+	t.ClearAltSvcCache()
+	return t.roundTripper().Close()
+}
+
+func (t *AltSvcTransport) getService(origin string) (*altService, bool) {
+	t.mutex.Lock()
+	svc, ok := t.services[origin]
+	if !ok || time.Now().Before(svc.expires) {
+		t.mutex.Unlock()
+		return svc, ok
+	}
+	delete(t.services, origin)
+	t.mutex.Unlock()
+
+	svc.close()
+	return nil, false
+}
+
+func (t *AltSvcTransport) addService(origin, authority string, maxAge time.Duration) {
+	t.mutex.Lock()
+	if t.services == nil {
+		t.services = make(map[string]*altService)
+	}
+	old, ok := t.services[origin]
+	if ok && old.authority == authority {
+		// keep using the existing connection
+		old.expires = time.Now().Add(maxAge)
+		t.mutex.Unlock()
+		return
+	}
+	t.services[origin] = &altService{authority: authority, expires: time.Now().Add(maxAge)}
+	t.mutex.Unlock()
+
+	if ok {
+		old.close()
+	}
+}
+
+// removeService removes the alternative service cached for the origin.
+// If svc is not nil, it is only removed if it is still the cached alternative service.
+func (t *AltSvcTransport) removeService(origin string, svc *altService) {
+	t.mutex.Lock()
+	cached, ok := t.services[origin]
+	if !ok || (svc != nil && cached != svc) {
+		t.mutex.Unlock()
+		return
+	}
+	delete(t.services, origin)
+	t.mutex.Unlock()
+
+	cached.close()
+}
+
+// markBroken removes the alternative service, and ignores the origin's alternative services for a while
+func (t *AltSvcTransport) markBroken(origin string, svc *altService) {
+	t.mutex.Lock()
+	if t.broken == nil {
+		t.broken = make(map[string]time.Time)
+	}
+	t.broken[origin] = time.Now().Add(altSvcBrokenDuration)
+	t.mutex.Unlock()
+
+	t.removeService(origin, svc)
+}
+
+func (t *AltSvcTransport) isBroken(origin string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	until, ok := t.broken[origin]
+	if !ok {
+		return false
+	}
+	if time.Now().Before(until) {
+		return true
+	}
+	delete(t.broken, origin)
+	return false
+}
+
+func (s *altService) close() {
+	s.dialMutex.Lock()
+	defer s.dialMutex.Unlock()
+
+	s.removed = true
+	if s.conn != nil {
+		s.conn.CloseWithError(quic.ApplicationErrorCode(errorNoError), "")
+	}
+}
+
+func (t *AltSvcTransport) fallback() http.RoundTripper {
+	if t.Fallback == nil {
+		return http.DefaultTransport
+	}
+	return t.Fallback
+}
+
+func (t *AltSvcTransport) roundTripper() *RoundTripper {
+	t.initOnce.Do(func() {
+		t.h3 = t.RoundTripper
+		if t.h3 == nil {
+			t.h3 = &RoundTripper{}
+		}
+	})
+	return t.h3
+}
+
+// alpn returns the ALPN of the HTTP/3 version that is used for dialing
+func (t *AltSvcTransport) alpn() string {
+	if conf := t.roundTripper().QuicConfig; conf != nil && len(conf.Versions) > 0 {
+		return versionToALPN(conf.Versions[0])
+	}
+	return versionToALPN(defaultQuicConfig.Versions[0])
+}
+
+// isReplayable says if a request can be sent again after it failed,
+// i.e. if its method is idempotent, or if it carries an idempotency key.
+// This matches the behavior of the net/http Transport.
+func isReplayable(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	_, ok := req.Header["X-Idempotency-Key"]
+	return ok
+}
+
+// isRequestRejected says if the server rejected the request without processing it, see Section 4.1.1 of RFC 9114
+func isRequestRejected(err error) bool {
+	var serr *quic.StreamError
+	return errors.As(err, &serr) && serr.ErrorCode == quic.StreamErrorCode(errorRequestRejected)
+}
+
+// parseAltSvc parses the values of the Alt-Svc header fields, see Section 3 of RFC 7838.
+// It returns the authority of the first alternative service using the given ALPN, and its freshness lifetime.
+// An empty host in the alternative service's authority refers to the origin's host.
+// If the alternative services were cleared, or none of them uses the ALPN, ok is false.
+func parseAltSvc(values []string, alpn, host string) (authority string, maxAge time.Duration, ok bool) {
+	for _, value := range values {
+		for _, alt := range splitQuoted(value, ',') {
+			alt = strings.TrimSpace(alt)
+			if alt == "clear" {
+				return "", 0, false
+			}
+			params := splitQuoted(alt, ';')
+			protocol, quotedAuthority, found := strings.Cut(strings.TrimSpace(params[0]), "=")
+			if !found {
+				continue
+			}
+			if protocol, err := url.PathUnescape(protocol); err != nil || protocol != alpn {
+				continue
+			}
+			h, port, err := net.SplitHostPort(unquote(quotedAuthority))
+			if err != nil {
+				continue
+			}
+			if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+				continue
+			}
+			if h == "" {
+				h = host
+			}
+			maxAge := defaultAltSvcMaxAge
+			for _, param := range params[1:] {
+				key, val, _ := strings.Cut(param, "=")
+				if strings.TrimSpace(key) != "ma" {
+					continue
+				}
+				if secs, err := strconv.ParseUint(unquote(val), 10, 64); err == nil {
+					maxAge = time.Duration(utils.Min(secs, math.MaxInt64/uint64(time.Second))) * time.Second
+				}
+			}
+			return net.JoinHostPort(h, port), maxAge, true
+		}
+	}
+	return "", 0, false
+}
+
+// splitQuoted splits s at every occurrence of sep that is not part of a quoted string
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	var quoted, escaped bool
+	var start int
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote trims whitespace, and removes the quotes and escaping from a quoted string.
+// Values that are not quoted are returned unchanged.
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
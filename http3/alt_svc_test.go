@@ -0,0 +1,384 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/fkwhite/quic-go"
+	mockquic "github.com/fkwhite/quic-go/internal/mocks/quic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+var _ = Describe("Alt-Svc", func() {
+	Context("parsing", func() {
+		It("parses an alternative service on the same host", func() {
+			authority, maxAge, ok := parseAltSvc([]string{`h3=":443"; ma=3600`}, "h3", "example.org")
+			Expect(ok).To(BeTrue())
+			Expect(authority).To(Equal("example.org:443"))
+			Expect(maxAge).To(Equal(time.Hour))
+		})
+
+		It("uses the first alternative service with the right ALPN", func() {
+			values := []string{`h2="alt.example.org:443", h3-29=":8443"; ma=60`, `h3="alt.example.org:8443"; persist=1, h3=":443"`}
+			authority, maxAge, ok := parseAltSvc(values, "h3", "example.org")
+			Expect(ok).To(BeTrue())
+			Expect(authority).To(Equal("alt.example.org:8443"))
+			Expect(maxAge).To(Equal(defaultAltSvcMaxAge))
+			authority, maxAge, ok = parseAltSvc(values, "h3-29", "example.org")
+			Expect(ok).To(BeTrue())
+			Expect(authority).To(Equal("example.org:8443"))
+			Expect(maxAge).To(Equal(time.Minute))
+		})
+
+		It("handles quoted strings", func() {
+			authority, maxAge, ok := parseAltSvc([]string{`foo=";,h3=\":1\""; ma="1,2", h3="[2001:db8::1]:443"; ma="120"`}, "h3", "example.org")
+			Expect(ok).To(BeTrue())
+			Expect(authority).To(Equal("[2001:db8::1]:443"))
+			Expect(maxAge).To(Equal(2 * time.Minute))
+		})
+
+		It("unescapes the protocol ID", func() {
+			authority, _, ok := parseAltSvc([]string{`%68%33=":443"`}, "h3", "example.org")
+			Expect(ok).To(BeTrue())
+			Expect(authority).To(Equal("example.org:443"))
+		})
+
+		It("limits the freshness lifetime", func() {
+			_, maxAge, ok := parseAltSvc([]string{`h3=":443"; ma=18446744073709551615`}, "h3", "example.org")
+			Expect(ok).To(BeTrue())
+			Expect(maxAge).To(BeEquivalentTo(math.MaxInt64 / int64(time.Second) * int64(time.Second)))
+		})
+
+		It("ignores invalid alternative services", func() {
+			for _, v := range []string{`h3`, `h3=":foo"`, `h3=":65536"`, `h3="example.org"`, `h2=":443"`} {
+				_, _, ok := parseAltSvc([]string{v}, "h3", "example.org")
+				Expect(ok).To(BeFalse())
+			}
+		})
+
+		It("clears alternative services", func() {
+			_, _, ok := parseAltSvc([]string{"clear"}, "h3", "example.org")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("transport", func() {
+		var (
+			transport    *AltSvcTransport
+			conn         *mockquic.MockEarlyConnection
+			h3Client     *mockClient
+			fallbackReqs []*http.Request
+			fallbackRsp  *http.Response
+			origDialAddr = dialAddr
+		)
+
+		newRequest := func() *http.Request {
+			req, err := http.NewRequest(http.MethodGet, "https://example.org/foo", nil)
+			Expect(err).ToNot(HaveOccurred())
+			return req
+		}
+
+		BeforeEach(func() {
+			conn = mockquic.NewMockEarlyConnection(mockCtrl)
+			conn.EXPECT().Context().Return(context.Background()).AnyTimes()
+			h3Client = &mockClient{}
+			fallbackReqs = nil
+			fallbackRsp = &http.Response{Header: http.Header{}}
+			transport = &AltSvcTransport{
+				Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					fallbackReqs = append(fallbackReqs, req)
+					return fallbackRsp, nil
+				}),
+				RoundTripper: &RoundTripper{
+					pinnedClients: map[quic.EarlyConnection]roundTripCloser{conn: h3Client},
+				},
+			}
+			origDialAddr = dialAddr
+			dialAddr = func(_ context.Context, addr string, tlsConf *tls.Config, _ *quic.Config) (quic.EarlyConnection, error) {
+				Expect(addr).To(Equal("alt.example.org:8443"))
+				Expect(tlsConf.ServerName).To(Equal("example.org"))
+				Expect(tlsConf.NextProtos).To(Equal([]string{nextProtoH3}))
+				return conn, nil
+			}
+		})
+
+		AfterEach(func() {
+			dialAddr = origDialAddr
+		})
+
+		It("uses the fallback if no alternative service was advertised", func() {
+			req := newRequest()
+			rsp, err := transport.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp).To(Equal(fallbackRsp))
+			Expect(fallbackReqs).To(Equal([]*http.Request{req}))
+			Expect(transport.services).To(BeEmpty())
+		})
+
+		It("upgrades to HTTP/3 once an alternative service was advertised", func() {
+			fallbackRsp.Header.Set("Alt-Svc", `h3="alt.example.org:8443"; ma=60`)
+			_, err := transport.RoundTrip(newRequest())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(transport.services).To(HaveKey("example.org:443"))
+
+			req := newRequest()
+			rsp, err := transport.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.Request.URL).To(Equal(req.URL)) // the response of the mockClient
+			Expect(fallbackReqs).To(HaveLen(1))
+			// the connection is reused
+			_, err = transport.RoundTrip(newRequest())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fallbackReqs).To(HaveLen(1))
+		})
+
+		It("doesn't upgrade requests to other origins", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			req, err := http.NewRequest(http.MethodGet, "https://example.org:8080/foo", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = transport.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fallbackReqs).To(Equal([]*http.Request{req}))
+		})
+
+		It("doesn't upgrade plain HTTP requests", func() {
+			transport.AddAltSvc("example.org:80", "alt.example.org:8443", time.Minute)
+			req, err := http.NewRequest(http.MethodGet, "http://example.org/foo", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = transport.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fallbackReqs).To(Equal([]*http.Request{req}))
+		})
+
+		It("falls back if an idempotent request fails", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			h3Client.err = errors.New("request failed")
+			h3Client.wroteHeaders = true
+			req := newRequest()
+			rsp, err := transport.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp).To(Equal(fallbackRsp))
+			Expect(fallbackReqs).To(Equal([]*http.Request{req}))
+			// the connection is still alive, and is used for the next request
+			Expect(transport.services).To(HaveKey("example.org:443"))
+			h3Client.err = nil
+			_, err = transport.RoundTrip(newRequest())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fallbackReqs).To(HaveLen(1))
+		})
+
+		It("doesn't fall back if a non-idempotent request fails after it was sent", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			testErr := errors.New("request failed")
+			h3Client.err = testErr
+			h3Client.wroteHeaders = true
+			req, err := http.NewRequest(http.MethodPost, "https://example.org/upload", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = transport.RoundTrip(req)
+			Expect(err).To(MatchError(testErr))
+			Expect(fallbackReqs).To(BeEmpty())
+			Expect(transport.services).To(HaveKey("example.org:443"))
+		})
+
+		It("falls back if a non-idempotent request carries an idempotency key", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			h3Client.err = errors.New("request failed")
+			h3Client.wroteHeaders = true
+			req, err := http.NewRequest(http.MethodPost, "https://example.org/upload", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Idempotency-Key", "foo")
+			_, err = transport.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fallbackReqs).To(HaveLen(1))
+		})
+
+		It("falls back if a non-idempotent request wasn't sent", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			h3Client.err = errors.New("couldn't open stream")
+			req, err := http.NewRequest(http.MethodPost, "https://example.org/upload", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = transport.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fallbackReqs).To(HaveLen(1))
+		})
+
+		It("falls back if the server rejected a non-idempotent request", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			h3Client.err = &quic.StreamError{StreamID: 4, ErrorCode: quic.StreamErrorCode(errorRequestRejected)}
+			h3Client.wroteHeaders = true
+			req, err := http.NewRequest(http.MethodPost, "https://example.org/upload", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = transport.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fallbackReqs).To(HaveLen(1))
+			Expect(transport.services).To(HaveKey("example.org:443"))
+		})
+
+		It("uses a new connection after receiving a GOAWAY", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			h3Client.err = ErrGoAway
+			req, err := http.NewRequest(http.MethodPost, "https://example.org/upload", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = transport.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fallbackReqs).To(HaveLen(1))
+			Expect(transport.services).To(HaveKey("example.org:443"))
+			Expect(transport.services["example.org:443"].conn).To(BeNil())
+		})
+
+		It("forgets the alternative service if the connection fails", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			failedConn := mockquic.NewMockEarlyConnection(mockCtrl)
+			// the connection fails while the request is sent
+			failedConn.EXPECT().Context().Return(context.Background()).Times(2)
+			failedConn.EXPECT().Context().Return(ctx).AnyTimes()
+			failedConn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "")
+			transport.RoundTripper.pinnedClients[failedConn] = &mockClient{err: errors.New("connection failed")}
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			transport.services["example.org:443"].conn = failedConn
+			req := newRequest()
+			rsp, err := transport.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp).To(Equal(fallbackRsp))
+			Expect(fallbackReqs).To(Equal([]*http.Request{req}))
+			Expect(transport.services).To(BeEmpty())
+			Expect(transport.broken).To(HaveKey("example.org:443"))
+		})
+
+		It("falls back if dialing fails", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			dialAddr = func(context.Context, string, *tls.Config, *quic.Config) (quic.EarlyConnection, error) {
+				return nil, errors.New("dial error")
+			}
+			_, err := transport.RoundTrip(newRequest())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fallbackReqs).To(HaveLen(1))
+			Expect(transport.services).To(BeEmpty())
+		})
+
+		It("ignores alternative services of an origin for a while after they failed", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			dialAddr = func(context.Context, string, *tls.Config, *quic.Config) (quic.EarlyConnection, error) {
+				return nil, errors.New("dial error")
+			}
+			fallbackRsp.Header.Set("Alt-Svc", `h3="alt.example.org:8443"; ma=60`)
+			_, err := transport.RoundTrip(newRequest())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(transport.services).To(BeEmpty())
+			Expect(transport.broken["example.org:443"]).To(BeTemporally("~", time.Now().Add(altSvcBrokenDuration), time.Second))
+			// the advertisement is ignored
+			_, err = transport.RoundTrip(newRequest())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fallbackReqs).To(HaveLen(2))
+			Expect(transport.services).To(BeEmpty())
+			// once the time has passed, the alternative service is used again
+			transport.broken["example.org:443"] = time.Now().Add(-time.Second)
+			_, err = transport.RoundTrip(newRequest())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(transport.services).To(HaveKey("example.org:443"))
+			Expect(transport.broken).To(BeEmpty())
+		})
+
+		It("uses alternative services added by the application, even if the origin's alternative services failed", func() {
+			transport.markBroken("example.org:443", nil)
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			Expect(transport.services).To(HaveKey("example.org:443"))
+			Expect(transport.broken).To(BeEmpty())
+		})
+
+		It("doesn't fall back if the request body can't be rewound", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			testErr := errors.New("request failed")
+			h3Client.err = testErr
+			req, err := http.NewRequest(http.MethodPut, "https://example.org/upload", &mockBody{})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = transport.RoundTrip(req)
+			Expect(err).To(MatchError(testErr))
+			Expect(fallbackReqs).To(BeEmpty())
+		})
+
+		It("doesn't fall back if the request was canceled", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			testErr := errors.New("request canceled")
+			h3Client.err = testErr
+			_, err := transport.RoundTrip(newRequest().WithContext(ctx))
+			Expect(err).To(MatchError(testErr))
+			Expect(fallbackReqs).To(BeEmpty())
+			Expect(transport.services).To(HaveKey("example.org:443"))
+		})
+
+		It("clears alternative services", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			transport.handleAltSvc("example.org:443", http.Header{"Alt-Svc": {"clear"}})
+			Expect(transport.services).To(BeEmpty())
+		})
+
+		It("removes alternative services", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			_, err := transport.RoundTrip(newRequest())
+			Expect(err).ToNot(HaveOccurred())
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "")
+			transport.RemoveAltSvc("example.org")
+			Expect(transport.services).To(BeEmpty())
+		})
+
+		It("replaces alternative services", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			_, err := transport.RoundTrip(newRequest())
+			Expect(err).ToNot(HaveOccurred())
+			// the same alternative service, the connection is kept
+			transport.handleAltSvc("example.org:443", http.Header{"Alt-Svc": {`h3="alt.example.org:8443"; ma=3600`}})
+			Expect(transport.services["example.org:443"].expires).To(BeTemporally("~", time.Now().Add(time.Hour), time.Second))
+			// a different alternative service
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "")
+			transport.handleAltSvc("example.org:443", http.Header{"Alt-Svc": {`h3=":443"`}})
+			Expect(transport.services["example.org:443"].authority).To(Equal("example.org:443"))
+		})
+
+		It("doesn't use expired alternative services", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			_, err := transport.RoundTrip(newRequest())
+			Expect(err).ToNot(HaveOccurred())
+			transport.services["example.org:443"].expires = time.Now().Add(-time.Second)
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "")
+			_, err = transport.RoundTrip(newRequest())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fallbackReqs).To(HaveLen(1))
+			Expect(transport.services).To(BeEmpty())
+		})
+
+		It("closes connections when clearing the cache", func() {
+			transport.AddAltSvc("example.org", "alt.example.org:8443", time.Minute)
+			transport.AddAltSvc("example.com", "alt.example.org:8443", time.Minute)
+			_, err := transport.RoundTrip(newRequest())
+			Expect(err).ToNot(HaveOccurred())
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "")
+			Expect(transport.Close()).To(Succeed())
+			Expect(transport.services).To(BeEmpty())
+			Expect(h3Client.closed).To(BeFalse())
+		})
+
+		It("uses the ALPN of the configured QUIC version", func() {
+			transport.RoundTripper = nil
+			Expect(transport.alpn()).To(Equal(nextProtoH3))
+			transport = &AltSvcTransport{RoundTripper: &RoundTripper{
+				QuicConfig: &quic.Config{Versions: []quic.VersionNumber{quic.VersionDraft29}},
+			}}
+			Expect(transport.alpn()).To(Equal(nextProtoH3Draft29))
+		})
+	})
+})
@@ -38,12 +38,16 @@ type Hijacker interface {
 type body struct {
 	str quic.Stream
 
+	// only set if HTTP/3 datagrams are enabled
+	datagrams *streamDatagrams
+
 	wasHijacked bool // set when HTTPStream is called
 }
 
 var (
 	_ io.ReadCloser = &body{}
 	_ HTTPStreamer  = &body{}
+	_ Datagrammer   = &body{}
 )
 
 func newRequestBody(str Stream) *body {
@@ -68,6 +72,20 @@ func (r *body) Close() error {
 	return nil
 }
 
+func (r *body) SendDatagram(b []byte) error {
+	if r.datagrams == nil {
+		return errDatagramsNotEnabled
+	}
+	return r.datagrams.SendDatagram(b)
+}
+
+func (r *body) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	if r.datagrams == nil {
+		return nil, errDatagramsNotEnabled
+	}
+	return r.datagrams.ReceiveDatagram(ctx)
+}
+
 type hijackableBody struct {
 	body
 	conn quic.Connection // only needed to implement Hijacker
@@ -82,6 +100,7 @@ type hijackableBody struct {
 var (
 	_ Hijacker     = &hijackableBody{}
 	_ HTTPStreamer = &hijackableBody{}
+	_ Datagrammer  = &hijackableBody{}
 )
 
 func newResponseBody(str Stream, conn quic.Connection, done chan<- struct{}) *hijackableBody {
@@ -122,6 +141,10 @@ func (r *body) StreamID() quic.StreamID {
 
 func (r *hijackableBody) Close() error {
 	r.requestDone()
+	// No datagrams are received for this request after the response body was closed.
+	if r.datagrams != nil {
+		r.datagrams.mux.unregister(r.datagrams)
+	}
 	// If the EOF was read, CancelRead() is a no-op.
 	r.str.CancelRead(quic.StreamErrorCode(errorRequestCanceled))
 	return nil
@@ -6,8 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,6 +30,7 @@ const MethodGet0RTT = "GET_0RTT"
 const (
 	defaultUserAgent              = "quic-go HTTP/3"
 	defaultMaxResponseHeaderBytes = 10 * 1 << 20 // 10 MB
+	max1xxResponses               = 5            // the maximum number of interim responses accepted before the final response
 )
 
 var defaultQuicConfig = &quic.Config{
@@ -45,6 +50,9 @@ type roundTripperOpts struct {
 	AdditionalSettings map[uint64]uint64
 	StreamHijacker     func(FrameType, quic.Connection, quic.Stream, error) (hijacked bool, err error)
 	UniStreamHijacker  func(StreamType, quic.Connection, quic.ReceiveStream, error) (hijacked bool)
+
+	QPACKMaxTableCapacity int
+	QPACKBlockedStreams   int
 }
 
 // client is a HTTP3 client doing requests
@@ -59,15 +67,52 @@ type client struct {
 
 	requestWriter *requestWriter
 
-	decoder *qpack.Decoder
+	encoder *qpackEncoder
+	decoder *qpackDecoder
 
-	hostname string
+	hostname string // empty if the client was created for an existing connection
 	conn     quic.EarlyConnection
 
+	datagrams *datagramMux // nil if HTTP/3 datagrams are disabled
+
+	goAwayMutex    sync.Mutex
+	receivedGoAway bool
+	goAwayID       quic.StreamID // requests on this and higher stream IDs won't be processed by the server
+
+	requestsMutex  sync.Mutex
+	activeRequests int  // number of requests whose response body hasn't been closed yet
+	closing        bool // set once closeWhenIdle was called
+
+	coalesceMutex sync.Mutex
+	handshakeConn quic.EarlyConnection // set once the handshake has completed
+	authorities   map[string]struct{}  // authorities that requests are coalesced onto this connection for
+
 	logger utils.Logger
 }
 
 func newClient(hostname string, tlsConf *tls.Config, opts *roundTripperOpts, conf *quic.Config, dialer dialFunc) (*client, error) {
+	tlsConf, conf, err := dialConfigs(tlsConf, conf, opts)
+	if err != nil {
+		return nil, err
+	}
+	logger := utils.DefaultLogger.WithPrefix("h3 client")
+
+	encoder := newQPACKEncoder(opts.qpackMaxTableCapacity(), logger)
+	return &client{
+		hostname:      authorityAddr("https", hostname),
+		tlsConf:       tlsConf,
+		requestWriter: newRequestWriter(encoder, logger),
+		encoder:       encoder,
+		decoder:       newQPACKDecoder(opts.qpackMaxTableCapacity(), opts.qpackBlockedStreams(), logger),
+		config:        conf,
+		opts:          opts,
+		dialer:        dialer,
+		logger:        logger,
+	}, nil
+}
+
+// dialConfigs returns the TLS and QUIC configuration used for dialing a HTTP/3 connection.
+func dialConfigs(tlsConf *tls.Config, conf *quic.Config, opts *roundTripperOpts) (*tls.Config, *quic.Config, error) {
 	if conf == nil {
 		conf = defaultQuicConfig.Clone()
 	} else if len(conf.Versions) == 0 {
@@ -75,13 +120,12 @@ func newClient(hostname string, tlsConf *tls.Config, opts *roundTripperOpts, con
 		conf.Versions = []quic.VersionNumber{defaultQuicConfig.Versions[0]}
 	}
 	if len(conf.Versions) != 1 {
-		return nil, errors.New("can only use a single QUIC version for dialing a HTTP/3 connection")
+		return nil, nil, errors.New("can only use a single QUIC version for dialing a HTTP/3 connection")
 	}
 	if conf.MaxIncomingStreams == 0 {
 		conf.MaxIncomingStreams = -1 // don't allow any bidirectional streams
 	}
 	conf.EnableDatagrams = opts.EnableDatagram
-	logger := utils.DefaultLogger.WithPrefix("h3 client")
 
 	if tlsConf == nil {
 		tlsConf = &tls.Config{}
@@ -90,29 +134,56 @@ func newClient(hostname string, tlsConf *tls.Config, opts *roundTripperOpts, con
 	}
 	// Replace existing ALPNs by H3
 	tlsConf.NextProtos = []string{versionToALPN(conf.Versions[0])}
+	return tlsConf, conf, nil
+}
 
+// newClientForConn creates a client that sends requests on an existing connection.
+// This connection is not associated with a single host, requests for any host can be sent on it.
+func newClientForConn(conn quic.EarlyConnection, opts *roundTripperOpts) *client {
+	logger := utils.DefaultLogger.WithPrefix("h3 client")
+	encoder := newQPACKEncoder(opts.qpackMaxTableCapacity(), logger)
 	return &client{
-		hostname:      authorityAddr("https", hostname),
-		tlsConf:       tlsConf,
-		requestWriter: newRequestWriter(logger),
-		decoder:       qpack.NewDecoder(func(hf qpack.HeaderField) {}),
-		config:        conf,
+		requestWriter: newRequestWriter(encoder, logger),
+		encoder:       encoder,
+		decoder:       newQPACKDecoder(opts.qpackMaxTableCapacity(), opts.qpackBlockedStreams(), logger),
 		opts:          opts,
-		dialer:        dialer,
-		logger:        logger,
-	}, nil
+		dialer: func(context.Context, string, *tls.Config, *quic.Config) (quic.EarlyConnection, error) {
+			return conn, nil
+		},
+		logger: logger,
+	}
 }
 
 func (c *client) dial(ctx context.Context) error {
+	// If the client was created for an existing connection, there's no connection to trace.
+	var trace *httptrace.ClientTrace
+	if c.hostname != "" {
+		trace = httptrace.ContextClientTrace(ctx)
+	}
+	addr := c.hostname
 	var err error
+	if c.dialer == nil && trace != nil && (trace.DNSStart != nil || trace.DNSDone != nil) {
+		if addr, err = c.resolve(ctx, trace); err != nil {
+			return err
+		}
+	}
+	if trace != nil && trace.ConnectStart != nil {
+		trace.ConnectStart("udp", addr)
+	}
 	if c.dialer != nil {
-		c.conn, err = c.dialer(ctx, c.hostname, c.tlsConf, c.config)
+		c.conn, err = c.dialer(ctx, addr, c.tlsConf, c.config)
 	} else {
-		c.conn, err = dialAddr(ctx, c.hostname, c.tlsConf, c.config)
+		c.conn, err = dialAddr(ctx, addr, c.tlsConf, c.config)
 	}
 	if err != nil {
+		if trace != nil && trace.ConnectDone != nil {
+			trace.ConnectDone("udp", addr, err)
+		}
 		return err
 	}
+	if c.opts.EnableDatagram {
+		c.datagrams = newDatagramMux(c.conn, c.logger)
+	}
 
 	// send the SETTINGs frame, using 0-RTT data, if possible
 	go func() {
@@ -129,6 +200,41 @@ func (c *client) dial(ctx context.Context) error {
 	return nil
 }
 
+// resolve resolves the hostname, and returns the address to dial.
+// It is only used when the DNS lookup is traced, otherwise the hostname is resolved when dialing.
+// Since the connection is then dialed using an IP address, the hostname is used as the SNI.
+func (c *client) resolve(ctx context.Context, trace *httptrace.ClientTrace) (string, error) {
+	host, port, err := net.SplitHostPort(c.hostname)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return c.hostname, nil
+	}
+	if trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+	}
+	ips, err := lookupIPAddr(ctx, host)
+	if trace.DNSDone != nil {
+		trace.DNSDone(httptrace.DNSDoneInfo{Addrs: ips, Err: err})
+	}
+	if err != nil {
+		return "", err
+	}
+	// Prefer IPv4 addresses, as net.ResolveUDPAddr does.
+	ip := ips[0].IP
+	for _, addr := range ips {
+		if addr.IP.To4() != nil {
+			ip = addr.IP
+			break
+		}
+	}
+	if c.tlsConf.ServerName == "" {
+		c.tlsConf.ServerName = host
+	}
+	return net.JoinHostPort(ip.String(), port), nil
+}
+
 func (c *client) setupConn() error {
 	// open the control stream
 	str, err := c.conn.OpenUniStream()
@@ -138,7 +244,12 @@ func (c *client) setupConn() error {
 	b := make([]byte, 0, 64)
 	b = quicvarint.Append(b, streamTypeControlStream)
 	// send the SETTINGS frame
-	b = (&settingsFrame{Datagram: c.opts.EnableDatagram, Other: c.opts.AdditionalSettings}).Append(b)
+	b = (&settingsFrame{
+		QPACKMaxTableCapacity: c.opts.qpackMaxTableCapacity(),
+		QPACKBlockedStreams:   c.opts.qpackBlockedStreams(),
+		Datagram:              c.opts.EnableDatagram,
+		Other:                 c.opts.AdditionalSettings,
+	}).Append(b)
 	_, err = str.Write(b)
 	return err
 }
@@ -185,9 +296,11 @@ func (c *client) handleUnidirectionalStreams() {
 			// We're only interested in the control stream here.
 			switch streamType {
 			case streamTypeControlStream:
-			case streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream:
-				// Our QPACK implementation doesn't use the dynamic table yet.
-				// TODO: check that only one stream of each type is opened.
+			case streamTypeQPACKEncoderStream:
+				c.decoder.handleEncoderStream(c.conn, str)
+				return
+			case streamTypeQPACKDecoderStream:
+				c.encoder.handleDecoderStream(c.conn, str)
 				return
 			case streamTypePushStream:
 				// We never increased the Push ID, so we don't expect any push streams.
@@ -210,19 +323,146 @@ func (c *client) handleUnidirectionalStreams() {
 				c.conn.CloseWithError(quic.ApplicationErrorCode(errorMissingSettings), "")
 				return
 			}
-			if !sf.Datagram {
-				return
-			}
+			c.encoder.handleSettings(c.conn, sf.QPACKMaxTableCapacity)
 			// If datagram support was enabled on our side as well as on the server side,
 			// we can expect it to have been negotiated both on the transport and on the HTTP/3 layer.
 			// Note: ConnectionState() will block until the handshake is complete (relevant when using 0-RTT).
-			if c.opts.EnableDatagram && !c.conn.ConnectionState().SupportsDatagrams {
-				c.conn.CloseWithError(quic.ApplicationErrorCode(errorSettingsError), "missing QUIC Datagram support")
+			if sf.Datagram && c.opts.EnableDatagram {
+				if !c.conn.ConnectionState().SupportsDatagrams {
+					c.conn.CloseWithError(quic.ApplicationErrorCode(errorSettingsError), "missing QUIC Datagram support")
+					return
+				}
+				c.datagrams.setNegotiated()
+			}
+			for {
+				f, err := parseNextFrame(str, nil)
+				if err != nil {
+					c.logger.Debugf("reading from the control stream failed: %s", err)
+					return
+				}
+				if gf, ok := f.(*goAwayFrame); ok {
+					if err := c.handleGoAway(gf.StreamID); err != nil {
+						c.conn.CloseWithError(quic.ApplicationErrorCode(errorIDError), err.Error())
+						return
+					}
+				}
 			}
 		}(str)
 	}
 }
 
+// handleGoAway handles a GOAWAY frame received on the control stream.
+// No new requests are sent on this connection afterwards.
+func (c *client) handleGoAway(id quic.StreamID) error {
+	if id.InitiatedBy() != protocol.PerspectiveClient || id.Type() != protocol.StreamTypeBidi {
+		return fmt.Errorf("received GOAWAY with invalid stream ID %d", id)
+	}
+	c.goAwayMutex.Lock()
+	defer c.goAwayMutex.Unlock()
+
+	if c.receivedGoAway && id > c.goAwayID {
+		return fmt.Errorf("received GOAWAY with increasing stream ID (%d, previously %d)", id, c.goAwayID)
+	}
+	c.logger.Debugf("Received GOAWAY for stream %d", id)
+	c.receivedGoAway = true
+	c.goAwayID = id
+	return nil
+}
+
+// goAwayStreamID returns the stream ID from the last GOAWAY frame, if any GOAWAY frame was received
+func (c *client) goAwayStreamID() (quic.StreamID, bool) {
+	c.goAwayMutex.Lock()
+	defer c.goAwayMutex.Unlock()
+	return c.goAwayID, c.receivedGoAway
+}
+
+// closeWhenIdle closes the connection once all requests that are in flight have completed,
+// i.e. once the application closed the bodies of their responses.
+func (c *client) closeWhenIdle() {
+	c.requestsMutex.Lock()
+	c.closing = true
+	idle := c.activeRequests == 0
+	c.requestsMutex.Unlock()
+	if idle {
+		c.Close()
+	}
+}
+
+func (c *client) startRequest() {
+	c.requestsMutex.Lock()
+	c.activeRequests++
+	c.requestsMutex.Unlock()
+}
+
+func (c *client) finishRequest() {
+	c.requestsMutex.Lock()
+	c.activeRequests--
+	idle := c.closing && c.activeRequests == 0
+	c.requestsMutex.Unlock()
+	if idle {
+		c.Close()
+	}
+}
+
+// coalesce checks if requests for authority can be sent on this connection, see Section 3.3 of RFC 9114.
+// This is the case if the connection was established to one of the IP addresses that authority resolves to,
+// on the same port, and if the server's certificate is valid for the host.
+// If so, requests for authority are accepted by this client from now on.
+func (c *client) coalesce(authority string, ips []net.IPAddr) bool {
+	host, port, err := net.SplitHostPort(authority)
+	if err != nil {
+		return false
+	}
+	if _, ok := c.goAwayStreamID(); ok {
+		return false
+	}
+
+	c.coalesceMutex.Lock()
+	defer c.coalesceMutex.Unlock()
+
+	if c.handshakeConn == nil || c.handshakeConn.Context().Err() != nil {
+		return false
+	}
+	addr, ok := c.handshakeConn.RemoteAddr().(*net.UDPAddr)
+	if !ok || strconv.Itoa(addr.Port) != port {
+		return false
+	}
+	var sameIP bool
+	for _, ip := range ips {
+		if ip.IP.Equal(addr.IP) {
+			sameIP = true
+			break
+		}
+	}
+	if !sameIP {
+		return false
+	}
+	certs := c.handshakeConn.ConnectionState().TLS.PeerCertificates
+	if len(certs) == 0 || certs[0].VerifyHostname(host) != nil {
+		return false
+	}
+	if c.authorities == nil {
+		c.authorities = make(map[string]struct{})
+	}
+	c.authorities[authority] = struct{}{}
+	return true
+}
+
+func (c *client) isCoalesced(authority string) bool {
+	c.coalesceMutex.Lock()
+	defer c.coalesceMutex.Unlock()
+	_, ok := c.authorities[authority]
+	return ok
+}
+
+// usesConn says if this client sends requests on conn.
+// This is only known once the handshake has completed.
+func (c *client) usesConn(conn quic.EarlyConnection) bool {
+	c.coalesceMutex.Lock()
+	defer c.coalesceMutex.Unlock()
+	return c.handshakeConn == conn
+}
+
 func (c *client) Close() error {
 	if c.conn == nil {
 		return nil
@@ -230,6 +470,23 @@ func (c *client) Close() error {
 	return c.conn.CloseWithError(quic.ApplicationErrorCode(errorNoError), "")
 }
 
+func (o *roundTripperOpts) qpackMaxTableCapacity() uint64 {
+	if o.QPACKMaxTableCapacity <= 0 {
+		return 0
+	}
+	return uint64(o.QPACKMaxTableCapacity)
+}
+
+func (o *roundTripperOpts) qpackBlockedStreams() uint64 {
+	if o.qpackMaxTableCapacity() == 0 || o.QPACKBlockedStreams < 0 {
+		return 0
+	}
+	if o.QPACKBlockedStreams == 0 {
+		return defaultQPACKBlockedStreams
+	}
+	return uint64(o.QPACKBlockedStreams)
+}
+
 func (c *client) maxHeaderBytes() uint64 {
 	if c.opts.MaxHeaderBytes <= 0 {
 		return defaultMaxResponseHeaderBytes
@@ -239,11 +496,18 @@ func (c *client) maxHeaderBytes() uint64 {
 
 // RoundTripOpt executes a request and returns a response
 func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Response, error) {
-	if authorityAddr("https", hostnameFromRequest(req)) != c.hostname {
+	if authority := authorityAddr("https", hostnameFromRequest(req)); c.hostname != "" && authority != c.hostname && !c.isCoalesced(authority) {
 		return nil, fmt.Errorf("http3 client BUG: RoundTripOpt called for the wrong client (expected %s, got %s)", c.hostname, req.Host)
 	}
 
+	trace := httptrace.ContextClientTrace(req.Context())
+	if trace != nil && trace.GetConn != nil {
+		trace.GetConn(authorityAddr("https", hostnameFromRequest(req)))
+	}
+
+	var dialed bool
 	c.dialOnce.Do(func() {
+		dialed = true
 		c.handshakeErr = c.dial(req.Context())
 	})
 
@@ -261,19 +525,45 @@ func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Respon
 		case <-req.Context().Done():
 			return nil, req.Context().Err()
 		}
+		c.coalesceMutex.Lock()
+		c.handshakeConn = c.conn
+		c.coalesceMutex.Unlock()
+	}
+	if trace != nil {
+		// For 0-RTT requests, the connection can be used before the handshake completes.
+		if dialed && c.hostname != "" && trace.ConnectDone != nil {
+			trace.ConnectDone("udp", c.conn.RemoteAddr().String(), nil)
+		}
+		if trace.GotConn != nil {
+			trace.GotConn(httptrace.GotConnInfo{Conn: &traceConn{conn: c.conn}, Reused: !dialed || c.hostname == ""})
+		}
 	}
 
+	if _, ok := c.goAwayStreamID(); ok {
+		return nil, ErrGoAway
+	}
 	str, err := c.conn.OpenStreamSync(req.Context())
 	if err != nil {
 		return nil, err
 	}
+	// The GOAWAY might have been received while we were waiting for the stream to be opened.
+	if id, ok := c.goAwayStreamID(); ok && str.StreamID() >= id {
+		str.CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
+		str.CancelRead(quic.StreamErrorCode(errorRequestCanceled))
+		return nil, ErrGoAway
+	}
+	if stats := requestStatsFromContext(req.Context()); stats != nil {
+		str = &countingStream{Stream: str, stats: stats}
+	}
 
 	// Request Cancellation:
 	// This go routine keeps running even after RoundTripOpt() returns.
 	// It is shut down when the application is done processing the body.
 	reqDone := make(chan struct{})
 	done := make(chan struct{})
+	c.startRequest()
 	go func() {
+		defer c.finishRequest()
 		defer close(done)
 		select {
 		case <-req.Context().Done():
@@ -283,14 +573,23 @@ func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Respon
 		}
 	}()
 
+	// Register the stream before sending the request, so that we don't miss any datagrams sent by the server.
+	var datagrams *streamDatagrams
+	if c.datagrams != nil {
+		datagrams = c.datagrams.register(str.StreamID())
+	}
+
 	doneChan := reqDone
 	if opt.DontCloseRequestStream {
 		doneChan = nil
 	}
-	rsp, rerr := c.doRequest(req, str, opt, doneChan)
+	rsp, rerr := c.doRequest(req, str, opt, doneChan, datagrams)
 	if rerr.err != nil { // if any error occurred
 		close(reqDone)
 		<-done
+		if datagrams != nil {
+			c.datagrams.unregister(datagrams)
+		}
 		if rerr.streamErr != 0 { // if it was a stream error
 			str.CancelWrite(quic.StreamErrorCode(rerr.streamErr))
 		}
@@ -301,6 +600,9 @@ func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Respon
 			}
 			c.conn.CloseWithError(quic.ApplicationErrorCode(rerr.connErr), reason)
 		}
+		if c.rejectedAfterGoAway(str, rerr.err) {
+			return nil, ErrGoAway
+		}
 	}
 	if opt.DontCloseRequestStream {
 		close(reqDone)
@@ -309,6 +611,69 @@ func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Respon
 	return rsp, rerr.err
 }
 
+// rejectedAfterGoAway says if the server rejected the request since it was sent after the GOAWAY
+func (c *client) rejectedAfterGoAway(str quic.Stream, err error) bool {
+	var serr *quic.StreamError
+	if !errors.As(err, &serr) || serr.ErrorCode != quic.StreamErrorCode(errorRequestRejected) {
+		return false
+	}
+	id, ok := c.goAwayStreamID()
+	return ok && str.StreamID() >= id
+}
+
+// readResponseHeaders reads and decodes the next HEADERS frame on the request stream
+func (c *client) readResponseHeaders(req *http.Request, str quic.Stream) ([]qpack.HeaderField, requestError) {
+	frame, err := parseNextFrame(str, nil)
+	if err != nil {
+		return nil, newStreamError(errorFrameError, err)
+	}
+	hf, ok := frame.(*headersFrame)
+	if !ok {
+		return nil, newConnError(errorFrameUnexpected, errors.New("expected first frame to be a HEADERS frame"))
+	}
+	if hf.Length > c.maxHeaderBytes() {
+		return nil, newStreamError(errorFrameError, fmt.Errorf("HEADERS frame too large: %d bytes (max: %d)", hf.Length, c.maxHeaderBytes()))
+	}
+	headerBlock := make([]byte, hf.Length)
+	if _, err := io.ReadFull(str, headerBlock); err != nil {
+		return nil, newStreamError(errorRequestIncomplete, err)
+	}
+	hfs, err := c.decoder.decode(req.Context(), str.StreamID(), headerBlock)
+	if err != nil {
+		var qerr qpackError
+		if errors.As(err, &qerr) {
+			return nil, newConnError(errorQPACKDecompressionFailed, err)
+		}
+		return nil, newStreamError(errorRequestCanceled, err)
+	}
+	return hfs, requestError{}
+}
+
+// newResponse creates the http.Response from the decoded response header
+func (c *client) newResponse(hfs []qpack.HeaderField) (*http.Response, requestError) {
+	connState := qtls.ToTLSConnectionState(c.conn.ConnectionState().TLS)
+	res := &http.Response{
+		Proto:      "HTTP/3.0",
+		ProtoMajor: 3,
+		Header:     http.Header{},
+		TLS:        &connState,
+	}
+	for _, hf := range hfs {
+		switch hf.Name {
+		case ":status":
+			status, err := strconv.Atoi(hf.Value)
+			if err != nil {
+				return nil, newStreamError(errorGeneralProtocolError, errors.New("malformed non-numeric status pseudo header"))
+			}
+			res.StatusCode = status
+			res.Status = hf.Value + " " + http.StatusText(status)
+		default:
+			res.Header.Add(hf.Name, hf.Value)
+		}
+	}
+	return res, requestError{}
+}
+
 func (c *client) sendRequestBody(str Stream, body io.ReadCloser) error {
 	defer body.Close()
 	b := make([]byte, bodyCopyBufferSize)
@@ -336,14 +701,21 @@ func (c *client) sendRequestBody(str Stream, body io.ReadCloser) error {
 	return nil
 }
 
-func (c *client) doRequest(req *http.Request, str quic.Stream, opt RoundTripOpt, reqDone chan<- struct{}) (*http.Response, requestError) {
+func (c *client) doRequest(req *http.Request, str quic.Stream, opt RoundTripOpt, reqDone chan<- struct{}, datagrams *streamDatagrams) (*http.Response, requestError) {
 	var requestGzip bool
 	if !c.opts.DisableCompression && req.Method != "HEAD" && req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
 		requestGzip = true
 	}
+	trace := httptrace.ContextClientTrace(req.Context())
 	if err := c.requestWriter.WriteRequestHeader(str, req, requestGzip); err != nil {
+		if trace != nil && trace.WroteRequest != nil {
+			trace.WroteRequest(httptrace.WroteRequestInfo{Err: err})
+		}
 		return nil, newStreamError(errorInternalError, err)
 	}
+	if trace != nil && trace.WroteHeaders != nil {
+		trace.WroteHeaders()
+	}
 
 	if req.Body == nil && !opt.DontCloseRequestStream {
 		str.Close()
@@ -353,57 +725,92 @@ func (c *client) doRequest(req *http.Request, str quic.Stream, opt RoundTripOpt,
 	if req.Body != nil {
 		// send the request body asynchronously
 		go func() {
-			if err := c.sendRequestBody(hstr, req.Body); err != nil {
+			err := c.sendRequestBody(hstr, req.Body)
+			if err != nil {
 				c.logger.Errorf("Error writing request: %s", err)
 			}
+			if trace != nil && trace.WroteRequest != nil {
+				trace.WroteRequest(httptrace.WroteRequestInfo{Err: err})
+			}
 			if !opt.DontCloseRequestStream {
 				hstr.Close()
 			}
 		}()
+	} else if trace != nil && trace.WroteRequest != nil {
+		trace.WroteRequest(httptrace.WroteRequestInfo{})
 	}
 
-	frame, err := parseNextFrame(str, nil)
-	if err != nil {
-		return nil, newStreamError(errorFrameError, err)
-	}
-	hf, ok := frame.(*headersFrame)
-	if !ok {
-		return nil, newConnError(errorFrameUnexpected, errors.New("expected first frame to be a HEADERS frame"))
-	}
-	if hf.Length > c.maxHeaderBytes() {
-		return nil, newStreamError(errorFrameError, fmt.Errorf("HEADERS frame too large: %d bytes (max: %d)", hf.Length, c.maxHeaderBytes()))
-	}
-	headerBlock := make([]byte, hf.Length)
-	if _, err := io.ReadFull(str, headerBlock); err != nil {
-		return nil, newStreamError(errorRequestIncomplete, err)
-	}
-	hfs, err := c.decoder.DecodeFull(headerBlock)
-	if err != nil {
-		// TODO: use the right error code
-		return nil, newConnError(errorGeneralProtocolError, err)
+	var res *http.Response
+	for num1xx := 0; ; num1xx++ {
+		hfs, rerr := c.readResponseHeaders(req, str)
+		if rerr.err != nil {
+			return nil, rerr
+		}
+		if num1xx == 0 && trace != nil && trace.GotFirstResponseByte != nil {
+			trace.GotFirstResponseByte()
+		}
+		res, rerr = c.newResponse(hfs)
+		if rerr.err != nil {
+			return nil, rerr
+		}
+		// Interim responses (1xx) are followed by the final response, see Section 4.1 of RFC 9114.
+		if res.StatusCode < 100 || res.StatusCode >= 200 {
+			break
+		}
+		if res.StatusCode == http.StatusSwitchingProtocols {
+			return nil, newStreamError(errorMessageError, errors.New("received 101 response"))
+		}
+		if num1xx >= max1xxResponses {
+			return nil, newStreamError(errorExcessiveLoad, errors.New("too many 1xx informational responses"))
+		}
+		if trace != nil && trace.Got1xxResponse != nil {
+			if err := trace.Got1xxResponse(res.StatusCode, textproto.MIMEHeader(res.Header)); err != nil {
+				return nil, newStreamError(errorRequestCanceled, err)
+			}
+		}
 	}
-
-	connState := qtls.ToTLSConnectionState(c.conn.ConnectionState().TLS)
-	res := &http.Response{
-		Proto:      "HTTP/3.0",
-		ProtoMajor: 3,
-		Header:     http.Header{},
-		TLS:        &connState,
+	// Trailers declared using the Trailer header are added to the Response.Trailer with a nil value,
+	// and set once the trailer is received, see http.Response for details.
+	if declared, ok := res.Header["Trailer"]; ok {
+		res.Trailer = make(http.Header)
+		for _, v := range declared {
+			for _, k := range strings.Split(v, ",") {
+				if k = http.CanonicalHeaderKey(strings.TrimSpace(k)); k != "" {
+					res.Trailer[k] = nil
+				}
+			}
+		}
+		res.Header.Del("Trailer")
 	}
-	for _, hf := range hfs {
-		switch hf.Name {
-		case ":status":
-			status, err := strconv.Atoi(hf.Value)
-			if err != nil {
-				return nil, newStreamError(errorGeneralProtocolError, errors.New("malformed non-numeric status pseudo header"))
+	hstr.parseTrailer = func(r io.Reader, l uint64) error {
+		if l > c.maxHeaderBytes() {
+			return fmt.Errorf("HEADERS frame too large: %d bytes (max: %d)", l, c.maxHeaderBytes())
+		}
+		b := make([]byte, l)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return err
+		}
+		fields, err := c.decoder.decode(req.Context(), str.StreamID(), b)
+		if err != nil {
+			var qerr qpackError
+			if errors.As(err, &qerr) {
+				c.conn.CloseWithError(quic.ApplicationErrorCode(errorQPACKDecompressionFailed), err.Error())
 			}
-			res.StatusCode = status
-			res.Status = hf.Value + " " + http.StatusText(status)
-		default:
-			res.Header.Add(hf.Name, hf.Value)
+			return err
+		}
+		if res.Trailer == nil {
+			res.Trailer = make(http.Header, len(fields))
 		}
+		for _, f := range fields {
+			if f.IsPseudo() {
+				return fmt.Errorf("invalid pseudo header in trailer: %s", f.Name)
+			}
+			res.Trailer.Add(f.Name, f.Value)
+		}
+		return nil
 	}
 	respBody := newResponseBody(hstr, c.conn, reqDone)
+	respBody.datagrams = datagrams
 
 	// Rules for when to set Content-Length are defined in https://tools.ietf.org/html/rfc7230#section-3.3.2.
 	_, hasTransferEncoding := res.Header["Transfer-Encoding"]
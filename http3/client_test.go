@@ -5,15 +5,20 @@ import (
 	"compress/gzip"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"time"
 
 	"github.com/fkwhite/quic-go"
 	mockquic "github.com/fkwhite/quic-go/internal/mocks/quic"
 	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/fkwhite/quic-go/internal/testdata"
 	"github.com/fkwhite/quic-go/internal/utils"
 	"github.com/fkwhite/quic-go/quicvarint"
 
@@ -147,6 +152,17 @@ var _ = Describe("Client", func() {
 		Expect(err).To(MatchError(testErr))
 	})
 
+	It("only uses the QPACK dynamic table if enabled", func() {
+		opts := &roundTripperOpts{}
+		Expect(opts.qpackMaxTableCapacity()).To(BeZero())
+		Expect(opts.qpackBlockedStreams()).To(BeZero())
+		opts.QPACKMaxTableCapacity = 4096
+		Expect(opts.qpackMaxTableCapacity()).To(BeEquivalentTo(4096))
+		Expect(opts.qpackBlockedStreams()).To(BeEquivalentTo(defaultQPACKBlockedStreams))
+		opts.QPACKBlockedStreams = -1
+		Expect(opts.qpackBlockedStreams()).To(BeZero())
+	})
+
 	It("errors when dialing fails", func() {
 		testErr := errors.New("handshake error")
 		client, err := newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
@@ -158,6 +174,26 @@ var _ = Describe("Client", func() {
 		Expect(err).To(MatchError(testErr))
 	})
 
+	It("traces dial errors", func() {
+		testErr := errors.New("dial error")
+		dialAddr = func(context.Context, string, *tls.Config, *quic.Config) (quic.EarlyConnection, error) {
+			return nil, testErr
+		}
+		var connectErr error
+		trace := &httptrace.ClientTrace{
+			ConnectStart: func(network, addr string) {
+				Expect(network).To(Equal("udp"))
+				Expect(addr).To(Equal("quic.clemente.io:1337"))
+			},
+			ConnectDone: func(_, _ string, err error) { connectErr = err },
+		}
+		req, err := http.NewRequest("GET", "https://quic.clemente.io:1337/file.html", nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = client.RoundTripOpt(req.WithContext(httptrace.WithClientTrace(context.Background(), trace)), RoundTripOpt{})
+		Expect(err).To(MatchError(testErr))
+		Expect(connectErr).To(MatchError(testErr))
+	})
+
 	It("closes correctly if connection was not created", func() {
 		client, err := newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
 		Expect(err).ToNot(HaveOccurred())
@@ -479,7 +515,7 @@ var _ = Describe("Client", func() {
 				name = "decoder"
 			}
 
-			It(fmt.Sprintf("ignores the QPACK %s streams", name), func() {
+			It(fmt.Sprintf("closes the connection when the QPACK %s stream is closed", name), func() {
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, streamType)
 				str := mockquic.NewMockStream(mockCtrl)
@@ -492,9 +528,13 @@ var _ = Describe("Client", func() {
 					<-testDone
 					return nil, errors.New("test done")
 				})
+				done := make(chan struct{})
+				conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorClosedCriticalStream), gomock.Any()).Do(func(quic.ApplicationErrorCode, string) {
+					close(done)
+				})
 				_, err := client.RoundTripOpt(req, RoundTripOpt{})
 				Expect(err).To(MatchError("done"))
-				time.Sleep(scaleDuration(20 * time.Millisecond)) // don't EXPECT any calls to str.CancelRead
+				Eventually(done).Should(BeClosed())
 			})
 		}
 
@@ -619,6 +659,217 @@ var _ = Describe("Client", func() {
 		})
 	})
 
+	Context("GOAWAY handling", func() {
+		var (
+			conn                 *mockquic.MockEarlyConnection
+			str                  *mockquic.MockStream
+			controlStrWriter     *io.PipeWriter
+			settingsFrameWritten chan struct{}
+			settingsFrameRead    chan struct{} // closed when the client read the server's SETTINGS frame
+			testDone             chan struct{}
+		)
+
+		sendGoAway := func(id quic.StreamID) {
+			settingsFrameRead, controlStrWriter := settingsFrameRead, controlStrWriter
+			go func() {
+				defer GinkgoRecover()
+				<-settingsFrameRead
+				controlStrWriter.Write((&goAwayFrame{StreamID: id}).Append(nil))
+			}()
+			Eventually(func() bool {
+				_, ok := client.goAwayStreamID()
+				return ok
+			}).Should(BeTrue())
+		}
+
+		BeforeEach(func() {
+			testDone = make(chan struct{})
+			settingsFrameWritten = make(chan struct{})
+			settingsFrameRead = make(chan struct{})
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(settingsFrameWritten) })
+			conn = mockquic.NewMockEarlyConnection(mockCtrl)
+			conn.EXPECT().OpenUniStream().Return(controlStr, nil)
+			conn.EXPECT().HandshakeComplete().Return(handshakeCtx).AnyTimes()
+			var r *io.PipeReader
+			r, controlStrWriter = io.Pipe()
+			serverControlStr := mockquic.NewMockStream(mockCtrl)
+			serverControlStr.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+			conn.EXPECT().AcceptUniStream(gomock.Any()).Return(serverControlStr, nil)
+			// the client's goroutines might still be running when the next test starts
+			done, w, settingsRead := testDone, controlStrWriter, settingsFrameRead
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-done
+				return nil, errors.New("test done")
+			})
+			go func() {
+				defer GinkgoRecover()
+				b := quicvarint.Append(nil, streamTypeControlStream)
+				b = (&settingsFrame{}).Append(b)
+				w.Write(b)
+				close(settingsRead)
+			}()
+			str = mockquic.NewMockStream(mockCtrl)
+			dialAddr = func(context.Context, string, *tls.Config, *quic.Config) (quic.EarlyConnection, error) {
+				return conn, nil
+			}
+			var err error
+			req, err = http.NewRequest("GET", "https://quic.clemente.io:1337/file1.dat", nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			close(testDone)
+			controlStrWriter.Close()
+			Eventually(settingsFrameWritten).Should(BeClosed())
+		})
+
+		It("completes requests sent before the GOAWAY, but doesn't send any new requests", func() {
+			conn.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+			str.EXPECT().StreamID().AnyTimes()
+			conn.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) { return len(p), nil }).AnyTimes()
+			str.EXPECT().Close()
+			rspBuf := &bytes.Buffer{}
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+				if rspBuf.Len() == 0 {
+					// the server sends a GOAWAY while this request is in flight
+					sendGoAway(4)
+					rstr := mockquic.NewMockStream(mockCtrl)
+					rstr.EXPECT().StreamID().AnyTimes()
+					rstr.EXPECT().Write(gomock.Any()).Do(rspBuf.Write).AnyTimes()
+					rw := newResponseWriter(rstr, nil, newQPACKEncoder(0, utils.DefaultLogger), utils.DefaultLogger)
+					rw.WriteHeader(http.StatusTeapot)
+					rw.Flush()
+				}
+				return rspBuf.Read(b)
+			}).AnyTimes()
+			rsp, err := client.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(http.StatusTeapot))
+
+			// don't EXPECT any calls to OpenStreamSync
+			_, err = client.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).To(MatchError(ErrGoAway))
+		})
+
+		It("closes the connection once the requests sent before the GOAWAY completed", func() {
+			conn.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+			str.EXPECT().StreamID().AnyTimes()
+			conn.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) { return len(p), nil }).AnyTimes()
+			str.EXPECT().Close()
+			str.EXPECT().CancelRead(gomock.Any()).AnyTimes()
+			rspBuf := &bytes.Buffer{}
+			rstr := mockquic.NewMockStream(mockCtrl)
+			rstr.EXPECT().StreamID().AnyTimes()
+			rstr.EXPECT().Write(gomock.Any()).Do(rspBuf.Write).AnyTimes()
+			rw := newResponseWriter(rstr, nil, newQPACKEncoder(0, utils.DefaultLogger), utils.DefaultLogger)
+			rw.WriteHeader(http.StatusOK)
+			rw.Flush()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+			rsp, err := client.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).ToNot(HaveOccurred())
+			sendGoAway(4)
+
+			client.closeWhenIdle()
+			// don't EXPECT any calls to CloseWithError while the response body is still open
+			time.Sleep(scaleDuration(20 * time.Millisecond))
+			closed := make(chan struct{})
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "").Do(func(quic.ApplicationErrorCode, string) { close(closed) })
+			Expect(rsp.Body.Close()).To(Succeed())
+			Eventually(closed).Should(BeClosed())
+		})
+
+		It("fails requests that the server rejected after sending a GOAWAY", func() {
+			conn.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+			str.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) { return len(p), nil }).AnyTimes()
+			str.EXPECT().Close()
+			str.EXPECT().CancelWrite(gomock.Any())
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func([]byte) (int, error) {
+				sendGoAway(4)
+				return 0, &quic.StreamError{StreamID: 4, ErrorCode: quic.StreamErrorCode(errorRequestRejected)}
+			})
+			_, err := client.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).To(MatchError(ErrGoAway))
+		})
+
+		It("closes the connection when the GOAWAY contains an invalid stream ID", func() {
+			done := make(chan struct{})
+			conn.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, errors.New("done"))
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorIDError), gomock.Any()).Do(func(quic.ApplicationErrorCode, string) { close(done) })
+			_, err := client.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).To(MatchError("done"))
+			go func() {
+				<-settingsFrameRead
+				controlStrWriter.Write((&goAwayFrame{StreamID: 5}).Append(nil))
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
+	Context("coalescing connections", func() {
+		var conn *mockquic.MockEarlyConnection
+		ips := []net.IPAddr{{IP: net.IPv4(192, 0, 2, 2)}, {IP: net.IPv4(192, 0, 2, 1)}}
+
+		BeforeEach(func() {
+			conn = mockquic.NewMockEarlyConnection(mockCtrl)
+			conn.EXPECT().Context().Return(context.Background()).AnyTimes()
+			conn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1337}).AnyTimes()
+			cert, err := x509.ParseCertificate(testdata.GetTLSConfig().Certificates[0].Certificate[0]) // valid for localhost
+			Expect(err).ToNot(HaveOccurred())
+			var cs quic.ConnectionState
+			cs.TLS.PeerCertificates = []*x509.Certificate{cert}
+			conn.EXPECT().ConnectionState().Return(cs).AnyTimes()
+			client.handshakeConn = conn
+		})
+
+		It("coalesces requests for hosts covered by the certificate", func() {
+			Expect(client.coalesce("localhost:1337", ips)).To(BeTrue())
+			// the client now accepts requests for this host
+			testErr := errors.New("handshake error")
+			dialAddr = func(context.Context, string, *tls.Config, *quic.Config) (quic.EarlyConnection, error) {
+				return nil, testErr
+			}
+			_, err := client.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).To(MatchError(testErr))
+		})
+
+		It("doesn't coalesce requests if the host doesn't resolve to the IP address", func() {
+			Expect(client.coalesce("localhost:1337", ips[:1])).To(BeFalse())
+		})
+
+		It("doesn't coalesce requests for a different port", func() {
+			Expect(client.coalesce("localhost:1338", ips)).To(BeFalse())
+		})
+
+		It("doesn't coalesce requests for hosts not covered by the certificate", func() {
+			Expect(client.coalesce("example.org:1337", ips)).To(BeFalse())
+			_, err := client.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).To(MatchError(ContainSubstring("RoundTripOpt called for the wrong client")))
+		})
+
+		It("doesn't coalesce requests before the handshake completed", func() {
+			client.handshakeConn = nil
+			Expect(client.coalesce("localhost:1337", ips)).To(BeFalse())
+		})
+
+		It("doesn't coalesce requests after receiving a GOAWAY", func() {
+			Expect(client.handleGoAway(4)).To(Succeed())
+			Expect(client.coalesce("localhost:1337", ips)).To(BeFalse())
+		})
+
+		It("doesn't coalesce requests on a closed connection", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			closedConn := mockquic.NewMockEarlyConnection(mockCtrl)
+			closedConn.EXPECT().Context().Return(ctx)
+			client.handshakeConn = closedConn
+			Expect(client.coalesce("localhost:1337", ips)).To(BeFalse())
+		})
+	})
+
 	Context("Doing requests", func() {
 		var (
 			req                  *http.Request
@@ -662,8 +913,9 @@ var _ = Describe("Client", func() {
 		getResponse := func(status int) []byte {
 			buf := &bytes.Buffer{}
 			rstr := mockquic.NewMockStream(mockCtrl)
+			rstr.EXPECT().StreamID().AnyTimes()
 			rstr.EXPECT().Write(gomock.Any()).Do(buf.Write).AnyTimes()
-			rw := newResponseWriter(rstr, nil, utils.DefaultLogger)
+			rw := newResponseWriter(rstr, nil, newQPACKEncoder(0, utils.DefaultLogger), utils.DefaultLogger)
 			rw.WriteHeader(status)
 			rw.Flush()
 			return buf.Bytes()
@@ -681,6 +933,7 @@ var _ = Describe("Client", func() {
 				close(settingsFrameWritten)
 			}) // SETTINGS frame
 			str = mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().AnyTimes()
 			conn = mockquic.NewMockEarlyConnection(mockCtrl)
 			conn.EXPECT().OpenUniStream().Return(controlStr, nil)
 			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
@@ -743,6 +996,176 @@ var _ = Describe("Client", func() {
 			Expect(rsp.StatusCode).To(Equal(418))
 		})
 
+		It("counts the bytes sent and received on the request stream", func() {
+			rsp := getResponse(200)
+			rspBuf := bytes.NewBuffer(rsp)
+			conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			conn.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+			conn.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+			var sent int
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) {
+				sent += len(p)
+				return len(p), nil
+			})
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+			var stats RequestStats
+			_, err := client.RoundTripOpt(req.WithContext(WithRequestStats(context.Background(), &stats)), RoundTripOpt{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sent).ToNot(BeZero())
+			Expect(stats.BytesSent()).To(BeEquivalentTo(sent))
+			Expect(stats.BytesReceived()).To(BeEquivalentTo(len(rsp) - rspBuf.Len()))
+			Expect(stats.BytesReceived()).ToNot(BeZero())
+		})
+
+		It("traces the request", func() {
+			origLookupIPAddr := lookupIPAddr
+			defer func() { lookupIPAddr = origLookupIPAddr }()
+			lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+				Expect(host).To(Equal("quic.clemente.io"))
+				return []net.IPAddr{{IP: net.ParseIP("2001:db8::1")}, {IP: net.IPv4(192, 0, 2, 1)}}, nil
+			}
+			dialAddr = func(_ context.Context, addr string, tlsConf *tls.Config, _ *quic.Config) (quic.EarlyConnection, error) {
+				Expect(addr).To(Equal("192.0.2.1:1337"))
+				Expect(tlsConf.ServerName).To(Equal("quic.clemente.io"))
+				return conn, nil
+			}
+			rspBuf := bytes.NewBuffer(getResponse(200))
+			conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			conn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1337}).Times(2)
+			conn.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+			conn.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+
+			var events []string
+			trace := &httptrace.ClientTrace{
+				GetConn:  func(hostPort string) { events = append(events, "GetConn "+hostPort) },
+				DNSStart: func(info httptrace.DNSStartInfo) { events = append(events, "DNSStart "+info.Host) },
+				DNSDone: func(info httptrace.DNSDoneInfo) {
+					Expect(info.Err).ToNot(HaveOccurred())
+					Expect(info.Addrs).To(HaveLen(2))
+					events = append(events, "DNSDone")
+				},
+				ConnectStart: func(network, addr string) { events = append(events, "ConnectStart "+network+" "+addr) },
+				ConnectDone: func(network, addr string, err error) {
+					Expect(err).ToNot(HaveOccurred())
+					events = append(events, "ConnectDone "+network+" "+addr)
+				},
+				GotConn: func(info httptrace.GotConnInfo) {
+					Expect(info.Reused).To(BeFalse())
+					Expect(info.Conn.RemoteAddr()).To(Equal(&net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1337}))
+					events = append(events, "GotConn")
+				},
+				WroteHeaders: func() { events = append(events, "WroteHeaders") },
+				WroteRequest: func(info httptrace.WroteRequestInfo) {
+					Expect(info.Err).ToNot(HaveOccurred())
+					events = append(events, "WroteRequest")
+				},
+				GotFirstResponseByte: func() { events = append(events, "GotFirstResponseByte") },
+			}
+			rsp, err := client.RoundTripOpt(req.WithContext(httptrace.WithClientTrace(context.Background(), trace)), RoundTripOpt{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(200))
+			Expect(events).To(Equal([]string{
+				"GetConn quic.clemente.io:1337",
+				"DNSStart quic.clemente.io",
+				"DNSDone",
+				"ConnectStart udp 192.0.2.1:1337",
+				"ConnectDone udp 192.0.2.1:1337",
+				"GotConn",
+				"WroteHeaders",
+				"WroteRequest",
+				"GotFirstResponseByte",
+			}))
+		})
+
+		Context("interim responses", func() {
+			getInterimResponse := func(status int, header http.Header) []byte {
+				buf := &bytes.Buffer{}
+				rstr := mockquic.NewMockStream(mockCtrl)
+				rstr.EXPECT().StreamID().AnyTimes()
+				rstr.EXPECT().Write(gomock.Any()).Do(buf.Write).AnyTimes()
+				rw := newResponseWriter(rstr, nil, newQPACKEncoder(0, utils.DefaultLogger), utils.DefaultLogger)
+				for k, v := range header {
+					rw.Header()[k] = v
+				}
+				rw.WriteHeader(status)
+				return buf.Bytes()
+			}
+
+			BeforeEach(func() {
+				conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				conn.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+				conn.EXPECT().ConnectionState().Return(quic.ConnectionState{}).AnyTimes()
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			})
+
+			It("reports interim responses, and returns the final response", func() {
+				rspBuf := bytes.NewBuffer(getInterimResponse(103, http.Header{"Link": {"</style.css>; rel=preload"}}))
+				rspBuf.Write(getInterimResponse(100, nil))
+				rspBuf.Write(getResponse(200))
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				var codes []int
+				var headers []textproto.MIMEHeader
+				trace := &httptrace.ClientTrace{
+					Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+						codes = append(codes, code)
+						headers = append(headers, header)
+						return nil
+					},
+				}
+				rsp, err := client.RoundTripOpt(req.WithContext(httptrace.WithClientTrace(context.Background(), trace)), RoundTripOpt{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+				Expect(rsp.Header).ToNot(HaveKey("Link"))
+				Expect(codes).To(Equal([]int{103, 100}))
+				Expect(headers[0].Get("Link")).To(Equal("</style.css>; rel=preload"))
+			})
+
+			It("aborts the request if the callback returns an error", func() {
+				rspBuf := bytes.NewBuffer(getInterimResponse(103, nil))
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
+				closed := make(chan struct{})
+				str.EXPECT().Close().Do(func() { close(closed) })
+				trace := &httptrace.ClientTrace{
+					Got1xxResponse: func(int, textproto.MIMEHeader) error { return errors.New("not interested") },
+				}
+				_, err := client.RoundTripOpt(req.WithContext(httptrace.WithClientTrace(context.Background(), trace)), RoundTripOpt{})
+				Expect(err).To(MatchError("not interested"))
+				Eventually(closed).Should(BeClosed())
+			})
+
+			It("rejects 101 responses", func() {
+				rspBuf := bytes.NewBuffer(getInterimResponse(101, nil))
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(errorMessageError))
+				closed := make(chan struct{})
+				str.EXPECT().Close().Do(func() { close(closed) })
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError("received 101 response"))
+				Eventually(closed).Should(BeClosed())
+			})
+
+			It("limits the number of interim responses", func() {
+				rspBuf := &bytes.Buffer{}
+				for i := 0; i <= max1xxResponses; i++ {
+					rspBuf.Write(getInterimResponse(103, nil))
+				}
+				rspBuf.Write(getResponse(200))
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(errorExcessiveLoad))
+				closed := make(chan struct{})
+				str.EXPECT().Close().Do(func() { close(closed) })
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError("too many 1xx informational responses"))
+				Eventually(closed).Should(BeClosed())
+			})
+		})
+
 		Context("requests containing a Body", func() {
 			var strBuf *bytes.Buffer
 
@@ -973,8 +1396,9 @@ var _ = Describe("Client", func() {
 				conn.EXPECT().ConnectionState().Return(quic.ConnectionState{})
 				buf := &bytes.Buffer{}
 				rstr := mockquic.NewMockStream(mockCtrl)
+				rstr.EXPECT().StreamID().AnyTimes()
 				rstr.EXPECT().Write(gomock.Any()).Do(buf.Write).AnyTimes()
-				rw := newResponseWriter(rstr, nil, utils.DefaultLogger)
+				rw := newResponseWriter(rstr, nil, newQPACKEncoder(0, utils.DefaultLogger), utils.DefaultLogger)
 				rw.Header().Set("Content-Encoding", "gzip")
 				gz := gzip.NewWriter(rw)
 				gz.Write([]byte("gzipped response"))
@@ -999,8 +1423,9 @@ var _ = Describe("Client", func() {
 				conn.EXPECT().ConnectionState().Return(quic.ConnectionState{})
 				buf := &bytes.Buffer{}
 				rstr := mockquic.NewMockStream(mockCtrl)
+				rstr.EXPECT().StreamID().AnyTimes()
 				rstr.EXPECT().Write(gomock.Any()).Do(buf.Write).AnyTimes()
-				rw := newResponseWriter(rstr, nil, utils.DefaultLogger)
+				rw := newResponseWriter(rstr, nil, newQPACKEncoder(0, utils.DefaultLogger), utils.DefaultLogger)
 				rw.Write([]byte("not gzipped"))
 				rw.Flush()
 				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
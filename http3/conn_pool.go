@@ -0,0 +1,151 @@
+package http3
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+
+	"github.com/fkwhite/quic-go"
+)
+
+// A ConnPool is a pool of HTTP/3 connections, keyed by the host they were dialed for.
+// It can be shared by multiple RoundTrippers, so that they don't dial duplicate connections to the same host.
+// Connections are only shared between RoundTrippers that use the same TLS and QUIC configuration
+// (i.e. the same *tls.Config and *quic.Config).
+// Connections that were coalesced for a different host are not used by RoundTrippers
+// that set DisableConnectionCoalescing.
+// All other options (e.g. Dial, EnableDatagrams and the QPACK settings) are taken from the RoundTripper
+// that first sends a request to a host, so RoundTrippers sharing a ConnPool should use the same options.
+// The zero value is an empty pool ready to use.
+type ConnPool struct {
+	mutex   sync.Mutex
+	clients map[connPoolKey]roundTripCloser
+}
+
+// connPoolKey identifies a connection in a ConnPool
+type connPoolKey struct {
+	authority string
+	tlsConf   *tls.Config
+	quicConf  *quic.Config
+	// coalesced is set for connections that were dialed for a different host
+	coalesced bool
+}
+
+// sameConfig says if two keys use the same TLS and QUIC configuration
+func (k connPoolKey) sameConfig(other connPoolKey) bool {
+	return k.tlsConf == other.tlsConf && k.quicConf == other.quicConf
+}
+
+// getClient returns the client for key.
+// If there's none, it tries to coalesce the request onto an existing connection, if ips is not empty.
+// Coalesced clients are only used if allowCoalescing is set.
+// Otherwise, newClient is called to create a new client, unless onlyCached is set.
+func (p *ConnPool) getClient(key connPoolKey, ips []net.IPAddr, allowCoalescing, onlyCached bool, newClient func() (roundTripCloser, error)) (roundTripCloser, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.clients == nil {
+		p.clients = make(map[connPoolKey]roundTripCloser)
+	}
+
+	client, ok := p.clients[key]
+	if !ok && allowCoalescing {
+		coalescedKey := key
+		coalescedKey.coalesced = true
+		client, ok = p.clients[coalescedKey]
+		if !ok && len(ips) > 0 {
+			for k, cl := range p.clients {
+				if k.sameConfig(key) && cl.coalesce(key.authority, ips) {
+					client = cl
+					ok = true
+					p.clients[coalescedKey] = cl
+					break
+				}
+			}
+		}
+	}
+	if !ok {
+		if onlyCached {
+			return nil, ErrNoCachedConn
+		}
+		var err error
+		client, err = newClient()
+		if err != nil {
+			return nil, err
+		}
+		p.clients[key] = client
+	}
+	return client, nil
+}
+
+// canCoalesce says if there's no client for key yet,
+// but it might be possible to reuse the connection of another client using the same configuration
+func (p *ConnPool) canCoalesce(key connPoolKey) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	coalescedKey := key
+	coalescedKey.coalesced = true
+	if _, ok := p.clients[key]; ok {
+		return false
+	}
+	if _, ok := p.clients[coalescedKey]; ok {
+		return false
+	}
+	for k := range p.clients {
+		if k.sameConfig(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// usesConn says if any client in the pool sends requests on conn
+func (p *ConnPool) usesConn(conn quic.EarlyConnection) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, cl := range p.clients {
+		if cl.usesConn(conn) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeClient removes a client that shouldn't be used for new requests any more,
+// for all hosts that it was used for, and closes it once the requests in flight have completed.
+func (p *ConnPool) removeClient(cl roundTripCloser) {
+	p.mutex.Lock()
+	var found bool
+	// coalesced connections are used for multiple hosts
+	for k, c := range p.clients {
+		if c == cl {
+			delete(p.clients, k)
+			found = true
+		}
+	}
+	p.mutex.Unlock()
+	if found {
+		cl.closeWhenIdle()
+	}
+}
+
+// Close closes all connections in the pool.
+// The pool can be used again afterwards, new connections are dialed as needed.
+func (p *ConnPool) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	closed := make(map[roundTripCloser]struct{}, len(p.clients))
+	for _, client := range p.clients {
+		// coalesced connections are used for multiple hosts
+		if _, ok := closed[client]; ok {
+			continue
+		}
+		closed[client] = struct{}{}
+		if err := client.Close(); err != nil {
+			return err
+		}
+	}
+	p.clients = nil
+	return nil
+}
@@ -0,0 +1,181 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/fkwhite/quic-go"
+	"github.com/fkwhite/quic-go/internal/utils"
+	"github.com/fkwhite/quic-go/quicvarint"
+)
+
+// A Datagrammer allows sending and receiving HTTP/3 datagrams (RFC 9297) that are associated with a request stream.
+// The interface is implemented by:
+// * for the server: the http.Request.Body
+// * for the client: the http.Response.Body
+// Datagrams can only be used after support for HTTP/3 datagrams was negotiated,
+// i.e. both endpoints enabled datagrams in their SETTINGS and on the QUIC layer.
+type Datagrammer interface {
+	// SendDatagram sends a datagram associated with the request stream.
+	SendDatagram([]byte) error
+	// ReceiveDatagram receives a datagram associated with the request stream.
+	// It blocks until a datagram is received, the context is canceled,
+	// or datagrams are not received on this stream any longer.
+	// The first call starts receiving datagrams on the QUIC connection.
+	// From then on, all datagrams returned by the connection's ReceiveMessage are consumed by the HTTP/3 layer,
+	// and the application must not call ReceiveMessage itself.
+	ReceiveDatagram(context.Context) ([]byte, error)
+}
+
+// maxQuarterStreamID is the maximum value of the Quarter Stream ID, see section 2.1 of RFC 9297.
+const maxQuarterStreamID = 1<<60 - 1
+
+// streamDatagramQueueLen is the number of datagrams that are queued per request stream.
+// If the application doesn't receive the datagrams fast enough, additional datagrams are dropped.
+const streamDatagramQueueLen = 32
+
+var (
+	errDatagramsNotEnabled    = errors.New("HTTP/3 datagrams not enabled")
+	errDatagramsNotNegotiated = errors.New("HTTP/3 datagrams not negotiated")
+	errDatagramStreamClosed   = errors.New("request stream not receiving datagrams any more")
+)
+
+// The datagramMux demultiplexes HTTP/3 datagrams received on a QUIC connection,
+// and passes them to the request stream they're associated with.
+// It only starts receiving datagrams once the application calls ReceiveDatagram on one of the streams.
+// From that point on, it consumes all datagrams returned by the connection's ReceiveMessage.
+type datagramMux struct {
+	conn   quic.Connection
+	logger utils.Logger
+
+	mutex      sync.Mutex
+	negotiated bool // set once the peer's SETTINGS were received
+	receiving  bool // set once the run loop was started
+	streams    map[quic.StreamID]*streamDatagrams
+}
+
+func newDatagramMux(conn quic.Connection, logger utils.Logger) *datagramMux {
+	return &datagramMux{
+		conn:    conn,
+		logger:  logger,
+		streams: make(map[quic.StreamID]*streamDatagrams),
+	}
+}
+
+// setNegotiated is called once HTTP/3 datagram support was negotiated on both the HTTP/3 and the QUIC layer.
+func (m *datagramMux) setNegotiated() {
+	m.mutex.Lock()
+	m.negotiated = true
+	m.mutex.Unlock()
+}
+
+// startReceiving starts the loop that receives datagrams from the QUIC connection, if it's not running yet.
+func (m *datagramMux) startReceiving() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.receiving {
+		return
+	}
+	m.receiving = true
+	go m.run()
+}
+
+func (m *datagramMux) canSend() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.negotiated
+}
+
+func (m *datagramMux) run() {
+	for {
+		data, err := m.conn.ReceiveMessage()
+		if err != nil {
+			m.logger.Debugf("Receiving datagrams failed: %s", err)
+			return
+		}
+		r := bytes.NewReader(data)
+		quarterStreamID, err := quicvarint.Read(r)
+		if err != nil || quarterStreamID > maxQuarterStreamID {
+			m.conn.CloseWithError(quic.ApplicationErrorCode(errorDatagramError), "invalid Quarter Stream ID")
+			return
+		}
+		id := quic.StreamID(4 * quarterStreamID)
+		m.mutex.Lock()
+		str, ok := m.streams[id]
+		m.mutex.Unlock()
+		if !ok {
+			// The stream might not have been opened yet, or it might already be closed.
+			m.logger.Debugf("Dropping datagram for unknown stream %d", id)
+			continue
+		}
+		str.enqueue(data[len(data)-r.Len():])
+	}
+}
+
+func (m *datagramMux) register(id quic.StreamID) *streamDatagrams {
+	str := &streamDatagrams{
+		id:     id,
+		mux:    m,
+		queue:  make(chan []byte, streamDatagramQueueLen),
+		closed: make(chan struct{}),
+	}
+	m.mutex.Lock()
+	m.streams[id] = str
+	m.mutex.Unlock()
+	return str
+}
+
+func (m *datagramMux) unregister(str *streamDatagrams) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.streams[str.id] != str {
+		return
+	}
+	delete(m.streams, str.id)
+	close(str.closed)
+}
+
+// streamDatagrams sends and receives the datagrams associated with a single request stream.
+type streamDatagrams struct {
+	id  quic.StreamID
+	mux *datagramMux
+
+	queue  chan []byte
+	closed chan struct{}
+}
+
+var _ Datagrammer = &streamDatagrams{}
+
+func (s *streamDatagrams) SendDatagram(b []byte) error {
+	if !s.mux.canSend() {
+		return errDatagramsNotNegotiated
+	}
+	data := make([]byte, 0, int(quicvarint.Len(uint64(s.id/4)))+len(b))
+	data = quicvarint.Append(data, uint64(s.id/4))
+	data = append(data, b...)
+	return s.mux.conn.SendMessage(data)
+}
+
+func (s *streamDatagrams) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	s.mux.startReceiving()
+	select {
+	case data := <-s.queue:
+		return data, nil
+	case <-s.closed:
+		return nil, errDatagramStreamClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *streamDatagrams) enqueue(data []byte) {
+	select {
+	case s.queue <- data:
+	default:
+		s.mux.logger.Debugf("Dropping datagram for stream %d, since the queue is full", s.id)
+	}
+}
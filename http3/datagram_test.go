@@ -0,0 +1,144 @@
+package http3
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fkwhite/quic-go"
+	mockquic "github.com/fkwhite/quic-go/internal/mocks/quic"
+	"github.com/fkwhite/quic-go/internal/utils"
+	"github.com/fkwhite/quic-go/quicvarint"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Datagrams", func() {
+	var (
+		conn *mockquic.MockEarlyConnection
+		mux  *datagramMux
+	)
+
+	BeforeEach(func() {
+		conn = mockquic.NewMockEarlyConnection(mockCtrl)
+		mux = newDatagramMux(conn, utils.DefaultLogger)
+	})
+
+	// receiveDatagrams makes the connection return the datagrams, and blocks afterwards until the test is done
+	receiveDatagrams := func(datagrams ...[]byte) chan<- struct{} {
+		done := make(chan struct{})
+		for _, d := range datagrams {
+			conn.EXPECT().ReceiveMessage().Return(d, nil)
+		}
+		conn.EXPECT().ReceiveMessage().DoAndReturn(func() ([]byte, error) {
+			<-done
+			return nil, errors.New("test done")
+		}).MaxTimes(1)
+		return done
+	}
+
+	It("refuses to send datagrams before datagram support was negotiated", func() {
+		str := mux.register(4)
+		Expect(str.SendDatagram([]byte("foobar"))).To(MatchError(errDatagramsNotNegotiated))
+	})
+
+	It("sends datagrams", func() {
+		mux.setNegotiated()
+		str := mux.register(1337 * 4)
+		conn.EXPECT().SendMessage(append(quicvarint.Append(nil, 1337), []byte("foobar")...))
+		Expect(str.SendDatagram([]byte("foobar"))).To(Succeed())
+	})
+
+	It("dispatches datagrams to the right stream", func() {
+		str1 := mux.register(4)
+		str2 := mux.register(8)
+		done := receiveDatagrams(
+			append(quicvarint.Append(nil, 2), []byte("foo")...),
+			append(quicvarint.Append(nil, 10), []byte("unknown")...), // no stream registered, will be dropped
+			append(quicvarint.Append(nil, 1), []byte("bar")...),
+		)
+		defer close(done)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		data, err := str1.ReceiveDatagram(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("bar")))
+		data, err = str2.ReceiveDatagram(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foo")))
+	})
+
+	It("drops datagrams when the queue is full", func() {
+		str := mux.register(4)
+		var datagrams [][]byte
+		for i := 0; i < streamDatagramQueueLen+5; i++ {
+			datagrams = append(datagrams, append(quicvarint.Append(nil, 1), byte(i)))
+		}
+		done := receiveDatagrams(datagrams...)
+		defer close(done)
+		mux.startReceiving()
+		Eventually(func() int { return len(str.queue) }).Should(Equal(streamDatagramQueueLen))
+		Consistently(func() int { return len(str.queue) }).Should(Equal(streamDatagramQueueLen))
+		data, err := str.ReceiveDatagram(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte{0}))
+	})
+
+	It("closes the connection when receiving a datagram without a Quarter Stream ID", func() {
+		conn.EXPECT().ReceiveMessage().Return([]byte{}, nil)
+		closed := make(chan struct{})
+		conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorDatagramError), gomock.Any()).Do(func(quic.ApplicationErrorCode, string) {
+			close(closed)
+		})
+		mux.startReceiving()
+		Eventually(closed).Should(BeClosed())
+	})
+
+	It("only receives datagrams once ReceiveDatagram is called", func() {
+		mux.setNegotiated()
+		str := mux.register(4)
+		// the mock connection fails the test if ReceiveMessage is called
+		Consistently(func() int { return len(str.queue) }, 50*time.Millisecond).Should(BeZero())
+
+		done := receiveDatagrams(append(quicvarint.Append(nil, 1), []byte("foobar")...))
+		defer close(done)
+		data, err := str.ReceiveDatagram(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+	})
+
+	It("returns from ReceiveDatagram when the stream is unregistered", func() {
+		done := receiveDatagrams()
+		defer close(done)
+		str := mux.register(4)
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := str.ReceiveDatagram(context.Background())
+			errChan <- err
+		}()
+		Consistently(errChan).ShouldNot(Receive())
+		mux.unregister(str)
+		Eventually(errChan).Should(Receive(MatchError(errDatagramStreamClosed)))
+		// unregistering a stream multiple times is a no-op
+		mux.unregister(str)
+	})
+
+	It("returns from ReceiveDatagram when the context is canceled", func() {
+		done := receiveDatagrams()
+		defer close(done)
+		str := mux.register(4)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := str.ReceiveDatagram(ctx)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("errors when datagrams are not enabled", func() {
+		b := newRequestBody(mockquic.NewMockStream(mockCtrl))
+		Expect(b.SendDatagram([]byte("foobar"))).To(MatchError(errDatagramsNotEnabled))
+		_, err := b.ReceiveDatagram(context.Background())
+		Expect(err).To(MatchError(errDatagramsNotEnabled))
+	})
+})
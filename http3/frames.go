@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/fkwhite/quic-go"
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/quicvarint"
 )
@@ -58,9 +59,10 @@ func parseNextFrame(r io.Reader, unknownFrameHandler unknownFrameHandlerFunc) (f
 			return &headersFrame{Length: l}, nil
 		case 0x4:
 			return parseSettingsFrame(r, l)
+		case 0x7:
+			return parseGoAwayFrame(r, l)
 		case 0x3: // CANCEL_PUSH
 		case 0x5: // PUSH_PROMISE
-		case 0x7: // GOAWAY
 		case 0xd: // MAX_PUSH_ID
 		}
 		// skip over unknown frames
@@ -88,11 +90,17 @@ func (f *headersFrame) Append(b []byte) []byte {
 	return quicvarint.Append(b, f.Length)
 }
 
-const settingDatagram = 0xffd277
+const (
+	settingQPACKMaxTableCapacity = 0x1
+	settingQPACKBlockedStreams   = 0x7
+	settingDatagram              = 0xffd277
+)
 
 type settingsFrame struct {
-	Datagram bool
-	Other    map[uint64]uint64 // all settings that we don't explicitly recognize
+	QPACKMaxTableCapacity uint64
+	QPACKBlockedStreams   uint64
+	Datagram              bool
+	Other                 map[uint64]uint64 // all settings that we don't explicitly recognize
 }
 
 func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
@@ -108,7 +116,7 @@ func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
 	}
 	frame := &settingsFrame{}
 	b := bytes.NewReader(buf)
-	var readDatagram bool
+	var readQPACKMaxTableCapacity, readQPACKBlockedStreams, readDatagram bool
 	for b.Len() > 0 {
 		id, err := quicvarint.Read(b)
 		if err != nil { // should not happen. We allocated the whole frame already.
@@ -120,6 +128,18 @@ func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
 		}
 
 		switch id {
+		case settingQPACKMaxTableCapacity:
+			if readQPACKMaxTableCapacity {
+				return nil, fmt.Errorf("duplicate setting: %d", id)
+			}
+			readQPACKMaxTableCapacity = true
+			frame.QPACKMaxTableCapacity = val
+		case settingQPACKBlockedStreams:
+			if readQPACKBlockedStreams {
+				return nil, fmt.Errorf("duplicate setting: %d", id)
+			}
+			readQPACKBlockedStreams = true
+			frame.QPACKBlockedStreams = val
 		case settingDatagram:
 			if readDatagram {
 				return nil, fmt.Errorf("duplicate setting: %d", id)
@@ -148,10 +168,24 @@ func (f *settingsFrame) Append(b []byte) []byte {
 	for id, val := range f.Other {
 		l += quicvarint.Len(id) + quicvarint.Len(val)
 	}
+	if f.QPACKMaxTableCapacity > 0 {
+		l += quicvarint.Len(settingQPACKMaxTableCapacity) + quicvarint.Len(f.QPACKMaxTableCapacity)
+	}
+	if f.QPACKBlockedStreams > 0 {
+		l += quicvarint.Len(settingQPACKBlockedStreams) + quicvarint.Len(f.QPACKBlockedStreams)
+	}
 	if f.Datagram {
 		l += quicvarint.Len(settingDatagram) + quicvarint.Len(1)
 	}
 	b = quicvarint.Append(b, uint64(l))
+	if f.QPACKMaxTableCapacity > 0 {
+		b = quicvarint.Append(b, settingQPACKMaxTableCapacity)
+		b = quicvarint.Append(b, f.QPACKMaxTableCapacity)
+	}
+	if f.QPACKBlockedStreams > 0 {
+		b = quicvarint.Append(b, settingQPACKBlockedStreams)
+		b = quicvarint.Append(b, f.QPACKBlockedStreams)
+	}
 	if f.Datagram {
 		b = quicvarint.Append(b, settingDatagram)
 		b = quicvarint.Append(b, 1)
@@ -162,3 +196,35 @@ func (f *settingsFrame) Append(b []byte) []byte {
 	}
 	return b
 }
+
+// A goAwayFrame is sent on the control stream to initiate a graceful shutdown of a connection.
+// When sent by the server, the ID is a client-initiated bidirectional stream ID:
+// Requests on this and all subsequent streams won't be processed.
+type goAwayFrame struct {
+	StreamID quic.StreamID
+}
+
+func parseGoAwayFrame(r io.Reader, l uint64) (*goAwayFrame, error) {
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	b := bytes.NewReader(buf)
+	id, err := quicvarint.Read(b)
+	if err != nil {
+		return nil, err
+	}
+	if b.Len() > 0 {
+		return nil, fmt.Errorf("unexpected size for GOAWAY frame: %d", l)
+	}
+	return &goAwayFrame{StreamID: quic.StreamID(id)}, nil
+}
+
+func (f *goAwayFrame) Append(b []byte) []byte {
+	b = quicvarint.Append(b, 0x7)
+	b = quicvarint.Append(b, uint64(quicvarint.Len(uint64(f.StreamID))))
+	return quicvarint.Append(b, uint64(f.StreamID))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/fkwhite/quic-go"
 	"github.com/fkwhite/quic-go/quicvarint"
 
 	. "github.com/onsi/ginkgo"
@@ -75,6 +76,42 @@ var _ = Describe("Frames", func() {
 		})
 	})
 
+	Context("GOAWAY frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, uint64(quicvarint.Len(1337)))
+			data = appendVarInt(data, 1337)
+			frame, err := parseNextFrame(bytes.NewReader(data), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(BeAssignableToTypeOf(&goAwayFrame{}))
+			Expect(frame.(*goAwayFrame).StreamID).To(Equal(quic.StreamID(1337)))
+		})
+
+		It("writes", func() {
+			b := (&goAwayFrame{StreamID: 1337}).Append(nil)
+			frame, err := parseNextFrame(bytes.NewReader(b), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{StreamID: 1337}))
+		})
+
+		It("rejects frames with the wrong length", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, uint64(quicvarint.Len(1337)+1))
+			data = appendVarInt(data, 1337)
+			data = append(data, 0)
+			_, err := parseNextFrame(bytes.NewReader(data), nil)
+			Expect(err).To(MatchError("unexpected size for GOAWAY frame: 3"))
+		})
+
+		It("errors on EOF", func() {
+			data := (&goAwayFrame{StreamID: 1337}).Append(nil)
+			for i := range data {
+				_, err := parseNextFrame(bytes.NewReader(data[:i]), nil)
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
+
 	Context("SETTINGS frames", func() {
 		It("parses", func() {
 			settings := appendVarInt(nil, 13)
@@ -106,9 +143,9 @@ var _ = Describe("Frames", func() {
 
 		It("writes", func() {
 			sf := &settingsFrame{Other: map[uint64]uint64{
-				1:  2,
-				99: 999,
-				13: 37,
+				0x42: 2,
+				99:   999,
+				13:   37,
 			}}
 			frame, err := parseNextFrame(bytes.NewReader(sf.Append(nil)), nil)
 			Expect(err).ToNot(HaveOccurred())
@@ -133,6 +170,46 @@ var _ = Describe("Frames", func() {
 			}
 		})
 
+		Context("QPACK settings", func() {
+			It("reads the QPACK settings", func() {
+				settings := appendVarInt(nil, settingQPACKMaxTableCapacity)
+				settings = appendVarInt(settings, 4096)
+				settings = appendVarInt(settings, settingQPACKBlockedStreams)
+				settings = appendVarInt(settings, 16)
+				data := appendVarInt(nil, 4) // type byte
+				data = appendVarInt(data, uint64(len(settings)))
+				data = append(data, settings...)
+				f, err := parseNextFrame(bytes.NewReader(data), nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(f).To(BeAssignableToTypeOf(&settingsFrame{}))
+				sf := f.(*settingsFrame)
+				Expect(sf.QPACKMaxTableCapacity).To(BeEquivalentTo(4096))
+				Expect(sf.QPACKBlockedStreams).To(BeEquivalentTo(16))
+				Expect(sf.Other).To(BeEmpty())
+			})
+
+			It("rejects duplicate QPACK settings", func() {
+				for _, id := range []uint64{settingQPACKMaxTableCapacity, settingQPACKBlockedStreams} {
+					settings := appendVarInt(nil, id)
+					settings = appendVarInt(settings, 1)
+					settings = appendVarInt(settings, id)
+					settings = appendVarInt(settings, 2)
+					data := appendVarInt(nil, 4) // type byte
+					data = appendVarInt(data, uint64(len(settings)))
+					data = append(data, settings...)
+					_, err := parseNextFrame(bytes.NewReader(data), nil)
+					Expect(err).To(MatchError(fmt.Sprintf("duplicate setting: %d", id)))
+				}
+			})
+
+			It("writes the QPACK settings", func() {
+				sf := &settingsFrame{QPACKMaxTableCapacity: 1337, QPACKBlockedStreams: 42}
+				frame, err := parseNextFrame(bytes.NewReader(sf.Append(nil)), nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(sf))
+			})
+		})
+
 		Context("H3_DATAGRAM", func() {
 			It("reads the H3_DATAGRAM value", func() {
 				settings := appendVarInt(nil, settingDatagram)
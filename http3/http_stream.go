@@ -2,8 +2,11 @@ package http3
 
 import (
 	"fmt"
+	"io"
+	"net"
 
 	"github.com/fkwhite/quic-go"
+	"github.com/fkwhite/quic-go/internal/protocol"
 )
 
 // A Stream is a HTTP/3 stream.
@@ -15,10 +18,16 @@ type Stream quic.Stream
 type stream struct {
 	quic.Stream
 
-	buf []byte
+	buf     []byte
+	readBuf []byte // used by ReadBuffer
 
 	onFrameError          func()
 	bytesRemainingInFrame uint64
+
+	// parseTrailer is called for HEADERS frames received after the header (i.e. the trailer).
+	// It is passed the length of the frame, and must read the frame payload from the reader.
+	// If it is not set, the trailer is skipped.
+	parseTrailer func(r io.Reader, length uint64) error
 }
 
 var _ Stream = &stream{}
@@ -41,7 +50,16 @@ func (s *stream) Read(b []byte) (int, error) {
 			}
 			switch f := frame.(type) {
 			case *headersFrame:
+				if s.parseTrailer != nil {
+					if err := s.parseTrailer(s.Stream, f.Length); err != nil {
+						return 0, err
+					}
+					continue
+				}
 				// skip HEADERS frames
+				if _, err := io.CopyN(io.Discard, s.Stream, int64(f.Length)); err != nil {
+					return 0, err
+				}
 				continue
 			case *dataFrame:
 				s.bytesRemainingInFrame = f.Length
@@ -66,6 +84,16 @@ func (s *stream) Read(b []byte) (int, error) {
 	return n, err
 }
 
+// ReadBuffer returns the next chunk of data.
+// Since the data is framed in DATA frames, it is copied into a buffer owned by the stream.
+func (s *stream) ReadBuffer() ([]byte, error) {
+	if s.readBuf == nil {
+		s.readBuf = make([]byte, protocol.MaxPacketBufferSize)
+	}
+	n, err := s.Read(s.readBuf)
+	return s.readBuf[:n], err
+}
+
 func (s *stream) Write(b []byte) (int, error) {
 	s.buf = s.buf[:0]
 	s.buf = (&dataFrame{Length: uint64(len(b))}).Append(s.buf)
@@ -74,3 +102,25 @@ func (s *stream) Write(b []byte) (int, error) {
 	}
 	return s.Stream.Write(b)
 }
+
+// WriteBuffers writes the contents of all buffers in a single DATA frame.
+func (s *stream) WriteBuffers(bufs net.Buffers) (int64, error) {
+	var l int
+	for _, b := range bufs {
+		l += len(b)
+	}
+	s.buf = s.buf[:0]
+	s.buf = (&dataFrame{Length: uint64(l)}).Append(s.buf)
+	hdrLen := int64(len(s.buf))
+	n, err := s.Stream.WriteBuffers(append(net.Buffers{s.buf}, bufs...))
+	if n < hdrLen {
+		return 0, err
+	}
+	return n - hdrLen, err
+}
+
+// ReadFrom implements io.ReaderFrom. Data is framed in DATA frames, just like when using Write.
+func (s *stream) ReadFrom(r io.Reader) (int64, error) {
+	// hide the ReadFrom method, so that io.Copy uses Write
+	return io.Copy(struct{ io.Writer }{s}, r)
+}
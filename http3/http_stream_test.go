@@ -2,7 +2,9 @@ package http3
 
 import (
 	"bytes"
+	"errors"
 	"io"
+	"net"
 
 	mockquic "github.com/fkwhite/quic-go/internal/mocks/quic"
 
@@ -93,6 +95,17 @@ var _ = Describe("Stream", func() {
 			Expect(b[:n]).To(Equal([]byte("bar")))
 		})
 
+		It("reads DATA frames using ReadBuffer", func() {
+			buf.Write(getDataFrame([]byte("foo")))
+			buf.Write(getDataFrame([]byte("bar")))
+			b, err := str.ReadBuffer()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte("foo")))
+			b, err = str.ReadBuffer()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte("bar")))
+		})
+
 		It("skips HEADERS frames", func() {
 			b := getDataFrame([]byte("foo"))
 			b = (&headersFrame{Length: 10}).Append(b)
@@ -106,6 +119,33 @@ var _ = Describe("Stream", func() {
 			Expect(r).To(Equal([]byte("foobar")))
 		})
 
+		It("passes the trailer to the callback", func() {
+			var trailer []byte
+			str.(*stream).parseTrailer = func(r io.Reader, l uint64) error {
+				trailer = make([]byte, l)
+				_, err := io.ReadFull(r, trailer)
+				return err
+			}
+			b := getDataFrame([]byte("foobar"))
+			b = (&headersFrame{Length: 6}).Append(b)
+			b = append(b, []byte("lorem!")...)
+			buf.Write(b)
+			data, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			Expect(trailer).To(Equal([]byte("lorem!")))
+		})
+
+		It("errors when parsing the trailer fails", func() {
+			str.(*stream).parseTrailer = func(io.Reader, uint64) error { return errors.New("invalid trailer") }
+			b := getDataFrame([]byte("foobar"))
+			b = (&headersFrame{Length: 6}).Append(b)
+			b = append(b, []byte("lorem!")...)
+			buf.Write(b)
+			_, err := io.ReadAll(str)
+			Expect(err).To(MatchError("invalid trailer"))
+		})
+
 		It("errors when it can't parse the frame", func() {
 			buf.Write([]byte("invalid"))
 			_, err := str.Read([]byte{0})
@@ -146,5 +186,37 @@ var _ = Describe("Stream", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(b).To(Equal([]byte("foobar")))
 		})
+
+		It("writes multiple buffers in a single DATA frame", func() {
+			buf := &bytes.Buffer{}
+			qstr := mockquic.NewMockStream(mockCtrl)
+			qstr.EXPECT().WriteBuffers(gomock.Any()).DoAndReturn(func(bufs net.Buffers) (int64, error) {
+				return bufs.WriteTo(buf)
+			})
+			str := newStream(qstr, nil)
+			n, err := str.WriteBuffers(net.Buffers{[]byte("foo"), []byte("bar")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeEquivalentTo(6))
+
+			f, err := parseNextFrame(buf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f).To(Equal(&dataFrame{Length: 6}))
+			Expect(buf.Bytes()).To(Equal([]byte("foobar")))
+		})
+
+		It("writes data frames when reading from an io.Reader", func() {
+			buf := &bytes.Buffer{}
+			qstr := mockquic.NewMockStream(mockCtrl)
+			qstr.EXPECT().Write(gomock.Any()).DoAndReturn(buf.Write).AnyTimes()
+			str := newStream(qstr, nil)
+			n, err := io.Copy(str, bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeEquivalentTo(6))
+
+			f, err := parseNextFrame(buf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f).To(Equal(&dataFrame{Length: 6}))
+			Expect(buf.Bytes()).To(Equal([]byte("foobar")))
+		})
 	})
 })
@@ -0,0 +1,185 @@
+package http3
+
+import (
+	"io"
+	"sync"
+
+	"github.com/fkwhite/quic-go"
+	"github.com/fkwhite/quic-go/quicvarint"
+	"github.com/marten-seemann/qpack"
+	"golang.org/x/net/http2/hpack"
+)
+
+// defaultQPACKBlockedStreams is the number of streams that can be blocked, if the dynamic table is enabled
+const defaultQPACKBlockedStreams = 16
+
+// A qpackError is a violation of the QPACK protocol by the peer.
+// Depending on where it occurs, it's treated as a connection error of type
+// QPACK_DECOMPRESSION_FAILED, QPACK_ENCODER_STREAM_ERROR or QPACK_DECODER_STREAM_ERROR, see Section 6 of RFC 9204.
+type qpackError string
+
+func (e qpackError) Error() string { return string(e) }
+
+// qpackEntrySize is the size of a dynamic table entry, as defined in Section 3.2.1 of RFC 9204.
+func qpackEntrySize(f qpack.HeaderField) uint64 {
+	return uint64(len(f.Name)+len(f.Value)) + 32
+}
+
+// qpackDynamicTable is the QPACK dynamic table, see Section 3.2 of RFC 9204.
+// Entries are identified by their absolute index, which starts at 0 for the first entry ever inserted.
+type qpackDynamicTable struct {
+	capacity uint64
+	size     uint64
+	entries  []qpack.HeaderField
+	dropped  uint64 // the number of evicted entries, which is the absolute index of entries[0]
+}
+
+// insertCount is the total number of entries inserted into the table.
+func (t *qpackDynamicTable) insertCount() uint64 {
+	return t.dropped + uint64(len(t.entries))
+}
+
+func (t *qpackDynamicTable) get(idx uint64) (qpack.HeaderField, bool) {
+	if idx < t.dropped || idx >= t.insertCount() {
+		return qpack.HeaderField{}, false
+	}
+	return t.entries[idx-t.dropped], true
+}
+
+// evict evicts the oldest entries until the table size doesn't exceed size.
+func (t *qpackDynamicTable) evict(size uint64) {
+	for t.size > size {
+		t.size -= qpackEntrySize(t.entries[0])
+		t.entries[0] = qpack.HeaderField{}
+		t.entries = t.entries[1:]
+		t.dropped++
+	}
+}
+
+// insert inserts an entry.
+// The caller needs to make sure that there's enough room in the table.
+func (t *qpackDynamicTable) insert(f qpack.HeaderField) {
+	t.entries = append(t.entries, f)
+	t.size += qpackEntrySize(f)
+}
+
+// A qpackInstructionWriter writes instructions to the QPACK encoder or decoder stream.
+// Instructions are queued while holding the encoder's (or decoder's) mutex, so that they're sent in the right order.
+// They're written to the stream without holding that mutex: A write that's blocked by flow control
+// must not block the encoding and decoding of field sections.
+type qpackInstructionWriter struct {
+	str quic.SendStream
+
+	mutex   sync.Mutex
+	queued  []byte
+	writing bool
+}
+
+func newQPACKInstructionWriter(str quic.SendStream) *qpackInstructionWriter {
+	return &qpackInstructionWriter{str: str}
+}
+
+func (w *qpackInstructionWriter) queue(b []byte) {
+	w.mutex.Lock()
+	w.queued = append(w.queued, b...)
+	w.mutex.Unlock()
+}
+
+// flush writes the queued instructions to the stream.
+// If another goroutine is currently writing to the stream, it returns immediately,
+// and the instructions are written by that goroutine.
+func (w *qpackInstructionWriter) flush() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.writing {
+		return nil
+	}
+	w.writing = true
+	defer func() { w.writing = false }()
+	for len(w.queued) > 0 {
+		b := w.queued
+		w.queued = nil
+		w.mutex.Unlock()
+		_, err := w.str.Write(b)
+		w.mutex.Lock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendQPACKInt appends an integer using an n-bit prefix, see Section 4.1.1 of RFC 9204.
+// The bits of the first byte that are not used by the prefix are taken from flags.
+func appendQPACKInt(b []byte, n uint8, flags byte, v uint64) []byte {
+	max := uint64(1)<<n - 1
+	if v < max {
+		return append(b, flags|byte(v))
+	}
+	b = append(b, flags|byte(max))
+	v -= max
+	for v >= 0x80 {
+		b = append(b, 0x80|byte(v&0x7f))
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// readQPACKInt reads an integer using an n-bit prefix.
+// The first byte of the integer has already been read.
+func readQPACKInt(r io.ByteReader, first byte, n uint8) (uint64, error) {
+	max := uint64(1)<<n - 1
+	v := uint64(first) & max
+	if v < max {
+		return v, nil
+	}
+	for shift := 0; ; shift += 7 {
+		if shift > 56 {
+			return 0, qpackError("integer overflow")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		v += uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+}
+
+// appendQPACKString appends a string literal with an n-bit prefix for the length, see Section 4.1.2 of RFC 9204.
+// The bit preceding the prefix is the Huffman flag. Huffman encoding is used if it reduces the length.
+func appendQPACKString(b []byte, n uint8, flags byte, s string) []byte {
+	if l := hpack.HuffmanEncodeLength(s); l < uint64(len(s)) {
+		b = appendQPACKInt(b, n, flags|1<<n, l)
+		return hpack.AppendHuffmanString(b, s)
+	}
+	b = appendQPACKInt(b, n, flags, uint64(len(s)))
+	return append(b, s...)
+}
+
+// readQPACKString reads a string literal with an n-bit prefix for the length.
+// The first byte of the string literal has already been read.
+func readQPACKString(r quicvarint.Reader, first byte, n uint8, maxLen uint64) (string, error) {
+	l, err := readQPACKInt(r, first, n)
+	if err != nil {
+		return "", err
+	}
+	if l > maxLen {
+		return "", qpackError("string literal too long")
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	if first&(1<<n) == 0 {
+		return string(b), nil
+	}
+	s, err := hpack.HuffmanDecodeToString(b)
+	if err != nil {
+		return "", qpackError(err.Error())
+	}
+	return s, nil
+}
@@ -0,0 +1,477 @@
+package http3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/fkwhite/quic-go"
+	"github.com/fkwhite/quic-go/internal/utils"
+	"github.com/fkwhite/quic-go/quicvarint"
+	"github.com/marten-seemann/qpack"
+)
+
+// A qpackDecoder decodes field sections.
+// It maintains the dynamic table according to the instructions received on the peer's encoder stream,
+// and acknowledges inserted entries and decoded field sections on our decoder stream.
+type qpackDecoder struct {
+	maxTableCapacity  uint64 // as advertised in our SETTINGS
+	maxBlockedStreams uint64 // as advertised in our SETTINGS
+
+	mutex                 sync.Mutex
+	receivedEncoderStream bool
+	table                 qpackDynamicTable
+	inserted              chan struct{} // closed (and replaced) whenever entries are inserted
+	closeErr              error         // set when the encoder stream is closed
+	numBlocked            uint64
+	knownReceivedCount    uint64                  // the number of inserts the encoder knows we received
+	writer                *qpackInstructionWriter // for the decoder stream, nil until the peer sends encoder instructions
+
+	logger utils.Logger
+}
+
+func newQPACKDecoder(maxTableCapacity, maxBlockedStreams uint64, logger utils.Logger) *qpackDecoder {
+	return &qpackDecoder{
+		maxTableCapacity:  maxTableCapacity,
+		maxBlockedStreams: maxBlockedStreams,
+		inserted:          make(chan struct{}),
+		logger:            logger,
+	}
+}
+
+// handleEncoderStream processes the instructions received on the peer's encoder stream.
+// Our decoder stream is opened as soon as the first instruction is received.
+// It returns when the stream or the connection is closed.
+func (d *qpackDecoder) handleEncoderStream(conn quic.Connection, str quic.ReceiveStream) {
+	d.mutex.Lock()
+	duplicate := d.receivedEncoderStream
+	d.receivedEncoderStream = true
+	d.mutex.Unlock()
+	if duplicate {
+		conn.CloseWithError(quic.ApplicationErrorCode(errorStreamCreationError), "duplicate QPACK encoder stream")
+		return
+	}
+
+	err := d.readEncoderStream(conn, str)
+	d.close(err)
+	var qerr qpackError
+	if errors.As(err, &qerr) {
+		conn.CloseWithError(quic.ApplicationErrorCode(errorQPACKEncoderStreamError), qerr.Error())
+		return
+	}
+	// If the connection was closed, this is a no-op.
+	conn.CloseWithError(quic.ApplicationErrorCode(errorClosedCriticalStream), "QPACK encoder stream closed")
+}
+
+func (d *qpackDecoder) readEncoderStream(conn quic.Connection, str quic.ReceiveStream) error {
+	r := bufio.NewReader(str)
+	if _, err := r.Peek(1); err != nil {
+		return err
+	}
+	decoderStr, err := conn.OpenUniStream()
+	if err != nil {
+		return err
+	}
+	if _, err := decoderStr.Write(quicvarint.Append(nil, streamTypeQPACKDecoderStream)); err != nil {
+		return err
+	}
+	d.mutex.Lock()
+	d.writer = newQPACKInstructionWriter(decoderStr)
+	d.mutex.Unlock()
+
+	for {
+		if err := d.handleEncoderInstruction(r); err != nil {
+			return err
+		}
+		// Acknowledge all entries inserted so far, unless more instructions are already waiting to be processed.
+		if r.Buffered() == 0 {
+			if err := d.acknowledgeInserts(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handleEncoderInstruction handles a single encoder instruction, see Section 4.3 of RFC 9204.
+func (d *qpackDecoder) handleEncoderInstruction(r quicvarint.Reader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch {
+	case b&0x80 > 0: // Insert with Name Reference
+		idx, err := readQPACKInt(r, b, 6)
+		if err != nil {
+			return err
+		}
+		var name string
+		if b&0x40 > 0 {
+			if idx >= uint64(len(qpackStaticTable)) {
+				return qpackError("invalid static table index")
+			}
+			name = qpackStaticTable[idx].Name
+		} else {
+			f, err := d.relativeEntry(idx)
+			if err != nil {
+				return err
+			}
+			name = f.Name
+		}
+		value, err := d.readValue(r)
+		if err != nil {
+			return err
+		}
+		return d.insert(qpack.HeaderField{Name: name, Value: value})
+	case b&0x40 > 0: // Insert with Literal Name
+		name, err := readQPACKString(r, b, 5, d.maxTableCapacity)
+		if err != nil {
+			return err
+		}
+		value, err := d.readValue(r)
+		if err != nil {
+			return err
+		}
+		return d.insert(qpack.HeaderField{Name: name, Value: value})
+	case b&0x20 > 0: // Set Dynamic Table Capacity
+		capacity, err := readQPACKInt(r, b, 5)
+		if err != nil {
+			return err
+		}
+		if capacity > d.maxTableCapacity {
+			return qpackError("dynamic table capacity exceeds the maximum")
+		}
+		d.mutex.Lock()
+		d.table.capacity = capacity
+		d.table.evict(capacity)
+		d.mutex.Unlock()
+		return nil
+	default: // Duplicate
+		idx, err := readQPACKInt(r, b, 5)
+		if err != nil {
+			return err
+		}
+		f, err := d.relativeEntry(idx)
+		if err != nil {
+			return err
+		}
+		return d.insert(f)
+	}
+}
+
+func (d *qpackDecoder) readValue(r quicvarint.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	return readQPACKString(r, b, 7, d.maxTableCapacity)
+}
+
+// relativeEntry returns the entry referenced by an index relative to the insert count, as used by encoder instructions.
+func (d *qpackDecoder) relativeEntry(idx uint64) (qpack.HeaderField, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	insertCount := d.table.insertCount()
+	if idx >= insertCount {
+		return qpack.HeaderField{}, qpackError("invalid dynamic table index")
+	}
+	f, ok := d.table.get(insertCount - 1 - idx)
+	if !ok {
+		return qpack.HeaderField{}, qpackError("reference to an evicted dynamic table entry")
+	}
+	return f, nil
+}
+
+func (d *qpackDecoder) insert(f qpack.HeaderField) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	size := qpackEntrySize(f)
+	if size > d.table.capacity {
+		return qpackError("entry exceeds the dynamic table capacity")
+	}
+	d.table.evict(d.table.capacity - size)
+	d.table.insert(f)
+	close(d.inserted)
+	d.inserted = make(chan struct{})
+	return nil
+}
+
+// acknowledgeInserts sends an Insert Count Increment instruction for all entries that the encoder doesn't know we received.
+func (d *qpackDecoder) acknowledgeInserts() error {
+	d.mutex.Lock()
+	insertCount := d.table.insertCount()
+	if insertCount <= d.knownReceivedCount {
+		d.mutex.Unlock()
+		return nil
+	}
+	d.writer.queue(appendQPACKInt(nil, 6, 0, insertCount-d.knownReceivedCount))
+	d.knownReceivedCount = insertCount
+	writer := d.writer
+	d.mutex.Unlock()
+
+	return writer.flush()
+}
+
+func (d *qpackDecoder) close(err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.closeErr = err
+	close(d.inserted)
+	d.inserted = make(chan struct{})
+}
+
+// decode decodes a field section received on a request stream, see Section 4.5 of RFC 9204.
+// If the field section references dynamic table entries that haven't been received yet,
+// decode blocks until these entries are inserted, the context is canceled, or the encoder stream is closed.
+// A qpackError is a connection error of type QPACK_DECOMPRESSION_FAILED.
+func (d *qpackDecoder) decode(ctx context.Context, id quic.StreamID, data []byte) ([]qpack.HeaderField, error) {
+	r := bytes.NewReader(data)
+	requiredInsertCount, base, err := d.readFieldSectionPrefix(r)
+	if err != nil {
+		return nil, err
+	}
+	if requiredInsertCount > 0 {
+		if err := d.waitForInserts(ctx, id, requiredInsertCount); err != nil {
+			// send the Stream Cancellation instruction queued by waitForInserts (if any)
+			d.flush()
+			return nil, err
+		}
+	}
+	fields, err := d.decodeFieldLines(r, requiredInsertCount, base)
+	if err != nil {
+		return nil, err
+	}
+	if requiredInsertCount > 0 {
+		d.acknowledgeSection(id, requiredInsertCount)
+	}
+	return fields, nil
+}
+
+func (d *qpackDecoder) readFieldSectionPrefix(r *bytes.Reader) (requiredInsertCount, base uint64, _ error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, qpackError("missing field section prefix")
+	}
+	encodedInsertCount, err := readQPACKInt(r, b, 8)
+	if err != nil {
+		return 0, 0, qpackError("invalid field section prefix")
+	}
+	b, err = r.ReadByte()
+	if err != nil {
+		return 0, 0, qpackError("missing field section prefix")
+	}
+	deltaBase, err := readQPACKInt(r, b, 7)
+	if err != nil {
+		return 0, 0, qpackError("invalid field section prefix")
+	}
+	requiredInsertCount, err = d.decodeRequiredInsertCount(encodedInsertCount)
+	if err != nil {
+		return 0, 0, err
+	}
+	if b&0x80 == 0 {
+		return requiredInsertCount, requiredInsertCount + deltaBase, nil
+	}
+	if deltaBase >= requiredInsertCount {
+		return 0, 0, qpackError("invalid base")
+	}
+	return requiredInsertCount, requiredInsertCount - deltaBase - 1, nil
+}
+
+// decodeRequiredInsertCount decodes the Required Insert Count, see Section 4.5.1.1 of RFC 9204.
+func (d *qpackDecoder) decodeRequiredInsertCount(encoded uint64) (uint64, error) {
+	if encoded == 0 {
+		return 0, nil
+	}
+	maxEntries := d.maxTableCapacity / 32
+	fullRange := 2 * maxEntries
+	if encoded > fullRange {
+		return 0, qpackError("invalid Required Insert Count")
+	}
+	d.mutex.Lock()
+	totalNumberOfInserts := d.table.insertCount()
+	d.mutex.Unlock()
+	maxValue := totalNumberOfInserts + maxEntries
+	maxWrapped := (maxValue / fullRange) * fullRange
+	requiredInsertCount := maxWrapped + encoded - 1
+	if requiredInsertCount > maxValue {
+		if requiredInsertCount <= fullRange {
+			return 0, qpackError("invalid Required Insert Count")
+		}
+		requiredInsertCount -= fullRange
+	}
+	if requiredInsertCount == 0 {
+		return 0, qpackError("invalid Required Insert Count")
+	}
+	return requiredInsertCount, nil
+}
+
+// waitForInserts blocks until requiredInsertCount entries have been inserted into the dynamic table.
+func (d *qpackDecoder) waitForInserts(ctx context.Context, id quic.StreamID, requiredInsertCount uint64) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.table.insertCount() >= requiredInsertCount {
+		return nil
+	}
+	if d.numBlocked >= d.maxBlockedStreams {
+		return qpackError("too many blocked streams")
+	}
+	d.numBlocked++
+	defer func() { d.numBlocked-- }()
+
+	for d.table.insertCount() < requiredInsertCount {
+		if d.closeErr != nil {
+			return d.closeErr
+		}
+		inserted := d.inserted
+		d.mutex.Unlock()
+		select {
+		case <-inserted:
+			d.mutex.Lock()
+		case <-ctx.Done():
+			d.mutex.Lock()
+			// Let the encoder know that this field section won't be acknowledged.
+			// The instruction is sent by the caller, after releasing the mutex.
+			if d.writer != nil {
+				d.writer.queue(appendQPACKInt(nil, 6, 0x40, uint64(id)))
+			}
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (d *qpackDecoder) decodeFieldLines(r *bytes.Reader, requiredInsertCount, base uint64) ([]qpack.HeaderField, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	var fields []qpack.HeaderField
+	for r.Len() > 0 {
+		b, _ := r.ReadByte()
+		var f qpack.HeaderField
+		var err error
+		switch {
+		case b&0x80 > 0: // Indexed Field Line
+			var idx uint64
+			if idx, err = readQPACKInt(r, b, 6); err != nil {
+				break
+			}
+			if b&0x40 > 0 {
+				f, err = d.staticEntry(idx)
+			} else {
+				f, err = d.preBaseEntry(idx, requiredInsertCount, base)
+			}
+		case b&0x40 > 0: // Literal Field Line with Name Reference
+			var idx uint64
+			if idx, err = readQPACKInt(r, b, 4); err != nil {
+				break
+			}
+			if b&0x10 > 0 {
+				f, err = d.staticEntry(idx)
+			} else {
+				f, err = d.preBaseEntry(idx, requiredInsertCount, base)
+			}
+			if err == nil {
+				f.Value, err = d.readFieldValue(r)
+			}
+		case b&0x20 > 0: // Literal Field Line with Literal Name
+			if f.Name, err = readQPACKString(r, b, 3, uint64(r.Len())); err == nil {
+				f.Value, err = d.readFieldValue(r)
+			}
+		case b&0x10 > 0: // Indexed Field Line with Post-Base Index
+			var idx uint64
+			if idx, err = readQPACKInt(r, b, 4); err == nil {
+				f, err = d.postBaseEntry(idx, requiredInsertCount, base)
+			}
+		default: // Literal Field Line with Post-Base Name Reference
+			var idx uint64
+			if idx, err = readQPACKInt(r, b, 3); err != nil {
+				break
+			}
+			if f, err = d.postBaseEntry(idx, requiredInsertCount, base); err == nil {
+				f.Value, err = d.readFieldValue(r)
+			}
+		}
+		if err != nil {
+			var qerr qpackError
+			if errors.As(err, &qerr) {
+				return nil, err
+			}
+			return nil, qpackError("invalid field line")
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func (d *qpackDecoder) readFieldValue(r *bytes.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	return readQPACKString(r, b, 7, uint64(r.Len()))
+}
+
+func (d *qpackDecoder) staticEntry(idx uint64) (qpack.HeaderField, error) {
+	if idx >= uint64(len(qpackStaticTable)) {
+		return qpack.HeaderField{}, qpackError("invalid static table index")
+	}
+	return qpackStaticTable[idx], nil
+}
+
+func (d *qpackDecoder) preBaseEntry(idx, requiredInsertCount, base uint64) (qpack.HeaderField, error) {
+	if idx >= base {
+		return qpack.HeaderField{}, qpackError("invalid dynamic table index")
+	}
+	return d.dynamicEntry(base-1-idx, requiredInsertCount)
+}
+
+func (d *qpackDecoder) postBaseEntry(idx, requiredInsertCount, base uint64) (qpack.HeaderField, error) {
+	if base >= requiredInsertCount || idx >= requiredInsertCount-base {
+		return qpack.HeaderField{}, qpackError("invalid dynamic table index")
+	}
+	return d.dynamicEntry(base+idx, requiredInsertCount)
+}
+
+// dynamicEntry returns the entry with the given absolute index.
+// Field sections must not reference entries beyond the Required Insert Count.
+func (d *qpackDecoder) dynamicEntry(idx, requiredInsertCount uint64) (qpack.HeaderField, error) {
+	if idx >= requiredInsertCount {
+		return qpack.HeaderField{}, qpackError("reference beyond the Required Insert Count")
+	}
+	f, ok := d.table.get(idx)
+	if !ok {
+		return qpack.HeaderField{}, qpackError("reference to an evicted dynamic table entry")
+	}
+	return f, nil
+}
+
+// acknowledgeSection sends a Section Acknowledgment instruction.
+func (d *qpackDecoder) acknowledgeSection(id quic.StreamID, requiredInsertCount uint64) {
+	d.mutex.Lock()
+	if requiredInsertCount > d.knownReceivedCount {
+		d.knownReceivedCount = requiredInsertCount
+	}
+	d.writer.queue(appendQPACKInt(nil, 7, 0x80, uint64(id)))
+	d.mutex.Unlock()
+
+	d.flush()
+}
+
+// flush writes the queued instructions to the decoder stream.
+// Errors are only logged: If the decoder stream can't be written to, the connection is being closed anyway.
+func (d *qpackDecoder) flush() {
+	d.mutex.Lock()
+	writer := d.writer
+	d.mutex.Unlock()
+	if writer == nil {
+		return
+	}
+	if err := writer.flush(); err != nil {
+		d.logger.Debugf("Writing to the QPACK decoder stream failed: %s", err)
+	}
+}
@@ -0,0 +1,238 @@
+package http3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	"github.com/fkwhite/quic-go"
+	mockquic "github.com/fkwhite/quic-go/internal/mocks/quic"
+	"github.com/fkwhite/quic-go/internal/utils"
+	"github.com/fkwhite/quic-go/quicvarint"
+
+	"github.com/golang/mock/gomock"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QPACK decoder", func() {
+	var (
+		decoder    *qpackDecoder
+		decoderBuf *bytes.Buffer // the data sent on the decoder stream
+	)
+
+	foo := qpack.HeaderField{Name: "foo", Value: "bar"}
+
+	// insertFoo is the encoder instruction inserting foo into the dynamic table
+	insertFoo := func() []byte {
+		b := appendQPACKString(nil, 5, 0x40, foo.Name)
+		return appendQPACKString(b, 7, 0, foo.Value)
+	}
+
+	handleInstructions := func(b []byte) error {
+		r := bufio.NewReader(bytes.NewReader(b))
+		for {
+			if err := decoder.handleEncoderInstruction(r); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+
+	BeforeEach(func() {
+		decoder = newQPACKDecoder(4096, 1, utils.DefaultLogger)
+		decoderBuf = &bytes.Buffer{}
+		decoderStr := mockquic.NewMockStream(mockCtrl)
+		decoderStr.EXPECT().Write(gomock.Any()).DoAndReturn(decoderBuf.Write).AnyTimes()
+		decoder.writer = newQPACKInstructionWriter(decoderStr)
+		Expect(handleInstructions(appendQPACKInt(nil, 5, 0x20, 4096))).To(Succeed())
+	})
+
+	It("decodes field sections encoded using the static table", func() {
+		fields := []qpack.HeaderField{
+			{Name: ":method", Value: "GET"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: "foo", Value: "bar"},
+		}
+		buf := &bytes.Buffer{}
+		enc := qpack.NewEncoder(buf)
+		for _, f := range fields {
+			Expect(enc.WriteField(f)).To(Succeed())
+		}
+		decoded, err := decoder.decode(context.Background(), 0, buf.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal(fields))
+		Expect(decoderBuf.Len()).To(BeZero())
+	})
+
+	It("inserts entries, and acknowledges them", func() {
+		Expect(handleInstructions(insertFoo())).To(Succeed())
+		Expect(decoder.acknowledgeInserts()).To(Succeed())
+		Expect(decoderBuf.Bytes()).To(Equal(appendQPACKInt(nil, 6, 0, 1)))
+	})
+
+	It("handles the different encoder instructions", func() {
+		b := insertFoo()
+		b = append(b, appendQPACKInt(nil, 6, 0xc0, 0)...) // Insert with Name Reference: :authority
+		b = appendQPACKString(b, 7, 0, "quic.clemente.io")
+		b = append(b, appendQPACKInt(nil, 6, 0x80, 1)...) // Insert with Name Reference: foo
+		b = appendQPACKString(b, 7, 0, "baz")
+		b = append(b, appendQPACKInt(nil, 5, 0, 1)...) // Duplicate: :authority
+		Expect(handleInstructions(b)).To(Succeed())
+		Expect(decoder.table.entries).To(Equal([]qpack.HeaderField{
+			foo,
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: "foo", Value: "baz"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+		}))
+	})
+
+	It("evicts entries when reducing the capacity", func() {
+		Expect(handleInstructions(insertFoo())).To(Succeed())
+		Expect(handleInstructions(appendQPACKInt(nil, 5, 0x20, 10))).To(Succeed())
+		Expect(decoder.table.entries).To(BeEmpty())
+		Expect(decoder.table.insertCount()).To(BeEquivalentTo(1))
+	})
+
+	It("decodes references to the dynamic table, and acknowledges the field section", func() {
+		Expect(handleInstructions(insertFoo())).To(Succeed())
+		// Required Insert Count 1, Base 1, Indexed Field Line (relative index 0)
+		data := []byte{2, 0, 0x80}
+		// Required Insert Count 1, Base 0, Indexed Field Line with Post-Base Index 0
+		data2 := []byte{2, 0x80, 0x10}
+		for i, d := range [][]byte{data, data2} {
+			fields, err := decoder.decode(context.Background(), quic.StreamID(4*i), d)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fields).To(Equal([]qpack.HeaderField{foo}))
+		}
+		Expect(decoderBuf.Bytes()).To(Equal([]byte{0x80, 0x84}))
+	})
+
+	It("blocks until the referenced entries are inserted", func() {
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			fields, err := decoder.decode(context.Background(), 4, []byte{2, 0, 0x80})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fields).To(Equal([]qpack.HeaderField{foo}))
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		Expect(handleInstructions(insertFoo())).To(Succeed())
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("errors when too many streams are blocked", func() {
+		go decoder.decode(context.Background(), 0, []byte{2, 0, 0x80})
+		Eventually(func() uint64 {
+			decoder.mutex.Lock()
+			defer decoder.mutex.Unlock()
+			return decoder.numBlocked
+		}).Should(BeEquivalentTo(1))
+		_, err := decoder.decode(context.Background(), 4, []byte{2, 0, 0x80})
+		Expect(err).To(MatchError(qpackError("too many blocked streams")))
+		decoder.close(errors.New("test done"))
+	})
+
+	It("cancels the stream when the context is canceled while blocked", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := decoder.decode(ctx, 8, []byte{2, 0, 0x80})
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(decoderBuf.Bytes()).To(Equal(appendQPACKInt(nil, 6, 0x40, 8)))
+	})
+
+	It("returns the error when the encoder stream is closed while blocked", func() {
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := decoder.decode(context.Background(), 4, []byte{2, 0, 0x80})
+			Expect(err).To(MatchError(io.EOF))
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		decoder.close(io.EOF)
+		Eventually(done).Should(BeClosed())
+	})
+
+	Context("invalid field sections", func() {
+		It("rejects an invalid Required Insert Count", func() {
+			_, err := decoder.decode(context.Background(), 0, []byte{0xff, 0x7f, 0})
+			Expect(err).To(MatchError(qpackError("invalid Required Insert Count")))
+		})
+
+		It("rejects references beyond the Required Insert Count", func() {
+			Expect(handleInstructions(append(insertFoo(), insertFoo()...))).To(Succeed())
+			// Required Insert Count 1, Base 0, Indexed Field Line with Post-Base Index 1
+			_, err := decoder.decode(context.Background(), 0, []byte{2, 0x80, 0x11})
+			Expect(err).To(MatchError(qpackError("invalid dynamic table index")))
+		})
+
+		It("rejects references to evicted entries", func() {
+			Expect(handleInstructions(insertFoo())).To(Succeed())
+			Expect(handleInstructions(appendQPACKInt(nil, 5, 0x20, 0))).To(Succeed())
+			_, err := decoder.decode(context.Background(), 0, []byte{2, 0, 0x80})
+			Expect(err).To(MatchError(qpackError("reference to an evicted dynamic table entry")))
+		})
+
+		It("rejects invalid static table indices", func() {
+			_, err := decoder.decode(context.Background(), 0, append([]byte{0, 0}, appendQPACKInt(nil, 6, 0xc0, 99)...))
+			Expect(err).To(MatchError(qpackError("invalid static table index")))
+		})
+
+		It("rejects truncated field lines", func() {
+			b := append([]byte{0, 0}, appendQPACKInt(nil, 4, 0x50, 0)...)
+			_, err := decoder.decode(context.Background(), 0, b)
+			Expect(err).To(MatchError(qpackError("invalid field line")))
+		})
+	})
+
+	Context("handling the encoder stream", func() {
+		var conn *mockquic.MockEarlyConnection
+
+		BeforeEach(func() {
+			conn = mockquic.NewMockEarlyConnection(mockCtrl)
+		})
+
+		It("opens the decoder stream when receiving the first instruction", func() {
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(bytes.NewReader(insertFoo()).Read).AnyTimes()
+			buf := &bytes.Buffer{}
+			decoderStr := mockquic.NewMockStream(mockCtrl)
+			decoderStr.EXPECT().Write(gomock.Any()).DoAndReturn(buf.Write).AnyTimes()
+			conn.EXPECT().OpenUniStream().Return(decoderStr, nil)
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorClosedCriticalStream), gomock.Any())
+			decoder.handleEncoderStream(conn, str)
+			streamType, err := quicvarint.Read(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(streamType).To(BeEquivalentTo(streamTypeQPACKDecoderStream))
+			Expect(buf.Bytes()).To(Equal(appendQPACKInt(nil, 6, 0, 1))) // Insert Count Increment
+		})
+
+		It("closes the connection when receiving an invalid instruction", func() {
+			str := mockquic.NewMockStream(mockCtrl)
+			b := appendQPACKInt(nil, 5, 0x20, 4097) // Set Dynamic Table Capacity, exceeding the maximum
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(bytes.NewReader(b).Read).AnyTimes()
+			decoderStr := mockquic.NewMockStream(mockCtrl)
+			decoderStr.EXPECT().Write(gomock.Any()).AnyTimes()
+			conn.EXPECT().OpenUniStream().Return(decoderStr, nil)
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorQPACKEncoderStreamError), gomock.Any())
+			decoder.handleEncoderStream(conn, str)
+		})
+
+		It("closes the connection when the peer opens a second encoder stream", func() {
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Read(gomock.Any()).Return(0, io.EOF).AnyTimes()
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorClosedCriticalStream), gomock.Any())
+			decoder.handleEncoderStream(conn, str)
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorStreamCreationError), gomock.Any())
+			decoder.handleEncoderStream(conn, str)
+		})
+	})
+})
@@ -0,0 +1,276 @@
+package http3
+
+import (
+	"bufio"
+	"errors"
+	"sync"
+
+	"github.com/fkwhite/quic-go"
+	"github.com/fkwhite/quic-go/internal/utils"
+	"github.com/fkwhite/quic-go/quicvarint"
+	"github.com/marten-seemann/qpack"
+)
+
+// qpackNeverIndexed are the fields that are never inserted into the dynamic table:
+// Sensitive fields (see Section 7.1.3 of RFC 9204), and fields that usually change with every message.
+var qpackNeverIndexed = map[string]struct{}{
+	"authorization":       {},
+	"proxy-authorization": {},
+	"cookie":              {},
+	"set-cookie":          {},
+	":path":               {},
+	"content-length":      {},
+	"date":                {},
+}
+
+// A qpackSection is a field section referencing the dynamic table that wasn't acknowledged yet.
+type qpackSection struct {
+	requiredInsertCount uint64
+	minIndex            uint64 // the smallest absolute index referenced
+}
+
+// A qpackEncoder encodes field sections.
+// As long as the peer doesn't allow the use of the dynamic table, only the static table is used.
+// Otherwise, header fields are inserted into the dynamic table, and referenced once the peer acknowledged them.
+// Since only acknowledged entries are referenced, field sections never block the peer's decoder.
+type qpackEncoder struct {
+	maxTableCapacity uint64 // the maximum capacity of the dynamic table we're willing to use
+
+	mutex                 sync.Mutex
+	writer                *qpackInstructionWriter // for the encoder stream, nil if the dynamic table is not used
+	peerMaxEntries        uint64                  // derived from the SETTINGS_QPACK_MAX_TABLE_CAPACITY sent by the peer
+	table                 qpackDynamicTable
+	fields                map[qpack.HeaderField]uint64 // the absolute index of the entries in the table
+	knownReceivedCount    uint64
+	unacked               map[quic.StreamID][]qpackSection
+	receivedDecoderStream bool
+
+	logger utils.Logger
+}
+
+func newQPACKEncoder(maxTableCapacity uint64, logger utils.Logger) *qpackEncoder {
+	return &qpackEncoder{
+		maxTableCapacity: maxTableCapacity,
+		fields:           make(map[qpack.HeaderField]uint64),
+		unacked:          make(map[quic.StreamID][]qpackSection),
+		logger:           logger,
+	}
+}
+
+// handleSettings is called when the peer's SETTINGS are received.
+// If both endpoints allow the use of the dynamic table, the encoder stream is opened.
+func (e *qpackEncoder) handleSettings(conn quic.Connection, peerMaxTableCapacity uint64) {
+	capacity := utils.Min(e.maxTableCapacity, peerMaxTableCapacity)
+	if capacity == 0 {
+		return
+	}
+	str, err := conn.OpenUniStream()
+	if err != nil {
+		e.logger.Debugf("Opening the QPACK encoder stream failed: %s", err)
+		return
+	}
+
+	// The stream isn't used by any other goroutine yet, so we can write to it directly.
+	b := quicvarint.Append(nil, streamTypeQPACKEncoderStream)
+	b = appendQPACKInt(b, 5, 0x20, capacity) // Set Dynamic Table Capacity
+	if _, err := str.Write(b); err != nil {
+		e.logger.Debugf("Writing to the QPACK encoder stream failed: %s", err)
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.writer = newQPACKInstructionWriter(str)
+	e.table.capacity = capacity
+	e.peerMaxEntries = peerMaxTableCapacity / 32
+}
+
+// encode encodes a field section sent on stream id, see Section 4.5 of RFC 9204.
+// The encoder instructions for inserting new entries are written to the encoder stream before it returns.
+// Since the field section only references entries that were already acknowledged,
+// it doesn't depend on these instructions.
+func (e *qpackEncoder) encode(id quic.StreamID, fields []qpack.HeaderField) ([]byte, error) {
+	b, writer := e.encodeFieldSection(id, fields)
+	if writer != nil {
+		if err := writer.flush(); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// encodeFieldSection encodes a field section, and queues the encoder instructions.
+// It returns the writer for the encoder stream, if the dynamic table is used.
+func (e *qpackEncoder) encodeFieldSection(id quic.StreamID, fields []qpack.HeaderField) ([]byte, *qpackInstructionWriter) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	var instructions []byte
+	var requiredInsertCount uint64
+	minIndex := e.table.insertCount()
+	dynamicRefs := make(map[int]uint64) // the absolute index of the dynamic table entry used for a field
+	if e.writer != nil {
+		for i, f := range fields {
+			if _, ok := qpackStaticFields[f]; ok {
+				continue
+			}
+			if idx, ok := e.fields[f]; ok && idx < e.knownReceivedCount {
+				dynamicRefs[i] = idx
+				requiredInsertCount = utils.Max(requiredInsertCount, idx+1)
+				minIndex = utils.Min(minIndex, idx)
+				continue
+			}
+			instructions = e.maybeInsert(instructions, f, minIndex)
+		}
+	}
+	if len(instructions) > 0 {
+		e.writer.queue(instructions)
+	}
+
+	// The Base is always equal to the Required Insert Count, so the Delta Base is 0.
+	var b []byte
+	if requiredInsertCount == 0 {
+		b = append(b, 0, 0)
+	} else {
+		b = appendQPACKInt(b, 8, 0, requiredInsertCount%(2*e.peerMaxEntries)+1)
+		b = append(b, 0)
+		e.unacked[id] = append(e.unacked[id], qpackSection{requiredInsertCount: requiredInsertCount, minIndex: minIndex})
+	}
+	for i, f := range fields {
+		if idx, ok := qpackStaticFields[f]; ok {
+			b = appendQPACKInt(b, 6, 0xc0, idx) // Indexed Field Line, static table
+			continue
+		}
+		if idx, ok := dynamicRefs[i]; ok {
+			b = appendQPACKInt(b, 6, 0x80, requiredInsertCount-1-idx) // Indexed Field Line, dynamic table
+			continue
+		}
+		if idx, ok := qpackStaticNames[f.Name]; ok {
+			b = appendQPACKInt(b, 4, 0x50, idx) // Literal Field Line with Name Reference, static table
+		} else {
+			b = appendQPACKString(b, 3, 0x20, f.Name) // Literal Field Line with Literal Name
+		}
+		b = appendQPACKString(b, 7, 0, f.Value)
+	}
+	return b, e.writer
+}
+
+// maybeInsert inserts a field into the dynamic table, unless that would require evicting entries that are still in use.
+// Entries are in use if they weren't acknowledged yet, or if they are referenced by an unacknowledged field section.
+// This includes the field section that is currently being encoded, which references entries starting at minIndex.
+// It appends the encoder instruction to b.
+func (e *qpackEncoder) maybeInsert(b []byte, f qpack.HeaderField, minIndex uint64) []byte {
+	if _, ok := qpackNeverIndexed[f.Name]; ok {
+		return b
+	}
+	if _, ok := e.fields[f]; ok { // already inserted, but not acknowledged yet
+		return b
+	}
+	size := qpackEntrySize(f)
+	if size > e.table.capacity {
+		return b
+	}
+	evictable := utils.Min(e.knownReceivedCount, minIndex)
+	for _, sections := range e.unacked {
+		for _, s := range sections {
+			evictable = utils.Min(evictable, s.minIndex)
+		}
+	}
+	available := e.table.capacity - e.table.size
+	for idx := e.table.dropped; available < size; idx++ {
+		if idx >= evictable {
+			return b
+		}
+		entry, _ := e.table.get(idx)
+		available += qpackEntrySize(entry)
+	}
+	for e.table.size+size > e.table.capacity {
+		evicted := e.table.entries[0]
+		if e.fields[evicted] == e.table.dropped {
+			delete(e.fields, evicted)
+		}
+		e.table.evict(e.table.size - qpackEntrySize(evicted))
+	}
+	e.fields[f] = e.table.insertCount()
+	e.table.insert(f)
+
+	if idx, ok := qpackStaticNames[f.Name]; ok {
+		b = appendQPACKInt(b, 6, 0xc0, idx) // Insert with Name Reference, static table
+	} else {
+		b = appendQPACKString(b, 5, 0x40, f.Name) // Insert with Literal Name
+	}
+	return appendQPACKString(b, 7, 0, f.Value)
+}
+
+// handleDecoderStream processes the instructions received on the peer's decoder stream.
+// It returns when the stream or the connection is closed.
+func (e *qpackEncoder) handleDecoderStream(conn quic.Connection, str quic.ReceiveStream) {
+	e.mutex.Lock()
+	duplicate := e.receivedDecoderStream
+	e.receivedDecoderStream = true
+	e.mutex.Unlock()
+	if duplicate {
+		conn.CloseWithError(quic.ApplicationErrorCode(errorStreamCreationError), "duplicate QPACK decoder stream")
+		return
+	}
+
+	r := bufio.NewReader(str)
+	var err error
+	for err == nil {
+		err = e.handleDecoderInstruction(r)
+	}
+	var qerr qpackError
+	if errors.As(err, &qerr) {
+		conn.CloseWithError(quic.ApplicationErrorCode(errorQPACKDecoderStreamError), qerr.Error())
+		return
+	}
+	// If the connection was closed, this is a no-op.
+	conn.CloseWithError(quic.ApplicationErrorCode(errorClosedCriticalStream), "QPACK decoder stream closed")
+}
+
+// handleDecoderInstruction handles a single decoder instruction, see Section 4.4 of RFC 9204.
+func (e *qpackEncoder) handleDecoderInstruction(r quicvarint.Reader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch {
+	case b&0x80 > 0: // Section Acknowledgment
+		id, err := readQPACKInt(r, b, 7)
+		if err != nil {
+			return err
+		}
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		sections := e.unacked[quic.StreamID(id)]
+		if len(sections) == 0 {
+			return qpackError("unexpected Section Acknowledgment")
+		}
+		e.knownReceivedCount = utils.Max(e.knownReceivedCount, sections[0].requiredInsertCount)
+		if len(sections) == 1 {
+			delete(e.unacked, quic.StreamID(id))
+		} else {
+			e.unacked[quic.StreamID(id)] = sections[1:]
+		}
+	case b&0x40 > 0: // Stream Cancellation
+		id, err := readQPACKInt(r, b, 6)
+		if err != nil {
+			return err
+		}
+		e.mutex.Lock()
+		delete(e.unacked, quic.StreamID(id))
+		e.mutex.Unlock()
+	default: // Insert Count Increment
+		increment, err := readQPACKInt(r, b, 6)
+		if err != nil {
+			return err
+		}
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		if increment == 0 || increment > e.table.insertCount()-e.knownReceivedCount {
+			return qpackError("invalid Insert Count Increment")
+		}
+		e.knownReceivedCount += increment
+	}
+	return nil
+}
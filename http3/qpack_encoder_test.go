@@ -0,0 +1,232 @@
+package http3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/fkwhite/quic-go"
+	mockquic "github.com/fkwhite/quic-go/internal/mocks/quic"
+	"github.com/fkwhite/quic-go/internal/utils"
+	"github.com/fkwhite/quic-go/quicvarint"
+
+	"github.com/golang/mock/gomock"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QPACK encoder", func() {
+	var (
+		encoder    *qpackEncoder
+		decoder    *qpackDecoder
+		conn       *mockquic.MockEarlyConnection
+		encoderBuf *bytes.Buffer // the data sent on the encoder stream
+		decoderBuf *bytes.Buffer // the data sent on the decoder stream
+	)
+
+	// processEncoderStream makes the decoder process all instructions sent on the encoder stream
+	processEncoderStream := func() {
+		r := bufio.NewReader(encoderBuf)
+		for r.Buffered() > 0 || encoderBuf.Len() > 0 {
+			ExpectWithOffset(1, decoder.handleEncoderInstruction(r)).To(Succeed())
+		}
+		ExpectWithOffset(1, decoder.acknowledgeInserts()).To(Succeed())
+	}
+
+	// processDecoderStream makes the encoder process all instructions sent on the decoder stream
+	processDecoderStream := func() {
+		r := bufio.NewReader(decoderBuf)
+		for r.Buffered() > 0 || decoderBuf.Len() > 0 {
+			ExpectWithOffset(1, encoder.handleDecoderInstruction(r)).To(Succeed())
+		}
+	}
+
+	decode := func(id quic.StreamID, data []byte) []qpack.HeaderField {
+		fields, err := decoder.decode(context.Background(), id, data)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		return fields
+	}
+
+	BeforeEach(func() {
+		encoder = newQPACKEncoder(4096, utils.DefaultLogger)
+		decoder = newQPACKDecoder(4096, 16, utils.DefaultLogger)
+		conn = mockquic.NewMockEarlyConnection(mockCtrl)
+		encoderBuf = &bytes.Buffer{}
+		decoderBuf = &bytes.Buffer{}
+		decoderStr := mockquic.NewMockStream(mockCtrl)
+		decoderStr.EXPECT().Write(gomock.Any()).DoAndReturn(decoderBuf.Write).AnyTimes()
+		decoder.writer = newQPACKInstructionWriter(decoderStr)
+	})
+
+	// enableDynamicTable makes the encoder open the encoder stream, and consumes the stream type.
+	// peerMaxTableCapacity is the capacity advertised by the decoder.
+	enableDynamicTable := func(peerMaxTableCapacity uint64) {
+		decoder.maxTableCapacity = peerMaxTableCapacity
+		encoderStr := mockquic.NewMockStream(mockCtrl)
+		encoderStr.EXPECT().Write(gomock.Any()).DoAndReturn(encoderBuf.Write).AnyTimes()
+		conn.EXPECT().OpenUniStream().Return(encoderStr, nil)
+		encoder.handleSettings(conn, peerMaxTableCapacity)
+		streamType, err := quicvarint.Read(encoderBuf)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		ExpectWithOffset(1, streamType).To(BeEquivalentTo(streamTypeQPACKEncoderStream))
+		processEncoderStream()
+	}
+
+	It("only uses the static table if the peer disabled the dynamic table", func() {
+		encoder.handleSettings(conn, 0) // don't EXPECT any calls to conn.OpenUniStream
+		fields := []qpack.HeaderField{
+			{Name: ":method", Value: "GET"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: "foo", Value: "bar"},
+		}
+		for i := 0; i < 2; i++ {
+			data, err := encoder.encode(0, fields)
+			Expect(err).ToNot(HaveOccurred())
+			decoded, err := qpack.NewDecoder(nil).DecodeFull(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded).To(Equal(fields))
+		}
+		Expect(encoder.table.insertCount()).To(BeZero())
+	})
+
+	It("sets the dynamic table capacity", func() {
+		enableDynamicTable(1000)
+		Expect(encoder.table.capacity).To(BeEquivalentTo(1000))
+		Expect(decoder.table.capacity).To(BeEquivalentTo(1000))
+	})
+
+	It("references inserted fields once they are acknowledged", func() {
+		enableDynamicTable(4096)
+		fields := []qpack.HeaderField{
+			{Name: ":method", Value: "GET"},
+			{Name: "user-agent", Value: "quic-go HTTP/3"},
+			{Name: "foo", Value: "bar"},
+		}
+		data1, err := encoder.encode(0, fields)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encoder.table.insertCount()).To(BeEquivalentTo(2))
+		processEncoderStream()
+		Expect(decode(0, data1)).To(Equal(fields))
+		processDecoderStream()
+		Expect(encoder.knownReceivedCount).To(BeEquivalentTo(2))
+
+		data2, err := encoder.encode(4, fields)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(data2)).To(BeNumerically("<", len(data1)))
+		Expect(encoderBuf.Len()).To(BeZero())
+		Expect(encoder.unacked).To(HaveKey(quic.StreamID(4)))
+		Expect(decode(4, data2)).To(Equal(fields))
+		processDecoderStream()
+		Expect(encoder.unacked).To(BeEmpty())
+	})
+
+	It("doesn't insert sensitive fields", func() {
+		enableDynamicTable(4096)
+		fields := []qpack.HeaderField{
+			{Name: "cookie", Value: "foo=bar"},
+			{Name: "authorization", Value: "secret"},
+		}
+		data, err := encoder.encode(0, fields)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encoderBuf.Len()).To(BeZero())
+		Expect(decode(0, data)).To(Equal(fields))
+	})
+
+	It("doesn't evict entries referenced by unacknowledged field sections", func() {
+		enableDynamicTable(64) // room for a single entry
+		foo := qpack.HeaderField{Name: "foo", Value: "bar"}
+		lorem := qpack.HeaderField{Name: "lorem", Value: "ipsum"}
+		_, err := encoder.encode(0, []qpack.HeaderField{foo})
+		Expect(err).ToNot(HaveOccurred())
+		processEncoderStream()
+		processDecoderStream()
+
+		data, err := encoder.encode(4, []qpack.HeaderField{foo, lorem})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encoder.table.insertCount()).To(BeEquivalentTo(1))
+		Expect(decode(4, data)).To(Equal([]qpack.HeaderField{foo, lorem}))
+
+		// the field section referencing foo is acknowledged now
+		processDecoderStream()
+		data, err = encoder.encode(8, []qpack.HeaderField{lorem})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encoder.table.insertCount()).To(BeEquivalentTo(2))
+		Expect(encoder.fields).ToNot(HaveKey(foo))
+		processEncoderStream()
+		Expect(decode(8, data)).To(Equal([]qpack.HeaderField{lorem}))
+		f, ok := decoder.table.get(1)
+		Expect(ok).To(BeTrue())
+		Expect(f).To(Equal(lorem))
+	})
+
+	It("encodes the Required Insert Count modulo the number of entries", func() {
+		enableDynamicTable(64) // MaxEntries = 2
+		for i := 0; i < 10; i++ {
+			f := qpack.HeaderField{Name: "foo", Value: string(rune('a' + i))}
+			_, err := encoder.encode(quic.StreamID(8*i), []qpack.HeaderField{f})
+			Expect(err).ToNot(HaveOccurred())
+			processEncoderStream()
+			processDecoderStream()
+			data, err := encoder.encode(quic.StreamID(8*i+4), []qpack.HeaderField{f})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data[0]).To(BeEquivalentTo(uint64(i+1)%4 + 1))
+			Expect(decode(quic.StreamID(8*i+4), data)).To(Equal([]qpack.HeaderField{f}))
+			processDecoderStream()
+		}
+	})
+
+	It("removes field sections on stream cancellations", func() {
+		enableDynamicTable(4096)
+		f := qpack.HeaderField{Name: "foo", Value: "bar"}
+		_, err := encoder.encode(0, []qpack.HeaderField{f})
+		Expect(err).ToNot(HaveOccurred())
+		processEncoderStream()
+		processDecoderStream()
+		_, err = encoder.encode(4, []qpack.HeaderField{f})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encoder.unacked).To(HaveKey(quic.StreamID(4)))
+		decoderBuf.Write(appendQPACKInt(nil, 6, 0x40, 4))
+		processDecoderStream()
+		Expect(encoder.unacked).To(BeEmpty())
+	})
+
+	Context("handling errors on the decoder stream", func() {
+		It("rejects acknowledgments for unknown field sections", func() {
+			r := bytes.NewReader(appendQPACKInt(nil, 7, 0x80, 4))
+			Expect(encoder.handleDecoderInstruction(r)).To(MatchError(qpackError("unexpected Section Acknowledgment")))
+		})
+
+		It("rejects invalid Insert Count Increments", func() {
+			r := bytes.NewReader(appendQPACKInt(nil, 6, 0, 0))
+			Expect(encoder.handleDecoderInstruction(r)).To(MatchError(qpackError("invalid Insert Count Increment")))
+			r = bytes.NewReader(appendQPACKInt(nil, 6, 0, 1))
+			Expect(encoder.handleDecoderInstruction(r)).To(MatchError(qpackError("invalid Insert Count Increment")))
+		})
+
+		It("closes the connection when receiving an invalid instruction", func() {
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(bytes.NewReader([]byte{0}).Read).AnyTimes()
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorQPACKDecoderStreamError), gomock.Any())
+			encoder.handleDecoderStream(conn, str)
+		})
+
+		It("closes the connection when the decoder stream is closed", func() {
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Read(gomock.Any()).Return(0, io.EOF).AnyTimes()
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorClosedCriticalStream), gomock.Any())
+			encoder.handleDecoderStream(conn, str)
+		})
+
+		It("closes the connection when the peer opens a second decoder stream", func() {
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Read(gomock.Any()).Return(0, io.EOF).AnyTimes()
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorClosedCriticalStream), gomock.Any())
+			encoder.handleDecoderStream(conn, str)
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorStreamCreationError), gomock.Any())
+			encoder.handleDecoderStream(conn, str)
+		})
+	})
+})
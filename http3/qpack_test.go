@@ -0,0 +1,193 @@
+package http3
+
+import (
+	"bytes"
+	"io"
+
+	mockquic "github.com/fkwhite/quic-go/internal/mocks/quic"
+
+	"github.com/golang/mock/gomock"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QPACK", func() {
+	Context("integers", func() {
+		It("encodes integers using the examples from RFC 7541", func() {
+			Expect(appendQPACKInt(nil, 5, 0, 10)).To(Equal([]byte{0x0a}))
+			Expect(appendQPACKInt(nil, 5, 0, 1337)).To(Equal([]byte{0x1f, 0x9a, 0x0a}))
+			Expect(appendQPACKInt(nil, 8, 0, 42)).To(Equal([]byte{0x2a}))
+		})
+
+		It("sets the flags", func() {
+			Expect(appendQPACKInt(nil, 6, 0xc0, 17)).To(Equal([]byte{0xc0 | 17}))
+			Expect(appendQPACKInt(nil, 6, 0xc0, 63)).To(Equal([]byte{0xff, 0}))
+		})
+
+		It("reads integers", func() {
+			for _, n := range []uint8{3, 4, 5, 6, 7, 8} {
+				for _, v := range []uint64{0, 1, 6, 7, 8, 126, 127, 128, 254, 255, 256, 1337, 1 << 30, 1 << 62} {
+					b := appendQPACKInt(nil, n, 0, v)
+					r := bytes.NewReader(b[1:])
+					val, err := readQPACKInt(r, b[0], n)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(val).To(Equal(v))
+					Expect(r.Len()).To(BeZero())
+				}
+			}
+		})
+
+		It("ignores the flags when reading", func() {
+			b := appendQPACKInt(nil, 4, 0xf0, 1337)
+			val, err := readQPACKInt(bytes.NewReader(b[1:]), b[0], 4)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(val).To(BeEquivalentTo(1337))
+		})
+
+		It("errors on EOF", func() {
+			b := appendQPACKInt(nil, 5, 0, 1<<30)
+			_, err := readQPACKInt(bytes.NewReader(b[1:len(b)-1]), b[0], 5)
+			Expect(err).To(MatchError(io.EOF))
+		})
+
+		It("errors on overflows", func() {
+			_, err := readQPACKInt(bytes.NewReader(bytes.Repeat([]byte{0xff}, 10)), 0x1f, 5)
+			Expect(err).To(MatchError(qpackError("integer overflow")))
+		})
+	})
+
+	Context("strings", func() {
+		It("writes and reads strings", func() {
+			b := appendQPACKString(nil, 7, 0, "QPACK!")
+			Expect(b).To(HaveLen(1 + len("QPACK!")))
+			Expect(b[0] & 0x80).To(BeZero())
+			r := bytes.NewReader(b[1:])
+			s, err := readQPACKString(r, b[0], 7, 1000)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s).To(Equal("QPACK!"))
+			Expect(r.Len()).To(BeZero())
+		})
+
+		It("uses Huffman encoding if that reduces the length", func() {
+			b := appendQPACKString(nil, 3, 0x20, "www.example.com")
+			Expect(b[0] & 0x8).ToNot(BeZero())
+			Expect(len(b)).To(BeNumerically("<", 1+len("www.example.com")))
+			Expect(b[0] & 0x20).ToNot(BeZero())
+			r := bytes.NewReader(b[1:])
+			s, err := readQPACKString(r, b[0], 3, 1000)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s).To(Equal("www.example.com"))
+			Expect(r.Len()).To(BeZero())
+		})
+
+		It("errors when the string is too long", func() {
+			b := appendQPACKString(nil, 7, 0, "QPACK!")
+			_, err := readQPACKString(bytes.NewReader(b[1:]), b[0], 7, 5)
+			Expect(err).To(MatchError(qpackError("string literal too long")))
+		})
+
+		It("errors on EOF", func() {
+			b := appendQPACKString(nil, 7, 0, "foobar")
+			_, err := readQPACKString(bytes.NewReader(b[1:len(b)-1]), b[0], 7, 1000)
+			Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		})
+	})
+
+	It("uses the same static table as the qpack package", func() {
+		Expect(qpackStaticTable).To(HaveLen(99))
+		// encode every entry as an Indexed Field Line referencing the static table
+		b := []byte{0, 0} // Required Insert Count and Base
+		for i := range qpackStaticTable {
+			b = appendQPACKInt(b, 6, 0xc0, uint64(i))
+		}
+		fields, err := qpack.NewDecoder(nil).DecodeFull(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fields).To(Equal(qpackStaticTable[:]))
+	})
+
+	Context("instruction writer", func() {
+		It("writes instructions in the order they were queued", func() {
+			str := mockquic.NewMockStream(mockCtrl)
+			buf := &bytes.Buffer{}
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(buf.Write).AnyTimes()
+			w := newQPACKInstructionWriter(str)
+			w.queue([]byte("foo"))
+			w.queue([]byte("bar"))
+			Expect(w.flush()).To(Succeed())
+			Expect(buf.String()).To(Equal("foobar"))
+			Expect(w.flush()).To(Succeed())
+			Expect(buf.String()).To(Equal("foobar"))
+		})
+
+		It("doesn't block while another goroutine is writing", func() {
+			str := mockquic.NewMockStream(mockCtrl)
+			buf := &bytes.Buffer{}
+			unblock := make(chan struct{})
+			writing := make(chan struct{})
+			gomock.InOrder(
+				str.EXPECT().Write([]byte("foo")).DoAndReturn(func(b []byte) (int, error) {
+					close(writing)
+					<-unblock // blocked by flow control
+					return buf.Write(b)
+				}),
+				str.EXPECT().Write([]byte("bar")).DoAndReturn(buf.Write),
+			)
+			w := newQPACKInstructionWriter(str)
+			w.queue([]byte("foo"))
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				Expect(w.flush()).To(Succeed())
+			}()
+			Eventually(writing).Should(BeClosed())
+			w.queue([]byte("bar"))
+			Expect(w.flush()).To(Succeed()) // returns immediately
+			Consistently(done).ShouldNot(BeClosed())
+			close(unblock)
+			Eventually(done).Should(BeClosed())
+			// the instruction queued while the first write was blocked is written by the first goroutine
+			Expect(buf.String()).To(Equal("foobar"))
+		})
+
+		It("returns write errors", func() {
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Write(gomock.Any()).Return(0, io.ErrClosedPipe)
+			w := newQPACKInstructionWriter(str)
+			w.queue([]byte("foo"))
+			Expect(w.flush()).To(MatchError(io.ErrClosedPipe))
+		})
+	})
+
+	Context("dynamic table", func() {
+		It("inserts and evicts entries", func() {
+			t := &qpackDynamicTable{capacity: 100}
+			foo := qpack.HeaderField{Name: "foo", Value: "bar"}
+			lorem := qpack.HeaderField{Name: "lorem", Value: "ipsum"}
+			t.insert(foo)
+			Expect(t.size).To(BeEquivalentTo(38))
+			t.insert(lorem)
+			Expect(t.size).To(BeEquivalentTo(80))
+			Expect(t.insertCount()).To(BeEquivalentTo(2))
+			f, ok := t.get(0)
+			Expect(ok).To(BeTrue())
+			Expect(f).To(Equal(foo))
+			f, ok = t.get(1)
+			Expect(ok).To(BeTrue())
+			Expect(f).To(Equal(lorem))
+			_, ok = t.get(2)
+			Expect(ok).To(BeFalse())
+
+			t.evict(60)
+			Expect(t.size).To(BeEquivalentTo(42))
+			Expect(t.insertCount()).To(BeEquivalentTo(2))
+			_, ok = t.get(0)
+			Expect(ok).To(BeFalse())
+			f, ok = t.get(1)
+			Expect(ok).To(BeTrue())
+			Expect(f).To(Equal(lorem))
+		})
+	})
+})
@@ -0,0 +1,80 @@
+package http3
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+
+	"github.com/fkwhite/quic-go"
+)
+
+type requestStatsKey struct{}
+
+// RequestStats counts the bytes sent and received on the stream of a HTTP/3 request.
+// This includes the HTTP/3 frame headers, the QPACK-encoded HEADERS frames, and the body.
+// Data sent on the control stream and on the QPACK encoder and decoder streams
+// is shared by all requests on a connection, and is not counted.
+// The counters can be read while the request is in progress.
+type RequestStats struct {
+	bytesSent     uint64
+	bytesReceived uint64
+}
+
+// WithRequestStats returns a copy of ctx that makes the RoundTripper count the bytes
+// of a request created with this context in stats.
+func WithRequestStats(ctx context.Context, stats *RequestStats) context.Context {
+	return context.WithValue(ctx, requestStatsKey{}, stats)
+}
+
+// BytesSent returns the number of bytes sent on the request stream
+func (s *RequestStats) BytesSent() uint64 {
+	return atomic.LoadUint64(&s.bytesSent)
+}
+
+// BytesReceived returns the number of bytes received on the request stream
+func (s *RequestStats) BytesReceived() uint64 {
+	return atomic.LoadUint64(&s.bytesReceived)
+}
+
+func requestStatsFromContext(ctx context.Context) *RequestStats {
+	stats, _ := ctx.Value(requestStatsKey{}).(*RequestStats)
+	return stats
+}
+
+// A countingStream counts the bytes read from and written to a stream
+type countingStream struct {
+	quic.Stream
+
+	stats *RequestStats
+}
+
+func (s *countingStream) Read(b []byte) (int, error) {
+	n, err := s.Stream.Read(b)
+	atomic.AddUint64(&s.stats.bytesReceived, uint64(n))
+	return n, err
+}
+
+func (s *countingStream) ReadBuffer() ([]byte, error) {
+	b, err := s.Stream.ReadBuffer()
+	atomic.AddUint64(&s.stats.bytesReceived, uint64(len(b)))
+	return b, err
+}
+
+func (s *countingStream) Write(b []byte) (int, error) {
+	n, err := s.Stream.Write(b)
+	atomic.AddUint64(&s.stats.bytesSent, uint64(n))
+	return n, err
+}
+
+func (s *countingStream) WriteBuffers(bufs net.Buffers) (int64, error) {
+	n, err := s.Stream.WriteBuffers(bufs)
+	atomic.AddUint64(&s.stats.bytesSent, uint64(n))
+	return n, err
+}
+
+func (s *countingStream) ReadFrom(r io.Reader) (int64, error) {
+	n, err := s.Stream.ReadFrom(r)
+	atomic.AddUint64(&s.stats.bytesSent, uint64(n))
+	return n, err
+}
@@ -0,0 +1,60 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+
+	mockquic "github.com/fkwhite/quic-go/internal/mocks/quic"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request Stats", func() {
+	It("retrieves the stats from the context", func() {
+		Expect(requestStatsFromContext(context.Background())).To(BeNil())
+		stats := &RequestStats{}
+		Expect(requestStatsFromContext(WithRequestStats(context.Background(), stats))).To(Equal(stats))
+	})
+
+	It("counts the bytes read and written", func() {
+		str := mockquic.NewMockStream(mockCtrl)
+		r := bytes.NewReader([]byte("foobar"))
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+		str.EXPECT().Write(gomock.Any()).DoAndReturn(func(b []byte) (int, error) { return len(b) - 1, io.ErrShortWrite })
+		stats := &RequestStats{}
+		cstr := &countingStream{Stream: str, stats: stats}
+		b := make([]byte, 4)
+		_, err := io.ReadFull(cstr, b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats.BytesReceived()).To(BeEquivalentTo(4))
+		_, err = io.ReadAll(cstr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats.BytesReceived()).To(BeEquivalentTo(6))
+		_, err = cstr.Write([]byte("lorem"))
+		Expect(err).To(MatchError(io.ErrShortWrite))
+		Expect(stats.BytesSent()).To(BeEquivalentTo(4))
+	})
+
+	It("counts the bytes read using ReadBuffer, and written using WriteBuffers and ReadFrom", func() {
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().ReadBuffer().Return([]byte("foobar"), nil)
+		str.EXPECT().WriteBuffers(gomock.Any()).Return(int64(6), nil)
+		str.EXPECT().ReadFrom(gomock.Any()).Return(int64(5), nil)
+		stats := &RequestStats{}
+		cstr := &countingStream{Stream: str, stats: stats}
+		_, err := cstr.ReadBuffer()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats.BytesReceived()).To(BeEquivalentTo(6))
+		_, err = cstr.WriteBuffers(net.Buffers{[]byte("foo"), []byte("bar")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats.BytesSent()).To(BeEquivalentTo(6))
+		_, err = cstr.ReadFrom(bytes.NewReader([]byte("lorem")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats.BytesSent()).To(BeEquivalentTo(11))
+	})
+})
@@ -21,52 +21,66 @@ import (
 const bodyCopyBufferSize = 8 * 1024
 
 type requestWriter struct {
-	mutex     sync.Mutex
-	encoder   *qpack.Encoder
-	headerBuf *bytes.Buffer
+	mutex   sync.Mutex
+	encoder *qpackEncoder
+	fields  []qpack.HeaderField
 
 	logger utils.Logger
 }
 
-func newRequestWriter(logger utils.Logger) *requestWriter {
-	headerBuf := &bytes.Buffer{}
-	encoder := qpack.NewEncoder(headerBuf)
+func newRequestWriter(encoder *qpackEncoder, logger utils.Logger) *requestWriter {
 	return &requestWriter{
-		encoder:   encoder,
-		headerBuf: headerBuf,
-		logger:    logger,
+		encoder: encoder,
+		logger:  logger,
 	}
 }
 
 func (w *requestWriter) WriteRequestHeader(str quic.Stream, req *http.Request, gzip bool) error {
 	// TODO: figure out how to add support for trailers
 	buf := &bytes.Buffer{}
-	if err := w.writeHeaders(buf, req, gzip); err != nil {
+	if err := w.writeHeaders(buf, str.StreamID(), req, gzip); err != nil {
 		return err
 	}
 	_, err := str.Write(buf.Bytes())
 	return err
 }
 
-func (w *requestWriter) writeHeaders(wr io.Writer, req *http.Request, gzip bool) error {
-	w.mutex.Lock()
-	defer w.mutex.Unlock()
-	defer w.encoder.Close()
-	defer w.headerBuf.Reset()
-
-	if err := w.encodeHeaders(req, gzip, "", actualContentLength(req)); err != nil {
+func (w *requestWriter) writeHeaders(wr io.Writer, id quic.StreamID, req *http.Request, gzip bool) error {
+	headers, encoderWriter, err := w.encodeFieldSection(id, req, gzip)
+	if err != nil {
 		return err
 	}
+	// Writing to the encoder stream might block on flow control.
+	// This must not block other requests from encoding their headers.
+	if encoderWriter != nil {
+		if err := encoderWriter.flush(); err != nil {
+			return err
+		}
+	}
 
 	b := make([]byte, 0, 128)
-	b = (&headersFrame{Length: uint64(w.headerBuf.Len())}).Append(b)
+	b = (&headersFrame{Length: uint64(len(headers))}).Append(b)
 	if _, err := wr.Write(b); err != nil {
 		return err
 	}
-	_, err := wr.Write(w.headerBuf.Bytes())
+	_, err = wr.Write(headers)
 	return err
 }
 
+// encodeFieldSection encodes the request headers.
+// It returns the writer for the encoder stream, which needs to be flushed before the field section is sent.
+func (w *requestWriter) encodeFieldSection(id quic.StreamID, req *http.Request, gzip bool) ([]byte, *qpackInstructionWriter, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	defer func() { w.fields = w.fields[:0] }()
+
+	if err := w.encodeHeaders(req, gzip, "", actualContentLength(req)); err != nil {
+		return nil, nil, err
+	}
+	headers, encoderWriter := w.encoder.encodeFieldSection(id, w.fields)
+	return headers, encoderWriter, nil
+}
+
 // copied from net/transport.go
 // Modified to support Extended CONNECT:
 // Contrary to what the godoc for the http.Request says,
@@ -199,7 +213,7 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 	// Header list size is ok. Write the headers.
 	enumerateHeaders(func(name, value string) {
 		name = strings.ToLower(name)
-		w.encoder.WriteField(qpack.HeaderField{Name: name, Value: value})
+		w.fields = append(w.fields, qpack.HeaderField{Name: name, Value: value})
 		// if traceHeaders {
 		// 	traceWroteHeaderField(trace, name, value)
 		// }
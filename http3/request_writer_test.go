@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/fkwhite/quic-go"
 	mockquic "github.com/fkwhite/quic-go/internal/mocks/quic"
 	"github.com/fkwhite/quic-go/internal/utils"
 
@@ -41,9 +42,10 @@ var _ = Describe("Request Writer", func() {
 	}
 
 	BeforeEach(func() {
-		rw = newRequestWriter(utils.DefaultLogger)
+		rw = newRequestWriter(newQPACKEncoder(0, utils.DefaultLogger), utils.DefaultLogger)
 		strBuf = &bytes.Buffer{}
 		str = mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().StreamID().AnyTimes()
 		str.EXPECT().Write(gomock.Any()).DoAndReturn(strBuf.Write).AnyTimes()
 	})
 
@@ -109,4 +111,42 @@ var _ = Describe("Request Writer", func() {
 		Expect(headerFields).To(HaveKeyWithValue(":scheme", "https"))
 		Expect(headerFields).To(HaveKeyWithValue(":protocol", "webtransport"))
 	})
+
+	It("doesn't block other requests while writing to the QPACK encoder stream", func() {
+		encoder := newQPACKEncoder(4096, utils.DefaultLogger)
+		rw = newRequestWriter(encoder, utils.DefaultLogger)
+		unblock := make(chan struct{})
+		encoderStr := mockquic.NewMockStream(mockCtrl)
+		gomock.InOrder(
+			encoderStr.EXPECT().Write(gomock.Any()).DoAndReturn(func(b []byte) (int, error) { return len(b), nil }), // stream type and capacity
+			encoderStr.EXPECT().Write(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+				<-unblock // the encoder stream is blocked by flow control
+				return len(b), nil
+			}).AnyTimes(),
+		)
+		conn := mockquic.NewMockEarlyConnection(mockCtrl)
+		conn.EXPECT().OpenUniStream().Return(encoderStr, nil)
+		encoder.handleSettings(conn, 4096)
+
+		req, err := http.NewRequest(http.MethodGet, "https://quic.clemente.io/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("foo", "bar")
+		str1 := mockquic.NewMockStream(mockCtrl)
+		str1.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+		str1.EXPECT().Write(gomock.Any()).AnyTimes()
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(rw.WriteRequestHeader(str1, req, false)).To(Succeed())
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+
+		Expect(rw.WriteRequestHeader(str, req, false)).To(Succeed())
+		headerFields := decode(strBuf)
+		Expect(headerFields).To(HaveKeyWithValue(":authority", "quic.clemente.io"))
+		Expect(headerFields).To(HaveKeyWithValue("foo", "bar"))
+		close(unblock)
+		Eventually(done).Should(BeClosed())
+	})
 })
@@ -2,7 +2,6 @@ package http3
 
 import (
 	"bufio"
-	"bytes"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,14 +11,37 @@ import (
 	"github.com/marten-seemann/qpack"
 )
 
+// A QUICStreamer allows taking over the QUIC stream that carries a HTTP/3 request,
+// e.g. to implement a protocol that uses its own framing on top of the stream.
+// It is implemented by the http.ResponseWriter passed to the Server's http.Handler.
+//
+// Once QUICStream was called, the stream is owned by the caller:
+//   - Data written to the http.ResponseWriter before is flushed, no more data must be written to it afterwards.
+//   - The HTTP/3 server won't send any response, and won't close or reset the stream after the handler returns.
+//     It's the caller's responsibility to close the stream (Close, CancelWrite) and to stop reading from it (CancelRead).
+//   - The request body must not be used, since it reads from the same stream.
+//     Data on the stream is read without removing the HTTP/3 framing.
+type QUICStreamer interface {
+	// Connection returns the QUIC connection that the request was received on.
+	Connection() quic.Connection
+	// QUICStream returns the QUIC stream that the request was received on.
+	QUICStream() quic.Stream
+}
+
 type responseWriter struct {
 	conn        quic.Connection
+	str         quic.Stream
 	bufferedStr *bufio.Writer
+	encoder     *qpackEncoder
 	buf         []byte
 
 	header        http.Header
 	status        int // status code passed to WriteHeader
 	headerWritten bool
+	wasTakenOver  bool // set when QUICStream is called
+
+	// trailers declared using the Trailer header, see http.ResponseWriter for details
+	trailers map[string]struct{}
 
 	logger utils.Logger
 }
@@ -28,14 +50,17 @@ var (
 	_ http.ResponseWriter = &responseWriter{}
 	_ http.Flusher        = &responseWriter{}
 	_ Hijacker            = &responseWriter{}
+	_ QUICStreamer        = &responseWriter{}
 )
 
-func newResponseWriter(str quic.Stream, conn quic.Connection, logger utils.Logger) *responseWriter {
+func newResponseWriter(str quic.Stream, conn quic.Connection, encoder *qpackEncoder, logger utils.Logger) *responseWriter {
 	return &responseWriter{
 		header:      http.Header{},
 		buf:         make([]byte, 16),
 		conn:        conn,
+		str:         str,
 		bufferedStr: bufio.NewWriter(str),
+		encoder:     encoder,
 		logger:      logger,
 	}
 }
@@ -51,26 +76,33 @@ func (w *responseWriter) WriteHeader(status int) {
 
 	if status < 100 || status >= 200 {
 		w.headerWritten = true
+		w.declareTrailers()
 	}
 	w.status = status
 
-	var headers bytes.Buffer
-	enc := qpack.NewEncoder(&headers)
-	enc.WriteField(qpack.HeaderField{Name: ":status", Value: strconv.Itoa(status)})
-
+	fields := []qpack.HeaderField{{Name: ":status", Value: strconv.Itoa(status)}}
 	for k, v := range w.header {
+		// Keys with the TrailerPrefix are sent in the trailer.
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			continue
+		}
 		for index := range v {
-			enc.WriteField(qpack.HeaderField{Name: strings.ToLower(k), Value: v[index]})
+			fields = append(fields, qpack.HeaderField{Name: strings.ToLower(k), Value: v[index]})
 		}
 	}
+	headers, err := w.encoder.encode(w.str.StreamID(), fields)
+	if err != nil {
+		w.logger.Errorf("could not encode headers: %s", err.Error())
+		return
+	}
 
 	w.buf = w.buf[:0]
-	w.buf = (&headersFrame{Length: uint64(headers.Len())}).Append(w.buf)
+	w.buf = (&headersFrame{Length: uint64(len(headers))}).Append(w.buf)
 	w.logger.Infof("Responding with %d", status)
 	if _, err := w.bufferedStr.Write(w.buf); err != nil {
 		w.logger.Errorf("could not write headers frame: %s", err.Error())
 	}
-	if _, err := w.bufferedStr.Write(headers.Bytes()); err != nil {
+	if _, err := w.bufferedStr.Write(headers); err != nil {
 		w.logger.Errorf("could not write header frame payload: %s", err.Error())
 	}
 	if !w.headerWritten {
@@ -94,6 +126,54 @@ func (w *responseWriter) Write(p []byte) (int, error) {
 	return w.bufferedStr.Write(p)
 }
 
+// declareTrailers records the trailers declared using the Trailer header
+func (w *responseWriter) declareTrailers() {
+	for _, v := range w.header["Trailer"] {
+		for _, k := range strings.Split(v, ",") {
+			k = http.CanonicalHeaderKey(strings.TrimSpace(k))
+			if k == "" {
+				continue
+			}
+			if w.trailers == nil {
+				w.trailers = make(map[string]struct{})
+			}
+			w.trailers[k] = struct{}{}
+		}
+	}
+}
+
+// writeTrailers sends the trailer (a HEADERS frame after the DATA frames), if any trailers were set.
+// This includes the trailers declared using the Trailer header,
+// as well as all header keys with the http.TrailerPrefix.
+func (w *responseWriter) writeTrailers() error {
+	var fields []qpack.HeaderField
+	for k, v := range w.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			k = strings.TrimPrefix(k, http.TrailerPrefix)
+		} else if _, ok := w.trailers[k]; !ok {
+			continue
+		}
+		for index := range v {
+			fields = append(fields, qpack.HeaderField{Name: strings.ToLower(k), Value: v[index]})
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	headers, err := w.encoder.encode(w.str.StreamID(), fields)
+	if err != nil {
+		return err
+	}
+
+	w.buf = w.buf[:0]
+	w.buf = (&headersFrame{Length: uint64(len(headers))}).Append(w.buf)
+	if _, err := w.bufferedStr.Write(w.buf); err != nil {
+		return err
+	}
+	_, err = w.bufferedStr.Write(headers)
+	return err
+}
+
 func (w *responseWriter) Flush() {
 	if err := w.bufferedStr.Flush(); err != nil {
 		w.logger.Errorf("could not flush to stream: %s", err.Error())
@@ -104,6 +184,20 @@ func (w *responseWriter) StreamCreator() StreamCreator {
 	return w.conn
 }
 
+func (w *responseWriter) Connection() quic.Connection {
+	return w.conn
+}
+
+func (w *responseWriter) QUICStream() quic.Stream {
+	w.Flush()
+	w.wasTakenOver = true
+	return w.str
+}
+
+func (w *responseWriter) wasStreamTakenOver() bool {
+	return w.wasTakenOver
+}
+
 // copied from http2/http2.go
 // bodyAllowedForStatus reports whether a given response status code
 // permits a body. See RFC 2616, section 4.4.
@@ -18,14 +18,16 @@ import (
 var _ = Describe("Response Writer", func() {
 	var (
 		rw     *responseWriter
+		str    *mockquic.MockStream
 		strBuf *bytes.Buffer
 	)
 
 	BeforeEach(func() {
 		strBuf = &bytes.Buffer{}
-		str := mockquic.NewMockStream(mockCtrl)
+		str = mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().StreamID().AnyTimes()
 		str.EXPECT().Write(gomock.Any()).DoAndReturn(strBuf.Write).AnyTimes()
-		rw = newResponseWriter(str, nil, utils.DefaultLogger)
+		rw = newResponseWriter(str, nil, newQPACKEncoder(0, utils.DefaultLogger), utils.DefaultLogger)
 	})
 
 	decodeHeader := func(str io.Reader) map[string][]string {
@@ -147,4 +149,57 @@ var _ = Describe("Response Writer", func() {
 		Expect(n).To(BeZero())
 		Expect(err).To(MatchError(http.ErrBodyNotAllowed))
 	})
+
+	It("sends trailers declared using the Trailer header", func() {
+		rw.Header().Set("Trailer", "X-Foo, x-bar")
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.WriteHeader(http.StatusOK)
+		rw.Write([]byte("foobar"))
+		rw.Header().Set("X-Foo", "foo")
+		rw.Header().Set("X-Bar", "bar")
+		rw.Header().Set("X-Undeclared", "ignored")
+		Expect(rw.writeTrailers()).To(Succeed())
+
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
+		Expect(fields).To(HaveKeyWithValue("trailer", []string{"X-Foo, x-bar"}))
+		Expect(fields).ToNot(HaveKey("x-foo"))
+		Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+		trailer := decodeHeader(strBuf)
+		Expect(trailer).To(HaveLen(2))
+		Expect(trailer).To(HaveKeyWithValue("x-foo", []string{"foo"}))
+		Expect(trailer).To(HaveKeyWithValue("x-bar", []string{"bar"}))
+	})
+
+	It("sends trailers set using the TrailerPrefix", func() {
+		rw.Header().Set(http.TrailerPrefix+"X-Foo", "before")
+		rw.Write([]byte("foobar"))
+		rw.Header().Add(http.TrailerPrefix+"X-Foo", "after")
+		Expect(rw.writeTrailers()).To(Succeed())
+
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
+		Expect(fields).To(HaveLen(1))
+		Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+		trailer := decodeHeader(strBuf)
+		Expect(trailer).To(HaveKeyWithValue("x-foo", []string{"before", "after"}))
+	})
+
+	It("doesn't send a trailer if no trailers were set", func() {
+		rw.Write([]byte("foobar"))
+		Expect(rw.writeTrailers()).To(Succeed())
+		decodeHeader(strBuf)
+		Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+		Expect(strBuf.Len()).To(BeZero())
+	})
+
+	It("flushes the response when the QUIC stream is taken over", func() {
+		rw.WriteHeader(http.StatusOK)
+		Expect(strBuf.Len()).To(BeZero())
+		Expect(rw.wasStreamTakenOver()).To(BeFalse())
+		Expect(rw.QUICStream()).To(Equal(str))
+		Expect(rw.wasStreamTakenOver()).To(BeTrue())
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
+	})
 })
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
@@ -18,8 +19,15 @@ import (
 type roundTripCloser interface {
 	RoundTripOpt(*http.Request, RoundTripOpt) (*http.Response, error)
 	io.Closer
+	// closeWhenIdle closes the connection once all requests in flight have completed.
+	closeWhenIdle()
+	coalesce(authority string, ips []net.IPAddr) bool
+	// usesConn says if requests are sent on conn
+	usesConn(conn quic.EarlyConnection) bool
 }
 
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
 // RoundTripper implements the http.RoundTripper interface
 type RoundTripper struct {
 	mutex sync.Mutex
@@ -44,6 +52,8 @@ type RoundTripper struct {
 
 	// Enable support for HTTP/3 datagrams.
 	// If set to true, QuicConfig.EnableDatagram will be set.
+	// Datagrams associated with a request can be sent and received using the Datagrammer implemented by the http.Response.Body.
+	// Once the application receives a datagram that way, the QUIC connection's ReceiveMessage is used by the HTTP/3 layer.
 	// See https://www.ietf.org/archive/id/draft-schinazi-masque-h3-datagram-02.html.
 	EnableDatagrams bool
 
@@ -75,7 +85,31 @@ type RoundTripper struct {
 	// Zero means to use a default limit.
 	MaxResponseHeaderBytes int64
 
-	clients map[string]roundTripCloser
+	// QPACKMaxTableCapacity is the maximum capacity of the QPACK dynamic table,
+	// for the headers sent by the client as well as for the headers sent by the server.
+	// If zero or negative (the default), the dynamic table is not used, and headers are only compressed using the static table.
+	// The dynamic table is only used if both endpoints enable it. A value of 4096 bytes is a good starting point.
+	QPACKMaxTableCapacity int
+
+	// QPACKBlockedStreams is the maximum number of request streams that can be blocked,
+	// waiting for QPACK dynamic table updates sent by the server.
+	// It is only used if the dynamic table is enabled (see QPACKMaxTableCapacity).
+	// If zero, a default value of 16 is used. If negative, no streams can be blocked.
+	QPACKBlockedStreams int
+
+	// DisableConnectionCoalescing, if true, prevents the RoundTripper from reusing
+	// a connection for requests to a different host (see Section 3.3 of RFC 9114).
+	// By default, a connection is reused if the host resolves to the IP address the connection
+	// was established to, and the certificate presented by the server is valid for the host.
+	DisableConnectionCoalescing bool
+
+	// ConnPool is the pool of connections used for sending requests.
+	// It can be shared by multiple RoundTrippers, see ConnPool for details.
+	// If nil, the RoundTripper uses a pool of its own.
+	ConnPool *ConnPool
+
+	connPool      ConnPool // used if ConnPool is nil
+	pinnedClients map[quic.EarlyConnection]roundTripCloser
 }
 
 // RoundTripOpt are options for the Transport.RoundTripOpt method.
@@ -86,6 +120,18 @@ type RoundTripOpt struct {
 	// DontCloseRequestStream controls whether the request stream is closed after sending the request.
 	// If set, context cancellations have no effect after the response headers are received.
 	DontCloseRequestStream bool
+	// Conn pins the request to an existing QUIC connection, which must have been established using the HTTP/3 ALPN.
+	// If set, the RoundTripper neither uses its cached connections nor dials a new connection,
+	// and requests for any host are sent on this connection.
+	// It is the caller's responsibility to make sure that the server is authoritative for the host.
+	// If the connection was closed, RoundTripOpt returns ErrConnClosed.
+	//
+	// When the connection is first used, the RoundTripper opens the HTTP/3 control stream and sends a SETTINGS frame.
+	// The connection therefore must not be used by any other HTTP/3 client (including other RoundTrippers),
+	// since the server would close the connection when it receives a second control stream (see Section 6.2.1 of RFC 9114).
+	// RoundTripOpt returns ErrConnInUse if the connection was dialed by this RoundTripper.
+	// Other HTTP/3 clients using the connection can't be detected.
+	Conn quic.EarlyConnection
 }
 
 var (
@@ -96,6 +142,17 @@ var (
 // ErrNoCachedConn is returned when RoundTripper.OnlyCachedConn is set
 var ErrNoCachedConn = errors.New("http3: no cached connection was available")
 
+// ErrConnClosed is returned when the connection passed in RoundTripOpt.Conn was closed
+var ErrConnClosed = errors.New("http3: connection was closed")
+
+// ErrConnInUse is returned when the connection passed in RoundTripOpt.Conn is already used by another HTTP/3 client
+var ErrConnInUse = errors.New("http3: connection is already used by another HTTP/3 client")
+
+// ErrGoAway is returned when a request wasn't processed since the server sent a GOAWAY on the connection.
+// It is safe to retry the request on a new connection.
+// The RoundTripper does so automatically, if the request body can be rewound.
+var ErrGoAway = errors.New("http3: server sent GOAWAY")
+
 // RoundTripOpt is like RoundTrip, but takes options.
 func (r *RoundTripper) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Response, error) {
 	if req.URL == nil {
@@ -132,65 +189,159 @@ func (r *RoundTripper) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.
 		return nil, fmt.Errorf("http3: invalid method %q", req.Method)
 	}
 
-	hostname := authorityAddr("https", hostnameFromRequest(req))
-	cl, err := r.getClient(hostname, opt.OnlyCachedConn)
+	if opt.Conn != nil {
+		cl, err := r.getPinnedClient(opt.Conn)
+		if err != nil {
+			return nil, err
+		}
+		return cl.RoundTripOpt(req, opt)
+	}
+
+	key := r.connPoolKey(authorityAddr("https", hostnameFromRequest(req)))
+	cl, err := r.getClient(req.Context(), key, opt.OnlyCachedConn)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := cl.RoundTripOpt(req, opt)
+	if !errors.Is(err, ErrGoAway) {
+		return rsp, err
+	}
+	// The server is shutting down the connection, and didn't process this request.
+	// Don't use this connection for any new requests, and retry the request on a new connection.
+	r.pool().removeClient(cl)
+	req, rerr := rewindBody(req)
+	if rerr != nil {
+		return nil, err
+	}
+	cl, err = r.getClient(req.Context(), key, opt.OnlyCachedConn)
 	if err != nil {
 		return nil, err
 	}
 	return cl.RoundTripOpt(req, opt)
 }
 
+// rewindBody returns a copy of the request that can be sent again
+func rewindBody(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("http3: can't rewind request body")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	newReq := *req
+	newReq.Body = body
+	return &newReq, nil
+}
+
 // RoundTrip does a round trip.
 func (r *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	return r.RoundTripOpt(req, RoundTripOpt{})
 }
 
-func (r *RoundTripper) getClient(hostname string, onlyCached bool) (roundTripCloser, error) {
+func (r *RoundTripper) getClient(ctx context.Context, key connPoolKey, onlyCached bool) (roundTripCloser, error) {
+	// Resolve the host before acquiring the lock, so that other requests aren't blocked by the DNS lookup.
+	var ips []net.IPAddr
+	if !r.DisableConnectionCoalescing && r.pool().canCoalesce(key) {
+		ips = resolveHost(ctx, key.authority)
+	}
+
+	return r.pool().getClient(key, ips, !r.DisableConnectionCoalescing, onlyCached, func() (roundTripCloser, error) {
+		return newClient(key.authority, r.TLSClientConfig, r.opts(), r.QuicConfig, r.Dial)
+	})
+}
+
+// connPoolKey returns the key of the connection used for requests to authority
+func (r *RoundTripper) connPoolKey(authority string) connPoolKey {
+	return connPoolKey{
+		authority: authority,
+		tlsConf:   r.TLSClientConfig,
+		quicConf:  r.QuicConfig,
+	}
+}
+
+// getPinnedClient returns the client for a connection passed in RoundTripOpt.Conn.
+// The client is removed once the connection is closed.
+func (r *RoundTripper) getPinnedClient(conn quic.EarlyConnection) (roundTripCloser, error) {
+	if conn.Context().Err() != nil {
+		return nil, ErrConnClosed
+	}
+
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if r.clients == nil {
-		r.clients = make(map[string]roundTripCloser)
+	if r.pinnedClients == nil {
+		r.pinnedClients = make(map[quic.EarlyConnection]roundTripCloser)
 	}
-
-	client, ok := r.clients[hostname]
+	client, ok := r.pinnedClients[conn]
 	if !ok {
-		if onlyCached {
-			return nil, ErrNoCachedConn
-		}
-		var err error
-		client, err = newClient(
-			hostname,
-			r.TLSClientConfig,
-			&roundTripperOpts{
-				EnableDatagram:     r.EnableDatagrams,
-				DisableCompression: r.DisableCompression,
-				MaxHeaderBytes:     r.MaxResponseHeaderBytes,
-				StreamHijacker:     r.StreamHijacker,
-				UniStreamHijacker:  r.UniStreamHijacker,
-			},
-			r.QuicConfig,
-			r.Dial,
-		)
-		if err != nil {
-			return nil, err
+		if r.pool().usesConn(conn) {
+			return nil, ErrConnInUse
 		}
-		r.clients[hostname] = client
+		client = newClientForConn(conn, r.opts())
+		r.pinnedClients[conn] = client
+		go func() {
+			<-conn.Context().Done()
+			r.mutex.Lock()
+			if r.pinnedClients[conn] == client {
+				delete(r.pinnedClients, conn)
+			}
+			r.mutex.Unlock()
+		}()
 	}
 	return client, nil
 }
 
-// Close closes the QUIC connections that this RoundTripper has used
+// resolveHost resolves the host of an authority.
+// Errors are ignored, since they will be reported when dialing a new connection.
+func resolveHost(ctx context.Context, authority string) []net.IPAddr {
+	host, _, err := net.SplitHostPort(authority)
+	if err != nil {
+		return nil
+	}
+	ips, err := lookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	return ips
+}
+
+func (r *RoundTripper) opts() *roundTripperOpts {
+	return &roundTripperOpts{
+		EnableDatagram:        r.EnableDatagrams,
+		DisableCompression:    r.DisableCompression,
+		MaxHeaderBytes:        r.MaxResponseHeaderBytes,
+		QPACKMaxTableCapacity: r.QPACKMaxTableCapacity,
+		QPACKBlockedStreams:   r.QPACKBlockedStreams,
+		StreamHijacker:        r.StreamHijacker,
+		UniStreamHijacker:     r.UniStreamHijacker,
+	}
+}
+
+// pool returns the ConnPool, or the RoundTripper's own pool if no ConnPool was set
+func (r *RoundTripper) pool() *ConnPool {
+	if r.ConnPool != nil {
+		return r.ConnPool
+	}
+	return &r.connPool
+}
+
+// Close closes the QUIC connections that this RoundTripper has dialed.
+// Connections passed in RoundTripOpt.Conn are not closed.
+// Since the control stream was already opened on these connections, they can't be used with RoundTripOpt.Conn again.
+// If a ConnPool is set, the connections in the pool are not closed either, they are closed by ConnPool.Close.
 func (r *RoundTripper) Close() error {
 	r.mutex.Lock()
-	defer r.mutex.Unlock()
-	for _, client := range r.clients {
-		if err := client.Close(); err != nil {
-			return err
-		}
+	r.pinnedClients = nil
+	r.mutex.Unlock()
+
+	if r.ConnPool != nil {
+		return nil
 	}
-	r.clients = nil
-	return nil
+	return r.connPool.Close()
 }
 
 func closeRequestBody(req *http.Request) {
@@ -6,21 +6,33 @@ import (
 	"crypto/tls"
 	"errors"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"time"
 
-	"github.com/golang/mock/gomock"
 	"github.com/fkwhite/quic-go"
 	mockquic "github.com/fkwhite/quic-go/internal/mocks/quic"
+	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
 type mockClient struct {
-	closed bool
+	err          error
+	wroteHeaders bool // if set, the request headers are reported as written before returning err
+	closed       bool
+	canCoalesce  bool
+	conn         quic.EarlyConnection
 }
 
 func (m *mockClient) RoundTripOpt(req *http.Request, _ RoundTripOpt) (*http.Response, error) {
+	if m.err != nil {
+		if trace := httptrace.ContextClientTrace(req.Context()); m.wroteHeaders && trace != nil && trace.WroteHeaders != nil {
+			trace.WroteHeaders()
+		}
+		return nil, m.err
+	}
 	return &http.Response{Request: req}, nil
 }
 
@@ -29,6 +41,18 @@ func (m *mockClient) Close() error {
 	return nil
 }
 
+func (m *mockClient) closeWhenIdle() {
+	m.closed = true
+}
+
+func (m *mockClient) coalesce(string, []net.IPAddr) bool {
+	return m.canCoalesce
+}
+
+func (m *mockClient) usesConn(conn quic.EarlyConnection) bool {
+	return m.conn == conn
+}
+
 var _ roundTripCloser = &mockClient{}
 
 type mockBody struct {
@@ -108,7 +132,7 @@ var _ = Describe("RoundTripper", func() {
 			conn.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(quic.ApplicationErrorCode, string) { close(closed) })
 			_, err = rt.RoundTrip(req)
 			Expect(err).To(MatchError(testErr))
-			Expect(rt.clients).To(HaveLen(1))
+			Expect(rt.connPool.clients).To(HaveLen(1))
 			Eventually(closed).Should(BeClosed())
 		})
 
@@ -152,15 +176,48 @@ var _ = Describe("RoundTripper", func() {
 			Expect(err).ToNot(HaveOccurred())
 			_, err = rt.RoundTrip(req)
 			Expect(err).To(MatchError(testErr))
-			Expect(rt.clients).To(HaveLen(1))
+			Expect(rt.connPool.clients).To(HaveLen(1))
 			req2, err := http.NewRequest("GET", "https://quic.clemente.io/file2.html", nil)
 			Expect(err).ToNot(HaveOccurred())
 			_, err = rt.RoundTrip(req2)
 			Expect(err).To(MatchError(testErr))
-			Expect(rt.clients).To(HaveLen(1))
+			Expect(rt.connPool.clients).To(HaveLen(1))
 			Eventually(closed).Should(BeClosed())
 		})
 
+		It("retries a request on a new connection when the server sent a GOAWAY", func() {
+			closed := make(chan struct{})
+			testErr := errors.New("test err")
+			conn.EXPECT().OpenUniStream().AnyTimes().Return(nil, testErr)
+			conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			conn.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr)
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-closed
+				return nil, errors.New("test done")
+			}).MaxTimes(1)
+			conn.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(quic.ApplicationErrorCode, string) { close(closed) })
+			goingAway := &mockClient{err: ErrGoAway}
+			rt.connPool.clients = map[connPoolKey]roundTripCloser{{authority: "quic.clemente.io:443"}: goingAway}
+			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(req)
+			Expect(err).To(MatchError(testErr))
+			Expect(rt.connPool.clients).To(HaveLen(1))
+			Expect(rt.connPool.clients[connPoolKey{authority: "quic.clemente.io:443"}]).ToNot(BeIdenticalTo(goingAway))
+			Expect(goingAway.closed).To(BeTrue())
+			Eventually(closed).Should(BeClosed())
+		})
+
+		It("doesn't retry a request after a GOAWAY if the body can't be rewound", func() {
+			goingAway := &mockClient{err: ErrGoAway}
+			rt.connPool.clients = map[connPoolKey]roundTripCloser{{authority: "quic.clemente.io:443"}: goingAway}
+			req, err := http.NewRequest("POST", "https://quic.clemente.io/upload", &mockBody{})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(req)
+			Expect(err).To(MatchError(ErrGoAway))
+			Expect(rt.connPool.clients).To(BeEmpty())
+		})
+
 		It("doesn't create new clients if RoundTripOpt.OnlyCachedConn is set", func() {
 			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
 			Expect(err).ToNot(HaveOccurred())
@@ -169,6 +226,136 @@ var _ = Describe("RoundTripper", func() {
 		})
 	})
 
+	Context("pinning connections", func() {
+		BeforeEach(func() {
+			conn = mockquic.NewMockEarlyConnection(mockCtrl)
+		})
+
+		It("sends requests on the pinned connection", func() {
+			closed := make(chan struct{})
+			testErr := errors.New("test err")
+			connCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			conn.EXPECT().Context().Return(connCtx).AnyTimes()
+			conn.EXPECT().OpenUniStream().AnyTimes().Return(nil, testErr)
+			conn.EXPECT().HandshakeComplete().Return(handshakeCtx).Times(2)
+			conn.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr).Times(2)
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-closed
+				return nil, errors.New("test done")
+			}).MaxTimes(1)
+			conn.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(quic.ApplicationErrorCode, string) { close(closed) })
+			// requests for different hosts can be sent on the same connection
+			req2, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			for _, req := range []*http.Request{req1, req2} {
+				_, err := rt.RoundTripOpt(req, RoundTripOpt{Conn: conn})
+				Expect(err).To(MatchError(testErr))
+			}
+			Expect(rt.connPool.clients).To(BeEmpty())
+			Expect(rt.pinnedClients).To(HaveLen(1))
+			Eventually(closed).Should(BeClosed())
+			// the client is removed once the connection is closed
+			cancel()
+			Eventually(func() int {
+				rt.mutex.Lock()
+				defer rt.mutex.Unlock()
+				return len(rt.pinnedClients)
+			}).Should(BeZero())
+		})
+
+		It("errors if the pinned connection was closed", func() {
+			connCtx, cancel := context.WithCancel(context.Background())
+			cancel()
+			conn.EXPECT().Context().Return(connCtx).AnyTimes()
+			_, err := rt.RoundTripOpt(req1, RoundTripOpt{Conn: conn})
+			Expect(err).To(MatchError(ErrConnClosed))
+			Expect(rt.pinnedClients).To(BeEmpty())
+		})
+
+		It("stops using the client once the pinned connection is closed", func() {
+			connCtx, cancel := context.WithCancel(context.Background())
+			conn.EXPECT().Context().Return(connCtx).AnyTimes()
+			cl := &mockClient{}
+			rt.pinnedClients = map[quic.EarlyConnection]roundTripCloser{conn: cl}
+			_, err := rt.RoundTripOpt(req1, RoundTripOpt{Conn: conn})
+			Expect(err).ToNot(HaveOccurred())
+			cancel()
+			_, err = rt.RoundTripOpt(req1, RoundTripOpt{Conn: conn})
+			Expect(err).To(MatchError(ErrConnClosed))
+		})
+
+		It("errors if the pinned connection was dialed by the RoundTripper", func() {
+			connCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			conn.EXPECT().Context().Return(connCtx).AnyTimes()
+			rt.connPool.clients = map[connPoolKey]roundTripCloser{{authority: "www.example.org:443"}: &mockClient{conn: conn}}
+			_, err := rt.RoundTripOpt(req1, RoundTripOpt{Conn: conn})
+			Expect(err).To(MatchError(ErrConnInUse))
+			Expect(rt.pinnedClients).To(BeEmpty())
+		})
+
+		It("doesn't close pinned connections", func() {
+			cl := &mockClient{}
+			rt.pinnedClients = map[quic.EarlyConnection]roundTripCloser{conn: cl}
+			Expect(rt.Close()).To(Succeed())
+			Expect(cl.closed).To(BeFalse())
+			Expect(rt.pinnedClients).To(BeEmpty())
+		})
+	})
+
+	Context("coalescing connections", func() {
+		origLookupIPAddr := lookupIPAddr
+
+		BeforeEach(func() {
+			origLookupIPAddr = lookupIPAddr
+			lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+				Expect(host).To(Equal("www.example.org"))
+				return []net.IPAddr{{IP: net.IPv4(192, 0, 2, 1)}}, nil
+			}
+		})
+
+		AfterEach(func() {
+			lookupIPAddr = origLookupIPAddr
+		})
+
+		It("reuses the connection of another host", func() {
+			cl := &mockClient{canCoalesce: true}
+			rt.connPool.clients = map[connPoolKey]roundTripCloser{{authority: "quic.clemente.io:443"}: cl}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rt.connPool.clients).To(HaveKeyWithValue(connPoolKey{authority: "www.example.org:443", coalesced: true}, cl))
+			Expect(rt.Close()).To(Succeed())
+			Expect(cl.closed).To(BeTrue())
+		})
+
+		It("doesn't reuse connections that can't be coalesced", func() {
+			rt.connPool.clients = map[connPoolKey]roundTripCloser{{authority: "quic.clemente.io:443"}: &mockClient{}}
+			_, err := rt.RoundTripOpt(req1, RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).To(MatchError(ErrNoCachedConn))
+		})
+
+		It("doesn't reuse connections if the host can't be resolved", func() {
+			lookupIPAddr = func(context.Context, string) ([]net.IPAddr, error) {
+				return nil, errors.New("no such host")
+			}
+			rt.connPool.clients = map[connPoolKey]roundTripCloser{{authority: "quic.clemente.io:443"}: &mockClient{canCoalesce: true}}
+			_, err := rt.RoundTripOpt(req1, RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).To(MatchError(ErrNoCachedConn))
+		})
+
+		It("doesn't coalesce connections if disabled", func() {
+			lookupIPAddr = func(context.Context, string) ([]net.IPAddr, error) {
+				Fail("didn't expect a DNS lookup")
+				return nil, nil
+			}
+			rt.DisableConnectionCoalescing = true
+			rt.connPool.clients = map[connPoolKey]roundTripCloser{{authority: "quic.clemente.io:443"}: &mockClient{canCoalesce: true}}
+			_, err := rt.RoundTripOpt(req1, RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).To(MatchError(ErrNoCachedConn))
+		})
+	})
+
 	Context("validating request", func() {
 		It("rejects plain HTTP requests", func() {
 			req, err := http.NewRequest("GET", "http://www.example.org/", nil)
@@ -231,22 +418,112 @@ var _ = Describe("RoundTripper", func() {
 		})
 	})
 
+	Context("sharing a connection pool", func() {
+		var pool *ConnPool
+
+		BeforeEach(func() {
+			pool = &ConnPool{}
+			rt.ConnPool = pool
+		})
+
+		It("uses the connections of the pool", func() {
+			cl := &mockClient{}
+			pool.clients = map[connPoolKey]roundTripCloser{{authority: "www.example.org:443"}: cl}
+			rt2 := &RoundTripper{ConnPool: pool}
+			for _, r := range []*RoundTripper{rt, rt2} {
+				rsp, err := r.RoundTripOpt(req1, RoundTripOpt{OnlyCachedConn: true})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.Request).To(Equal(req1))
+			}
+			Expect(rt.connPool.clients).To(BeEmpty())
+		})
+
+		It("adds new connections to the pool", func() {
+			dialed := make(chan struct{})
+			rt.Dial = func(context.Context, string, *tls.Config, *quic.Config) (quic.EarlyConnection, error) {
+				close(dialed)
+				return nil, errors.New("handshake error")
+			}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).To(MatchError("handshake error"))
+			Eventually(dialed).Should(BeClosed())
+			Expect(pool.clients).To(HaveKey(connPoolKey{authority: "www.example.org:443"}))
+		})
+
+		It("removes clients from the pool when the server sends a GOAWAY", func() {
+			goingAway := &mockClient{err: ErrGoAway}
+			pool.clients = map[connPoolKey]roundTripCloser{{authority: "www.example.org:443"}: goingAway}
+			_, err := rt.RoundTripOpt(req1, RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).To(MatchError(ErrNoCachedConn))
+			Expect(pool.clients).To(BeEmpty())
+			Expect(goingAway.closed).To(BeTrue())
+		})
+
+		It("removes coalesced clients for all hosts when the server sends a GOAWAY", func() {
+			goingAway := &mockClient{err: ErrGoAway}
+			pool.clients = map[connPoolKey]roundTripCloser{
+				{authority: "www.example.org:443"}:              goingAway,
+				{authority: "example.com:443", coalesced: true}: goingAway,
+				{authority: "example.net:443"}:                  &mockClient{},
+			}
+			_, err := rt.RoundTripOpt(req1, RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).To(MatchError(ErrNoCachedConn))
+			Expect(pool.clients).To(HaveLen(1))
+			Expect(pool.clients).To(HaveKey(connPoolKey{authority: "example.net:443"}))
+			Expect(goingAway.closed).To(BeTrue())
+		})
+
+		It("doesn't share connections between RoundTrippers using different configurations", func() {
+			cl := &mockClient{}
+			pool.clients = map[connPoolKey]roundTripCloser{{authority: "www.example.org:443"}: cl}
+			for _, r := range []*RoundTripper{
+				{ConnPool: pool, TLSClientConfig: &tls.Config{}},
+				{ConnPool: pool, QuicConfig: &quic.Config{}},
+			} {
+				_, err := r.RoundTripOpt(req1, RoundTripOpt{OnlyCachedConn: true})
+				Expect(err).To(MatchError(ErrNoCachedConn))
+			}
+		})
+
+		It("doesn't use coalesced connections if coalescing is disabled", func() {
+			cl := &mockClient{}
+			pool.clients = map[connPoolKey]roundTripCloser{{authority: "www.example.org:443", coalesced: true}: cl}
+			rt2 := &RoundTripper{ConnPool: pool, DisableConnectionCoalescing: true}
+			_, err := rt2.RoundTripOpt(req1, RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).To(MatchError(ErrNoCachedConn))
+			// the RoundTripper that coalesced the connection still uses it
+			rsp, err := rt.RoundTripOpt(req1, RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.Request).To(Equal(req1))
+		})
+
+		It("doesn't close the pool when the RoundTripper is closed", func() {
+			cl := &mockClient{}
+			pool.clients = map[connPoolKey]roundTripCloser{{authority: "www.example.org:443"}: cl}
+			Expect(rt.Close()).To(Succeed())
+			Expect(cl.closed).To(BeFalse())
+			Expect(pool.Close()).To(Succeed())
+			Expect(cl.closed).To(BeTrue())
+			Expect(pool.clients).To(BeEmpty())
+		})
+	})
+
 	Context("closing", func() {
 		It("closes", func() {
-			rt.clients = make(map[string]roundTripCloser)
+			rt.connPool.clients = make(map[connPoolKey]roundTripCloser)
 			cl := &mockClient{}
-			rt.clients["foo.bar"] = cl
+			rt.connPool.clients[connPoolKey{authority: "foo.bar"}] = cl
 			err := rt.Close()
 			Expect(err).ToNot(HaveOccurred())
-			Expect(len(rt.clients)).To(BeZero())
+			Expect(len(rt.connPool.clients)).To(BeZero())
 			Expect(cl.closed).To(BeTrue())
 		})
 
 		It("closes a RoundTripper that has never been used", func() {
-			Expect(len(rt.clients)).To(BeZero())
+			Expect(len(rt.connPool.clients)).To(BeZero())
 			err := rt.Close()
 			Expect(err).ToNot(HaveOccurred())
-			Expect(len(rt.clients)).To(BeZero())
+			Expect(len(rt.connPool.clients)).To(BeZero())
 		})
 	})
 })
@@ -18,7 +18,6 @@ import (
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/utils"
 	"github.com/fkwhite/quic-go/quicvarint"
-	"github.com/marten-seemann/qpack"
 )
 
 // allows mocking of quic.Listen and quic.ListenAddr
@@ -27,6 +26,11 @@ var (
 	quicListenAddr = quic.ListenAddrEarly
 )
 
+// goAwayGracePeriod is the time that a connection is kept open after sending the GOAWAY frame,
+// even if there are no active requests.
+// This gives requests that the client sent before it received the GOAWAY frame time to arrive.
+var goAwayGracePeriod = time.Second
+
 const (
 	nextProtoH3Draft29 = "h3-29"
 	nextProtoH3        = "h3"
@@ -160,6 +164,8 @@ type Server struct {
 
 	// EnableDatagrams enables support for HTTP/3 datagrams.
 	// If set to true, QuicConfig.EnableDatagram will be set.
+	// Datagrams associated with a request can be sent and received using the Datagrammer implemented by the http.Request.Body.
+	// Once the application receives a datagram that way, the QUIC connection's ReceiveMessage is used by the HTTP/3 layer.
 	// See https://datatracker.ietf.org/doc/html/draft-ietf-masque-h3-datagram-07.
 	EnableDatagrams bool
 
@@ -169,6 +175,18 @@ type Server struct {
 	// used.
 	MaxHeaderBytes int
 
+	// QPACKMaxTableCapacity is the maximum capacity of the QPACK dynamic table,
+	// for the headers sent by the client as well as for the headers sent by the server.
+	// If zero or negative (the default), the dynamic table is not used, and headers are only compressed using the static table.
+	// The dynamic table is only used if both endpoints enable it. A value of 4096 bytes is a good starting point.
+	QPACKMaxTableCapacity int
+
+	// QPACKBlockedStreams is the maximum number of request streams that can be blocked,
+	// waiting for QPACK dynamic table updates sent by the client.
+	// It is only used if the dynamic table is enabled (see QPACKMaxTableCapacity).
+	// If zero, a default value of 16 is used. If negative, no streams can be blocked.
+	QPACKBlockedStreams int
+
 	// AdditionalSettings specifies additional HTTP/3 settings.
 	// It is invalid to specify any settings defined by the HTTP/3 draft and the datagram draft.
 	AdditionalSettings map[uint64]uint64
@@ -189,6 +207,7 @@ type Server struct {
 
 	mutex     sync.RWMutex
 	listeners map[*quic.EarlyListener]listenerInfo
+	conns     map[*serverConn]struct{}
 
 	closed bool
 
@@ -404,7 +423,8 @@ func (s *Server) removeListener(l *quic.EarlyListener) {
 }
 
 func (s *Server) handleConn(conn quic.EarlyConnection) {
-	decoder := qpack.NewDecoder(nil)
+	decoder := newQPACKDecoder(s.qpackMaxTableCapacity(), s.qpackBlockedStreams(), s.logger)
+	encoder := newQPACKEncoder(s.qpackMaxTableCapacity(), s.logger)
 
 	// send a SETTINGS frame
 	str, err := conn.OpenUniStream()
@@ -414,10 +434,36 @@ func (s *Server) handleConn(conn quic.EarlyConnection) {
 	}
 	b := make([]byte, 0, 64)
 	b = quicvarint.Append(b, streamTypeControlStream) // stream type
-	b = (&settingsFrame{Datagram: s.EnableDatagrams, Other: s.AdditionalSettings}).Append(b)
+	b = (&settingsFrame{
+		QPACKMaxTableCapacity: s.qpackMaxTableCapacity(),
+		QPACKBlockedStreams:   s.qpackBlockedStreams(),
+		Datagram:              s.EnableDatagrams,
+		Other:                 s.AdditionalSettings,
+	}).Append(b)
 	str.Write(b)
 
-	go s.handleUnidirectionalStreams(conn)
+	sc := &serverConn{EarlyConnection: conn, controlStr: str}
+	if s.EnableDatagrams {
+		sc.datagrams = newDatagramMux(conn, s.logger)
+	}
+	s.mutex.Lock()
+	if s.conns == nil {
+		s.conns = make(map[*serverConn]struct{})
+	}
+	s.conns[sc] = struct{}{}
+	closed := s.closed
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.conns, sc)
+		s.mutex.Unlock()
+	}()
+	// The server is already shutting down. Don't accept any requests on this connection.
+	if closed {
+		sc.goAway()
+	}
+
+	go s.handleUnidirectionalStreams(conn, sc.datagrams, decoder, encoder)
 
 	// Process all requests immediately.
 	// It's the client's responsibility to decide which requests are eligible for 0-RTT.
@@ -427,8 +473,15 @@ func (s *Server) handleConn(conn quic.EarlyConnection) {
 			s.logger.Debugf("Accepting stream failed: %s", err)
 			return
 		}
+		if !sc.startRequest(str.StreamID()) {
+			s.logger.Debugf("Rejecting request on stream %d, since a GOAWAY was sent.", str.StreamID())
+			str.CancelRead(quic.StreamErrorCode(errorRequestRejected))
+			str.CancelWrite(quic.StreamErrorCode(errorRequestRejected))
+			continue
+		}
 		go func() {
-			rerr := s.handleRequest(conn, str, decoder, func() {
+			defer sc.finishRequest()
+			rerr := s.handleRequest(conn, str, sc.datagrams, decoder, encoder, func() {
 				conn.CloseWithError(quic.ApplicationErrorCode(errorFrameUnexpected), "")
 			})
 			if rerr.err == errHijacked {
@@ -453,7 +506,7 @@ func (s *Server) handleConn(conn quic.EarlyConnection) {
 	}
 }
 
-func (s *Server) handleUnidirectionalStreams(conn quic.EarlyConnection) {
+func (s *Server) handleUnidirectionalStreams(conn quic.EarlyConnection, datagrams *datagramMux, decoder *qpackDecoder, encoder *qpackEncoder) {
 	for {
 		str, err := conn.AcceptUniStream(context.Background())
 		if err != nil {
@@ -473,9 +526,11 @@ func (s *Server) handleUnidirectionalStreams(conn quic.EarlyConnection) {
 			// We're only interested in the control stream here.
 			switch streamType {
 			case streamTypeControlStream:
-			case streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream:
-				// Our QPACK implementation doesn't use the dynamic table yet.
-				// TODO: check that only one stream of each type is opened.
+			case streamTypeQPACKEncoderStream:
+				decoder.handleEncoderStream(conn, str)
+				return
+			case streamTypeQPACKDecoderStream:
+				encoder.handleDecoderStream(conn, str)
 				return
 			case streamTypePushStream: // only the server can push
 				conn.CloseWithError(quic.ApplicationErrorCode(errorStreamCreationError), "")
@@ -497,19 +552,94 @@ func (s *Server) handleUnidirectionalStreams(conn quic.EarlyConnection) {
 				conn.CloseWithError(quic.ApplicationErrorCode(errorMissingSettings), "")
 				return
 			}
+			encoder.handleSettings(conn, sf.QPACKMaxTableCapacity)
 			if !sf.Datagram {
 				return
 			}
 			// If datagram support was enabled on our side as well as on the client side,
 			// we can expect it to have been negotiated both on the transport and on the HTTP/3 layer.
 			// Note: ConnectionState() will block until the handshake is complete (relevant when using 0-RTT).
-			if s.EnableDatagrams && !conn.ConnectionState().SupportsDatagrams {
-				conn.CloseWithError(quic.ApplicationErrorCode(errorSettingsError), "missing QUIC Datagram support")
+			if s.EnableDatagrams {
+				if !conn.ConnectionState().SupportsDatagrams {
+					conn.CloseWithError(quic.ApplicationErrorCode(errorSettingsError), "missing QUIC Datagram support")
+					return
+				}
+				datagrams.setNegotiated()
 			}
 		}(str)
 	}
 }
 
+// A serverConn tracks the requests on a connection, such that the connection can be shut down gracefully.
+type serverConn struct {
+	quic.EarlyConnection
+	controlStr quic.SendStream
+	datagrams  *datagramMux // nil if HTTP/3 datagrams are disabled
+
+	mutex          sync.Mutex
+	goingAway      bool
+	gracePeriodEnd bool          // set when the grace period after sending the GOAWAY has passed
+	goAwayID       quic.StreamID // requests on this and higher stream IDs are rejected
+	nextStreamID   quic.StreamID // the stream ID following the highest request stream ID accepted so far
+	numRequests    int
+}
+
+// startRequest is called for every request stream accepted.
+// It returns false if the request must be rejected, since it was opened after the GOAWAY.
+func (c *serverConn) startRequest(id quic.StreamID) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.goingAway && id >= c.goAwayID {
+		return false
+	}
+	if id >= c.nextStreamID {
+		c.nextStreamID = id + 4
+	}
+	c.numRequests++
+	return true
+}
+
+func (c *serverConn) finishRequest() {
+	c.mutex.Lock()
+	c.numRequests--
+	done := c.gracePeriodEnd && c.numRequests == 0
+	c.mutex.Unlock()
+
+	if done {
+		c.close()
+	}
+}
+
+// goAway sends a GOAWAY frame on the control stream.
+// The connection is closed once the grace period has passed and all requests have been completed.
+func (c *serverConn) goAway() {
+	c.mutex.Lock()
+	if c.goingAway {
+		c.mutex.Unlock()
+		return
+	}
+	c.goingAway = true
+	c.goAwayID = c.nextStreamID
+	c.mutex.Unlock()
+
+	c.controlStr.Write((&goAwayFrame{StreamID: c.goAwayID}).Append(nil))
+	time.AfterFunc(goAwayGracePeriod, func() {
+		c.mutex.Lock()
+		c.gracePeriodEnd = true
+		done := c.numRequests == 0
+		c.mutex.Unlock()
+
+		if done {
+			c.close()
+		}
+	})
+}
+
+func (c *serverConn) close() {
+	c.CloseWithError(quic.ApplicationErrorCode(errorNoError), "")
+}
+
 func (s *Server) maxHeaderBytes() uint64 {
 	if s.MaxHeaderBytes <= 0 {
 		return http.DefaultMaxHeaderBytes
@@ -517,7 +647,24 @@ func (s *Server) maxHeaderBytes() uint64 {
 	return uint64(s.MaxHeaderBytes)
 }
 
-func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *qpack.Decoder, onFrameError func()) requestError {
+func (s *Server) qpackMaxTableCapacity() uint64 {
+	if s.QPACKMaxTableCapacity <= 0 {
+		return 0
+	}
+	return uint64(s.QPACKMaxTableCapacity)
+}
+
+func (s *Server) qpackBlockedStreams() uint64 {
+	if s.qpackMaxTableCapacity() == 0 || s.QPACKBlockedStreams < 0 {
+		return 0
+	}
+	if s.QPACKBlockedStreams == 0 {
+		return defaultQPACKBlockedStreams
+	}
+	return uint64(s.QPACKBlockedStreams)
+}
+
+func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, datagrams *datagramMux, decoder *qpackDecoder, encoder *qpackEncoder, onFrameError func()) requestError {
 	var ufh unknownFrameHandlerFunc
 	if s.StreamHijacker != nil {
 		ufh = func(ft FrameType, e error) (processed bool, err error) { return s.StreamHijacker(ft, conn, str, e) }
@@ -540,10 +687,16 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 	if _, err := io.ReadFull(str, headerBlock); err != nil {
 		return newStreamError(errorRequestIncomplete, err)
 	}
-	hfs, err := decoder.DecodeFull(headerBlock)
+	// The request context is canceled when the client cancels the request (i.e. sends a STOP_SENDING frame),
+	// and when the connection is closed. Writes to the response fail afterwards.
+	ctx := str.Context()
+	hfs, err := decoder.decode(ctx, str.StreamID(), headerBlock)
 	if err != nil {
-		// TODO: use the right error code
-		return newConnError(errorGeneralProtocolError, err)
+		var qerr qpackError
+		if errors.As(err, &qerr) {
+			return newConnError(errorQPACKDecompressionFailed, err)
+		}
+		return newStreamError(errorRequestIncomplete, err)
 	}
 	req, err := requestFromHeaders(hfs)
 	if err != nil {
@@ -553,6 +706,11 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 
 	req.RemoteAddr = conn.RemoteAddr().String()
 	body := newRequestBody(newStream(str, onFrameError))
+	if datagrams != nil {
+		// Datagrams for this request are received until the handler returns.
+		body.datagrams = datagrams.register(str.StreamID())
+		defer datagrams.unregister(body.datagrams)
+	}
 	req.Body = body
 
 	if s.logger.Debug() {
@@ -561,11 +719,10 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 		s.logger.Infof("%s %s%s", req.Method, req.Host, req.RequestURI)
 	}
 
-	ctx := str.Context()
 	ctx = context.WithValue(ctx, ServerContextKey, s)
 	ctx = context.WithValue(ctx, http.LocalAddrContextKey, conn.LocalAddr())
 	req = req.WithContext(ctx)
-	r := newResponseWriter(str, conn, s.logger)
+	r := newResponseWriter(str, conn, encoder, s.logger)
 	defer r.Flush()
 	handler := s.Handler
 	if handler == nil {
@@ -587,7 +744,7 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 		handler.ServeHTTP(r, req)
 	}()
 
-	if body.wasStreamHijacked() {
+	if body.wasStreamHijacked() || r.wasStreamTakenOver() {
 		return requestError{err: errHijacked}
 	}
 
@@ -595,6 +752,9 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 		r.WriteHeader(500)
 	} else {
 		r.WriteHeader(200)
+		if err := r.writeTrailers(); err != nil {
+			s.logger.Errorf("could not write trailers: %s", err)
+		}
 	}
 	// If the EOF was read by the handler, CancelRead() is a no-op.
 	str.CancelRead(quic.StreamErrorCode(errorNoError))
@@ -618,11 +778,76 @@ func (s *Server) Close() error {
 	return err
 }
 
+// Shutdown shuts down the server gracefully.
+// It stops accepting new connections and sends a GOAWAY frame on all open connections,
+// such that clients don't send any new requests. Requests that were already received are still processed.
+// Once all requests on a connection have completed, the connection is closed.
+// If the context expires before that, all remaining connections are closed with H3_NO_ERROR, and the context's error is returned.
+// Shutdown in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	s.closed = true
+	listeners := make([]quic.EarlyListener, 0, len(s.listeners))
+	for ln := range s.listeners {
+		listeners = append(listeners, *ln)
+	}
+	conns := make([]*serverConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mutex.Unlock()
+
+	for _, c := range conns {
+		c.goAway()
+	}
+	if len(listeners) == 0 {
+		return nil
+	}
+
+	// The listeners stop accepting new connections, and wait for the existing connections to be closed.
+	// They are only cancelled after we closed our connections, since they'd use a QUIC application error code of 0.
+	lnCtx, lnCancel := context.WithCancel(context.Background())
+	defer lnCancel()
+	errChan := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		go func(ln quic.EarlyListener) { errChan <- ln.Shutdown(lnCtx) }(ln)
+	}
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		for range listeners {
+			if lerr := <-errChan; lerr != nil && err == nil {
+				err = lerr
+			}
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		s.mutex.RLock()
+		conns = conns[:0]
+		for c := range s.conns {
+			conns = append(conns, c)
+		}
+		s.mutex.RUnlock()
+		for _, c := range conns {
+			c.close()
+		}
+		lnCancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
 // CloseGracefully shuts down the server gracefully. The server sends a GOAWAY frame first, then waits for either timeout to trigger, or for all running requests to complete.
 // CloseGracefully in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
 func (s *Server) CloseGracefully(timeout time.Duration) error {
-	// TODO: implement
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.Shutdown(ctx)
 }
 
 // ErrNoAltSvcPort is the error returned by SetQuicHeaders when no port was found
@@ -10,6 +10,7 @@ import (
 	"net"
 	"net/http"
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -104,7 +105,7 @@ var _ = Describe("Server", func() {
 
 	Context("handling requests", func() {
 		var (
-			qpackDecoder       *qpack.Decoder
+			qpackDecoder       *qpackDecoder
 			str                *mockquic.MockStream
 			conn               *mockquic.MockEarlyConnection
 			exampleGetRequest  *http.Request
@@ -134,8 +135,9 @@ var _ = Describe("Server", func() {
 		encodeRequest := func(req *http.Request) []byte {
 			buf := &bytes.Buffer{}
 			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().AnyTimes()
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(buf.Write).AnyTimes()
-			rw := newRequestWriter(utils.DefaultLogger)
+			rw := newRequestWriter(newQPACKEncoder(0, utils.DefaultLogger), utils.DefaultLogger)
 			Expect(rw.WriteRequestHeader(str, req, false)).To(Succeed())
 			return buf.Bytes()
 		}
@@ -157,8 +159,9 @@ var _ = Describe("Server", func() {
 			examplePostRequest, err = http.NewRequest("POST", "https://www.example.com", bytes.NewReader([]byte("foobar")))
 			Expect(err).ToNot(HaveOccurred())
 
-			qpackDecoder = qpack.NewDecoder(nil)
+			qpackDecoder = newQPACKDecoder(0, 0, utils.DefaultLogger)
 			str = mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().AnyTimes()
 			conn = mockquic.NewMockEarlyConnection(mockCtrl)
 			addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
 			conn.EXPECT().RemoteAddr().Return(addr).AnyTimes()
@@ -178,7 +181,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			Expect(s.handleRequest(conn, str, qpackDecoder, nil)).To(Equal(requestError{}))
+			Expect(s.handleRequest(conn, str, nil, qpackDecoder, newQPACKEncoder(0, utils.DefaultLogger), nil)).To(Equal(requestError{}))
 			var req *http.Request
 			Eventually(requestChan).Should(Receive(&req))
 			Expect(req.Host).To(Equal("www.example.com"))
@@ -195,7 +198,7 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, qpackDecoder, newQPACKEncoder(0, utils.DefaultLogger), nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
@@ -212,7 +215,7 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, qpackDecoder, newQPACKEncoder(0, utils.DefaultLogger), nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"500"}))
@@ -245,6 +248,7 @@ var _ = Describe("Server", func() {
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, 0x41)
 				unknownStr := mockquic.NewMockStream(mockCtrl)
+				unknownStr.EXPECT().StreamID().AnyTimes()
 				unknownStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
 				conn.EXPECT().AcceptStream(gomock.Any()).Return(unknownStr, nil)
 				conn.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
@@ -268,6 +272,7 @@ var _ = Describe("Server", func() {
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, 0x41)
 				unknownStr := mockquic.NewMockStream(mockCtrl)
+				unknownStr.EXPECT().StreamID().AnyTimes()
 				unknownStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
 				unknownStr.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestIncomplete))
 				conn.EXPECT().AcceptStream(gomock.Any()).Return(unknownStr, nil)
@@ -292,6 +297,7 @@ var _ = Describe("Server", func() {
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, 0x41)
 				unknownStr := mockquic.NewMockStream(mockCtrl)
+				unknownStr.EXPECT().StreamID().AnyTimes()
 				unknownStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
 				unknownStr.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestIncomplete))
 				conn.EXPECT().AcceptStream(gomock.Any()).Return(unknownStr, nil)
@@ -309,6 +315,7 @@ var _ = Describe("Server", func() {
 				testErr := errors.New("test error")
 				done := make(chan struct{})
 				unknownStr := mockquic.NewMockStream(mockCtrl)
+				unknownStr.EXPECT().StreamID().AnyTimes()
 				s.StreamHijacker = func(ft FrameType, _ quic.Connection, str quic.Stream, err error) (bool, error) {
 					defer close(done)
 					Expect(ft).To(BeZero())
@@ -461,7 +468,7 @@ var _ = Describe("Server", func() {
 					name = "decoder"
 				}
 
-				It(fmt.Sprintf("ignores the QPACK %s streams", name), func() {
+				It(fmt.Sprintf("closes the connection when the QPACK %s stream is closed", name), func() {
 					buf := &bytes.Buffer{}
 					quicvarint.Write(buf, streamType)
 					str := mockquic.NewMockStream(mockCtrl)
@@ -474,8 +481,12 @@ var _ = Describe("Server", func() {
 						<-testDone
 						return nil, errors.New("test done")
 					})
+					done := make(chan struct{})
+					conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorClosedCriticalStream), gomock.Any()).Do(func(quic.ApplicationErrorCode, string) {
+						close(done)
+					})
 					s.handleConn(conn)
-					time.Sleep(scaleDuration(20 * time.Millisecond)) // don't EXPECT any calls to str.CancelRead
+					Eventually(done).Should(BeClosed())
 				})
 			}
 
@@ -662,6 +673,28 @@ var _ = Describe("Server", func() {
 				Eventually(handlerCalled).Should(BeClosed())
 			})
 
+			It("doesn't close the stream if the stream was taken over (via QUICStream)", func() {
+				handlerCalled := make(chan struct{})
+				var qconn quic.Connection
+				var qstr quic.Stream
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					defer close(handlerCalled)
+					qconn = w.(QUICStreamer).Connection()
+					qstr = w.(QUICStreamer).QUICStream()
+					qstr.Write([]byte("foobar"))
+				})
+
+				requestData := encodeRequest(exampleGetRequest)
+				setRequest(requestData)
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write([]byte("foobar")).Return(6, nil)
+
+				s.handleConn(conn)
+				Eventually(handlerCalled).Should(BeClosed())
+				Expect(qconn).To(Equal(conn))
+				Expect(qstr).To(Equal(str))
+			})
+
 			It("errors when the client sends a too large header frame", func() {
 				s.MaxHeaderBytes = 20
 				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -741,6 +774,171 @@ var _ = Describe("Server", func() {
 			})
 		})
 
+		Context("graceful shutdown", func() {
+			var (
+				conn       *mockquic.MockEarlyConnection
+				controlBuf *bytes.Buffer
+				testDone   chan struct{}
+			)
+
+			BeforeEach(func() {
+				testDone = make(chan struct{})
+				controlBuf = &bytes.Buffer{}
+				conn = mockquic.NewMockEarlyConnection(mockCtrl)
+				controlStr := mockquic.NewMockStream(mockCtrl)
+				controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(controlBuf.Write).AnyTimes()
+				conn.EXPECT().OpenUniStream().Return(controlStr, nil)
+				done := testDone // the connection's goroutines might still be running when the next test starts
+				conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-done
+					return nil, errors.New("test done")
+				})
+				conn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}).AnyTimes()
+				conn.EXPECT().LocalAddr().AnyTimes()
+			})
+
+			var origGoAwayGracePeriod time.Duration
+			BeforeEach(func() {
+				origGoAwayGracePeriod = goAwayGracePeriod
+				goAwayGracePeriod = scaleDuration(20 * time.Millisecond)
+			})
+
+			AfterEach(func() {
+				close(testDone)
+				goAwayGracePeriod = origGoAwayGracePeriod
+			})
+
+			parseGoAway := func() *goAwayFrame {
+				r := bytes.NewReader(controlBuf.Bytes())
+				t, err := quicvarint.Read(r)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred())
+				ExpectWithOffset(1, t).To(BeEquivalentTo(streamTypeControlStream))
+				f, err := parseNextFrame(r, nil)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred())
+				ExpectWithOffset(1, f).To(BeAssignableToTypeOf(&settingsFrame{}))
+				f, err = parseNextFrame(r, nil)
+				ExpectWithOffset(1, err).ToNot(HaveOccurred())
+				ExpectWithOffset(1, f).To(BeAssignableToTypeOf(&goAwayFrame{}))
+				return f.(*goAwayFrame)
+			}
+
+			acceptStreamsUntilTestDone := func() {
+				done := testDone
+				conn.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					<-done
+					return nil, errors.New("test done")
+				})
+			}
+
+			numConns := func() int {
+				s.mutex.RLock()
+				defer s.mutex.RUnlock()
+				return len(s.conns)
+			}
+
+			It("sends a GOAWAY, rejects new requests and closes the connection once all requests have completed", func() {
+				handlerStarted := make(chan struct{})
+				unblockHandler := make(chan struct{})
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					close(handlerStarted)
+					<-unblockHandler
+				})
+				setRequest(encodeRequest(exampleGetRequest))
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) { return len(p), nil }).AnyTimes()
+				str.EXPECT().CancelRead(quic.StreamErrorCode(errorNoError))
+				str.EXPECT().Close()
+				conn.EXPECT().AcceptStream(gomock.Any()).Return(str, nil)
+				acceptNext := make(chan struct{})
+				rejectedStr := mockquic.NewMockStream(mockCtrl)
+				rejectedStr.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+				conn.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					<-acceptNext
+					return rejectedStr, nil
+				})
+				acceptStreamsUntilTestDone()
+
+				go s.handleConn(conn)
+				Eventually(handlerStarted).Should(BeClosed())
+				Expect(s.Shutdown(context.Background())).To(Succeed())
+				Expect(parseGoAway().StreamID).To(Equal(quic.StreamID(4)))
+
+				rejected := make(chan struct{})
+				rejectedStr.EXPECT().CancelRead(quic.StreamErrorCode(errorRequestRejected))
+				rejectedStr.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestRejected)).Do(func(quic.StreamErrorCode) { close(rejected) })
+				close(acceptNext)
+				Eventually(rejected).Should(BeClosed())
+
+				closed := make(chan struct{})
+				conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "").Do(func(quic.ApplicationErrorCode, string) { close(closed) })
+				close(unblockHandler)
+				Eventually(closed).Should(BeClosed())
+			})
+
+			It("closes the connection after the grace period if there are no active requests", func() {
+				acceptStreamsUntilTestDone()
+				go s.handleConn(conn)
+				Eventually(numConns).Should(Equal(1))
+
+				closed := make(chan struct{})
+				conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "").Do(func(quic.ApplicationErrorCode, string) { close(closed) })
+				start := time.Now()
+				Expect(s.Shutdown(context.Background())).To(Succeed())
+				Expect(parseGoAway().StreamID).To(BeZero())
+				Eventually(closed).Should(BeClosed())
+				Expect(time.Since(start)).To(BeNumerically(">=", goAwayGracePeriod))
+			})
+
+			It("closes the connection with H3_NO_ERROR when the context expires", func() {
+				handlerStarted := make(chan struct{})
+				done := testDone
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					close(handlerStarted)
+					<-done
+				})
+				setRequest(encodeRequest(exampleGetRequest))
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) { return len(p), nil }).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any()).AnyTimes()
+				str.EXPECT().Close().AnyTimes()
+				conn.EXPECT().AcceptStream(gomock.Any()).Return(str, nil)
+				acceptStreamsUntilTestDone()
+
+				ln := newMockAddrListener(":443")
+				ln.EXPECT().Addr()
+				ln.EXPECT().Shutdown(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				})
+				var qln quic.EarlyListener = ln
+				Expect(s.addListener(&qln)).To(Succeed())
+
+				go s.handleConn(conn)
+				Eventually(handlerStarted).Should(BeClosed())
+				closed := make(chan struct{})
+				var closeOnce sync.Once
+				// The connection is closed again when the handler returns at the end of the test.
+				conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "").Do(func(quic.ApplicationErrorCode, string) {
+					closeOnce.Do(func() { close(closed) })
+				}).MinTimes(1)
+				ctx, cancel := context.WithTimeout(context.Background(), 2*goAwayGracePeriod)
+				defer cancel()
+				Expect(s.Shutdown(ctx)).To(MatchError(context.DeadlineExceeded))
+				Expect(closed).To(BeClosed())
+			})
+
+			It("sends a GOAWAY on new connections while shutting down", func() {
+				Expect(s.Shutdown(context.Background())).To(Succeed())
+
+				closed := make(chan struct{})
+				conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "").Do(func(quic.ApplicationErrorCode, string) { close(closed) })
+				acceptStreamsUntilTestDone()
+				go s.handleConn(conn)
+				Eventually(closed).Should(BeClosed())
+				Expect(parseGoAway().StreamID).To(BeZero())
+			})
+		})
+
 		It("resets the stream when the body of POST request is not read, and the request handler replaces the request.Body", func() {
 			handlerCalled := make(chan struct{})
 			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -758,7 +956,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.StreamErrorCode(errorNoError))
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, qpackDecoder, newQPACKEncoder(0, utils.DefaultLogger), nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
@@ -781,7 +979,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.StreamErrorCode(errorNoError))
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, qpackDecoder, newQPACKEncoder(0, utils.DefaultLogger), nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
@@ -1083,6 +1281,31 @@ var _ = Describe("Server", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("shuts down the listener", func() {
+			ln := newMockAddrListener(":443")
+			s := &Server{}
+
+			stopAccept := make(chan struct{})
+			ln.EXPECT().Accept(gomock.Any()).DoAndReturn(func(context.Context) (quic.Connection, error) {
+				<-stopAccept
+				return nil, errors.New("closed")
+			})
+			ln.EXPECT().Addr() // generate alt-svc headers
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				s.ServeListener(ln)
+			}()
+
+			Consistently(done).ShouldNot(BeClosed())
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ln.EXPECT().Shutdown(gomock.Any()).Do(func(context.Context) { close(stopAccept) })
+			Expect(s.Shutdown(ctx)).To(Succeed())
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("serves two listeners", func() {
 			var called int32
 			ln1 := newMockAddrListener(":443")
@@ -1278,6 +1501,16 @@ var _ = Describe("Server", func() {
 		Expect(ListenAndServeQUIC("", fullpem, privkey, nil)).To(MatchError(testErr))
 	})
 
+	It("only uses the QPACK dynamic table if enabled", func() {
+		Expect(s.qpackMaxTableCapacity()).To(BeZero())
+		Expect(s.qpackBlockedStreams()).To(BeZero())
+		s.QPACKMaxTableCapacity = 4096
+		Expect(s.qpackMaxTableCapacity()).To(BeEquivalentTo(4096))
+		Expect(s.qpackBlockedStreams()).To(BeEquivalentTo(defaultQPACKBlockedStreams))
+		s.QPACKBlockedStreams = -1
+		Expect(s.qpackBlockedStreams()).To(BeZero())
+	})
+
 	It("supports H3_DATAGRAM", func() {
 		s.EnableDatagrams = true
 		var receivedConf *quic.Config
@@ -0,0 +1,30 @@
+package http3
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/fkwhite/quic-go"
+)
+
+var errTraceConn = errors.New("http3: the connection can't be used directly")
+
+// A traceConn is the net.Conn passed to httptrace.ClientTrace.GotConn.
+// A QUIC connection can't be represented as a net.Conn,
+// so only the addresses of the connection are available.
+type traceConn struct {
+	conn quic.Connection
+}
+
+var _ net.Conn = &traceConn{}
+
+func (c *traceConn) LocalAddr() net.Addr  { return c.conn.LocalAddr() }
+func (c *traceConn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+func (c *traceConn) Read([]byte) (int, error)         { return 0, errTraceConn }
+func (c *traceConn) Write([]byte) (int, error)        { return 0, errTraceConn }
+func (c *traceConn) Close() error                     { return errTraceConn }
+func (c *traceConn) SetDeadline(time.Time) error      { return errTraceConn }
+func (c *traceConn) SetReadDeadline(time.Time) error  { return errTraceConn }
+func (c *traceConn) SetWriteDeadline(time.Time) error { return errTraceConn }
@@ -189,7 +189,7 @@ var _ = Describe("Stream Cancelations", func() {
 						b := make([]byte, 32)
 						if _, err := str.Read(b); err != nil {
 							atomic.AddInt32(&counter, 1)
-							Expect(err.Error()).To(ContainSubstring("canceled with error code 1234"))
+							Expect(err.Error()).To(ContainSubstring("canceled by local with error code 1234"))
 							return
 						}
 					}()
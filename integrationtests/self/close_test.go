@@ -71,9 +71,12 @@ var _ = Describe("Connection ID lengths tests", func() {
 					Expect(err).ToNot(HaveOccurred())
 					time.Sleep(time.Millisecond)
 				}
-				// Expect retransmissions of the CONNECTION_CLOSE for the
-				// 1st, 2nd, 4th, 8th, 16th, 32th, 64th packet: 7 in total (+1 for the original packet)
-				Eventually(dropped).Should(HaveLen(8))
+				// Expect a retransmission of the CONNECTION_CLOSE for the 1st packet (+1 for the original packet).
+				// Retransmissions for the 2nd, 4th, 8th, ... packet are rate limited to one every 100ms,
+				// so at most one more retransmission is sent while the 100 packets arrive.
+				Eventually(func() int { return len(dropped) }).Should(BeNumerically(">=", 2))
+				time.Sleep(50 * time.Millisecond)
+				Expect(len(dropped)).To(BeNumerically("<=", 3))
 				first := <-dropped
 				for len(dropped) > 0 {
 					Expect(<-dropped).To(Equal(first)) // these packets are all identical
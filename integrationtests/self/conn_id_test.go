@@ -7,8 +7,11 @@ import (
 	"io"
 	mrand "math/rand"
 	"net"
+	"sync"
+	"time"
 
 	"github.com/fkwhite/quic-go"
+	quicproxy "github.com/fkwhite/quic-go/integrationtests/tools/proxy"
 	"github.com/fkwhite/quic-go/internal/protocol"
 
 	. "github.com/onsi/ginkgo"
@@ -119,4 +122,95 @@ var _ = Describe("Connection ID lengths tests", func() {
 		defer ln.Close()
 		runClient(ln.Addr(), clientConf)
 	})
+
+	It("exposes the connection IDs chosen during the handshake", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		serverConnChan := make(chan quic.Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			serverConnChan <- conn
+		}()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		var serverConn quic.Connection
+		Eventually(serverConnChan).Should(Receive(&serverConn))
+		// DialAddr uses a zero-length connection ID, since the UDP socket isn't shared
+		Expect(conn.LocalConnectionID().Len()).To(BeZero())
+		Expect(conn.RemoteConnectionID().Len()).ToNot(BeZero())
+		Expect(conn.RemoteConnectionID()).To(Equal(serverConn.LocalConnectionID()))
+		Expect(conn.LocalConnectionID()).To(Equal(serverConn.RemoteConnectionID()))
+	})
+
+	It("rotates the connection ID when requested by the application", func() {
+		const connIDLen = 8
+		ln, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{ConnectionIDLength: connIDLen}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			// the client closes the connection when it's done
+			io.Copy(str, str)
+		}()
+
+		var mutex sync.Mutex
+		connIDs := make(map[string]struct{})
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			DelayPacket: func(dir quicproxy.Direction, data []byte) time.Duration {
+				// record the destination connection ID of short header packets sent by the client
+				if dir == quicproxy.DirectionIncoming && data[0]&0x80 == 0 && len(data) > connIDLen {
+					mutex.Lock()
+					connIDs[string(data[1:1+connIDLen])] = struct{}{}
+					mutex.Unlock()
+				}
+				return 0
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		const numRotations = 3
+		for i := 0; i < numRotations; i++ {
+			// wait for the server to issue a new connection ID
+			Eventually(conn.RotateConnectionID).Should(Succeed())
+			msg := []byte(fmt.Sprintf("message %d", i))
+			_, err := str.Write(msg)
+			Expect(err).ToNot(HaveOccurred())
+			b := make([]byte, len(msg))
+			_, err = io.ReadFull(str, b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal(msg))
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		// The client might have rotated its connection ID on its own as well.
+		Expect(len(connIDs)).To(BeNumerically(">=", numRotations+1))
+	})
 })
@@ -455,6 +455,49 @@ var _ = Describe("Handshake tests", func() {
 			Expect(ln.Close()).To(Succeed())
 		})
 
+		It("uses the tls.Config returned by GetConfigForClient", func() {
+			type clientHelloInfo struct {
+				serverName string
+				remoteAddr net.Addr
+				params     *logging.TransportParameters
+			}
+			infoChan := make(chan clientHelloInfo, 1)
+			tlsConf := &tls.Config{
+				GetConfigForClient: func(info *tls.ClientHelloInfo) (*tls.Config, error) {
+					conn := info.Conn.(quic.ClientHelloConn)
+					infoChan <- clientHelloInfo{
+						serverName: info.ServerName,
+						remoteAddr: conn.RemoteAddr(),
+						params:     conn.GetTransportParameters(),
+					}
+					conf := getTLSConfig()
+					conf.NextProtos = []string{fmt.Sprintf("proto-%s", conn.GetQUICVersion())}
+					return conf, nil
+				},
+			}
+			ln, err := quic.ListenAddr("localhost:0", tlsConf, serverConfig)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+
+			clientTLSConf := getTLSClientConfig()
+			clientTLSConf.NextProtos = []string{"foobar", fmt.Sprintf("proto-%s", protocol.Version1)}
+			conn, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				clientTLSConf,
+				getQuicConfig(&quic.Config{Versions: []quic.VersionNumber{protocol.Version1}}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.CloseWithError(0, "")
+			Expect(conn.ConnectionState().TLS.NegotiatedProtocol).To(Equal(fmt.Sprintf("proto-%s", protocol.Version1)))
+
+			var info clientHelloInfo
+			Eventually(infoChan).Should(Receive(&info))
+			Expect(info.serverName).To(Equal("localhost"))
+			Expect(info.remoteAddr.(*net.UDPAddr).Port).To(Equal(conn.LocalAddr().(*net.UDPAddr).Port))
+			Expect(info.params).ToNot(BeNil())
+			Expect(info.params.InitialSourceConnectionID.Len()).ToNot(BeZero())
+		})
+
 		It("errors if application protocol negotiation fails", func() {
 			runServer(getTLSConfig())
 
@@ -11,7 +11,10 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/fkwhite/quic-go"
@@ -121,6 +124,135 @@ var _ = Describe("HTTP tests", func() {
 				Expect(string(body)).To(Equal("Hello, World!\n"))
 			})
 
+			It("counts the bytes of a request", func() {
+				var stats http3.RequestStats
+				req, err := http.NewRequestWithContext(
+					http3.WithRequestStats(context.Background(), &stats),
+					http.MethodPost,
+					"https://localhost:"+port+"/echo",
+					bytes.NewReader([]byte("foobar")),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				resp, err := client.Do(req)
+				Expect(err).ToNot(HaveOccurred())
+				body, err := io.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(body)).To(Equal("foobar"))
+				// HEADERS frame and DATA frame
+				Expect(stats.BytesSent()).To(BeNumerically(">", len("foobar")+2))
+				Expect(stats.BytesReceived()).To(BeNumerically(">", len("foobar")+2))
+			})
+
+			It("sends requests on a pinned connection", func() {
+				alpn := "h3"
+				if version == protocol.VersionDraft29 {
+					alpn = "h3-29"
+				}
+				conn, err := quic.DialAddrEarly(
+					"localhost:"+port,
+					&tls.Config{RootCAs: testdata.GetRootCA(), NextProtos: []string{alpn}},
+					getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.CloseWithError(0, "")
+
+				rt := client.Transport.(*http3.RoundTripper)
+				for i := 0; i < 2; i++ {
+					req, err := http.NewRequest(http.MethodGet, "https://localhost:"+port+"/hello", nil)
+					Expect(err).ToNot(HaveOccurred())
+					rsp, err := rt.RoundTripOpt(req, http3.RoundTripOpt{Conn: conn})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rsp.StatusCode).To(Equal(200))
+					body, err := io.ReadAll(gbytes.TimeoutReader(rsp.Body, 3*time.Second))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(body)).To(Equal("Hello, World!\n"))
+				}
+
+				conn.CloseWithError(0, "")
+				Eventually(conn.Context().Done()).Should(BeClosed())
+				req, err := http.NewRequest(http.MethodGet, "https://localhost:"+port+"/hello", nil)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = rt.RoundTripOpt(req, http3.RoundTripOpt{Conn: conn})
+				Expect(err).To(MatchError(http3.ErrConnClosed))
+			})
+
+			It("upgrades to HTTP/3 using Alt-Svc", func() {
+				mux.HandleFunc("/proto", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					Expect(server.SetQuicHeaders(w.Header())).To(Succeed())
+					w.Write([]byte(r.Proto))
+				})
+				ln, err := tls.Listen("tcp", "localhost:0", testdata.GetTLSConfig())
+				Expect(err).ToNot(HaveOccurred())
+				tcpServer := &http.Server{Handler: mux}
+				go tcpServer.Serve(ln)
+				defer tcpServer.Close()
+
+				transport := &http3.AltSvcTransport{
+					Fallback:     &http.Transport{TLSClientConfig: &tls.Config{RootCAs: testdata.GetRootCA()}},
+					RoundTripper: client.Transport.(*http3.RoundTripper),
+				}
+				defer transport.Close()
+				getProto := func() string {
+					req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://localhost:%d/proto", ln.Addr().(*net.TCPAddr).Port), nil)
+					Expect(err).ToNot(HaveOccurred())
+					rsp, err := transport.RoundTrip(req)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rsp.StatusCode).To(Equal(200))
+					Expect(rsp.Header.Get("Alt-Svc")).To(ContainSubstring(":" + port))
+					body, err := io.ReadAll(gbytes.TimeoutReader(rsp.Body, 3*time.Second))
+					Expect(err).ToNot(HaveOccurred())
+					return string(body)
+				}
+				Expect(getProto()).To(Equal("HTTP/1.1"))
+				Expect(getProto()).To(Equal("HTTP/3.0"))
+				Expect(getProto()).To(Equal("HTTP/3.0"))
+			})
+
+			It("compresses headers using the QPACK dynamic table", func() {
+				headersReceived := make(chan http.Header, 100)
+				mux := http.NewServeMux()
+				mux.HandleFunc("/headers", func(w http.ResponseWriter, r *http.Request) {
+					headersReceived <- r.Header
+				})
+				server := &http3.Server{
+					Handler:               mux,
+					TLSConfig:             testdata.GetTLSConfig(),
+					QuicConfig:            getQuicConfig(&quic.Config{Versions: versions}),
+					QPACKMaxTableCapacity: 4096,
+				}
+				conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+				Expect(err).ToNot(HaveOccurred())
+				go server.Serve(conn)
+				defer server.Close()
+
+				rt := client.Transport.(*http3.RoundTripper)
+				rt.QPACKMaxTableCapacity = 4096
+				value := strings.Repeat("foobar", 50)
+				sendRequest := func() uint64 {
+					var stats http3.RequestStats
+					req, err := http.NewRequestWithContext(
+						http3.WithRequestStats(context.Background(), &stats),
+						http.MethodGet,
+						fmt.Sprintf("https://localhost:%d/headers", conn.LocalAddr().(*net.UDPAddr).Port),
+						nil,
+					)
+					Expect(err).ToNot(HaveOccurred())
+					req.Header.Set("foo", value)
+					rsp, err := rt.RoundTrip(req)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rsp.StatusCode).To(Equal(200))
+					rsp.Body.Close()
+					var hdr http.Header
+					Eventually(headersReceived).Should(Receive(&hdr))
+					Expect(hdr.Get("foo")).To(Equal(value))
+					return stats.BytesSent()
+				}
+				first := sendRequest()
+				// The header field is only referenced once the server acknowledged the insertion.
+				Eventually(sendRequest).Should(BeNumerically("<", first/2))
+			})
+
 			It("sets and gets request headers", func() {
 				handlerCalled := make(chan struct{})
 				mux.HandleFunc("/headers/request", func(w http.ResponseWriter, r *http.Request) {
@@ -154,6 +286,65 @@ var _ = Describe("HTTP tests", func() {
 				Expect(resp.Header.Get("lorem")).To(Equal("ipsum"))
 			})
 
+			It("receives interim responses", func() {
+				mux.HandleFunc("/early-hints", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+					w.WriteHeader(http.StatusEarlyHints)
+					w.Write([]byte("foobar"))
+				})
+
+				var linkHeaders []string
+				trace := &httptrace.ClientTrace{
+					Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+						Expect(code).To(Equal(http.StatusEarlyHints))
+						linkHeaders = append(linkHeaders, header.Get("Link"))
+						return nil
+					},
+				}
+				req, err := http.NewRequestWithContext(
+					httptrace.WithClientTrace(context.Background(), trace),
+					http.MethodGet,
+					"https://localhost:"+port+"/early-hints",
+					nil,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				resp, err := client.Do(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				Expect(linkHeaders).To(Equal([]string{"</style.css>; rel=preload; as=style"}))
+				body, err := io.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(body)).To(Equal("foobar"))
+			})
+
+			It("sends and receives trailers", func() {
+				mux.HandleFunc("/trailers", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					w.Header().Set("Trailer", "X-Checksum")
+					w.WriteHeader(http.StatusOK)
+					for i := 0; i < 3; i++ {
+						w.Write([]byte("chunk"))
+						w.(http.Flusher).Flush()
+					}
+					w.Header().Set("X-Checksum", "1234")
+					w.Header().Set(http.TrailerPrefix+"X-Undeclared", "foobar")
+				})
+
+				resp, err := client.Get("https://localhost:" + port + "/trailers")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				Expect(resp.Header).ToNot(HaveKey("Trailer"))
+				Expect(resp.Trailer).To(Equal(http.Header{"X-Checksum": nil}))
+				body, err := io.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(body)).To(Equal("chunkchunkchunk"))
+				Expect(resp.Trailer).To(Equal(http.Header{
+					"X-Checksum":   []string{"1234"},
+					"X-Undeclared": []string{"foobar"},
+				}))
+			})
+
 			It("downloads a small file", func() {
 				resp, err := client.Get("https://localhost:" + port + "/prdata")
 				Expect(err).ToNot(HaveOccurred())
@@ -274,6 +465,45 @@ var _ = Describe("HTTP tests", func() {
 				Expect(err).To(HaveOccurred())
 			})
 
+			It("stops the handler when a request is canceled during a large streamed response", func() {
+				handlerDone := make(chan struct{})
+				var written int
+				mux.HandleFunc("/cancel-download", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					defer close(handlerDone)
+					chunk := GeneratePRData(10 * 1024)
+					for {
+						n, err := w.Write(chunk)
+						written += n
+						if err != nil {
+							Expect(r.Context().Done()).To(BeClosed())
+							var strErr *quic.StreamError
+							Expect(errors.As(err, &strErr)).To(BeTrue())
+							Expect(strErr.ErrorCode).To(Equal(quic.StreamErrorCode(0x10c)))
+							return
+						}
+						w.(http.Flusher).Flush()
+					}
+				})
+
+				req, err := http.NewRequest(http.MethodGet, "https://localhost:"+port+"/cancel-download", nil)
+				Expect(err).ToNot(HaveOccurred())
+				ctx, cancel := context.WithCancel(context.Background())
+				req = req.WithContext(ctx)
+				resp, err := client.Do(req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				// read some data, then cancel the request
+				_, err = io.ReadFull(resp.Body, make([]byte, 100*1024))
+				Expect(err).ToNot(HaveOccurred())
+				cancel()
+				Eventually(handlerDone).Should(BeClosed())
+				// The server is limited by flow control, so it can't have written much more data than we read.
+				Expect(written).To(BeNumerically("<", 100*1024+int(protocol.DefaultMaxReceiveStreamFlowControlWindow)+10*1024))
+				_, err = io.ReadAll(resp.Body)
+				Expect(err).To(HaveOccurred())
+			})
+
 			It("allows streamed HTTP requests", func() {
 				done := make(chan struct{})
 				mux.HandleFunc("/echoline", func(w http.ResponseWriter, r *http.Request) {
@@ -3,7 +3,9 @@ package self_test
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/fkwhite/quic-go"
@@ -115,4 +117,85 @@ var _ = Describe("Packetization", func() {
 			BeNumerically(">", numMsg*9/10),
 		))
 	})
+
+	// The proxy only forwards packets up to 1452 bytes, so this test connects to the server directly.
+	It("sends packets larger than 1452 bytes", func() {
+		const packetSize = 9000
+
+		serverTracer := &packetSizeTracer{}
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				InitialPacketSize:       packetSize,
+				MaxPacketSize:           packetSize,
+				DisablePathMTUDiscovery: true,
+				Tracer:                  newTracer(func() logging.ConnectionTracer { return serverTracer }),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		clientTracer := &packetSizeTracer{}
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				InitialPacketSize:       packetSize,
+				MaxPacketSize:           packetSize,
+				DisablePathMTUDiscovery: true,
+				Tracer:                  newTracer(func() logging.ConnectionTracer { return clientTracer }),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := conn.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+		Expect(conn.CloseWithError(0, "")).To(Succeed())
+
+		Expect(serverTracer.getMaxReceived()).To(BeEquivalentTo(packetSize))
+		Expect(clientTracer.getMaxReceived()).To(BeEquivalentTo(packetSize))
+	})
 })
+
+type packetSizeTracer struct {
+	logging.NullConnectionTracer
+
+	mutex       sync.Mutex
+	maxReceived logging.ByteCount
+}
+
+func (t *packetSizeTracer) ReceivedLongHeaderPacket(_ *logging.ExtendedHeader, size logging.ByteCount, _ []logging.Frame) {
+	t.received(size)
+}
+
+func (t *packetSizeTracer) ReceivedShortHeaderPacket(_ *logging.ShortHeader, size logging.ByteCount, _ []logging.Frame) {
+	t.received(size)
+}
+
+func (t *packetSizeTracer) received(size logging.ByteCount) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if size > t.maxReceived {
+		t.maxReceived = size
+	}
+}
+
+func (t *packetSizeTracer) getMaxReceived() logging.ByteCount {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.maxReceived
+}
@@ -91,6 +91,86 @@ var _ = Describe("non-zero RTT", func() {
 				})
 			}
 
+			It("measures the RTT using PING frames", func() {
+				const rtt = 50 * time.Millisecond
+				ln, err := quic.ListenAddr(
+					"localhost:0",
+					getTLSConfig(),
+					getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				go func() {
+					defer GinkgoRecover()
+					_, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				serverPort := ln.Addr().(*net.UDPAddr).Port
+				proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+					RemoteAddr: fmt.Sprintf("localhost:%d", serverPort),
+					DelayPacket: func(quicproxy.Direction, []byte) time.Duration {
+						return rtt / 2
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+				defer proxy.Close()
+
+				conn, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+					getTLSClientConfig(),
+					getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.CloseWithError(0, "")
+				for i := 0; i < 3; i++ {
+					measured, err := conn.Ping(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(measured).To(BeNumerically(">=", rtt))
+					// the peer might delay the acknowledgement by up to max_ack_delay
+					Expect(measured).To(BeNumerically("<", rtt+protocol.MaxAckDelay+scaleDuration(20*time.Millisecond)))
+				}
+			})
+
+			It("measures the RTT without ACK delay, if both endpoints support the ACK frequency extension", func() {
+				const rtt = 50 * time.Millisecond
+				ln, err := quic.ListenAddr(
+					"localhost:0",
+					getTLSConfig(),
+					getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}, EnableAckFrequency: true}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				go func() {
+					defer GinkgoRecover()
+					_, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				serverPort := ln.Addr().(*net.UDPAddr).Port
+				proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+					RemoteAddr: fmt.Sprintf("localhost:%d", serverPort),
+					DelayPacket: func(quicproxy.Direction, []byte) time.Duration {
+						return rtt / 2
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+				defer proxy.Close()
+
+				conn, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+					getTLSClientConfig(),
+					getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}, EnableAckFrequency: true}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.CloseWithError(0, "")
+				for i := 0; i < 3; i++ {
+					measured, err := conn.Ping(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(measured).To(BeNumerically(">=", rtt))
+					// the IMMEDIATE_ACK frame makes the peer acknowledge the PING frame right away
+					Expect(measured).To(BeNumerically("<", rtt+scaleDuration(15*time.Millisecond)))
+				}
+			})
+
 			for _, r := range [...]time.Duration{
 				10 * time.Millisecond,
 				40 * time.Millisecond,
@@ -3,6 +3,7 @@ package self_test
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	mrand "math/rand"
@@ -240,6 +241,38 @@ var _ = Describe("0-RTT", func() {
 				})
 			}
 
+			It("transfers 0-RTT data, after restoring a serialized session state", func() {
+				tlsConf, clientTLSConf := dialAndReceiveSessionTicket(nil)
+
+				// simulate a restart of the client: serialize the session states, and restore them into a new cache
+				cache := clientTLSConf.ClientSessionCache.(*clientSessionCache)
+				restored := newClientSessionCache(make(chan string, 100), make(chan string, 100))
+				cache.mutex.Lock()
+				for key, state := range cache.cache {
+					data, err := quic.MarshalClientSessionState(state)
+					Expect(err).ToNot(HaveOccurred())
+					restored.cache[key], err = quic.UnmarshalClientSessionState(data)
+					Expect(err).ToNot(HaveOccurred())
+				}
+				cache.mutex.Unlock()
+				Expect(restored.cache).ToNot(BeEmpty())
+				clientTLSConf.ClientSessionCache = restored
+
+				ln, err := quic.ListenAddrEarly(
+					"localhost:0",
+					tlsConf,
+					getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+
+				proxy, num0RTTPackets := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+				defer proxy.Close()
+
+				transfer0RTTData(ln, proxy.LocalPort(), clientTLSConf, nil, PRData)
+				Expect(atomic.LoadUint32(num0RTTPackets)).ToNot(BeZero())
+			})
+
 			// Test that data intended to be sent with 1-RTT protection is not sent in 0-RTT packets.
 			It("waits for a connection until the handshake is done", func() {
 				tlsConf, clientConf := dialAndReceiveSessionTicket(nil)
@@ -519,6 +552,38 @@ var _ = Describe("0-RTT", func() {
 				Expect(get0RTTPackets(tracer.getRcvdLongHeaderPackets())).To(BeEmpty())
 			})
 
+			It("rejects 0-RTT when the application doesn't allow it", func() {
+				tlsConf, clientConf := dialAndReceiveSessionTicket(nil)
+
+				tracer := newPacketTracer()
+				var allowCalled uint32
+				ln, err := quic.ListenAddrEarly(
+					"localhost:0",
+					tlsConf,
+					getQuicConfig(&quic.Config{
+						Versions: []protocol.VersionNumber{version},
+						Allow0RTT: func(net.Addr) bool {
+							atomic.StoreUint32(&allowCalled, 1)
+							return false
+						},
+						Tracer: newTracer(func() logging.ConnectionTracer { return tracer }),
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				proxy, num0RTTPackets := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+				defer proxy.Close()
+
+				check0RTTRejected(ln, proxy.LocalPort(), clientConf)
+				Expect(atomic.LoadUint32(&allowCalled)).To(BeEquivalentTo(1))
+
+				// The client should send 0-RTT packets, but the server doesn't process them.
+				num0RTT := atomic.LoadUint32(num0RTTPackets)
+				fmt.Fprintf(GinkgoWriter, "Sent %d 0-RTT packets.", num0RTT)
+				Expect(num0RTT).ToNot(BeZero())
+				Expect(get0RTTPackets(tracer.getRcvdLongHeaderPackets())).To(BeEmpty())
+			})
+
 			It("rejects 0-RTT when the ALPN changed", func() {
 				tlsConf, clientConf := dialAndReceiveSessionTicket(nil)
 
@@ -677,6 +742,10 @@ var _ = Describe("0-RTT", func() {
 					Eventually(written).Should(Receive())
 					_, err = firstStr.Write([]byte("foobar"))
 					Expect(err).To(MatchError(quic.Err0RTTRejected))
+					var rejectedErr *quic.Stream0RTTRejectedError
+					Expect(errors.As(err, &rejectedErr)).To(BeTrue())
+					Expect(rejectedErr.StreamID).To(Equal(firstStr.StreamID()))
+					Expect(rejectedErr.BytesWritten).To(BeEquivalentTo(len("first flight")))
 					_, err = conn.OpenUniStream()
 					Expect(err).To(MatchError(quic.Err0RTTRejected))
 
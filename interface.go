@@ -48,8 +48,22 @@ type TokenStore interface {
 // * Accept{Uni}Stream
 // * Stream.Read and Stream.Write
 // when the server rejects a 0-RTT connection attempt.
+// Stream.Write (and the Write method of SendStream) returns a *Stream0RTTRejectedError,
+// which matches Err0RTTRejected when using errors.Is.
 var Err0RTTRejected = errors.New("0-RTT rejected")
 
+// ErrNoConnectionIDAvailable is returned from Connection.RotateConnectionID
+// when the peer hasn't issued any connection ID that could be switched to.
+var ErrNoConnectionIDAvailable = errors.New("no unused connection ID available")
+
+// ErrActiveMigrationDisabled is returned from Connection.MigrateTo
+// when the server sent the disable_active_migration transport parameter.
+var ErrActiveMigrationDisabled = errors.New("peer disabled active migration")
+
+// ErrPathValidationTimeout is returned from Connection.MigrateTo
+// when the path from the new packet conn couldn't be validated in time.
+var ErrPathValidationTimeout = errors.New("path validation timed out")
+
 // ConnectionTracingKey can be used to associate a ConnectionTracer with a Connection.
 // It is set on the Connection.Context() context,
 // as well as on the context passed to logging.Tracer.NewConnectionTracer.
@@ -91,6 +105,24 @@ type ReceiveStream interface {
 	// A zero value for t means Read will not time out.
 
 	SetReadDeadline(t time.Time) error
+	// SetReadBufferSize sets a soft limit for the amount of data that is buffered on this stream,
+	// i.e. data that was received from the peer, but not yet read by the application.
+	// As long as more data is buffered, no flow control updates (MAX_STREAM_DATA frames) are sent,
+	// which trades throughput for memory usage.
+	// The limit only takes effect if it is smaller than the flow control window.
+	// A value of 0 (the default) means that the amount of buffered data is only limited by flow control.
+	SetReadBufferSize(size uint64)
+	// ReadBuffer returns the next chunk of data received on this stream, without copying it.
+	// It blocks until data is available, and respects the deadline set by SetReadDeadline.
+	// The returned slice is owned by the stream: it must not be modified, and it is only valid
+	// until the next call to Read, ReadBuffer or ReleaseBuffer.
+	// It returns the same errors as Read. In particular, io.EOF may be returned together with the last chunk of data.
+	// ReadBuffer must not be called concurrently with Read.
+	ReadBuffer() ([]byte, error)
+	// ReleaseBuffer releases the slice returned by the last call to ReadBuffer.
+	// Calling it is optional, since the next call to Read or ReadBuffer releases the slice as well.
+	// It allows the memory to be reused earlier, if the application doesn't read from the stream right away.
+	ReleaseBuffer()
 }
 
 // A SendStream is a unidirectional Send Stream.
@@ -105,6 +137,16 @@ type SendStream interface {
 	// If the connection was closed due to a timeout, the error satisfies
 	// the net.Error interface, and Timeout() will be true.
 	io.Writer
+	// WriteBuffers writes the contents of multiple buffers to the stream, as if they were concatenated.
+	// Small buffers are coalesced into STREAM frames, so there's no need to concatenate them beforehand.
+	// Otherwise, it behaves like Write.
+	// Note that net.Buffers.WriteTo doesn't use this method, its fast path only works for the standard library's connections.
+	WriteBuffers(bufs net.Buffers) (int64, error)
+	// ReadFrom reads data from r until io.EOF or an error occurs, and writes it to the stream.
+	// Data is read in chunks of (at most) one packet, using a buffer from quic-go's buffer pool,
+	// so io.Copy doesn't need to allocate a buffer.
+	// Otherwise, it behaves like Write.
+	io.ReaderFrom
 	// Close closes the write-direction of the stream.
 	// Future calls to Write are not permitted after calling Close.
 	// It must not be called concurrently with Write.
@@ -115,6 +157,12 @@ type SendStream interface {
 	// Write will unblock immediately, and future calls to Write will fail.
 	// When called multiple times or after closing the stream it is a no-op.
 	CancelWrite(StreamErrorCode)
+	// CancelWriteAt aborts sending on this stream, like CancelWrite.
+	// However, the first reliableSize bytes of the stream are guaranteed to be delivered to the peer.
+	// reliableSize is capped to the amount of data that was already sent out.
+	// This uses a RESET_STREAM_AT frame, which is only sent if the peer enabled Config.EnableStreamResetPartialDelivery.
+	// Otherwise, the stream is reset with a RESET_STREAM frame, just like with CancelWrite.
+	CancelWriteAt(errorCode StreamErrorCode, reliableSize uint64)
 	// The Context is canceled as soon as the write-side of the stream is closed.
 	// This happens when Close() or CancelWrite() is called, or when the peer
 	// cancels the read-side of their stream.
@@ -125,6 +173,11 @@ type SendStream interface {
 	// some data was successfully written.
 	// A zero value for t means Write will not time out.
 	SetWriteDeadline(t time.Time) error
+	// BufferedBytes returns the number of bytes written to the stream that haven't been acknowledged by the peer yet.
+	// This includes data that wasn't sent yet, for example because sending is blocked by flow control or congestion control.
+	// It can be used to avoid buffering too much data on a stream.
+	// Once writing was canceled, or the connection was closed, it returns 0.
+	BufferedBytes() uint64
 }
 
 // A Connection is a QUIC connection between two peers.
@@ -148,7 +201,8 @@ type Connection interface {
 	// There is no signaling to the peer about new streams:
 	// The peer can only accept the stream after data has been sent on the stream.
 	// If the error is non-nil, it satisfies the net.Error interface.
-	// When reaching the peer's stream limit, err.Temporary() will be true.
+	// When reaching the peer's stream limit, err.Temporary() will be true,
+	// and the error is a *StreamLimitReachedError.
 	// If the connection was closed due to a timeout, Timeout() will be true.
 	OpenStream() (Stream, error)
 	// OpenStreamSync opens a new bidirectional QUIC stream.
@@ -158,7 +212,8 @@ type Connection interface {
 	OpenStreamSync(context.Context) (Stream, error)
 	// OpenUniStream opens a new outgoing unidirectional QUIC stream.
 	// If the error is non-nil, it satisfies the net.Error interface.
-	// When reaching the peer's stream limit, Temporary() will be true.
+	// When reaching the peer's stream limit, Temporary() will be true,
+	// and the error is a *StreamLimitReachedError.
 	// If the connection was closed due to a timeout, Timeout() will be true.
 	OpenUniStream() (SendStream, error)
 	// OpenUniStreamSync opens a new outgoing unidirectional QUIC stream.
@@ -169,16 +224,85 @@ type Connection interface {
 	// LocalAddr returns the local address.
 	LocalAddr() net.Addr
 	// RemoteAddr returns the address of the peer.
+	// When the peer migrates to a new address, it returns the new address once the new path has been validated.
 	RemoteAddr() net.Addr
+	// LocalConnectionID returns the source connection ID that this endpoint chose during the handshake.
+	// Together with RemoteConnectionID, this allows correlating the logs of the client and the server.
+	// Note that the peer is free to use any other connection ID that we issued later,
+	// and switches connection IDs when it rotates them or migrates to a new path.
+	LocalConnectionID() ConnectionID
+	// RemoteConnectionID returns the source connection ID that the peer chose during the handshake.
+	// For a client, this is the connection ID chosen by the server.
+	// It blocks until the handshake completes. If the connection is closed before that, it returns a zero-length connection ID.
+	// Note that after the handshake, packets are sent using other connection IDs issued by the peer,
+	// for example when the connection ID is rotated (see RotateConnectionID) or after a migration.
+	RemoteConnectionID() ConnectionID
+	// ResetAllStreams cancels reading and writing on all streams that are currently open,
+	// using the given error code, as if CancelRead and CancelWrite were called on every stream.
+	// This includes streams opened by the peer that haven't been accepted yet.
+	// Streams opened concurrently might not be reset.
+	// Streams that were already closed or canceled don't need to be skipped by the caller.
+	ResetAllStreams(StreamErrorCode)
 	// CloseWithError closes the connection with an error.
 	// The error string will be sent to the peer.
+	// Data that was written to a stream, but not yet sent or acknowledged, might be lost.
 	CloseWithError(ApplicationErrorCode, string) error
+	// CloseGracefully closes the connection once all stream data that was written so far
+	// has been sent and acknowledged by the peer, and then closes the connection with an error (like CloseWithError).
+	// After calling it, opening new streams and sending datagrams fails. The application should stop writing to
+	// existing streams: data written after CloseGracefully was called delays closing the connection.
+	// Only data that is sendable is waited for: data that is blocked by flow control when all other data has been
+	// acknowledged is lost. Datagrams are not retransmitted, and are therefore never waited for.
+	// If the context is canceled before all data was acknowledged, the connection is closed immediately,
+	// and the context's error is returned.
+	// It blocks until the connection is closed.
+	CloseGracefully(ctx context.Context, code ApplicationErrorCode, desc string) error
 	// The context is cancelled when the connection is closed.
+	// The error that closed the connection can be retrieved using ContextCause.
 	Context() context.Context
 	// ConnectionState returns basic details about the QUIC connection.
 	// It blocks until the handshake completes.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() ConnectionState
+	// IdleTimeout returns the idle timeout in effect for this connection.
+	// This is the minimum of the max_idle_timeout values advertised by both endpoints.
+	// It blocks until the handshake completes.
+	// If the connection is closed before that, it returns 0.
+	IdleTimeout() time.Duration
+	// PathMTU returns the maximum size of the packets (i.e. the UDP payload) currently sent on this connection.
+	// It starts at Config.InitialPacketSize, and is adjusted by Path MTU Discovery.
+	PathMTU() uint16
+	// SetMaxSendRate limits the rate at which this connection sends data, in bytes per second,
+	// for example to share the available bandwidth fairly between multiple connections.
+	// The limit is enforced by the pacer, in addition to (and independently of) the congestion controller:
+	// the connection sends at the lower of the two rates. Short bursts (of about 10 packets) are still possible.
+	// A value of 0 removes the limit.
+	// It has no effect if pacing is disabled using Config.DisablePacing.
+	SetMaxSendRate(bytesPerSecond uint64)
+	// Ping sends a PING frame, and returns the time that elapsed until the peer acknowledged it.
+	// This provides an RTT sample without having to send any application data.
+	// The measured time includes the time the peer delayed sending the acknowledgement,
+	// unless both endpoints enabled Config.EnableAckFrequency.
+	// It blocks until the handshake completes. If the PING frame is lost, it is retransmitted.
+	Ping(context.Context) (time.Duration, error)
+	// RotateConnectionID switches to a new connection ID issued by the peer,
+	// and retires the connection ID currently in use.
+	// This makes it harder for on-path observers to link packets sent before and after the rotation.
+	// It returns ErrNoConnectionIDAvailable if the peer hasn't issued any unused connection IDs,
+	// and an error if the handshake hasn't completed yet.
+	RotateConnectionID() error
+	// MigrateTo moves the connection to a new packet conn, e.g. after the network interface changed.
+	// It can only be used by the client, once the handshake is confirmed.
+	// Packets are sent from the new local address right away, and a new connection ID is used if available.
+	// MigrateTo blocks until the new path has been validated.
+	// If ctx is canceled before that, the migration is continued nevertheless.
+	// If the path isn't validated within 3 PTOs, the connection moves back to the old packet conn,
+	// and ErrPathValidationTimeout is returned.
+	// The connection takes ownership of the new packet conn, which must not be used for anything else:
+	// It is closed when the migration fails, or when the connection is closed.
+	// The application is responsible for closing the old packet conn (if it was passed to Dial).
+	// It returns ErrActiveMigrationDisabled if the server disabled active migration.
+	MigrateTo(ctx context.Context, conn net.PacketConn) error
 
 	// SendMessage sends a message as a datagram, as specified in RFC 9221.
 	SendMessage([]byte) error
@@ -190,6 +314,14 @@ type Connection interface {
 // Data sent during the handshake is encrypted using the forward secure keys.
 // When using client certificates, the client's identity is only verified
 // after completion of the handshake.
+//
+// On the server side, an EarlyConnection is returned by the EarlyListener as soon as
+// the client's transport parameters have been processed. If the client uses 0-RTT,
+// streams opened by the client in 0-RTT can be accepted and read from immediately.
+// 0-RTT data can be replayed by an attacker, so the application should wait for
+// HandshakeComplete before performing any non-idempotent operation.
+// Streams opened during the 0-RTT phase are regular streams of this connection:
+// they stay usable after the handshake completes, there's no need to call NextConnection.
 type EarlyConnection interface {
 	Connection
 
@@ -197,8 +329,26 @@ type EarlyConnection interface {
 	// Data sent before completion of the handshake is encrypted with 1-RTT keys.
 	// Note that the client's identity hasn't been verified yet.
 	HandshakeComplete() context.Context
+	// AwaitHandshake blocks until the handshake completes.
+	// It returns the error that closed the connection if the handshake fails,
+	// and the context's error if the context is canceled before the handshake completes.
+	// Together with DialAddrEarly, this allows establishing a connection ahead of time:
+	// the application dials, calls AwaitHandshake (e.g. in a separate Go routine) to learn
+	// when the connection is ready to be used, and sets Config.KeepAlivePeriod to prevent the
+	// connection from timing out while it is idle.
+	AwaitHandshake(context.Context) error
 
+	// NextConnection blocks until the handshake completes, and then returns the connection
+	// that is used after the handshake.
+	// If the server rejected 0-RTT, all streams opened on the client's EarlyConnection are closed
+	// with Err0RTTRejected, and new streams need to be opened on the returned connection.
+	// Until NextConnection is called, opening and accepting streams keeps returning Err0RTTRejected.
 	NextConnection() Connection
+	// ZeroRTTTransportParameters returns the server's transport parameters that were remembered from a previous connection,
+	// and that were used to send 0-RTT data on this connection.
+	// They can be compared to the transport parameters that the server sends during this handshake.
+	// It returns nil if 0-RTT was not attempted, and on the server side.
+	ZeroRTTTransportParameters() *logging.TransportParameters
 }
 
 // A ConnectionID is a QUIC Connection ID, as defined in RFC 9000.
@@ -245,17 +395,116 @@ type Config struct {
 	// Specifically, if we don't receive any packet from the peer within this time, the connection attempt is aborted.
 	// If this value is zero, the timeout is set to 5 seconds.
 	HandshakeIdleTimeout time.Duration
+	// HandshakeTimeout is the maximum time the handshake may take, even if packets are received from the peer.
+	// If the handshake doesn't complete within this time, the connection attempt fails with a HandshakeTimeoutError.
+	// Canceling the context passed to Dial aborts the handshake earlier.
+	// If this value is zero, the timeout is set to the larger of 10 seconds and twice the HandshakeIdleTimeout.
+	HandshakeTimeout time.Duration
 	// MaxIdleTimeout is the maximum duration that may pass without any incoming network activity.
 	// The actual value for the idle timeout is the minimum of this value and the peer's.
 	// This value only applies after the handshake has completed.
 	// If the timeout is exceeded, the connection is closed.
 	// If this value is zero, the timeout is set to 30 seconds.
 	MaxIdleTimeout time.Duration
+	// InitialRTT is the RTT estimate used before the first RTT sample is taken.
+	// It determines the probe timeout (PTO) for the first packets sent, which is twice this value.
+	// This is useful on links with a high RTT (e.g. satellite links), to avoid spurious retransmissions.
+	// It must not be larger than 10 seconds. If this value is zero, 100ms is used.
+	InitialRTT time.Duration
+	// AckDelayExponent is the ack_delay_exponent, which determines the granularity of the ACK delay
+	// in the ACK frames we send (see section 18.2 of RFC 9000).
+	// Lower values reduce the quantization of the ACK delay on links with a low RTT,
+	// at the cost of slightly larger ACK frames.
+	// Values above 20 are invalid.
+	// If not set, it will default to 3.
+	// If set to a negative value, an exponent of 0 is used.
+	AckDelayExponent int
+	// MaxAckDelay is the maximum time by which we delay sending ACKs for 1-RTT packets.
+	// The peer uses this value (plus the timer granularity) when calculating its probe timeout (PTO),
+	// see section 18.2 of RFC 9000.
+	// It must be smaller than 2^14 milliseconds. If this value is zero, 25ms is used.
+	MaxAckDelay time.Duration
+	// InitialCongestionWindow is the initial congestion window, in packets.
+	// If this value is zero, 32 packets are used.
+	// RFC 9002 recommends an initial window of 10 packets (see section 7.2).
+	// Raising it beyond that is not compliant when sending on the open internet,
+	// and should only be done on networks that are known to be able to handle the burst.
+	InitialCongestionWindow uint32
+	// MinCongestionWindow is the minimum congestion window, in packets.
+	// The congestion window is never reduced below this value, not even after a persistent congestion event.
+	// It must be at least 2 packets (the minimum mandated by RFC 9002), which is also the default.
+	MinCongestionWindow uint32
+	// DisablePacing disables pacing of outgoing packets.
+	// Packets are then sent as fast as the congestion window allows.
+	// This reduces latency for traffic on the local host or in benchmarks,
+	// but will likely lead to packet loss when sending over the internet.
+	DisablePacing bool
+	// PathDegradingThreshold is the number of consecutive probe timeouts (PTOs) after which PathDegradingCallback is called.
+	// If this value is zero, 3 PTOs are used.
+	PathDegradingThreshold uint32
+	// PathDegradingCallback is called when PathDegradingThreshold consecutive PTOs fired without any
+	// acknowledgement from the peer. This is an indication that the peer might have vanished,
+	// long before the idle timeout expires. It can be used to migrate the connection or to warn the user.
+	// The callback is called again if the PTO count crosses the threshold again after the peer responded.
+	// It is also called when the smoothed RTT exceeds the PathDegradingRTTThreshold.
+	// It is called from the connection's run loop, and must not block.
+	PathDegradingCallback func(Connection)
+	// PathDegradingRTTThreshold is the smoothed RTT above which PathDegradingCallback is called.
+	// The callback is called again if the smoothed RTT crosses the threshold again after it dropped below it.
+	// If this value is zero, the RTT is not taken into account.
+	PathDegradingRTTThreshold time.Duration
+	// PathDownCallback is called when the peer hasn't responded to any of our packets for so long
+	// that the connection is about to run into the idle timeout, 3 PTOs before the idle timeout expires.
+	// While PathDegradingCallback signals that the path is getting worse, this signals that the path is most likely dead.
+	// Applications can use it to open a backup connection.
+	// The callback is called again if the peer responded and then stops responding again.
+	// It is called from the connection's run loop, and must not block.
+	PathDownCallback func(Connection)
+	// RemoteAddressChanged is called when the peer migrated to a new address, once the new path has been validated.
+	// From that point on, Connection.RemoteAddr returns the new address.
+	// Servers handle migrations of the peer, clients migrate to the server's preferred address.
+	// It is called on a new goroutine, so it may block. Calls for consecutive migrations might run concurrently.
+	RemoteAddressChanged func(conn Connection, oldAddr, newAddr net.Addr)
+	// DisableActiveMigration sends the disable_active_migration transport parameter (see section 18.2 of RFC 9000),
+	// telling the peer that it must not migrate the connection to a new address.
+	// A server drops all packets it receives from a new client address once the handshake is confirmed.
+	// Note that this also breaks connections of clients whose address is changed by a NAT rebinding.
+	// Migrating to the server's preferred address is still allowed.
+	DisableActiveMigration bool
+	// PreferredAddressIPv4 and PreferredAddressIPv6 are sent to the client in the preferred_address transport parameter.
+	// Once the handshake is confirmed, the client validates the path to the preferred address
+	// of the same address family it used for the handshake, and migrates the connection to it.
+	// Packets sent to the preferred address must be delivered to the same socket that the server is listening on.
+	// Only valid for a server.
+	PreferredAddressIPv4 *net.UDPAddr
+	PreferredAddressIPv6 *net.UDPAddr
 	// RequireAddressValidation determines if a QUIC Retry packet is sent.
 	// This allows the server to verify the client's address, at the cost of increasing the handshake latency by 1 RTT.
 	// See https://datatracker.ietf.org/doc/html/rfc9000#section-8 for details.
 	// If not set, every client is forced to prove its remote address.
 	RequireAddressValidation func(net.Addr) bool
+	// VerifyClient is called when an Initial packet for a new connection is received,
+	// before any cryptographic work for the handshake is done.
+	// hasValidToken says if the client presented a valid token, i.e. if it proved ownership of its address.
+	// If it returns false, the connection attempt is rejected with a CONNECTION_REFUSED error.
+	// If not set, all clients are accepted. Only valid for a server.
+	VerifyClient func(clientAddr net.Addr, hasValidToken bool) bool
+	// MaxHandshakesPerIP is the maximum number of concurrent handshakes that a single source IP address can have.
+	// Connection attempts exceeding this limit are rejected with a CONNECTION_REFUSED error.
+	// If zero, the number of handshakes is not limited. Only valid for a server.
+	MaxHandshakesPerIP int
+	// MaxAcceptQueueSize is the maximum number of connections that the server queues until they are accepted
+	// by the application, i.e. connections that completed the handshake (or, when using ListenEarly,
+	// that are ready to be used) but weren't returned by Accept yet.
+	// While the queue is full, new connection attempts are rejected with a CONNECTION_REFUSED error.
+	// Handshakes that are already in progress are not aborted, so the queue can temporarily exceed this size.
+	// If zero, a default value of 32 is used. Only valid for a server.
+	MaxAcceptQueueSize int
+	// Allow0RTT is called when a client attempts to use 0-RTT on a resumed connection,
+	// and the transport parameters allow accepting 0-RTT data.
+	// If it returns false, 0-RTT is rejected, and all data is delivered to the application after the handshake completes.
+	// If not set, 0-RTT is accepted. Only valid for a server that accepts early connections (see ListenEarly).
+	Allow0RTT func(clientAddr net.Addr) bool
 	// MaxRetryTokenAge is the maximum age of a Retry token.
 	// If not set, it defaults to 5 seconds. Only valid for a server.
 	MaxRetryTokenAge time.Duration
@@ -306,25 +555,156 @@ type Config struct {
 	StatelessResetKey []byte
 	// KeepAlivePeriod defines whether this peer will periodically send a packet to keep the connection alive.
 	// If set to 0, then no keep alive is sent. Otherwise, the keep alive is sent on that period (or at most
-	// every half of MaxIdleTimeout, whichever is smaller).
+	// every half of MaxIdleTimeout, whichever is smaller), unless ExactKeepAlivePeriod is set.
 	KeepAlivePeriod time.Duration
+	// ExactKeepAlivePeriod makes this peer use the KeepAlivePeriod as is, instead of limiting it to
+	// half of the idle timeout (and to 20s). A PING is then sent whenever no packet was received for KeepAlivePeriod.
+	// This is useful to keep NAT bindings alive on connections that use a long idle timeout.
+	// Note that the idle timeout is only reset when a packet is received from the peer (e.g. the ACK for the PING).
+	// Using a KeepAlivePeriod that's larger than the idle timeout therefore doesn't prevent the connection from timing out.
+	ExactKeepAlivePeriod bool
 	// DisablePathMTUDiscovery disables Path MTU Discovery (RFC 8899).
-	// Packets will then be at most 1252 (IPv4) / 1232 (IPv6) bytes in size.
+	// Packets will then be at most InitialPacketSize bytes in size.
 	// Note that if Path MTU discovery is causing issues on your system, please open a new issue
 	DisablePathMTUDiscovery bool
+	// InitialPacketSize is the size of the packets sent before Path MTU Discovery finds a larger size.
+	// It should only be set if it is known that the path supports packets of this size.
+	// It must be at least 1200 bytes, and at most MaxPacketSize (or 1452 bytes, if MaxPacketSize is not set).
+	// If not set, it defaults to 1252 bytes (IPv4) / 1232 bytes (IPv6).
+	InitialPacketSize uint16
+	// MaxPacketSize is the largest packet size that Path MTU Discovery probes for.
+	// It is also the size of the largest packets that we're willing to receive,
+	// which is sent to the peer in the max_udp_payload_size transport parameter.
+	// It must be at least 1200 bytes, and at most 65527 bytes.
+	// Values larger than 1452 bytes are only useful on links that support jumbo frames.
+	// Larger packet buffers are then used for reading from the net.PacketConn (for all connections using it),
+	// and for packing packets.
+	// The peer's max_udp_payload_size transport parameter further limits the packet size.
+	// If not set, it defaults to 1452 bytes.
+	MaxPacketSize uint16
 	// DisableVersionNegotiationPackets disables the sending of Version Negotiation packets.
 	// This can be useful if version information is exchanged out-of-band.
 	// It has no effect for a client.
 	DisableVersionNegotiationPackets bool
+	// DisableVersionNegotiation makes the client fail the connection attempt when it receives a Version Negotiation packet,
+	// instead of retrying with a version that is supported by both endpoints.
+	// The connection attempt fails with a VersionNegotiationError.
+	// This is useful when the client knows which version the server supports, and wants to fail fast otherwise.
+	// It has no effect for a server.
+	DisableVersionNegotiation bool
+	// DisableGSO disables UDP Generic Segmentation Offload.
+	// By default, GSO is used on Linux if the kernel supports it, allowing multiple packets to be sent with a single syscall.
+	// Some NICs and drivers don't handle GSO correctly. Packets are then sent one by one.
+	// GSO is currently only used by servers, this has no effect for a client.
+	DisableGSO bool
+	// EnableTxTimestamps makes the connection report the time every packet was sent to the tracer,
+	// see logging.ConnectionTracer.SentPacketTimestamp. It has no effect if no Tracer is set.
+	// On Linux, servers use the software TX timestamps reported by the kernel (SO_TIMESTAMPING).
+	// Requesting these timestamps comes with a small performance cost.
+	EnableTxTimestamps bool
 	// Enable QUIC datagram support (RFC 9221).
 	EnableDatagrams bool
-	Tracer          logging.Tracer
+	// MaxDatagramFrameSize is the maximum size of a DATAGRAM frame (including the frame header) that we're willing to receive.
+	// It is sent to the peer in the max_datagram_frame_size transport parameter, if EnableDatagrams is set.
+	// DATAGRAM frames can't be split across multiple packets, so values larger than the packet size only
+	// make sense on paths that support larger packets.
+	// If this value is zero, 1220 bytes are used, such that a DATAGRAM frame fits into a full-size QUIC packet.
+	MaxDatagramFrameSize uint64
+	// EnableStreamResetPartialDelivery enables support for the RESET_STREAM_AT frame
+	// (draft-ietf-quic-reliable-stream-reset), see SendStream.CancelWriteAt.
+	EnableStreamResetPartialDelivery bool
+	// EnableAckFrequency advertises support for the ACK frequency extension (draft-ietf-quic-ack-frequency),
+	// using the min_ack_delay transport parameter.
+	// The peer can then send ACK_FREQUENCY frames to change the rate at which ACKs are sent,
+	// and IMMEDIATE_ACK frames to request an immediate acknowledgement.
+	// If both endpoints enable it, Connection.Ping and PTO probe packets request an immediate acknowledgement.
+	// If the peer doesn't advertise the min_ack_delay, the extension is not used.
+	EnableAckFrequency bool
+	// MinAckDelay is the min_ack_delay advertised to the peer if EnableAckFrequency is set.
+	// The peer can't request a max_ack_delay smaller than this value using ACK_FREQUENCY frames.
+	// It must not be larger than MaxAckDelay. If this value is zero, 1ms is used.
+	MinAckDelay time.Duration
+	// ExtensionFrameTypes registers frame types that are not handled by quic-go,
+	// e.g. frame types defined by an extension, or used for experiments.
+	// The ExtensionFrameParser determines the length of a frame of this type.
+	// Registering a frame type doesn't negotiate its use with the peer, this has to happen out of band.
+	// Frame types 0x00 - 0x3f are assigned by Standards Action or IESG Approval (see Section 22.4 of RFC 9000).
+	// Only large frame types that are not registered with IANA are safe to use for experiments.
+	// Frames of all other unknown frame types are treated as a connection error (FRAME_ENCODING_ERROR).
+	ExtensionFrameTypes map[uint64]ExtensionFrameParser
+	// HandleExtensionFrame is called for every frame of a type registered in ExtensionFrameTypes.
+	// frame is the complete frame, including the frame type. It must not be used after the callback returns.
+	// If the callback returns an error, the connection is closed with a PROTOCOL_VIOLATION.
+	// If it is nil, these frames are ignored.
+	HandleExtensionFrame func(c Connection, frameType uint64, frame []byte) error
+	Tracer               logging.Tracer
+}
+
+// An ExtensionFrameParser is passed the packet data starting at the frame type.
+// It returns the length of the frame, including the frame type,
+// or an error if the frame is malformed.
+type ExtensionFrameParser func(b []byte) (int, error)
+
+// A ClientHelloConn is the net.Conn set in the tls.ClientHelloInfo that is passed to
+// the tls.Config's GetConfigForClient and GetCertificate callbacks of a server.
+// Its RemoteAddr is the address of the client.
+// The tls.Config returned by GetConfigForClient is used for the handshake, including its
+// certificates, its GetCertificate callback and its NextProtos.
+type ClientHelloConn interface {
+	net.Conn
+	// GetQUICVersion returns the QUIC version used on the connection.
+	GetQUICVersion() VersionNumber
+	// GetTransportParameters returns the transport parameters sent by the client.
+	// It returns nil if the ClientHello didn't contain valid transport parameters.
+	GetTransportParameters() *logging.TransportParameters
 }
 
 // ConnectionState records basic details about a QUIC connection
 type ConnectionState struct {
-	TLS               handshake.ConnectionState
-	SupportsDatagrams bool
+	TLS                  handshake.ConnectionState
+	SupportsDatagrams    bool
+	Handshake            HandshakeTimeline
+	UndecryptablePackets UndecryptablePacketStats
+	// ECNCongestionSignals is the number of times the peer reported an increase of the ECN-CE count,
+	// i.e. that packets were marked as having experienced congestion.
+	// The congestion controller reacts to these signals like to packet loss, by reducing the congestion window
+	// (at most once per round trip).
+	ECNCongestionSignals uint64
+	// UsesPacketInfo says if packets are sent from the local address that the peer sent its packets to.
+	// This requires reading the destination address of received packets (IP_PKTINFO / IPV6_PKTINFO),
+	// and is used when listening on an unspecified address (e.g. 0.0.0.0), if supported by the platform.
+	// On a multi-homed host, this makes sure that the server replies from the right local address.
+	UsesPacketInfo bool
+}
+
+// HandshakeTimeline records when the milestones of the handshake were reached.
+// Milestones that haven't been reached yet are the zero time.Time.
+type HandshakeTimeline struct {
+	// Start is the time when the connection was created.
+	Start time.Time
+	// FirstFlightSent is the time when the first packet was sent.
+	FirstFlightSent time.Time
+	// HandshakeKeys is the time when the first Handshake packet was received,
+	// i.e. when Handshake keys were available to decrypt it.
+	HandshakeKeys time.Time
+	// HandshakeComplete is the time when the TLS handshake completed.
+	HandshakeComplete time.Time
+	// HandshakeConfirmed is the time when the handshake was confirmed.
+	// For the server, this is the same time as HandshakeComplete.
+	// For the client, this is the time when the HANDSHAKE_DONE frame was received.
+	HandshakeConfirmed time.Time
+}
+
+// UndecryptablePacketStats counts packets that were received before the keys to decrypt them were available.
+// These packets are queued, and decrypted once the keys become available.
+type UndecryptablePacketStats struct {
+	// Buffered is the number of packets that were queued for later decryption.
+	Buffered uint64
+	// Decrypted is the number of queued packets that were decrypted later.
+	Decrypted uint64
+	// Dropped is the number of packets that couldn't be decrypted,
+	// either because the queue was full, or because the keys were never (or no longer) available.
+	Dropped uint64
 }
 
 // A Listener for incoming QUIC connections
@@ -335,6 +715,12 @@ type Listener interface {
 	Addr() net.Addr
 	// Accept returns new connections. It should be called in a loop.
 	Accept(context.Context) (Connection, error)
+	// Shutdown gracefully shuts down the server, without interrupting active connections.
+	// It stops accepting new connections, and waits for all active connections to be closed,
+	// or for the context to expire, whichever happens first.
+	// In the latter case, the remaining connections are closed with an application error.
+	// While draining, active connections stop issuing new connection IDs to the peer.
+	Shutdown(context.Context) error
 }
 
 // An EarlyListener listens for incoming QUIC connections,
@@ -345,5 +731,14 @@ type EarlyListener interface {
 	// Addr returns the local network addr that the server is listening on.
 	Addr() net.Addr
 	// Accept returns new early connections. It should be called in a loop.
+	// Connections are returned before the handshake completes, allowing the application
+	// to accept and read streams opened by the client in 0-RTT.
+	// Use EarlyConnection.HandshakeComplete to wait for the completion of the handshake.
 	Accept(context.Context) (EarlyConnection, error)
+	// Shutdown gracefully shuts down the server, without interrupting active connections.
+	// It stops accepting new connections, and waits for all active connections to be closed,
+	// or for the context to expire, whichever happens first.
+	// In the latter case, the remaining connections are closed with an application error.
+	// While draining, active connections stop issuing new connection IDs to the peer.
+	Shutdown(context.Context) error
 }
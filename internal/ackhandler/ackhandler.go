@@ -1,6 +1,8 @@
 package ackhandler
 
 import (
+	"time"
+
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/utils"
 	"github.com/fkwhite/quic-go/logging"
@@ -9,9 +11,17 @@ import (
 // NewAckHandler creates a new SentPacketHandler and a new ReceivedPacketHandler.
 // clientAddressValidated indicates whether the address was validated beforehand by an address validation token.
 // clientAddressValidated has no effect for a client.
+// The initial and the minimum congestion window are given in packets.
+// ackDelayExponent is the exponent used to encode the ACK delay of ACK frames sent in 1-RTT packets.
+// maxAckDelay is the maximum time by which ACKs for 1-RTT packets are delayed.
 func NewAckHandler(
 	initialPacketNumber protocol.PacketNumber,
 	initialMaxDatagramSize protocol.ByteCount,
+	initialCongestionWindow uint32,
+	minCongestionWindow uint32,
+	disablePacing bool,
+	ackDelayExponent uint8,
+	maxAckDelay time.Duration,
 	rttStats *utils.RTTStats,
 	clientAddressValidated bool,
 	pers protocol.Perspective,
@@ -19,6 +29,6 @@ func NewAckHandler(
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) (SentPacketHandler, ReceivedPacketHandler) {
-	sph := newSentPacketHandler(initialPacketNumber, initialMaxDatagramSize, rttStats, clientAddressValidated, pers, tracer, logger)
-	return sph, newReceivedPacketHandler(sph, rttStats, logger, version)
+	sph := newSentPacketHandler(initialPacketNumber, initialMaxDatagramSize, initialCongestionWindow, minCongestionWindow, disablePacing, rttStats, clientAddressValidated, pers, tracer, logger)
+	return sph, newReceivedPacketHandler(sph, ackDelayExponent, maxAckDelay, rttStats, logger, version)
 }
@@ -13,6 +13,14 @@ type SentPacketHandler interface {
 	SentPacket(packet *Packet)
 	ReceivedAck(ackFrame *wire.AckFrame, encLevel protocol.EncryptionLevel, recvTime time.Time) (bool /* 1-RTT packet acked */, error)
 	ReceivedBytes(protocol.ByteCount)
+	// MigratedPath is called when the peer migrated to a new address, which hasn't been validated yet.
+	// The bytes received are the size of the packet that was received from the new address.
+	// Until PathValidated is called, sending is limited by the anti-amplification limit.
+	// Unless only the peer's port changed, the congestion controller and the RTT estimate are reset
+	// (see section 9.4 of RFC 9000).
+	MigratedPath(bytesReceived protocol.ByteCount, resetCongestionState bool)
+	// PathValidated is called when the peer's current address was validated.
+	PathValidated()
 	DropPackets(protocol.EncryptionLevel)
 	ResetForRetry() error
 	SetHandshakeConfirmed()
@@ -25,6 +33,12 @@ type SentPacketHandler interface {
 	// HasPacingBudget says if the pacer allows sending of a (full size) packet at this moment.
 	HasPacingBudget() bool
 	SetMaxDatagramSize(count protocol.ByteCount)
+	// SetMaxSendRate limits the rate at which packets are sent, in bytes/s. 0 means unlimited.
+	// Unlike the other methods, it is safe to call it concurrently.
+	SetMaxSendRate(bytesPerSecond uint64)
+	// SetPacketFeedbackCallback sets a callback that is called when a 1-RTT packet is acknowledged or declared lost.
+	// Path MTU Discovery uses this to detect black holes.
+	SetPacketFeedbackCallback(func(size protocol.ByteCount, lost bool))
 
 	// only to be called once the handshake is complete
 	QueueProbePacket(protocol.EncryptionLevel) bool /* was a packet queued */
@@ -34,6 +48,14 @@ type SentPacketHandler interface {
 
 	GetLossDetectionTimeout() time.Time
 	OnLossDetectionTimeout() error
+	// PTOCount is the number of consecutive PTOs that fired without receiving an acknowledgement.
+	PTOCount() uint32
+	// ECNCongestionSignals is the number of ACK frames that reported an increase of the ECN-CE count,
+	// each of which was passed to the congestion controller as a congestion signal.
+	// Unlike the other methods, it is safe to call it concurrently.
+	ECNCongestionSignals() uint64
+	// HasOutstandingPackets says if there are ack-eliciting packets that were neither acknowledged nor declared lost.
+	HasOutstandingPackets() bool
 }
 
 type sentPacketTracker interface {
@@ -46,6 +68,12 @@ type ReceivedPacketHandler interface {
 	IsPotentiallyDuplicate(protocol.PacketNumber, protocol.EncryptionLevel) bool
 	ReceivedPacket(pn protocol.PacketNumber, ecn protocol.ECN, encLevel protocol.EncryptionLevel, rcvTime time.Time, shouldInstigateAck bool) error
 	DropPackets(protocol.EncryptionLevel)
+	// QueueAck queues an ACK for the application data packet number space,
+	// e.g. when the peer requested an immediate acknowledgement.
+	QueueAck()
+	// HandleAckFrequencyFrame applies the ACK frequency requested by the peer
+	// to the application data packet number space.
+	HandleAckFrequencyFrame(*wire.AckFrequencyFrame)
 
 	GetAlarmTimeout() time.Time
 	GetAckFrame(encLevel protocol.EncryptionLevel, onlyIfQueued bool) *wire.AckFrame
@@ -21,6 +21,8 @@ type Packet struct {
 	includedInBytesInFlight bool
 	declaredLost            bool
 	skippedPacket           bool
+
+	inPool bool // set when the packet is returned to the pool, used to detect double returns
 }
 
 func (p *Packet) outstanding() bool {
@@ -41,9 +43,17 @@ func GetPacket() *Packet {
 	p.includedInBytesInFlight = false
 	p.declaredLost = false
 	p.skippedPacket = false
+	p.inPool = false
 	return p
 }
 
-// We currently only return Packets back into the pool when they're acknowledged (not when they're lost).
-// This simplifies the code, and gives the vast majority of the performance benefit we can gain from using the pool.
-func putPacket(p *Packet) { packetPool.Put(p) }
+// Packets are returned to the pool when they're acknowledged,
+// and when a packet that was declared lost is deleted from the sent packet history.
+// At that point, the frames have already been retransmitted (or abandoned), so the packet isn't referenced any more.
+func putPacket(p *Packet) {
+	if p.inPool {
+		panic("ackhandler BUG: packet returned to the pool twice")
+	}
+	p.inPool = true
+	packetPool.Put(p)
+}
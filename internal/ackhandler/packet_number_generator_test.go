@@ -2,7 +2,9 @@ package ackhandler
 
 import (
 	"fmt"
+	"io"
 	"math"
+	"math/rand"
 
 	"github.com/fkwhite/quic-go/internal/protocol"
 
@@ -32,6 +34,24 @@ var _ = Describe("Skipping Packet Number Generator", func() {
 		Expect(2 * protocol.SkipPacketMaxPeriod).To(BeNumerically("<", math.MaxInt32))
 	})
 
+	It("uses a configurable source of randomness", func() {
+		defer func(r io.Reader) { protocol.RandReader = r }(protocol.RandReader)
+
+		popN := func(png packetNumberGenerator) []protocol.PacketNumber {
+			pns := make([]protocol.PacketNumber, 0, 1000)
+			for i := 0; i < 1000; i++ {
+				pns = append(pns, png.Pop())
+			}
+			return pns
+		}
+		protocol.RandReader = rand.New(rand.NewSource(42))
+		pns := popN(newSkippingPacketNumberGenerator(initialPN, initialPeriod, maxPeriod))
+		Expect(pns[len(pns)-1]).To(BeNumerically(">", initialPN+1000)) // packet numbers were skipped
+		// the same seed leads to the same packet numbers being skipped
+		protocol.RandReader = rand.New(rand.NewSource(42))
+		Expect(popN(newSkippingPacketNumberGenerator(initialPN, initialPeriod, maxPeriod))).To(Equal(pns))
+	})
+
 	It("can be initialized to return any first packet number", func() {
 		png := newSkippingPacketNumberGenerator(12345, initialPeriod, maxPeriod)
 		Expect(png.Pop()).To(Equal(protocol.PacketNumber(12345)))
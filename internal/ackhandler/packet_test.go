@@ -0,0 +1,76 @@
+package ackhandler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/fkwhite/quic-go/internal/utils"
+	"github.com/fkwhite/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Packet", func() {
+	It("resets packets obtained from the pool", func() {
+		for i := 0; i < 100; i++ {
+			p := GetPacket()
+			Expect(p.PacketNumber).To(BeZero())
+			Expect(p.Frames).To(BeEmpty())
+			Expect(p.declaredLost).To(BeFalse())
+			Expect(p.inPool).To(BeFalse())
+
+			p.PacketNumber = 42
+			p.Frames = []Frame{{Frame: &wire.PingFrame{}}}
+			p.EncryptionLevel = protocol.Encryption1RTT
+			p.SendTime = time.Now()
+			p.declaredLost = true
+			putPacket(p)
+		}
+	})
+
+	It("panics when a packet is returned to the pool twice", func() {
+		p := GetPacket()
+		putPacket(p)
+		Expect(func() { putPacket(p) }).To(Panic())
+	})
+
+	// This test is most useful when run with the race detector:
+	// It fails if a packet is still accessed after it was returned to the pool.
+	It("recycles acknowledged and lost packets used by multiple connections", func() {
+		const numConns = 8
+		const numPackets = 1000
+
+		var wg sync.WaitGroup
+		wg.Add(numConns)
+		for i := 0; i < numConns; i++ {
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				rttStats := utils.NewRTTStats()
+				hist := newSentPacketHistory(rttStats)
+				now := time.Now()
+				for pn := protocol.PacketNumber(0); pn < numPackets; pn++ {
+					p := GetPacket()
+					p.PacketNumber = pn
+					p.Frames = []Frame{{Frame: &wire.PingFrame{}}}
+					p.SendTime = now.Add(-time.Hour)
+					hist.SentAckElicitingPacket(p)
+					if pn%2 == 0 {
+						// acknowledge the packet
+						Expect(hist.Remove(pn)).To(Succeed())
+						putPacket(p)
+					} else {
+						hist.DeclareLost(p)
+					}
+					// delete the lost packet (if any)
+					hist.DeleteOldPackets(now)
+					Expect(hist.Len()).To(BeZero())
+				}
+			}()
+		}
+		wg.Wait()
+	})
+})
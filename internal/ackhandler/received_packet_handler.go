@@ -23,15 +23,18 @@ var _ ReceivedPacketHandler = &receivedPacketHandler{}
 
 func newReceivedPacketHandler(
 	sentPackets sentPacketTracker,
+	ackDelayExponent uint8,
+	maxAckDelay time.Duration,
 	rttStats *utils.RTTStats,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) ReceivedPacketHandler {
+	// The ack_delay_exponent and the max_ack_delay only apply to ACK frames sent in 1-RTT packets.
 	return &receivedPacketHandler{
 		sentPackets:      sentPackets,
-		initialPackets:   newReceivedPacketTracker(rttStats, logger, version),
-		handshakePackets: newReceivedPacketTracker(rttStats, logger, version),
-		appDataPackets:   newReceivedPacketTracker(rttStats, logger, version),
+		initialPackets:   newReceivedPacketTracker(protocol.DefaultAckDelayExponent, protocol.MaxAckDelay, rttStats, logger, version),
+		handshakePackets: newReceivedPacketTracker(protocol.DefaultAckDelayExponent, protocol.MaxAckDelay, rttStats, logger, version),
+		appDataPackets:   newReceivedPacketTracker(ackDelayExponent, maxAckDelay, rttStats, logger, version),
 		lowest1RTTPacket: protocol.InvalidPacketNumber,
 	}
 }
@@ -81,6 +84,14 @@ func (h *receivedPacketHandler) DropPackets(encLevel protocol.EncryptionLevel) {
 	}
 }
 
+func (h *receivedPacketHandler) QueueAck() {
+	h.appDataPackets.QueueAck()
+}
+
+func (h *receivedPacketHandler) HandleAckFrequencyFrame(f *wire.AckFrequencyFrame) {
+	h.appDataPackets.HandleAckFrequencyFrame(f)
+}
+
 func (h *receivedPacketHandler) GetAlarmTimeout() time.Time {
 	var initialAlarm, handshakeAlarm time.Time
 	if h.initialPackets != nil {
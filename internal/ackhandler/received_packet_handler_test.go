@@ -21,6 +21,8 @@ var _ = Describe("Received Packet Handler", func() {
 		sentPackets = NewMockSentPacketTracker(mockCtrl)
 		handler = newReceivedPacketHandler(
 			sentPackets,
+			protocol.AckDelayExponent,
+			protocol.MaxAckDelay,
 			&utils.RTTStats{},
 			utils.DefaultLogger,
 			protocol.VersionWhatever,
@@ -8,8 +8,9 @@ import (
 	"github.com/fkwhite/quic-go/internal/wire"
 )
 
-// number of ack-eliciting packets received before sending an ack.
-const packetsBeforeAck = 2
+// number of ack-eliciting packets received before sending an ack,
+// unless the peer requested a different value using an ACK_FREQUENCY frame.
+const defaultPacketsBeforeAck = 2
 
 type receivedPacketTracker struct {
 	largestObserved             protocol.PacketNumber
@@ -19,8 +20,9 @@ type receivedPacketTracker struct {
 
 	packetHistory *receivedPacketHistory
 
-	maxAckDelay time.Duration
-	rttStats    *utils.RTTStats
+	maxAckDelay      time.Duration
+	ackDelayExponent uint8
+	rttStats         *utils.RTTStats
 
 	hasNewAck bool // true as soon as we received an ack-eliciting new packet
 	ackQueued bool // true once we received more than 2 (or later in the connection 10) ack-eliciting packets
@@ -29,22 +31,33 @@ type receivedPacketTracker struct {
 	ackAlarm                                time.Time
 	lastAck                                 *wire.AckFrame
 
+	// parameters that can be changed by the peer using ACK_FREQUENCY frames
+	packetsBeforeAck int
+	// ignoreReordering is set when the peer asked us not to send ACKs immediately when packets are reordered
+	ignoreReordering bool
+	// the next sequence number expected for an ACK_FREQUENCY frame
+	nextAckFrequencySeqNum uint64
+
 	logger utils.Logger
 
 	version protocol.VersionNumber
 }
 
 func newReceivedPacketTracker(
+	ackDelayExponent uint8,
+	maxAckDelay time.Duration,
 	rttStats *utils.RTTStats,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) *receivedPacketTracker {
 	return &receivedPacketTracker{
-		packetHistory: newReceivedPacketHistory(),
-		maxAckDelay:   protocol.MaxAckDelay,
-		rttStats:      rttStats,
-		logger:        logger,
-		version:       version,
+		packetHistory:    newReceivedPacketHistory(),
+		maxAckDelay:      maxAckDelay,
+		packetsBeforeAck: defaultPacketsBeforeAck,
+		ackDelayExponent: ackDelayExponent,
+		rttStats:         rttStats,
+		logger:           logger,
+		version:          version,
 	}
 }
 
@@ -125,17 +138,17 @@ func (h *receivedPacketTracker) maybeQueueAck(pn protocol.PacketNumber, rcvTime
 	// Send an ACK if this packet was reported missing in an ACK sent before.
 	// Ack decimation with reordering relies on the timer to send an ACK, but if
 	// missing packets we reported in the previous ack, send an ACK immediately.
-	if wasMissing {
+	if wasMissing && !h.ignoreReordering {
 		if h.logger.Debug() {
 			h.logger.Debugf("\tQueueing ACK because packet %d was missing before.", pn)
 		}
 		h.ackQueued = true
 	}
 
-	// send an ACK every 2 ack-eliciting packets
-	if h.ackElicitingPacketsReceivedSinceLastAck >= packetsBeforeAck {
+	// send an ACK every 2 ack-eliciting packets, unless the peer requested a different threshold
+	if h.ackElicitingPacketsReceivedSinceLastAck >= h.packetsBeforeAck {
 		if h.logger.Debug() {
-			h.logger.Debugf("\tQueueing ACK because packet %d packets were received after the last ACK (using initial threshold: %d).", h.ackElicitingPacketsReceivedSinceLastAck, packetsBeforeAck)
+			h.logger.Debugf("\tQueueing ACK because packet %d packets were received after the last ACK (using threshold: %d).", h.ackElicitingPacketsReceivedSinceLastAck, h.packetsBeforeAck)
 		}
 		h.ackQueued = true
 	} else if h.ackAlarm.IsZero() {
@@ -146,7 +159,7 @@ func (h *receivedPacketTracker) maybeQueueAck(pn protocol.PacketNumber, rcvTime
 	}
 
 	// Queue an ACK if there are new missing packets to report.
-	if h.hasNewMissingPackets() {
+	if !h.ignoreReordering && h.hasNewMissingPackets() {
 		h.logger.Debugf("\tQueuing ACK because there's a new missing packet to report.")
 		h.ackQueued = true
 	}
@@ -157,6 +170,32 @@ func (h *receivedPacketTracker) maybeQueueAck(pn protocol.PacketNumber, rcvTime
 	}
 }
 
+// HandleAckFrequencyFrame applies the parameters requested by the peer in an ACK_FREQUENCY frame.
+// Frames with a sequence number smaller than a previously received frame are ignored.
+// A reordering threshold of 0 disables sending ACKs immediately when packets are received out of order.
+// For all other values, reordering is reported immediately, as described in RFC 9000.
+func (h *receivedPacketTracker) HandleAckFrequencyFrame(f *wire.AckFrequencyFrame) {
+	if f.SequenceNumber < h.nextAckFrequencySeqNum {
+		return
+	}
+	h.nextAckFrequencySeqNum = f.SequenceNumber + 1
+	h.packetsBeforeAck = int(utils.Min(f.AckElicitingThreshold, 1<<16)) + 1
+	h.maxAckDelay = f.RequestMaxAckDelay
+	h.ignoreReordering = f.ReorderingThreshold == 0
+	if h.logger.Debug() {
+		h.logger.Debugf("\tPeer requested an ACK after %d ack-eliciting packets, with a max_ack_delay of %s", h.packetsBeforeAck, h.maxAckDelay)
+	}
+}
+
+// QueueAck queues an ACK, without waiting for the ACK alarm.
+func (h *receivedPacketTracker) QueueAck() {
+	if !h.ackQueued {
+		h.logger.Debugf("\tQueueing ACK because an immediate acknowledgement was requested.")
+	}
+	h.ackQueued = true
+	h.ackAlarm = time.Time{}
+}
+
 func (h *receivedPacketTracker) GetAckFrame(onlyIfQueued bool) *wire.AckFrame {
 	if !h.hasNewAck {
 		return nil
@@ -173,6 +212,7 @@ func (h *receivedPacketTracker) GetAckFrame(onlyIfQueued bool) *wire.AckFrame {
 
 	ack := wire.GetAckFrame()
 	ack.DelayTime = utils.Max(0, now.Sub(h.largestObservedReceivedTime))
+	ack.AckDelayExponent = h.ackDelayExponent
 	ack.ECT0 = h.ect0
 	ack.ECT1 = h.ect1
 	ack.ECNCE = h.ecnce
@@ -19,7 +19,7 @@ var _ = Describe("Received Packet Tracker", func() {
 
 	BeforeEach(func() {
 		rttStats = &utils.RTTStats{}
-		tracker = newReceivedPacketTracker(rttStats, utils.DefaultLogger, protocol.VersionWhatever)
+		tracker = newReceivedPacketTracker(protocol.AckDelayExponent, protocol.MaxAckDelay, rttStats, utils.DefaultLogger, protocol.VersionWhatever)
 	})
 
 	Context("accepting packets", func() {
@@ -126,6 +126,70 @@ var _ = Describe("Received Packet Tracker", func() {
 				Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(protocol.MaxAckDelay)))
 			})
 
+			It("uses the configured max_ack_delay for the timer", func() {
+				tracker = newReceivedPacketTracker(protocol.AckDelayExponent, 5*time.Millisecond, rttStats, utils.DefaultLogger, protocol.VersionWhatever)
+				receiveAndAck10Packets()
+				rcvTime := time.Now()
+				tracker.ReceivedPacket(11, protocol.ECNNon, rcvTime, true)
+				Expect(tracker.ackQueued).To(BeFalse())
+				Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(5 * time.Millisecond)))
+			})
+
+			It("queues an ACK when an immediate acknowledgement is requested", func() {
+				receiveAndAck10Packets()
+				tracker.QueueAck()
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
+				Expect(tracker.ackQueued).To(BeTrue())
+				Expect(tracker.GetAlarmTimeout()).To(BeZero())
+				ack := tracker.GetAckFrame(true)
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(11)))
+			})
+
+			It("cancels the ACK timer when an immediate acknowledgement is requested", func() {
+				receiveAndAck10Packets()
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
+				Expect(tracker.GetAlarmTimeout()).ToNot(BeZero())
+				tracker.QueueAck()
+				Expect(tracker.GetAlarmTimeout()).To(BeZero())
+				Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
+			})
+
+			Context("handling ACK_FREQUENCY frames", func() {
+				It("uses the requested ack-eliciting threshold and max_ack_delay", func() {
+					receiveAndAck10Packets()
+					tracker.HandleAckFrequencyFrame(&wire.AckFrequencyFrame{
+						SequenceNumber:        0,
+						AckElicitingThreshold: 3,
+						RequestMaxAckDelay:    50 * time.Millisecond,
+						ReorderingThreshold:   1,
+					})
+					rcvTime := time.Now()
+					for i := 11; i < 14; i++ {
+						tracker.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, rcvTime, true)
+						Expect(tracker.ackQueued).To(BeFalse())
+						Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(50 * time.Millisecond)))
+					}
+					tracker.ReceivedPacket(14, protocol.ECNNon, rcvTime, true)
+					Expect(tracker.ackQueued).To(BeTrue())
+				})
+
+				It("ignores reordered ACK_FREQUENCY frames", func() {
+					tracker.HandleAckFrequencyFrame(&wire.AckFrequencyFrame{SequenceNumber: 2, AckElicitingThreshold: 5, RequestMaxAckDelay: time.Millisecond})
+					tracker.HandleAckFrequencyFrame(&wire.AckFrequencyFrame{SequenceNumber: 1, AckElicitingThreshold: 9, RequestMaxAckDelay: time.Second})
+					Expect(tracker.packetsBeforeAck).To(Equal(6))
+					Expect(tracker.maxAckDelay).To(Equal(time.Millisecond))
+				})
+
+				It("doesn't queue an ACK for reordered packets, if the reordering threshold is 0", func() {
+					receiveAndAck10Packets()
+					tracker.HandleAckFrequencyFrame(&wire.AckFrequencyFrame{AckElicitingThreshold: 10, RequestMaxAckDelay: time.Second})
+					tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
+					tracker.ReceivedPacket(13, protocol.ECNNon, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeFalse())
+				})
+			})
+
 			It("queues an ACK if it was reported missing before", func() {
 				receiveAndAck10Packets()
 				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
@@ -3,6 +3,7 @@ package ackhandler
 import (
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/fkwhite/quic-go/internal/congestion"
@@ -34,6 +35,9 @@ type packetNumberSpace struct {
 
 	largestAcked protocol.PacketNumber
 	largestSent  protocol.PacketNumber
+
+	// the largest ECN-CE count reported by the peer in an ACK frame
+	ecnCE uint64
 }
 
 func newPacketNumberSpace(initialPN protocol.PacketNumber, skipPNs bool, rttStats *utils.RTTStats) *packetNumberSpace {
@@ -77,9 +81,15 @@ type sentPacketHandler struct {
 
 	bytesInFlight protocol.ByteCount
 
+	// the number of ACK frames that reported an increase of the ECN-CE count, accessed atomically
+	numECNCongestionSignals uint64
+
 	congestion congestion.SendAlgorithmWithDebugInfos
 	rttStats   *utils.RTTStats
 
+	// packetFeedbackCallback is called for acknowledged and lost 1-RTT packets (excluding Path MTU probe packets).
+	packetFeedbackCallback func(size protocol.ByteCount, lost bool)
+
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
 	ptoMode  SendMode
@@ -106,6 +116,9 @@ var (
 func newSentPacketHandler(
 	initialPN protocol.PacketNumber,
 	initialMaxDatagramSize protocol.ByteCount,
+	initialCongestionWindow uint32,
+	minCongestionWindow uint32,
+	disablePacing bool,
 	rttStats *utils.RTTStats,
 	clientAddressValidated bool,
 	pers protocol.Perspective,
@@ -116,7 +129,10 @@ func newSentPacketHandler(
 		congestion.DefaultClock{},
 		rttStats,
 		initialMaxDatagramSize,
+		initialCongestionWindow,
+		minCongestionWindow,
 		true, // use Reno
+		disablePacing,
 		tracer,
 	)
 
@@ -208,6 +224,27 @@ func (h *sentPacketHandler) ReceivedBytes(n protocol.ByteCount) {
 	}
 }
 
+func (h *sentPacketHandler) MigratedPath(bytesReceived protocol.ByteCount, resetCongestionState bool) {
+	h.peerAddressValidated = false
+	h.bytesReceived = bytesReceived
+	h.bytesSent = 0
+	if resetCongestionState {
+		h.rttStats.OnConnectionMigration()
+		h.congestion.OnConnectionMigration()
+		if h.tracer != nil {
+			h.tracer.UpdatedMetrics(h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight, h.packetsInFlight())
+		}
+	}
+}
+
+func (h *sentPacketHandler) PathValidated() {
+	if h.peerAddressValidated {
+		return
+	}
+	h.peerAddressValidated = true
+	h.setLossDetectionTimer()
+}
+
 func (h *sentPacketHandler) ReceivedPacket(l protocol.EncryptionLevel) {
 	if h.perspective == protocol.PerspectiveServer && l == protocol.EncryptionHandshake && !h.peerAddressValidated {
 		h.peerAddressValidated = true
@@ -291,10 +328,10 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 
 	largestAcked := ack.LargestAcked()
 	if largestAcked > pnSpace.largestSent {
-		return false, &qerr.TransportError{
-			ErrorCode:    qerr.ProtocolViolation,
-			ErrorMessage: "received ACK for an unsent packet",
+		if h.tracer != nil {
+			h.tracer.ReceivedAckForUnsentPacket(encLevel, largestAcked)
 		}
+		return false, qerr.NewOptimisticAckError(encLevel, largestAcked, false)
 	}
 
 	pnSpace.largestAcked = utils.Max(pnSpace.largestAcked, largestAcked)
@@ -331,6 +368,13 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	if err := h.detectLostPackets(rcvTime, encLevel); err != nil {
 		return false, err
 	}
+	// An increase of the ECN-CE count means that a router on the path experienced congestion.
+	// The congestion controller reacts to this like to a packet loss (see section 7.1 of RFC 9002).
+	if ack.ECNCE > pnSpace.ecnCE {
+		pnSpace.ecnCE = ack.ECNCE
+		h.congestion.OnECNCongestionEvent(ackedPackets[len(ackedPackets)-1].PacketNumber)
+		atomic.AddUint64(&h.numECNCongestionSignals, 1)
+	}
 	var acked1RTTPacket bool
 	for _, p := range ackedPackets {
 		if p.includedInBytesInFlight && !p.declaredLost {
@@ -338,6 +382,9 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 		}
 		if p.EncryptionLevel == protocol.Encryption1RTT {
 			acked1RTTPacket = true
+			if h.packetFeedbackCallback != nil && !p.IsPathMTUProbePacket {
+				h.packetFeedbackCallback(p.Length, false)
+			}
 		}
 		h.removeFromBytesInFlight(p)
 		putPacket(p)
@@ -401,10 +448,10 @@ func (h *sentPacketHandler) detectAndRemoveAckedPackets(ack *wire.AckFrame, encL
 			}
 		}
 		if p.skippedPacket {
-			return false, &qerr.TransportError{
-				ErrorCode:    qerr.ProtocolViolation,
-				ErrorMessage: fmt.Sprintf("received an ACK for skipped packet number: %d (%s)", p.PacketNumber, encLevel),
+			if h.tracer != nil {
+				h.tracer.ReceivedAckForUnsentPacket(encLevel, p.PacketNumber)
 			}
+			return false, qerr.NewOptimisticAckError(encLevel, p.PacketNumber, true)
 		}
 		h.ackedPackets = append(h.ackedPackets, p)
 		return true, nil
@@ -432,6 +479,9 @@ func (h *sentPacketHandler) detectAndRemoveAckedPackets(ack *wire.AckFrame, encL
 		}
 		if h.tracer != nil {
 			h.tracer.AcknowledgedPacket(encLevel, p.PacketNumber)
+			if p.declaredLost {
+				h.tracer.DetectedSpuriousLoss(encLevel, p.PacketNumber)
+			}
 		}
 	}
 
@@ -505,7 +555,7 @@ func (h *sentPacketHandler) hasOutstandingCryptoPackets() bool {
 	return false
 }
 
-func (h *sentPacketHandler) hasOutstandingPackets() bool {
+func (h *sentPacketHandler) HasOutstandingPackets() bool {
 	return h.appDataPackets.history.HasOutstandingPackets() || h.hasOutstandingCryptoPackets()
 }
 
@@ -534,7 +584,7 @@ func (h *sentPacketHandler) setLossDetectionTimer() {
 	}
 
 	// Cancel the alarm if no packets are outstanding
-	if !h.hasOutstandingPackets() && h.peerCompletedAddressValidation {
+	if !h.HasOutstandingPackets() && h.peerCompletedAddressValidation {
 		h.alarm = time.Time{}
 		if !oldAlarm.IsZero() {
 			h.logger.Debugf("Canceling loss detection timer. No packets in flight.")
@@ -617,6 +667,9 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 			h.queueFramesForRetransmission(p)
 			if !p.IsPathMTUProbePacket {
 				h.congestion.OnPacketLost(p.PacketNumber, p.Length, priorInFlight)
+				if h.packetFeedbackCallback != nil && p.EncryptionLevel == protocol.Encryption1RTT {
+					h.packetFeedbackCallback(p.Length, true)
+				}
 			}
 		}
 		return true, nil
@@ -691,6 +744,10 @@ func (h *sentPacketHandler) GetLossDetectionTimeout() time.Time {
 	return h.alarm
 }
 
+func (h *sentPacketHandler) PTOCount() uint32 {
+	return h.ptoCount
+}
+
 func (h *sentPacketHandler) PeekPacketNumber(encLevel protocol.EncryptionLevel) (protocol.PacketNumber, protocol.PacketNumberLen) {
 	pnSpace := h.getPacketNumberSpace(encLevel)
 
@@ -763,6 +820,18 @@ func (h *sentPacketHandler) SetMaxDatagramSize(s protocol.ByteCount) {
 	h.congestion.SetMaxDatagramSize(s)
 }
 
+func (h *sentPacketHandler) ECNCongestionSignals() uint64 {
+	return atomic.LoadUint64(&h.numECNCongestionSignals)
+}
+
+func (h *sentPacketHandler) SetMaxSendRate(bytesPerSecond uint64) {
+	h.congestion.SetMaxSendRate(bytesPerSecond)
+}
+
+func (h *sentPacketHandler) SetPacketFeedbackCallback(cb func(size protocol.ByteCount, lost bool)) {
+	h.packetFeedbackCallback = cb
+}
+
 func (h *sentPacketHandler) isAmplificationLimited() bool {
 	if h.peerAddressValidated {
 		return false
@@ -781,6 +850,12 @@ func (h *sentPacketHandler) QueueProbePacket(encLevel protocol.EncryptionLevel)
 	// Keep track of acknowledged frames instead.
 	h.removeFromBytesInFlight(p)
 	pnSpace.history.DeclareLost(p)
+	if h.logger.Debug() {
+		h.logger.Debugf("\tlost packet %d (PTO)", p.PacketNumber)
+	}
+	if h.tracer != nil {
+		h.tracer.LostPacket(p.EncryptionLevel, p.PacketNumber, logging.PacketLossPTO)
+	}
 	return true
 }
 
@@ -7,10 +7,12 @@ import (
 	"github.com/golang/mock/gomock"
 
 	"github.com/fkwhite/quic-go/internal/mocks"
+	mocklogging "github.com/fkwhite/quic-go/internal/mocks/logging"
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/qerr"
 	"github.com/fkwhite/quic-go/internal/utils"
 	"github.com/fkwhite/quic-go/internal/wire"
+	"github.com/fkwhite/quic-go/logging"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -29,7 +31,7 @@ var _ = Describe("SentPacketHandler", func() {
 	JustBeforeEach(func() {
 		lostPackets = nil
 		rttStats := utils.NewRTTStats()
-		handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, false, perspective, nil, utils.DefaultLogger)
+		handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, protocol.DefaultInitialCongestionWindowPackets, protocol.MinCongestionWindowPackets, false, rttStats, false, perspective, nil, utils.DefaultLogger)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -199,21 +201,31 @@ var _ = Describe("SentPacketHandler", func() {
 			It("rejects ACKs that acknowledge a skipped packet number", func() {
 				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 100}))
 				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 102}))
+				tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+				handler.tracer = tracer
+				tracer.EXPECT().ReceivedAckForUnsentPacket(protocol.Encryption1RTT, protocol.PacketNumber(101))
+				// packet 100 is processed before the skipped packet number is encountered
+				tracer.EXPECT().AcknowledgedPacket(protocol.Encryption1RTT, protocol.PacketNumber(100))
 				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 100, Largest: 102}}}
 				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
 				Expect(err).To(MatchError(&qerr.TransportError{
 					ErrorCode:    qerr.ProtocolViolation,
 					ErrorMessage: "received an ACK for skipped packet number: 101 (1-RTT)",
 				}))
+				Expect(err).To(MatchError(qerr.NewOptimisticAckError(protocol.Encryption1RTT, 101, true)))
 			})
 
 			It("rejects ACKs with a too high LargestAcked packet number", func() {
+				tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+				handler.tracer = tracer
+				tracer.EXPECT().ReceivedAckForUnsentPacket(protocol.Encryption1RTT, protocol.PacketNumber(9999))
 				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 0, Largest: 9999}}}
 				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
 				Expect(err).To(MatchError(&qerr.TransportError{
 					ErrorCode:    qerr.ProtocolViolation,
 					ErrorMessage: "received ACK for an unsent packet",
 				}))
+				Expect(err).To(MatchError(qerr.NewOptimisticAckError(protocol.Encryption1RTT, 9999, false)))
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(10)))
 			})
 
@@ -563,6 +575,32 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.bytesInFlight).To(BeZero())
 		})
 
+		It("reports the size of acknowledged and lost packets", func() {
+			type feedback struct {
+				size protocol.ByteCount
+				lost bool
+			}
+			var feedbacks []feedback
+			handler.SetPacketFeedbackCallback(func(size protocol.ByteCount, lost bool) {
+				feedbacks = append(feedbacks, feedback{size: size, lost: lost})
+			})
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+			cong.EXPECT().MaybeExitSlowStart()
+			cong.EXPECT().OnPacketLost(gomock.Any(), gomock.Any(), gomock.Any())
+			cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, Length: 1400, SendTime: time.Now().Add(-time.Hour)}))
+			// Path MTU probe packets are not reported
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, Length: 1450, SendTime: time.Now().Add(-time.Hour), IsPathMTUProbePacket: true}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3, Length: 100}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 3, Largest: 3}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(feedbacks).To(Equal([]feedback{
+				{size: 1400, lost: true},
+				{size: 100, lost: false},
+			}))
+		})
+
 		It("calls OnPacketAcked and OnPacketLost with the right bytes_in_flight value", func() {
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(4)
 			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
@@ -589,6 +627,30 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("notifies the congestion controller when the ECN-CE count increases", func() {
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+			cong.EXPECT().MaybeExitSlowStart().AnyTimes()
+			cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3}))
+			cong.EXPECT().OnECNCongestionEvent(protocol.PacketNumber(1))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}, ECT0: 1, ECNCE: 1}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.ECNCongestionSignals()).To(BeEquivalentTo(1))
+			// the ECN-CE count didn't increase
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}, ECT0: 2, ECNCE: 1}
+			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.ECNCongestionSignals()).To(BeEquivalentTo(1))
+			cong.EXPECT().OnECNCongestionEvent(protocol.PacketNumber(3))
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}, ECT0: 2, ECNCE: 2}
+			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.ECNCongestionSignals()).To(BeEquivalentTo(2))
+		})
+
 		It("passes the bytes in flight to the congestion controller", func() {
 			handler.ReceivedPacket(protocol.EncryptionHandshake)
 			cong.EXPECT().OnPacketSent(gomock.Any(), protocol.ByteCount(42), gomock.Any(), protocol.ByteCount(42), true)
@@ -669,6 +731,16 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(lostPackets).To(Equal([]protocol.PacketNumber{10}))
 		})
 
+		It("traces packets declared lost when queueing a probe packet", func() {
+			tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+			handler.tracer = tracer
+			tracer.EXPECT().UpdatedMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().SetLossTimer(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 10}))
+			tracer.EXPECT().LostPacket(protocol.Encryption1RTT, protocol.PacketNumber(10), logging.PacketLossPTO)
+			Expect(handler.QueueProbePacket(protocol.Encryption1RTT)).To(BeTrue())
+		})
+
 		It("says when it can't queue a probe packet", func() {
 			queued := handler.QueueProbePacket(protocol.Encryption1RTT)
 			Expect(queued).To(BeFalse())
@@ -947,7 +1019,7 @@ var _ = Describe("SentPacketHandler", func() {
 	Context("amplification limit, for the server, with validated address", func() {
 		JustBeforeEach(func() {
 			rttStats := utils.NewRTTStats()
-			handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, true, perspective, nil, utils.DefaultLogger)
+			handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, protocol.DefaultInitialCongestionWindowPackets, protocol.MinCongestionWindowPackets, false, rttStats, true, perspective, nil, utils.DefaultLogger)
 		})
 
 		It("do not limits the window", func() {
@@ -964,6 +1036,57 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 
+	Context("amplification limit, after the peer migrated", func() {
+		JustBeforeEach(func() {
+			rttStats := utils.NewRTTStats()
+			handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, protocol.DefaultInitialCongestionWindowPackets, protocol.MinCongestionWindowPackets, false, rttStats, true, perspective, nil, utils.DefaultLogger)
+			handler.SetHandshakeConfirmed()
+		})
+
+		It("limits the window to 3x the bytes received on the new path, until the path is validated", func() {
+			handler.ReceivedBytes(1000)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, Length: 5000}))
+			Expect(handler.SendMode()).To(Equal(SendAny))
+			handler.MigratedPath(100, false)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, Length: 299}))
+			Expect(handler.SendMode()).To(Equal(SendAny))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3, Length: 1}))
+			Expect(handler.SendMode()).To(Equal(SendNone))
+			handler.ReceivedBytes(1)
+			Expect(handler.SendMode()).To(Equal(SendAny))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 4, Length: 3}))
+			Expect(handler.SendMode()).To(Equal(SendNone))
+			handler.PathValidated()
+			Expect(handler.SendMode()).To(Equal(SendAny))
+		})
+
+		It("resets the congestion controller and the RTT estimate", func() {
+			handler.rttStats.UpdateRTT(time.Second, 0, time.Now())
+			cong := mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			handler.congestion = cong
+			cong.EXPECT().OnConnectionMigration()
+			handler.MigratedPath(100, true)
+			Expect(handler.rttStats.SmoothedRTT()).To(BeZero())
+			Expect(handler.rttStats.LatestRTT()).To(BeZero())
+		})
+
+		It("doesn't reset the congestion controller if only the port changed", func() {
+			handler.rttStats.UpdateRTT(time.Second, 0, time.Now())
+			handler.congestion = mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			handler.MigratedPath(100, false)
+			Expect(handler.rttStats.SmoothedRTT()).To(Equal(time.Second))
+		})
+
+		It("resets the loss detection timer when the path is validated", func() {
+			handler.MigratedPath(100, false)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, Length: 300}))
+			// Amplification limited. We don't need to set a timer now.
+			Expect(handler.GetLossDetectionTimeout()).To(BeZero())
+			handler.PathValidated()
+			Expect(handler.GetLossDetectionTimeout()).ToNot(BeZero())
+		})
+	})
+
 	Context("amplification limit, for the client", func() {
 		BeforeEach(func() {
 			perspective = protocol.PerspectiveClient
@@ -1077,6 +1200,28 @@ var _ = Describe("SentPacketHandler", func() {
 			expectInPacketHistory([]protocol.PacketNumber{4, 5}, protocol.Encryption1RTT)
 			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
 		})
+
+		It("traces spurious losses", func() {
+			tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+			handler.tracer = tracer
+			tracer.EXPECT().UpdatedMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().SetLossTimer(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			now := time.Now()
+			for i := protocol.PacketNumber(1); i <= 4; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+			}
+			tracer.EXPECT().AcknowledgedPacket(protocol.Encryption1RTT, protocol.PacketNumber(4))
+			tracer.EXPECT().LostPacket(protocol.Encryption1RTT, protocol.PacketNumber(1), logging.PacketLossReorderingThreshold)
+			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 4, Largest: 4}}}, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			// now the peer acknowledges packet 1, which was already declared lost
+			gomock.InOrder(
+				tracer.EXPECT().AcknowledgedPacket(protocol.Encryption1RTT, protocol.PacketNumber(1)),
+				tracer.EXPECT().DetectedSpuriousLoss(protocol.Encryption1RTT, protocol.PacketNumber(1)),
+			)
+			_, err = handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 4, Largest: 4}, {Smallest: 1, Largest: 1}}}, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+		})
 	})
 
 	Context("Delay-based loss detection", func() {
@@ -131,6 +131,10 @@ func (h *sentPacketHistory) DeleteOldPackets(now time.Time) {
 		}
 		delete(h.packetMap, p.PacketNumber)
 		h.etcPacketList.Remove(el)
+		// Skipped packets are not obtained from the pool.
+		if !p.skippedPacket {
+			putPacket(p)
+		}
 	}
 }
 
@@ -245,6 +245,34 @@ var _ = Describe("SentPacketHistory", func() {
 			expectInHistory([]protocol.PacketNumber{})
 		})
 
+		It("returns lost packets to the pool when deleting them", func() {
+			now := time.Now()
+			p := GetPacket()
+			p.PacketNumber = 10
+			p.SendTime = now.Add(-3 * pto)
+			p.declaredLost = true
+			hist.SentAckElicitingPacket(p)
+			hist.DeleteOldPackets(now)
+			expectInHistory([]protocol.PacketNumber{})
+			Expect(p.inPool).To(BeTrue())
+		})
+
+		It("doesn't return skipped packets to the pool", func() {
+			now := time.Now()
+			hist.SentAckElicitingPacket(&Packet{PacketNumber: 2, SendTime: now.Add(-3 * pto), declaredLost: true})
+			var skipped *Packet
+			hist.Iterate(func(p *Packet) (bool, error) {
+				if p.skippedPacket {
+					skipped = p
+				}
+				return skipped == nil, nil
+			})
+			Expect(skipped).ToNot(BeNil())
+			hist.DeleteOldPackets(now)
+			Expect(hist.Len()).To(BeZero())
+			Expect(skipped.inPool).To(BeFalse())
+		})
+
 		It("doesn't delete a packet if it hasn't been declared lost yet", func() {
 			now := time.Now()
 			hist.SentAckElicitingPacket(&Packet{PacketNumber: 10, SendTime: now.Add(-3 * pto), declaredLost: true})
@@ -1,7 +1,6 @@
 package congestion
 
 import (
-	"fmt"
 	"time"
 
 	"github.com/fkwhite/quic-go/internal/protocol"
@@ -12,11 +11,9 @@ import (
 const (
 	// maxDatagramSize is the default maximum packet size used in the Linux TCP implementation.
 	// Used in QUIC for congestion window computations in bytes.
-	initialMaxDatagramSize     = protocol.ByteCount(protocol.InitialPacketSizeIPv4)
-	maxBurstPackets            = 3
-	renoBeta                   = 0.7 // Reno backoff factor.
-	minCongestionWindowPackets = 2
-	initialCongestionWindow    = 32
+	initialMaxDatagramSize = protocol.ByteCount(protocol.InitialPacketSizeIPv4)
+	maxBurstPackets        = 3
+	renoBeta               = 0.7 // Reno backoff factor.
 )
 
 type cubicSender struct {
@@ -28,6 +25,9 @@ type cubicSender struct {
 
 	reno bool
 
+	// If pacing is disabled, packets are sent as fast as the congestion window allows.
+	pacingDisabled bool
+
 	// Track the largest packet that has been sent.
 	largestSentPacketNumber protocol.PacketNumber
 
@@ -52,6 +52,7 @@ type cubicSender struct {
 
 	initialCongestionWindow    protocol.ByteCount
 	initialMaxCongestionWindow protocol.ByteCount
+	minCongestionWindowPackets protocol.ByteCount
 
 	maxDatagramSize protocol.ByteCount
 
@@ -64,23 +65,30 @@ var (
 	_ SendAlgorithmWithDebugInfos = &cubicSender{}
 )
 
-// NewCubicSender makes a new cubic sender
+// NewCubicSender makes a new cubic sender.
+// The initial and the minimum congestion window are given in packets.
 func NewCubicSender(
 	clock Clock,
 	rttStats *utils.RTTStats,
 	initialMaxDatagramSize protocol.ByteCount,
+	initialCongestionWindowPackets uint32,
+	minCongestionWindowPackets uint32,
 	reno bool,
+	disablePacing bool,
 	tracer logging.ConnectionTracer,
 ) *cubicSender {
-	return newCubicSender(
+	c := newCubicSender(
 		clock,
 		rttStats,
 		reno,
 		initialMaxDatagramSize,
-		initialCongestionWindow*initialMaxDatagramSize,
+		protocol.ByteCount(initialCongestionWindowPackets)*initialMaxDatagramSize,
 		protocol.MaxCongestionWindowPackets*initialMaxDatagramSize,
+		protocol.ByteCount(minCongestionWindowPackets),
 		tracer,
 	)
+	c.pacingDisabled = disablePacing
+	return c
 }
 
 func newCubicSender(
@@ -89,7 +97,8 @@ func newCubicSender(
 	reno bool,
 	initialMaxDatagramSize,
 	initialCongestionWindow,
-	initialMaxCongestionWindow protocol.ByteCount,
+	initialMaxCongestionWindow,
+	minCongestionWindowPackets protocol.ByteCount,
 	tracer logging.ConnectionTracer,
 ) *cubicSender {
 	c := &cubicSender{
@@ -99,6 +108,7 @@ func newCubicSender(
 		largestSentAtLastCutback:   protocol.InvalidPacketNumber,
 		initialCongestionWindow:    initialCongestionWindow,
 		initialMaxCongestionWindow: initialMaxCongestionWindow,
+		minCongestionWindowPackets: minCongestionWindowPackets,
 		congestionWindow:           initialCongestionWindow,
 		slowStartThreshold:         protocol.MaxByteCount,
 		cubic:                      NewCubic(clock),
@@ -117,10 +127,16 @@ func newCubicSender(
 
 // TimeUntilSend returns when the next packet should be sent.
 func (c *cubicSender) TimeUntilSend(_ protocol.ByteCount) time.Time {
+	if c.pacingDisabled {
+		return time.Time{}
+	}
 	return c.pacer.TimeUntilSend()
 }
 
 func (c *cubicSender) HasPacingBudget() bool {
+	if c.pacingDisabled {
+		return true
+	}
 	return c.pacer.Budget(c.clock.Now()) >= c.maxDatagramSize
 }
 
@@ -129,7 +145,7 @@ func (c *cubicSender) maxCongestionWindow() protocol.ByteCount {
 }
 
 func (c *cubicSender) minCongestionWindow() protocol.ByteCount {
-	return c.maxDatagramSize * minCongestionWindowPackets
+	return c.maxDatagramSize * c.minCongestionWindowPackets
 }
 
 func (c *cubicSender) OnPacketSent(
@@ -189,6 +205,16 @@ func (c *cubicSender) OnPacketAcked(
 }
 
 func (c *cubicSender) OnPacketLost(packetNumber protocol.PacketNumber, lostBytes, priorInFlight protocol.ByteCount) {
+	c.onCongestionEvent(packetNumber)
+}
+
+// OnECNCongestionEvent is called when the peer reported an increase of the ECN-CE count.
+// This is treated the same way as a packet loss (see section 7.1 of RFC 9002).
+func (c *cubicSender) OnECNCongestionEvent(packetNumber protocol.PacketNumber) {
+	c.onCongestionEvent(packetNumber)
+}
+
+func (c *cubicSender) onCongestionEvent(packetNumber protocol.PacketNumber) {
 	// TCP NewReno (RFC6582) says that once a loss occurs, any losses in packets
 	// already sent should be treated as a single loss event, since it's expected.
 	if packetNumber <= c.largestSentAtLastCutback {
@@ -282,7 +308,7 @@ func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 	c.congestionWindow = c.minCongestionWindow()
 }
 
-// OnConnectionMigration is called when the connection is migrated (?)
+// OnConnectionMigration is called when the peer migrated to a new address.
 func (c *cubicSender) OnConnectionMigration() {
 	c.hybridSlowStart.Restart()
 	c.largestSentPacketNumber = protocol.InvalidPacketNumber
@@ -303,14 +329,17 @@ func (c *cubicSender) maybeTraceStateChange(new logging.CongestionState) {
 	c.lastState = new
 }
 
+func (c *cubicSender) SetMaxSendRate(bytesPerSecond uint64) {
+	c.pacer.SetMaxSendRate(bytesPerSecond)
+}
+
 func (c *cubicSender) SetMaxDatagramSize(s protocol.ByteCount) {
-	if s < c.maxDatagramSize {
-		panic(fmt.Sprintf("congestion BUG: decreased max datagram size from %d to %d", c.maxDatagramSize, s))
-	}
 	cwndIsMinCwnd := c.congestionWindow == c.minCongestionWindow()
 	c.maxDatagramSize = s
 	if cwndIsMinCwnd {
 		c.congestionWindow = c.minCongestionWindow()
 	}
+	// The maximum datagram size decreases when Path MTU Discovery detects a black hole.
+	c.congestionWindow = utils.Min(c.congestionWindow, c.maxCongestionWindow())
 	c.pacer.SetMaxDatagramSize(s)
 }
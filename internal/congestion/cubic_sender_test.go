@@ -49,6 +49,7 @@ var _ = Describe("Cubic Sender", func() {
 			protocol.InitialPacketSizeIPv4,
 			initialCongestionWindowPackets*maxDatagramSize,
 			MaxCongestionWindow,
+			protocol.MinCongestionWindowPackets,
 			nil,
 		)
 	})
@@ -315,10 +316,29 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
 	})
 
+	It("uses the configured initial congestion window", func() {
+		sender = NewCubicSender(&clock, rttStats, protocol.InitialPacketSizeIPv4, 20, protocol.MinCongestionWindowPackets, true, false, nil)
+		Expect(sender.GetCongestionWindow()).To(Equal(20 * initialMaxDatagramSize))
+	})
+
+	It("ignores the pacing budget if pacing is disabled", func() {
+		sender = NewCubicSender(&clock, rttStats, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets, protocol.MinCongestionWindowPackets, true, true, nil)
+		rttStats.UpdateRTT(time.Second, 0, clock.Now())
+		Expect(SendAvailableSendWindow()).To(Equal(initialCongestionWindowPackets))
+		Expect(sender.HasPacingBudget()).To(BeTrue())
+		Expect(sender.TimeUntilSend(bytesInFlight)).To(BeZero())
+	})
+
+	It("uses the configured minimum congestion window", func() {
+		sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, 4, nil)
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(4 * maxDatagramSize))
+	})
+
 	It("tcp cubic reset epoch on quiescence", func() {
 		const maxCongestionWindow = 50
 		const maxCongestionWindowBytes = maxCongestionWindow * maxDatagramSize
-		sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindowBytes, nil)
+		sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindowBytes, protocol.MinCongestionWindowPackets, nil)
 
 		numSent := SendAvailableSendWindow()
 
@@ -371,6 +391,25 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(postLossWindow).To(BeNumerically(">", sender.GetCongestionWindow()))
 	})
 
+	It("reduces the congestion window on ECN congestion events", func() {
+		SendAvailableSendWindow()
+		AckNPackets(2)
+		initialWindow := sender.GetCongestionWindow()
+		sender.OnECNCongestionEvent(ackedPacketNumber)
+		postCongestionWindow := sender.GetCongestionWindow()
+		Expect(postCongestionWindow).To(BeNumerically("<", initialWindow))
+		Expect(sender.InRecovery()).To(BeTrue())
+		// only one reduction per recovery period
+		sender.OnECNCongestionEvent(ackedPacketNumber + 1)
+		Expect(sender.GetCongestionWindow()).To(Equal(postCongestionWindow))
+		LosePacket(ackedPacketNumber + 2)
+		Expect(sender.GetCongestionWindow()).To(Equal(postCongestionWindow))
+		// a congestion event for a packet sent after the reduction reduces the window again
+		sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, maxDatagramSize, true)
+		sender.OnECNCongestionEvent(packetNumber)
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("<", postCongestionWindow))
+	})
+
 	It("1 connection congestion avoidance at end of recovery", func() {
 		// Ack 10 packets in 5 acks to raise the CWND to 20.
 		const numberOfAcks = 5
@@ -459,7 +498,7 @@ var _ = Describe("Cubic Sender", func() {
 
 	It("slow starts up to the maximum congestion window", func() {
 		const initialMaxCongestionWindow = protocol.MaxCongestionWindowPackets * initialMaxDatagramSize
-		sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, initialMaxCongestionWindow, nil)
+		sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, initialMaxCongestionWindow, protocol.MinCongestionWindowPackets, nil)
 
 		for i := 1; i < protocol.MaxCongestionWindowPackets; i++ {
 			sender.MaybeExitSlowStart()
@@ -468,13 +507,25 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(sender.GetCongestionWindow()).To(Equal(initialMaxCongestionWindow))
 	})
 
-	It("doesn't allow reductions of the maximum packet size", func() {
-		Expect(func() { sender.SetMaxDatagramSize(initialMaxDatagramSize - 1) }).To(Panic())
+	It("allows reductions of the maximum packet size", func() {
+		sender.SetMaxDatagramSize(initialMaxDatagramSize + 100)
+		Expect(sender.GetCongestionWindow()).To(Equal(initialCongestionWindowPackets * maxDatagramSize))
+		sender.SetMaxDatagramSize(initialMaxDatagramSize - 100)
+		Expect(sender.GetCongestionWindow()).To(Equal(initialCongestionWindowPackets * maxDatagramSize))
+	})
+
+	It("reduces the congestion window to the minimum when the maximum packet size is reduced", func() {
+		sender.SetMaxDatagramSize(initialMaxDatagramSize + 100)
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(sender.minCongestionWindow()))
+		sender.SetMaxDatagramSize(initialMaxDatagramSize - 100)
+		Expect(sender.GetCongestionWindow()).To(Equal(sender.minCongestionWindow()))
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.MinCongestionWindowPackets * (initialMaxDatagramSize - 100)))
 	})
 
 	It("slow starts up to maximum congestion window, if larger packets are sent", func() {
 		const initialMaxCongestionWindow = protocol.MaxCongestionWindowPackets * initialMaxDatagramSize
-		sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, initialMaxCongestionWindow, nil)
+		sender = newCubicSender(&clock, rttStats, true, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, initialMaxCongestionWindow, protocol.MinCongestionWindowPackets, nil)
 		const packetSize = initialMaxDatagramSize + 100
 		sender.SetMaxDatagramSize(packetSize)
 		for i := 1; i < protocol.MaxCongestionWindowPackets; i++ {
@@ -489,7 +540,7 @@ var _ = Describe("Cubic Sender", func() {
 
 	It("limit cwnd increase in congestion avoidance", func() {
 		// Enable Cubic.
-		sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, nil)
+		sender = newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, protocol.MinCongestionWindowPackets, nil)
 		numSent := SendAvailableSendWindow()
 
 		// Make sure we fall out of slow start.
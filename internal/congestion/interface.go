@@ -15,8 +15,16 @@ type SendAlgorithm interface {
 	MaybeExitSlowStart()
 	OnPacketAcked(number protocol.PacketNumber, ackedBytes protocol.ByteCount, priorInFlight protocol.ByteCount, eventTime time.Time)
 	OnPacketLost(number protocol.PacketNumber, lostBytes protocol.ByteCount, priorInFlight protocol.ByteCount)
+	// OnECNCongestionEvent is called when an ACK reported an increase of the ECN-CE count.
+	// The packet number is the largest packet number newly acknowledged by that ACK.
+	OnECNCongestionEvent(number protocol.PacketNumber)
 	OnRetransmissionTimeout(packetsRetransmitted bool)
+	// OnConnectionMigration resets the congestion controller to its initial state.
+	OnConnectionMigration()
 	SetMaxDatagramSize(protocol.ByteCount)
+	// SetMaxSendRate limits the pacing rate, in bytes/s. 0 means unlimited.
+	// It may be called concurrently with the other methods.
+	SetMaxSendRate(bytesPerSecond uint64)
 }
 
 // A SendAlgorithmWithDebugInfos is a SendAlgorithm that exposes some debug infos
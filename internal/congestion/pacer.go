@@ -2,6 +2,7 @@ package congestion
 
 import (
 	"math"
+	"sync/atomic"
 	"time"
 
 	"github.com/fkwhite/quic-go/internal/protocol"
@@ -16,6 +17,7 @@ type pacer struct {
 	maxDatagramSize      protocol.ByteCount
 	lastSentTime         time.Time
 	getAdjustedBandwidth func() uint64 // in bytes/s
+	maxSendRate          uint64        // in bytes/s, 0 if the send rate is not limited. Accessed atomically.
 }
 
 func newPacer(getBandwidth func() Bandwidth) *pacer {
@@ -49,13 +51,13 @@ func (p *pacer) Budget(now time.Time) protocol.ByteCount {
 	if p.lastSentTime.IsZero() {
 		return p.maxBurstSize()
 	}
-	budget := p.budgetAtLastSent + (protocol.ByteCount(p.getAdjustedBandwidth())*protocol.ByteCount(now.Sub(p.lastSentTime).Nanoseconds()))/1e9
+	budget := p.budgetAtLastSent + (protocol.ByteCount(p.bandwidth())*protocol.ByteCount(now.Sub(p.lastSentTime).Nanoseconds()))/1e9
 	return utils.Min(p.maxBurstSize(), budget)
 }
 
 func (p *pacer) maxBurstSize() protocol.ByteCount {
 	return utils.Max(
-		protocol.ByteCount(uint64((protocol.MinPacingDelay+protocol.TimerGranularity).Nanoseconds())*p.getAdjustedBandwidth())/1e9,
+		protocol.ByteCount(uint64((protocol.MinPacingDelay+protocol.TimerGranularity).Nanoseconds())*p.bandwidth())/1e9,
 		maxBurstSizePackets*p.maxDatagramSize,
 	)
 }
@@ -68,10 +70,27 @@ func (p *pacer) TimeUntilSend() time.Time {
 	}
 	return p.lastSentTime.Add(utils.Max(
 		protocol.MinPacingDelay,
-		time.Duration(math.Ceil(float64(p.maxDatagramSize-p.budgetAtLastSent)*1e9/float64(p.getAdjustedBandwidth())))*time.Nanosecond,
+		time.Duration(math.Ceil(float64(p.maxDatagramSize-p.budgetAtLastSent)*1e9/float64(p.bandwidth())))*time.Nanosecond,
 	))
 }
 
+// bandwidth is the pacing rate in bytes/s.
+// It is derived from the bandwidth estimate, and capped by the maximum send rate.
+func (p *pacer) bandwidth() uint64 {
+	bw := p.getAdjustedBandwidth()
+	if maxSendRate := atomic.LoadUint64(&p.maxSendRate); maxSendRate > 0 && maxSendRate < bw {
+		return maxSendRate
+	}
+	return bw
+}
+
+// SetMaxSendRate limits the pacing rate to the given rate in bytes/s.
+// A value of 0 removes the limit.
+// It is safe to call it concurrently with the other methods.
+func (p *pacer) SetMaxSendRate(rate uint64) {
+	atomic.StoreUint64(&p.maxSendRate, rate)
+}
+
 func (p *pacer) SetMaxDatagramSize(s protocol.ByteCount) {
 	p.maxDatagramSize = s
 }
@@ -121,6 +121,25 @@ var _ = Describe("Pacer", func() {
 		Expect(p.TimeUntilSend()).To(Equal(t.Add(time.Second / 5)))
 	})
 
+	It("limits the send rate", func() {
+		t := time.Now()
+		sendBurst(t)
+		p.SetMaxSendRate(uint64(5 * initialMaxDatagramSize)) // 5 packets per second
+		Expect(p.TimeUntilSend()).To(Equal(t.Add(time.Second / 5)))
+		// the rate limit doesn't allow bursts larger than the maximum burst size
+		Expect(p.Budget(t.Add(time.Hour))).To(BeEquivalentTo(maxBurstSizePackets * initialMaxDatagramSize))
+		// remove the limit
+		p.SetMaxSendRate(0)
+		Expect(p.TimeUntilSend()).To(BeTemporally("~", t.Add(time.Second/packetsPerSecond), time.Nanosecond))
+	})
+
+	It("doesn't pace faster if the send rate limit is higher than the bandwidth", func() {
+		t := time.Now()
+		sendBurst(t)
+		p.SetMaxSendRate(uint64(100 * packetsPerSecond * initialMaxDatagramSize))
+		Expect(p.TimeUntilSend()).To(BeTemporally("~", t.Add(time.Second/packetsPerSecond), time.Nanosecond))
+	})
+
 	It("doesn't pace faster than the minimum pacing duration", func() {
 		t := time.Now()
 		sendBurst(t)
@@ -24,6 +24,9 @@ type StreamFlowController interface {
 	// Abandon should be called when reading from the stream is aborted early,
 	// and there won't be any further calls to AddBytesRead.
 	Abandon()
+	// SetMaxBufferedBytes sets a soft limit for the amount of data that is received, but not yet read.
+	// While this limit is exceeded, window updates are delayed. A value of 0 means no limit.
+	SetMaxBufferedBytes(protocol.ByteCount)
 }
 
 // The ConnectionFlowController is the flow controller for the connection.
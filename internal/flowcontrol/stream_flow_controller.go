@@ -18,6 +18,10 @@ type streamFlowController struct {
 	connection connectionFlowControllerI
 
 	receivedFinalOffset bool
+
+	// If set, window updates are delayed as long as more than this number of bytes
+	// were received but not yet read by the application.
+	maxBufferedBytes protocol.ByteCount
 }
 
 var _ StreamFlowController = &streamFlowController{}
@@ -126,8 +130,25 @@ func (c *streamFlowController) SendWindowSize() protocol.ByteCount {
 	return utils.Min(c.baseFlowController.sendWindowSize(), c.connection.SendWindowSize())
 }
 
+// SetMaxBufferedBytes sets a soft limit for the number of bytes that are received, but not yet read.
+// As long as this limit is exceeded, no window updates are sent.
+func (c *streamFlowController) SetMaxBufferedBytes(n protocol.ByteCount) {
+	c.mutex.Lock()
+	c.maxBufferedBytes = n
+	shouldQueueWindowUpdate := c.shouldQueueWindowUpdate()
+	c.mutex.Unlock()
+	if shouldQueueWindowUpdate {
+		c.queueWindowUpdate()
+	}
+}
+
+// needs to be called with locked mutex
+func (c *streamFlowController) isBufferLimited() bool {
+	return c.maxBufferedBytes > 0 && c.highestReceived-c.bytesRead > c.maxBufferedBytes
+}
+
 func (c *streamFlowController) shouldQueueWindowUpdate() bool {
-	return !c.receivedFinalOffset && c.hasWindowUpdate()
+	return !c.receivedFinalOffset && !c.isBufferLimited() && c.hasWindowUpdate()
 }
 
 func (c *streamFlowController) GetWindowUpdate() protocol.ByteCount {
@@ -138,6 +159,10 @@ func (c *streamFlowController) GetWindowUpdate() protocol.ByteCount {
 
 	// Don't use defer for unlocking the mutex here, GetWindowUpdate() is called frequently and defer shows up in the profiler
 	c.mutex.Lock()
+	if c.isBufferLimited() {
+		c.mutex.Unlock()
+		return 0
+	}
 	oldWindowSize := c.receiveWindowSize
 	offset := c.baseFlowController.getWindowUpdate()
 	if c.receiveWindowSize > oldWindowSize { // auto-tuning enlarged the window size
@@ -196,6 +196,28 @@ var _ = Describe("Stream Flow controller", func() {
 				Expect(queuedWindowUpdate).To(BeFalse())
 			})
 
+			It("delays window updates while too much data is buffered", func() {
+				Expect(controller.UpdateHighestReceived(90, false)).To(Succeed())
+				controller.SetMaxBufferedBytes(10)
+				Expect(queuedWindowUpdate).To(BeFalse())
+				controller.AddBytesRead(30) // 20 bytes are still buffered
+				Expect(queuedWindowUpdate).To(BeFalse())
+				Expect(controller.GetWindowUpdate()).To(BeZero())
+				controller.AddBytesRead(10) // 10 bytes are still buffered
+				Expect(queuedWindowUpdate).To(BeTrue())
+				Expect(controller.GetWindowUpdate()).ToNot(BeZero())
+			})
+
+			It("queues a window update when the buffer limit is removed", func() {
+				Expect(controller.UpdateHighestReceived(90, false)).To(Succeed())
+				controller.SetMaxBufferedBytes(10)
+				controller.AddBytesRead(30)
+				Expect(queuedWindowUpdate).To(BeFalse())
+				controller.SetMaxBufferedBytes(0)
+				Expect(queuedWindowUpdate).To(BeTrue())
+				Expect(controller.GetWindowUpdate()).ToNot(BeZero())
+			})
+
 			It("tells the connection flow controller when the window was auto-tuned", func() {
 				var allowed protocol.ByteCount
 				controller.connection.(*connectionFlowController).allowWindowIncrease = func(size protocol.ByteCount) bool {
@@ -64,11 +64,16 @@ const clientSessionStateRevision = 3
 type conn struct {
 	localAddr, remoteAddr net.Addr
 	version               protocol.VersionNumber
+	// only set for the server, as soon as the ClientHello is received
+	peerParams *wire.TransportParameters
 }
 
-var _ ConnWithVersion = &conn{}
+var (
+	_ ConnWithVersion             = &conn{}
+	_ ConnWithTransportParameters = &conn{}
+)
 
-func newConn(local, remote net.Addr, version protocol.VersionNumber) ConnWithVersion {
+func newConn(local, remote net.Addr, version protocol.VersionNumber) *conn {
 	return &conn{
 		localAddr:  local,
 		remoteAddr: remote,
@@ -88,10 +93,14 @@ func (c *conn) SetWriteDeadline(time.Time) error       { return nil }
 func (c *conn) SetDeadline(time.Time) error            { return nil }
 func (c *conn) GetQUICVersion() protocol.VersionNumber { return c.version }
 
+func (c *conn) GetTransportParameters() *wire.TransportParameters { return c.peerParams }
+
 type cryptoSetup struct {
 	tlsConf   *tls.Config
 	extraConf *qtls.ExtraConfig
 	conn      *qtls.Conn
+	// the connection passed to qtls, only used by the server
+	clientHelloConn *conn
 
 	version protocol.VersionNumber
 
@@ -112,6 +121,7 @@ type cryptoSetup struct {
 	closeChan chan struct{}
 
 	zeroRTTParameters      *wire.TransportParameters
+	allow0RTT              func() bool // only set for the server
 	clientHelloWritten     bool
 	clientHelloWrittenChan chan struct{} // is closed as soon as the ClientHello is written
 	zeroRTTParametersChan  chan<- *wire.TransportParameters
@@ -196,6 +206,7 @@ func NewCryptoSetupServer(
 	runner handshakeRunner,
 	tlsConf *tls.Config,
 	enable0RTT bool,
+	allow0RTT func() bool,
 	rttStats *utils.RTTStats,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
@@ -215,7 +226,9 @@ func NewCryptoSetupServer(
 		protocol.PerspectiveServer,
 		version,
 	)
-	cs.conn = qtls.Server(newConn(localAddr, remoteAddr, version), cs.tlsConf, cs.extraConf)
+	cs.allow0RTT = allow0RTT
+	cs.clientHelloConn = newConn(localAddr, remoteAddr, version)
+	cs.conn = qtls.Server(cs.clientHelloConn, cs.tlsConf, cs.extraConf)
 	return cs
 }
 
@@ -365,6 +378,11 @@ func (h *cryptoSetup) HandleMessage(data []byte, encLevel protocol.EncryptionLev
 		h.onError(alertUnexpectedMessage, err.Error())
 		return false
 	}
+	if msgType == typeClientHello && h.clientHelloConn != nil {
+		// Make the client's transport parameters available to the GetConfigForClient and GetCertificate callbacks.
+		// They are validated (and the handshake is aborted if they are invalid) once qtls processes the ClientHello.
+		h.clientHelloConn.peerParams = h.parseClientHelloTransportParameters(data)
+	}
 	h.messageChan <- data
 	if encLevel == protocol.Encryption1RTT {
 		h.handlePostHandshakeMessage()
@@ -418,6 +436,18 @@ func (h *cryptoSetup) checkEncryptionLevel(msgType messageType, encLevel protoco
 	return nil
 }
 
+func (h *cryptoSetup) parseClientHelloTransportParameters(clientHello []byte) *wire.TransportParameters {
+	data, ok := getClientHelloExtension(clientHello, transportParametersExtensionType(h.version))
+	if !ok {
+		return nil
+	}
+	var tp wire.TransportParameters
+	if err := tp.Unmarshal(data, protocol.PerspectiveClient); err != nil {
+		return nil
+	}
+	return &tp
+}
+
 func (h *cryptoSetup) handleTransportParameters(data []byte) {
 	var tp wire.TransportParameters
 	if err := tp.Unmarshal(data, h.perspective.Opposite()); err != nil {
@@ -489,14 +519,17 @@ func (h *cryptoSetup) accept0RTT(sessionTicketData []byte) bool {
 		h.logger.Debugf("Unmarshalling transport parameters from session ticket failed: %s", err.Error())
 		return false
 	}
-	valid := h.ourParams.ValidFor0RTT(t.Parameters)
-	if valid {
-		h.logger.Debugf("Accepting 0-RTT. Restoring RTT from session ticket: %s", t.RTT)
-		h.rttStats.SetInitialRTT(t.RTT)
-	} else {
+	if !h.ourParams.ValidFor0RTT(t.Parameters) {
 		h.logger.Debugf("Transport parameters changed. Rejecting 0-RTT.")
+		return false
+	}
+	if h.allow0RTT != nil && !h.allow0RTT() {
+		h.logger.Debugf("0-RTT not allowed. Rejecting 0-RTT.")
+		return false
 	}
-	return valid
+	h.logger.Debugf("Accepting 0-RTT. Restoring RTT from session ticket: %s", t.RTT)
+	h.rttStats.SetInitialRTT(t.RTT)
+	return true
 }
 
 // rejected0RTT is called for the client when the server rejects 0-RTT.
@@ -13,6 +13,7 @@ import (
 	mocktls "github.com/fkwhite/quic-go/internal/mocks/tls"
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/qerr"
+	"github.com/fkwhite/quic-go/internal/qtls"
 	"github.com/fkwhite/quic-go/internal/testdata"
 	"github.com/fkwhite/quic-go/internal/utils"
 	"github.com/fkwhite/quic-go/internal/wire"
@@ -96,6 +97,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			testdata.GetTLSConfig(),
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -178,6 +180,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			testdata.GetTLSConfig(),
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -219,6 +222,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			serverConf,
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -254,6 +258,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			NewMockHandshakeRunner(mockCtrl),
 			serverConf,
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -271,6 +276,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 	})
 
 	Context("doing the handshake", func() {
+		var serverAllow0RTT func() bool
+
+		BeforeEach(func() {
+			serverAllow0RTT = nil
+		})
+
 		generateCert := func() tls.Certificate {
 			priv, err := rsa.GenerateKey(rand.Reader, 2048)
 			Expect(err).ToNot(HaveOccurred())
@@ -399,6 +410,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sRunner,
 				serverConf,
 				enable0RTT,
+				serverAllow0RTT,
 				serverRTTStats,
 				nil,
 				utils.DefaultLogger.WithPrefix("server"),
@@ -537,6 +549,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sRunner,
 				serverConf,
 				false,
+				nil,
 				&utils.RTTStats{},
 				nil,
 				utils.DefaultLogger.WithPrefix("server"),
@@ -592,6 +605,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					sRunner,
 					serverConf,
 					false,
+					nil,
 					&utils.RTTStats{},
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
@@ -651,6 +665,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					sRunner,
 					serverConf,
 					false,
+					nil,
 					&utils.RTTStats{},
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
@@ -809,6 +824,53 @@ var _ = Describe("Crypto Setup TLS", func() {
 				Expect(client.ConnectionState().Used0RTT).To(BeTrue())
 			})
 
+			It("uses 0-RTT with a session state that was serialized", func() {
+				csc := mocktls.NewMockClientSessionCache(mockCtrl)
+				var data []byte
+				receivedSessionTicket := make(chan struct{})
+				csc.EXPECT().Get(gomock.Any())
+				csc.EXPECT().Put(gomock.Any(), gomock.Any()).Do(func(_ string, css *tls.ClientSessionState) {
+					var err error
+					data, err = qtls.MarshalClientSessionState(css)
+					Expect(err).ToNot(HaveOccurred())
+					close(receivedSessionTicket)
+				})
+				clientConf.ClientSessionCache = csc
+				const clientRTT = 30 * time.Millisecond
+				const initialMaxData protocol.ByteCount = 1337
+				_, _, clientErr, _, serverErr := handshakeWithTLSConf(
+					clientConf, serverConf,
+					newRTTStatsWithRTT(clientRTT), &utils.RTTStats{},
+					&wire.TransportParameters{}, &wire.TransportParameters{InitialMaxData: initialMaxData},
+					true,
+				)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				Eventually(receivedSessionTicket).Should(BeClosed())
+
+				// restore the session state, as if the client had been restarted
+				state, err := qtls.UnmarshalClientSessionState(data)
+				Expect(err).ToNot(HaveOccurred())
+				csc.EXPECT().Get(gomock.Any()).Return(state, true)
+				csc.EXPECT().Put(gomock.Any(), nil)
+				csc.EXPECT().Put(gomock.Any(), gomock.Any()).MaxTimes(1)
+				clientRTTStats := &utils.RTTStats{}
+				clientHelloWrittenChan, client, clientErr, server, serverErr := handshakeWithTLSConf(
+					clientConf, serverConf,
+					clientRTTStats, &utils.RTTStats{},
+					&wire.TransportParameters{}, &wire.TransportParameters{InitialMaxData: initialMaxData},
+					true,
+				)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				Expect(clientRTTStats.SmoothedRTT()).To(Equal(clientRTT))
+				var tp *wire.TransportParameters
+				Expect(clientHelloWrittenChan).To(Receive(&tp))
+				Expect(tp.InitialMaxData).To(Equal(initialMaxData))
+				Expect(server.ConnectionState().DidResume).To(BeTrue())
+				Expect(client.ConnectionState().Used0RTT).To(BeTrue())
+			})
+
 			It("rejects 0-RTT, when the transport parameters changed", func() {
 				csc := mocktls.NewMockClientSessionCache(mockCtrl)
 				var state *tls.ClientSessionState
@@ -859,6 +921,50 @@ var _ = Describe("Crypto Setup TLS", func() {
 				Expect(server.ConnectionState().Used0RTT).To(BeFalse())
 				Expect(client.ConnectionState().Used0RTT).To(BeFalse())
 			})
+
+			It("rejects 0-RTT, when it is not allowed", func() {
+				csc := mocktls.NewMockClientSessionCache(mockCtrl)
+				var state *tls.ClientSessionState
+				receivedSessionTicket := make(chan struct{})
+				csc.EXPECT().Get(gomock.Any())
+				csc.EXPECT().Put(gomock.Any(), gomock.Any()).Do(func(_ string, css *tls.ClientSessionState) {
+					state = css
+					close(receivedSessionTicket)
+				})
+				clientConf.ClientSessionCache = csc
+				_, _, clientErr, _, serverErr := handshakeWithTLSConf(
+					clientConf, serverConf,
+					&utils.RTTStats{}, &utils.RTTStats{},
+					&wire.TransportParameters{}, &wire.TransportParameters{},
+					true,
+				)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				Eventually(receivedSessionTicket).Should(BeClosed())
+
+				csc.EXPECT().Get(gomock.Any()).Return(state, true)
+				csc.EXPECT().Put(gomock.Any(), nil)
+				csc.EXPECT().Put(gomock.Any(), gomock.Any()).MaxTimes(1)
+
+				var called bool
+				serverAllow0RTT = func() bool {
+					called = true
+					return false
+				}
+				_, client, clientErr, server, serverErr := handshakeWithTLSConf(
+					clientConf, serverConf,
+					&utils.RTTStats{}, &utils.RTTStats{},
+					&wire.TransportParameters{}, &wire.TransportParameters{},
+					true,
+				)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				Expect(called).To(BeTrue())
+				Expect(server.ConnectionState().DidResume).To(BeTrue())
+				Expect(client.ConnectionState().DidResume).To(BeTrue())
+				Expect(server.ConnectionState().Used0RTT).To(BeFalse())
+				Expect(client.ConnectionState().Used0RTT).To(BeFalse())
+			})
 		})
 	})
 })
@@ -100,3 +100,10 @@ type ConnWithVersion interface {
 	net.Conn
 	GetQUICVersion() protocol.VersionNumber
 }
+
+// ConnWithTransportParameters is the connection used in the ClientHelloInfo on the server side.
+// It can be used to access the transport parameters sent by the client.
+type ConnWithTransportParameters interface {
+	net.Conn
+	GetTransportParameters() *wire.TransportParameters
+}
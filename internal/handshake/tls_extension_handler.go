@@ -3,6 +3,8 @@ package handshake
 import (
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/qtls"
+
+	"golang.org/x/crypto/cryptobyte"
 )
 
 const (
@@ -23,16 +25,49 @@ var _ tlsExtensionHandler = &extensionHandler{}
 
 // newExtensionHandler creates a new extension handler
 func newExtensionHandler(params []byte, pers protocol.Perspective, v protocol.VersionNumber) tlsExtensionHandler {
-	et := uint16(quicTLSExtensionType)
-	if v != protocol.Version1 {
-		et = quicTLSExtensionTypeOldDrafts
-	}
 	return &extensionHandler{
 		ourParams:     params,
 		paramsChan:    make(chan []byte),
 		perspective:   pers,
-		extensionType: et,
+		extensionType: transportParametersExtensionType(v),
+	}
+}
+
+func transportParametersExtensionType(v protocol.VersionNumber) uint16 {
+	if v != protocol.Version1 {
+		return quicTLSExtensionTypeOldDrafts
+	}
+	return quicTLSExtensionType
+}
+
+// getClientHelloExtension returns the data of the extension of type extType contained in a ClientHello message.
+// The message includes the 4 byte handshake message header.
+func getClientHelloExtension(msg []byte, extType uint16) ([]byte, bool) {
+	s := cryptobyte.String(msg)
+	var msgType uint8
+	var body cryptobyte.String
+	if !s.ReadUint8(&msgType) || messageType(msgType) != typeClientHello || !s.ReadUint24LengthPrefixed(&body) {
+		return nil, false
+	}
+	var sessionID, cipherSuites, compressionMethods, extensions cryptobyte.String
+	if !body.Skip(2+32) || // legacy_version and random
+		!body.ReadUint8LengthPrefixed(&sessionID) ||
+		!body.ReadUint16LengthPrefixed(&cipherSuites) ||
+		!body.ReadUint8LengthPrefixed(&compressionMethods) ||
+		!body.ReadUint16LengthPrefixed(&extensions) {
+		return nil, false
+	}
+	for !extensions.Empty() {
+		var typ uint16
+		var data cryptobyte.String
+		if !extensions.ReadUint16(&typ) || !extensions.ReadUint16LengthPrefixed(&data) {
+			return nil, false
+		}
+		if typ == extType {
+			return data, true
+		}
 	}
+	return nil, false
 }
 
 func (h *extensionHandler) GetExtensions(msgType uint8) []qtls.Extension {
@@ -6,6 +6,8 @@ import (
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/qtls"
 
+	"golang.org/x/crypto/cryptobyte"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -208,3 +210,58 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 		})
 	})
 })
+
+var _ = Describe("Parsing the ClientHello", func() {
+	getClientHello := func(exts []qtls.Extension) []byte {
+		b := cryptobyte.NewBuilder(nil)
+		b.AddUint8(uint8(typeClientHello))
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(0x0303)          // legacy_version
+			b.AddBytes(make([]byte, 32)) // random
+			// legacy_session_id
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte{1, 2, 3}) })
+			// cipher_suites
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint16(0x1301) })
+			// legacy_compression_methods
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				for _, ext := range exts {
+					b.AddUint16(ext.Type)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(ext.Data) })
+				}
+			})
+		})
+		return b.BytesOrPanic()
+	}
+
+	It("finds an extension", func() {
+		ch := getClientHello([]qtls.Extension{
+			{Type: 0x1337, Data: []byte("foo")},
+			{Type: quicTLSExtensionType, Data: []byte("bar")},
+		})
+		data, ok := getClientHelloExtension(ch, quicTLSExtensionType)
+		Expect(ok).To(BeTrue())
+		Expect(data).To(Equal([]byte("bar")))
+	})
+
+	It("doesn't find a missing extension", func() {
+		ch := getClientHello([]qtls.Extension{{Type: 0x1337, Data: []byte("foo")}})
+		_, ok := getClientHelloExtension(ch, quicTLSExtensionType)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("rejects other handshake messages", func() {
+		ch := getClientHello([]qtls.Extension{{Type: quicTLSExtensionType, Data: []byte("bar")}})
+		ch[0] = uint8(typeServerHello)
+		_, ok := getClientHelloExtension(ch, quicTLSExtensionType)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("errors on truncated messages", func() {
+		ch := getClientHello([]qtls.Extension{{Type: quicTLSExtensionType, Data: []byte("bar")}})
+		for i := 0; i < len(ch); i++ {
+			_, ok := getClientHelloExtension(ch[:i], quicTLSExtensionType)
+			Expect(ok).To(BeFalse())
+		}
+	})
+})
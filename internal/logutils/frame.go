@@ -31,6 +31,14 @@ func ConvertFrame(frame wire.Frame) logging.Frame {
 		return &logging.DatagramFrame{
 			Length: logging.ByteCount(len(f.Data)),
 		}
+	case *wire.ExtensionFrame:
+		return &logging.ExtensionFrame{
+			FrameType: f.FrameType,
+			Length:    logging.ByteCount(len(f.Data)),
+		}
+	case *wire.MaxDataFrame:
+		// We use a pool for MAX_DATA frames, so we need to make a copy here.
+		return &logging.MaxDataFrame{MaximumData: f.MaximumData}
 	default:
 		return logging.Frame(frame)
 	}
@@ -41,6 +41,14 @@ var _ = Describe("CRYPTO frame", func() {
 		Expect(df.Length).To(Equal(logging.ByteCount(6)))
 	})
 
+	It("converts extension frames", func() {
+		f := ConvertFrame(&wire.ExtensionFrame{FrameType: 0x2f5a8b, Data: []byte("foobar")})
+		Expect(f).To(BeAssignableToTypeOf(&logging.ExtensionFrame{}))
+		ef := f.(*logging.ExtensionFrame)
+		Expect(ef.FrameType).To(BeEquivalentTo(0x2f5a8b))
+		Expect(ef.Length).To(Equal(logging.ByteCount(6)))
+	})
+
 	It("converts other frames", func() {
 		f := ConvertFrame(&wire.MaxDataFrame{MaximumData: 1234})
 		Expect(f).To(BeAssignableToTypeOf(&logging.MaxDataFrame{}))
@@ -49,6 +49,20 @@ func (mr *MockSentPacketHandlerMockRecorder) DropPackets(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DropPackets", reflect.TypeOf((*MockSentPacketHandler)(nil).DropPackets), arg0)
 }
 
+// ECNCongestionSignals mocks base method.
+func (m *MockSentPacketHandler) ECNCongestionSignals() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ECNCongestionSignals")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// ECNCongestionSignals indicates an expected call of ECNCongestionSignals.
+func (mr *MockSentPacketHandlerMockRecorder) ECNCongestionSignals() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ECNCongestionSignals", reflect.TypeOf((*MockSentPacketHandler)(nil).ECNCongestionSignals))
+}
+
 // GetLossDetectionTimeout mocks base method.
 func (m *MockSentPacketHandler) GetLossDetectionTimeout() time.Time {
 	m.ctrl.T.Helper()
@@ -63,6 +77,20 @@ func (mr *MockSentPacketHandlerMockRecorder) GetLossDetectionTimeout() *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLossDetectionTimeout", reflect.TypeOf((*MockSentPacketHandler)(nil).GetLossDetectionTimeout))
 }
 
+// HasOutstandingPackets mocks base method.
+func (m *MockSentPacketHandler) HasOutstandingPackets() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasOutstandingPackets")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// HasOutstandingPackets indicates an expected call of HasOutstandingPackets.
+func (mr *MockSentPacketHandlerMockRecorder) HasOutstandingPackets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasOutstandingPackets", reflect.TypeOf((*MockSentPacketHandler)(nil).HasOutstandingPackets))
+}
+
 // HasPacingBudget mocks base method.
 func (m *MockSentPacketHandler) HasPacingBudget() bool {
 	m.ctrl.T.Helper()
@@ -77,6 +105,18 @@ func (mr *MockSentPacketHandlerMockRecorder) HasPacingBudget() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasPacingBudget", reflect.TypeOf((*MockSentPacketHandler)(nil).HasPacingBudget))
 }
 
+// MigratedPath mocks base method.
+func (m *MockSentPacketHandler) MigratedPath(arg0 protocol.ByteCount, arg1 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "MigratedPath", arg0, arg1)
+}
+
+// MigratedPath indicates an expected call of MigratedPath.
+func (mr *MockSentPacketHandlerMockRecorder) MigratedPath(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigratedPath", reflect.TypeOf((*MockSentPacketHandler)(nil).MigratedPath), arg0, arg1)
+}
+
 // OnLossDetectionTimeout mocks base method.
 func (m *MockSentPacketHandler) OnLossDetectionTimeout() error {
 	m.ctrl.T.Helper()
@@ -91,6 +131,32 @@ func (mr *MockSentPacketHandlerMockRecorder) OnLossDetectionTimeout() *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnLossDetectionTimeout", reflect.TypeOf((*MockSentPacketHandler)(nil).OnLossDetectionTimeout))
 }
 
+// PTOCount mocks base method.
+func (m *MockSentPacketHandler) PTOCount() uint32 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PTOCount")
+	ret0, _ := ret[0].(uint32)
+	return ret0
+}
+
+// PTOCount indicates an expected call of PTOCount.
+func (mr *MockSentPacketHandlerMockRecorder) PTOCount() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PTOCount", reflect.TypeOf((*MockSentPacketHandler)(nil).PTOCount))
+}
+
+// PathValidated mocks base method.
+func (m *MockSentPacketHandler) PathValidated() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PathValidated")
+}
+
+// PathValidated indicates an expected call of PathValidated.
+func (mr *MockSentPacketHandlerMockRecorder) PathValidated() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PathValidated", reflect.TypeOf((*MockSentPacketHandler)(nil).PathValidated))
+}
+
 // PeekPacketNumber mocks base method.
 func (m *MockSentPacketHandler) PeekPacketNumber(arg0 protocol.EncryptionLevel) (protocol.PacketNumber, protocol.PacketNumberLen) {
 	m.ctrl.T.Helper()
@@ -225,6 +291,30 @@ func (mr *MockSentPacketHandlerMockRecorder) SetMaxDatagramSize(arg0 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxDatagramSize", reflect.TypeOf((*MockSentPacketHandler)(nil).SetMaxDatagramSize), arg0)
 }
 
+// SetMaxSendRate mocks base method.
+func (m *MockSentPacketHandler) SetMaxSendRate(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxSendRate", arg0)
+}
+
+// SetMaxSendRate indicates an expected call of SetMaxSendRate.
+func (mr *MockSentPacketHandlerMockRecorder) SetMaxSendRate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxSendRate", reflect.TypeOf((*MockSentPacketHandler)(nil).SetMaxSendRate), arg0)
+}
+
+// SetPacketFeedbackCallback mocks base method.
+func (m *MockSentPacketHandler) SetPacketFeedbackCallback(arg0 func(protocol.ByteCount, bool)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPacketFeedbackCallback", arg0)
+}
+
+// SetPacketFeedbackCallback indicates an expected call of SetPacketFeedbackCallback.
+func (mr *MockSentPacketHandlerMockRecorder) SetPacketFeedbackCallback(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPacketFeedbackCallback", reflect.TypeOf((*MockSentPacketHandler)(nil).SetPacketFeedbackCallback), arg0)
+}
+
 // TimeUntilSend mocks base method.
 func (m *MockSentPacketHandler) TimeUntilSend() time.Time {
 	m.ctrl.T.Helper()
@@ -117,6 +117,30 @@ func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) MaybeExitSlowStart() *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaybeExitSlowStart", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).MaybeExitSlowStart))
 }
 
+// OnConnectionMigration mocks base method.
+func (m *MockSendAlgorithmWithDebugInfos) OnConnectionMigration() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnConnectionMigration")
+}
+
+// OnConnectionMigration indicates an expected call of OnConnectionMigration.
+func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) OnConnectionMigration() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnConnectionMigration", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).OnConnectionMigration))
+}
+
+// OnECNCongestionEvent mocks base method.
+func (m *MockSendAlgorithmWithDebugInfos) OnECNCongestionEvent(arg0 protocol.PacketNumber) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnECNCongestionEvent", arg0)
+}
+
+// OnECNCongestionEvent indicates an expected call of OnECNCongestionEvent.
+func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) OnECNCongestionEvent(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnECNCongestionEvent", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).OnECNCongestionEvent), arg0)
+}
+
 // OnPacketAcked mocks base method.
 func (m *MockSendAlgorithmWithDebugInfos) OnPacketAcked(arg0 protocol.PacketNumber, arg1, arg2 protocol.ByteCount, arg3 time.Time) {
 	m.ctrl.T.Helper()
@@ -177,6 +201,18 @@ func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) SetMaxDatagramSize(arg0 i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxDatagramSize", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).SetMaxDatagramSize), arg0)
 }
 
+// SetMaxSendRate mocks base method.
+func (m *MockSendAlgorithmWithDebugInfos) SetMaxSendRate(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxSendRate", arg0)
+}
+
+// SetMaxSendRate indicates an expected call of SetMaxSendRate.
+func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) SetMaxSendRate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxSendRate", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).SetMaxSendRate), arg0)
+}
+
 // TimeUntilSend mocks base method.
 func (m *MockSendAlgorithmWithDebugInfos) TimeUntilSend(arg0 protocol.ByteCount) time.Time {
 	m.ctrl.T.Helper()
@@ -8,10 +8,13 @@ import (
 	context "context"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	quic "github.com/fkwhite/quic-go"
+	protocol "github.com/fkwhite/quic-go/internal/protocol"
 	qerr "github.com/fkwhite/quic-go/internal/qerr"
+	wire "github.com/fkwhite/quic-go/internal/wire"
 )
 
 // MockEarlyConnection is a mock of EarlyConnection interface.
@@ -67,6 +70,34 @@ func (mr *MockEarlyConnectionMockRecorder) AcceptUniStream(arg0 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockEarlyConnection)(nil).AcceptUniStream), arg0)
 }
 
+// AwaitHandshake mocks base method.
+func (m *MockEarlyConnection) AwaitHandshake(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AwaitHandshake", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AwaitHandshake indicates an expected call of AwaitHandshake.
+func (mr *MockEarlyConnectionMockRecorder) AwaitHandshake(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AwaitHandshake", reflect.TypeOf((*MockEarlyConnection)(nil).AwaitHandshake), arg0)
+}
+
+// CloseGracefully mocks base method.
+func (m *MockEarlyConnection) CloseGracefully(arg0 context.Context, arg1 quic.ApplicationErrorCode, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseGracefully", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseGracefully indicates an expected call of CloseGracefully.
+func (mr *MockEarlyConnectionMockRecorder) CloseGracefully(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseGracefully", reflect.TypeOf((*MockEarlyConnection)(nil).CloseGracefully), arg0, arg1, arg2)
+}
+
 // CloseWithError mocks base method.
 func (m *MockEarlyConnection) CloseWithError(arg0 qerr.ApplicationErrorCode, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -123,6 +154,20 @@ func (mr *MockEarlyConnectionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockEarlyConnection)(nil).HandshakeComplete))
 }
 
+// IdleTimeout mocks base method.
+func (m *MockEarlyConnection) IdleTimeout() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IdleTimeout")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// IdleTimeout indicates an expected call of IdleTimeout.
+func (mr *MockEarlyConnectionMockRecorder) IdleTimeout() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IdleTimeout", reflect.TypeOf((*MockEarlyConnection)(nil).IdleTimeout))
+}
+
 // LocalAddr mocks base method.
 func (m *MockEarlyConnection) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -137,6 +182,34 @@ func (mr *MockEarlyConnectionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockEarlyConnection)(nil).LocalAddr))
 }
 
+// LocalConnectionID mocks base method.
+func (m *MockEarlyConnection) LocalConnectionID() protocol.ConnectionID {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LocalConnectionID")
+	ret0, _ := ret[0].(protocol.ConnectionID)
+	return ret0
+}
+
+// LocalConnectionID indicates an expected call of LocalConnectionID.
+func (mr *MockEarlyConnectionMockRecorder) LocalConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalConnectionID", reflect.TypeOf((*MockEarlyConnection)(nil).LocalConnectionID))
+}
+
+// MigrateTo mocks base method.
+func (m *MockEarlyConnection) MigrateTo(arg0 context.Context, arg1 net.PacketConn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateTo", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigrateTo indicates an expected call of MigrateTo.
+func (mr *MockEarlyConnectionMockRecorder) MigrateTo(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateTo", reflect.TypeOf((*MockEarlyConnection)(nil).MigrateTo), arg0, arg1)
+}
+
 // NextConnection mocks base method.
 func (m *MockEarlyConnection) NextConnection() quic.Connection {
 	m.ctrl.T.Helper()
@@ -211,6 +284,35 @@ func (mr *MockEarlyConnectionMockRecorder) OpenUniStreamSync(arg0 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockEarlyConnection)(nil).OpenUniStreamSync), arg0)
 }
 
+// PathMTU mocks base method.
+func (m *MockEarlyConnection) PathMTU() uint16 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PathMTU")
+	ret0, _ := ret[0].(uint16)
+	return ret0
+}
+
+// PathMTU indicates an expected call of PathMTU.
+func (mr *MockEarlyConnectionMockRecorder) PathMTU() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PathMTU", reflect.TypeOf((*MockEarlyConnection)(nil).PathMTU))
+}
+
+// Ping mocks base method.
+func (m *MockEarlyConnection) Ping(arg0 context.Context) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", arg0)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockEarlyConnectionMockRecorder) Ping(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockEarlyConnection)(nil).Ping), arg0)
+}
+
 // ReceiveMessage mocks base method.
 func (m *MockEarlyConnection) ReceiveMessage() ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -240,6 +342,46 @@ func (mr *MockEarlyConnectionMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockEarlyConnection)(nil).RemoteAddr))
 }
 
+// RemoteConnectionID mocks base method.
+func (m *MockEarlyConnection) RemoteConnectionID() protocol.ConnectionID {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoteConnectionID")
+	ret0, _ := ret[0].(protocol.ConnectionID)
+	return ret0
+}
+
+// RemoteConnectionID indicates an expected call of RemoteConnectionID.
+func (mr *MockEarlyConnectionMockRecorder) RemoteConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteConnectionID", reflect.TypeOf((*MockEarlyConnection)(nil).RemoteConnectionID))
+}
+
+// ResetAllStreams mocks base method.
+func (m *MockEarlyConnection) ResetAllStreams(arg0 qerr.StreamErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ResetAllStreams", arg0)
+}
+
+// ResetAllStreams indicates an expected call of ResetAllStreams.
+func (mr *MockEarlyConnectionMockRecorder) ResetAllStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetAllStreams", reflect.TypeOf((*MockEarlyConnection)(nil).ResetAllStreams), arg0)
+}
+
+// RotateConnectionID mocks base method.
+func (m *MockEarlyConnection) RotateConnectionID() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateConnectionID")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateConnectionID indicates an expected call of RotateConnectionID.
+func (mr *MockEarlyConnectionMockRecorder) RotateConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateConnectionID", reflect.TypeOf((*MockEarlyConnection)(nil).RotateConnectionID))
+}
+
 // SendMessage mocks base method.
 func (m *MockEarlyConnection) SendMessage(arg0 []byte) error {
 	m.ctrl.T.Helper()
@@ -253,3 +395,29 @@ func (mr *MockEarlyConnectionMockRecorder) SendMessage(arg0 interface{}) *gomock
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockEarlyConnection)(nil).SendMessage), arg0)
 }
+
+// SetMaxSendRate mocks base method.
+func (m *MockEarlyConnection) SetMaxSendRate(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxSendRate", arg0)
+}
+
+// SetMaxSendRate indicates an expected call of SetMaxSendRate.
+func (mr *MockEarlyConnectionMockRecorder) SetMaxSendRate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxSendRate", reflect.TypeOf((*MockEarlyConnection)(nil).SetMaxSendRate), arg0)
+}
+
+// ZeroRTTTransportParameters mocks base method.
+func (m *MockEarlyConnection) ZeroRTTTransportParameters() *wire.TransportParameters {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZeroRTTTransportParameters")
+	ret0, _ := ret[0].(*wire.TransportParameters)
+	return ret0
+}
+
+// ZeroRTTTransportParameters indicates an expected call of ZeroRTTTransportParameters.
+func (mr *MockEarlyConnectionMockRecorder) ZeroRTTTransportParameters() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZeroRTTTransportParameters", reflect.TypeOf((*MockEarlyConnection)(nil).ZeroRTTTransportParameters))
+}
@@ -6,6 +6,8 @@ package mockquic
 
 import (
 	context "context"
+	io "io"
+	net "net"
 	reflect "reflect"
 	time "time"
 
@@ -37,6 +39,20 @@ func (m *MockStream) EXPECT() *MockStreamMockRecorder {
 	return m.recorder
 }
 
+// BufferedBytes mocks base method.
+func (m *MockStream) BufferedBytes() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BufferedBytes")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// BufferedBytes indicates an expected call of BufferedBytes.
+func (mr *MockStreamMockRecorder) BufferedBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedBytes", reflect.TypeOf((*MockStream)(nil).BufferedBytes))
+}
+
 // CancelRead mocks base method.
 func (m *MockStream) CancelRead(arg0 qerr.StreamErrorCode) {
 	m.ctrl.T.Helper()
@@ -61,6 +77,18 @@ func (mr *MockStreamMockRecorder) CancelWrite(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWrite", reflect.TypeOf((*MockStream)(nil).CancelWrite), arg0)
 }
 
+// CancelWriteAt mocks base method.
+func (m *MockStream) CancelWriteAt(arg0 qerr.StreamErrorCode, arg1 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CancelWriteAt", arg0, arg1)
+}
+
+// CancelWriteAt indicates an expected call of CancelWriteAt.
+func (mr *MockStreamMockRecorder) CancelWriteAt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWriteAt", reflect.TypeOf((*MockStream)(nil).CancelWriteAt), arg0, arg1)
+}
+
 // Close mocks base method.
 func (m *MockStream) Close() error {
 	m.ctrl.T.Helper()
@@ -104,6 +132,48 @@ func (mr *MockStreamMockRecorder) Read(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStream)(nil).Read), arg0)
 }
 
+// ReadBuffer mocks base method.
+func (m *MockStream) ReadBuffer() ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadBuffer")
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadBuffer indicates an expected call of ReadBuffer.
+func (mr *MockStreamMockRecorder) ReadBuffer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadBuffer", reflect.TypeOf((*MockStream)(nil).ReadBuffer))
+}
+
+// ReadFrom mocks base method.
+func (m *MockStream) ReadFrom(arg0 io.Reader) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadFrom", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadFrom indicates an expected call of ReadFrom.
+func (mr *MockStreamMockRecorder) ReadFrom(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadFrom", reflect.TypeOf((*MockStream)(nil).ReadFrom), arg0)
+}
+
+// ReleaseBuffer mocks base method.
+func (m *MockStream) ReleaseBuffer() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReleaseBuffer")
+}
+
+// ReleaseBuffer indicates an expected call of ReleaseBuffer.
+func (mr *MockStreamMockRecorder) ReleaseBuffer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseBuffer", reflect.TypeOf((*MockStream)(nil).ReleaseBuffer))
+}
+
 // SetDeadline mocks base method.
 func (m *MockStream) SetDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -118,6 +188,18 @@ func (mr *MockStreamMockRecorder) SetDeadline(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockStream)(nil).SetDeadline), arg0)
 }
 
+// SetReadBufferSize mocks base method.
+func (m *MockStream) SetReadBufferSize(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReadBufferSize", arg0)
+}
+
+// SetReadBufferSize indicates an expected call of SetReadBufferSize.
+func (mr *MockStreamMockRecorder) SetReadBufferSize(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadBufferSize", reflect.TypeOf((*MockStream)(nil).SetReadBufferSize), arg0)
+}
+
 // SetReadDeadline mocks base method.
 func (m *MockStream) SetReadDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -174,3 +256,18 @@ func (mr *MockStreamMockRecorder) Write(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockStream)(nil).Write), arg0)
 }
+
+// WriteBuffers mocks base method.
+func (m *MockStream) WriteBuffers(arg0 net.Buffers) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteBuffers", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteBuffers indicates an expected call of WriteBuffers.
+func (mr *MockStreamMockRecorder) WriteBuffers(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteBuffers", reflect.TypeOf((*MockStream)(nil).WriteBuffers), arg0)
+}
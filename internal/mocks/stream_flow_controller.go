@@ -113,6 +113,18 @@ func (mr *MockStreamFlowControllerMockRecorder) SendWindowSize() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendWindowSize", reflect.TypeOf((*MockStreamFlowController)(nil).SendWindowSize))
 }
 
+// SetMaxBufferedBytes mocks base method.
+func (m *MockStreamFlowController) SetMaxBufferedBytes(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxBufferedBytes", arg0)
+}
+
+// SetMaxBufferedBytes indicates an expected call of SetMaxBufferedBytes.
+func (mr *MockStreamFlowControllerMockRecorder) SetMaxBufferedBytes(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxBufferedBytes", reflect.TypeOf((*MockStreamFlowController)(nil).SetMaxBufferedBytes), arg0)
+}
+
 // UpdateHighestReceived mocks base method.
 func (m *MockStreamFlowController) UpdateHighestReceived(arg0 protocol.ByteCount, arg1 bool) error {
 	m.ctrl.T.Helper()
@@ -1,7 +1,6 @@
 package protocol
 
 import (
-	"crypto/rand"
 	"errors"
 	"fmt"
 	"io"
@@ -37,11 +36,11 @@ type ConnectionID struct {
 	l uint8
 }
 
-// GenerateConnectionID generates a connection ID using cryptographic random
+// GenerateConnectionID generates a connection ID using the RandReader
 func GenerateConnectionID(l int) (ConnectionID, error) {
 	var c ConnectionID
 	c.l = uint8(l)
-	_, err := rand.Read(c.b[:l])
+	_, err := io.ReadFull(RandReader, c.b[:l])
 	return c, err
 }
 
@@ -61,7 +60,7 @@ func ParseConnectionID(b []byte) ConnectionID {
 // It uses a length randomly chosen between 8 and 20 bytes.
 func GenerateConnectionIDForInitial() (ConnectionID, error) {
 	r := make([]byte, 1)
-	if _, err := rand.Read(r); err != nil {
+	if _, err := io.ReadFull(RandReader, r); err != nil {
 		return ConnectionID{}, err
 	}
 	l := MinConnectionIDLenInitial + int(r[0])%(maxConnectionIDLen-MinConnectionIDLenInitial+1)
@@ -14,6 +14,23 @@ const InitialPacketSizeIPv6 = 1232
 // MaxCongestionWindowPackets is the maximum congestion window in packet.
 const MaxCongestionWindowPackets = 10000
 
+// DefaultInitialCongestionWindowPackets is the default initial congestion window in packets.
+const DefaultInitialCongestionWindowPackets = 32
+
+// MinCongestionWindowPackets is the minimum congestion window in packets.
+const MinCongestionWindowPackets = 2
+
+// DefaultPathDegradingThreshold is the default number of consecutive PTOs after which the path is considered degraded.
+const DefaultPathDegradingThreshold = 3
+
+// MaxOutstandingPathChallenges is the maximum number of PATH_CHALLENGE frames that are tracked at the same time.
+// When this limit is reached, the oldest challenge is discarded.
+const MaxOutstandingPathChallenges = 4
+
+// MaxPreferredAddressProbes is the maximum number of PATH_CHALLENGE frames a client sends to the server's preferred address.
+// If none of them is answered, the client stays on the original path.
+const MaxPreferredAddressProbes = 3
+
 // MaxUndecryptablePackets limits the number of undecryptable packets that are queued in the connection.
 const MaxUndecryptablePackets = 32
 
@@ -55,7 +72,7 @@ const SkipPacketInitialPeriod PacketNumber = 256
 // SkipPacketMaxPeriod is the maximum period length used for packet number skipping.
 const SkipPacketMaxPeriod PacketNumber = 128 * 1024
 
-// MaxAcceptQueueSize is the maximum number of connections that the server queues for accepting.
+// MaxAcceptQueueSize is the default maximum number of connections that the server queues for accepting.
 // If the queue is full, new connection attempts will be rejected.
 const MaxAcceptQueueSize = 32
 
@@ -92,9 +109,11 @@ const MinStreamFrameBufferSize = 128
 // If a packet has less than this number of bytes, we won't coalesce any more packets onto it.
 const MinCoalescedPacketSize = 128
 
-// MaxCryptoStreamOffset is the maximum offset allowed on any of the crypto streams.
+// MaxCryptoStreamBufferSize is the maximum amount of data buffered on any of the crypto streams.
+// It is counted from the first byte that wasn't yet passed to TLS, up to the highest offset received,
+// so that gaps created by out-of-order CRYPTO frames count towards the limit as well.
 // This limits the size of the ClientHello and Certificates that can be received.
-const MaxCryptoStreamOffset = 16 * (1 << 10)
+const MaxCryptoStreamBufferSize = 16 * (1 << 10)
 
 // MinRemoteIdleTimeout is the minimum value that we accept for the remote idle timeout
 const MinRemoteIdleTimeout = 5 * time.Second
@@ -105,6 +124,9 @@ const DefaultIdleTimeout = 30 * time.Second
 // DefaultHandshakeIdleTimeout is the default idle timeout used before handshake completion.
 const DefaultHandshakeIdleTimeout = 5 * time.Second
 
+// MaxInitialRTT is the maximum value that can be configured for the initial RTT estimate.
+const MaxInitialRTT = 10 * time.Second
+
 // DefaultHandshakeTimeout is the default timeout for a connection until the crypto handshake succeeds.
 const DefaultHandshakeTimeout = 10 * time.Second
 
@@ -116,6 +138,11 @@ const MaxKeepAliveInterval = 20 * time.Second
 // after this time all information about the old connection will be deleted
 const RetiredConnectionIDDeleteTimeout = 5 * time.Second
 
+// MinConnectionCloseRetransmissionInterval is the minimum time between two retransmissions of a CONNECTION_CLOSE
+// in response to packets received for a closed connection.
+// This limits the amplification that can be achieved by sending packets for a closed connection.
+const MinConnectionCloseRetransmissionInterval = 100 * time.Millisecond
+
 // MinStreamFrameSize is the minimum size that has to be left in a packet, so that we add another STREAM frame.
 // This avoids splitting up STREAM frames into small pieces, which has 2 advantages:
 // 1. it reduces the framing overhead
@@ -132,13 +159,17 @@ const MaxPostHandshakeCryptoFrameSize = 1000
 // but must ensure that a maximum size ACK frame fits into one packet.
 const MaxAckFrameSize ByteCount = 1000
 
-// MaxDatagramFrameSize is the maximum size of a DATAGRAM frame (RFC 9221).
+// MaxDatagramFrameSize is the default maximum size of a DATAGRAM frame (RFC 9221) that we accept.
 // The size is chosen such that a DATAGRAM frame fits into a QUIC packet.
 const MaxDatagramFrameSize ByteCount = 1220
 
 // DatagramRcvQueueLen is the length of the receive queue for DATAGRAM frames (RFC 9221)
 const DatagramRcvQueueLen = 128
 
+// NonQUICPacketQueueLen is the length of the queue for received packets that are not QUIC packets.
+// Packets are dropped if the application doesn't read them fast enough.
+const NonQUICPacketQueueLen = 32
+
 // MaxNumAckRanges is the maximum number of ACK ranges that we send in an ACK frame.
 // It also serves as a limit for the packet history.
 // If at any point we keep track of more ranges, old ranges are discarded.
@@ -170,12 +201,12 @@ const AckDelayExponent = 3
 // The loss detection timer will not be set to a value smaller than granularity.
 const TimerGranularity = time.Millisecond
 
-// MaxAckDelay is the maximum time by which we delay sending ACKs.
+// MaxAckDelay is the default maximum time by which we delay sending ACKs.
+// The max_ack_delay advertised to the peer includes the timer granularity.
 const MaxAckDelay = 25 * time.Millisecond
 
-// MaxAckDelayInclGranularity is the max_ack_delay including the timer granularity.
-// This is the value that should be advertised to the peer.
-const MaxAckDelayInclGranularity = MaxAckDelay + TimerGranularity
+// MinAckDelay is the default min_ack_delay advertised to the peer, if the ACK frequency extension is enabled.
+const MinAckDelay = TimerGranularity
 
 // KeyUpdateInterval is the maximum number of packets we send or receive before initiating a key update.
 const KeyUpdateInterval = 100 * 1000
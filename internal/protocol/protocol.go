@@ -61,6 +61,14 @@ type StatelessResetToken [16]byte
 // Ethernet's max packet size is 1500 bytes,  1500 - 48 = 1452.
 const MaxPacketBufferSize ByteCount = 1452
 
+// MaxLargePacketBufferSize is the size of buffers that can hold multiple UDP datagrams,
+// as received from the kernel when using Generic Receive Offload (GRO).
+const MaxLargePacketBufferSize ByteCount = 64 * 1024
+
+// MaxUDPPayloadSize is the largest value allowed for the max_udp_payload_size transport parameter,
+// see Section 18.2 of RFC 9000.
+const MaxUDPPayloadSize ByteCount = 65527
+
 // MinInitialPacketSize is the minimum size an Initial packet is required to have.
 const MinInitialPacketSize = 1200
 
@@ -0,0 +1,13 @@
+package protocol
+
+import (
+	"crypto/rand"
+	"io"
+)
+
+// RandReader is the source of randomness for values that are not security-critical,
+// e.g. connection IDs, greased version numbers and skipped packet numbers.
+// By default, crypto/rand is used.
+// It's a package-level variable to allow making these values deterministic for testing purposes.
+// It must not be modified while connections are running.
+var RandReader io.Reader = rand.Reader
@@ -12,6 +12,7 @@ var (
 	ErrIdleTimeout      = &IdleTimeoutError{}
 )
 
+// A TransportError occurs when a QUIC protocol error is detected, either by us or by the peer (if Remote is set).
 type TransportError struct {
 	Remote       bool
 	FrameType    uint64
@@ -58,6 +59,7 @@ func (e *ApplicationError) Is(target error) bool {
 // A StreamErrorCode is an error code used to cancel streams.
 type StreamErrorCode uint64
 
+// An ApplicationError occurs when the connection is closed by the application, either by us or by the peer (if Remote is set).
 type ApplicationError struct {
 	Remote       bool
 	ErrorCode    ApplicationErrorCode
@@ -73,6 +75,7 @@ func (e *ApplicationError) Error() string {
 	return fmt.Sprintf("Application error %#x: %s", e.ErrorCode, e.ErrorMessage)
 }
 
+// An IdleTimeoutError occurs when there's no network activity for the duration of the idle timeout.
 type IdleTimeoutError struct{}
 
 var _ error = &IdleTimeoutError{}
@@ -82,6 +85,7 @@ func (e *IdleTimeoutError) Temporary() bool      { return false }
 func (e *IdleTimeoutError) Error() string        { return "timeout: no recent network activity" }
 func (e *IdleTimeoutError) Is(target error) bool { return target == net.ErrClosed }
 
+// A HandshakeTimeoutError occurs when the handshake doesn't complete in time.
 type HandshakeTimeoutError struct{}
 
 var _ error = &HandshakeTimeoutError{}
@@ -122,3 +126,40 @@ func (e *StatelessResetError) Is(target error) bool {
 
 func (e *StatelessResetError) Timeout() bool   { return false }
 func (e *StatelessResetError) Temporary() bool { return true }
+
+// An OptimisticAckError occurs when the peer acknowledges a packet that we never sent.
+// This is either a packet number that was skipped on purpose, or a packet number larger than any packet sent so far.
+// It is a strong indication of an optimistic ACK attack.
+// On the wire, the connection is closed with a PROTOCOL_VIOLATION, which is the *TransportError that this error wraps.
+type OptimisticAckError struct {
+	EncryptionLevel protocol.EncryptionLevel
+	PacketNumber    protocol.PacketNumber
+	Skipped         bool // the packet number was skipped, as opposed to being larger than any packet sent so far
+
+	transportErr *TransportError
+}
+
+var _ error = &OptimisticAckError{}
+
+// NewOptimisticAckError creates a new OptimisticAckError.
+func NewOptimisticAckError(encLevel protocol.EncryptionLevel, pn protocol.PacketNumber, skipped bool) *OptimisticAckError {
+	msg := "received ACK for an unsent packet"
+	if skipped {
+		msg = fmt.Sprintf("received an ACK for skipped packet number: %d (%s)", pn, encLevel)
+	}
+	return &OptimisticAckError{
+		EncryptionLevel: encLevel,
+		PacketNumber:    pn,
+		Skipped:         skipped,
+		transportErr:    &TransportError{ErrorCode: ProtocolViolation, ErrorMessage: msg},
+	}
+}
+
+func (e *OptimisticAckError) Error() string { return e.transportErr.Error() }
+func (e *OptimisticAckError) Unwrap() error { return e.transportErr }
+
+// Is reports whether target is a *TransportError equal to the one sent to the peer.
+func (e *OptimisticAckError) Is(target error) bool {
+	t, ok := target.(*TransportError)
+	return ok && *t == *e.transportErr
+}
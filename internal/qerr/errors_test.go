@@ -113,6 +113,28 @@ var _ = Describe("QUIC Errors", func() {
 		})
 	})
 
+	Context("Optimistic ACK errors", func() {
+		It("has a string representation for skipped packet numbers", func() {
+			err := NewOptimisticAckError(protocol.Encryption1RTT, 42, true)
+			Expect(err.Error()).To(Equal("PROTOCOL_VIOLATION: received an ACK for skipped packet number: 42 (1-RTT)"))
+		})
+
+		It("has a string representation for packet numbers that were not sent yet", func() {
+			err := NewOptimisticAckError(protocol.EncryptionHandshake, 1337, false)
+			Expect(err.Error()).To(Equal("PROTOCOL_VIOLATION: received ACK for an unsent packet"))
+		})
+
+		It("wraps a PROTOCOL_VIOLATION", func() {
+			var err error = NewOptimisticAckError(protocol.Encryption1RTT, 42, false)
+			var transportErr *TransportError
+			Expect(errors.As(err, &transportErr)).To(BeTrue())
+			Expect(transportErr.ErrorCode).To(Equal(ProtocolViolation))
+			Expect(transportErr.ErrorMessage).To(Equal("received ACK for an unsent packet"))
+			Expect(errors.Is(err, &TransportError{ErrorCode: ProtocolViolation, ErrorMessage: "received ACK for an unsent packet"})).To(BeTrue())
+			Expect(errors.Is(err, &TransportError{ErrorCode: ProtocolViolation})).To(BeFalse())
+		})
+	})
+
 	It("says that errors are net.ErrClosed errors", func() {
 		Expect(errors.Is(&TransportError{}, net.ErrClosed)).To(BeTrue())
 		Expect(errors.Is(&ApplicationError{}, net.ErrClosed)).To(BeTrue())
@@ -120,5 +142,6 @@ var _ = Describe("QUIC Errors", func() {
 		Expect(errors.Is(&HandshakeTimeoutError{}, net.ErrClosed)).To(BeTrue())
 		Expect(errors.Is(&StatelessResetError{}, net.ErrClosed)).To(BeTrue())
 		Expect(errors.Is(&VersionNegotiationError{}, net.ErrClosed)).To(BeTrue())
+		Expect(errors.Is(NewOptimisticAckError(protocol.Encryption1RTT, 0, false), net.ErrClosed)).To(BeTrue())
 	})
 })
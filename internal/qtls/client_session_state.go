@@ -0,0 +1,173 @@
+package qtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const clientSessionStateSerializationRevision = 1
+
+// clientSessionState has the same layout as the tls.ClientSessionState.
+// qtls verifies at initialization that its own copy of this struct matches the tls.ClientSessionState,
+// and it uses this layout for every tls.ClientSessionState that it passes to the tls.ClientSessionCache.
+type clientSessionState struct {
+	sessionTicket      []uint8               // Encrypted ticket used for session resumption with server
+	vers               uint16                // TLS version negotiated for the session
+	cipherSuite        uint16                // Ciphersuite negotiated for the session
+	masterSecret       []byte                // Full handshake MasterSecret, or TLS 1.3 resumption_master_secret
+	serverCertificates []*x509.Certificate   // Certificate chain presented by the server
+	verifiedChains     [][]*x509.Certificate // Certificate chains we built for verification
+	receivedAt         time.Time             // When the session ticket was received from the server
+	ocspResponse       []byte                // Stapled OCSP response presented by the server
+	scts               [][]byte              // SCTs presented by the server
+
+	// TLS 1.3 fields.
+	nonce  []byte    // Ticket nonce sent by the server, to derive PSK
+	useBy  time.Time // Expiration of the ticket lifetime as set by the server
+	ageAdd uint32    // Random obfuscation factor for sending the ticket age
+}
+
+// MarshalClientSessionState serializes a tls.ClientSessionState that was stored in the tls.ClientSessionCache.
+// Besides the TLS session ticket, the state contains the QUIC state that is needed to use 0-RTT.
+func MarshalClientSessionState(state *tls.ClientSessionState) ([]byte, error) {
+	if state == nil {
+		return nil, errors.New("no session state")
+	}
+	s := (*clientSessionState)(unsafe.Pointer(state))
+	var b cryptobyte.Builder
+	b.AddUint16(clientSessionStateSerializationRevision)
+	b.AddUint16(s.vers)
+	b.AddUint16(s.cipherSuite)
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(s.sessionTicket) })
+	b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(s.masterSecret) })
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { addCertificates(b, s.serverCertificates) })
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, chain := range s.verifiedChains {
+			b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { addCertificates(b, chain) })
+		}
+	})
+	b.AddUint64(uint64(s.receivedAt.UnixNano()))
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(s.ocspResponse) })
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, sct := range s.scts {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sct) })
+		}
+	})
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(s.nonce) })
+	b.AddUint64(uint64(s.useBy.UnixNano()))
+	b.AddUint32(s.ageAdd)
+	return b.Bytes()
+}
+
+func addCertificates(b *cryptobyte.Builder, certs []*x509.Certificate) {
+	for _, cert := range certs {
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(cert.Raw) })
+	}
+}
+
+// UnmarshalClientSessionState restores a tls.ClientSessionState serialized by MarshalClientSessionState.
+func UnmarshalClientSessionState(data []byte) (*tls.ClientSessionState, error) {
+	str := cryptobyte.String(data)
+	var rev uint16
+	if !str.ReadUint16(&rev) {
+		return nil, errors.New("failed to read session state revision")
+	}
+	if rev != clientSessionStateSerializationRevision {
+		return nil, fmt.Errorf("unknown session state revision: %d", rev)
+	}
+	s := &clientSessionState{}
+	var certs, chains, scts cryptobyte.String
+	var receivedAt, useBy uint64
+	if !str.ReadUint16(&s.vers) ||
+		!str.ReadUint16(&s.cipherSuite) ||
+		!readUint24LengthPrefixed(&str, &s.sessionTicket) ||
+		!readUint8LengthPrefixed(&str, &s.masterSecret) ||
+		!str.ReadUint24LengthPrefixed(&certs) ||
+		!str.ReadUint24LengthPrefixed(&chains) ||
+		!str.ReadUint64(&receivedAt) ||
+		!readUint24LengthPrefixed(&str, &s.ocspResponse) ||
+		!str.ReadUint24LengthPrefixed(&scts) ||
+		!readUint24LengthPrefixed(&str, &s.nonce) ||
+		!str.ReadUint64(&useBy) ||
+		!str.ReadUint32(&s.ageAdd) ||
+		!str.Empty() {
+		return nil, errors.New("malformed session state")
+	}
+	var err error
+	if s.serverCertificates, err = readCertificates(certs); err != nil {
+		return nil, err
+	}
+	for !chains.Empty() {
+		var chain cryptobyte.String
+		if !chains.ReadUint24LengthPrefixed(&chain) {
+			return nil, errors.New("malformed certificate chain")
+		}
+		certs, err := readCertificates(chain)
+		if err != nil {
+			return nil, err
+		}
+		s.verifiedChains = append(s.verifiedChains, certs)
+	}
+	for !scts.Empty() {
+		var sct []byte
+		if !readUint16LengthPrefixed(&scts, &sct) {
+			return nil, errors.New("malformed SCT")
+		}
+		s.scts = append(s.scts, sct)
+	}
+	s.receivedAt = time.Unix(0, int64(receivedAt))
+	s.useBy = time.Unix(0, int64(useBy))
+	return (*tls.ClientSessionState)(unsafe.Pointer(s)), nil
+}
+
+func readCertificates(str cryptobyte.String) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for !str.Empty() {
+		var raw []byte
+		if !readUint24LengthPrefixed(&str, &raw) {
+			return nil, errors.New("malformed certificate")
+		}
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// The cryptobyte.String returned by the ReadUint*LengthPrefixed functions aliases the serialized data.
+// These functions copy the data, so that the session state doesn't depend on the buffer passed to UnmarshalClientSessionState.
+
+func readUint8LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+	var v cryptobyte.String
+	if !s.ReadUint8LengthPrefixed(&v) {
+		return false
+	}
+	*out = append([]byte{}, v...)
+	return true
+}
+
+func readUint16LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+	var v cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&v) {
+		return false
+	}
+	*out = append([]byte{}, v...)
+	return true
+}
+
+func readUint24LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+	var v cryptobyte.String
+	if !s.ReadUint24LengthPrefixed(&v) {
+		return false
+	}
+	*out = append([]byte{}, v...)
+	return true
+}
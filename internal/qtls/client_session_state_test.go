@@ -0,0 +1,86 @@
+package qtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+	"unsafe"
+
+	"github.com/fkwhite/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client Session State", func() {
+	var state *clientSessionState
+
+	BeforeEach(func() {
+		tlsConf := testdata.GetTLSConfig()
+		leaf, err := x509.ParseCertificate(tlsConf.Certificates[0].Certificate[0])
+		Expect(err).ToNot(HaveOccurred())
+		state = &clientSessionState{
+			sessionTicket:      []byte("session ticket"),
+			vers:               tls.VersionTLS13,
+			cipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+			masterSecret:       []byte("master secret"),
+			serverCertificates: []*x509.Certificate{leaf},
+			verifiedChains:     [][]*x509.Certificate{{leaf}, {leaf}},
+			receivedAt:         time.Now(),
+			ocspResponse:       []byte("OCSP response"),
+			scts:               [][]byte{[]byte("foo"), []byte("bar")},
+			nonce:              []byte("nonce, including the QUIC state"),
+			useBy:              time.Now().Add(time.Hour),
+			ageAdd:             1337,
+		}
+	})
+
+	It("marshals and unmarshals", func() {
+		data, err := MarshalClientSessionState((*tls.ClientSessionState)(unsafe.Pointer(state)))
+		Expect(err).ToNot(HaveOccurred())
+		restored, err := UnmarshalClientSessionState(data)
+		Expect(err).ToNot(HaveOccurred())
+		s := (*clientSessionState)(unsafe.Pointer(restored))
+		Expect(s.sessionTicket).To(Equal(state.sessionTicket))
+		Expect(s.vers).To(Equal(state.vers))
+		Expect(s.cipherSuite).To(Equal(state.cipherSuite))
+		Expect(s.masterSecret).To(Equal(state.masterSecret))
+		Expect(s.serverCertificates).To(HaveLen(1))
+		Expect(s.serverCertificates[0].Equal(state.serverCertificates[0])).To(BeTrue())
+		Expect(s.verifiedChains).To(HaveLen(2))
+		for _, chain := range s.verifiedChains {
+			Expect(chain).To(HaveLen(1))
+			Expect(chain[0].Equal(state.serverCertificates[0])).To(BeTrue())
+		}
+		Expect(s.receivedAt.Equal(state.receivedAt)).To(BeTrue())
+		Expect(s.ocspResponse).To(Equal(state.ocspResponse))
+		Expect(s.scts).To(Equal(state.scts))
+		Expect(s.nonce).To(Equal(state.nonce))
+		Expect(s.useBy.Equal(state.useBy)).To(BeTrue())
+		Expect(s.ageAdd).To(Equal(state.ageAdd))
+	})
+
+	It("doesn't marshal a nil session state", func() {
+		_, err := MarshalClientSessionState(nil)
+		Expect(err).To(MatchError("no session state"))
+	})
+
+	It("errors on an unknown revision", func() {
+		data, err := MarshalClientSessionState((*tls.ClientSessionState)(unsafe.Pointer(state)))
+		Expect(err).ToNot(HaveOccurred())
+		data[1]++
+		_, err = UnmarshalClientSessionState(data)
+		Expect(err).To(MatchError("unknown session state revision: 2"))
+	})
+
+	It("errors on malformed data", func() {
+		data, err := MarshalClientSessionState((*tls.ClientSessionState)(unsafe.Pointer(state)))
+		Expect(err).ToNot(HaveOccurred())
+		for i := range data {
+			_, err := UnmarshalClientSessionState(data[:i])
+			Expect(err).To(HaveOccurred())
+		}
+		_, err = UnmarshalClientSessionState(append(data, 0))
+		Expect(err).To(MatchError("malformed session state"))
+	})
+})
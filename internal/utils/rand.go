@@ -1,17 +1,20 @@
 package utils
 
 import (
-	"crypto/rand"
 	"encoding/binary"
+	"io"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
 )
 
-// Rand is a wrapper around crypto/rand that adds some convenience functions known from math/rand.
+// Rand is a wrapper around protocol.RandReader (crypto/rand by default)
+// that adds some convenience functions known from math/rand.
 type Rand struct {
 	buf [4]byte
 }
 
 func (r *Rand) Int31() int32 {
-	rand.Read(r.buf[:])
+	io.ReadFull(protocol.RandReader, r.buf[:])
 	return int32(binary.BigEndian.Uint32(r.buf[:]) & ^uint32(1<<31))
 }
 
@@ -25,6 +25,10 @@ type RTTStats struct {
 	meanDeviation time.Duration
 
 	maxAckDelay time.Duration
+
+	// the RTT used to calculate the PTO before an RTT sample is taken
+	// If not set, defaultInitialRTT is used.
+	initialRTT time.Duration
 }
 
 // NewRTTStats makes a properly initialized RTTStats object
@@ -53,6 +57,9 @@ func (r *RTTStats) MaxAckDelay() time.Duration { return r.maxAckDelay }
 // PTO gets the probe timeout duration.
 func (r *RTTStats) PTO(includeMaxAckDelay bool) time.Duration {
 	if r.SmoothedRTT() == 0 {
+		if r.initialRTT > 0 {
+			return 2 * r.initialRTT
+		}
 		return 2 * defaultInitialRTT
 	}
 	pto := r.SmoothedRTT() + Max(4*r.MeanDeviation(), protocol.TimerGranularity)
@@ -110,8 +117,15 @@ func (r *RTTStats) SetInitialRTT(t time.Duration) {
 	r.latestRTT = t
 }
 
+// SetInitialRTTEstimate sets the RTT used to calculate the PTO before the first RTT sample is taken.
+// Contrary to SetInitialRTT, it doesn't set the smoothed RTT.
+func (r *RTTStats) SetInitialRTTEstimate(t time.Duration) {
+	r.initialRTT = t
+}
+
 // OnConnectionMigration is called when connection migrates and rtt measurement needs to be reset.
 func (r *RTTStats) OnConnectionMigration() {
+	r.hasMeasurement = false
 	r.latestRTT = 0
 	r.minRTT = 0
 	r.smoothedRTT = 0
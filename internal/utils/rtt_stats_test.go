@@ -143,6 +143,17 @@ var _ = Describe("RTT stats", func() {
 		Expect(rttStats.MinRTT()).To(Equal(time.Duration(0)))
 	})
 
+	It("uses the initial RTT estimate to calculate the PTO", func() {
+		Expect(rttStats.PTO(false)).To(Equal(2 * defaultInitialRTT))
+		rttStats.SetInitialRTTEstimate(time.Second)
+		Expect(rttStats.SmoothedRTT()).To(BeZero())
+		Expect(rttStats.PTO(false)).To(Equal(2 * time.Second))
+		Expect(rttStats.PTO(true)).To(Equal(2 * time.Second))
+		// once an RTT sample is taken, the estimate is not used any more
+		rttStats.UpdateRTT(200*time.Millisecond, 0, time.Time{})
+		Expect(rttStats.PTO(false)).To(Equal(200*time.Millisecond + 4*100*time.Millisecond))
+	})
+
 	It("restores the RTT", func() {
 		rttStats.SetInitialRTT(10 * time.Second)
 		Expect(rttStats.LatestRTT()).To(Equal(10 * time.Second))
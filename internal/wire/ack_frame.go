@@ -17,6 +17,8 @@ var errInvalidAckRanges = errors.New("AckFrame: ACK frame contains invalid ACK r
 type AckFrame struct {
 	AckRanges []AckRange // has to be ordered. The highest ACK range goes first, the lowest ACK range goes last
 	DelayTime time.Duration
+	// AckDelayExponent is the ack_delay_exponent used to encode the DelayTime.
+	AckDelayExponent uint8
 
 	ECT0, ECT1, ECNCE uint64
 }
@@ -47,6 +49,7 @@ func parseAckFrame(r *bytes.Reader, ackDelayExponent uint8, _ protocol.VersionNu
 		delayTime = utils.InfDuration
 	}
 	frame.DelayTime = delayTime
+	frame.AckDelayExponent = ackDelayExponent
 
 	numBlocks, err := quicvarint.Read(r)
 	if err != nil {
@@ -115,7 +118,7 @@ func (f *AckFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
 		b = append(b, 0b10)
 	}
 	b = quicvarint.Append(b, uint64(f.LargestAcked()))
-	b = quicvarint.Append(b, encodeAckDelay(f.DelayTime))
+	b = quicvarint.Append(b, encodeAckDelay(f.DelayTime, f.AckDelayExponent))
 
 	numRanges := f.numEncodableAckRanges()
 	b = quicvarint.Append(b, uint64(numRanges-1))
@@ -144,7 +147,7 @@ func (f *AckFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
 	largestAcked := f.AckRanges[0].Largest
 	numRanges := f.numEncodableAckRanges()
 
-	length := 1 + quicvarint.Len(uint64(largestAcked)) + quicvarint.Len(encodeAckDelay(f.DelayTime))
+	length := 1 + quicvarint.Len(uint64(largestAcked)) + quicvarint.Len(encodeAckDelay(f.DelayTime, f.AckDelayExponent))
 
 	length += quicvarint.Len(uint64(numRanges - 1))
 	lowestInFirstRange := f.AckRanges[0].Smallest
@@ -163,10 +166,21 @@ func (f *AckFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
 	return length
 }
 
+// maxAckRangesWithoutSizeCheck is the number of ACK ranges that always fit into an ACK frame of MaxAckFrameSize,
+// even if every value is encoded as an 8 byte varint:
+// 1 byte for the type, 8 bytes each for the largest acknowledged and the ACK delay, 2 bytes for the number of ranges,
+// and 16 bytes for every ACK range following the first one.
+const maxAckRangesWithoutSizeCheck = 1 + int(protocol.MaxAckFrameSize-1-8-8-2)/16
+
 // gets the number of ACK ranges that can be encoded
 // such that the resulting frame is smaller than the maximum ACK frame size
 func (f *AckFrame) numEncodableAckRanges() int {
-	length := 1 + quicvarint.Len(uint64(f.LargestAcked())) + quicvarint.Len(encodeAckDelay(f.DelayTime))
+	// Fast path: Most ACK frames only contain a few ACK ranges.
+	// There's no need to calculate the length of every single range for these frames.
+	if len(f.AckRanges) <= maxAckRangesWithoutSizeCheck {
+		return len(f.AckRanges)
+	}
+	length := 1 + quicvarint.Len(uint64(f.LargestAcked())) + quicvarint.Len(encodeAckDelay(f.DelayTime, f.AckDelayExponent))
 	length += 2 // assume that the number of ranges will consume 2 bytes
 	for i := 1; i < len(f.AckRanges); i++ {
 		gap, len := f.encodeAckRange(i)
@@ -246,6 +260,6 @@ func (f *AckFrame) AcksPacket(p protocol.PacketNumber) bool {
 	return p <= f.AckRanges[i].Largest
 }
 
-func encodeAckDelay(delay time.Duration) uint64 {
-	return uint64(delay.Nanoseconds() / (1000 * (1 << protocol.AckDelayExponent)))
+func encodeAckDelay(delay time.Duration, ackDelayExponent uint8) uint64 {
+	return uint64(delay.Nanoseconds() / (1000 * (1 << ackDelayExponent)))
 }
@@ -1,6 +1,10 @@
 package wire
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+)
 
 var ackFramePool = sync.Pool{New: func() any {
 	return &AckFrame{}
@@ -13,6 +17,7 @@ func GetAckFrame() *AckFrame {
 	f.ECT0 = 0
 	f.ECT1 = 0
 	f.DelayTime = 0
+	f.AckDelayExponent = protocol.AckDelayExponent
 	return f
 }
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"math"
+	"testing"
 	"time"
 
 	"github.com/fkwhite/quic-go/internal/protocol"
@@ -117,8 +118,9 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 		It("uses the ack delay exponent", func() {
 			const delayTime = 1 << 10 * time.Millisecond
 			f := &AckFrame{
-				AckRanges: []AckRange{{Smallest: 1, Largest: 1}},
-				DelayTime: delayTime,
+				AckRanges:        []AckRange{{Smallest: 1, Largest: 1}},
+				DelayTime:        delayTime,
+				AckDelayExponent: protocol.AckDelayExponent,
 			}
 			b, err := f.Append(nil, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
@@ -238,8 +240,9 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 
 		It("writes a frame that acks a single packet", func() {
 			f := &AckFrame{
-				AckRanges: []AckRange{{Smallest: 0x2eadbeef, Largest: 0x2eadbeef}},
-				DelayTime: 18 * time.Millisecond,
+				AckRanges:        []AckRange{{Smallest: 0x2eadbeef, Largest: 0x2eadbeef}},
+				DelayTime:        18 * time.Millisecond,
+				AckDelayExponent: protocol.AckDelayExponent,
 			}
 			b, err := f.Append(nil, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
@@ -255,7 +258,8 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 
 		It("writes a frame that acks many packets", func() {
 			f := &AckFrame{
-				AckRanges: []AckRange{{Smallest: 0x1337, Largest: 0x2eadbeef}},
+				AckDelayExponent: protocol.AckDelayExponent,
+				AckRanges:        []AckRange{{Smallest: 0x1337, Largest: 0x2eadbeef}},
 			}
 			b, err := f.Append(nil, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
@@ -270,6 +274,7 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 
 		It("writes a frame with a a single gap", func() {
 			f := &AckFrame{
+				AckDelayExponent: protocol.AckDelayExponent,
 				AckRanges: []AckRange{
 					{Smallest: 400, Largest: 1000},
 					{Smallest: 100, Largest: 200},
@@ -289,6 +294,7 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 
 		It("writes a frame with multiple ranges", func() {
 			f := &AckFrame{
+				AckDelayExponent: protocol.AckDelayExponent,
 				AckRanges: []AckRange{
 					{Smallest: 10, Largest: 10},
 					{Smallest: 8, Largest: 8},
@@ -308,6 +314,27 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 			Expect(r.Len()).To(BeZero())
 		})
 
+		It("encodes all ACK ranges, if they fit into the maximum ACK frame size", func() {
+			// use ranges and gaps that require 8 byte varints
+			const rangeLen = 1 << 31
+			ackRanges := make([]AckRange, maxAckRangesWithoutSizeCheck)
+			for i := 0; i < maxAckRangesWithoutSizeCheck; i++ {
+				smallest := protocol.PacketNumber(maxAckRangesWithoutSizeCheck-i) * 2 * rangeLen
+				ackRanges[i] = AckRange{Smallest: smallest, Largest: smallest + rangeLen}
+			}
+			f := &AckFrame{AckRanges: ackRanges}
+			Expect(f.validateAckRanges()).To(BeTrue())
+			b, err := f.Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(HaveLen(int(f.Length(protocol.Version1))))
+			Expect(len(b)).To(BeNumerically("<=", protocol.MaxAckFrameSize))
+			r := bytes.NewReader(b)
+			frame, err := parseAckFrame(r, protocol.AckDelayExponent, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.AckRanges).To(Equal(ackRanges))
+			Expect(r.Len()).To(BeZero())
+		})
+
 		It("limits the maximum size of the ACK frame", func() {
 			const numRanges = 1000
 			ackRanges := make([]AckRange, numRanges)
@@ -451,3 +478,24 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 		})
 	})
 })
+
+func benchmarkAckFrameAppend(b *testing.B, numRanges int) {
+	ackRanges := make([]AckRange, numRanges)
+	for i := 1; i <= numRanges; i++ {
+		ackRanges[numRanges-i] = AckRange{Smallest: protocol.PacketNumber(2 * i), Largest: protocol.PacketNumber(2 * i)}
+	}
+	f := &AckFrame{AckRanges: ackRanges, DelayTime: 10 * time.Millisecond}
+	buf := make([]byte, 0, protocol.MaxAckFrameSize)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = f.Length(protocol.Version1)
+		if _, err := f.Append(buf, protocol.Version1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAckFrameAppendFewRanges(b *testing.B)  { benchmarkAckFrameAppend(b, 10) }
+func BenchmarkAckFrameAppendManyRanges(b *testing.B) { benchmarkAckFrameAppend(b, 1000) }
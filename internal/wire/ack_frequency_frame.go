@@ -0,0 +1,65 @@
+package wire
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/fkwhite/quic-go/quicvarint"
+)
+
+const ackFrequencyFrameType = 0xaf
+
+// An AckFrequencyFrame is an ACK_FREQUENCY frame, as defined in draft-ietf-quic-ack-frequency.
+// It asks the peer to change the rate at which it sends ACK frames.
+type AckFrequencyFrame struct {
+	SequenceNumber        uint64
+	AckElicitingThreshold uint64
+	RequestMaxAckDelay    time.Duration
+	ReorderingThreshold   uint64
+}
+
+func parseAckFrequencyFrame(r *bytes.Reader, _ protocol.VersionNumber) (*AckFrequencyFrame, error) {
+	// The frame type is encoded in two bytes.
+	if _, err := quicvarint.Read(r); err != nil {
+		return nil, err
+	}
+
+	seq, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	delay, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	reorderingThreshold, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	return &AckFrequencyFrame{
+		SequenceNumber:        seq,
+		AckElicitingThreshold: threshold,
+		RequestMaxAckDelay:    time.Duration(delay) * time.Microsecond,
+		ReorderingThreshold:   reorderingThreshold,
+	}, nil
+}
+
+func (f *AckFrequencyFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
+	b = quicvarint.Append(b, ackFrequencyFrameType)
+	b = quicvarint.Append(b, f.SequenceNumber)
+	b = quicvarint.Append(b, f.AckElicitingThreshold)
+	b = quicvarint.Append(b, uint64(f.RequestMaxAckDelay/time.Microsecond))
+	b = quicvarint.Append(b, f.ReorderingThreshold)
+	return b, nil
+}
+
+// Length of a written frame
+func (f *AckFrequencyFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
+	return quicvarint.Len(ackFrequencyFrameType) + quicvarint.Len(f.SequenceNumber) + quicvarint.Len(f.AckElicitingThreshold) +
+		quicvarint.Len(uint64(f.RequestMaxAckDelay/time.Microsecond)) + quicvarint.Len(f.ReorderingThreshold)
+}
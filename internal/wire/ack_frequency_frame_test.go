@@ -0,0 +1,76 @@
+package wire
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ACK_FREQUENCY frame", func() {
+	Context("when parsing", func() {
+		It("accepts sample frame", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(0xdeadbeef)...) // sequence number
+			data = append(data, encodeVarInt(9)...)          // ack-eliciting threshold
+			data = append(data, encodeVarInt(25000)...)      // request max ack delay
+			data = append(data, encodeVarInt(3)...)          // reordering threshold
+			b := bytes.NewReader(data)
+			frame, err := parseAckFrequencyFrame(b, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.SequenceNumber).To(BeEquivalentTo(0xdeadbeef))
+			Expect(frame.AckElicitingThreshold).To(BeEquivalentTo(9))
+			Expect(frame.RequestMaxAckDelay).To(Equal(25 * time.Millisecond))
+			Expect(frame.ReorderingThreshold).To(BeEquivalentTo(3))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors on EOFs", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(0xdeadbeef)...) // sequence number
+			data = append(data, encodeVarInt(9)...)          // ack-eliciting threshold
+			data = append(data, encodeVarInt(25000)...)      // request max ack delay
+			data = append(data, encodeVarInt(3)...)          // reordering threshold
+			_, err := parseAckFrequencyFrame(bytes.NewReader(data), protocol.Version1)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parseAckFrequencyFrame(bytes.NewReader(data[0:i]), protocol.Version1)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes a sample frame", func() {
+			frame := AckFrequencyFrame{
+				SequenceNumber:        0x1337,
+				AckElicitingThreshold: 42,
+				RequestMaxAckDelay:    10 * time.Millisecond,
+				ReorderingThreshold:   1,
+			}
+			b, err := frame.Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			expected := encodeVarInt(0xaf)
+			expected = append(expected, encodeVarInt(0x1337)...)
+			expected = append(expected, encodeVarInt(42)...)
+			expected = append(expected, encodeVarInt(10000)...)
+			expected = append(expected, encodeVarInt(1)...)
+			Expect(b).To(Equal(expected))
+		})
+
+		It("has the correct length", func() {
+			frame := AckFrequencyFrame{
+				SequenceNumber:        0xdecafbad,
+				AckElicitingThreshold: 0x1337,
+				RequestMaxAckDelay:    time.Second,
+				ReorderingThreshold:   2,
+			}
+			b, err := frame.Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(HaveLen(int(frame.Length(protocol.Version1))))
+		})
+	})
+})
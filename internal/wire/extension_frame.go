@@ -0,0 +1,20 @@
+package wire
+
+import "github.com/fkwhite/quic-go/internal/protocol"
+
+// An ExtensionFrame is a frame of a frame type registered using FrameParser.RegisterFrameType.
+type ExtensionFrame struct {
+	FrameType uint64
+	// Data is the complete frame, including the frame type.
+	// It points into the packet buffer, and is only valid while the frame is handled.
+	Data []byte
+}
+
+func (f *ExtensionFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
+	return append(b, f.Data...), nil
+}
+
+// Length of a written frame
+func (f *ExtensionFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
+	return protocol.ByteCount(len(f.Data))
+}
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/qerr"
+	"github.com/fkwhite/quic-go/quicvarint"
 )
 
 type frameParser struct {
@@ -15,38 +17,108 @@ type frameParser struct {
 
 	ackDelayExponent uint8
 
-	supportsDatagrams bool
+	supportsDatagrams     bool
+	supportsResetStreamAt bool
+	supportsAckFrequency  bool
+
+	registeredFrames map[uint64]func([]byte) (int, error)
 
 	version protocol.VersionNumber
 }
 
+// FrameParserOptions configures which extension frames are accepted by the frame parser.
+type FrameParserOptions struct {
+	// SupportsDatagrams enables parsing of DATAGRAM frames (RFC 9221).
+	SupportsDatagrams bool
+	// SupportsResetStreamAt enables parsing of RESET_STREAM_AT frames.
+	SupportsResetStreamAt bool
+	// SupportsAckFrequency enables parsing of ACK_FREQUENCY and IMMEDIATE_ACK frames.
+	SupportsAckFrequency bool
+}
+
 // NewFrameParser creates a new frame parser.
 func NewFrameParser(supportsDatagrams bool, v protocol.VersionNumber) FrameParser {
+	return NewFrameParserWithOptions(FrameParserOptions{SupportsDatagrams: supportsDatagrams}, v)
+}
+
+// NewFrameParserWithOptions creates a new frame parser that accepts the extension frames enabled in opts.
+func NewFrameParserWithOptions(opts FrameParserOptions, v protocol.VersionNumber) FrameParser {
 	return &frameParser{
-		r:                 *bytes.NewReader(nil),
-		supportsDatagrams: supportsDatagrams,
-		version:           v,
+		r:                     *bytes.NewReader(nil),
+		supportsDatagrams:     opts.SupportsDatagrams,
+		supportsResetStreamAt: opts.SupportsResetStreamAt,
+		supportsAckFrequency:  opts.SupportsAckFrequency,
+		version:               v,
 	}
 }
 
 // ParseNext parses the next frame.
 // It skips PADDING frames.
+// Frames of the frame types registered using RegisterFrameType are returned as an ExtensionFrame.
+// RFC 9000 doesn't reserve any frame types for greasing, so all other unknown frame types
+// are treated as a FRAME_ENCODING_ERROR (see Section 12.4 of RFC 9000).
 func (p *frameParser) ParseNext(data []byte, encLevel protocol.EncryptionLevel) (int, Frame, error) {
 	startLen := len(data)
 	p.r.Reset(data)
-	frame, err := p.parseNext(&p.r, encLevel)
+	frame, err := p.parseNext(&p.r, data, encLevel)
 	n := startLen - p.r.Len()
 	p.r.Reset(nil)
 	return n, frame, err
 }
 
-func (p *frameParser) parseNext(r *bytes.Reader, encLevel protocol.EncryptionLevel) (Frame, error) {
+func (p *frameParser) parseNext(r *bytes.Reader, data []byte, encLevel protocol.EncryptionLevel) (Frame, error) {
 	for r.Len() != 0 {
 		typeByte, _ := p.r.ReadByte()
 		if typeByte == 0x0 { // PADDING frame
 			continue
 		}
 		r.UnreadByte()
+		start := len(data) - r.Len()
+
+		frameType, err := quicvarint.Read(r)
+		if err != nil {
+			return nil, &qerr.TransportError{
+				FrameType:    uint64(typeByte),
+				ErrorCode:    qerr.FrameEncodingError,
+				ErrorMessage: err.Error(),
+			}
+		}
+		if parse, ok := p.registeredFrames[frameType]; ok {
+			f, err := p.parseExtensionFrame(data[start:], frameType, parse, encLevel)
+			if err != nil {
+				return nil, &qerr.TransportError{
+					FrameType:    frameType,
+					ErrorCode:    qerr.FrameEncodingError,
+					ErrorMessage: err.Error(),
+				}
+			}
+			r.Seek(int64(start+len(f.Data)), io.SeekStart)
+			return f, nil
+		}
+		if frameType == ackFrequencyFrameType && p.supportsAckFrequency {
+			r.Seek(int64(start), io.SeekStart)
+			f, err := parseAckFrequencyFrame(r, p.version)
+			if err == nil && !p.isAllowedAtEncLevel(f, encLevel) {
+				err = fmt.Errorf("AckFrequencyFrame not allowed at encryption level %s", encLevel)
+			}
+			if err != nil {
+				return nil, &qerr.TransportError{
+					FrameType:    frameType,
+					ErrorCode:    qerr.FrameEncodingError,
+					ErrorMessage: err.Error(),
+				}
+			}
+			return f, nil
+		}
+		// All other frame types defined by RFC 9000 and the extensions we support are encoded in a single byte.
+		if frameType != uint64(typeByte) {
+			return nil, &qerr.TransportError{
+				FrameType:    frameType,
+				ErrorCode:    qerr.FrameEncodingError,
+				ErrorMessage: "unknown frame type",
+			}
+		}
+		r.UnreadByte()
 
 		f, err := p.parseFrame(r, typeByte, encLevel)
 		if err != nil {
@@ -108,6 +180,18 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 			frame, err = parseConnectionCloseFrame(r, p.version)
 		case 0x1e:
 			frame, err = parseHandshakeDoneFrame(r, p.version)
+		case 0x1f:
+			if p.supportsAckFrequency {
+				frame, err = parseImmediateAckFrame(r, p.version)
+				break
+			}
+			err = errors.New("unknown frame type")
+		case 0x24:
+			if p.supportsResetStreamAt {
+				frame, err = parseResetStreamAtFrame(r, p.version)
+				break
+			}
+			err = errors.New("unknown frame type")
 		case 0x30, 0x31:
 			if p.supportsDatagrams {
 				frame, err = parseDatagramFrame(r, p.version)
@@ -127,6 +211,21 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 	return frame, nil
 }
 
+func (p *frameParser) parseExtensionFrame(data []byte, frameType uint64, parse func([]byte) (int, error), encLevel protocol.EncryptionLevel) (*ExtensionFrame, error) {
+	// Extension frames can only be used once the extension was negotiated using the transport parameters.
+	if encLevel != protocol.Encryption0RTT && encLevel != protocol.Encryption1RTT {
+		return nil, fmt.Errorf("frame type %#x not allowed at encryption level %s", frameType, encLevel)
+	}
+	l, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if l <= 0 || l > len(data) {
+		return nil, fmt.Errorf("invalid length of frame type %#x: %d", frameType, l)
+	}
+	return &ExtensionFrame{FrameType: frameType, Data: data[:l]}, nil
+}
+
 func (p *frameParser) isAllowedAtEncLevel(f Frame, encLevel protocol.EncryptionLevel) bool {
 	switch encLevel {
 	case protocol.EncryptionInitial, protocol.EncryptionHandshake:
@@ -150,6 +249,46 @@ func (p *frameParser) isAllowedAtEncLevel(f Frame, encLevel protocol.EncryptionL
 	}
 }
 
+func (p *frameParser) Release(f Frame) {
+	switch f := f.(type) {
+	case *AckFrame:
+		PutAckFrame(f)
+	case *MaxDataFrame:
+		putMaxDataFrame(f)
+	}
+}
+
 func (p *frameParser) SetAckDelayExponent(exp uint8) {
 	p.ackDelayExponent = exp
 }
+
+// RegisterFrameType registers a frame type that is not handled by the frame parser.
+// It panics if the frame type is already handled by the frame parser, or if it was already registered.
+func (p *frameParser) RegisterFrameType(frameType uint64, parse func([]byte) (int, error)) {
+	if IsKnownFrameType(frameType) {
+		panic(fmt.Sprintf("frame type %#x is handled by the frame parser", frameType))
+	}
+	if _, ok := p.registeredFrames[frameType]; ok {
+		panic(fmt.Sprintf("frame type %#x already registered", frameType))
+	}
+	if p.registeredFrames == nil {
+		p.registeredFrames = make(map[uint64]func([]byte) (int, error))
+	}
+	p.registeredFrames[frameType] = parse
+}
+
+// IsKnownFrameType says if the frame type is defined by RFC 9000, or by one of the extensions we implement.
+func IsKnownFrameType(frameType uint64) bool {
+	switch {
+	case frameType <= 0x1f: // RFC 9000 and IMMEDIATE_ACK
+		return true
+	case frameType == ackFrequencyFrameType: // ACK_FREQUENCY
+		return true
+	case frameType == 0x24: // RESET_STREAM_AT
+		return true
+	case frameType == 0x30 || frameType == 0x31: // DATAGRAM
+		return true
+	default:
+		return false
+	}
+}
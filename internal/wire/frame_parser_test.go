@@ -1,19 +1,51 @@
 package wire
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
 	"time"
 
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/qerr"
+	"github.com/fkwhite/quic-go/quicvarint"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+const experimentalFrameType = 0x2f5a8b
+
+// experimentalFrame is a frame that is not handled by the frame parser
+type experimentalFrame struct {
+	Value uint64
+}
+
+func (f *experimentalFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
+	b = quicvarint.Append(b, experimentalFrameType)
+	return quicvarint.Append(b, f.Value), nil
+}
+
+func (f *experimentalFrame) Length(protocol.VersionNumber) protocol.ByteCount {
+	return protocol.ByteCount(quicvarint.Len(experimentalFrameType) + quicvarint.Len(f.Value))
+}
+
+func parseExperimentalFrame(b []byte) (int, error) {
+	r := bytes.NewReader(b)
+	if _, err := quicvarint.Read(r); err != nil {
+		return 0, err
+	}
+	if _, err := quicvarint.Read(r); err != nil {
+		return 0, err
+	}
+	return len(b) - r.Len(), nil
+}
+
 var _ = Describe("Frame parsing", func() {
 	var parser FrameParser
 
 	BeforeEach(func() {
-		parser = NewFrameParser(true, protocol.Version1)
+		parser = NewFrameParserWithOptions(FrameParserOptions{SupportsDatagrams: true, SupportsResetStreamAt: true, SupportsAckFrequency: true}, protocol.Version1)
 	})
 
 	It("returns nil if there's nothing more to read", func() {
@@ -68,14 +100,15 @@ var _ = Describe("Frame parsing", func() {
 	It("uses the custom ack delay exponent for 1RTT packets", func() {
 		parser.SetAckDelayExponent(protocol.AckDelayExponent + 2)
 		f := &AckFrame{
-			AckRanges: []AckRange{{Smallest: 1, Largest: 1}},
-			DelayTime: time.Second,
+			AckRanges:        []AckRange{{Smallest: 1, Largest: 1}},
+			DelayTime:        time.Second,
+			AckDelayExponent: protocol.AckDelayExponent,
 		}
 		b, err := f.Append(nil, protocol.Version1)
 		Expect(err).ToNot(HaveOccurred())
 		_, frame, err := parser.ParseNext(b, protocol.Encryption1RTT)
 		Expect(err).ToNot(HaveOccurred())
-		// The ACK frame is always written using the protocol.AckDelayExponent.
+		// The ACK frame was written using the protocol.AckDelayExponent.
 		// That's why we expect a different value when parsing.
 		Expect(frame.(*AckFrame).DelayTime).To(Equal(4 * time.Second))
 	})
@@ -83,8 +116,9 @@ var _ = Describe("Frame parsing", func() {
 	It("uses the default ack delay exponent for non-1RTT packets", func() {
 		parser.SetAckDelayExponent(protocol.AckDelayExponent + 2)
 		f := &AckFrame{
-			AckRanges: []AckRange{{Smallest: 1, Largest: 1}},
-			DelayTime: time.Second,
+			AckRanges:        []AckRange{{Smallest: 1, Largest: 1}},
+			DelayTime:        time.Second,
+			AckDelayExponent: protocol.AckDelayExponent,
 		}
 		b, err := f.Append(nil, protocol.Version1)
 		Expect(err).ToNot(HaveOccurred())
@@ -170,6 +204,23 @@ var _ = Describe("Frame parsing", func() {
 		Expect(l).To(Equal(len(b)))
 	})
 
+	It("releases frames", func() {
+		b, err := (&AckFrame{AckRanges: []AckRange{{Smallest: 1, Largest: 42}}}).Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		b, err = (&MaxDataFrame{MaximumData: 0xcafe}).Append(b, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		for len(b) > 0 {
+			l, frame, err := parser.ParseNext(b, protocol.Encryption1RTT)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).ToNot(BeNil())
+			parser.Release(frame)
+			b = b[l:]
+		}
+		// the frames obtained from the pool are reset
+		Expect(getMaxDataFrame().MaximumData).To(BeZero())
+		Expect(GetAckFrame().AckRanges).To(BeEmpty())
+	})
+
 	It("unpacks MAX_STREAM_DATA frames", func() {
 		f := &MaxStreamDataFrame{
 			StreamID:          0xdeadbeef,
@@ -303,6 +354,94 @@ var _ = Describe("Frame parsing", func() {
 		Expect(l).To(Equal(len(b)))
 	})
 
+	It("unpacks IMMEDIATE_ACK frames", func() {
+		f := &ImmediateAckFrame{}
+		b, err := f.Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		l, frame, err := parser.ParseNext(b, protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+		Expect(l).To(Equal(len(b)))
+	})
+
+	It("errors when IMMEDIATE_ACK frames are not supported", func() {
+		parser = NewFrameParserWithOptions(FrameParserOptions{SupportsDatagrams: true, SupportsResetStreamAt: true}, protocol.Version1)
+		b, err := (&ImmediateAckFrame{}).Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		_, _, err = parser.ParseNext(b, protocol.Encryption1RTT)
+		Expect(err).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.FrameEncodingError,
+			FrameType:    0x1f,
+			ErrorMessage: "unknown frame type",
+		}))
+	})
+
+	It("unpacks ACK_FREQUENCY frames", func() {
+		f := &AckFrequencyFrame{
+			SequenceNumber:        1,
+			AckElicitingThreshold: 10,
+			RequestMaxAckDelay:    20 * time.Millisecond,
+			ReorderingThreshold:   1,
+		}
+		b, err := f.Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		l, frame, err := parser.ParseNext(b, protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+		Expect(l).To(Equal(len(b)))
+	})
+
+	It("errors when ACK_FREQUENCY frames are not supported", func() {
+		parser = NewFrameParserWithOptions(FrameParserOptions{SupportsDatagrams: true, SupportsResetStreamAt: true}, protocol.Version1)
+		b, err := (&AckFrequencyFrame{}).Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		_, _, err = parser.ParseNext(b, protocol.Encryption1RTT)
+		Expect(err).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.FrameEncodingError,
+			FrameType:    0xaf,
+			ErrorMessage: "unknown frame type",
+		}))
+	})
+
+	It("rejects ACK_FREQUENCY frames in Handshake packets", func() {
+		b, err := (&AckFrequencyFrame{}).Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		_, _, err = parser.ParseNext(b, protocol.EncryptionHandshake)
+		Expect(err).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.FrameEncodingError,
+			FrameType:    0xaf,
+			ErrorMessage: "AckFrequencyFrame not allowed at encryption level Handshake",
+		}))
+	})
+
+	It("unpacks RESET_STREAM_AT frames", func() {
+		f := &ResetStreamAtFrame{
+			StreamID:     0xdeadbeef,
+			FinalSize:    0xdecafbad1234,
+			ReliableSize: 0x1337,
+			ErrorCode:    0x1234,
+		}
+		b, err := f.Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		l, frame, err := parser.ParseNext(b, protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+		Expect(l).To(Equal(len(b)))
+	})
+
+	It("errors when RESET_STREAM_AT frames are not supported", func() {
+		parser = NewFrameParserWithOptions(FrameParserOptions{SupportsDatagrams: true, SupportsAckFrequency: true}, protocol.Version1)
+		f := &ResetStreamAtFrame{StreamID: 0x1337}
+		b, err := f.Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		_, _, err = parser.ParseNext(b, protocol.Encryption1RTT)
+		Expect(err).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.FrameEncodingError,
+			FrameType:    0x24,
+			ErrorMessage: "unknown frame type",
+		}))
+	})
+
 	It("unpacks DATAGRAM frames", func() {
 		f := &DatagramFrame{Data: []byte("foobar")}
 		b, err := f.Append(nil, protocol.Version1)
@@ -327,7 +466,7 @@ var _ = Describe("Frame parsing", func() {
 	})
 
 	It("errors on invalid type", func() {
-		_, _, err := parser.ParseNext([]byte{0x42}, protocol.Encryption1RTT)
+		_, _, err := parser.ParseNext(quicvarint.Append(nil, 0x42), protocol.Encryption1RTT)
 		Expect(err).To(MatchError(&qerr.TransportError{
 			ErrorCode:    qerr.FrameEncodingError,
 			FrameType:    0x42,
@@ -335,6 +474,110 @@ var _ = Describe("Frame parsing", func() {
 		}))
 	})
 
+	It("errors when the frame type is truncated", func() {
+		_, _, err := parser.ParseNext([]byte{0x42}, protocol.Encryption1RTT)
+		Expect(err).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.FrameEncodingError,
+			FrameType:    0x42,
+			ErrorMessage: io.EOF.Error(),
+		}))
+	})
+
+	Context("extension frames", func() {
+		It("errors on frames that were not registered", func() {
+			b, err := (&experimentalFrame{Value: 0xdecafbad}).Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			_, _, err = parser.ParseNext(b, protocol.Encryption1RTT)
+			Expect(err).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.FrameEncodingError,
+				FrameType:    experimentalFrameType,
+				ErrorMessage: "unknown frame type",
+			}))
+		})
+
+		It("refuses to register frame types handled by the frame parser", func() {
+			for _, frameType := range []uint64{0x1, 0x1f, 0x24, 0x30, 0x31, 0xaf} {
+				Expect(func() { parser.RegisterFrameType(frameType, parseExperimentalFrame) }).To(Panic())
+			}
+		})
+
+		It("refuses to register a frame type twice", func() {
+			parser.RegisterFrameType(experimentalFrameType, parseExperimentalFrame)
+			Expect(func() { parser.RegisterFrameType(experimentalFrameType, parseExperimentalFrame) }).To(Panic())
+		})
+
+		Context("registered frame types", func() {
+			BeforeEach(func() {
+				parser.RegisterFrameType(experimentalFrameType, parseExperimentalFrame)
+			})
+
+			It("parses registered frames", func() {
+				b, err := (&experimentalFrame{Value: 0xdecafbad}).Append(nil, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				frameLen := len(b)
+				b, err = (&MaxDataFrame{MaximumData: 0x1234}).Append(b, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				l, frame, err := parser.ParseNext(b, protocol.Encryption1RTT)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(&ExtensionFrame{FrameType: experimentalFrameType, Data: b[:frameLen]}))
+				Expect(FrameType(frame)).To(BeEquivalentTo(experimentalFrameType))
+				Expect(l).To(Equal(frameLen))
+				l, frame, err = parser.ParseNext(b[l:], protocol.Encryption1RTT)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(&MaxDataFrame{MaximumData: 0x1234}))
+				Expect(l).To(Equal(len(b) - frameLen))
+			})
+
+			It("parses registered frames after PADDING", func() {
+				b, err := (&experimentalFrame{Value: 42}).Append([]byte{0, 0}, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				l, frame, err := parser.ParseNext(b, protocol.Encryption0RTT)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(&ExtensionFrame{FrameType: experimentalFrameType, Data: b[2:]}))
+				Expect(l).To(Equal(len(b)))
+			})
+
+			It("errors when the registered parser fails", func() {
+				b, err := (&experimentalFrame{Value: 0xdecafbad}).Append(nil, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				_, _, err = parser.ParseNext(b[:len(b)-1], protocol.Encryption1RTT)
+				Expect(err).To(MatchError(&qerr.TransportError{
+					ErrorCode:    qerr.FrameEncodingError,
+					FrameType:    experimentalFrameType,
+					ErrorMessage: io.EOF.Error(),
+				}))
+			})
+
+			It("errors when the registered parser returns an invalid length", func() {
+				parser = NewFrameParserWithOptions(FrameParserOptions{SupportsDatagrams: true, SupportsResetStreamAt: true, SupportsAckFrequency: true}, protocol.Version1)
+				parser.RegisterFrameType(experimentalFrameType, func(b []byte) (int, error) {
+					return len(b) + 1, nil
+				})
+				b, err := (&experimentalFrame{Value: 1}).Append(nil, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				_, _, err = parser.ParseNext(b, protocol.Encryption1RTT)
+				Expect(err).To(MatchError(&qerr.TransportError{
+					ErrorCode:    qerr.FrameEncodingError,
+					FrameType:    experimentalFrameType,
+					ErrorMessage: fmt.Sprintf("invalid length of frame type %#x: %d", experimentalFrameType, len(b)+1),
+				}))
+			})
+
+			It("rejects registered frames in Initial and Handshake packets", func() {
+				b, err := (&experimentalFrame{Value: 1}).Append(nil, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				for _, encLevel := range []protocol.EncryptionLevel{protocol.EncryptionInitial, protocol.EncryptionHandshake} {
+					_, _, err := parser.ParseNext(b, encLevel)
+					Expect(err).To(MatchError(&qerr.TransportError{
+						ErrorCode:    qerr.FrameEncodingError,
+						FrameType:    experimentalFrameType,
+						ErrorMessage: fmt.Sprintf("frame type %#x not allowed at encryption level %s", experimentalFrameType, encLevel),
+					}))
+				}
+			})
+		})
+	})
+
 	It("errors on invalid frames", func() {
 		f := &MaxStreamDataFrame{
 			StreamID:          0x1337,
@@ -347,11 +590,49 @@ var _ = Describe("Frame parsing", func() {
 		Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.FrameEncodingError))
 	})
 
+	It("determines the frame type", func() {
+		for _, f := range []Frame{
+			&PingFrame{},
+			&AckFrame{AckRanges: []AckRange{{Smallest: 1, Largest: 42}}},
+			&AckFrame{AckRanges: []AckRange{{Smallest: 1, Largest: 42}}, ECNCE: 1},
+			&ResetStreamFrame{},
+			&ResetStreamAtFrame{},
+			&StopSendingFrame{},
+			&CryptoFrame{},
+			&NewTokenFrame{Token: []byte("lorem ipsum")},
+			&StreamFrame{Data: []byte("foobar")},
+			&StreamFrame{Data: []byte("foobar"), Offset: 42, DataLenPresent: true, Fin: true},
+			&MaxDataFrame{},
+			&MaxStreamDataFrame{},
+			&MaxStreamsFrame{Type: protocol.StreamTypeBidi},
+			&MaxStreamsFrame{Type: protocol.StreamTypeUni},
+			&DataBlockedFrame{},
+			&StreamDataBlockedFrame{},
+			&StreamsBlockedFrame{Type: protocol.StreamTypeBidi},
+			&StreamsBlockedFrame{Type: protocol.StreamTypeUni},
+			&NewConnectionIDFrame{ConnectionID: protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef})},
+			&RetireConnectionIDFrame{},
+			&PathChallengeFrame{},
+			&PathResponseFrame{},
+			&ConnectionCloseFrame{},
+			&ConnectionCloseFrame{IsApplicationError: true},
+			&HandshakeDoneFrame{},
+			&ImmediateAckFrame{},
+			&DatagramFrame{},
+			&DatagramFrame{DataLenPresent: true},
+		} {
+			b, err := f.Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(FrameType(f)).To(BeEquivalentTo(b[0]))
+		}
+	})
+
 	Context("encryption level check", func() {
 		frames := []Frame{
 			&PingFrame{},
 			&AckFrame{AckRanges: []AckRange{{Smallest: 1, Largest: 42}}},
 			&ResetStreamFrame{},
+			&ResetStreamAtFrame{},
 			&StopSendingFrame{},
 			&CryptoFrame{},
 			&NewTokenFrame{Token: []byte("lorem ipsum")},
@@ -368,6 +649,7 @@ var _ = Describe("Frame parsing", func() {
 			&PathResponseFrame{},
 			&ConnectionCloseFrame{},
 			&HandshakeDoneFrame{},
+			&ImmediateAckFrame{},
 			&DatagramFrame{},
 		}
 
@@ -432,3 +714,41 @@ var _ = Describe("Frame parsing", func() {
 		})
 	})
 })
+
+func BenchmarkParseFrames(b *testing.B) {
+	var data []byte
+	for i := 0; i < 10; i++ {
+		var err error
+		data, err = (&AckFrame{AckRanges: []AckRange{{Smallest: 10, Largest: 42}, {Smallest: 1, Largest: 5}}}).Append(data, protocol.Version1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		data, err = (&MaxDataFrame{MaximumData: protocol.ByteCount(1337 * i)}).Append(data, protocol.Version1)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("without release", func(b *testing.B) { benchmarkParseFrames(b, data, false) })
+	b.Run("with release", func(b *testing.B) { benchmarkParseFrames(b, data, true) })
+}
+
+func benchmarkParseFrames(b *testing.B, data []byte, release bool) {
+	parser := NewFrameParser(false, protocol.Version1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d := data
+		for len(d) > 0 {
+			l, f, err := parser.ParseNext(d, protocol.Encryption1RTT)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if release {
+				parser.Release(f)
+			}
+			d = d[l:]
+		}
+	}
+}
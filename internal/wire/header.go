@@ -81,6 +81,16 @@ func IsLongHeaderPacket(firstByte byte) bool {
 	return firstByte&0x80 > 0
 }
 
+// IsPotentialQUICPacket says if a packet can be a QUIC packet, based on the first bytes of the packet.
+// With the exception of Version Negotiation packets, all QUIC packets have the fixed bit set.
+// This allows demultiplexing QUIC and other protocols (e.g. STUN) on the same UDP socket, see RFC 9443.
+func IsPotentialQUICPacket(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	return b[0]&0x40 > 0 || IsVersionNegotiationPacket(b)
+}
+
 // ParseVersion parses the QUIC version.
 // It should only be called for Long Header packets (Short Header packets don't contain a version number).
 func ParseVersion(data []byte) (protocol.VersionNumber, error) {
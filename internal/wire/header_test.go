@@ -193,6 +193,15 @@ var _ = Describe("Header Parsing", func() {
 			Expect(IsVersionNegotiationPacket([]byte{0x80, 0, 0, 0, 1})).To(BeFalse())
 		})
 
+		It("recognizes potential QUIC packets", func() {
+			Expect(IsPotentialQUICPacket([]byte{0x40, 1, 2, 3})).To(BeTrue())     // short header
+			Expect(IsPotentialQUICPacket([]byte{0xc0, 1, 2, 3, 4})).To(BeTrue())  // long header
+			Expect(IsPotentialQUICPacket([]byte{0x80, 0, 0, 0, 0})).To(BeTrue())  // Version Negotiation packet
+			Expect(IsPotentialQUICPacket([]byte{0x00, 0x01, 0, 0})).To(BeFalse()) // STUN
+			Expect(IsPotentialQUICPacket([]byte{0x80, 1, 2, 3, 4})).To(BeFalse()) // RTP
+			Expect(IsPotentialQUICPacket([]byte{})).To(BeFalse())
+		})
+
 		It("returns false on EOF", func() {
 			vnp := []byte{0x80, 0, 0, 0, 0}
 			for i := range vnp {
@@ -0,0 +1,27 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+)
+
+// An ImmediateAckFrame is an IMMEDIATE_ACK frame, as defined in draft-ietf-quic-ack-frequency.
+// It asks the peer to send an ACK frame immediately.
+type ImmediateAckFrame struct{}
+
+func parseImmediateAckFrame(r *bytes.Reader, _ protocol.VersionNumber) (*ImmediateAckFrame, error) {
+	if _, err := r.ReadByte(); err != nil {
+		return nil, err
+	}
+	return &ImmediateAckFrame{}, nil
+}
+
+func (f *ImmediateAckFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
+	return append(b, 0x1f), nil
+}
+
+// Length of a written frame
+func (f *ImmediateAckFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
+	return 1
+}
@@ -0,0 +1,39 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IMMEDIATE_ACK frame", func() {
+	Context("when parsing", func() {
+		It("accepts sample frame", func() {
+			b := bytes.NewReader([]byte{0x1f})
+			_, err := parseImmediateAckFrame(b, protocol.VersionWhatever)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors on EOFs", func() {
+			_, err := parseImmediateAckFrame(bytes.NewReader(nil), protocol.VersionWhatever)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes a sample frame", func() {
+			frame := ImmediateAckFrame{}
+			b, err := frame.Append(nil, protocol.VersionWhatever)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte{0x1f}))
+		})
+
+		It("has the correct length", func() {
+			frame := ImmediateAckFrame{}
+			Expect(frame.Length(protocol.VersionWhatever)).To(Equal(protocol.ByteCount(1)))
+		})
+	})
+})
@@ -13,5 +13,97 @@ type Frame interface {
 // A FrameParser parses QUIC frames, one by one.
 type FrameParser interface {
 	ParseNext([]byte, protocol.EncryptionLevel) (int, Frame, error)
+	// Release returns a frame to the pool, once it was handled.
+	// STREAM frames are returned to the pool using StreamFrame.PutBack instead,
+	// since their data is still used after the frame was handled.
+	Release(Frame)
 	SetAckDelayExponent(uint8)
+	// RegisterFrameType registers a frame type that is not handled by the frame parser,
+	// e.g. a frame type defined by an extension, or used for experiments.
+	// parse is passed the packet data starting at the frame type, and returns the length of the frame.
+	// ParseNext returns these frames as an ExtensionFrame.
+	// Frame types 0x00 - 0x3f are assigned by Standards Action or IESG Approval (see Section 22.4 of RFC 9000).
+	// Only large frame types that are not registered with IANA are safe to use for experiments.
+	RegisterFrameType(frameType uint64, parse func([]byte) (int, error))
+}
+
+// FrameType returns the frame type of a frame, as it is encoded on the wire.
+func FrameType(f Frame) uint64 {
+	switch f := f.(type) {
+	case *PingFrame:
+		return 0x1
+	case *AckFrame:
+		if f.ECT0 > 0 || f.ECT1 > 0 || f.ECNCE > 0 {
+			return 0x3
+		}
+		return 0x2
+	case *ResetStreamFrame:
+		return 0x4
+	case *StopSendingFrame:
+		return 0x5
+	case *CryptoFrame:
+		return 0x6
+	case *NewTokenFrame:
+		return 0x7
+	case *StreamFrame:
+		typ := uint64(0x8)
+		if f.Fin {
+			typ ^= 0b1
+		}
+		if f.DataLenPresent {
+			typ ^= 0b10
+		}
+		if f.Offset != 0 {
+			typ ^= 0b100
+		}
+		return typ
+	case *MaxDataFrame:
+		return 0x10
+	case *MaxStreamDataFrame:
+		return 0x11
+	case *MaxStreamsFrame:
+		if f.Type == protocol.StreamTypeUni {
+			return 0x13
+		}
+		return 0x12
+	case *DataBlockedFrame:
+		return 0x14
+	case *StreamDataBlockedFrame:
+		return 0x15
+	case *StreamsBlockedFrame:
+		if f.Type == protocol.StreamTypeUni {
+			return 0x17
+		}
+		return 0x16
+	case *NewConnectionIDFrame:
+		return 0x18
+	case *RetireConnectionIDFrame:
+		return 0x19
+	case *PathChallengeFrame:
+		return 0x1a
+	case *PathResponseFrame:
+		return 0x1b
+	case *ConnectionCloseFrame:
+		if f.IsApplicationError {
+			return 0x1d
+		}
+		return 0x1c
+	case *HandshakeDoneFrame:
+		return 0x1e
+	case *ImmediateAckFrame:
+		return 0x1f
+	case *AckFrequencyFrame:
+		return ackFrequencyFrameType
+	case *ExtensionFrame:
+		return f.FrameType
+	case *ResetStreamAtFrame:
+		return 0x24
+	case *DatagramFrame:
+		if f.DataLenPresent {
+			return 0x31
+		}
+		return 0x30
+	default:
+		return 0
+	}
 }
@@ -18,7 +18,7 @@ func parseMaxDataFrame(r *bytes.Reader, _ protocol.VersionNumber) (*MaxDataFrame
 		return nil, err
 	}
 
-	frame := &MaxDataFrame{}
+	frame := getMaxDataFrame()
 	byteOffset, err := quicvarint.Read(r)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,17 @@
+package wire
+
+import "sync"
+
+var maxDataFramePool = sync.Pool{New: func() any {
+	return &MaxDataFrame{}
+}}
+
+func getMaxDataFrame() *MaxDataFrame {
+	f := maxDataFramePool.Get().(*MaxDataFrame)
+	f.MaximumData = 0
+	return f
+}
+
+func putMaxDataFrame(f *MaxDataFrame) {
+	maxDataFramePool.Put(f)
+}
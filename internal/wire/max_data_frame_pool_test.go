@@ -0,0 +1,18 @@
+package wire
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MAX_DATA frame pool", func() {
+	It("gets a MAX_DATA frame from the pool", func() {
+		for i := 0; i < 100; i++ {
+			f := getMaxDataFrame()
+			Expect(f.MaximumData).To(BeZero())
+
+			f.MaximumData = 1337
+			putMaxDataFrame(f)
+		}
+	})
+})
@@ -22,6 +22,16 @@ func GetStreamFrame() *StreamFrame {
 	return f
 }
 
+// GetStreamFrameWithSize returns a StreamFrame that can hold size bytes of data.
+// Frames that don't fit into a protocol.MaxPacketBufferSize packet are only used with large packets.
+// They are allocated, and not returned to the pool.
+func GetStreamFrameWithSize(size protocol.ByteCount) *StreamFrame {
+	if size > protocol.MaxPacketBufferSize {
+		return &StreamFrame{Data: make([]byte, 0, size)}
+	}
+	return GetStreamFrame()
+}
+
 func putStreamFrame(f *StreamFrame) {
 	if !f.fromPool {
 		return
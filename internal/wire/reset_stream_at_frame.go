@@ -0,0 +1,66 @@
+package wire
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/fkwhite/quic-go/internal/qerr"
+	"github.com/fkwhite/quic-go/quicvarint"
+)
+
+// A ResetStreamAtFrame is a RESET_STREAM_AT frame, as defined in draft-ietf-quic-reliable-stream-reset.
+// It resets a stream, but obliges the sender to reliably deliver all data up to ReliableSize.
+type ResetStreamAtFrame struct {
+	StreamID     protocol.StreamID
+	ErrorCode    qerr.StreamErrorCode
+	FinalSize    protocol.ByteCount
+	ReliableSize protocol.ByteCount
+}
+
+func parseResetStreamAtFrame(r *bytes.Reader, _ protocol.VersionNumber) (*ResetStreamAtFrame, error) {
+	if _, err := r.ReadByte(); err != nil { // read the TypeByte
+		return nil, err
+	}
+
+	sid, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	errorCode, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	finalSize, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	reliableSize, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if reliableSize > finalSize {
+		return nil, fmt.Errorf("RESET_STREAM_AT: reliable size (%d) larger than final size (%d)", reliableSize, finalSize)
+	}
+
+	return &ResetStreamAtFrame{
+		StreamID:     protocol.StreamID(sid),
+		ErrorCode:    qerr.StreamErrorCode(errorCode),
+		FinalSize:    protocol.ByteCount(finalSize),
+		ReliableSize: protocol.ByteCount(reliableSize),
+	}, nil
+}
+
+func (f *ResetStreamAtFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
+	b = append(b, 0x24)
+	b = quicvarint.Append(b, uint64(f.StreamID))
+	b = quicvarint.Append(b, uint64(f.ErrorCode))
+	b = quicvarint.Append(b, uint64(f.FinalSize))
+	b = quicvarint.Append(b, uint64(f.ReliableSize))
+	return b, nil
+}
+
+// Length of a written frame
+func (f *ResetStreamAtFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
+	return 1 + quicvarint.Len(uint64(f.StreamID)) + quicvarint.Len(uint64(f.ErrorCode)) + quicvarint.Len(uint64(f.FinalSize)) + quicvarint.Len(uint64(f.ReliableSize))
+}
@@ -0,0 +1,86 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/fkwhite/quic-go/internal/qerr"
+	"github.com/fkwhite/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RESET_STREAM_AT frame", func() {
+	Context("when parsing", func() {
+		It("accepts sample frame", func() {
+			data := []byte{0x24}
+			data = append(data, encodeVarInt(0xdeadbeef)...)  // stream ID
+			data = append(data, encodeVarInt(0x1337)...)      // error code
+			data = append(data, encodeVarInt(0x987654321)...) // final size
+			data = append(data, encodeVarInt(0x12345)...)     // reliable size
+			b := bytes.NewReader(data)
+			frame, err := parseResetStreamAtFrame(b, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.StreamID).To(Equal(protocol.StreamID(0xdeadbeef)))
+			Expect(frame.FinalSize).To(Equal(protocol.ByteCount(0x987654321)))
+			Expect(frame.ReliableSize).To(Equal(protocol.ByteCount(0x12345)))
+			Expect(frame.ErrorCode).To(Equal(qerr.StreamErrorCode(0x1337)))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors when the reliable size is larger than the final size", func() {
+			data := []byte{0x24}
+			data = append(data, encodeVarInt(0xdeadbeef)...) // stream ID
+			data = append(data, encodeVarInt(0x1337)...)     // error code
+			data = append(data, encodeVarInt(100)...)        // final size
+			data = append(data, encodeVarInt(101)...)        // reliable size
+			_, err := parseResetStreamAtFrame(bytes.NewReader(data), protocol.Version1)
+			Expect(err).To(MatchError("RESET_STREAM_AT: reliable size (101) larger than final size (100)"))
+		})
+
+		It("errors on EOFs", func() {
+			data := []byte{0x24}
+			data = append(data, encodeVarInt(0xdeadbeef)...)  // stream ID
+			data = append(data, encodeVarInt(0x1337)...)      // error code
+			data = append(data, encodeVarInt(0x987654321)...) // final size
+			data = append(data, encodeVarInt(0x12345)...)     // reliable size
+			_, err := parseResetStreamAtFrame(bytes.NewReader(data), protocol.Version1)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parseResetStreamAtFrame(bytes.NewReader(data[0:i]), protocol.Version1)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes a sample frame", func() {
+			frame := ResetStreamAtFrame{
+				StreamID:     0x1337,
+				FinalSize:    0x11223344decafbad,
+				ReliableSize: 0x1234,
+				ErrorCode:    0xcafe,
+			}
+			b, err := frame.Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			expected := []byte{0x24}
+			expected = append(expected, encodeVarInt(0x1337)...)
+			expected = append(expected, encodeVarInt(0xcafe)...)
+			expected = append(expected, encodeVarInt(0x11223344decafbad)...)
+			expected = append(expected, encodeVarInt(0x1234)...)
+			Expect(b).To(Equal(expected))
+		})
+
+		It("has the correct length", func() {
+			rst := ResetStreamAtFrame{
+				StreamID:     0x1337,
+				FinalSize:    0x1234567,
+				ReliableSize: 0x42,
+				ErrorCode:    0xde,
+			}
+			expectedLen := 1 + quicvarint.Len(0x1337) + quicvarint.Len(0x1234567) + quicvarint.Len(0x42) + 2
+			Expect(rst.Length(protocol.Version1)).To(Equal(expectedLen))
+		})
+	})
+})
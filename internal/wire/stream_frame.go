@@ -58,12 +58,12 @@ func parseStreamFrame(r *bytes.Reader, _ protocol.VersionNumber) (*StreamFrame,
 	if dataLen < protocol.MinStreamFrameBufferSize {
 		frame = &StreamFrame{Data: make([]byte, dataLen)}
 	} else {
-		frame = GetStreamFrame()
-		// The STREAM frame can't be larger than the StreamFrame we obtained from the buffer,
-		// since those StreamFrames have a buffer length of the maximum packet size.
-		if dataLen > uint64(cap(frame.Data)) {
+		// The STREAM frame can't be larger than the rest of the packet.
+		// Checking this before obtaining the buffer prevents large allocations.
+		if dataLen > uint64(r.Len()) {
 			return nil, io.EOF
 		}
+		frame = GetStreamFrameWithSize(protocol.ByteCount(dataLen))
 		frame.Data = frame.Data[:dataLen]
 	}
 
@@ -124,6 +124,12 @@ func (f *StreamFrame) Length(version protocol.VersionNumber) protocol.ByteCount
 	return length + f.DataLen()
 }
 
+// IsFinOnly says if the frame only carries the FIN bit, without any data.
+// Such a frame signals the final size of the stream, which is its offset.
+func (f *StreamFrame) IsFinOnly() bool {
+	return f.Fin && len(f.Data) == 0
+}
+
 // DataLen gives the length of data in bytes
 func (f *StreamFrame) DataLen() protocol.ByteCount {
 	return protocol.ByteCount(len(f.Data))
@@ -166,7 +172,8 @@ func (f *StreamFrame) MaybeSplitOffFrame(maxSize protocol.ByteCount, version pro
 		return nil, true
 	}
 
-	new := GetStreamFrame()
+	// After swapping the data slices, the remaining data needs to fit into the buffer of the new frame.
+	new := GetStreamFrameWithSize(protocol.ByteCount(len(f.Data)) - n)
 	new.StreamID = f.StreamID
 	new.Offset = f.Offset
 	new.Fin = false
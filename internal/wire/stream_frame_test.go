@@ -68,6 +68,39 @@ var _ = Describe("STREAM frame", func() {
 			Expect(f.Offset).To(Equal(protocol.ByteCount(0x12345)))
 			Expect(f.Data).To(BeEmpty())
 			Expect(f.Fin).To(BeFalse())
+			Expect(f.IsFinOnly()).To(BeFalse())
+		})
+
+		It("parses a FIN-only frame at an offset", func() {
+			data := []byte{0x8 ^ 0x4 ^ 0x1}
+			data = append(data, encodeVarInt(0x1337)...)  // stream ID
+			data = append(data, encodeVarInt(0x12345)...) // offset
+			r := bytes.NewReader(data)
+			f, err := parseStreamFrame(r, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.StreamID).To(Equal(protocol.StreamID(0x1337)))
+			Expect(f.Offset).To(Equal(protocol.ByteCount(0x12345)))
+			Expect(f.Data).To(BeEmpty())
+			Expect(f.Fin).To(BeTrue())
+			Expect(f.IsFinOnly()).To(BeTrue())
+			Expect(r.Len()).To(BeZero())
+		})
+
+		It("parses a FIN-only frame with a zero data length", func() {
+			data := []byte{0x8 ^ 0x4 ^ 0x2 ^ 0x1}
+			data = append(data, encodeVarInt(0x1337)...)  // stream ID
+			data = append(data, encodeVarInt(0x12345)...) // offset
+			data = append(data, encodeVarInt(0)...)       // data length
+			data = append(data, []byte("foobar")...)      // the next frame
+			r := bytes.NewReader(data)
+			f, err := parseStreamFrame(r, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.StreamID).To(Equal(protocol.StreamID(0x1337)))
+			Expect(f.Offset).To(Equal(protocol.ByteCount(0x12345)))
+			Expect(f.Data).To(BeEmpty())
+			Expect(f.DataLenPresent).To(BeTrue())
+			Expect(f.IsFinOnly()).To(BeTrue())
+			Expect(r.Len()).To(Equal(6))
 		})
 
 		It("rejects frames that overflow the maximum offset", func() {
@@ -82,14 +115,27 @@ var _ = Describe("STREAM frame", func() {
 
 		It("rejects frames that claim to be longer than the packet size", func() {
 			data := []byte{0x8 ^ 0x2}
-			data = append(data, encodeVarInt(0x12345)...)                                // stream ID
-			data = append(data, encodeVarInt(uint64(protocol.MaxPacketBufferSize)+1)...) // data length
-			data = append(data, make([]byte, protocol.MaxPacketBufferSize+1)...)
+			data = append(data, encodeVarInt(0x12345)...) // stream ID
+			data = append(data, encodeVarInt(1<<30)...)   // data length
+			data = append(data, make([]byte, protocol.MaxPacketBufferSize)...)
 			r := bytes.NewReader(data)
 			_, err := parseStreamFrame(r, protocol.Version1)
 			Expect(err).To(Equal(io.EOF))
 		})
 
+		It("parses frames that are larger than the maximum packet buffer size", func() {
+			data := []byte{0x8 ^ 0x2}
+			data = append(data, encodeVarInt(0x12345)...)                                // stream ID
+			data = append(data, encodeVarInt(uint64(protocol.MaxPacketBufferSize)+1)...) // data length
+			data = append(data, bytes.Repeat([]byte{'f'}, int(protocol.MaxPacketBufferSize)+1)...)
+			r := bytes.NewReader(data)
+			frame, err := parseStreamFrame(r, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.Data).To(Equal(bytes.Repeat([]byte{'f'}, int(protocol.MaxPacketBufferSize)+1)))
+			Expect(r.Len()).To(BeZero())
+			frame.PutBack() // frames that don't fit into the pool are not returned to it
+		})
+
 		It("errors on EOFs", func() {
 			data := []byte{0x8 ^ 0x4 ^ 0x2}
 			data = append(data, encodeVarInt(0x12345)...)    // stream ID
@@ -103,6 +149,19 @@ var _ = Describe("STREAM frame", func() {
 				Expect(err).To(HaveOccurred())
 			}
 		})
+
+		It("errors on EOFs, for FIN-only frames", func() {
+			data := []byte{0x8 ^ 0x4 ^ 0x2 ^ 0x1}
+			data = append(data, encodeVarInt(0x12345)...)    // stream ID
+			data = append(data, encodeVarInt(0xdecafbad)...) // offset
+			data = append(data, encodeVarInt(0)...)          // data length
+			_, err := parseStreamFrame(bytes.NewReader(data), protocol.Version1)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parseStreamFrame(bytes.NewReader(data[0:i]), protocol.Version1)
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
 	})
 
 	Context("using the buffer", func() {
@@ -214,6 +273,28 @@ var _ = Describe("STREAM frame", func() {
 			Expect(b).To(Equal(expected))
 		})
 
+		It("writes a FIN-only frame that can be parsed again", func() {
+			for _, dataLenPresent := range []bool{false, true} {
+				f := &StreamFrame{
+					StreamID:       0x1337,
+					Offset:         0x123456,
+					Fin:            true,
+					DataLenPresent: dataLenPresent,
+				}
+				b, err := f.Append(nil, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).To(HaveLen(int(f.Length(protocol.Version1))))
+				r := bytes.NewReader(b)
+				frame, err := parseStreamFrame(r, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.StreamID).To(Equal(f.StreamID))
+				Expect(frame.Offset).To(Equal(f.Offset))
+				Expect(frame.DataLenPresent).To(Equal(dataLenPresent))
+				Expect(frame.IsFinOnly()).To(BeTrue())
+				Expect(r.Len()).To(BeZero())
+			}
+		})
+
 		It("refuses to write an empty frame without FIN", func() {
 			f := &StreamFrame{
 				StreamID: 0x42,
@@ -361,6 +442,19 @@ var _ = Describe("STREAM frame", func() {
 			Expect(f.Data).To(Equal([]byte("bar")))
 		})
 
+		It("splits frames that are larger than the maximum packet buffer size", func() {
+			data := bytes.Repeat([]byte("foobar"), 1000)
+			f := &StreamFrame{
+				StreamID: 0x1337,
+				Data:     append([]byte{}, data...),
+			}
+			frame, needsSplit := f.MaybeSplitOffFrame(1000, protocol.Version1)
+			Expect(needsSplit).To(BeTrue())
+			Expect(frame).ToNot(BeNil())
+			Expect(append(frame.Data, f.Data...)).To(Equal(data))
+			Expect(f.Offset).To(Equal(frame.DataLen()))
+		})
+
 		It("preserves the FIN bit", func() {
 			f := &StreamFrame{
 				StreamID: 0x1337,
@@ -42,6 +42,7 @@ var _ = Describe("Transport Parameters", func() {
 
 	It("has a string representation", func() {
 		rcid := protocol.ParseConnectionID([]byte{0xde, 0xad, 0xc0, 0xde})
+		minAckDelay := 2 * time.Millisecond
 		p := &TransportParameters{
 			InitialMaxStreamDataBidiLocal:   1234,
 			InitialMaxStreamDataBidiRemote:  2345,
@@ -58,8 +59,10 @@ var _ = Describe("Transport Parameters", func() {
 			StatelessResetToken:             &protocol.StatelessResetToken{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff, 0x00},
 			ActiveConnectionIDLimit:         123,
 			MaxDatagramFrameSize:            876,
+			EnableResetStreamAt:             true,
+			MinAckDelay:                     &minAckDelay,
 		}
-		Expect(p.String()).To(Equal("&wire.TransportParameters{OriginalDestinationConnectionID: deadbeef, InitialSourceConnectionID: decafbad, RetrySourceConnectionID: deadc0de, InitialMaxStreamDataBidiLocal: 1234, InitialMaxStreamDataBidiRemote: 2345, InitialMaxStreamDataUni: 3456, InitialMaxData: 4567, MaxBidiStreamNum: 1337, MaxUniStreamNum: 7331, MaxIdleTimeout: 42s, AckDelayExponent: 14, MaxAckDelay: 37ms, ActiveConnectionIDLimit: 123, StatelessResetToken: 0x112233445566778899aabbccddeeff00, MaxDatagramFrameSize: 876}"))
+		Expect(p.String()).To(Equal("&wire.TransportParameters{OriginalDestinationConnectionID: deadbeef, InitialSourceConnectionID: decafbad, RetrySourceConnectionID: deadc0de, InitialMaxStreamDataBidiLocal: 1234, InitialMaxStreamDataBidiRemote: 2345, InitialMaxStreamDataUni: 3456, InitialMaxData: 4567, MaxBidiStreamNum: 1337, MaxUniStreamNum: 7331, MaxIdleTimeout: 42s, AckDelayExponent: 14, MaxAckDelay: 37ms, ActiveConnectionIDLimit: 123, StatelessResetToken: 0x112233445566778899aabbccddeeff00, MaxDatagramFrameSize: 876, EnableResetStreamAt: true, MinAckDelay: 2ms}"))
 	})
 
 	It("has a string representation, if there's no stateless reset token, no Retry source connection id and no datagram support", func() {
@@ -85,6 +88,7 @@ var _ = Describe("Transport Parameters", func() {
 		var token protocol.StatelessResetToken
 		rand.Read(token[:])
 		rcid := protocol.ParseConnectionID([]byte{0xde, 0xad, 0xc0, 0xde})
+		minAckDelay := 1337 * time.Microsecond
 		params := &TransportParameters{
 			InitialMaxStreamDataBidiLocal:   protocol.ByteCount(getRandomValue()),
 			InitialMaxStreamDataBidiRemote:  protocol.ByteCount(getRandomValue()),
@@ -102,6 +106,8 @@ var _ = Describe("Transport Parameters", func() {
 			MaxAckDelay:                     42 * time.Millisecond,
 			ActiveConnectionIDLimit:         getRandomValue(),
 			MaxDatagramFrameSize:            protocol.ByteCount(getRandomValue()),
+			EnableResetStreamAt:             true,
+			MinAckDelay:                     &minAckDelay,
 		}
 		data := params.Marshal(protocol.PerspectiveServer)
 
@@ -123,6 +129,8 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.MaxAckDelay).To(Equal(42 * time.Millisecond))
 		Expect(p.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
 		Expect(p.MaxDatagramFrameSize).To(Equal(params.MaxDatagramFrameSize))
+		Expect(p.EnableResetStreamAt).To(BeTrue())
+		Expect(p.MinAckDelay).To(Equal(&minAckDelay))
 	})
 
 	It("doesn't marshal a retry_source_connection_id, if no Retry was performed", func() {
@@ -146,6 +154,26 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.RetrySourceConnectionID.Len()).To(BeZero())
 	})
 
+	It("marshals and unmarshals the max_datagram_frame_size", func() {
+		data := (&TransportParameters{
+			MaxDatagramFrameSize: 1337,
+			StatelessResetToken:  &protocol.StatelessResetToken{},
+		}).Marshal(protocol.PerspectiveServer)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MaxDatagramFrameSize).To(Equal(protocol.ByteCount(1337)))
+	})
+
+	It("doesn't send the max_datagram_frame_size, if datagrams are not supported", func() {
+		data := (&TransportParameters{
+			MaxDatagramFrameSize: protocol.InvalidByteCount,
+			StatelessResetToken:  &protocol.StatelessResetToken{},
+		}).Marshal(protocol.PerspectiveServer)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MaxDatagramFrameSize).To(Equal(protocol.InvalidByteCount))
+	})
+
 	It("errors when the stateless_reset_token has the wrong length", func() {
 		b := &bytes.Buffer{}
 		quicvarint.Write(b, uint64(statelessResetTokenParameterID))
@@ -168,6 +196,21 @@ var _ = Describe("Transport Parameters", func() {
 		}))
 	})
 
+	It("marshals the max_packet_size", func() {
+		data := (&TransportParameters{StatelessResetToken: &protocol.StatelessResetToken{}}).Marshal(protocol.PerspectiveServer)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MaxUDPPayloadSize).To(Equal(protocol.MaxPacketBufferSize))
+
+		data = (&TransportParameters{
+			MaxUDPPayloadSize:   9000,
+			StatelessResetToken: &protocol.StatelessResetToken{},
+		}).Marshal(protocol.PerspectiveServer)
+		p = &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MaxUDPPayloadSize).To(BeEquivalentTo(9000))
+	})
+
 	It("errors when disable_active_migration has content", func() {
 		b := &bytes.Buffer{}
 		quicvarint.Write(b, uint64(disableActiveMigrationParameterID))
@@ -179,6 +222,62 @@ var _ = Describe("Transport Parameters", func() {
 		}))
 	})
 
+	It("errors when reset_stream_at has content", func() {
+		b := &bytes.Buffer{}
+		quicvarint.Write(b, uint64(resetStreamAtParameterID))
+		quicvarint.Write(b, 6)
+		b.Write([]byte("foobar"))
+		Expect((&TransportParameters{}).Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.TransportParameterError,
+			ErrorMessage: "wrong length for reset_stream_at: 6 (expected empty)",
+		}))
+	})
+
+	It("doesn't send the min_ack_delay, if the ACK frequency extension is not supported", func() {
+		data := (&TransportParameters{StatelessResetToken: &protocol.StatelessResetToken{}}).Marshal(protocol.PerspectiveServer)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MinAckDelay).To(BeNil())
+	})
+
+	It("marshals and unmarshals the min_ack_delay", func() {
+		minAckDelay := 1500 * time.Microsecond
+		data := (&TransportParameters{
+			MaxAckDelay:         protocol.DefaultMaxAckDelay,
+			MinAckDelay:         &minAckDelay,
+			StatelessResetToken: &protocol.StatelessResetToken{},
+		}).Marshal(protocol.PerspectiveServer)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MinAckDelay).ToNot(BeNil())
+		Expect(*p.MinAckDelay).To(Equal(minAckDelay))
+	})
+
+	It("errors when the min_ack_delay is too large", func() {
+		b := &bytes.Buffer{}
+		quicvarint.Write(b, uint64(minAckDelayParameterID))
+		quicvarint.Write(b, uint64(quicvarint.Len(1<<24)))
+		quicvarint.Write(b, 1<<24)
+		addInitialSourceConnectionID(b)
+		Expect((&TransportParameters{}).Unmarshal(b.Bytes(), protocol.PerspectiveClient)).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.TransportParameterError,
+			ErrorMessage: "invalid value for min_ack_delay: 16777216us (maximum 16777215us)",
+		}))
+	})
+
+	It("errors when the min_ack_delay is larger than the max_ack_delay", func() {
+		minAckDelay := 30 * time.Millisecond
+		data := (&TransportParameters{
+			MaxAckDelay:               25 * time.Millisecond,
+			MinAckDelay:               &minAckDelay,
+			InitialSourceConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+		}).Marshal(protocol.PerspectiveClient)
+		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveClient)).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.TransportParameterError,
+			ErrorMessage: "min_ack_delay (30ms) larger than max_ack_delay (25ms)",
+		}))
+	})
+
 	It("errors when the server doesn't set the original_destination_connection_id", func() {
 		b := &bytes.Buffer{}
 		quicvarint.Write(b, uint64(statelessResetTokenParameterID))
@@ -429,6 +528,40 @@ var _ = Describe("Transport Parameters", func() {
 			Expect(p.PreferredAddress.StatelessResetToken).To(Equal(pa.StatelessResetToken))
 		})
 
+		It("marshals and unmarshals a preferred_address that only has an IPv4 address", func() {
+			pa.IPv6 = nil
+			pa.IPv6Port = 0
+			data := (&TransportParameters{
+				PreferredAddress:    pa,
+				StatelessResetToken: &protocol.StatelessResetToken{},
+			}).Marshal(protocol.PerspectiveServer)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+			Expect(p.PreferredAddress.IPv4.String()).To(Equal("127.0.0.1"))
+			Expect(p.PreferredAddress.IPv4Port).To(BeEquivalentTo(42))
+			Expect(p.PreferredAddress.IPv6.IsUnspecified()).To(BeTrue())
+			Expect(p.PreferredAddress.IPv6Port).To(BeZero())
+			Expect(p.PreferredAddress.ConnectionID).To(Equal(pa.ConnectionID))
+			Expect(p.PreferredAddress.StatelessResetToken).To(Equal(pa.StatelessResetToken))
+		})
+
+		It("marshals and unmarshals a preferred_address that only has an IPv6 address", func() {
+			pa.IPv4 = nil
+			pa.IPv4Port = 0
+			data := (&TransportParameters{
+				PreferredAddress:    pa,
+				StatelessResetToken: &protocol.StatelessResetToken{},
+			}).Marshal(protocol.PerspectiveServer)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+			Expect(p.PreferredAddress.IPv4.IsUnspecified()).To(BeTrue())
+			Expect(p.PreferredAddress.IPv4Port).To(BeZero())
+			Expect(p.PreferredAddress.IPv6.String()).To(Equal(pa.IPv6.String()))
+			Expect(p.PreferredAddress.IPv6Port).To(BeEquivalentTo(13))
+			Expect(p.PreferredAddress.ConnectionID).To(Equal(pa.ConnectionID))
+			Expect(p.PreferredAddress.StatelessResetToken).To(Equal(pa.StatelessResetToken))
+		})
+
 		It("errors if the client sent a preferred_address", func() {
 			b := &bytes.Buffer{}
 			quicvarint.Write(b, uint64(preferredAddressParameterID))
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"math/rand"
 	"net"
 	"sort"
 	"time"
@@ -19,10 +18,6 @@ import (
 
 const transportParameterMarshalingVersion = 1
 
-func init() {
-	rand.Seed(time.Now().UTC().UnixNano())
-}
-
 type transportParameterID uint64
 
 const (
@@ -45,6 +40,10 @@ const (
 	retrySourceConnectionIDParameterID         transportParameterID = 0x10
 	// RFC 9221
 	maxDatagramFrameSizeParameterID transportParameterID = 0x20
+	// draft-ietf-quic-reliable-stream-reset
+	resetStreamAtParameterID transportParameterID = 0x17f7586d2cb571
+	// draft-ietf-quic-ack-frequency
+	minAckDelayParameterID transportParameterID = 0xff04de1b
 )
 
 // PreferredAddress is the value encoding in the preferred_address transport parameter
@@ -86,6 +85,10 @@ type TransportParameters struct {
 	ActiveConnectionIDLimit uint64
 
 	MaxDatagramFrameSize protocol.ByteCount
+
+	EnableResetStreamAt bool
+
+	MinAckDelay *time.Duration // nil if the peer doesn't support the ACK frequency extension
 }
 
 // Unmarshal the transport parameters
@@ -138,6 +141,7 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 			maxAckDelayParameterID,
 			activeConnectionIDLimitParameterID,
 			maxDatagramFrameSizeParameterID,
+			minAckDelayParameterID,
 			ackDelayExponentParameterID:
 			if err := p.readNumericTransportParameter(r, paramID, int(paramLen)); err != nil {
 				return err
@@ -154,6 +158,11 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 				return fmt.Errorf("wrong length for disable_active_migration: %d (expected empty)", paramLen)
 			}
 			p.DisableActiveMigration = true
+		case resetStreamAtParameterID:
+			if paramLen != 0 {
+				return fmt.Errorf("wrong length for reset_stream_at: %d (expected empty)", paramLen)
+			}
+			p.EnableResetStreamAt = true
 		case statelessResetTokenParameterID:
 			if sentBy == protocol.PerspectiveClient {
 				return errors.New("client sent a stateless_reset_token")
@@ -196,6 +205,10 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 		}
 	}
 
+	if p.MinAckDelay != nil && *p.MinAckDelay > p.MaxAckDelay {
+		return fmt.Errorf("min_ack_delay (%s) larger than max_ack_delay (%s)", *p.MinAckDelay, p.MaxAckDelay)
+	}
+
 	// check that every transport parameter was sent at most once
 	sort.Slice(parameterIDs, func(i, j int) bool { return parameterIDs[i] < parameterIDs[j] })
 	for i := 0; i < len(parameterIDs)-1; i++ {
@@ -306,6 +319,12 @@ func (p *TransportParameters) readNumericTransportParameter(
 		p.ActiveConnectionIDLimit = val
 	case maxDatagramFrameSizeParameterID:
 		p.MaxDatagramFrameSize = protocol.ByteCount(val)
+	case minAckDelayParameterID:
+		if val >= 1<<24 {
+			return fmt.Errorf("invalid value for min_ack_delay: %dus (maximum %dus)", val, 1<<24-1)
+		}
+		minAckDelay := time.Duration(val) * time.Microsecond
+		p.MinAckDelay = &minAckDelay
 	default:
 		return fmt.Errorf("TransportParameter BUG: transport parameter %d not found", paramID)
 	}
@@ -320,11 +339,12 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	b := make([]byte, 0, 256)
 
 	// add a greased value
-	b = quicvarint.Append(b, uint64(27+31*rand.Intn(100)))
-	length := rand.Intn(16)
+	var r utils.Rand
+	b = quicvarint.Append(b, uint64(27+31*r.Int31n(100)))
+	length := int(r.Int31n(16))
 	b = quicvarint.Append(b, uint64(length))
 	b = b[:len(b)+length]
-	rand.Read(b[len(b)-length:])
+	io.ReadFull(protocol.RandReader, b[len(b)-length:])
 
 	// initial_max_stream_data_bidi_local
 	b = p.marshalVarintParam(b, initialMaxStreamDataBidiLocalParameterID, uint64(p.InitialMaxStreamDataBidiLocal))
@@ -341,7 +361,11 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	// idle_timeout
 	b = p.marshalVarintParam(b, maxIdleTimeoutParameterID, uint64(p.MaxIdleTimeout/time.Millisecond))
 	// max_packet_size
-	b = p.marshalVarintParam(b, maxUDPPayloadSizeParameterID, uint64(protocol.MaxPacketBufferSize))
+	maxUDPPayloadSize := protocol.MaxPacketBufferSize
+	if p.MaxUDPPayloadSize != 0 {
+		maxUDPPayloadSize = p.MaxUDPPayloadSize
+	}
+	b = p.marshalVarintParam(b, maxUDPPayloadSizeParameterID, uint64(maxUDPPayloadSize))
 	// max_ack_delay
 	// Only send it if is different from the default value.
 	if p.MaxAckDelay != protocol.DefaultMaxAckDelay {
@@ -372,11 +396,19 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 		if p.PreferredAddress != nil {
 			b = quicvarint.Append(b, uint64(preferredAddressParameterID))
 			b = quicvarint.Append(b, 4+2+16+2+1+uint64(p.PreferredAddress.ConnectionID.Len())+16)
-			ipv4 := p.PreferredAddress.IPv4
-			b = append(b, ipv4[len(ipv4)-4:]...)
+			// An address family that's not set is encoded as all-zero address and port.
+			ipv4 := make([]byte, 4)
+			if ip := p.PreferredAddress.IPv4.To4(); ip != nil {
+				copy(ipv4, ip)
+			}
+			b = append(b, ipv4...)
 			b = append(b, []byte{0, 0}...)
 			binary.BigEndian.PutUint16(b[len(b)-2:], p.PreferredAddress.IPv4Port)
-			b = append(b, p.PreferredAddress.IPv6...)
+			ipv6 := make([]byte, 16)
+			if ip := p.PreferredAddress.IPv6.To16(); ip != nil {
+				copy(ipv6, ip)
+			}
+			b = append(b, ipv6...)
 			b = append(b, []byte{0, 0}...)
 			binary.BigEndian.PutUint16(b[len(b)-2:], p.PreferredAddress.IPv6Port)
 			b = append(b, uint8(p.PreferredAddress.ConnectionID.Len()))
@@ -399,6 +431,15 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	if p.MaxDatagramFrameSize != protocol.InvalidByteCount {
 		b = p.marshalVarintParam(b, maxDatagramFrameSizeParameterID, uint64(p.MaxDatagramFrameSize))
 	}
+	// reset_stream_at
+	if p.EnableResetStreamAt {
+		b = quicvarint.Append(b, uint64(resetStreamAtParameterID))
+		b = quicvarint.Append(b, 0)
+	}
+	// min_ack_delay
+	if p.MinAckDelay != nil {
+		b = p.marshalVarintParam(b, minAckDelayParameterID, uint64(*p.MinAckDelay/time.Microsecond))
+	}
 	return b
 }
 
@@ -476,6 +517,13 @@ func (p *TransportParameters) String() string {
 		logString += ", MaxDatagramFrameSize: %d"
 		logParams = append(logParams, p.MaxDatagramFrameSize)
 	}
+	if p.EnableResetStreamAt {
+		logString += ", EnableResetStreamAt: true"
+	}
+	if p.MinAckDelay != nil {
+		logString += ", MinAckDelay: %s"
+		logParams = append(logParams, *p.MinAckDelay)
+	}
 	logString += "}"
 	return fmt.Sprintf(logString, logParams...)
 }
@@ -55,6 +55,8 @@ type (
 	TransportError = qerr.TransportErrorCode
 	// An ApplicationError is an application-defined error code.
 	ApplicationError = qerr.TransportErrorCode
+	// A StreamErrorCode is an error code used to cancel streams.
+	StreamErrorCode = qerr.StreamErrorCode
 
 	// The RTTStats contain statistics used by the congestion controller.
 	RTTStats = utils.RTTStats
@@ -100,6 +102,14 @@ type ShortHeader struct {
 	KeyPhase         KeyPhaseBit
 }
 
+// A StreamCloseState describes how one direction of a stream was closed.
+type StreamCloseState struct {
+	// FinalSize is the final size of this direction of the stream.
+	FinalSize ByteCount
+	// ErrorCode is set if this direction was reset, either by us or by the peer.
+	ErrorCode *StreamErrorCode
+}
+
 // A Tracer traces events.
 type Tracer interface {
 	// TracerForConnection requests a new tracer for a connection.
@@ -122,8 +132,23 @@ type ConnectionTracer interface {
 	ReceivedTransportParameters(*TransportParameters)
 	RestoredTransportParameters(parameters *TransportParameters) // for 0-RTT
 	SentPacket(hdr *ExtendedHeader, size ByteCount, ack *AckFrame, frames []Frame)
+	// SentPacketTimestamp is called with the time a packet was actually sent.
+	// It is only called if quic.Config.EnableTxTimestamps is set.
+	// Where supported (on Linux), this is the software TX timestamp reported by the kernel (SO_TIMESTAMPING),
+	// otherwise it is the time the packet was passed to the kernel.
+	// The timestamp is reported asynchronously, so this is called some time after SentPacket.
+	// Timestamps reported after the connection was closed are dropped.
+	SentPacketTimestamp(EncryptionLevel, PacketNumber, time.Time)
 	ReceivedVersionNegotiationPacket(dest, src ArbitraryLenConnectionID, _ []VersionNumber)
 	ReceivedRetry(*Header)
+	// ReceivedHandshakeDone is called when the client receives a HANDSHAKE_DONE frame.
+	// The first HANDSHAKE_DONE frame confirms the handshake, unless it was already confirmed by an acknowledgment for a 1-RTT packet.
+	ReceivedHandshakeDone()
+	// CompletedHandshake is called when the handshake completes.
+	// attempted0RTT says if the client attempted to use 0-RTT, i.e. if it sent 0-RTT packets (client),
+	// or if 0-RTT packets were received from the client (server).
+	// accepted0RTT says if the server accepted 0-RTT.
+	CompletedHandshake(attempted0RTT, accepted0RTT bool)
 	ReceivedLongHeaderPacket(hdr *ExtendedHeader, size ByteCount, frames []Frame)
 	ReceivedShortHeaderPacket(hdr *ShortHeader, size ByteCount, frames []Frame)
 	BufferedPacket(PacketType)
@@ -131,8 +156,22 @@ type ConnectionTracer interface {
 	UpdatedMetrics(rttStats *RTTStats, cwnd, bytesInFlight ByteCount, packetsInFlight int)
 	AcknowledgedPacket(EncryptionLevel, PacketNumber)
 	LostPacket(EncryptionLevel, PacketNumber, PacketLossReason)
+	// DetectedSpuriousLoss is called when a packet that was previously declared lost is acknowledged by the peer.
+	DetectedSpuriousLoss(EncryptionLevel, PacketNumber)
+	// ReceivedAckForUnsentPacket is called when the peer acknowledges a packet number that was never sent.
+	// This is either a packet number that was skipped on purpose, or a packet number larger than any packet sent so far.
+	// This is a strong indication of an optimistic ACK attack. The connection is closed with a PROTOCOL_VIOLATION (a quic.OptimisticAckError).
+	ReceivedAckForUnsentPacket(EncryptionLevel, PacketNumber)
 	UpdatedCongestionState(CongestionState)
 	UpdatedPTOCount(value uint32)
+	// UpdatedMTU is called when Path MTU Discovery changed the packet size.
+	// The size is increased when a probe packet is acknowledged, and reduced when a black hole is detected.
+	// done is set when Path MTU Discovery concluded, i.e. no more probe packets will be sent.
+	UpdatedMTU(mtu ByteCount, done bool)
+	// Blocked is called when a DATA_BLOCKED, STREAM_DATA_BLOCKED or STREAMS_BLOCKED frame is queued for sending (remote == false),
+	// i.e. when we're blocked by flow control or by the peer's stream limit.
+	// It is also called when such a frame is received (remote == true), i.e. when the peer is blocked.
+	Blocked(f Frame, remote bool)
 	UpdatedKeyFromTLS(EncryptionLevel, Perspective)
 	UpdatedKey(generation KeyPhase, remote bool)
 	DroppedEncryptionLevel(EncryptionLevel)
@@ -140,6 +179,13 @@ type ConnectionTracer interface {
 	SetLossTimer(TimerType, EncryptionLevel, time.Time)
 	LossTimerExpired(TimerType, EncryptionLevel)
 	LossTimerCanceled()
+	// OpenedStream is called when a stream is opened, either by us or by the peer.
+	// It might be called concurrently with the other events.
+	OpenedStream(StreamID)
+	// ClosedStream is called when a stream is closed, i.e. when both directions of the stream have completed.
+	// send and receive describe how the two directions of the stream were closed.
+	// For unidirectional streams, the direction that doesn't exist is nil.
+	ClosedStream(id StreamID, send, receive *StreamCloseState)
 	// Close is called when the connection is closed.
 	Close()
 	Debug(name, msg string)
@@ -50,6 +50,18 @@ func (mr *MockConnectionTracerMockRecorder) AcknowledgedPacket(arg0, arg1 interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcknowledgedPacket", reflect.TypeOf((*MockConnectionTracer)(nil).AcknowledgedPacket), arg0, arg1)
 }
 
+// Blocked mocks base method.
+func (m *MockConnectionTracer) Blocked(arg0 Frame, arg1 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Blocked", arg0, arg1)
+}
+
+// Blocked indicates an expected call of Blocked.
+func (mr *MockConnectionTracerMockRecorder) Blocked(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Blocked", reflect.TypeOf((*MockConnectionTracer)(nil).Blocked), arg0, arg1)
+}
+
 // BufferedPacket mocks base method.
 func (m *MockConnectionTracer) BufferedPacket(arg0 PacketType) {
 	m.ctrl.T.Helper()
@@ -86,6 +98,30 @@ func (mr *MockConnectionTracerMockRecorder) ClosedConnection(arg0 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClosedConnection", reflect.TypeOf((*MockConnectionTracer)(nil).ClosedConnection), arg0)
 }
 
+// ClosedStream mocks base method.
+func (m *MockConnectionTracer) ClosedStream(arg0 protocol.StreamID, arg1, arg2 *StreamCloseState) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ClosedStream", arg0, arg1, arg2)
+}
+
+// ClosedStream indicates an expected call of ClosedStream.
+func (mr *MockConnectionTracerMockRecorder) ClosedStream(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClosedStream", reflect.TypeOf((*MockConnectionTracer)(nil).ClosedStream), arg0, arg1, arg2)
+}
+
+// CompletedHandshake mocks base method.
+func (m *MockConnectionTracer) CompletedHandshake(arg0, arg1 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CompletedHandshake", arg0, arg1)
+}
+
+// CompletedHandshake indicates an expected call of CompletedHandshake.
+func (mr *MockConnectionTracerMockRecorder) CompletedHandshake(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompletedHandshake", reflect.TypeOf((*MockConnectionTracer)(nil).CompletedHandshake), arg0, arg1)
+}
+
 // Debug mocks base method.
 func (m *MockConnectionTracer) Debug(arg0, arg1 string) {
 	m.ctrl.T.Helper()
@@ -98,6 +134,18 @@ func (mr *MockConnectionTracerMockRecorder) Debug(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Debug", reflect.TypeOf((*MockConnectionTracer)(nil).Debug), arg0, arg1)
 }
 
+// DetectedSpuriousLoss mocks base method.
+func (m *MockConnectionTracer) DetectedSpuriousLoss(arg0 protocol.EncryptionLevel, arg1 protocol.PacketNumber) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DetectedSpuriousLoss", arg0, arg1)
+}
+
+// DetectedSpuriousLoss indicates an expected call of DetectedSpuriousLoss.
+func (mr *MockConnectionTracerMockRecorder) DetectedSpuriousLoss(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetectedSpuriousLoss", reflect.TypeOf((*MockConnectionTracer)(nil).DetectedSpuriousLoss), arg0, arg1)
+}
+
 // DroppedEncryptionLevel mocks base method.
 func (m *MockConnectionTracer) DroppedEncryptionLevel(arg0 protocol.EncryptionLevel) {
 	m.ctrl.T.Helper()
@@ -182,6 +230,42 @@ func (mr *MockConnectionTracerMockRecorder) NegotiatedVersion(arg0, arg1, arg2 i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NegotiatedVersion", reflect.TypeOf((*MockConnectionTracer)(nil).NegotiatedVersion), arg0, arg1, arg2)
 }
 
+// OpenedStream mocks base method.
+func (m *MockConnectionTracer) OpenedStream(arg0 protocol.StreamID) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OpenedStream", arg0)
+}
+
+// OpenedStream indicates an expected call of OpenedStream.
+func (mr *MockConnectionTracerMockRecorder) OpenedStream(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenedStream", reflect.TypeOf((*MockConnectionTracer)(nil).OpenedStream), arg0)
+}
+
+// ReceivedAckForUnsentPacket mocks base method.
+func (m *MockConnectionTracer) ReceivedAckForUnsentPacket(arg0 protocol.EncryptionLevel, arg1 protocol.PacketNumber) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReceivedAckForUnsentPacket", arg0, arg1)
+}
+
+// ReceivedAckForUnsentPacket indicates an expected call of ReceivedAckForUnsentPacket.
+func (mr *MockConnectionTracerMockRecorder) ReceivedAckForUnsentPacket(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedAckForUnsentPacket", reflect.TypeOf((*MockConnectionTracer)(nil).ReceivedAckForUnsentPacket), arg0, arg1)
+}
+
+// ReceivedHandshakeDone mocks base method.
+func (m *MockConnectionTracer) ReceivedHandshakeDone() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReceivedHandshakeDone")
+}
+
+// ReceivedHandshakeDone indicates an expected call of ReceivedHandshakeDone.
+func (mr *MockConnectionTracerMockRecorder) ReceivedHandshakeDone() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedHandshakeDone", reflect.TypeOf((*MockConnectionTracer)(nil).ReceivedHandshakeDone))
+}
+
 // ReceivedLongHeaderPacket mocks base method.
 func (m *MockConnectionTracer) ReceivedLongHeaderPacket(arg0 *wire.ExtendedHeader, arg1 protocol.ByteCount, arg2 []Frame) {
 	m.ctrl.T.Helper()
@@ -266,6 +350,18 @@ func (mr *MockConnectionTracerMockRecorder) SentPacket(arg0, arg1, arg2, arg3 in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SentPacket", reflect.TypeOf((*MockConnectionTracer)(nil).SentPacket), arg0, arg1, arg2, arg3)
 }
 
+// SentPacketTimestamp mocks base method.
+func (m *MockConnectionTracer) SentPacketTimestamp(arg0 protocol.EncryptionLevel, arg1 protocol.PacketNumber, arg2 time.Time) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SentPacketTimestamp", arg0, arg1, arg2)
+}
+
+// SentPacketTimestamp indicates an expected call of SentPacketTimestamp.
+func (mr *MockConnectionTracerMockRecorder) SentPacketTimestamp(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SentPacketTimestamp", reflect.TypeOf((*MockConnectionTracer)(nil).SentPacketTimestamp), arg0, arg1, arg2)
+}
+
 // SentTransportParameters mocks base method.
 func (m *MockConnectionTracer) SentTransportParameters(arg0 *wire.TransportParameters) {
 	m.ctrl.T.Helper()
@@ -338,6 +434,18 @@ func (mr *MockConnectionTracerMockRecorder) UpdatedKeyFromTLS(arg0, arg1 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedKeyFromTLS", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedKeyFromTLS), arg0, arg1)
 }
 
+// UpdatedMTU mocks base method.
+func (m *MockConnectionTracer) UpdatedMTU(arg0 protocol.ByteCount, arg1 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdatedMTU", arg0, arg1)
+}
+
+// UpdatedMTU indicates an expected call of UpdatedMTU.
+func (mr *MockConnectionTracerMockRecorder) UpdatedMTU(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedMTU", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedMTU), arg0, arg1)
+}
+
 // UpdatedMetrics mocks base method.
 func (m *MockConnectionTracer) UpdatedMetrics(arg0 *utils.RTTStats, arg1, arg2 protocol.ByteCount, arg3 int) {
 	m.ctrl.T.Helper()
@@ -3,9 +3,28 @@ package logging
 import (
 	"context"
 	"net"
+	"runtime/debug"
 	"time"
+
+	"github.com/fkwhite/quic-go/internal/utils"
 )
 
+// onTracerPanic is called when a tracer panics. It is replaced in the tests.
+var onTracerPanic = func(r interface{}) {
+	utils.DefaultLogger.Errorf("tracer panicked: %v\n%s", r, debug.Stack())
+}
+
+// callSafely calls f, recovering from a panic.
+// This makes sure that a panicking tracer doesn't prevent the other tracers from receiving the event.
+func callSafely(f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			onTracerPanic(r)
+		}
+	}()
+	f()
+}
+
 type tracerMultiplexer struct {
 	tracers []Tracer
 }
@@ -13,6 +32,7 @@ type tracerMultiplexer struct {
 var _ Tracer = &tracerMultiplexer{}
 
 // NewMultiplexedTracer creates a new tracer that multiplexes events to multiple tracers.
+// A panic in one of the tracers is recovered, and doesn't prevent the other tracers from receiving the event.
 func NewMultiplexedTracer(tracers ...Tracer) Tracer {
 	if len(tracers) == 0 {
 		return nil
@@ -26,7 +46,9 @@ func NewMultiplexedTracer(tracers ...Tracer) Tracer {
 func (m *tracerMultiplexer) TracerForConnection(ctx context.Context, p Perspective, odcid ConnectionID) ConnectionTracer {
 	var connTracers []ConnectionTracer
 	for _, t := range m.tracers {
-		if ct := t.TracerForConnection(ctx, p, odcid); ct != nil {
+		var ct ConnectionTracer
+		callSafely(func() { ct = t.TracerForConnection(ctx, p, odcid) })
+		if ct != nil {
 			connTracers = append(connTracers, ct)
 		}
 	}
@@ -35,19 +57,19 @@ func (m *tracerMultiplexer) TracerForConnection(ctx context.Context, p Perspecti
 
 func (m *tracerMultiplexer) SentPacket(remote net.Addr, hdr *Header, size ByteCount, frames []Frame) {
 	for _, t := range m.tracers {
-		t.SentPacket(remote, hdr, size, frames)
+		callSafely(func() { t.SentPacket(remote, hdr, size, frames) })
 	}
 }
 
 func (m *tracerMultiplexer) SentVersionNegotiationPacket(remote net.Addr, dest, src ArbitraryLenConnectionID, versions []VersionNumber) {
 	for _, t := range m.tracers {
-		t.SentVersionNegotiationPacket(remote, dest, src, versions)
+		callSafely(func() { t.SentVersionNegotiationPacket(remote, dest, src, versions) })
 	}
 }
 
 func (m *tracerMultiplexer) DroppedPacket(remote net.Addr, typ PacketType, size ByteCount, reason PacketDropReason) {
 	for _, t := range m.tracers {
-		t.DroppedPacket(remote, typ, size, reason)
+		callSafely(func() { t.DroppedPacket(remote, typ, size, reason) })
 	}
 }
 
@@ -58,6 +80,7 @@ type connTracerMultiplexer struct {
 var _ ConnectionTracer = &connTracerMultiplexer{}
 
 // NewMultiplexedConnectionTracer creates a new connection tracer that multiplexes events to multiple tracers.
+// A panic in one of the tracers is recovered, and doesn't prevent the other tracers from receiving the event.
 func NewMultiplexedConnectionTracer(tracers ...ConnectionTracer) ConnectionTracer {
 	if len(tracers) == 0 {
 		return nil
@@ -70,162 +93,216 @@ func NewMultiplexedConnectionTracer(tracers ...ConnectionTracer) ConnectionTrace
 
 func (m *connTracerMultiplexer) StartedConnection(local, remote net.Addr, srcConnID, destConnID ConnectionID) {
 	for _, t := range m.tracers {
-		t.StartedConnection(local, remote, srcConnID, destConnID)
+		callSafely(func() { t.StartedConnection(local, remote, srcConnID, destConnID) })
 	}
 }
 
 func (m *connTracerMultiplexer) NegotiatedVersion(chosen VersionNumber, clientVersions, serverVersions []VersionNumber) {
 	for _, t := range m.tracers {
-		t.NegotiatedVersion(chosen, clientVersions, serverVersions)
+		callSafely(func() { t.NegotiatedVersion(chosen, clientVersions, serverVersions) })
 	}
 }
 
 func (m *connTracerMultiplexer) ClosedConnection(e error) {
 	for _, t := range m.tracers {
-		t.ClosedConnection(e)
+		callSafely(func() { t.ClosedConnection(e) })
 	}
 }
 
 func (m *connTracerMultiplexer) SentTransportParameters(tp *TransportParameters) {
 	for _, t := range m.tracers {
-		t.SentTransportParameters(tp)
+		callSafely(func() { t.SentTransportParameters(tp) })
 	}
 }
 
 func (m *connTracerMultiplexer) ReceivedTransportParameters(tp *TransportParameters) {
 	for _, t := range m.tracers {
-		t.ReceivedTransportParameters(tp)
+		callSafely(func() { t.ReceivedTransportParameters(tp) })
 	}
 }
 
 func (m *connTracerMultiplexer) RestoredTransportParameters(tp *TransportParameters) {
 	for _, t := range m.tracers {
-		t.RestoredTransportParameters(tp)
+		callSafely(func() { t.RestoredTransportParameters(tp) })
 	}
 }
 
 func (m *connTracerMultiplexer) SentPacket(hdr *ExtendedHeader, size ByteCount, ack *AckFrame, frames []Frame) {
 	for _, t := range m.tracers {
-		t.SentPacket(hdr, size, ack, frames)
+		callSafely(func() { t.SentPacket(hdr, size, ack, frames) })
 	}
 }
 
 func (m *connTracerMultiplexer) ReceivedVersionNegotiationPacket(dest, src ArbitraryLenConnectionID, versions []VersionNumber) {
 	for _, t := range m.tracers {
-		t.ReceivedVersionNegotiationPacket(dest, src, versions)
+		callSafely(func() { t.ReceivedVersionNegotiationPacket(dest, src, versions) })
 	}
 }
 
 func (m *connTracerMultiplexer) ReceivedRetry(hdr *Header) {
 	for _, t := range m.tracers {
-		t.ReceivedRetry(hdr)
+		callSafely(func() { t.ReceivedRetry(hdr) })
+	}
+}
+
+func (m *connTracerMultiplexer) ReceivedHandshakeDone() {
+	for _, t := range m.tracers {
+		callSafely(func() { t.ReceivedHandshakeDone() })
+	}
+}
+
+func (m *connTracerMultiplexer) CompletedHandshake(attempted0RTT, accepted0RTT bool) {
+	for _, t := range m.tracers {
+		callSafely(func() { t.CompletedHandshake(attempted0RTT, accepted0RTT) })
 	}
 }
 
 func (m *connTracerMultiplexer) ReceivedLongHeaderPacket(hdr *ExtendedHeader, size ByteCount, frames []Frame) {
 	for _, t := range m.tracers {
-		t.ReceivedLongHeaderPacket(hdr, size, frames)
+		callSafely(func() { t.ReceivedLongHeaderPacket(hdr, size, frames) })
 	}
 }
 
 func (m *connTracerMultiplexer) ReceivedShortHeaderPacket(hdr *ShortHeader, size ByteCount, frames []Frame) {
 	for _, t := range m.tracers {
-		t.ReceivedShortHeaderPacket(hdr, size, frames)
+		callSafely(func() { t.ReceivedShortHeaderPacket(hdr, size, frames) })
 	}
 }
 
 func (m *connTracerMultiplexer) BufferedPacket(typ PacketType) {
 	for _, t := range m.tracers {
-		t.BufferedPacket(typ)
+		callSafely(func() { t.BufferedPacket(typ) })
 	}
 }
 
 func (m *connTracerMultiplexer) DroppedPacket(typ PacketType, size ByteCount, reason PacketDropReason) {
 	for _, t := range m.tracers {
-		t.DroppedPacket(typ, size, reason)
+		callSafely(func() { t.DroppedPacket(typ, size, reason) })
 	}
 }
 
 func (m *connTracerMultiplexer) UpdatedCongestionState(state CongestionState) {
 	for _, t := range m.tracers {
-		t.UpdatedCongestionState(state)
+		callSafely(func() { t.UpdatedCongestionState(state) })
 	}
 }
 
 func (m *connTracerMultiplexer) UpdatedMetrics(rttStats *RTTStats, cwnd, bytesInFLight ByteCount, packetsInFlight int) {
 	for _, t := range m.tracers {
-		t.UpdatedMetrics(rttStats, cwnd, bytesInFLight, packetsInFlight)
+		callSafely(func() { t.UpdatedMetrics(rttStats, cwnd, bytesInFLight, packetsInFlight) })
+	}
+}
+
+func (m *connTracerMultiplexer) SentPacketTimestamp(encLevel EncryptionLevel, pn PacketNumber, sendTime time.Time) {
+	for _, t := range m.tracers {
+		callSafely(func() { t.SentPacketTimestamp(encLevel, pn, sendTime) })
 	}
 }
 
 func (m *connTracerMultiplexer) AcknowledgedPacket(encLevel EncryptionLevel, pn PacketNumber) {
 	for _, t := range m.tracers {
-		t.AcknowledgedPacket(encLevel, pn)
+		callSafely(func() { t.AcknowledgedPacket(encLevel, pn) })
 	}
 }
 
 func (m *connTracerMultiplexer) LostPacket(encLevel EncryptionLevel, pn PacketNumber, reason PacketLossReason) {
 	for _, t := range m.tracers {
-		t.LostPacket(encLevel, pn, reason)
+		callSafely(func() { t.LostPacket(encLevel, pn, reason) })
+	}
+}
+
+func (m *connTracerMultiplexer) DetectedSpuriousLoss(encLevel EncryptionLevel, pn PacketNumber) {
+	for _, t := range m.tracers {
+		callSafely(func() { t.DetectedSpuriousLoss(encLevel, pn) })
+	}
+}
+
+func (m *connTracerMultiplexer) ReceivedAckForUnsentPacket(encLevel EncryptionLevel, pn PacketNumber) {
+	for _, t := range m.tracers {
+		callSafely(func() { t.ReceivedAckForUnsentPacket(encLevel, pn) })
 	}
 }
 
 func (m *connTracerMultiplexer) UpdatedPTOCount(value uint32) {
 	for _, t := range m.tracers {
-		t.UpdatedPTOCount(value)
+		callSafely(func() { t.UpdatedPTOCount(value) })
+	}
+}
+
+func (m *connTracerMultiplexer) UpdatedMTU(mtu ByteCount, done bool) {
+	for _, t := range m.tracers {
+		callSafely(func() { t.UpdatedMTU(mtu, done) })
+	}
+}
+
+func (m *connTracerMultiplexer) Blocked(f Frame, remote bool) {
+	for _, t := range m.tracers {
+		callSafely(func() { t.Blocked(f, remote) })
 	}
 }
 
 func (m *connTracerMultiplexer) UpdatedKeyFromTLS(encLevel EncryptionLevel, perspective Perspective) {
 	for _, t := range m.tracers {
-		t.UpdatedKeyFromTLS(encLevel, perspective)
+		callSafely(func() { t.UpdatedKeyFromTLS(encLevel, perspective) })
 	}
 }
 
 func (m *connTracerMultiplexer) UpdatedKey(generation KeyPhase, remote bool) {
 	for _, t := range m.tracers {
-		t.UpdatedKey(generation, remote)
+		callSafely(func() { t.UpdatedKey(generation, remote) })
 	}
 }
 
 func (m *connTracerMultiplexer) DroppedEncryptionLevel(encLevel EncryptionLevel) {
 	for _, t := range m.tracers {
-		t.DroppedEncryptionLevel(encLevel)
+		callSafely(func() { t.DroppedEncryptionLevel(encLevel) })
 	}
 }
 
 func (m *connTracerMultiplexer) DroppedKey(generation KeyPhase) {
 	for _, t := range m.tracers {
-		t.DroppedKey(generation)
+		callSafely(func() { t.DroppedKey(generation) })
 	}
 }
 
 func (m *connTracerMultiplexer) SetLossTimer(typ TimerType, encLevel EncryptionLevel, exp time.Time) {
 	for _, t := range m.tracers {
-		t.SetLossTimer(typ, encLevel, exp)
+		callSafely(func() { t.SetLossTimer(typ, encLevel, exp) })
 	}
 }
 
 func (m *connTracerMultiplexer) LossTimerExpired(typ TimerType, encLevel EncryptionLevel) {
 	for _, t := range m.tracers {
-		t.LossTimerExpired(typ, encLevel)
+		callSafely(func() { t.LossTimerExpired(typ, encLevel) })
 	}
 }
 
 func (m *connTracerMultiplexer) LossTimerCanceled() {
 	for _, t := range m.tracers {
-		t.LossTimerCanceled()
+		callSafely(func() { t.LossTimerCanceled() })
+	}
+}
+
+func (m *connTracerMultiplexer) OpenedStream(id StreamID) {
+	for _, t := range m.tracers {
+		callSafely(func() { t.OpenedStream(id) })
+	}
+}
+
+func (m *connTracerMultiplexer) ClosedStream(id StreamID, send, receive *StreamCloseState) {
+	for _, t := range m.tracers {
+		callSafely(func() { t.ClosedStream(id, send, receive) })
 	}
 }
 
 func (m *connTracerMultiplexer) Debug(name, msg string) {
 	for _, t := range m.tracers {
-		t.Debug(name, msg)
+		callSafely(func() { t.Debug(name, msg) })
 	}
 }
 
 func (m *connTracerMultiplexer) Close() {
 	for _, t := range m.tracers {
-		t.Close()
+		callSafely(func() { t.Close() })
 	}
 }
@@ -78,6 +78,29 @@ var _ = Describe("Tracing", func() {
 				Expect(tracer.TracerForConnection(ctx, PerspectiveClient, connID)).To(BeNil())
 			})
 
+			It("uses the other connection tracers if one tracer panics in TracerForConnection", func() {
+				ctx := context.Background()
+				ctr2 := NewMockConnectionTracer(mockCtrl)
+				connID := protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5})
+				tr1.EXPECT().TracerForConnection(ctx, PerspectiveClient, connID).Do(func(context.Context, Perspective, ConnectionID) { panic("foobar") })
+				tr2.EXPECT().TracerForConnection(ctx, PerspectiveClient, connID).Return(ctr2)
+				tr := tracer.TracerForConnection(ctx, PerspectiveClient, connID)
+				ctr2.EXPECT().LossTimerCanceled()
+				tr.LossTimerCanceled()
+			})
+
+			It("passes events to the other tracers if one tracer panics", func() {
+				var panicked interface{}
+				origOnTracerPanic := onTracerPanic
+				defer func() { onTracerPanic = origOnTracerPanic }()
+				onTracerPanic = func(r interface{}) { panicked = r }
+				remote := &net.UDPAddr{IP: net.IPv4(4, 3, 2, 1)}
+				tr1.EXPECT().DroppedPacket(remote, PacketTypeInitial, ByteCount(1337), PacketDropHeaderParseError).Do(func(net.Addr, PacketType, ByteCount, PacketDropReason) { panic("foobar") })
+				tr2.EXPECT().DroppedPacket(remote, PacketTypeInitial, ByteCount(1337), PacketDropHeaderParseError)
+				tracer.DroppedPacket(remote, PacketTypeInitial, 1337, PacketDropHeaderParseError)
+				Expect(panicked).To(Equal("foobar"))
+			})
+
 			It("traces the PacketSent event", func() {
 				remote := &net.UDPAddr{IP: net.IPv4(4, 3, 2, 1)}
 				hdr := &Header{DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3})}
@@ -119,6 +142,15 @@ var _ = Describe("Tracing", func() {
 			tracer = NewMultiplexedConnectionTracer(tr1, tr2)
 		})
 
+		It("passes events to the other tracers if one tracer panics", func() {
+			tr1.EXPECT().UpdatedPTOCount(uint32(42)).Do(func(uint32) { panic("foobar") })
+			tr2.EXPECT().UpdatedPTOCount(uint32(42))
+			tracer.UpdatedPTOCount(42)
+			tr1.EXPECT().Close()
+			tr2.EXPECT().Close()
+			tracer.Close()
+		})
+
 		It("trace the ConnectionStarted event", func() {
 			local := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4)}
 			remote := &net.UDPAddr{IP: net.IPv4(4, 3, 2, 1)}
@@ -181,6 +213,18 @@ var _ = Describe("Tracing", func() {
 			tracer.ReceivedRetry(hdr)
 		})
 
+		It("traces the ReceivedHandshakeDone event", func() {
+			tr1.EXPECT().ReceivedHandshakeDone()
+			tr2.EXPECT().ReceivedHandshakeDone()
+			tracer.ReceivedHandshakeDone()
+		})
+
+		It("traces the CompletedHandshake event", func() {
+			tr1.EXPECT().CompletedHandshake(true, false)
+			tr2.EXPECT().CompletedHandshake(true, false)
+			tracer.CompletedHandshake(true, false)
+		})
+
 		It("traces the ReceivedLongHeaderPacket event", func() {
 			hdr := &ExtendedHeader{Header: Header{DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3})}}
 			ping := &PingFrame{}
@@ -223,6 +267,13 @@ var _ = Describe("Tracing", func() {
 			tracer.UpdatedMetrics(rttStats, 1337, 42, 13)
 		})
 
+		It("traces the SentPacketTimestamp event", func() {
+			now := time.Now()
+			tr1.EXPECT().SentPacketTimestamp(Encryption1RTT, PacketNumber(42), now)
+			tr2.EXPECT().SentPacketTimestamp(Encryption1RTT, PacketNumber(42), now)
+			tracer.SentPacketTimestamp(Encryption1RTT, 42, now)
+		})
+
 		It("traces the AcknowledgedPacket event", func() {
 			tr1.EXPECT().AcknowledgedPacket(EncryptionHandshake, PacketNumber(42))
 			tr2.EXPECT().AcknowledgedPacket(EncryptionHandshake, PacketNumber(42))
@@ -235,12 +286,37 @@ var _ = Describe("Tracing", func() {
 			tracer.LostPacket(EncryptionHandshake, 42, PacketLossReorderingThreshold)
 		})
 
+		It("traces the DetectedSpuriousLoss event", func() {
+			tr1.EXPECT().DetectedSpuriousLoss(Encryption1RTT, PacketNumber(42))
+			tr2.EXPECT().DetectedSpuriousLoss(Encryption1RTT, PacketNumber(42))
+			tracer.DetectedSpuriousLoss(Encryption1RTT, 42)
+		})
+
+		It("traces the ReceivedAckForUnsentPacket event", func() {
+			tr1.EXPECT().ReceivedAckForUnsentPacket(Encryption1RTT, PacketNumber(42))
+			tr2.EXPECT().ReceivedAckForUnsentPacket(Encryption1RTT, PacketNumber(42))
+			tracer.ReceivedAckForUnsentPacket(Encryption1RTT, 42)
+		})
+
 		It("traces the UpdatedPTOCount event", func() {
 			tr1.EXPECT().UpdatedPTOCount(uint32(88))
 			tr2.EXPECT().UpdatedPTOCount(uint32(88))
 			tracer.UpdatedPTOCount(88)
 		})
 
+		It("traces the UpdatedMTU event", func() {
+			tr1.EXPECT().UpdatedMTU(ByteCount(1337), true)
+			tr2.EXPECT().UpdatedMTU(ByteCount(1337), true)
+			tracer.UpdatedMTU(1337, true)
+		})
+
+		It("traces the Blocked event", func() {
+			f := &DataBlockedFrame{MaximumData: 1337}
+			tr1.EXPECT().Blocked(f, true)
+			tr2.EXPECT().Blocked(f, true)
+			tracer.Blocked(f, true)
+		})
+
 		It("traces the UpdatedKeyFromTLS event", func() {
 			tr1.EXPECT().UpdatedKeyFromTLS(EncryptionHandshake, PerspectiveClient)
 			tr2.EXPECT().UpdatedKeyFromTLS(EncryptionHandshake, PerspectiveClient)
@@ -284,6 +360,21 @@ var _ = Describe("Tracing", func() {
 			tracer.LossTimerCanceled()
 		})
 
+		It("traces the OpenedStream event", func() {
+			tr1.EXPECT().OpenedStream(protocol.StreamID(4))
+			tr2.EXPECT().OpenedStream(protocol.StreamID(4))
+			tracer.OpenedStream(4)
+		})
+
+		It("traces the ClosedStream event", func() {
+			errorCode := StreamErrorCode(42)
+			send := &StreamCloseState{FinalSize: 1337}
+			receive := &StreamCloseState{FinalSize: 42, ErrorCode: &errorCode}
+			tr1.EXPECT().ClosedStream(protocol.StreamID(4), send, receive)
+			tr2.EXPECT().ClosedStream(protocol.StreamID(4), send, receive)
+			tracer.ClosedStream(4, send, receive)
+		})
+
 		It("traces the Close event", func() {
 			tr1.EXPECT().Close()
 			tr2.EXPECT().Close()
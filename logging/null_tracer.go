@@ -39,6 +39,8 @@ func (n NullConnectionTracer) SentPacket(*ExtendedHeader, ByteCount, *AckFrame,
 func (n NullConnectionTracer) ReceivedVersionNegotiationPacket(dest, src ArbitraryLenConnectionID, _ []VersionNumber) {
 }
 func (n NullConnectionTracer) ReceivedRetry(*Header)                                        {}
+func (n NullConnectionTracer) ReceivedHandshakeDone()                                       {}
+func (n NullConnectionTracer) CompletedHandshake(bool, bool)                                {}
 func (n NullConnectionTracer) ReceivedLongHeaderPacket(*ExtendedHeader, ByteCount, []Frame) {}
 func (n NullConnectionTracer) ReceivedShortHeaderPacket(*ShortHeader, ByteCount, []Frame)   {}
 func (n NullConnectionTracer) BufferedPacket(PacketType)                                    {}
@@ -46,16 +48,23 @@ func (n NullConnectionTracer) DroppedPacket(PacketType, ByteCount, PacketDropRea
 
 func (n NullConnectionTracer) UpdatedMetrics(rttStats *RTTStats, cwnd, bytesInFlight ByteCount, packetsInFlight int) {
 }
-func (n NullConnectionTracer) AcknowledgedPacket(EncryptionLevel, PacketNumber)            {}
-func (n NullConnectionTracer) LostPacket(EncryptionLevel, PacketNumber, PacketLossReason)  {}
-func (n NullConnectionTracer) UpdatedCongestionState(CongestionState)                      {}
-func (n NullConnectionTracer) UpdatedPTOCount(uint32)                                      {}
-func (n NullConnectionTracer) UpdatedKeyFromTLS(EncryptionLevel, Perspective)              {}
-func (n NullConnectionTracer) UpdatedKey(keyPhase KeyPhase, remote bool)                   {}
-func (n NullConnectionTracer) DroppedEncryptionLevel(EncryptionLevel)                      {}
-func (n NullConnectionTracer) DroppedKey(KeyPhase)                                         {}
-func (n NullConnectionTracer) SetLossTimer(TimerType, EncryptionLevel, time.Time)          {}
-func (n NullConnectionTracer) LossTimerExpired(timerType TimerType, level EncryptionLevel) {}
-func (n NullConnectionTracer) LossTimerCanceled()                                          {}
-func (n NullConnectionTracer) Close()                                                      {}
-func (n NullConnectionTracer) Debug(name, msg string)                                      {}
+func (n NullConnectionTracer) SentPacketTimestamp(EncryptionLevel, PacketNumber, time.Time) {}
+func (n NullConnectionTracer) AcknowledgedPacket(EncryptionLevel, PacketNumber)             {}
+func (n NullConnectionTracer) LostPacket(EncryptionLevel, PacketNumber, PacketLossReason)   {}
+func (n NullConnectionTracer) DetectedSpuriousLoss(EncryptionLevel, PacketNumber)           {}
+func (n NullConnectionTracer) ReceivedAckForUnsentPacket(EncryptionLevel, PacketNumber)     {}
+func (n NullConnectionTracer) UpdatedCongestionState(CongestionState)                       {}
+func (n NullConnectionTracer) UpdatedPTOCount(uint32)                                       {}
+func (n NullConnectionTracer) UpdatedMTU(ByteCount, bool)                                   {}
+func (n NullConnectionTracer) Blocked(Frame, bool)                                          {}
+func (n NullConnectionTracer) UpdatedKeyFromTLS(EncryptionLevel, Perspective)               {}
+func (n NullConnectionTracer) UpdatedKey(keyPhase KeyPhase, remote bool)                    {}
+func (n NullConnectionTracer) DroppedEncryptionLevel(EncryptionLevel)                       {}
+func (n NullConnectionTracer) DroppedKey(KeyPhase)                                          {}
+func (n NullConnectionTracer) SetLossTimer(TimerType, EncryptionLevel, time.Time)           {}
+func (n NullConnectionTracer) LossTimerExpired(timerType TimerType, level EncryptionLevel)  {}
+func (n NullConnectionTracer) LossTimerCanceled()                                           {}
+func (n NullConnectionTracer) OpenedStream(StreamID)                                        {}
+func (n NullConnectionTracer) ClosedStream(StreamID, *StreamCloseState, *StreamCloseState)  {}
+func (n NullConnectionTracer) Close()                                                       {}
+func (n NullConnectionTracer) Debug(name, msg string)                                       {}
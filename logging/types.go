@@ -29,6 +29,8 @@ const (
 	PacketLossReorderingThreshold PacketLossReason = iota
 	// PacketLossTimeThreshold: when a packet is deemed lost due to time threshold
 	PacketLossTimeThreshold
+	// PacketLossPTO: when a packet is deemed lost because the probe timeout (PTO) expired
+	PacketLossPTO
 )
 
 type PacketDropReason uint8
@@ -56,6 +58,12 @@ const (
 	PacketDropUnexpectedVersion
 	// PacketDropDuplicate is used when a duplicate packet is received
 	PacketDropDuplicate
+	// PacketDropAcceptQueueFull is used when a new connection attempt is rejected because the server's accept queue is full
+	PacketDropAcceptQueueFull
+	// PacketDropClientVerificationFailed is used when a new connection attempt is rejected because the Config.VerifyClient callback returned false
+	PacketDropClientVerificationFailed
+	// PacketDropServerShuttingDown is used when a new connection attempt is rejected because the server is shutting down
+	PacketDropServerShuttingDown
 )
 
 // TimerType is the type of the loss detection timer
@@ -0,0 +1,251 @@
+// Package metrics implements a logging.Tracer that exports QUIC metrics to Prometheus.
+//
+// It lives in a separate Go module, so that quic-go itself doesn't depend on Prometheus.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/fkwhite/quic-go"
+	"github.com/fkwhite/quic-go/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "quicgo"
+
+// A Collector collects metrics about QUIC connections.
+// It implements prometheus.Collector, and needs to be registered with a prometheus.Registerer.
+// The tracer returned by Tracer feeds the metrics.
+type Collector struct {
+	connsStarted      *prometheus.CounterVec
+	connsClosed       *prometheus.CounterVec
+	packetsSent       *prometheus.CounterVec
+	packetsReceived   *prometheus.CounterVec
+	packetsLost       *prometheus.CounterVec
+	bytesSent         *prometheus.CounterVec
+	bytesReceived     *prometheus.CounterVec
+	rtt               *prometheus.HistogramVec
+	handshakeDuration *prometheus.HistogramVec
+}
+
+var _ prometheus.Collector = &Collector{}
+
+// NewCollector creates a new Collector.
+func NewCollector() *Collector {
+	return &Collector{
+		connsStarted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "connections_started_total",
+			Help:      "Connections Started",
+		}, []string{"perspective"}),
+		connsClosed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "connections_closed_total",
+			Help:      "Connections Closed",
+		}, []string{"perspective", "reason"}),
+		packetsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "packets_sent_total",
+			Help:      "Packets Sent",
+		}, []string{"perspective"}),
+		packetsReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "packets_received_total",
+			Help:      "Packets Received",
+		}, []string{"perspective"}),
+		packetsLost: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "packets_lost_total",
+			Help:      "Packets Lost",
+		}, []string{"perspective", "reason"}),
+		bytesSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "bytes_sent_total",
+			Help:      "Bytes Sent (UDP payload)",
+		}, []string{"perspective"}),
+		bytesReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "bytes_received_total",
+			Help:      "Bytes Received (UDP payload)",
+		}, []string{"perspective"}),
+		rtt: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rtt_seconds",
+			Help:      "RTT samples",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14), // 1ms to ~8s
+		}, []string{"perspective"}),
+		handshakeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "handshake_duration_seconds",
+			Help:      "Duration of the QUIC handshake, until the handshake is confirmed",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14), // 1ms to ~8s
+		}, []string{"perspective"}),
+	}
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.connsStarted,
+		c.connsClosed,
+		c.packetsSent,
+		c.packetsReceived,
+		c.packetsLost,
+		c.bytesSent,
+		c.bytesReceived,
+		c.rtt,
+		c.handshakeDuration,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, col := range c.collectors() {
+		col.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, col := range c.collectors() {
+		col.Collect(ch)
+	}
+}
+
+// Tracer returns a logging.Tracer that records metrics for all connections.
+// It is intended to be used as the quic.Config.Tracer.
+func (c *Collector) Tracer() logging.Tracer {
+	return &tracer{collector: c}
+}
+
+type tracer struct {
+	logging.NullTracer
+
+	collector *Collector
+}
+
+var _ logging.Tracer = &tracer{}
+
+func (t *tracer) TracerForConnection(_ context.Context, p logging.Perspective, _ logging.ConnectionID) logging.ConnectionTracer {
+	return newConnectionTracer(t.collector, p)
+}
+
+type connectionTracer struct {
+	logging.NullConnectionTracer
+
+	collector   *Collector
+	perspective string
+
+	packetsSent     prometheus.Counter
+	packetsReceived prometheus.Counter
+	bytesSent       prometheus.Counter
+	bytesReceived   prometheus.Counter
+	rtt             prometheus.Observer
+
+	startTime          time.Time
+	handshakeConfirmed bool
+	latestRTT          time.Duration
+}
+
+var _ logging.ConnectionTracer = &connectionTracer{}
+
+func newConnectionTracer(c *Collector, p logging.Perspective) *connectionTracer {
+	pers := p.String()
+	return &connectionTracer{
+		collector:       c,
+		perspective:     pers,
+		packetsSent:     c.packetsSent.WithLabelValues(pers),
+		packetsReceived: c.packetsReceived.WithLabelValues(pers),
+		bytesSent:       c.bytesSent.WithLabelValues(pers),
+		bytesReceived:   c.bytesReceived.WithLabelValues(pers),
+		rtt:             c.rtt.WithLabelValues(pers),
+	}
+}
+
+func (t *connectionTracer) StartedConnection(net.Addr, net.Addr, logging.ConnectionID, logging.ConnectionID) {
+	t.startTime = time.Now()
+	t.collector.connsStarted.WithLabelValues(t.perspective).Inc()
+}
+
+func (t *connectionTracer) ClosedConnection(e error) {
+	t.collector.connsClosed.WithLabelValues(t.perspective, closeReason(e)).Inc()
+}
+
+func (t *connectionTracer) SentPacket(_ *logging.ExtendedHeader, size logging.ByteCount, _ *logging.AckFrame, _ []logging.Frame) {
+	t.packetsSent.Inc()
+	t.bytesSent.Add(float64(size))
+}
+
+func (t *connectionTracer) ReceivedLongHeaderPacket(_ *logging.ExtendedHeader, size logging.ByteCount, _ []logging.Frame) {
+	t.packetsReceived.Inc()
+	t.bytesReceived.Add(float64(size))
+}
+
+func (t *connectionTracer) ReceivedShortHeaderPacket(_ *logging.ShortHeader, size logging.ByteCount, _ []logging.Frame) {
+	t.packetsReceived.Inc()
+	t.bytesReceived.Add(float64(size))
+}
+
+func (t *connectionTracer) LostPacket(_ logging.EncryptionLevel, _ logging.PacketNumber, reason logging.PacketLossReason) {
+	t.collector.packetsLost.WithLabelValues(t.perspective, lossReason(reason)).Inc()
+}
+
+func (t *connectionTracer) UpdatedMetrics(rttStats *logging.RTTStats, _, _ logging.ByteCount, _ int) {
+	// UpdatedMetrics is called more often than a new RTT sample is taken.
+	if rtt := rttStats.LatestRTT(); rtt != t.latestRTT {
+		t.latestRTT = rtt
+		t.rtt.Observe(rtt.Seconds())
+	}
+}
+
+func (t *connectionTracer) DroppedEncryptionLevel(encLevel logging.EncryptionLevel) {
+	// The Handshake keys are dropped when the handshake is confirmed.
+	if encLevel != logging.EncryptionHandshake || t.handshakeConfirmed || t.startTime.IsZero() {
+		return
+	}
+	t.handshakeConfirmed = true
+	t.collector.handshakeDuration.WithLabelValues(t.perspective).Observe(time.Since(t.startTime).Seconds())
+}
+
+func lossReason(r logging.PacketLossReason) string {
+	switch r {
+	case logging.PacketLossReorderingThreshold:
+		return "reordering_threshold"
+	case logging.PacketLossTimeThreshold:
+		return "time_threshold"
+	case logging.PacketLossPTO:
+		return "pto"
+	default:
+		return "unknown"
+	}
+}
+
+func closeReason(e error) string {
+	var (
+		statelessResetErr     *quic.StatelessResetError
+		handshakeTimeoutErr   *quic.HandshakeTimeoutError
+		idleTimeoutErr        *quic.IdleTimeoutError
+		applicationErr        *quic.ApplicationError
+		transportErr          *quic.TransportError
+		versionNegotiationErr *quic.VersionNegotiationError
+	)
+	switch {
+	case errors.As(e, &statelessResetErr):
+		return "stateless_reset"
+	case errors.As(e, &handshakeTimeoutErr):
+		return "handshake_timeout"
+	case errors.As(e, &idleTimeoutErr):
+		return "idle_timeout"
+	case errors.As(e, &applicationErr):
+		return "application_error"
+	case errors.As(e, &transportErr):
+		return "transport_error"
+	case errors.As(e, &versionNegotiationErr):
+		return "version_negotiation"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/fkwhite/quic-go"
+	"github.com/fkwhite/quic-go/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics", func() {
+	var (
+		collector *Collector
+		tracer    logging.ConnectionTracer
+	)
+
+	getSampleCount := func(o prometheus.Observer) uint64 {
+		m := &dto.Metric{}
+		Expect(o.(prometheus.Metric).Write(m)).To(Succeed())
+		return m.GetHistogram().GetSampleCount()
+	}
+
+	BeforeEach(func() {
+		collector = NewCollector()
+		tracer = collector.Tracer().TracerForConnection(context.Background(), logging.PerspectiveClient, logging.ConnectionID{})
+		tracer.StartedConnection(&net.UDPAddr{}, &net.UDPAddr{}, logging.ConnectionID{}, logging.ConnectionID{})
+	})
+
+	It("can be registered", func() {
+		Expect(prometheus.NewRegistry().Register(collector)).To(Succeed())
+	})
+
+	It("counts connections", func() {
+		Expect(testutil.ToFloat64(collector.connsStarted.WithLabelValues("client"))).To(Equal(float64(1)))
+		tracer.ClosedConnection(&quic.IdleTimeoutError{})
+		Expect(testutil.ToFloat64(collector.connsClosed.WithLabelValues("client", "idle_timeout"))).To(Equal(float64(1)))
+	})
+
+	It("counts sent and received packets", func() {
+		tracer.SentPacket(&logging.ExtendedHeader{}, 1200, nil, nil)
+		tracer.SentPacket(&logging.ExtendedHeader{}, 100, nil, nil)
+		tracer.ReceivedShortHeaderPacket(&logging.ShortHeader{}, 1000, nil)
+		tracer.ReceivedLongHeaderPacket(&logging.ExtendedHeader{}, 1200, nil)
+		Expect(testutil.ToFloat64(collector.packetsSent.WithLabelValues("client"))).To(Equal(float64(2)))
+		Expect(testutil.ToFloat64(collector.bytesSent.WithLabelValues("client"))).To(Equal(float64(1300)))
+		Expect(testutil.ToFloat64(collector.packetsReceived.WithLabelValues("client"))).To(Equal(float64(2)))
+		Expect(testutil.ToFloat64(collector.bytesReceived.WithLabelValues("client"))).To(Equal(float64(2200)))
+	})
+
+	It("counts lost packets", func() {
+		tracer.LostPacket(logging.Encryption1RTT, 1, logging.PacketLossReorderingThreshold)
+		tracer.LostPacket(logging.Encryption1RTT, 2, logging.PacketLossTimeThreshold)
+		tracer.LostPacket(logging.Encryption1RTT, 3, logging.PacketLossTimeThreshold)
+		Expect(testutil.ToFloat64(collector.packetsLost.WithLabelValues("client", "reordering_threshold"))).To(Equal(float64(1)))
+		Expect(testutil.ToFloat64(collector.packetsLost.WithLabelValues("client", "time_threshold"))).To(Equal(float64(2)))
+	})
+
+	It("records RTT samples", func() {
+		rttStats := &logging.RTTStats{}
+		rttStats.UpdateRTT(50*time.Millisecond, 0, time.Now())
+		tracer.UpdatedMetrics(rttStats, 0, 0, 0)
+		// no new RTT sample
+		tracer.UpdatedMetrics(rttStats, 0, 0, 0)
+		Expect(getSampleCount(collector.rtt.WithLabelValues("client"))).To(BeEquivalentTo(1))
+	})
+
+	It("records the handshake duration", func() {
+		tracer.DroppedEncryptionLevel(logging.EncryptionInitial)
+		Expect(getSampleCount(collector.handshakeDuration.WithLabelValues("client"))).To(BeZero())
+		tracer.DroppedEncryptionLevel(logging.EncryptionHandshake)
+		Expect(getSampleCount(collector.handshakeDuration.WithLabelValues("client"))).To(BeEquivalentTo(1))
+	})
+})
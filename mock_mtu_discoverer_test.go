@@ -51,6 +51,30 @@ func (mr *MockMtuDiscovererMockRecorder) GetPing() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPing", reflect.TypeOf((*MockMtuDiscoverer)(nil).GetPing))
 }
 
+// OnPacketAcked mocks base method.
+func (m *MockMtuDiscoverer) OnPacketAcked(size protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnPacketAcked", size)
+}
+
+// OnPacketAcked indicates an expected call of OnPacketAcked.
+func (mr *MockMtuDiscovererMockRecorder) OnPacketAcked(size interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnPacketAcked", reflect.TypeOf((*MockMtuDiscoverer)(nil).OnPacketAcked), size)
+}
+
+// OnPacketLost mocks base method.
+func (m *MockMtuDiscoverer) OnPacketLost(size protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnPacketLost", size)
+}
+
+// OnPacketLost indicates an expected call of OnPacketLost.
+func (mr *MockMtuDiscovererMockRecorder) OnPacketLost(size interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnPacketLost", reflect.TypeOf((*MockMtuDiscoverer)(nil).OnPacketLost), size)
+}
+
 // ShouldSendProbe mocks base method.
 func (m *MockMtuDiscoverer) ShouldSendProbe(now time.Time) bool {
 	m.ctrl.T.Helper()
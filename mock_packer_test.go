@@ -139,6 +139,21 @@ func (mr *MockPackerMockRecorder) PackPacket(onlyAck interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PackPacket", reflect.TypeOf((*MockPacker)(nil).PackPacket), onlyAck)
 }
 
+// PackPathProbePacket mocks base method.
+func (m *MockPacker) PackPathProbePacket(connID protocol.ConnectionID, challenge ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PackPathProbePacket", connID, challenge, size)
+	ret0, _ := ret[0].(*packedPacket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PackPathProbePacket indicates an expected call of PackPathProbePacket.
+func (mr *MockPackerMockRecorder) PackPathProbePacket(connID, challenge, size interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PackPathProbePacket", reflect.TypeOf((*MockPacker)(nil).PackPathProbePacket), connID, challenge, size)
+}
+
 // SetMaxPacketSize mocks base method.
 func (m *MockPacker) SetMaxPacketSize(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
@@ -173,3 +173,15 @@ func (mr *MockPacketHandlerManagerMockRecorder) SetServer(arg0 interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetServer", reflect.TypeOf((*MockPacketHandlerManager)(nil).SetServer), arg0)
 }
+
+// UseLargeBuffers mocks base method.
+func (m *MockPacketHandlerManager) UseLargeBuffers() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UseLargeBuffers")
+}
+
+// UseLargeBuffers indicates an expected call of UseLargeBuffers.
+func (mr *MockPacketHandlerManagerMockRecorder) UseLargeBuffers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseLargeBuffers", reflect.TypeOf((*MockPacketHandlerManager)(nil).UseLargeBuffers))
+}
@@ -8,9 +8,11 @@ import (
 	context "context"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	protocol "github.com/fkwhite/quic-go/internal/protocol"
+	wire "github.com/fkwhite/quic-go/internal/wire"
 )
 
 // MockQuicConn is a mock of QuicConn interface.
@@ -66,6 +68,34 @@ func (mr *MockQuicConnMockRecorder) AcceptUniStream(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockQuicConn)(nil).AcceptUniStream), arg0)
 }
 
+// AwaitHandshake mocks base method.
+func (m *MockQuicConn) AwaitHandshake(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AwaitHandshake", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AwaitHandshake indicates an expected call of AwaitHandshake.
+func (mr *MockQuicConnMockRecorder) AwaitHandshake(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AwaitHandshake", reflect.TypeOf((*MockQuicConn)(nil).AwaitHandshake), arg0)
+}
+
+// CloseGracefully mocks base method.
+func (m *MockQuicConn) CloseGracefully(arg0 context.Context, arg1 ApplicationErrorCode, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseGracefully", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseGracefully indicates an expected call of CloseGracefully.
+func (mr *MockQuicConnMockRecorder) CloseGracefully(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseGracefully", reflect.TypeOf((*MockQuicConn)(nil).CloseGracefully), arg0, arg1, arg2)
+}
+
 // CloseWithError mocks base method.
 func (m *MockQuicConn) CloseWithError(arg0 ApplicationErrorCode, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -136,6 +166,20 @@ func (mr *MockQuicConnMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockQuicConn)(nil).HandshakeComplete))
 }
 
+// IdleTimeout mocks base method.
+func (m *MockQuicConn) IdleTimeout() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IdleTimeout")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// IdleTimeout indicates an expected call of IdleTimeout.
+func (mr *MockQuicConnMockRecorder) IdleTimeout() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IdleTimeout", reflect.TypeOf((*MockQuicConn)(nil).IdleTimeout))
+}
+
 // LocalAddr mocks base method.
 func (m *MockQuicConn) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -150,6 +194,34 @@ func (mr *MockQuicConnMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockQuicConn)(nil).LocalAddr))
 }
 
+// LocalConnectionID mocks base method.
+func (m *MockQuicConn) LocalConnectionID() protocol.ConnectionID {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LocalConnectionID")
+	ret0, _ := ret[0].(protocol.ConnectionID)
+	return ret0
+}
+
+// LocalConnectionID indicates an expected call of LocalConnectionID.
+func (mr *MockQuicConnMockRecorder) LocalConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalConnectionID", reflect.TypeOf((*MockQuicConn)(nil).LocalConnectionID))
+}
+
+// MigrateTo mocks base method.
+func (m *MockQuicConn) MigrateTo(arg0 context.Context, arg1 net.PacketConn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateTo", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigrateTo indicates an expected call of MigrateTo.
+func (mr *MockQuicConnMockRecorder) MigrateTo(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateTo", reflect.TypeOf((*MockQuicConn)(nil).MigrateTo), arg0, arg1)
+}
+
 // NextConnection mocks base method.
 func (m *MockQuicConn) NextConnection() Connection {
 	m.ctrl.T.Helper()
@@ -224,6 +296,35 @@ func (mr *MockQuicConnMockRecorder) OpenUniStreamSync(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockQuicConn)(nil).OpenUniStreamSync), arg0)
 }
 
+// PathMTU mocks base method.
+func (m *MockQuicConn) PathMTU() uint16 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PathMTU")
+	ret0, _ := ret[0].(uint16)
+	return ret0
+}
+
+// PathMTU indicates an expected call of PathMTU.
+func (mr *MockQuicConnMockRecorder) PathMTU() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PathMTU", reflect.TypeOf((*MockQuicConn)(nil).PathMTU))
+}
+
+// Ping mocks base method.
+func (m *MockQuicConn) Ping(arg0 context.Context) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", arg0)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockQuicConnMockRecorder) Ping(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockQuicConn)(nil).Ping), arg0)
+}
+
 // ReceiveMessage mocks base method.
 func (m *MockQuicConn) ReceiveMessage() ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -253,6 +354,46 @@ func (mr *MockQuicConnMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockQuicConn)(nil).RemoteAddr))
 }
 
+// RemoteConnectionID mocks base method.
+func (m *MockQuicConn) RemoteConnectionID() protocol.ConnectionID {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoteConnectionID")
+	ret0, _ := ret[0].(protocol.ConnectionID)
+	return ret0
+}
+
+// RemoteConnectionID indicates an expected call of RemoteConnectionID.
+func (mr *MockQuicConnMockRecorder) RemoteConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteConnectionID", reflect.TypeOf((*MockQuicConn)(nil).RemoteConnectionID))
+}
+
+// ResetAllStreams mocks base method.
+func (m *MockQuicConn) ResetAllStreams(arg0 StreamErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ResetAllStreams", arg0)
+}
+
+// ResetAllStreams indicates an expected call of ResetAllStreams.
+func (mr *MockQuicConnMockRecorder) ResetAllStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetAllStreams", reflect.TypeOf((*MockQuicConn)(nil).ResetAllStreams), arg0)
+}
+
+// RotateConnectionID mocks base method.
+func (m *MockQuicConn) RotateConnectionID() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateConnectionID")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateConnectionID indicates an expected call of RotateConnectionID.
+func (mr *MockQuicConnMockRecorder) RotateConnectionID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateConnectionID", reflect.TypeOf((*MockQuicConn)(nil).RotateConnectionID))
+}
+
 // SendMessage mocks base method.
 func (m *MockQuicConn) SendMessage(arg0 []byte) error {
 	m.ctrl.T.Helper()
@@ -267,6 +408,32 @@ func (mr *MockQuicConnMockRecorder) SendMessage(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockQuicConn)(nil).SendMessage), arg0)
 }
 
+// SetMaxSendRate mocks base method.
+func (m *MockQuicConn) SetMaxSendRate(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxSendRate", arg0)
+}
+
+// SetMaxSendRate indicates an expected call of SetMaxSendRate.
+func (mr *MockQuicConnMockRecorder) SetMaxSendRate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxSendRate", reflect.TypeOf((*MockQuicConn)(nil).SetMaxSendRate), arg0)
+}
+
+// ZeroRTTTransportParameters mocks base method.
+func (m *MockQuicConn) ZeroRTTTransportParameters() *wire.TransportParameters {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ZeroRTTTransportParameters")
+	ret0, _ := ret[0].(*wire.TransportParameters)
+	return ret0
+}
+
+// ZeroRTTTransportParameters indicates an expected call of ZeroRTTTransportParameters.
+func (mr *MockQuicConnMockRecorder) ZeroRTTTransportParameters() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ZeroRTTTransportParameters", reflect.TypeOf((*MockQuicConn)(nil).ZeroRTTTransportParameters))
+}
+
 // destroy mocks base method.
 func (m *MockQuicConn) destroy(arg0 error) {
 	m.ctrl.T.Helper()
@@ -344,3 +511,15 @@ func (mr *MockQuicConnMockRecorder) shutdown() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "shutdown", reflect.TypeOf((*MockQuicConn)(nil).shutdown))
 }
+
+// stopIssuingConnectionIDs mocks base method.
+func (m *MockQuicConn) stopIssuingConnectionIDs() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "stopIssuingConnectionIDs")
+}
+
+// stopIssuingConnectionIDs indicates an expected call of stopIssuingConnectionIDs.
+func (mr *MockQuicConnMockRecorder) stopIssuingConnectionIDs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "stopIssuingConnectionIDs", reflect.TypeOf((*MockQuicConn)(nil).stopIssuingConnectionIDs))
+}
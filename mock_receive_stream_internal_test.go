@@ -11,6 +11,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	protocol "github.com/fkwhite/quic-go/internal/protocol"
 	wire "github.com/fkwhite/quic-go/internal/wire"
+	logging "github.com/fkwhite/quic-go/logging"
 )
 
 // MockReceiveStreamI is a mock of ReceiveStreamI interface.
@@ -63,6 +64,45 @@ func (mr *MockReceiveStreamIMockRecorder) Read(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockReceiveStreamI)(nil).Read), p)
 }
 
+// ReadBuffer mocks base method.
+func (m *MockReceiveStreamI) ReadBuffer() ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadBuffer")
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadBuffer indicates an expected call of ReadBuffer.
+func (mr *MockReceiveStreamIMockRecorder) ReadBuffer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadBuffer", reflect.TypeOf((*MockReceiveStreamI)(nil).ReadBuffer))
+}
+
+// ReleaseBuffer mocks base method.
+func (m *MockReceiveStreamI) ReleaseBuffer() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReleaseBuffer")
+}
+
+// ReleaseBuffer indicates an expected call of ReleaseBuffer.
+func (mr *MockReceiveStreamIMockRecorder) ReleaseBuffer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseBuffer", reflect.TypeOf((*MockReceiveStreamI)(nil).ReleaseBuffer))
+}
+
+// SetReadBufferSize mocks base method.
+func (m *MockReceiveStreamI) SetReadBufferSize(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReadBufferSize", arg0)
+}
+
+// SetReadBufferSize indicates an expected call of SetReadBufferSize.
+func (mr *MockReceiveStreamIMockRecorder) SetReadBufferSize(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadBufferSize", reflect.TypeOf((*MockReceiveStreamI)(nil).SetReadBufferSize), arg0)
+}
+
 // SetReadDeadline mocks base method.
 func (m *MockReceiveStreamI) SetReadDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -117,6 +157,20 @@ func (mr *MockReceiveStreamIMockRecorder) getWindowUpdate() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getWindowUpdate", reflect.TypeOf((*MockReceiveStreamI)(nil).getWindowUpdate))
 }
 
+// handleResetStreamAtFrame mocks base method.
+func (m *MockReceiveStreamI) handleResetStreamAtFrame(arg0 *wire.ResetStreamAtFrame) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleResetStreamAtFrame", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// handleResetStreamAtFrame indicates an expected call of handleResetStreamAtFrame.
+func (mr *MockReceiveStreamIMockRecorder) handleResetStreamAtFrame(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleResetStreamAtFrame", reflect.TypeOf((*MockReceiveStreamI)(nil).handleResetStreamAtFrame), arg0)
+}
+
 // handleResetStreamFrame mocks base method.
 func (m *MockReceiveStreamI) handleResetStreamFrame(arg0 *wire.ResetStreamFrame) error {
 	m.ctrl.T.Helper()
@@ -144,3 +198,17 @@ func (mr *MockReceiveStreamIMockRecorder) handleStreamFrame(arg0 interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleStreamFrame", reflect.TypeOf((*MockReceiveStreamI)(nil).handleStreamFrame), arg0)
 }
+
+// receiveCloseState mocks base method.
+func (m *MockReceiveStreamI) receiveCloseState() *logging.StreamCloseState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "receiveCloseState")
+	ret0, _ := ret[0].(*logging.StreamCloseState)
+	return ret0
+}
+
+// receiveCloseState indicates an expected call of receiveCloseState.
+func (mr *MockReceiveStreamIMockRecorder) receiveCloseState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "receiveCloseState", reflect.TypeOf((*MockReceiveStreamI)(nil).receiveCloseState))
+}
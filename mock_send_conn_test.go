@@ -7,6 +7,7 @@ package quic
 import (
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -76,6 +77,44 @@ func (mr *MockSendConnMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockSendConn)(nil).RemoteAddr))
 }
 
+// SetPacketInfo mocks base method.
+func (m *MockSendConn) SetPacketInfo(arg0 *packetInfo) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPacketInfo", arg0)
+}
+
+// SetPacketInfo indicates an expected call of SetPacketInfo.
+func (mr *MockSendConnMockRecorder) SetPacketInfo(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPacketInfo", reflect.TypeOf((*MockSendConn)(nil).SetPacketInfo), arg0)
+}
+
+// SetRemoteAddr mocks base method.
+func (m *MockSendConn) SetRemoteAddr(arg0 net.Addr) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetRemoteAddr", arg0)
+}
+
+// SetRemoteAddr indicates an expected call of SetRemoteAddr.
+func (mr *MockSendConnMockRecorder) SetRemoteAddr(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRemoteAddr", reflect.TypeOf((*MockSendConn)(nil).SetRemoteAddr), arg0)
+}
+
+// UsesPacketInfo mocks base method.
+func (m *MockSendConn) UsesPacketInfo() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsesPacketInfo")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// UsesPacketInfo indicates an expected call of UsesPacketInfo.
+func (mr *MockSendConnMockRecorder) UsesPacketInfo() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsesPacketInfo", reflect.TypeOf((*MockSendConn)(nil).UsesPacketInfo))
+}
+
 // Write mocks base method.
 func (m *MockSendConn) Write(arg0 []byte) error {
 	m.ctrl.T.Helper()
@@ -89,3 +128,47 @@ func (mr *MockSendConnMockRecorder) Write(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockSendConn)(nil).Write), arg0)
 }
+
+// WriteBatch mocks base method.
+func (m *MockSendConn) WriteBatch(arg0 [][]byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteBatch", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteBatch indicates an expected call of WriteBatch.
+func (mr *MockSendConnMockRecorder) WriteBatch(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteBatch", reflect.TypeOf((*MockSendConn)(nil).WriteBatch), arg0)
+}
+
+// WriteBatchWithTimestamps mocks base method.
+func (m *MockSendConn) WriteBatchWithTimestamps(arg0 [][]byte, arg1 []func(time.Time)) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteBatchWithTimestamps", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteBatchWithTimestamps indicates an expected call of WriteBatchWithTimestamps.
+func (mr *MockSendConnMockRecorder) WriteBatchWithTimestamps(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteBatchWithTimestamps", reflect.TypeOf((*MockSendConn)(nil).WriteBatchWithTimestamps), arg0, arg1)
+}
+
+// WritePacketTo mocks base method.
+func (m *MockSendConn) WritePacketTo(arg0 []byte, arg1 net.Addr) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WritePacketTo", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WritePacketTo indicates an expected call of WritePacketTo.
+func (mr *MockSendConnMockRecorder) WritePacketTo(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WritePacketTo", reflect.TypeOf((*MockSendConn)(nil).WritePacketTo), arg0, arg1)
+}
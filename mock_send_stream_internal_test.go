@@ -6,6 +6,8 @@ package quic
 
 import (
 	context "context"
+	io "io"
+	net "net"
 	reflect "reflect"
 	time "time"
 
@@ -13,6 +15,7 @@ import (
 	ackhandler "github.com/fkwhite/quic-go/internal/ackhandler"
 	protocol "github.com/fkwhite/quic-go/internal/protocol"
 	wire "github.com/fkwhite/quic-go/internal/wire"
+	logging "github.com/fkwhite/quic-go/logging"
 )
 
 // MockSendStreamI is a mock of SendStreamI interface.
@@ -38,6 +41,20 @@ func (m *MockSendStreamI) EXPECT() *MockSendStreamIMockRecorder {
 	return m.recorder
 }
 
+// BufferedBytes mocks base method.
+func (m *MockSendStreamI) BufferedBytes() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BufferedBytes")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// BufferedBytes indicates an expected call of BufferedBytes.
+func (mr *MockSendStreamIMockRecorder) BufferedBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedBytes", reflect.TypeOf((*MockSendStreamI)(nil).BufferedBytes))
+}
+
 // CancelWrite mocks base method.
 func (m *MockSendStreamI) CancelWrite(arg0 StreamErrorCode) {
 	m.ctrl.T.Helper()
@@ -50,6 +67,18 @@ func (mr *MockSendStreamIMockRecorder) CancelWrite(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWrite", reflect.TypeOf((*MockSendStreamI)(nil).CancelWrite), arg0)
 }
 
+// CancelWriteAt mocks base method.
+func (m *MockSendStreamI) CancelWriteAt(arg0 StreamErrorCode, arg1 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CancelWriteAt", arg0, arg1)
+}
+
+// CancelWriteAt indicates an expected call of CancelWriteAt.
+func (mr *MockSendStreamIMockRecorder) CancelWriteAt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWriteAt", reflect.TypeOf((*MockSendStreamI)(nil).CancelWriteAt), arg0, arg1)
+}
+
 // Close mocks base method.
 func (m *MockSendStreamI) Close() error {
 	m.ctrl.T.Helper()
@@ -78,6 +107,21 @@ func (mr *MockSendStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockSendStreamI)(nil).Context))
 }
 
+// ReadFrom mocks base method.
+func (m *MockSendStreamI) ReadFrom(arg0 io.Reader) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadFrom", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadFrom indicates an expected call of ReadFrom.
+func (mr *MockSendStreamIMockRecorder) ReadFrom(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadFrom", reflect.TypeOf((*MockSendStreamI)(nil).ReadFrom), arg0)
+}
+
 // SetWriteDeadline mocks base method.
 func (m *MockSendStreamI) SetWriteDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -121,6 +165,21 @@ func (mr *MockSendStreamIMockRecorder) Write(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockSendStreamI)(nil).Write), p)
 }
 
+// WriteBuffers mocks base method.
+func (m *MockSendStreamI) WriteBuffers(bufs net.Buffers) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteBuffers", bufs)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteBuffers indicates an expected call of WriteBuffers.
+func (mr *MockSendStreamIMockRecorder) WriteBuffers(bufs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteBuffers", reflect.TypeOf((*MockSendStreamI)(nil).WriteBuffers), bufs)
+}
+
 // closeForShutdown mocks base method.
 func (m *MockSendStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.T.Helper()
@@ -174,6 +233,20 @@ func (mr *MockSendStreamIMockRecorder) popStreamFrame(maxBytes interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popStreamFrame", reflect.TypeOf((*MockSendStreamI)(nil).popStreamFrame), maxBytes)
 }
 
+// sendCloseState mocks base method.
+func (m *MockSendStreamI) sendCloseState() *logging.StreamCloseState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "sendCloseState")
+	ret0, _ := ret[0].(*logging.StreamCloseState)
+	return ret0
+}
+
+// sendCloseState indicates an expected call of sendCloseState.
+func (mr *MockSendStreamIMockRecorder) sendCloseState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "sendCloseState", reflect.TypeOf((*MockSendStreamI)(nil).sendCloseState))
+}
+
 // updateSendWindow mocks base method.
 func (m *MockSendStreamI) updateSendWindow(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
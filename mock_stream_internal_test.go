@@ -6,6 +6,8 @@ package quic
 
 import (
 	context "context"
+	io "io"
+	net "net"
 	reflect "reflect"
 	time "time"
 
@@ -13,6 +15,7 @@ import (
 	ackhandler "github.com/fkwhite/quic-go/internal/ackhandler"
 	protocol "github.com/fkwhite/quic-go/internal/protocol"
 	wire "github.com/fkwhite/quic-go/internal/wire"
+	logging "github.com/fkwhite/quic-go/logging"
 )
 
 // MockStreamI is a mock of StreamI interface.
@@ -38,6 +41,20 @@ func (m *MockStreamI) EXPECT() *MockStreamIMockRecorder {
 	return m.recorder
 }
 
+// BufferedBytes mocks base method.
+func (m *MockStreamI) BufferedBytes() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BufferedBytes")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// BufferedBytes indicates an expected call of BufferedBytes.
+func (mr *MockStreamIMockRecorder) BufferedBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedBytes", reflect.TypeOf((*MockStreamI)(nil).BufferedBytes))
+}
+
 // CancelRead mocks base method.
 func (m *MockStreamI) CancelRead(arg0 StreamErrorCode) {
 	m.ctrl.T.Helper()
@@ -62,6 +79,18 @@ func (mr *MockStreamIMockRecorder) CancelWrite(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWrite", reflect.TypeOf((*MockStreamI)(nil).CancelWrite), arg0)
 }
 
+// CancelWriteAt mocks base method.
+func (m *MockStreamI) CancelWriteAt(arg0 StreamErrorCode, arg1 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CancelWriteAt", arg0, arg1)
+}
+
+// CancelWriteAt indicates an expected call of CancelWriteAt.
+func (mr *MockStreamIMockRecorder) CancelWriteAt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWriteAt", reflect.TypeOf((*MockStreamI)(nil).CancelWriteAt), arg0, arg1)
+}
+
 // Close mocks base method.
 func (m *MockStreamI) Close() error {
 	m.ctrl.T.Helper()
@@ -105,6 +134,48 @@ func (mr *MockStreamIMockRecorder) Read(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStreamI)(nil).Read), p)
 }
 
+// ReadBuffer mocks base method.
+func (m *MockStreamI) ReadBuffer() ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadBuffer")
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadBuffer indicates an expected call of ReadBuffer.
+func (mr *MockStreamIMockRecorder) ReadBuffer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadBuffer", reflect.TypeOf((*MockStreamI)(nil).ReadBuffer))
+}
+
+// ReadFrom mocks base method.
+func (m *MockStreamI) ReadFrom(arg0 io.Reader) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadFrom", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadFrom indicates an expected call of ReadFrom.
+func (mr *MockStreamIMockRecorder) ReadFrom(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadFrom", reflect.TypeOf((*MockStreamI)(nil).ReadFrom), arg0)
+}
+
+// ReleaseBuffer mocks base method.
+func (m *MockStreamI) ReleaseBuffer() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReleaseBuffer")
+}
+
+// ReleaseBuffer indicates an expected call of ReleaseBuffer.
+func (mr *MockStreamIMockRecorder) ReleaseBuffer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseBuffer", reflect.TypeOf((*MockStreamI)(nil).ReleaseBuffer))
+}
+
 // SetDeadline mocks base method.
 func (m *MockStreamI) SetDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -119,6 +190,18 @@ func (mr *MockStreamIMockRecorder) SetDeadline(t interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockStreamI)(nil).SetDeadline), t)
 }
 
+// SetReadBufferSize mocks base method.
+func (m *MockStreamI) SetReadBufferSize(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReadBufferSize", arg0)
+}
+
+// SetReadBufferSize indicates an expected call of SetReadBufferSize.
+func (mr *MockStreamIMockRecorder) SetReadBufferSize(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadBufferSize", reflect.TypeOf((*MockStreamI)(nil).SetReadBufferSize), arg0)
+}
+
 // SetReadDeadline mocks base method.
 func (m *MockStreamI) SetReadDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -176,6 +259,21 @@ func (mr *MockStreamIMockRecorder) Write(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockStreamI)(nil).Write), p)
 }
 
+// WriteBuffers mocks base method.
+func (m *MockStreamI) WriteBuffers(bufs net.Buffers) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteBuffers", bufs)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteBuffers indicates an expected call of WriteBuffers.
+func (mr *MockStreamIMockRecorder) WriteBuffers(bufs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteBuffers", reflect.TypeOf((*MockStreamI)(nil).WriteBuffers), bufs)
+}
+
 // closeForShutdown mocks base method.
 func (m *MockStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.T.Helper()
@@ -202,6 +300,20 @@ func (mr *MockStreamIMockRecorder) getWindowUpdate() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getWindowUpdate", reflect.TypeOf((*MockStreamI)(nil).getWindowUpdate))
 }
 
+// handleResetStreamAtFrame mocks base method.
+func (m *MockStreamI) handleResetStreamAtFrame(arg0 *wire.ResetStreamAtFrame) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleResetStreamAtFrame", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// handleResetStreamAtFrame indicates an expected call of handleResetStreamAtFrame.
+func (mr *MockStreamIMockRecorder) handleResetStreamAtFrame(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleResetStreamAtFrame", reflect.TypeOf((*MockStreamI)(nil).handleResetStreamAtFrame), arg0)
+}
+
 // handleResetStreamFrame mocks base method.
 func (m *MockStreamI) handleResetStreamFrame(arg0 *wire.ResetStreamFrame) error {
 	m.ctrl.T.Helper()
@@ -271,6 +383,34 @@ func (mr *MockStreamIMockRecorder) popStreamFrame(maxBytes interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popStreamFrame", reflect.TypeOf((*MockStreamI)(nil).popStreamFrame), maxBytes)
 }
 
+// receiveCloseState mocks base method.
+func (m *MockStreamI) receiveCloseState() *logging.StreamCloseState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "receiveCloseState")
+	ret0, _ := ret[0].(*logging.StreamCloseState)
+	return ret0
+}
+
+// receiveCloseState indicates an expected call of receiveCloseState.
+func (mr *MockStreamIMockRecorder) receiveCloseState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "receiveCloseState", reflect.TypeOf((*MockStreamI)(nil).receiveCloseState))
+}
+
+// sendCloseState mocks base method.
+func (m *MockStreamI) sendCloseState() *logging.StreamCloseState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "sendCloseState")
+	ret0, _ := ret[0].(*logging.StreamCloseState)
+	return ret0
+}
+
+// sendCloseState indicates an expected call of sendCloseState.
+func (mr *MockStreamIMockRecorder) sendCloseState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "sendCloseState", reflect.TypeOf((*MockStreamI)(nil).sendCloseState))
+}
+
 // updateSendWindow mocks base method.
 func (m *MockStreamI) updateSendWindow(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
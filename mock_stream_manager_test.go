@@ -194,6 +194,18 @@ func (mr *MockStreamManagerMockRecorder) OpenUniStreamSync(arg0 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockStreamManager)(nil).OpenUniStreamSync), arg0)
 }
 
+// ResetAllStreams mocks base method.
+func (m *MockStreamManager) ResetAllStreams(arg0 StreamErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ResetAllStreams", arg0)
+}
+
+// ResetAllStreams indicates an expected call of ResetAllStreams.
+func (mr *MockStreamManagerMockRecorder) ResetAllStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetAllStreams", reflect.TypeOf((*MockStreamManager)(nil).ResetAllStreams), arg0)
+}
+
 // ResetFor0RTT mocks base method.
 func (m *MockStreamManager) ResetFor0RTT() {
 	m.ctrl.T.Helper()
@@ -70,3 +70,17 @@ func (mr *MockStreamSenderMockRecorder) queueControlFrame(arg0 interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "queueControlFrame", reflect.TypeOf((*MockStreamSender)(nil).queueControlFrame), arg0)
 }
+
+// supportsResetStreamAt mocks base method.
+func (m *MockStreamSender) supportsResetStreamAt() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "supportsResetStreamAt")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// supportsResetStreamAt indicates an expected call of supportsResetStreamAt.
+func (mr *MockStreamSenderMockRecorder) supportsResetStreamAt() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "supportsResetStreamAt", reflect.TypeOf((*MockStreamSender)(nil).supportsResetStreamAt))
+}
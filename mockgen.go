@@ -23,5 +23,6 @@ package quic
 //go:generate sh -c "./mockgen_private.sh quic mock_packet_handler_manager_test.go github.com/fkwhite/quic-go packetHandlerManager"
 //go:generate sh -c "./mockgen_private.sh quic mock_multiplexer_test.go github.com/fkwhite/quic-go multiplexer"
 //go:generate sh -c "./mockgen_private.sh quic mock_batch_conn_test.go github.com/fkwhite/quic-go batchConn"
+//go:generate sh -c "./mockgen_private.sh quic mock_batch_write_conn_test.go github.com/fkwhite/quic-go batchWriteConn"
 //go:generate sh -c "mockgen -package quic -self_package github.com/fkwhite/quic-go -destination mock_token_store_test.go github.com/fkwhite/quic-go TokenStore"
 //go:generate sh -c "mockgen -package quic -self_package github.com/fkwhite/quic-go -destination mock_packetconn_test.go net PacketConn"
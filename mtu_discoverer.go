@@ -7,11 +7,16 @@ import (
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/utils"
 	"github.com/fkwhite/quic-go/internal/wire"
+	"github.com/fkwhite/quic-go/logging"
 )
 
 type mtuDiscoverer interface {
 	ShouldSendProbe(now time.Time) bool
 	GetPing() (ping ackhandler.Frame, datagramSize protocol.ByteCount)
+	// OnPacketAcked and OnPacketLost are called for regular (i.e. non-probe) packets.
+	// They are used to detect black holes.
+	OnPacketAcked(size protocol.ByteCount)
+	OnPacketLost(size protocol.ByteCount)
 }
 
 const (
@@ -20,27 +25,46 @@ const (
 	maxMTUDiff = 20
 	// send a probe packet every mtuProbeDelay RTTs
 	mtuProbeDelay = 5
+	// If this many packets larger than the base size are lost, while smaller packets are acknowledged,
+	// we suspect that the path MTU decreased (see section 4.3 of RFC 8899).
+	// This is confirmed by sending a probe packet of the base size.
+	maxLostPacketsBeforeBlackHole = 3
 )
 
 type mtuFinder struct {
 	lastProbeTime time.Time
 	probeInFlight bool
-	mtuIncreased  func(protocol.ByteCount)
+	mtuChanged    func(protocol.ByteCount)
 
 	rttStats *utils.RTTStats
+	base     protocol.ByteCount // the size we started with, assumed to be supported by the path
 	current  protocol.ByteCount
 	max      protocol.ByteCount // the maximum value, as advertised by the peer (or our maximum size buffer)
+
+	// black hole detection
+	numLostLargePackets int
+	smallPacketAcked    bool
+	blackHoleSuspected  bool // if set, a probe packet of the base size is sent to confirm the black hole
+
+	tracer logging.ConnectionTracer
 }
 
 var _ mtuDiscoverer = &mtuFinder{}
 
-func newMTUDiscoverer(rttStats *utils.RTTStats, start, max protocol.ByteCount, mtuIncreased func(protocol.ByteCount)) mtuDiscoverer {
+func newMTUDiscoverer(
+	rttStats *utils.RTTStats,
+	start, max protocol.ByteCount,
+	mtuChanged func(protocol.ByteCount),
+	tracer logging.ConnectionTracer,
+) mtuDiscoverer {
 	return &mtuFinder{
+		base:          start,
 		current:       start,
 		rttStats:      rttStats,
 		lastProbeTime: time.Now(), // to make sure the first probe packet is not sent immediately
-		mtuIncreased:  mtuIncreased,
+		mtuChanged:    mtuChanged,
 		max:           max,
+		tracer:        tracer,
 	}
 }
 
@@ -49,26 +73,109 @@ func (f *mtuFinder) done() bool {
 }
 
 func (f *mtuFinder) ShouldSendProbe(now time.Time) bool {
-	if f.probeInFlight || f.done() {
+	if f.probeInFlight {
+		return false
+	}
+	if f.blackHoleSuspected {
+		return true
+	}
+	if f.done() {
 		return false
 	}
 	return !now.Before(f.lastProbeTime.Add(mtuProbeDelay * f.rttStats.SmoothedRTT()))
 }
 
 func (f *mtuFinder) GetPing() (ackhandler.Frame, protocol.ByteCount) {
+	f.probeInFlight = true
+	if f.blackHoleSuspected {
+		return f.getConfirmationPing(), f.base
+	}
 	size := (f.max + f.current) / 2
 	f.lastProbeTime = time.Now()
-	f.probeInFlight = true
 	return ackhandler.Frame{
 		Frame: &wire.PingFrame{},
 		OnLost: func(wire.Frame) {
 			f.probeInFlight = false
-			f.max = size
+			// The maximum might have been reduced by black hole detection while the probe was in flight.
+			if size < f.max {
+				f.max = size
+			}
+			if f.tracer != nil && f.done() {
+				f.tracer.UpdatedMTU(f.current, true)
+			}
 		},
 		OnAcked: func(wire.Frame) {
 			f.probeInFlight = false
 			f.current = size
-			f.mtuIncreased(size)
+			f.resetBlackHoleDetection()
+			f.mtuChanged(size)
+			if f.tracer != nil {
+				f.tracer.UpdatedMTU(size, f.done())
+			}
 		},
 	}, size
 }
+
+// getConfirmationPing returns a probe packet of the base size.
+// If it is acknowledged, the path still supports the base size, and the black hole is confirmed.
+// If it is lost, packets are lost independent of their size, so this is not a black hole.
+func (f *mtuFinder) getConfirmationPing() ackhandler.Frame {
+	return ackhandler.Frame{
+		Frame: &wire.PingFrame{},
+		OnLost: func(wire.Frame) {
+			f.probeInFlight = false
+			f.resetBlackHoleDetection()
+		},
+		OnAcked: func(wire.Frame) {
+			f.probeInFlight = false
+			if f.blackHoleSuspected {
+				f.onBlackHole()
+			}
+		},
+	}
+}
+
+func (f *mtuFinder) resetBlackHoleDetection() {
+	f.numLostLargePackets = 0
+	f.smallPacketAcked = false
+	f.blackHoleSuspected = false
+}
+
+func (f *mtuFinder) OnPacketAcked(size protocol.ByteCount) {
+	if size > f.base && size >= f.current {
+		// Full-size packets are getting through.
+		f.resetBlackHoleDetection()
+		return
+	}
+	if f.numLostLargePackets > 0 {
+		f.smallPacketAcked = true
+		f.maybeDetectBlackHole()
+	}
+}
+
+func (f *mtuFinder) OnPacketLost(size protocol.ByteCount) {
+	if size <= f.base {
+		return
+	}
+	f.numLostLargePackets++
+	f.maybeDetectBlackHole()
+}
+
+func (f *mtuFinder) maybeDetectBlackHole() {
+	if f.numLostLargePackets < maxLostPacketsBeforeBlackHole || !f.smallPacketAcked {
+		return
+	}
+	f.blackHoleSuspected = true
+}
+
+func (f *mtuFinder) onBlackHole() {
+	// Fall back to the base size, and search for the new MTU below the size that failed.
+	f.max = f.current
+	f.current = f.base
+	f.resetBlackHoleDetection()
+	f.lastProbeTime = time.Now()
+	f.mtuChanged(f.base)
+	if f.tracer != nil {
+		f.tracer.UpdatedMTU(f.base, f.done())
+	}
+}
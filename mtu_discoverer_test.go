@@ -4,8 +4,8 @@ import (
 	"math/rand"
 	"time"
 
+	mocklogging "github.com/fkwhite/quic-go/internal/mocks/logging"
 	"github.com/fkwhite/quic-go/internal/protocol"
-
 	"github.com/fkwhite/quic-go/internal/utils"
 
 	. "github.com/onsi/ginkgo"
@@ -30,7 +30,7 @@ var _ = Describe("MTU Discoverer", func() {
 		rttStats = &utils.RTTStats{}
 		rttStats.SetInitialRTT(rtt)
 		Expect(rttStats.SmoothedRTT()).To(Equal(rtt))
-		d = newMTUDiscoverer(rttStats, startMTU, maxMTU, func(s protocol.ByteCount) { discoveredMTU = s })
+		d = newMTUDiscoverer(rttStats, startMTU, maxMTU, func(s protocol.ByteCount) { discoveredMTU = s }, nil)
 		now = time.Now()
 		_ = discoveredMTU
 	})
@@ -78,13 +78,141 @@ var _ = Describe("MTU Discoverer", func() {
 		Expect(d.ShouldSendProbe(t.Add(10 * rtt))).To(BeFalse())
 	})
 
+	It("traces MTU updates", func() {
+		tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+		d := newMTUDiscoverer(rttStats, startMTU, maxMTU, func(protocol.ByteCount) {}, tracer)
+		// acknowledge the first probes
+		for _, s := range []protocol.ByteCount{1500, 1750, 1875} {
+			ping, size := d.GetPing()
+			Expect(size).To(Equal(s))
+			tracer.EXPECT().UpdatedMTU(size, false)
+			ping.OnAcked(ping.Frame)
+		}
+		// lose all subsequent probes
+		for _, s := range []protocol.ByteCount{1937, 1906} {
+			ping, size := d.GetPing()
+			Expect(size).To(Equal(s))
+			ping.OnLost(ping.Frame)
+		}
+		ping, size := d.GetPing()
+		Expect(size).To(Equal(protocol.ByteCount(1890)))
+		tracer.EXPECT().UpdatedMTU(protocol.ByteCount(1875), true)
+		ping.OnLost(ping.Frame)
+		Expect(d.ShouldSendProbe(now.Add(time.Hour))).To(BeFalse())
+	})
+
+	Context("black hole detection", func() {
+		increaseMTU := func() {
+			for _, s := range []protocol.ByteCount{1500, 1750} {
+				ping, size := d.GetPing()
+				ExpectWithOffset(1, size).To(Equal(s))
+				ping.OnAcked(ping.Frame)
+			}
+			ExpectWithOffset(1, discoveredMTU).To(Equal(protocol.ByteCount(1750)))
+		}
+
+		It("detects a reduction of the MTU and finds the new MTU", func() {
+			increaseMTU()
+			// The path MTU drops to 1300 bytes.
+			const realMTU protocol.ByteCount = 1300
+			for i := 0; i < maxLostPacketsBeforeBlackHole; i++ {
+				d.OnPacketLost(1750)
+			}
+			Expect(discoveredMTU).To(Equal(protocol.ByteCount(1750)))
+			d.OnPacketAcked(100)
+			Expect(discoveredMTU).To(Equal(protocol.ByteCount(1750)))
+			// a probe packet of the base size is sent to confirm the black hole
+			Expect(d.ShouldSendProbe(time.Now())).To(BeTrue())
+			ping, size := d.GetPing()
+			Expect(size).To(Equal(startMTU))
+			Expect(d.ShouldSendProbe(time.Now())).To(BeFalse())
+			ping.OnAcked(ping.Frame)
+			Expect(discoveredMTU).To(Equal(startMTU))
+			// a new probe is only sent after the probe delay
+			Expect(d.ShouldSendProbe(time.Now())).To(BeFalse())
+			t := time.Now().Add(mtuProbeDelay * rtt)
+			for d.ShouldSendProbe(t) {
+				ping, size := d.GetPing()
+				Expect(size).To(BeNumerically("<", 1750))
+				if size <= realMTU {
+					ping.OnAcked(ping.Frame)
+				} else {
+					ping.OnLost(ping.Frame)
+				}
+				t = t.Add(mtuProbeDelay * rtt)
+			}
+			Expect(discoveredMTU).To(And(
+				BeNumerically("<=", realMTU),
+				BeNumerically(">=", realMTU-maxMTUDiff),
+			))
+		})
+
+		It("doesn't detect a black hole if the confirmation probe is lost", func() {
+			increaseMTU()
+			for i := 0; i < maxLostPacketsBeforeBlackHole; i++ {
+				d.OnPacketLost(1750)
+			}
+			d.OnPacketAcked(100)
+			Expect(d.ShouldSendProbe(time.Now())).To(BeTrue())
+			ping, size := d.GetPing()
+			Expect(size).To(Equal(startMTU))
+			ping.OnLost(ping.Frame)
+			Expect(discoveredMTU).To(Equal(protocol.ByteCount(1750)))
+			// black hole detection starts over
+			d.OnPacketLost(1750)
+			d.OnPacketAcked(100)
+			Expect(d.ShouldSendProbe(time.Now())).To(BeFalse())
+		})
+
+		It("doesn't detect a black hole if full-size packets are acknowledged while confirming it", func() {
+			increaseMTU()
+			for i := 0; i < maxLostPacketsBeforeBlackHole; i++ {
+				d.OnPacketLost(1750)
+			}
+			d.OnPacketAcked(100)
+			ping, size := d.GetPing()
+			Expect(size).To(Equal(startMTU))
+			d.OnPacketAcked(1750)
+			ping.OnAcked(ping.Frame)
+			Expect(discoveredMTU).To(Equal(protocol.ByteCount(1750)))
+		})
+
+		It("doesn't detect a black hole if no smaller packets are acknowledged", func() {
+			increaseMTU()
+			for i := 0; i < 2*maxLostPacketsBeforeBlackHole; i++ {
+				d.OnPacketLost(1750)
+			}
+			Expect(discoveredMTU).To(Equal(protocol.ByteCount(1750)))
+		})
+
+		It("doesn't detect a black hole if full-size packets are acknowledged", func() {
+			increaseMTU()
+			for i := 0; i < maxLostPacketsBeforeBlackHole-1; i++ {
+				d.OnPacketLost(1750)
+			}
+			d.OnPacketAcked(1750)
+			d.OnPacketLost(1750)
+			d.OnPacketAcked(100)
+			Expect(discoveredMTU).To(Equal(protocol.ByteCount(1750)))
+		})
+
+		It("ignores the loss of packets that are not larger than the base size", func() {
+			increaseMTU()
+			for i := 0; i < 2*maxLostPacketsBeforeBlackHole; i++ {
+				d.OnPacketLost(startMTU)
+			}
+			d.OnPacketAcked(100)
+			Expect(discoveredMTU).To(Equal(protocol.ByteCount(1750)))
+		})
+	})
+
 	It("finds the MTU", func() {
 		const rep = 3000
 		var maxDiff protocol.ByteCount
 		for i := 0; i < rep; i++ {
 			max := protocol.ByteCount(rand.Intn(int(3000-startMTU))) + startMTU + 1
 			currentMTU := startMTU
-			d := newMTUDiscoverer(rttStats, startMTU, max, func(s protocol.ByteCount) { currentMTU = s })
+			d := newMTUDiscoverer(rttStats, startMTU, max, func(s protocol.ByteCount) { currentMTU = s }, nil)
 			now := time.Now()
 			realMTU := protocol.ByteCount(rand.Intn(int(max-startMTU))) + startMTU
 			t := now.Add(mtuProbeDelay * rtt)
@@ -2,6 +2,7 @@ package quic
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
@@ -105,3 +106,24 @@ func (m *connMultiplexer) RemoveConn(c indexableConn) error {
 	delete(m.conns, connIndex)
 	return nil
 }
+
+// getPacketHandlerMap returns the packetHandlerMap that handles the packets received on conn.
+func getPacketHandlerMap(conn net.PacketConn) (*packetHandlerMap, error) {
+	m, ok := getMultiplexer().(*connMultiplexer)
+	if !ok {
+		return nil, errors.New("unexpected multiplexer")
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	addr := conn.LocalAddr()
+	p, ok := m.conns[addr.Network()+" "+addr.String()]
+	if !ok {
+		return nil, errors.New("conn is not used by quic-go")
+	}
+	h, ok := p.manager.(*packetHandlerMap)
+	if !ok {
+		return nil, errors.New("unexpected packet handler manager")
+	}
+	return h, nil
+}
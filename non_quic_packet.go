@@ -0,0 +1,23 @@
+package quic
+
+import (
+	"context"
+	"net"
+)
+
+// ReadNonQUICPacket reads the next packet received on conn that is not a QUIC packet.
+// This allows running QUIC and other protocols (e.g. STUN and TURN, as used by ICE) on the same UDP socket.
+// Packets that don't have the QUIC fixed bit set are considered non-QUIC packets (see RFC 9443).
+// The conn must be in use by a Listener or a dialed connection.
+//
+// Until ReadNonQUICPacket is called for the first time, all packets are handled as QUIC packets.
+// From then on, non-QUIC packets that arrive while the internal queue is full are dropped.
+// If b is too small to hold the packet, the packet is truncated.
+// Packets can be sent by writing to conn directly.
+func ReadNonQUICPacket(ctx context.Context, conn net.PacketConn, b []byte) (int, net.Addr, error) {
+	h, err := getPacketHandlerMap(conn)
+	if err != nil {
+		return 0, nil, err
+	}
+	return h.ReadNonQUICPacket(ctx, b)
+}
@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
@@ -25,6 +26,8 @@ import (
 // rawConn is a connection that allow reading of a receivedPacket.
 type rawConn interface {
 	ReadPacket() (*receivedPacket, error)
+	// UseLargeBuffers makes ReadPacket use buffers that can hold packets larger than protocol.MaxPacketBufferSize.
+	UseLargeBuffers()
 	WritePacket(b []byte, addr net.Addr, oob []byte) (int, error)
 	LocalAddr() net.Addr
 	io.Closer
@@ -63,6 +66,10 @@ type packetHandlerMap struct {
 	statelessResetMutex   sync.Mutex
 	statelessResetHasher  hash.Hash
 
+	// packets that are not QUIC packets
+	// Only created once the application starts reading them, until then these packets are dropped.
+	nonQUICPackets chan *receivedPacket
+
 	tracer logging.Tracer
 	logger utils.Logger
 }
@@ -299,6 +306,12 @@ func (h *packetHandlerMap) SetServer(s unknownPacketHandler) {
 	h.mutex.Unlock()
 }
 
+// UseLargeBuffers makes the packetHandlerMap read packets larger than protocol.MaxPacketBufferSize.
+// It is used when the application configured a Config.MaxPacketSize larger than that.
+func (h *packetHandlerMap) UseLargeBuffers() {
+	h.conn.UseLargeBuffers()
+}
+
 func (h *packetHandlerMap) CloseServer() {
 	h.mutex.Lock()
 	if h.server == nil {
@@ -353,6 +366,7 @@ func (h *packetHandlerMap) close(e error) error {
 	h.closed = true
 	h.mutex.Unlock()
 	wg.Wait()
+	h.removeReceivedPacketHook()
 	return getMultiplexer().RemoveConn(h.conn)
 }
 
@@ -368,15 +382,57 @@ func (h *packetHandlerMap) listen() {
 			h.logger.Debugf("Temporary error reading from conn: %w", err)
 			continue
 		}
+		if isConnRefusedErr(err) {
+			h.logger.Debugf("Ignoring ICMP error reported on a connected UDP socket: %s", err)
+			continue
+		}
 		if err != nil {
 			h.close(err)
 			return
 		}
+		if p.segmentSize > 0 {
+			for _, sp := range splitGROPacket(p) {
+				h.handlePacket(sp)
+			}
+			continue
+		}
 		h.handlePacket(p)
 	}
 }
 
+// splitGROPacket splits a packet that was received using UDP GRO into the individual UDP datagrams.
+// All datagrams are segmentSize bytes long, except for the last one, which may be shorter.
+// Every datagram is copied into a regular packet buffer (or a large buffer, if it doesn't fit), and the large buffer is released.
+// Packets that only contain a single datagram don't have a segmentSize, and are handled without copying.
+func splitGROPacket(p *receivedPacket) []*receivedPacket {
+	packets := make([]*receivedPacket, 0, (len(p.data)+int(p.segmentSize)-1)/int(p.segmentSize))
+	for data := p.data; len(data) > 0; {
+		l := utils.Min(len(data), int(p.segmentSize))
+		buffer := getPacketBufferWithSize(protocol.ByteCount(l))
+		buffer.Data = buffer.Data[:l]
+		copy(buffer.Data, data[:l])
+		packets = append(packets, &receivedPacket{
+			buffer:     buffer,
+			remoteAddr: p.remoteAddr,
+			rcvTime:    p.rcvTime,
+			data:       buffer.Data,
+			ecn:        p.ecn,
+			info:       p.info,
+		})
+		data = data[l:]
+	}
+	p.buffer.Release()
+	return packets
+}
+
 func (h *packetHandlerMap) handlePacket(p *receivedPacket) {
+	h.captureReceivedPacket(p)
+
+	if !wire.IsPotentialQUICPacket(p.data) && h.readsNonQUICPackets() {
+		h.handleNonQUICPacket(p)
+		return
+	}
+
 	connID, err := wire.ParseConnectionID(p.data, h.connIDLen)
 	if err != nil {
 		h.logger.Debugf("error parsing connection ID on packet from %s: %s", p.remoteAddr, err)
@@ -445,6 +501,52 @@ func (h *packetHandlerMap) handlePacket(p *receivedPacket) {
 	h.server.handlePacket(p)
 }
 
+// readsNonQUICPackets says if the application called ReadNonQUICPacket.
+// Until then, all packets are handled as QUIC packets.
+func (h *packetHandlerMap) readsNonQUICPackets() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.nonQUICPackets != nil
+}
+
+func (h *packetHandlerMap) handleNonQUICPacket(p *receivedPacket) {
+	h.mutex.Lock()
+	queue := h.nonQUICPackets
+	h.mutex.Unlock()
+
+	select {
+	case queue <- p:
+	default:
+		h.logger.Debugf("dropping non-QUIC packet from %s (%d bytes), queue full", p.remoteAddr, p.Size())
+		if h.tracer != nil {
+			h.tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropDOSPrevention)
+		}
+		p.buffer.Release()
+	}
+}
+
+// ReadNonQUICPacket reads the next packet that is not a QUIC packet.
+// Packets received before the first call are handled as QUIC packets.
+func (h *packetHandlerMap) ReadNonQUICPacket(ctx context.Context, b []byte) (int, net.Addr, error) {
+	h.mutex.Lock()
+	if h.nonQUICPackets == nil {
+		h.nonQUICPackets = make(chan *receivedPacket, protocol.NonQUICPacketQueueLen)
+	}
+	queue := h.nonQUICPackets
+	h.mutex.Unlock()
+
+	select {
+	case p := <-queue:
+		n := copy(b, p.data)
+		p.buffer.Release()
+		return n, p.remoteAddr, nil
+	case <-h.listening:
+		return 0, nil, net.ErrClosed
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	}
+}
+
 func (h *packetHandlerMap) maybeHandleStatelessReset(data []byte) bool {
 	// stateless resets are always short header packets
 	if wire.IsLongHeaderPacket(data[0]) {
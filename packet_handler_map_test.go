@@ -2,6 +2,7 @@ package quic
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"errors"
 	"net"
@@ -101,6 +102,25 @@ var _ = Describe("Packet Handler Map", func() {
 		Eventually(handler.listening).Should(BeClosed())
 	})
 
+	It("stops reading non-QUIC packets when it is closed", func() {
+		getMultiplexer() // make the sync.Once execute
+		mockMultiplexer := NewMockMultiplexer(mockCtrl)
+		origMultiplexer := connMuxer
+		connMuxer = mockMultiplexer
+		defer func() { connMuxer = origMultiplexer }()
+
+		errChan := make(chan error, 1)
+		go func() {
+			defer GinkgoRecover()
+			_, _, err := handler.ReadNonQUICPacket(context.Background(), make([]byte, 100))
+			errChan <- err
+		}()
+		Consistently(errChan).ShouldNot(Receive())
+		mockMultiplexer.EXPECT().RemoveConn(gomock.Any())
+		close(packetChan)
+		Eventually(errChan).Should(Receive(MatchError(net.ErrClosed)))
+	})
+
 	Context("other operations", func() {
 		AfterEach(func() {
 			// delete connections and the server before closing
@@ -160,6 +180,67 @@ var _ = Describe("Packet Handler Map", func() {
 				})
 			})
 
+			Context("non-QUIC packets", func() {
+				addr := &net.UDPAddr{IP: net.IPv4(9, 8, 7, 6), Port: 1234}
+				stunPacket := []byte{0, 1, 0, 0, 0x21, 0x12, 0xa4, 0x42}
+
+				getNonQUICPacket := func() *receivedPacket {
+					buf := getPacketBuffer()
+					buf.Data = append(buf.Data[:0], stunPacket...)
+					return &receivedPacket{buffer: buf, remoteAddr: addr, data: buf.Data}
+				}
+
+				It("handles packets without the fixed bit as QUIC packets if the application doesn't read non-QUIC packets", func() {
+					// the packet is too short to trigger a stateless reset, so it is ignored
+					handler.handlePacket(getNonQUICPacket())
+					Expect(handler.nonQUICPackets).To(BeNil())
+				})
+
+				It("passes non-QUIC packets to the application", func() {
+					type result struct {
+						data []byte
+						addr net.Addr
+						err  error
+					}
+					resultChan := make(chan result, 1)
+					go func() {
+						defer GinkgoRecover()
+						b := make([]byte, 100)
+						n, addr, err := handler.ReadNonQUICPacket(context.Background(), b)
+						resultChan <- result{data: b[:n], addr: addr, err: err}
+					}()
+					Eventually(func() bool {
+						handler.mutex.Lock()
+						defer handler.mutex.Unlock()
+						return handler.nonQUICPackets != nil
+					}).Should(BeTrue())
+					packetChan <- packetToRead{addr: addr, data: stunPacket}
+					var res result
+					Eventually(resultChan).Should(Receive(&res))
+					Expect(res.err).ToNot(HaveOccurred())
+					Expect(res.data).To(Equal(stunPacket))
+					Expect(res.addr).To(Equal(addr))
+				})
+
+				It("drops non-QUIC packets when the queue is full", func() {
+					ctx, cancel := context.WithCancel(context.Background())
+					cancel()
+					_, _, err := handler.ReadNonQUICPacket(ctx, nil)
+					Expect(err).To(MatchError(context.Canceled))
+					for i := 0; i < protocol.NonQUICPacketQueueLen; i++ {
+						handler.handlePacket(getNonQUICPacket())
+					}
+					tracer.EXPECT().DroppedPacket(addr, logging.PacketTypeNotDetermined, protocol.ByteCount(len(stunPacket)), logging.PacketDropDOSPrevention)
+					handler.handlePacket(getNonQUICPacket())
+					for i := 0; i < protocol.NonQUICPacketQueueLen; i++ {
+						b := make([]byte, 100)
+						n, _, err := handler.ReadNonQUICPacket(context.Background(), b)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(b[:n]).To(Equal(stunPacket))
+					}
+				})
+			})
+
 			It("deletes removed connections immediately", func() {
 				handler.deleteRetiredConnsAfter = time.Hour
 				connID := protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8})
@@ -407,6 +488,47 @@ var _ = Describe("Packet Handler Map", func() {
 					Eventually(destroyed).Should(BeClosed())
 				})
 
+				It("handles stateless resets using the token of a connection ID that is not in use", func() {
+					packetHandler := NewMockPacketHandler(mockCtrl)
+					m := newConnIDManager(
+						protocol.ParseConnectionID([]byte{0, 0, 0, 0, 0}),
+						func(token protocol.StatelessResetToken) { handler.AddResetToken(token, packetHandler) },
+						handler.RemoveResetToken,
+						func(wire.Frame) {},
+					)
+					m.SetStatelessResetToken(protocol.StatelessResetToken{0xde, 0xad})
+					for i := uint8(1); i <= 2; i++ {
+						Expect(m.Add(&wire.NewConnectionIDFrame{
+							SequenceNumber:      uint64(i),
+							ConnectionID:        protocol.ParseConnectionID([]byte{i, i, i, i, i}),
+							StatelessResetToken: protocol.StatelessResetToken{i},
+						})).To(Succeed())
+					}
+					m.SetHandshakeComplete()
+					Expect(m.Rotate()).To(Succeed())
+					Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{1, 1, 1, 1, 1})))
+
+					// The token of the retired connection ID was removed.
+					retiredToken := protocol.StatelessResetToken{0xde, 0xad}
+					packet := append([]byte{0x40} /* short header packet */, make([]byte, 50)...)
+					packet = append(packet, retiredToken[:]...)
+					handler.handlePacket(&receivedPacket{data: packet, buffer: getPacketBuffer()})
+
+					// The peer might use the token of any connection ID that hasn't been retired.
+					destroyed := make(chan struct{})
+					token := protocol.StatelessResetToken{2}
+					packet = append([]byte{0x40} /* short header packet */, make([]byte, 50)...)
+					packetHandler.EXPECT().destroy(gomock.Any()).Do(func(err error) {
+						defer GinkgoRecover()
+						defer close(destroyed)
+						var resetErr *StatelessResetError
+						Expect(errors.As(err, &resetErr)).To(BeTrue())
+						Expect(resetErr.Token).To(Equal(token))
+					})
+					packetChan <- packetToRead{data: append(packet, token[:]...)}
+					Eventually(destroyed).Should(BeClosed())
+				})
+
 				It("removes reset tokens", func() {
 					connID := protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef, 0x42})
 					packetHandler := NewMockPacketHandler(mockCtrl)
@@ -497,3 +619,64 @@ var _ = Describe("Packet Handler Map", func() {
 		})
 	})
 })
+
+var _ = Describe("Splitting GRO packets", func() {
+	It("splits a packet into segments, with a trailing short segment", func() {
+		data := make([]byte, 2*100+42)
+		rand.Read(data)
+		buffer := getLargePacketBuffer()
+		buffer.Data = append(buffer.Data, data...)
+		addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
+		rcvTime := time.Now().Add(-time.Second)
+		info := &packetInfo{addr: net.IPv4(127, 0, 0, 1)}
+		packets := splitGROPacket(&receivedPacket{
+			buffer:      buffer,
+			remoteAddr:  addr,
+			rcvTime:     rcvTime,
+			data:        buffer.Data,
+			ecn:         protocol.ECT1,
+			info:        info,
+			segmentSize: 100,
+		})
+		Expect(packets).To(HaveLen(3))
+		Expect(packets[0].data).To(Equal(data[:100]))
+		Expect(packets[1].data).To(Equal(data[100:200]))
+		Expect(packets[2].data).To(Equal(data[200:]))
+		for _, p := range packets {
+			Expect(p.remoteAddr).To(Equal(addr))
+			Expect(p.rcvTime).To(Equal(rcvTime))
+			Expect(p.ecn).To(Equal(protocol.ECT1))
+			Expect(p.info).To(Equal(info))
+			Expect(p.segmentSize).To(BeZero())
+			Expect(p.buffer).ToNot(Equal(buffer))
+			Expect(p.data).To(Equal(p.buffer.Data))
+		}
+	})
+
+	It("splits a packet into segments that are larger than the regular packet buffers", func() {
+		data := make([]byte, 2*9000)
+		rand.Read(data)
+		buffer := getLargePacketBuffer()
+		buffer.Data = append(buffer.Data, data...)
+		packets := splitGROPacket(&receivedPacket{
+			buffer:      buffer,
+			data:        buffer.Data,
+			segmentSize: 9000,
+		})
+		Expect(packets).To(HaveLen(2))
+		Expect(packets[0].data).To(Equal(data[:9000]))
+		Expect(packets[1].data).To(Equal(data[9000:]))
+	})
+
+	It("handles a packet containing a single segment", func() {
+		buffer := getLargePacketBuffer()
+		buffer.Data = append(buffer.Data, []byte("foobar")...)
+		packets := splitGROPacket(&receivedPacket{
+			buffer:      buffer,
+			data:        buffer.Data,
+			segmentSize: 6,
+		})
+		Expect(packets).To(HaveLen(1))
+		Expect(packets[0].data).To(Equal([]byte("foobar")))
+	})
+})
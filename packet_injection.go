@@ -0,0 +1,64 @@
+//go:build quic_testing
+
+package quic
+
+import (
+	"net"
+	"sync"
+)
+
+// The functions in this file allow bypassing quic-go's packet handling.
+// They are intended for testing middleboxes and for interop testing,
+// and are only available when building with the quic_testing build tag.
+
+var (
+	receivedPacketHooksMutex sync.RWMutex
+	receivedPacketHooks      = make(map[*packetHandlerMap]func(net.Addr, []byte))
+)
+
+// InjectPacket sends a raw UDP payload to addr, using the socket that quic-go manages for conn.
+// The payload is sent as is, it isn't processed by any QUIC connection.
+// The conn must be in use by a Listener or a dialed connection.
+func InjectPacket(conn net.PacketConn, addr net.Addr, b []byte) error {
+	h, err := getPacketHandlerMap(conn)
+	if err != nil {
+		return err
+	}
+	_, err = h.conn.WritePacket(b, addr, nil)
+	return err
+}
+
+// SetReceivedPacketHook sets a callback that is called for every UDP datagram received on conn,
+// before the datagram is passed to the QUIC connection it belongs to.
+// The data must not be modified, and must not be used after the callback returns.
+// The hook is called from the go routine that reads from the socket, so it must not block.
+// Passing a nil hook removes the hook.
+func SetReceivedPacketHook(conn net.PacketConn, hook func(addr net.Addr, data []byte)) error {
+	h, err := getPacketHandlerMap(conn)
+	if err != nil {
+		return err
+	}
+	receivedPacketHooksMutex.Lock()
+	defer receivedPacketHooksMutex.Unlock()
+	if hook == nil {
+		delete(receivedPacketHooks, h)
+	} else {
+		receivedPacketHooks[h] = hook
+	}
+	return nil
+}
+
+func (h *packetHandlerMap) captureReceivedPacket(p *receivedPacket) {
+	receivedPacketHooksMutex.RLock()
+	hook, ok := receivedPacketHooks[h]
+	receivedPacketHooksMutex.RUnlock()
+	if ok {
+		hook(p.remoteAddr, p.data)
+	}
+}
+
+func (h *packetHandlerMap) removeReceivedPacketHook() {
+	receivedPacketHooksMutex.Lock()
+	delete(receivedPacketHooks, h)
+	receivedPacketHooksMutex.Unlock()
+}
@@ -0,0 +1,7 @@
+//go:build !quic_testing
+
+package quic
+
+func (h *packetHandlerMap) captureReceivedPacket(*receivedPacket) {}
+
+func (h *packetHandlerMap) removeReceivedPacketHook() {}
@@ -0,0 +1,67 @@
+//go:build quic_testing
+
+package quic
+
+import (
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Packet Injection", func() {
+	var (
+		conn    *net.UDPConn
+		peer    *net.UDPConn
+		manager packetHandlerManager
+	)
+
+	BeforeEach(func() {
+		addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+		Expect(err).ToNot(HaveOccurred())
+		conn, err = net.ListenUDP("udp", addr)
+		Expect(err).ToNot(HaveOccurred())
+		peer, err = net.ListenUDP("udp", addr)
+		Expect(err).ToNot(HaveOccurred())
+		manager, err = getMultiplexer().AddConn(conn, 4, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(manager.Destroy()).To(Succeed())
+		Expect(peer.Close()).To(Succeed())
+	})
+
+	It("injects packets", func() {
+		Expect(InjectPacket(conn, peer.LocalAddr(), []byte("foobar"))).To(Succeed())
+		b := make([]byte, 100)
+		Expect(peer.SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+		n, addr, err := peer.ReadFrom(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b[:n]).To(Equal([]byte("foobar")))
+		Expect(addr.String()).To(Equal(conn.LocalAddr().String()))
+	})
+
+	It("captures received packets", func() {
+		type capturedPacket struct {
+			addr net.Addr
+			data []byte
+		}
+		captured := make(chan capturedPacket, 1)
+		Expect(SetReceivedPacketHook(conn, func(addr net.Addr, data []byte) {
+			captured <- capturedPacket{addr: addr, data: append([]byte{}, data...)}
+		})).To(Succeed())
+		_, err := peer.WriteTo([]byte("malformed"), conn.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		var p capturedPacket
+		Eventually(captured).Should(Receive(&p))
+		Expect(p.data).To(Equal([]byte("malformed")))
+		Expect(p.addr.String()).To(Equal(peer.LocalAddr().String()))
+	})
+
+	It("errors for conns that aren't used by quic-go", func() {
+		Expect(InjectPacket(peer, conn.LocalAddr(), []byte("foobar"))).To(MatchError("conn is not used by quic-go"))
+		Expect(SetReceivedPacketHook(peer, func(net.Addr, []byte) {})).To(MatchError("conn is not used by quic-go"))
+	})
+})
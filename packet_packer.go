@@ -24,6 +24,7 @@ type packer interface {
 
 	SetMaxPacketSize(protocol.ByteCount)
 	PackMTUProbePacket(ping ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error)
+	PackPathProbePacket(connID protocol.ConnectionID, challenge ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error)
 
 	HandleTransportParameters(*wire.TransportParameters)
 	SetToken([]byte)
@@ -179,7 +180,7 @@ func newPacketPacker(
 	handshakeStream cryptoStream,
 	packetNumberManager packetNumberManager,
 	retransmissionQueue *retransmissionQueue,
-	remoteAddr net.Addr, // only used for determining the max packet size
+	maxPacketSize protocol.ByteCount,
 	cryptoSetup sealingManager,
 	framer frameSource,
 	acks ackFrameSource,
@@ -200,7 +201,7 @@ func newPacketPacker(
 		framer:              framer,
 		acks:                acks,
 		pnManager:           packetNumberManager,
-		maxPacketSize:       getMaxPacketSize(remoteAddr),
+		maxPacketSize:       maxPacketSize,
 	}
 }
 
@@ -289,7 +290,7 @@ func (p *packetPacker) packConnectionClose(
 		numPackets++
 	}
 	contents := make([]*packetContents, 0, numPackets)
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferWithSize(p.maxPacketSize)
 	for i, encLevel := range encLevels {
 		if sealers[i] == nil {
 			continue
@@ -331,6 +332,22 @@ func (p *packetPacker) initialPaddingLen(frames []ackhandler.Frame, size protoco
 	return p.maxPacketSize - size
 }
 
+// pathValidationPaddingLen returns the padding needed for packets containing a PATH_CHALLENGE or a PATH_RESPONSE frame.
+// These packets are expanded to at least 1200 bytes, to check that the path supports this packet size (see section 8.2 of RFC 9000).
+// size is the expected size of the packet, if no padding was applied.
+func (p *packetPacker) pathValidationPaddingLen(frames []ackhandler.Frame, size protocol.ByteCount) protocol.ByteCount {
+	if size >= protocol.MinInitialPacketSize {
+		return 0
+	}
+	for _, f := range frames {
+		switch f.Frame.(type) {
+		case *wire.PathChallengeFrame, *wire.PathResponseFrame:
+			return protocol.MinInitialPacketSize - size
+		}
+	}
+	return 0
+}
+
 // PackCoalescedPacket packs a new packet.
 // It packs an Initial / Handshake if there is data to send in these packet number spaces.
 // It should only be called before the handshake is confirmed.
@@ -405,7 +422,7 @@ func (p *packetPacker) PackCoalescedPacket(onlyAck bool) (*coalescedPacket, erro
 		return nil, nil
 	}
 
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferWithSize(p.maxPacketSize)
 	packet := &coalescedPacket{
 		buffer:  buffer,
 		packets: make([]*packetContents, 0, numPackets),
@@ -446,8 +463,9 @@ func (p *packetPacker) PackPacket(onlyAck bool) (*packedPacket, error) {
 	if payload == nil {
 		return nil, nil
 	}
-	buffer := getPacketBuffer()
-	cont, err := p.appendPacket(buffer, hdr, payload, 0, protocol.Encryption1RTT, sealer, false)
+	padding := p.pathValidationPaddingLen(payload.frames, p.packetLength(hdr, payload)+protocol.ByteCount(sealer.Overhead()))
+	buffer := getPacketBufferWithSize(p.maxPacketSize)
+	cont, err := p.appendPacket(buffer, hdr, payload, padding, protocol.Encryption1RTT, sealer, false)
 	if err != nil {
 		return nil, err
 	}
@@ -673,10 +691,14 @@ func (p *packetPacker) MaybePackProbePacket(encLevel protocol.EncryptionLevel) (
 	}
 	size := p.packetLength(hdr, payload) + protocol.ByteCount(sealer.Overhead())
 	var padding protocol.ByteCount
-	if encLevel == protocol.EncryptionInitial {
+	//nolint:exhaustive // Probe packets are never sent for 0-RTT.
+	switch encLevel {
+	case protocol.EncryptionInitial:
 		padding = p.initialPaddingLen(payload.frames, size)
+	case protocol.Encryption1RTT:
+		padding = p.pathValidationPaddingLen(payload.frames, size)
 	}
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferWithSize(p.maxPacketSize)
 	cont, err := p.appendPacket(buffer, hdr, payload, padding, encLevel, sealer, false)
 	if err != nil {
 		return nil, err
@@ -692,7 +714,7 @@ func (p *packetPacker) PackMTUProbePacket(ping ackhandler.Frame, size protocol.B
 		frames: []ackhandler.Frame{ping},
 		length: ping.Length(p.version),
 	}
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferWithSize(size)
 	sealer, err := p.cryptoSetup.Get1RTTSealer()
 	if err != nil {
 		return nil, err
@@ -710,6 +732,31 @@ func (p *packetPacker) PackMTUProbePacket(ping ackhandler.Frame, size protocol.B
 	}, nil
 }
 
+// PackPathProbePacket packs a packet containing a PATH_CHALLENGE frame, padded to size.
+// The packet is sent on a different path, and therefore uses a different connection ID.
+func (p *packetPacker) PackPathProbePacket(connID protocol.ConnectionID, challenge ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error) {
+	payload := &payload{
+		frames: []ackhandler.Frame{challenge},
+		length: challenge.Length(p.version),
+	}
+	buffer := getPacketBuffer()
+	sealer, err := p.cryptoSetup.Get1RTTSealer()
+	if err != nil {
+		return nil, err
+	}
+	hdr := p.getShortHeader(sealer.KeyPhase())
+	hdr.DestConnectionID = connID
+	padding := size - p.packetLength(hdr, payload) - protocol.ByteCount(sealer.Overhead())
+	contents, err := p.appendPacket(buffer, hdr, payload, padding, protocol.Encryption1RTT, sealer, false)
+	if err != nil {
+		return nil, err
+	}
+	return &packedPacket{
+		buffer:         buffer,
+		packetContents: contents,
+	}, nil
+}
+
 func (p *packetPacker) getShortHeader(kp protocol.KeyPhaseBit) *wire.ExtendedHeader {
 	pn, pnLen := p.pnManager.PeekPacketNumber(protocol.Encryption1RTT)
 	hdr := &wire.ExtendedHeader{}
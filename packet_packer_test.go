@@ -100,7 +100,7 @@ var _ = Describe("Packet packer", func() {
 			handshakeStream,
 			pnManager,
 			retransmissionQueue,
-			&net.TCPAddr{},
+			maxPacketSize,
 			sealingManager,
 			framer,
 			ackFramer,
@@ -581,6 +581,29 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.buffer.Len()).ToNot(BeZero())
 			})
 
+			for _, f := range []wire.Frame{
+				&wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}},
+				&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}},
+			} {
+				frame := f
+
+				It(fmt.Sprintf("pads packets containing a %T to the minimum packet size", frame), func() {
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+					sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+					framer.EXPECT().HasData().Return(true)
+					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+					expectAppendControlFrames(ackhandler.Frame{Frame: frame})
+					expectAppendStreamFrames()
+					p, err := packer.PackPacket(false)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p).ToNot(BeNil())
+					Expect(p.frames).To(Equal([]ackhandler.Frame{{Frame: frame}}))
+					Expect(p.length).To(BeEquivalentTo(protocol.MinInitialPacketSize))
+					Expect(p.buffer.Data).To(HaveLen(protocol.MinInitialPacketSize))
+				})
+			}
+
 			It("packs DATAGRAM frames", func() {
 				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true)
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
@@ -1504,6 +1527,25 @@ var _ = Describe("Packet packer", func() {
 				Expect(packet.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
 				Expect(packet.frames).To(HaveLen(1))
 				Expect(packet.frames[0].Frame).To(Equal(f))
+				Expect(packet.length).To(BeNumerically("<", protocol.MinInitialPacketSize))
+			})
+
+			It("pads a 1-RTT probe packet containing a PATH_CHALLENGE frame", func() {
+				f := &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				framer.EXPECT().HasData().Return(true)
+				expectAppendControlFrames(ackhandler.Frame{Frame: f})
+				expectAppendStreamFrames()
+
+				packet, err := packer.MaybePackProbePacket(protocol.Encryption1RTT)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(packet).ToNot(BeNil())
+				Expect(packet.frames).To(Equal([]ackhandler.Frame{{Frame: f}}))
+				Expect(packet.length).To(BeEquivalentTo(protocol.MinInitialPacketSize))
+				Expect(packet.buffer.Data).To(HaveLen(protocol.MinInitialPacketSize))
 			})
 
 			It("packs a full size 1-RTT probe packet", func() {
@@ -1556,6 +1598,23 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.buffer.Data).To(HaveLen(int(probePacketSize)))
 				Expect(p.packetContents.isMTUProbePacket).To(BeTrue())
 			})
+
+			It("packs a path probe packet", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43))
+				connID := protocol.ParseConnectionID([]byte{0xde, 0xca, 0xfb, 0xad})
+				challenge := ackhandler.Frame{Frame: &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}}
+				p, err := packer.PackPathProbePacket(connID, challenge, protocol.MinInitialPacketSize)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.length).To(BeEquivalentTo(protocol.MinInitialPacketSize))
+				Expect(p.header.IsLongHeader).To(BeFalse())
+				Expect(p.header.DestConnectionID).To(Equal(connID))
+				Expect(p.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
+				Expect(p.frames).To(Equal([]ackhandler.Frame{challenge}))
+				Expect(p.buffer.Data).To(HaveLen(protocol.MinInitialPacketSize))
+				Expect(p.packetContents.isMTUProbePacket).To(BeFalse())
+			})
 		})
 	})
 })
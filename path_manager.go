@@ -0,0 +1,78 @@
+package quic
+
+import (
+	"crypto/rand"
+	"net"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/fkwhite/quic-go/internal/wire"
+)
+
+type pathChallenge struct {
+	data   [8]byte
+	local  net.Addr
+	remote net.Addr
+}
+
+func (c *pathChallenge) isOnPath(local, remote net.Addr) bool {
+	return c.local.String() == local.String() && c.remote.String() == remote.String()
+}
+
+// The pathManager keeps track of the PATH_CHALLENGE frames sent, and matches PATH_RESPONSE frames to them.
+// Multiple paths can be probed at the same time.
+// Every challenge is bound to the local and remote address of the path it was sent on.
+type pathManager struct {
+	// outstanding challenges, ordered by the time they were created
+	challenges []pathChallenge
+}
+
+func newPathManager() *pathManager {
+	return &pathManager{}
+}
+
+// NewChallenge creates a new PATH_CHALLENGE frame for the path between the local and the remote address.
+// If the maximum number of outstanding challenges is reached, the oldest challenge is discarded.
+func (m *pathManager) NewChallenge(local, remote net.Addr) *wire.PathChallengeFrame {
+	var data [8]byte
+	rand.Read(data[:])
+	if len(m.challenges) >= protocol.MaxOutstandingPathChallenges {
+		m.challenges = m.challenges[1:]
+	}
+	m.challenges = append(m.challenges, pathChallenge{data: data, local: local, remote: remote})
+	return &wire.PathChallengeFrame{Data: data}
+}
+
+// HandlePathResponse matches a PATH_RESPONSE frame to the outstanding challenges.
+// A PATH_RESPONSE validates the path that the corresponding PATH_CHALLENGE was sent on,
+// independent of the path it was received on (see section 8.2.3 of RFC 9000).
+// If a path is validated, it returns the local and the remote address of this path,
+// and all challenges for this path are removed.
+// Unknown responses (e.g. duplicates, or responses to discarded challenges) don't validate any path,
+// and should be ignored by the caller.
+func (m *pathManager) HandlePathResponse(f *wire.PathResponseFrame) (local, remote net.Addr, ok bool) {
+	for _, c := range m.challenges {
+		if c.data != f.Data {
+			continue
+		}
+		local, remote = c.local, c.remote
+		challenges := m.challenges[:0]
+		for _, other := range m.challenges {
+			if !other.isOnPath(local, remote) {
+				challenges = append(challenges, other)
+			}
+		}
+		m.challenges = challenges
+		return local, remote, true
+	}
+	return nil, nil, false
+}
+
+// HasOutstandingChallenge says if there's an outstanding challenge for the path between the local and the remote address.
+func (m *pathManager) HasOutstandingChallenge(local, remote net.Addr) bool {
+	for _, c := range m.challenges {
+		if c.isOnPath(local, remote) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package quic
+
+import (
+	"net"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/fkwhite/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path Manager", func() {
+	var m *pathManager
+	local := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1234}
+	remote1 := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443}
+	remote2 := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 443}
+
+	BeforeEach(func() {
+		m = newPathManager()
+	})
+
+	It("uses random data for challenges", func() {
+		f1 := m.NewChallenge(local, remote1)
+		f2 := m.NewChallenge(local, remote1)
+		Expect(f1.Data).ToNot(Equal(f2.Data))
+	})
+
+	It("validates a path", func() {
+		f := m.NewChallenge(local, remote1)
+		Expect(m.HasOutstandingChallenge(local, remote1)).To(BeTrue())
+		l, r, ok := m.HandlePathResponse(&wire.PathResponseFrame{Data: f.Data})
+		Expect(ok).To(BeTrue())
+		Expect(l).To(Equal(local))
+		Expect(r).To(Equal(remote1))
+		Expect(m.HasOutstandingChallenge(local, remote1)).To(BeFalse())
+	})
+
+	It("rejects responses that don't match any challenge", func() {
+		m.NewChallenge(local, remote1)
+		_, _, ok := m.HandlePathResponse(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}})
+		Expect(ok).To(BeFalse())
+		Expect(m.HasOutstandingChallenge(local, remote1)).To(BeTrue())
+	})
+
+	It("handles concurrent challenges for two paths, with responses arriving out of order", func() {
+		f1 := m.NewChallenge(local, remote1)
+		f2 := m.NewChallenge(local, remote2)
+		l, r, ok := m.HandlePathResponse(&wire.PathResponseFrame{Data: f2.Data})
+		Expect(ok).To(BeTrue())
+		Expect(l).To(Equal(local))
+		Expect(r).To(Equal(remote2))
+		Expect(m.HasOutstandingChallenge(local, remote1)).To(BeTrue())
+		Expect(m.HasOutstandingChallenge(local, remote2)).To(BeFalse())
+		l, r, ok = m.HandlePathResponse(&wire.PathResponseFrame{Data: f1.Data})
+		Expect(ok).To(BeTrue())
+		Expect(l).To(Equal(local))
+		Expect(r).To(Equal(remote1))
+		Expect(m.HasOutstandingChallenge(local, remote1)).To(BeFalse())
+	})
+
+	It("removes all challenges for a path when the path is validated", func() {
+		f1 := m.NewChallenge(local, remote1)
+		f2 := m.NewChallenge(local, remote1)
+		_, _, ok := m.HandlePathResponse(&wire.PathResponseFrame{Data: f2.Data})
+		Expect(ok).To(BeTrue())
+		// a late response to the first challenge doesn't validate the path a second time
+		_, _, ok = m.HandlePathResponse(&wire.PathResponseFrame{Data: f1.Data})
+		Expect(ok).To(BeFalse())
+	})
+
+	It("ignores duplicate responses", func() {
+		f := m.NewChallenge(local, remote1)
+		_, _, ok := m.HandlePathResponse(&wire.PathResponseFrame{Data: f.Data})
+		Expect(ok).To(BeTrue())
+		// the duplicate doesn't validate the path again
+		_, _, ok = m.HandlePathResponse(&wire.PathResponseFrame{Data: f.Data})
+		Expect(ok).To(BeFalse())
+		Expect(m.HasOutstandingChallenge(local, remote1)).To(BeFalse())
+	})
+
+	It("discards the oldest challenge when too many challenges are outstanding", func() {
+		var frames []*wire.PathChallengeFrame
+		for i := 0; i < protocol.MaxOutstandingPathChallenges+1; i++ {
+			remote := &net.UDPAddr{IP: net.IPv4(10, 0, 1, byte(i)), Port: 443}
+			frames = append(frames, m.NewChallenge(local, remote))
+		}
+		_, _, ok := m.HandlePathResponse(&wire.PathResponseFrame{Data: frames[0].Data})
+		Expect(ok).To(BeFalse())
+		for _, f := range frames[1:] {
+			_, _, ok := m.HandlePathResponse(&wire.PathResponseFrame{Data: f.Data})
+			Expect(ok).To(BeTrue())
+		}
+	})
+})
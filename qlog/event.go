@@ -225,6 +225,20 @@ func (e eventVersionNegotiationReceived) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.ArrayKey("supported_versions", versions(e.SupportedVersions))
 }
 
+type eventHandshakeCompleted struct {
+	Attempted0RTT bool
+	Accepted0RTT  bool
+}
+
+func (e eventHandshakeCompleted) Category() category { return categoryTransport }
+func (e eventHandshakeCompleted) Name() string       { return "handshake_completed" }
+func (e eventHandshakeCompleted) IsNil() bool        { return false }
+
+func (e eventHandshakeCompleted) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.BoolKey("0rtt_attempted", e.Attempted0RTT)
+	enc.BoolKey("0rtt_accepted", e.Accepted0RTT)
+}
+
 type eventPacketBuffered struct {
 	PacketType logging.PacketType
 }
@@ -312,6 +326,38 @@ func (e eventUpdatedPTO) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.Uint32Key("pto_count", e.Value)
 }
 
+type eventAckForUnsentPacket struct {
+	PacketType   logging.PacketType
+	PacketNumber protocol.PacketNumber
+}
+
+func (e eventAckForUnsentPacket) Category() category { return categoryRecovery }
+func (e eventAckForUnsentPacket) Name() string       { return "ack_for_unsent_packet" }
+func (e eventAckForUnsentPacket) IsNil() bool        { return false }
+
+func (e eventAckForUnsentPacket) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.ObjectKey("header", packetHeaderWithTypeAndPacketNumber{
+		PacketType:   e.PacketType,
+		PacketNumber: e.PacketNumber,
+	})
+}
+
+type eventSpuriousLoss struct {
+	PacketType   logging.PacketType
+	PacketNumber protocol.PacketNumber
+}
+
+func (e eventSpuriousLoss) Category() category { return categoryRecovery }
+func (e eventSpuriousLoss) Name() string       { return "spurious_loss" }
+func (e eventSpuriousLoss) IsNil() bool        { return false }
+
+func (e eventSpuriousLoss) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.ObjectKey("header", packetHeaderWithTypeAndPacketNumber{
+		PacketType:   e.PacketType,
+		PacketNumber: e.PacketNumber,
+	})
+}
+
 type eventPacketLost struct {
 	PacketType   logging.PacketType
 	PacketNumber protocol.PacketNumber
@@ -330,6 +376,24 @@ func (e eventPacketLost) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("trigger", e.Trigger.String())
 }
 
+type eventBlocked struct {
+	Frame  frame
+	Remote bool
+}
+
+func (e eventBlocked) Category() category { return categoryTransport }
+func (e eventBlocked) Name() string       { return "blocked" }
+func (e eventBlocked) IsNil() bool        { return false }
+
+func (e eventBlocked) MarshalJSONObject(enc *gojay.Encoder) {
+	owner := ownerLocal
+	if e.Remote {
+		owner = ownerRemote
+	}
+	enc.StringKey("owner", owner.String())
+	enc.ObjectKey("frame", e.Frame)
+}
+
 type eventKeyUpdated struct {
 	Trigger    keyUpdateTrigger
 	KeyType    keyType
@@ -503,6 +567,37 @@ func (e eventLossTimerCanceled) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("event_type", "cancelled")
 }
 
+type eventStreamStateUpdated struct {
+	StreamID protocol.StreamID
+	State    string
+	// only set for closed streams
+	Send    *logging.StreamCloseState
+	Receive *logging.StreamCloseState
+}
+
+func (e eventStreamStateUpdated) Category() category { return categoryTransport }
+func (e eventStreamStateUpdated) Name() string       { return "stream_state_updated" }
+func (e eventStreamStateUpdated) IsNil() bool        { return false }
+
+func (e eventStreamStateUpdated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Int64Key("stream_id", int64(e.StreamID))
+	enc.StringKey("stream_type", streamType(e.StreamID.Type()).String())
+	enc.StringKey("new", e.State)
+	if e.Send != nil {
+		marshalStreamCloseState(enc, "sending", e.Send)
+	}
+	if e.Receive != nil {
+		marshalStreamCloseState(enc, "receiving", e.Receive)
+	}
+}
+
+func marshalStreamCloseState(enc *gojay.Encoder, side string, s *logging.StreamCloseState) {
+	enc.Int64Key(side+"_final_size", int64(s.FinalSize))
+	if s.ErrorCode != nil {
+		enc.Uint64Key(side+"_error_code", uint64(*s.ErrorCode))
+	}
+}
+
 type eventCongestionStateUpdated struct {
 	state congestionState
 }
@@ -515,6 +610,20 @@ func (e eventCongestionStateUpdated) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("new", e.state.String())
 }
 
+type eventMTUUpdated struct {
+	mtu  protocol.ByteCount
+	done bool
+}
+
+func (e eventMTUUpdated) Category() category { return categoryConnectivity }
+func (e eventMTUUpdated) Name() string       { return "mtu_updated" }
+func (e eventMTUUpdated) IsNil() bool        { return false }
+
+func (e eventMTUUpdated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Int64Key("new", int64(e.mtu))
+	enc.BoolKeyOmitEmpty("done", e.done)
+}
+
 type eventGeneric struct {
 	name string
 	msg  string
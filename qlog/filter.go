@@ -0,0 +1,55 @@
+package qlog
+
+import (
+	"context"
+	"io"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/fkwhite/quic-go/logging"
+)
+
+// An EventFilter decides if an event is recorded.
+// It is called with the category (e.g. "recovery") and the name (e.g. "packet_lost") of the event.
+// Filtered events are dropped before they are encoded.
+type EventFilter func(category, name string) bool
+
+// FilterCategories returns an EventFilter that only records events of the given categories,
+// e.g. "transport", "security" or "recovery".
+func FilterCategories(categories ...string) EventFilter {
+	m := make(map[string]struct{}, len(categories))
+	for _, c := range categories {
+		m[c] = struct{}{}
+	}
+	return func(category, _ string) bool {
+		_, ok := m[category]
+		return ok
+	}
+}
+
+type filteringTracer struct {
+	logging.NullTracer
+
+	getLogWriter func(p logging.Perspective, connectionID []byte) io.WriteCloser
+	filter       EventFilter
+}
+
+var _ logging.Tracer = &filteringTracer{}
+
+// NewTracerWithFilter creates a new qlog tracer that only records the events selected by filter.
+func NewTracerWithFilter(getLogWriter func(p logging.Perspective, connectionID []byte) io.WriteCloser, filter EventFilter) logging.Tracer {
+	return &filteringTracer{getLogWriter: getLogWriter, filter: filter}
+}
+
+func (t *filteringTracer) TracerForConnection(_ context.Context, p logging.Perspective, odcid protocol.ConnectionID) logging.ConnectionTracer {
+	if w := t.getLogWriter(p, odcid.Bytes()); w != nil {
+		return NewConnectionTracerWithFilter(w, p, odcid, t.filter)
+	}
+	return nil
+}
+
+// NewConnectionTracerWithFilter creates a new tracer to record a qlog for a connection.
+// Only the events selected by filter are recorded.
+// The qlog header is always written, so the resulting file is a valid qlog even if all events are filtered.
+func NewConnectionTracerWithFilter(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID, filter EventFilter) logging.ConnectionTracer {
+	return newConnectionTracer(w, p, odcid, nil, nil, filter)
+}
@@ -0,0 +1,110 @@
+package qlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/fkwhite/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Filtering", func() {
+	// parseEvents checks that the qlog starts with the qlog header, and returns the names of the events
+	parseEvents := func(buf *bytes.Buffer) []string {
+		line, err := buf.ReadBytes('\n')
+		Expect(err).ToNot(HaveOccurred())
+		m := make(map[string]interface{})
+		Expect(json.Unmarshal(line, &m)).To(Succeed())
+		Expect(m).To(HaveKey("trace"))
+
+		var names []string
+		for buf.Len() > 0 {
+			line, err := buf.ReadBytes('\n')
+			Expect(err).ToNot(HaveOccurred())
+			ev := make(map[string]interface{})
+			Expect(json.Unmarshal(line, &ev)).To(Succeed())
+			names = append(names, ev["name"].(string))
+		}
+		return names
+	}
+
+	It("filters by category", func() {
+		filter := FilterCategories("recovery", "security")
+		Expect(filter("recovery", "packet_lost")).To(BeTrue())
+		Expect(filter("security", "key_updated")).To(BeTrue())
+		Expect(filter("transport", "packet_sent")).To(BeFalse())
+	})
+
+	It("only records the selected events", func() {
+		buf := &bytes.Buffer{}
+		tracer := NewConnectionTracerWithFilter(
+			nopWriteCloser(buf),
+			protocol.PerspectiveServer,
+			protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+			FilterCategories("recovery"),
+		)
+		tracer.NegotiatedVersion(0x1337, nil, nil)
+		tracer.UpdatedPTOCount(1)
+		tracer.LostPacket(protocol.Encryption1RTT, 42, logging.PacketLossTimeThreshold)
+		tracer.DroppedEncryptionLevel(protocol.EncryptionHandshake)
+		tracer.Close()
+		Expect(parseEvents(buf)).To(Equal([]string{"recovery:metrics_updated", "recovery:packet_lost"}))
+	})
+
+	It("passes the event name to the filter", func() {
+		buf := &bytes.Buffer{}
+		tracer := NewConnectionTracerWithFilter(
+			nopWriteCloser(buf),
+			protocol.PerspectiveServer,
+			protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+			func(category, name string) bool { return name == "packet_lost" },
+		)
+		tracer.UpdatedPTOCount(1)
+		tracer.LostPacket(protocol.Encryption1RTT, 42, logging.PacketLossTimeThreshold)
+		tracer.Close()
+		Expect(parseEvents(buf)).To(Equal([]string{"recovery:packet_lost"}))
+	})
+
+	It("writes a valid qlog if all events are filtered", func() {
+		buf := &bytes.Buffer{}
+		tracer := NewConnectionTracerWithFilter(
+			nopWriteCloser(buf),
+			protocol.PerspectiveServer,
+			protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+			func(string, string) bool { return false },
+		)
+		tracer.UpdatedPTOCount(1)
+		tracer.Close()
+		Expect(parseEvents(buf)).To(BeEmpty())
+	})
+
+	Context("tracer", func() {
+		It("returns nil when there's no io.WriteCloser", func() {
+			t := NewTracerWithFilter(func(logging.Perspective, []byte) io.WriteCloser { return nil }, FilterCategories("recovery"))
+			Expect(t.TracerForConnection(
+				context.Background(),
+				logging.PerspectiveClient,
+				protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			)).To(BeNil())
+		})
+
+		It("applies the filter to the connection tracers", func() {
+			buf := &bytes.Buffer{}
+			t := NewTracerWithFilter(func(logging.Perspective, []byte) io.WriteCloser { return nopWriteCloser(buf) }, FilterCategories("transport"))
+			tracer := t.TracerForConnection(
+				context.Background(),
+				logging.PerspectiveServer,
+				protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+			)
+			tracer.UpdatedPTOCount(1)
+			tracer.NegotiatedVersion(0x1337, nil, nil)
+			tracer.Close()
+			Expect(parseEvents(buf)).To(Equal([]string{"transport:version_information"}))
+		})
+	})
+})
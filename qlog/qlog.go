@@ -81,6 +81,15 @@ type connectionTracer struct {
 	encodeErr  error
 	runStopped chan struct{}
 
+	// only set when the qlog is rotated
+	rotation      *RotationConfig
+	getNextWriter func(index int) io.WriteCloser
+	fileIndex     int
+	bytesWritten  int64
+	eventsWritten int
+
+	filter EventFilter
+
 	lastMetrics *metrics
 }
 
@@ -88,6 +97,17 @@ var _ logging.ConnectionTracer = &connectionTracer{}
 
 // NewConnectionTracer creates a new tracer to record a qlog for a connection.
 func NewConnectionTracer(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID) logging.ConnectionTracer {
+	return newConnectionTracer(w, p, odcid, nil, nil, nil)
+}
+
+func newConnectionTracer(
+	w io.WriteCloser,
+	p protocol.Perspective,
+	odcid protocol.ConnectionID,
+	rotation *RotationConfig,
+	getNextWriter func(index int) io.WriteCloser,
+	filter EventFilter,
+) *connectionTracer {
 	t := &connectionTracer{
 		w:             w,
 		perspective:   p,
@@ -95,6 +115,9 @@ func NewConnectionTracer(w io.WriteCloser, p protocol.Perspective, odcid protoco
 		runStopped:    make(chan struct{}),
 		events:        make(chan event, eventChanSize),
 		referenceTime: time.Now(),
+		rotation:      rotation,
+		getNextWriter: getNextWriter,
+		filter:        filter,
 	}
 	go t.run()
 	return t
@@ -102,6 +125,41 @@ func NewConnectionTracer(w io.WriteCloser, p protocol.Perspective, odcid protoco
 
 func (t *connectionTracer) run() {
 	defer close(t.runStopped)
+	t.encodeErr = t.writeHeader()
+	buf := &bytes.Buffer{}
+	enc := gojay.NewEncoder(buf)
+	for ev := range t.events {
+		if t.encodeErr != nil { // if encoding failed, just continue draining the event channel
+			continue
+		}
+		// Encode the event into a buffer first.
+		// This guarantees that a rotation never splits an event across two files.
+		buf.Reset()
+		if err := enc.Encode(ev); err != nil {
+			t.encodeErr = err
+			continue
+		}
+		if err := buf.WriteByte('\n'); err != nil {
+			panic(fmt.Sprintf("qlog encoding into a bytes.Buffer failed: %s", err))
+		}
+		if t.shouldRotate(buf.Len()) {
+			if err := t.rotate(); err != nil {
+				t.encodeErr = err
+				continue
+			}
+		}
+		n, err := t.w.Write(buf.Bytes())
+		t.bytesWritten += int64(n)
+		t.eventsWritten++
+		if err != nil {
+			t.encodeErr = err
+		}
+	}
+}
+
+// writeHeader writes the top-level qlog fields.
+// Every file starts with this header.
+func (t *connectionTracer) writeHeader() error {
 	buf := &bytes.Buffer{}
 	enc := gojay.NewEncoder(buf)
 	tl := &topLevel{
@@ -120,22 +178,10 @@ func (t *connectionTracer) run() {
 	if err := buf.WriteByte('\n'); err != nil {
 		panic(fmt.Sprintf("qlog encoding into a bytes.Buffer failed: %s", err))
 	}
-	if _, err := t.w.Write(buf.Bytes()); err != nil {
-		t.encodeErr = err
-	}
-	enc = gojay.NewEncoder(t.w)
-	for ev := range t.events {
-		if t.encodeErr != nil { // if encoding failed, just continue draining the event channel
-			continue
-		}
-		if err := enc.Encode(ev); err != nil {
-			t.encodeErr = err
-			continue
-		}
-		if _, err := t.w.Write([]byte{'\n'}); err != nil {
-			t.encodeErr = err
-		}
-	}
+	n, err := t.w.Write(buf.Bytes())
+	t.bytesWritten = int64(n)
+	t.eventsWritten = 0
+	return err
 }
 
 func (t *connectionTracer) Close() {
@@ -155,6 +201,9 @@ func (t *connectionTracer) export() error {
 }
 
 func (t *connectionTracer) recordEvent(eventTime time.Time, details eventDetails) {
+	if t.filter != nil && !t.filter(details.Category().String(), details.Name()) {
+		return
+	}
 	t.events <- event{
 		RelativeTime: eventTime.Sub(t.referenceTime),
 		eventDetails: details,
@@ -172,13 +221,13 @@ func (t *connectionTracer) StartedConnection(local, remote net.Addr, srcConnID,
 		return
 	}
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventConnectionStarted{
 		SrcAddr:          localAddr,
 		DestAddr:         remoteAddr,
 		SrcConnectionID:  srcConnID,
 		DestConnectionID: destConnID,
 	})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) NegotiatedVersion(chosen logging.VersionNumber, client, server []logging.VersionNumber) {
@@ -196,18 +245,18 @@ func (t *connectionTracer) NegotiatedVersion(chosen logging.VersionNumber, clien
 		}
 	}
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventVersionNegotiated{
 		clientVersions: clientVersions,
 		serverVersions: serverVersions,
 		chosenVersion:  versionNumber(chosen),
 	})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) ClosedConnection(e error) {
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventConnectionClosed{e: e})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) SentTransportParameters(tp *wire.TransportParameters) {
@@ -223,8 +272,8 @@ func (t *connectionTracer) RestoredTransportParameters(tp *wire.TransportParamet
 	ev.Restore = true
 
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), ev)
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) recordTransportParameters(sentBy protocol.Perspective, tp *wire.TransportParameters) {
@@ -236,8 +285,8 @@ func (t *connectionTracer) recordTransportParameters(sentBy protocol.Perspective
 	ev.SentBy = sentBy
 
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), ev)
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) toTransportParameters(tp *wire.TransportParameters) *eventTransportParameters {
@@ -288,13 +337,13 @@ func (t *connectionTracer) SentPacket(hdr *wire.ExtendedHeader, packetSize loggi
 	}
 	header := *transformLongHeader(hdr)
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventPacketSent{
 		Header:        header,
 		Length:        packetSize,
 		PayloadLength: hdr.Length,
 		Frames:        fs,
 	})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) ReceivedLongHeaderPacket(hdr *logging.ExtendedHeader, packetSize logging.ByteCount, frames []logging.Frame) {
@@ -304,13 +353,13 @@ func (t *connectionTracer) ReceivedLongHeaderPacket(hdr *logging.ExtendedHeader,
 	}
 	header := *transformLongHeader(hdr)
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventPacketReceived{
 		Header:        header,
 		Length:        packetSize,
 		PayloadLength: hdr.Length,
 		Frames:        fs,
 	})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) ReceivedShortHeaderPacket(hdr *logging.ShortHeader, packetSize logging.ByteCount, frames []logging.Frame) {
@@ -321,21 +370,33 @@ func (t *connectionTracer) ReceivedShortHeaderPacket(hdr *logging.ShortHeader, p
 	header := *transformShortHeader(hdr)
 	hdrLen := 1 + hdr.DestConnectionID.Len() + int(hdr.PacketNumberLen)
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventPacketReceived{
 		Header:        header,
 		Length:        packetSize,
 		PayloadLength: packetSize - protocol.ByteCount(hdrLen),
 		Frames:        fs,
 	})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) ReceivedRetry(hdr *wire.Header) {
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventRetryReceived{
 		Header: *transformHeader(hdr),
 	})
-	t.mutex.Unlock()
+}
+
+// The HANDSHAKE_DONE frame is already contained in the packet_received event.
+func (t *connectionTracer) ReceivedHandshakeDone() {}
+
+func (t *connectionTracer) CompletedHandshake(attempted0RTT, accepted0RTT bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.recordEvent(time.Now(), &eventHandshakeCompleted{
+		Attempted0RTT: attempted0RTT,
+		Accepted0RTT:  accepted0RTT,
+	})
 }
 
 func (t *connectionTracer) ReceivedVersionNegotiationPacket(dest, src logging.ArbitraryLenConnectionID, versions []logging.VersionNumber) {
@@ -344,6 +405,7 @@ func (t *connectionTracer) ReceivedVersionNegotiationPacket(dest, src logging.Ar
 		ver[i] = versionNumber(v)
 	}
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventVersionNegotiationReceived{
 		Header: packetHeaderVersionNegotiation{
 			SrcConnectionID:  src,
@@ -351,23 +413,22 @@ func (t *connectionTracer) ReceivedVersionNegotiationPacket(dest, src logging.Ar
 		},
 		SupportedVersions: ver,
 	})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) BufferedPacket(pt logging.PacketType) {
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventPacketBuffered{PacketType: pt})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) DroppedPacket(pt logging.PacketType, size protocol.ByteCount, reason logging.PacketDropReason) {
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventPacketDropped{
 		PacketType: pt,
 		PacketSize: size,
 		Trigger:    packetDropReason(reason),
 	})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) UpdatedMetrics(rttStats *utils.RTTStats, cwnd, bytesInFlight protocol.ByteCount, packetsInFlight int) {
@@ -381,45 +442,78 @@ func (t *connectionTracer) UpdatedMetrics(rttStats *utils.RTTStats, cwnd, bytesI
 		PacketsInFlight:  packetsInFlight,
 	}
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventMetricsUpdated{
 		Last:    t.lastMetrics,
 		Current: m,
 	})
 	t.lastMetrics = m
-	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) SentPacketTimestamp(protocol.EncryptionLevel, protocol.PacketNumber, time.Time) {
 }
 
 func (t *connectionTracer) AcknowledgedPacket(protocol.EncryptionLevel, protocol.PacketNumber) {}
 
 func (t *connectionTracer) LostPacket(encLevel protocol.EncryptionLevel, pn protocol.PacketNumber, lossReason logging.PacketLossReason) {
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventPacketLost{
 		PacketType:   getPacketTypeFromEncryptionLevel(encLevel),
 		PacketNumber: pn,
 		Trigger:      packetLossReason(lossReason),
 	})
-	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) DetectedSpuriousLoss(encLevel protocol.EncryptionLevel, pn protocol.PacketNumber) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.recordEvent(time.Now(), &eventSpuriousLoss{
+		PacketType:   getPacketTypeFromEncryptionLevel(encLevel),
+		PacketNumber: pn,
+	})
+}
+
+func (t *connectionTracer) ReceivedAckForUnsentPacket(encLevel protocol.EncryptionLevel, pn protocol.PacketNumber) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.recordEvent(time.Now(), &eventAckForUnsentPacket{
+		PacketType:   getPacketTypeFromEncryptionLevel(encLevel),
+		PacketNumber: pn,
+	})
 }
 
 func (t *connectionTracer) UpdatedCongestionState(state logging.CongestionState) {
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventCongestionStateUpdated{state: congestionState(state)})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) UpdatedPTOCount(value uint32) {
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventUpdatedPTO{Value: value})
-	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) UpdatedMTU(mtu logging.ByteCount, done bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.recordEvent(time.Now(), &eventMTUUpdated{mtu: mtu, done: done})
+}
+
+func (t *connectionTracer) Blocked(f logging.Frame, remote bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.recordEvent(time.Now(), &eventBlocked{Frame: frame{Frame: f}, Remote: remote})
 }
 
 func (t *connectionTracer) UpdatedKeyFromTLS(encLevel protocol.EncryptionLevel, pers protocol.Perspective) {
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventKeyUpdated{
 		Trigger: keyUpdateTLS,
 		KeyType: encLevelToKeyType(encLevel, pers),
 	})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) UpdatedKey(generation protocol.KeyPhase, remote bool) {
@@ -428,6 +522,7 @@ func (t *connectionTracer) UpdatedKey(generation protocol.KeyPhase, remote bool)
 		trigger = keyUpdateRemote
 	}
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	now := time.Now()
 	t.recordEvent(now, &eventKeyUpdated{
 		Trigger:    trigger,
@@ -439,11 +534,11 @@ func (t *connectionTracer) UpdatedKey(generation protocol.KeyPhase, remote bool)
 		KeyType:    keyTypeServer1RTT,
 		Generation: generation,
 	})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) DroppedEncryptionLevel(encLevel protocol.EncryptionLevel) {
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	now := time.Now()
 	if encLevel == protocol.Encryption0RTT {
 		t.recordEvent(now, &eventKeyDiscarded{KeyType: encLevelToKeyType(encLevel, t.perspective)})
@@ -451,11 +546,11 @@ func (t *connectionTracer) DroppedEncryptionLevel(encLevel protocol.EncryptionLe
 		t.recordEvent(now, &eventKeyDiscarded{KeyType: encLevelToKeyType(encLevel, protocol.PerspectiveServer)})
 		t.recordEvent(now, &eventKeyDiscarded{KeyType: encLevelToKeyType(encLevel, protocol.PerspectiveClient)})
 	}
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) DroppedKey(generation protocol.KeyPhase) {
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	now := time.Now()
 	t.recordEvent(now, &eventKeyDiscarded{
 		KeyType:    encLevelToKeyType(protocol.Encryption1RTT, protocol.PerspectiveServer),
@@ -465,40 +560,56 @@ func (t *connectionTracer) DroppedKey(generation protocol.KeyPhase) {
 		KeyType:    encLevelToKeyType(protocol.Encryption1RTT, protocol.PerspectiveClient),
 		Generation: generation,
 	})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) SetLossTimer(tt logging.TimerType, encLevel protocol.EncryptionLevel, timeout time.Time) {
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	now := time.Now()
 	t.recordEvent(now, &eventLossTimerSet{
 		TimerType: timerType(tt),
 		EncLevel:  encLevel,
 		Delta:     timeout.Sub(now),
 	})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) LossTimerExpired(tt logging.TimerType, encLevel protocol.EncryptionLevel) {
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventLossTimerExpired{
 		TimerType: timerType(tt),
 		EncLevel:  encLevel,
 	})
-	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) LossTimerCanceled() {
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventLossTimerCanceled{})
-	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) OpenedStream(id protocol.StreamID) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.recordEvent(time.Now(), &eventStreamStateUpdated{StreamID: id, State: "open"})
+}
+
+func (t *connectionTracer) ClosedStream(id protocol.StreamID, send, receive *logging.StreamCloseState) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.recordEvent(time.Now(), &eventStreamStateUpdated{
+		StreamID: id,
+		State:    "closed",
+		Send:     send,
+		Receive:  receive,
+	})
 }
 
 func (t *connectionTracer) Debug(name, msg string) {
 	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.recordEvent(time.Now(), &eventGeneric{
 		name: name,
 		msg:  msg,
 	})
-	t.mutex.Unlock()
 }
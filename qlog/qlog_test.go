@@ -420,6 +420,16 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("initial_max_stream_data_uni", float64(300)))
 			})
 
+			It("records the completion of the handshake", func() {
+				tracer.CompletedHandshake(true, false)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:handshake_completed"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("0rtt_attempted", true))
+				Expect(ev).To(HaveKeyWithValue("0rtt_accepted", false))
+			})
+
 			It("records a sent packet, without an ACK", func() {
 				tracer.SentPacket(
 					&logging.ExtendedHeader{
@@ -726,6 +736,39 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("trigger", "reordering_threshold"))
 			})
 
+			It("records packets lost due to a PTO", func() {
+				tracer.LostPacket(protocol.Encryption1RTT, 42, logging.PacketLossPTO)
+				entry := exportAndParseSingle()
+				Expect(entry.Name).To(Equal("recovery:packet_lost"))
+				Expect(entry.Event).To(HaveKeyWithValue("trigger", "pto_expired"))
+			})
+
+			It("records spurious losses", func() {
+				tracer.DetectedSpuriousLoss(protocol.Encryption1RTT, 42)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("recovery:spurious_loss"))
+				ev := entry.Event
+				Expect(ev).To(HaveKey("header"))
+				hdr := ev["header"].(map[string]interface{})
+				Expect(hdr).To(HaveLen(2))
+				Expect(hdr).To(HaveKeyWithValue("packet_type", "1RTT"))
+				Expect(hdr).To(HaveKeyWithValue("packet_number", float64(42)))
+			})
+
+			It("records ACKs for unsent packets", func() {
+				tracer.ReceivedAckForUnsentPacket(protocol.Encryption1RTT, 42)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("recovery:ack_for_unsent_packet"))
+				ev := entry.Event
+				Expect(ev).To(HaveKey("header"))
+				hdr := ev["header"].(map[string]interface{})
+				Expect(hdr).To(HaveLen(2))
+				Expect(hdr).To(HaveKeyWithValue("packet_type", "1RTT"))
+				Expect(hdr).To(HaveKeyWithValue("packet_number", float64(42)))
+			})
+
 			It("records congestion state updates", func() {
 				tracer.UpdatedCongestionState(logging.CongestionStateCongestionAvoidance)
 				entry := exportAndParseSingle()
@@ -743,6 +786,36 @@ var _ = Describe("Tracing", func() {
 				Expect(entry.Event).To(HaveKeyWithValue("pto_count", float64(42)))
 			})
 
+			It("records MTU updates", func() {
+				tracer.UpdatedMTU(1337, true)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("connectivity:mtu_updated"))
+				Expect(entry.Event).To(HaveKeyWithValue("new", float64(1337)))
+				Expect(entry.Event).To(HaveKeyWithValue("done", true))
+			})
+
+			It("records when we're blocked", func() {
+				tracer.Blocked(&logging.StreamDataBlockedFrame{StreamID: 42, MaximumStreamData: 1337}, false)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:blocked"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("owner", "local"))
+				Expect(ev).To(HaveKey("frame"))
+				frame := ev["frame"].(map[string]interface{})
+				Expect(frame).To(HaveKeyWithValue("frame_type", "stream_data_blocked"))
+				Expect(frame).To(HaveKeyWithValue("stream_id", float64(42)))
+				Expect(frame).To(HaveKeyWithValue("limit", float64(1337)))
+			})
+
+			It("records when the peer is blocked", func() {
+				tracer.Blocked(&logging.DataBlockedFrame{MaximumData: 1337}, true)
+				entry := exportAndParseSingle()
+				Expect(entry.Name).To(Equal("transport:blocked"))
+				Expect(entry.Event).To(HaveKeyWithValue("owner", "remote"))
+			})
+
 			It("records TLS key updates", func() {
 				tracer.UpdatedKeyFromTLS(protocol.EncryptionHandshake, protocol.PerspectiveClient)
 				entry := exportAndParseSingle()
@@ -872,6 +945,50 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("event_type", "cancelled"))
 			})
 
+			It("records opened streams", func() {
+				tracer.OpenedStream(4)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:stream_state_updated"))
+				ev := entry.Event
+				Expect(ev).To(HaveLen(3))
+				Expect(ev).To(HaveKeyWithValue("stream_id", float64(4)))
+				Expect(ev).To(HaveKeyWithValue("stream_type", "bidirectional"))
+				Expect(ev).To(HaveKeyWithValue("new", "open"))
+			})
+
+			It("records closed streams", func() {
+				tracer.ClosedStream(3, &logging.StreamCloseState{FinalSize: 1337}, nil)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:stream_state_updated"))
+				ev := entry.Event
+				Expect(ev).To(HaveLen(4))
+				Expect(ev).To(HaveKeyWithValue("stream_id", float64(3)))
+				Expect(ev).To(HaveKeyWithValue("stream_type", "unidirectional"))
+				Expect(ev).To(HaveKeyWithValue("new", "closed"))
+				Expect(ev).To(HaveKeyWithValue("sending_final_size", float64(1337)))
+			})
+
+			It("records closed streams that were reset", func() {
+				sendErrorCode := logging.StreamErrorCode(42)
+				receiveErrorCode := logging.StreamErrorCode(43)
+				tracer.ClosedStream(4,
+					&logging.StreamCloseState{FinalSize: 1337, ErrorCode: &sendErrorCode},
+					&logging.StreamCloseState{FinalSize: 1234, ErrorCode: &receiveErrorCode},
+				)
+				entry := exportAndParseSingle()
+				Expect(entry.Name).To(Equal("transport:stream_state_updated"))
+				ev := entry.Event
+				Expect(ev).To(HaveLen(7))
+				Expect(ev).To(HaveKeyWithValue("stream_id", float64(4)))
+				Expect(ev).To(HaveKeyWithValue("stream_type", "bidirectional"))
+				Expect(ev).To(HaveKeyWithValue("sending_final_size", float64(1337)))
+				Expect(ev).To(HaveKeyWithValue("sending_error_code", float64(42)))
+				Expect(ev).To(HaveKeyWithValue("receiving_final_size", float64(1234)))
+				Expect(ev).To(HaveKeyWithValue("receiving_error_code", float64(43)))
+			})
+
 			It("records a generic event", func() {
 				tracer.Debug("foo", "bar")
 				entry := exportAndParseSingle()
@@ -0,0 +1,84 @@
+package qlog
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/fkwhite/quic-go/logging"
+)
+
+// RotationConfig configures how the qlog of a single connection is split into multiple files.
+// Every file starts with the qlog header, so that it can be loaded independently of the other files.
+// An event is never split across two files.
+type RotationConfig struct {
+	// MaxFileSize is the maximum size of a single file, in bytes.
+	// A file contains at least one event, so it can exceed this size if an event is larger than MaxFileSize.
+	// If zero, files are not rotated based on their size.
+	MaxFileSize int64
+	// MaxEvents is the maximum number of events written to a single file.
+	// If zero, files are not rotated based on the number of events.
+	MaxEvents int
+}
+
+type rotatingTracer struct {
+	logging.NullTracer
+
+	getLogWriter func(p logging.Perspective, connectionID []byte, index int) io.WriteCloser
+	config       RotationConfig
+}
+
+var _ logging.Tracer = &rotatingTracer{}
+
+// NewRotatingTracer creates a new qlog tracer that splits the qlog of every connection into multiple files.
+// getLogWriter is called for every file. The index starts at 0, and is incremented for every new file of a connection.
+func NewRotatingTracer(getLogWriter func(p logging.Perspective, connectionID []byte, index int) io.WriteCloser, config RotationConfig) logging.Tracer {
+	return &rotatingTracer{getLogWriter: getLogWriter, config: config}
+}
+
+func (t *rotatingTracer) TracerForConnection(_ context.Context, p logging.Perspective, odcid protocol.ConnectionID) logging.ConnectionTracer {
+	connID := odcid.Bytes()
+	getLogWriter := func(index int) io.WriteCloser { return t.getLogWriter(p, connID, index) }
+	w := getLogWriter(0)
+	if w == nil {
+		return nil
+	}
+	return newConnectionTracer(w, p, odcid, &t.config, getLogWriter, nil)
+}
+
+// NewRotatingConnectionTracer creates a new tracer to record a qlog for a connection, split into multiple files.
+// getLogWriter is called for every file. The index starts at 0, and is incremented for every new file.
+// It must not return nil for the first file.
+func NewRotatingConnectionTracer(
+	getLogWriter func(index int) io.WriteCloser,
+	config RotationConfig,
+	p protocol.Perspective,
+	odcid protocol.ConnectionID,
+) logging.ConnectionTracer {
+	return newConnectionTracer(getLogWriter(0), p, odcid, &config, getLogWriter, nil)
+}
+
+// shouldRotate says if a new file needs to be started before writing an event of size bytes.
+func (t *connectionTracer) shouldRotate(size int) bool {
+	if t.rotation == nil || t.eventsWritten == 0 {
+		return false
+	}
+	if t.rotation.MaxEvents > 0 && t.eventsWritten >= t.rotation.MaxEvents {
+		return true
+	}
+	return t.rotation.MaxFileSize > 0 && t.bytesWritten+int64(size) > t.rotation.MaxFileSize
+}
+
+func (t *connectionTracer) rotate() error {
+	if err := t.w.Close(); err != nil {
+		return err
+	}
+	t.fileIndex++
+	w := t.getNextWriter(t.fileIndex)
+	if w == nil {
+		return fmt.Errorf("qlog: no io.WriteCloser for file %d", t.fileIndex)
+	}
+	t.w = w
+	return t.writeHeader()
+}
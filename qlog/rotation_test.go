@@ -0,0 +1,163 @@
+package qlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/fkwhite/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rotation", func() {
+	var files []*bytes.Buffer
+
+	getLogWriter := func(index int) io.WriteCloser {
+		defer GinkgoRecover()
+		Expect(index).To(Equal(len(files)))
+		buf := &bytes.Buffer{}
+		files = append(files, buf)
+		return nopWriteCloser(buf)
+	}
+
+	// parseFile checks that a file starts with the qlog header, and returns the names of the events in the file
+	parseFile := func(buf *bytes.Buffer) []string {
+		line, err := buf.ReadBytes('\n')
+		Expect(err).ToNot(HaveOccurred())
+		m := make(map[string]interface{})
+		Expect(json.Unmarshal(line, &m)).To(Succeed())
+		Expect(m).To(HaveKeyWithValue("qlog_version", "draft-02"))
+		Expect(m).To(HaveKey("trace"))
+		trace := m["trace"].(map[string]interface{})
+		Expect(trace).To(HaveKey("common_fields"))
+		Expect(trace["common_fields"].(map[string]interface{})).To(HaveKeyWithValue("ODCID", "deadbeef"))
+
+		var names []string
+		for buf.Len() > 0 {
+			line, err := buf.ReadBytes('\n')
+			Expect(err).ToNot(HaveOccurred())
+			ev := make(map[string]interface{})
+			Expect(json.Unmarshal(line, &ev)).To(Succeed())
+			Expect(ev).To(HaveKey("name"))
+			names = append(names, ev["name"].(string))
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		files = nil
+	})
+
+	It("rotates after a number of events", func() {
+		tracer := NewRotatingConnectionTracer(
+			getLogWriter,
+			RotationConfig{MaxEvents: 2},
+			protocol.PerspectiveServer,
+			protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+		)
+		for i := uint32(0); i < 5; i++ {
+			tracer.UpdatedPTOCount(i)
+		}
+		tracer.Close()
+		Expect(files).To(HaveLen(3))
+		Expect(parseFile(files[0])).To(HaveLen(2))
+		Expect(parseFile(files[1])).To(HaveLen(2))
+		Expect(parseFile(files[2])).To(HaveLen(1))
+	})
+
+	It("rotates when the maximum file size is reached", func() {
+		tracer := NewRotatingConnectionTracer(
+			getLogWriter,
+			RotationConfig{MaxFileSize: 1000},
+			protocol.PerspectiveServer,
+			protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+		)
+		for i := uint32(0); i < 100; i++ {
+			tracer.UpdatedPTOCount(i)
+		}
+		tracer.Close()
+		Expect(len(files)).To(BeNumerically(">", 1))
+		var numEvents int
+		for _, f := range files {
+			Expect(f.Len()).To(BeNumerically("<=", 1000))
+			numEvents += len(parseFile(f))
+		}
+		Expect(numEvents).To(Equal(100))
+	})
+
+	It("writes an event that's larger than the maximum file size into a file of its own", func() {
+		tracer := NewRotatingConnectionTracer(
+			getLogWriter,
+			RotationConfig{MaxFileSize: 1},
+			protocol.PerspectiveServer,
+			protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+		)
+		for i := uint32(0); i < 3; i++ {
+			tracer.UpdatedPTOCount(i)
+		}
+		tracer.Close()
+		Expect(files).To(HaveLen(3))
+		for _, f := range files {
+			Expect(parseFile(f)).To(Equal([]string{"recovery:metrics_updated"}))
+		}
+	})
+
+	It("stops writing when no writer is returned for the next file", func() {
+		tracer := NewRotatingConnectionTracer(
+			func(index int) io.WriteCloser {
+				if index > 0 {
+					return nil
+				}
+				return getLogWriter(index)
+			},
+			RotationConfig{MaxEvents: 1},
+			protocol.PerspectiveServer,
+			protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+		)
+		tracer.UpdatedPTOCount(1)
+		tracer.UpdatedPTOCount(2)
+
+		b := &bytes.Buffer{}
+		log.SetOutput(b)
+		defer log.SetOutput(os.Stdout)
+		tracer.Close()
+		Expect(b.String()).To(ContainSubstring("qlog: no io.WriteCloser for file 1"))
+		Expect(files).To(HaveLen(1))
+		Expect(parseFile(files[0])).To(HaveLen(1))
+	})
+
+	Context("tracer", func() {
+		It("returns nil when there's no io.WriteCloser", func() {
+			t := NewRotatingTracer(func(logging.Perspective, []byte, int) io.WriteCloser { return nil }, RotationConfig{MaxEvents: 1})
+			Expect(t.TracerForConnection(
+				context.Background(),
+				logging.PerspectiveClient,
+				protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			)).To(BeNil())
+		})
+
+		It("passes the perspective and the connection ID to the callback", func() {
+			t := NewRotatingTracer(func(p logging.Perspective, connID []byte, index int) io.WriteCloser {
+				defer GinkgoRecover()
+				Expect(p).To(Equal(logging.PerspectiveServer))
+				Expect(connID).To(Equal([]byte{0xde, 0xad, 0xbe, 0xef}))
+				return getLogWriter(index)
+			}, RotationConfig{MaxEvents: 1})
+			tracer := t.TracerForConnection(
+				context.Background(),
+				logging.PerspectiveServer,
+				protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+			)
+			tracer.UpdatedPTOCount(1)
+			tracer.UpdatedPTOCount(2)
+			tracer.Close()
+			Expect(files).To(HaveLen(2))
+		})
+	})
+})
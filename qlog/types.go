@@ -247,6 +247,8 @@ func (r packetLossReason) String() string {
 		return "reordering_threshold"
 	case logging.PacketLossTimeThreshold:
 		return "time_threshold"
+	case logging.PacketLossPTO:
+		return "pto_expired"
 	default:
 		return "unknown loss reason"
 	}
@@ -278,6 +280,12 @@ func (r packetDropReason) String() string {
 		return "unexpected_version"
 	case logging.PacketDropDuplicate:
 		return "duplicate"
+	case logging.PacketDropAcceptQueueFull:
+		return "accept_queue_full"
+	case logging.PacketDropClientVerificationFailed:
+		return "client_verification_failed"
+	case logging.PacketDropServerShuttingDown:
+		return "server_shutting_down"
 	default:
 		return "unknown packet drop reason"
 	}
@@ -51,6 +51,9 @@ var _ = Describe("Types", func() {
 		Expect(packetDropReason(logging.PacketDropUnexpectedPacket).String()).To(Equal("unexpected_packet"))
 		Expect(packetDropReason(logging.PacketDropUnexpectedSourceConnectionID).String()).To(Equal("unexpected_source_connection_id"))
 		Expect(packetDropReason(logging.PacketDropUnexpectedVersion).String()).To(Equal("unexpected_version"))
+		Expect(packetDropReason(logging.PacketDropAcceptQueueFull).String()).To(Equal("accept_queue_full"))
+		Expect(packetDropReason(logging.PacketDropClientVerificationFailed).String()).To(Equal("client_verification_failed"))
+		Expect(packetDropReason(logging.PacketDropServerShuttingDown).String()).To(Equal("server_shutting_down"))
 	})
 
 	It("has a string representation for the timer type", func() {
@@ -11,6 +11,7 @@ import (
 	"github.com/fkwhite/quic-go/internal/qerr"
 	"github.com/fkwhite/quic-go/internal/utils"
 	"github.com/fkwhite/quic-go/internal/wire"
+	"github.com/fkwhite/quic-go/logging"
 )
 
 type receiveStreamI interface {
@@ -18,8 +19,10 @@ type receiveStreamI interface {
 
 	handleStreamFrame(*wire.StreamFrame) error
 	handleResetStreamFrame(*wire.ResetStreamFrame) error
+	handleResetStreamAtFrame(*wire.ResetStreamAtFrame) error
 	closeForShutdown(error)
 	getWindowUpdate() protocol.ByteCount
+	receiveCloseState() *logging.StreamCloseState
 }
 
 type receiveStream struct {
@@ -31,6 +34,9 @@ type receiveStream struct {
 
 	frameQueue  *frameSorter
 	finalOffset protocol.ByteCount
+	readOffset  protocol.ByteCount
+	// set when a RESET_STREAM_AT frame is received: data up to this offset is still delivered to the application
+	reliableSize protocol.ByteCount
 
 	currentFrame       []byte
 	currentFrameDone   func()
@@ -39,12 +45,13 @@ type receiveStream struct {
 
 	closeForShutdownErr error
 	cancelReadErr       error
+	cancelReadErrorCode qerr.StreamErrorCode
 	resetRemotelyErr    *StreamError
 
 	closedForShutdown bool // set when CloseForShutdown() is called
 	finRead           bool // set once we read a frame with a Fin
 	canceledRead      bool // set when CancelRead() is called
-	resetRemotely     bool // set when HandleResetStreamFrame() is called
+	resetRemotely     bool // set when HandleResetStreamFrame() is called, or when all reliable data of a RESET_STREAM_AT frame was read
 
 	readChan chan struct{}
 	readOnce chan struct{} // cap: 1, to protect against concurrent use of Read
@@ -100,21 +107,17 @@ func (s *receiveStream) Read(p []byte) (int, error) {
 }
 
 func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, error) {
-	if s.finRead {
-		return false, 0, io.EOF
-	}
-	if s.canceledRead {
-		return false, 0, s.cancelReadErr
-	}
-	if s.resetRemotely {
-		return false, 0, s.resetRemotelyErr
-	}
-	if s.closedForShutdown {
-		return false, 0, s.closeForShutdownErr
+	if err := s.checkReadable(); err != nil {
+		return false, 0, err
 	}
 
 	var bytesRead int
 	var deadlineTimer *utils.Timer
+	defer func() {
+		if deadlineTimer != nil {
+			deadlineTimer.Stop()
+		}
+	}()
 	for bytesRead < len(p) {
 		if s.currentFrame == nil || s.readPosInFrame >= len(s.currentFrame) {
 			s.dequeueNextFrame()
@@ -122,49 +125,8 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 		if s.currentFrame == nil && bytesRead > 0 {
 			return false, bytesRead, s.closeForShutdownErr
 		}
-
-		for {
-			// Stop waiting on errors
-			if s.closedForShutdown {
-				return false, bytesRead, s.closeForShutdownErr
-			}
-			if s.canceledRead {
-				return false, bytesRead, s.cancelReadErr
-			}
-			if s.resetRemotely {
-				return false, bytesRead, s.resetRemotelyErr
-			}
-
-			deadline := s.deadline
-			if !deadline.IsZero() {
-				if !time.Now().Before(deadline) {
-					return false, bytesRead, errDeadline
-				}
-				if deadlineTimer == nil {
-					deadlineTimer = utils.NewTimer()
-					defer deadlineTimer.Stop()
-				}
-				deadlineTimer.Reset(deadline)
-			}
-
-			if s.currentFrame != nil || s.currentFrameIsLast {
-				break
-			}
-
-			s.mutex.Unlock()
-			if deadline.IsZero() {
-				<-s.readChan
-			} else {
-				select {
-				case <-s.readChan:
-				case <-deadlineTimer.Chan():
-					deadlineTimer.SetRead()
-				}
-			}
-			s.mutex.Lock()
-			if s.currentFrame == nil {
-				s.dequeueNextFrame()
-			}
+		if err := s.waitForData(&deadlineTimer); err != nil {
+			return false, bytesRead, err
 		}
 
 		if bytesRead > len(p) {
@@ -174,21 +136,168 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 			return false, bytesRead, fmt.Errorf("BUG: readPosInFrame (%d) > frame.DataLen (%d) in stream.Read", s.readPosInFrame, len(s.currentFrame))
 		}
 
-		m := copy(p[bytesRead:], s.currentFrame[s.readPosInFrame:])
-		s.readPosInFrame += m
+		m := copy(p[bytesRead:], s.unreadData())
 		bytesRead += m
+		if completed, err := s.consumeData(m); completed {
+			return true, bytesRead, err
+		}
+	}
+	return false, bytesRead, nil
+}
+
+// ReadBuffer returns the next chunk of data without copying it. It is not thread safe!
+func (s *receiveStream) ReadBuffer() ([]byte, error) {
+	s.readOnce <- struct{}{}
+	defer func() { <-s.readOnce }()
+
+	s.mutex.Lock()
+	completed, data, err := s.readBufferImpl()
+	s.mutex.Unlock()
+
+	if completed {
+		s.sender.onStreamCompleted(s.streamID)
+	}
+	return data, err
+}
+
+func (s *receiveStream) readBufferImpl() (bool /* stream completed */, []byte, error) {
+	if err := s.checkReadable(); err != nil {
+		return false, nil, err
+	}
+
+	if s.currentFrame == nil || s.readPosInFrame >= len(s.currentFrame) {
+		s.dequeueNextFrame()
+	}
+	var deadlineTimer *utils.Timer
+	err := s.waitForData(&deadlineTimer)
+	if deadlineTimer != nil {
+		deadlineTimer.Stop()
+	}
+	if err != nil {
+		return false, nil, err
+	}
+
+	data := s.unreadData()
+	completed, err := s.consumeData(len(data))
+	return completed, data, err
+}
+
+// checkReadable returns the error returned by Read if the stream can't be read from (anymore).
+func (s *receiveStream) checkReadable() error {
+	if s.finRead {
+		return io.EOF
+	}
+	if s.canceledRead {
+		return s.cancelReadErr
+	}
+	if s.resetRemotely {
+		return s.resetRemotelyErr
+	}
+	if s.closedForShutdown {
+		return s.closeForShutdownErr
+	}
+	if !s.deadline.IsZero() && !time.Now().Before(s.deadline) {
+		return errDeadline
+	}
+	return nil
+}
+
+// waitForData blocks until the current frame contains data, or the final frame was received.
+// It must be called with the mutex held, and releases the mutex while blocking.
+// The deadline timer is created when it is first needed, and must be stopped by the caller.
+func (s *receiveStream) waitForData(deadlineTimer **utils.Timer) error {
+	for {
+		// Stop waiting on errors
+		if s.closedForShutdown {
+			return s.closeForShutdownErr
+		}
+		if s.canceledRead {
+			return s.cancelReadErr
+		}
+		if s.resetRemotely {
+			return s.resetRemotelyErr
+		}
+
+		deadline := s.deadline
+		if !deadline.IsZero() {
+			if !time.Now().Before(deadline) {
+				return errDeadline
+			}
+			if *deadlineTimer == nil {
+				*deadlineTimer = utils.NewTimer()
+			}
+			(*deadlineTimer).Reset(deadline)
+		}
 
-		// when a RESET_STREAM was received, the was already informed about the final byteOffset for this stream
-		if !s.resetRemotely {
-			s.flowController.AddBytesRead(protocol.ByteCount(m))
+		if s.currentFrame != nil || s.currentFrameIsLast {
+			return nil
 		}
 
-		if s.readPosInFrame >= len(s.currentFrame) && s.currentFrameIsLast {
-			s.finRead = true
-			return true, bytesRead, io.EOF
+		s.mutex.Unlock()
+		if deadline.IsZero() {
+			<-s.readChan
+		} else {
+			select {
+			case <-s.readChan:
+			case <-(*deadlineTimer).Chan():
+				(*deadlineTimer).SetRead()
+			}
+		}
+		s.mutex.Lock()
+		if s.currentFrame == nil {
+			s.dequeueNextFrame()
 		}
 	}
-	return false, bytesRead, nil
+}
+
+// unreadData returns the data of the current frame that wasn't read yet.
+func (s *receiveStream) unreadData() []byte {
+	data := s.currentFrame[s.readPosInFrame:]
+	// after receiving a RESET_STREAM_AT frame, only deliver data up to the reliable size
+	if s.resetRemotelyErr != nil && s.readOffset+protocol.ByteCount(len(data)) > s.reliableSize {
+		data = data[:s.reliableSize-s.readOffset]
+	}
+	return data
+}
+
+// consumeData marks n bytes of the current frame as read.
+// It returns true if this completed the stream, together with the error that is returned to the application.
+func (s *receiveStream) consumeData(n int) (bool /* stream completed */, error) {
+	s.readPosInFrame += n
+	s.readOffset += protocol.ByteCount(n)
+
+	// when a RESET_STREAM was received, the was already informed about the final byteOffset for this stream
+	if !s.resetRemotely {
+		s.flowController.AddBytesRead(protocol.ByteCount(n))
+	}
+
+	if s.resetRemotelyErr != nil && s.readOffset >= s.reliableSize {
+		s.resetRemotely = true
+		s.flowController.Abandon()
+		return true, s.resetRemotelyErr
+	}
+	if s.readPosInFrame >= len(s.currentFrame) && s.currentFrameIsLast {
+		s.finRead = true
+		return true, io.EOF
+	}
+	return false, nil
+}
+
+// ReleaseBuffer releases the frame returned by the last call to ReadBuffer.
+func (s *receiveStream) ReleaseBuffer() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// only release the frame if it was read completely
+	if s.currentFrame == nil || s.readPosInFrame < len(s.currentFrame) {
+		return
+	}
+	if s.currentFrameDone != nil {
+		s.currentFrameDone()
+	}
+	s.currentFrame = nil
+	s.currentFrameDone = nil
+	s.readPosInFrame = 0
 }
 
 func (s *receiveStream) dequeueNextFrame() {
@@ -218,7 +327,8 @@ func (s *receiveStream) cancelReadImpl(errorCode qerr.StreamErrorCode) bool /* c
 		return false
 	}
 	s.canceledRead = true
-	s.cancelReadErr = fmt.Errorf("Read on stream %d canceled with error code %d", s.streamID, errorCode)
+	s.cancelReadErr = &StreamError{StreamID: s.streamID, ErrorCode: errorCode, Remote: false}
+	s.cancelReadErrorCode = errorCode
 	s.signalRead()
 	s.sender.queueControlFrame(&wire.StopSendingFrame{
 		StreamID:  s.streamID,
@@ -261,8 +371,16 @@ func (s *receiveStream) handleStreamFrameImpl(frame *wire.StreamFrame) (bool /*
 }
 
 func (s *receiveStream) handleResetStreamFrame(frame *wire.ResetStreamFrame) error {
+	return s.handleResetStream(frame.ErrorCode, frame.FinalSize, 0)
+}
+
+func (s *receiveStream) handleResetStreamAtFrame(frame *wire.ResetStreamAtFrame) error {
+	return s.handleResetStream(frame.ErrorCode, frame.FinalSize, frame.ReliableSize)
+}
+
+func (s *receiveStream) handleResetStream(errorCode qerr.StreamErrorCode, finalSize, reliableSize protocol.ByteCount) error {
 	s.mutex.Lock()
-	completed, err := s.handleResetStreamFrameImpl(frame)
+	completed, err := s.handleResetStreamImpl(errorCode, finalSize, reliableSize)
 	s.mutex.Unlock()
 
 	if completed {
@@ -272,33 +390,56 @@ func (s *receiveStream) handleResetStreamFrame(frame *wire.ResetStreamFrame) err
 	return err
 }
 
-func (s *receiveStream) handleResetStreamFrameImpl(frame *wire.ResetStreamFrame) (bool /*completed */, error) {
+func (s *receiveStream) handleResetStreamImpl(errorCode qerr.StreamErrorCode, finalSize, reliableSize protocol.ByteCount) (bool /*completed */, error) {
 	if s.closedForShutdown {
 		return false, nil
 	}
-	if err := s.flowController.UpdateHighestReceived(frame.FinalSize, true); err != nil {
+	if err := s.flowController.UpdateHighestReceived(finalSize, true); err != nil {
 		return false, err
 	}
 	newlyRcvdFinalOffset := s.finalOffset == protocol.MaxByteCount
-	s.finalOffset = frame.FinalSize
+	s.finalOffset = finalSize
 
 	// ignore duplicate RESET_STREAM frames for this stream (after checking their final offset)
 	if s.resetRemotely {
 		return false, nil
 	}
-	s.resetRemotely = true
+	// A RESET_STREAM_AT frame can reduce the reliable size of a previous one, but it can't increase it.
+	resetPending := s.resetRemotelyErr != nil
+	if resetPending && reliableSize >= s.reliableSize {
+		return false, nil
+	}
 	s.resetRemotelyErr = &StreamError{
 		StreamID:  s.streamID,
-		ErrorCode: frame.ErrorCode,
+		ErrorCode: errorCode,
+		Remote:    true,
 	}
+	s.reliableSize = reliableSize
 	s.signalRead()
-	return newlyRcvdFinalOffset, nil
+	// The application still needs to read the data up to the reliable size.
+	// The stream is completed once that data has been read.
+	if s.readOffset < reliableSize && !s.canceledRead {
+		return false, nil
+	}
+	s.resetRemotely = true
+	// If the FIN was read, the stream was already reported as completed by Read.
+	if s.finRead {
+		return false, nil
+	}
+	// If reading was canceled after the final offset was received, the stream was already reported as completed.
+	// In all other cases, including when the RESET_STREAM crossed a FIN that wasn't read yet,
+	// the stream is completed now: Read won't consume any more data.
+	return newlyRcvdFinalOffset || !s.canceledRead, nil
 }
 
 func (s *receiveStream) CloseRemote(offset protocol.ByteCount) {
 	s.handleStreamFrame(&wire.StreamFrame{Fin: true, Offset: offset})
 }
 
+func (s *receiveStream) SetReadBufferSize(size uint64) {
+	s.flowController.SetMaxBufferedBytes(protocol.ByteCount(size))
+}
+
 func (s *receiveStream) SetReadDeadline(t time.Time) error {
 	s.mutex.Lock()
 	s.deadline = t
@@ -318,6 +459,25 @@ func (s *receiveStream) closeForShutdown(err error) {
 	s.signalRead()
 }
 
+// receiveCloseState returns the final size of the stream, and the error code if it was reset.
+// If the peer reset the stream, this is the error code of the RESET_STREAM frame.
+// Otherwise, if reading was canceled, it is the error code sent in the STOP_SENDING frame.
+// It is used for tracing, once the stream has completed.
+func (s *receiveStream) receiveCloseState() *logging.StreamCloseState {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state := &logging.StreamCloseState{FinalSize: s.finalOffset}
+	if s.resetRemotelyErr != nil {
+		errorCode := s.resetRemotelyErr.ErrorCode
+		state.ErrorCode = &errorCode
+	} else if s.canceledRead {
+		errorCode := s.cancelReadErrorCode
+		state.ErrorCode = &errorCode
+	}
+	return state
+}
+
 func (s *receiveStream) getWindowUpdate() protocol.ByteCount {
 	return s.flowController.GetWindowUpdate()
 }
@@ -3,6 +3,7 @@ package quic
 import (
 	"errors"
 	"io"
+	"os"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -12,6 +13,7 @@ import (
 	"github.com/fkwhite/quic-go/internal/mocks"
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/wire"
+	"github.com/fkwhite/quic-go/logging"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -41,6 +43,11 @@ var _ = Describe("Receive Stream", func() {
 		Expect(str.StreamID()).To(Equal(protocol.StreamID(1337)))
 	})
 
+	It("sets the read buffer size on the flow controller", func() {
+		mockFC.EXPECT().SetMaxBufferedBytes(protocol.ByteCount(1234))
+		str.SetReadBufferSize(1234)
+	})
+
 	Context("reading", func() {
 		It("reads a single STREAM frame", func() {
 			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
@@ -218,6 +225,99 @@ var _ = Describe("Receive Stream", func() {
 			Expect(b).To(Equal([]byte("foobar")))
 		})
 
+		Context("reading without copying", func() {
+			It("returns the data of the STREAM frames", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2)).Times(2)
+				frame1 := wire.StreamFrame{Data: []byte{0xDE, 0xAD}}
+				frame2 := wire.StreamFrame{Offset: 2, Data: []byte{0xBE, 0xEF}}
+				Expect(str.handleStreamFrame(&frame1)).To(Succeed())
+				Expect(str.handleStreamFrame(&frame2)).To(Succeed())
+				data, err := str.ReadBuffer()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte{0xDE, 0xAD}))
+				Expect(&data[0]).To(BeIdenticalTo(&frame1.Data[0]))
+				data, err = str.ReadBuffer()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte{0xBE, 0xEF}))
+				Expect(&data[0]).To(BeIdenticalTo(&frame2.Data[0]))
+			})
+
+			It("returns the rest of a partially read STREAM frame", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(1))
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}})).To(Succeed())
+				b := make([]byte, 1)
+				_, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).To(Equal([]byte{0xDE}))
+				data, err := str.ReadBuffer()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte{0xAD, 0xBE, 0xEF}))
+			})
+
+			It("waits until data is available", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					data, err := str.ReadBuffer()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal([]byte{0xDE, 0xAD, 0xBE, 0xEF}))
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}})).To(Succeed())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("returns io.EOF together with the last chunk of data", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), true)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}, Fin: true})).To(Succeed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				data, err := str.ReadBuffer()
+				Expect(err).To(MatchError(io.EOF))
+				Expect(data).To(Equal([]byte{0xDE, 0xAD, 0xBE, 0xEF}))
+				data, err = str.ReadBuffer()
+				Expect(err).To(MatchError(io.EOF))
+				Expect(data).To(BeEmpty())
+			})
+
+			It("respects the read deadline", func() {
+				Expect(str.SetReadDeadline(time.Now().Add(scaleDuration(20 * time.Millisecond)))).To(Succeed())
+				_, err := str.ReadBuffer()
+				Expect(err).To(MatchError(errDeadline))
+			})
+
+			It("releases the data", func() {
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				var released bool
+				Expect(str.frameQueue.Push([]byte{0xDE, 0xAD, 0xBE, 0xEF}, 0, func() { released = true })).To(Succeed())
+				data, err := str.ReadBuffer()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte{0xDE, 0xAD, 0xBE, 0xEF}))
+				Expect(released).To(BeFalse())
+				str.ReleaseBuffer()
+				Expect(released).To(BeTrue())
+				Expect(str.currentFrame).To(BeNil())
+			})
+
+			It("doesn't release data that wasn't read yet", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}})).To(Succeed())
+				b := make([]byte, 2)
+				_, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				str.ReleaseBuffer()
+				Expect(str.currentFrame).To(Equal([]byte{0xDE, 0xAD, 0xBE, 0xEF}))
+			})
+		})
+
 		Context("deadlines", func() {
 			It("the deadline error has the right net.Error properties", func() {
 				Expect(errDeadline.Timeout()).To(BeTrue())
@@ -236,6 +336,27 @@ var _ = Describe("Receive Stream", func() {
 				Expect(n).To(BeZero())
 			})
 
+			It("returns an error for an empty Read after the deadline", func() {
+				str.SetReadDeadline(time.Now().Add(-time.Second))
+				n, err := strWithTimeout.Read(nil)
+				Expect(err).To(MatchError(errDeadline))
+				Expect(errors.Is(err, os.ErrDeadlineExceeded)).To(BeTrue())
+				Expect(n).To(BeZero())
+			})
+
+			It("unblocks when a deadline is set during a blocked Read", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					_, err := str.Read(make([]byte, 6))
+					Expect(errors.Is(err, os.ErrDeadlineExceeded)).To(BeTrue())
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				str.SetReadDeadline(time.Now().Add(scaleDuration(20 * time.Millisecond)))
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("unblocks when the deadline is changed to the past", func() {
 				str.SetReadDeadline(time.Now().Add(time.Hour))
 				done := make(chan struct{})
@@ -492,7 +613,7 @@ var _ = Describe("Receive Stream", func() {
 				go func() {
 					defer GinkgoRecover()
 					_, err := strWithTimeout.Read([]byte{0})
-					Expect(err).To(MatchError("Read on stream 1337 canceled with error code 1234"))
+					Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234}))
 					close(done)
 				}()
 				Consistently(done).ShouldNot(BeClosed())
@@ -504,7 +625,7 @@ var _ = Describe("Receive Stream", func() {
 				mockSender.EXPECT().queueControlFrame(gomock.Any())
 				str.CancelRead(1234)
 				_, err := strWithTimeout.Read([]byte{0})
-				Expect(err).To(MatchError("Read on stream 1337 canceled with error code 1234"))
+				Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234}))
 			})
 
 			It("does nothing when CancelRead is called twice", func() {
@@ -512,7 +633,7 @@ var _ = Describe("Receive Stream", func() {
 				str.CancelRead(1234)
 				str.CancelRead(1234)
 				_, err := strWithTimeout.Read([]byte{0})
-				Expect(err).To(MatchError("Read on stream 1337 canceled with error code 1234"))
+				Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234}))
 			})
 
 			It("queues a STOP_SENDING frame", func() {
@@ -523,6 +644,13 @@ var _ = Describe("Receive Stream", func() {
 				str.CancelRead(1234)
 			})
 
+			It("reports the error code of the STOP_SENDING frame for tracing", func() {
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				str.CancelRead(1234)
+				errorCode := StreamErrorCode(1234)
+				Expect(str.receiveCloseState().ErrorCode).To(Equal(&errorCode))
+			})
+
 			It("doesn't send a STOP_SENDING frame, if the FIN was already read", func() {
 				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
 				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
@@ -609,9 +737,10 @@ var _ = Describe("Receive Stream", func() {
 				go func() {
 					defer GinkgoRecover()
 					_, err := strWithTimeout.Read([]byte{0})
-					Expect(err).To(MatchError(&StreamError{
+					Expect(err).To(Equal(&StreamError{
 						StreamID:  streamID,
 						ErrorCode: 1234,
+						Remote:    true,
 					}))
 					close(done)
 				}()
@@ -633,9 +762,22 @@ var _ = Describe("Receive Stream", func() {
 				)
 				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
 				_, err := strWithTimeout.Read([]byte{0})
-				Expect(err).To(MatchError(&StreamError{
+				Expect(err).To(Equal(&StreamError{
 					StreamID:  streamID,
 					ErrorCode: 1234,
+					Remote:    true,
+				}))
+			})
+
+			It("reports the final size and the error code for tracing", func() {
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				mockFC.EXPECT().Abandon()
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				errorCode := StreamErrorCode(1234)
+				Expect(str.receiveCloseState()).To(Equal(&logging.StreamCloseState{
+					FinalSize: 42,
+					ErrorCode: &errorCode,
 				}))
 			})
 
@@ -668,12 +810,165 @@ var _ = Describe("Receive Stream", func() {
 				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
 			})
 
+			It("returns the peer's error code when a RESET_STREAM crosses a FIN that wasn't read yet", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true).Times(2)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{
+					StreamID: streamID,
+					Offset:   rst.FinalSize - 6,
+					Data:     []byte("foobar"),
+					Fin:      true,
+				})).To(Succeed())
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				_, err := strWithTimeout.Read([]byte{0})
+				Expect(err).To(Equal(&StreamError{
+					StreamID:  streamID,
+					ErrorCode: 1234,
+					Remote:    true,
+				}))
+			})
+
+			It("returns io.EOF when a RESET_STREAM is received after the FIN was read", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true).Times(2)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{
+					StreamID: streamID,
+					Data:     []byte("foobar"),
+					Fin:      true,
+				})).To(Succeed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				b := make([]byte, 6)
+				n, err := strWithTimeout.Read(b)
+				Expect(err).To(MatchError(io.EOF))
+				Expect(n).To(Equal(6))
+				// the stream was already completed, so the RESET_STREAM doesn't complete it again
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					FinalSize: 6,
+					ErrorCode: 1234,
+				})).To(Succeed())
+				_, err = strWithTimeout.Read(b)
+				Expect(err).To(MatchError(io.EOF))
+			})
+
+			It("reports a locally canceled read as a local error", func() {
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				str.CancelRead(4321)
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				mockFC.EXPECT().Abandon()
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				_, err := strWithTimeout.Read([]byte{0})
+				Expect(err).To(Equal(&StreamError{
+					StreamID:  streamID,
+					ErrorCode: 4321,
+				}))
+				Expect(err.(*StreamError).Remote).To(BeFalse())
+			})
+
 			It("doesn't do anyting when it was closed for shutdown", func() {
 				str.closeForShutdown(nil)
 				err := str.handleResetStreamFrame(rst)
 				Expect(err).ToNot(HaveOccurred())
 			})
 		})
+
+		Context("receiving RESET_STREAM_AT frames", func() {
+			rst := &wire.ResetStreamAtFrame{
+				StreamID:     streamID,
+				FinalSize:    10,
+				ReliableSize: 4,
+				ErrorCode:    1234,
+			}
+
+			It("delivers data up to the reliable size", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), true)
+				Expect(str.handleResetStreamAtFrame(rst)).To(Succeed())
+				gomock.InOrder(
+					mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4)),
+					mockFC.EXPECT().Abandon(),
+				)
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				b := make([]byte, 10)
+				n, err := strWithTimeout.Read(b)
+				Expect(err).To(Equal(&StreamError{
+					StreamID:  streamID,
+					ErrorCode: 1234,
+					Remote:    true,
+				}))
+				Expect(b[:n]).To(Equal([]byte("foob")))
+				_, err = strWithTimeout.Read(b)
+				Expect(err).To(Equal(&StreamError{
+					StreamID:  streamID,
+					ErrorCode: 1234,
+					Remote:    true,
+				}))
+			})
+
+			It("resets the stream immediately if the data up to the reliable size was already read", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				b := make([]byte, 6)
+				n, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(6))
+				gomock.InOrder(
+					mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), true),
+					mockFC.EXPECT().Abandon(),
+				)
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleResetStreamAtFrame(rst)).To(Succeed())
+				_, err = strWithTimeout.Read(b)
+				Expect(err).To(Equal(&StreamError{
+					StreamID:  streamID,
+					ErrorCode: 1234,
+					Remote:    true,
+				}))
+			})
+
+			It("completes the stream when a RESET_STREAM frame is received", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), true).Times(2)
+				Expect(str.handleResetStreamAtFrame(rst)).To(Succeed())
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					FinalSize: 10,
+					ErrorCode: 4321,
+				})).To(Succeed())
+				_, err := strWithTimeout.Read([]byte{0})
+				Expect(err).To(Equal(&StreamError{
+					StreamID:  streamID,
+					ErrorCode: 4321,
+					Remote:    true,
+				}))
+			})
+
+			It("doesn't increase the reliable size", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), true).Times(2)
+				Expect(str.handleResetStreamAtFrame(rst)).To(Succeed())
+				Expect(str.handleResetStreamAtFrame(&wire.ResetStreamAtFrame{
+					StreamID:     streamID,
+					FinalSize:    10,
+					ReliableSize: 8,
+					ErrorCode:    1234,
+				})).To(Succeed())
+				Expect(str.reliableSize).To(Equal(protocol.ByteCount(4)))
+			})
+
+			It("completes the stream when reading is canceled", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), true)
+				Expect(str.handleResetStreamAtFrame(rst)).To(Succeed())
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.CancelRead(1337)
+			})
+		})
 	})
 
 	Context("flow control", func() {
@@ -2,50 +2,148 @@ package quic
 
 import (
 	"net"
+	"sync"
+	"time"
+
+	"github.com/fkwhite/quic-go/internal/utils"
 )
 
 // A sendConn allows sending using a simple Write() on a non-connected packet conn.
 type sendConn interface {
 	Write([]byte) error
+	// WriteBatch writes multiple packets, using a single syscall if supported by the platform.
+	// It returns the number of packets that were written before an error occurred.
+	WriteBatch([][]byte) (int, error)
+	// WriteBatchWithTimestamps writes multiple packets, like WriteBatch.
+	// onSent[i] is called with the time packets[i] was sent, which might happen after WriteBatchWithTimestamps returned.
+	// Where supported, this is the TX timestamp reported by the kernel.
+	// The onSent slice is not retained.
+	WriteBatchWithTimestamps(packets [][]byte, onSent []func(time.Time)) (int, error)
+	// WritePacketTo writes a packet to a different address than the remote address.
+	// It is used to probe a new path.
+	WritePacketTo([]byte, net.Addr) error
 	Close() error
 	LocalAddr() net.Addr
 	RemoteAddr() net.Addr
+	// SetRemoteAddr changes the address that packets are sent to.
+	// It is used when the peer migrates to a new address.
+	SetRemoteAddr(net.Addr)
+	// SetPacketInfo changes the local address that packets are sent from.
+	// It is used when the peer starts sending to a different local address, e.g. the server's preferred address.
+	SetPacketInfo(*packetInfo)
+	// UsesPacketInfo says if the local address that packets are sent from is set explicitly,
+	// based on the packet info of the packets received from the peer.
+	UsesPacketInfo() bool
+}
+
+// remoteAddrHolder holds the address packets are sent to.
+// It is accessed from the send queue, and changed from the connection's run loop when the peer migrates.
+type remoteAddrHolder struct {
+	mutex sync.Mutex
+	addr  net.Addr
+}
+
+func (a *remoteAddrHolder) RemoteAddr() net.Addr {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.addr
+}
+
+func (a *remoteAddrHolder) SetRemoteAddr(addr net.Addr) {
+	a.mutex.Lock()
+	a.addr = addr
+	a.mutex.Unlock()
 }
 
 type sconn struct {
 	rawConn
+	remoteAddrHolder
 
-	remoteAddr net.Addr
-	info       *packetInfo
-	oob        []byte
+	// info and oob are changed when the peer starts sending to a different local address
+	infoMutex sync.Mutex
+	info      *packetInfo
+	oob       []byte
+	// set if batches are sent using UDP GSO
+	gsoWriter gsoWriter
 }
 
 var _ sendConn = &sconn{}
 
-func newSendConn(c rawConn, remote net.Addr, info *packetInfo) sendConn {
-	return &sconn{
-		rawConn:    c,
-		remoteAddr: remote,
-		info:       info,
-		oob:        info.OOB(),
+func newSendConn(c rawConn, remote net.Addr, info *packetInfo, disableGSO bool) sendConn {
+	sc := &sconn{
+		rawConn:          c,
+		remoteAddrHolder: remoteAddrHolder{addr: remote},
+		info:             info,
+		oob:              info.OOB(),
 	}
+	if !disableGSO && supportsGSO(c) {
+		sc.gsoWriter = c.(gsoWriter)
+	}
+	return sc
 }
 
 func (c *sconn) Write(p []byte) error {
-	_, err := c.WritePacket(p, c.remoteAddr, c.oob)
+	_, err := c.WritePacket(p, c.RemoteAddr(), c.getOOB())
+	return err
+}
+
+func (c *sconn) WritePacketTo(p []byte, addr net.Addr) error {
+	_, err := c.WritePacket(p, addr, c.getOOB())
 	return err
 }
 
-func (c *sconn) RemoteAddr() net.Addr {
-	return c.remoteAddr
+func (c *sconn) WriteBatch(packets [][]byte) (int, error) {
+	oob := c.getOOB()
+	if c.gsoWriter != nil && c.gsoWriter.SupportsGSO() && canSendWithGSO(packets) {
+		return c.gsoWriter.WriteGSO(packets, c.RemoteAddr(), oob)
+	}
+	if bw, ok := c.rawConn.(batchWriter); ok {
+		return bw.WritePackets(packets, c.RemoteAddr(), oob)
+	}
+	return writePacketsIndividually(c.rawConn, packets, c.RemoteAddr(), oob)
+}
+
+func (c *sconn) WriteBatchWithTimestamps(packets [][]byte, onSent []func(time.Time)) (int, error) {
+	// GSO isn't used here, since the kernel would only report a single timestamp for all packets.
+	if tw, ok := c.rawConn.(timestampWriter); ok {
+		return tw.WritePacketsWithTimestamps(packets, c.RemoteAddr(), c.getOOB(), onSent)
+	}
+	n, err := c.WriteBatch(packets)
+	reportSendTime(onSent[:n], time.Now())
+	return n, err
+}
+
+func (c *sconn) getOOB() []byte {
+	c.infoMutex.Lock()
+	defer c.infoMutex.Unlock()
+	return c.oob
+}
+
+func (c *sconn) SetPacketInfo(info *packetInfo) {
+	c.infoMutex.Lock()
+	defer c.infoMutex.Unlock()
+	if info == nil || (c.info != nil && c.info.addr.Equal(info.addr) && c.info.ifIndex == info.ifIndex) {
+		return
+	}
+	c.info = info
+	c.oob = info.OOB()
+}
+
+func (c *sconn) UsesPacketInfo() bool {
+	c.infoMutex.Lock()
+	defer c.infoMutex.Unlock()
+	return c.info != nil
 }
 
 func (c *sconn) LocalAddr() net.Addr {
 	addr := c.rawConn.LocalAddr()
-	if c.info != nil {
+	c.infoMutex.Lock()
+	info := c.info
+	c.infoMutex.Unlock()
+	if info != nil {
 		if udpAddr, ok := addr.(*net.UDPAddr); ok {
 			addrCopy := *udpAddr
-			addrCopy.IP = c.info.addr
+			addrCopy.IP = info.addr
 			addr = &addrCopy
 		}
 	}
@@ -54,21 +152,232 @@ func (c *sconn) LocalAddr() net.Addr {
 
 type spconn struct {
 	net.PacketConn
-
-	remoteAddr net.Addr
+	remoteAddrHolder
 }
 
 var _ sendConn = &spconn{}
 
 func newSendPconn(c net.PacketConn, remote net.Addr) sendConn {
-	return &spconn{PacketConn: c, remoteAddr: remote}
+	return &spconn{PacketConn: c, remoteAddrHolder: remoteAddrHolder{addr: remote}}
 }
 
 func (c *spconn) Write(p []byte) error {
-	_, err := c.WriteTo(p, c.remoteAddr)
+	_, err := c.WriteTo(p, c.RemoteAddr())
+	return err
+}
+
+func (c *spconn) WritePacketTo(p []byte, addr net.Addr) error {
+	_, err := c.WriteTo(p, addr)
 	return err
 }
 
-func (c *spconn) RemoteAddr() net.Addr {
-	return c.remoteAddr
+// SetPacketInfo is a no-op, since the packet conn doesn't support packet info.
+func (c *spconn) SetPacketInfo(*packetInfo) {}
+
+func (c *spconn) UsesPacketInfo() bool { return false }
+
+func (c *spconn) WriteBatch(packets [][]byte) (int, error) {
+	for i, p := range packets {
+		if err := c.Write(p); err != nil {
+			return i, err
+		}
+	}
+	return len(packets), nil
+}
+
+func (c *spconn) WriteBatchWithTimestamps(packets [][]byte, onSent []func(time.Time)) (int, error) {
+	n, err := c.WriteBatch(packets)
+	reportSendTime(onSent[:n], time.Now())
+	return n, err
+}
+
+// A connectedPconn is used by clients that created their own UDP socket (i.e. when using DialAddr).
+// The socket is connected to the server's address, and packets are sent using Write,
+// saving the kernel a route lookup for every packet.
+// Before sending to a different address (e.g. when probing the server's preferred address),
+// the socket is disconnected, and packets are sent using WriteTo from then on.
+type connectedPconn struct {
+	spconn
+	conn *net.UDPConn
+
+	mutex     sync.RWMutex
+	connected bool
+}
+
+var _ sendConn = &connectedPconn{}
+
+func newConnectedPconn(c *net.UDPConn, remote *net.UDPAddr) (sendConn, error) {
+	if err := connectUDP(c, remote); err != nil {
+		return nil, err
+	}
+	return &connectedPconn{
+		spconn:    spconn{PacketConn: c, remoteAddrHolder: remoteAddrHolder{addr: remote}},
+		conn:      c,
+		connected: true,
+	}, nil
+}
+
+func (c *connectedPconn) Write(p []byte) error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if !c.connected {
+		return c.spconn.Write(p)
+	}
+	// An ICMP error received for a previous packet is reported by the next write.
+	// Don't fail the connection, an unconnected socket would not have reported it.
+	if _, err := c.conn.Write(p); err != nil && !isConnRefusedErr(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *connectedPconn) WritePacketTo(p []byte, addr net.Addr) error {
+	c.disconnect()
+	return c.spconn.WritePacketTo(p, addr)
+}
+
+func (c *connectedPconn) SetRemoteAddr(addr net.Addr) {
+	if addr.String() != c.RemoteAddr().String() {
+		c.disconnect()
+	}
+	c.spconn.SetRemoteAddr(addr)
+}
+
+func (c *connectedPconn) WriteBatch(packets [][]byte) (int, error) {
+	for i, p := range packets {
+		if err := c.Write(p); err != nil {
+			return i, err
+		}
+	}
+	return len(packets), nil
+}
+
+func (c *connectedPconn) WriteBatchWithTimestamps(packets [][]byte, onSent []func(time.Time)) (int, error) {
+	n, err := c.WriteBatch(packets)
+	reportSendTime(onSent[:n], time.Now())
+	return n, err
+}
+
+func (c *connectedPconn) disconnect() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.connected {
+		return
+	}
+	c.connected = false
+	if err := disconnectUDP(c.conn); err != nil {
+		utils.DefaultLogger.Debugf("Disconnecting the UDP socket failed: %s", err)
+	}
+}
+
+// A switchableSendConn is the sendConn used by clients.
+// It allows switching to a different sendConn when the connection migrates to a new packet conn
+// (see Connection.MigrateTo).
+type switchableSendConn struct {
+	mutex sync.RWMutex
+	conn  sendConn
+}
+
+var _ sendConn = &switchableSendConn{}
+
+func newSwitchableSendConn(c sendConn) *switchableSendConn {
+	return &switchableSendConn{conn: c}
+}
+
+// Switch switches to a new sendConn. It doesn't close the old sendConn.
+func (c *switchableSendConn) Switch(conn sendConn) {
+	c.mutex.Lock()
+	c.conn = conn
+	c.mutex.Unlock()
+}
+
+func (c *switchableSendConn) get() sendConn {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.conn
+}
+
+func (c *switchableSendConn) Write(p []byte) error { return c.get().Write(p) }
+
+func (c *switchableSendConn) WriteBatch(packets [][]byte) (int, error) {
+	return c.get().WriteBatch(packets)
+}
+
+func (c *switchableSendConn) WriteBatchWithTimestamps(packets [][]byte, onSent []func(time.Time)) (int, error) {
+	return c.get().WriteBatchWithTimestamps(packets, onSent)
+}
+
+func (c *switchableSendConn) WritePacketTo(p []byte, addr net.Addr) error {
+	return c.get().WritePacketTo(p, addr)
+}
+
+func (c *switchableSendConn) Close() error                   { return c.get().Close() }
+func (c *switchableSendConn) LocalAddr() net.Addr            { return c.get().LocalAddr() }
+func (c *switchableSendConn) RemoteAddr() net.Addr           { return c.get().RemoteAddr() }
+func (c *switchableSendConn) SetRemoteAddr(addr net.Addr)    { c.get().SetRemoteAddr(addr) }
+func (c *switchableSendConn) SetPacketInfo(info *packetInfo) { c.get().SetPacketInfo(info) }
+func (c *switchableSendConn) UsesPacketInfo() bool           { return c.get().UsesPacketInfo() }
+
+// reportSendTime is used when the kernel doesn't report TX timestamps.
+// It reports the time the packets were passed to the kernel.
+func reportSendTime(onSent []func(time.Time), t time.Time) {
+	for _, f := range onSent {
+		f(t)
+	}
+}
+
+// A batchWriter is a rawConn that can send multiple packets with a single syscall.
+type batchWriter interface {
+	WritePackets(packets [][]byte, addr net.Addr, oob []byte) (int, error)
+}
+
+// A timestampWriter is a rawConn that can obtain TX timestamps from the kernel.
+type timestampWriter interface {
+	WritePacketsWithTimestamps(packets [][]byte, addr net.Addr, oob []byte, onSent []func(time.Time)) (int, error)
+}
+
+// A gsoWriter is a rawConn that can send multiple packets using UDP Generic Segmentation Offload.
+type gsoWriter interface {
+	SupportsGSO() bool
+	WriteGSO(packets [][]byte, addr net.Addr, oob []byte) (int, error)
+}
+
+func supportsGSO(c rawConn) bool {
+	gw, ok := c.(gsoWriter)
+	return ok && gw.SupportsGSO()
+}
+
+const (
+	// maxGSOSegments is the maximum number of segments the kernel accepts for a single send call (UDP_MAX_SEGMENTS).
+	maxGSOSegments = 64
+	// maxGSOSize is the maximum payload of a UDP datagram sent over IPv4.
+	maxGSOSize = 65507
+)
+
+// canSendWithGSO checks if a batch of packets can be sent using GSO.
+// This requires all packets to have the same size, except for the last one, which may be shorter.
+func canSendWithGSO(packets [][]byte) bool {
+	if len(packets) < 2 || len(packets) > maxGSOSegments {
+		return false
+	}
+	segmentSize := len(packets[0])
+	if segmentSize == 0 || segmentSize*len(packets) > maxGSOSize {
+		return false
+	}
+	for _, p := range packets[1 : len(packets)-1] {
+		if len(p) != segmentSize {
+			return false
+		}
+	}
+	last := len(packets[len(packets)-1])
+	return last > 0 && last <= segmentSize
+}
+
+func writePacketsIndividually(c rawConn, packets [][]byte, addr net.Addr, oob []byte) (int, error) {
+	for i, p := range packets {
+		if _, err := c.WritePacket(p, addr, oob); err != nil {
+			return i, err
+		}
+	}
+	return len(packets), nil
 }
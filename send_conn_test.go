@@ -29,6 +29,21 @@ var _ = Describe("Connection (for sending packets)", func() {
 		Expect(c.RemoteAddr().String()).To(Equal("192.168.100.200:1337"))
 	})
 
+	It("changes the remote address", func() {
+		newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 201), Port: 4242}
+		c.SetRemoteAddr(newAddr)
+		Expect(c.RemoteAddr()).To(Equal(newAddr))
+		packetConn.EXPECT().WriteTo([]byte("foobar"), newAddr)
+		Expect(c.Write([]byte("foobar"))).To(Succeed())
+	})
+
+	It("writes to a different address", func() {
+		newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 201), Port: 4242}
+		packetConn.EXPECT().WriteTo([]byte("foobar"), newAddr)
+		Expect(c.WritePacketTo([]byte("foobar"), newAddr)).To(Succeed())
+		Expect(c.RemoteAddr()).To(Equal(addr))
+	})
+
 	It("gets the local address", func() {
 		addr := &net.UDPAddr{
 			IP:   net.IPv4(192, 168, 0, 1),
@@ -42,4 +57,38 @@ var _ = Describe("Connection (for sending packets)", func() {
 		packetConn.EXPECT().Close()
 		Expect(c.Close()).To(Succeed())
 	})
+
+	It("checks if a batch can be sent using GSO", func() {
+		Expect(canSendWithGSO([][]byte{[]byte("foo")})).To(BeFalse())
+		Expect(canSendWithGSO([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})).To(BeTrue())
+		// the last packet may be shorter
+		Expect(canSendWithGSO([][]byte{[]byte("foo"), []byte("bar"), []byte("b")})).To(BeTrue())
+		Expect(canSendWithGSO([][]byte{[]byte("foo"), []byte("bar"), []byte("")})).To(BeFalse())
+		Expect(canSendWithGSO([][]byte{[]byte("foo"), []byte("bar"), []byte("foobar")})).To(BeFalse())
+		Expect(canSendWithGSO([][]byte{[]byte("foo"), []byte("b"), []byte("bar")})).To(BeFalse())
+		packets := make([][]byte, maxGSOSegments+1)
+		for i := range packets {
+			packets[i] = []byte("foo")
+		}
+		Expect(canSendWithGSO(packets)).To(BeFalse())
+		Expect(canSendWithGSO(packets[:maxGSOSegments])).To(BeTrue())
+		Expect(canSendWithGSO([][]byte{make([]byte, maxGSOSize/2), make([]byte, maxGSOSize/2), []byte("foo")})).To(BeFalse())
+	})
+})
+
+var _ = Describe("Switchable connection (for sending packets)", func() {
+	It("switches to a new connection", func() {
+		addr := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 200), Port: 1337}
+		packetConn1 := NewMockPacketConn(mockCtrl)
+		packetConn2 := NewMockPacketConn(mockCtrl)
+		c := newSwitchableSendConn(newSendPconn(packetConn1, addr))
+		packetConn1.EXPECT().WriteTo([]byte("foo"), addr)
+		Expect(c.Write([]byte("foo"))).To(Succeed())
+		c.Switch(newSendPconn(packetConn2, addr))
+		packetConn2.EXPECT().WriteTo([]byte("bar"), addr)
+		Expect(c.Write([]byte("bar"))).To(Succeed())
+		localAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 1234}
+		packetConn2.EXPECT().LocalAddr().Return(localAddr)
+		Expect(c.LocalAddr()).To(Equal(localAddr))
+	})
 })
@@ -1,5 +1,7 @@
 package quic
 
+import "time"
+
 type sender interface {
 	Send(p *packetBuffer)
 	Run() error
@@ -14,12 +16,20 @@ type sendQueue struct {
 	runStopped  chan struct{} // runStopped when the run loop returns
 	available   chan struct{}
 	conn        sendConn
+
+	// reused for every batch of packets
+	batch   []*packetBuffer
+	packets [][]byte
+	onSent  []func(time.Time)
 }
 
 var _ sender = &sendQueue{}
 
 const sendQueueCapacity = 8
 
+// maxSendBatchSize is the maximum number of packets passed to a single WriteBatch call
+const maxSendBatchSize = sendQueueCapacity
+
 func newSendQueue(conn sendConn) sender {
 	return &sendQueue{
 		conn:        conn,
@@ -63,16 +73,24 @@ func (h *sendQueue) Run() error {
 			// make sure that all queued packets are actually sent out
 			shouldClose = true
 		case p := <-h.queue:
-			if err := h.conn.Write(p.Data); err != nil {
-				// This additional check enables:
-				// 1. Checking for "datagram too large" message from the kernel, as such,
-				// 2. Path MTU discovery,and
-				// 3. Eventual detection of loss PingFrame.
-				if !isMsgSizeErr(err) {
-					return err
+			batch := append(h.batch[:0], p)
+			// Send all packets that were queued in the meantime using a single syscall (if possible).
+		drain:
+			for len(batch) < maxSendBatchSize {
+				select {
+				case p := <-h.queue:
+					batch = append(batch, p)
+				default:
+					break drain
 				}
 			}
-			p.Release()
+			h.batch = batch
+			if err := h.send(batch); err != nil {
+				return err
+			}
+			for _, p := range batch {
+				p.Release()
+			}
 			select {
 			case h.available <- struct{}{}:
 			default:
@@ -81,6 +99,57 @@ func (h *sendQueue) Run() error {
 	}
 }
 
+func (h *sendQueue) send(batch []*packetBuffer) error {
+	// All packets of a connection are traced, or none of them are.
+	onSent := h.onSent[:0]
+	if batch[0].onSent != nil {
+		for _, p := range batch {
+			onSent = append(onSent, p.onSent)
+		}
+	}
+	h.onSent = onSent
+
+	if len(batch) == 1 && len(onSent) == 0 {
+		if err := h.conn.Write(batch[0].Data); err != nil {
+			// This additional check enables:
+			// 1. Checking for "datagram too large" message from the kernel, as such,
+			// 2. Path MTU discovery,and
+			// 3. Eventual detection of loss PingFrame.
+			if !isMsgSizeErr(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	packets := h.packets[:0]
+	for _, p := range batch {
+		packets = append(packets, p.Data)
+	}
+	h.packets = packets
+	for len(packets) > 0 {
+		var n int
+		var err error
+		if len(onSent) > 0 {
+			n, err = h.conn.WriteBatchWithTimestamps(packets, onSent)
+		} else {
+			n, err = h.conn.WriteBatch(packets)
+		}
+		if err == nil {
+			break
+		}
+		if !isMsgSizeErr(err) {
+			return err
+		}
+		// skip the packet that was too large, and send the remaining packets
+		packets = packets[n+1:]
+		if len(onSent) > 0 {
+			onSent = onSent[n+1:]
+		}
+	}
+	return nil
+}
+
 func (h *sendQueue) Close() {
 	close(h.closeCalled)
 	// wait until the run loop returned
@@ -2,6 +2,7 @@ package quic
 
 import (
 	"errors"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
@@ -42,6 +43,70 @@ var _ = Describe("Send Queue", func() {
 		Eventually(done).Should(BeClosed())
 	})
 
+	It("sends multiple queued packets in a single batch", func() {
+		q.Send(getPacket([]byte("foo")))
+		q.Send(getPacket([]byte("bar")))
+		q.Send(getPacket([]byte("baz")))
+
+		written := make(chan struct{})
+		c.EXPECT().WriteBatch([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")}).DoAndReturn(func([][]byte) (int, error) {
+			close(written)
+			return 3, nil
+		})
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			q.Run()
+			close(done)
+		}()
+
+		Eventually(written).Should(BeClosed())
+		q.Close()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("reports the send timestamps, sending packets in a batch", func() {
+		t1 := time.Now().Add(-time.Second)
+		t2 := time.Now().Add(-time.Millisecond)
+		timestamps := make(chan time.Time, 2)
+		for _, b := range [][]byte{[]byte("foo"), []byte("bar")} {
+			p := getPacket(b)
+			p.onSent = func(t time.Time) { timestamps <- t }
+			q.Send(p)
+		}
+		c.EXPECT().WriteBatchWithTimestamps([][]byte{[]byte("foo"), []byte("bar")}, gomock.Any()).DoAndReturn(func(_ [][]byte, onSent []func(time.Time)) (int, error) {
+			Expect(onSent).To(HaveLen(2))
+			onSent[0](t1)
+			onSent[1](t2)
+			return 2, nil
+		})
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			q.Run()
+			close(done)
+		}()
+
+		Eventually(timestamps).Should(Receive(Equal(t1)))
+		Eventually(timestamps).Should(Receive(Equal(t2)))
+		q.Close()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("returns errors that occur when sending a batch", func() {
+		q.Send(getPacket([]byte("foo")))
+		q.Send(getPacket([]byte("bar")))
+
+		testErr := errors.New("test error")
+		c.EXPECT().WriteBatch(gomock.Any()).Return(1, testErr)
+		errChan := make(chan error, 1)
+		go func() {
+			defer GinkgoRecover()
+			errChan <- q.Run()
+		}()
+		Eventually(errChan).Should(Receive(MatchError(testErr)))
+	})
+
 	It("panics when Send() is called although there's no space in the queue", func() {
 		for i := 0; i < sendQueueCapacity; i++ {
 			Expect(q.WouldBlock()).To(BeFalse())
@@ -3,6 +3,8 @@ package quic
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
 	"sync"
 	"time"
 
@@ -12,6 +14,7 @@ import (
 	"github.com/fkwhite/quic-go/internal/qerr"
 	"github.com/fkwhite/quic-go/internal/utils"
 	"github.com/fkwhite/quic-go/internal/wire"
+	"github.com/fkwhite/quic-go/logging"
 )
 
 type sendStreamI interface {
@@ -21,6 +24,7 @@ type sendStreamI interface {
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
 	closeForShutdown(error)
 	updateSendWindow(protocol.ByteCount)
+	sendCloseState() *logging.StreamCloseState
 }
 
 type sendStream struct {
@@ -36,9 +40,13 @@ type sendStream struct {
 	sender   streamSender
 
 	writeOffset protocol.ByteCount
+	bytesAcked  protocol.ByteCount // the number of bytes in STREAM frames that were acknowledged
+	// set by CancelWriteAt: data up to this offset is retransmitted after the stream was canceled
+	reliableSize protocol.ByteCount
 
-	cancelWriteErr      error
-	closeForShutdownErr error
+	cancelWriteErr       error
+	cancelWriteErrorCode qerr.StreamErrorCode
+	closeForShutdownErr  error
 
 	closedForShutdown bool // set when CloseForShutdown() is called
 	finishedWriting   bool // set once Close() is called
@@ -92,6 +100,73 @@ func (s *sendStream) Write(p []byte) (int, error) {
 	s.writeOnce <- struct{}{}
 	defer func() { <-s.writeOnce }()
 
+	return s.write(p)
+}
+
+// WriteBuffers writes multiple buffers. It is not thread safe!
+func (s *sendStream) WriteBuffers(bufs net.Buffers) (int64, error) {
+	s.writeOnce <- struct{}{}
+	defer func() { <-s.writeOnce }()
+
+	var n int64
+	for len(bufs) > 0 {
+		// Coalesce consecutive buffers that fit into a single STREAM frame.
+		// Large buffers are written without copying them.
+		var l, num int
+		for num < len(bufs) && l+len(bufs[num]) <= int(protocol.MaxPacketBufferSize) {
+			l += len(bufs[num])
+			num++
+		}
+		var written int
+		var err error
+		if num <= 1 {
+			num = 1
+			written, err = s.write(bufs[0])
+		} else {
+			buf := getPacketBuffer()
+			for _, b := range bufs[:num] {
+				buf.Data = append(buf.Data, b...)
+			}
+			written, err = s.write(buf.Data)
+			buf.Release()
+		}
+		n += int64(written)
+		if err != nil {
+			return n, err
+		}
+		bufs = bufs[num:]
+	}
+	return n, nil
+}
+
+// ReadFrom implements io.ReaderFrom. It is not thread safe!
+func (s *sendStream) ReadFrom(r io.Reader) (int64, error) {
+	s.writeOnce <- struct{}{}
+	defer func() { <-s.writeOnce }()
+
+	buf := getPacketBuffer()
+	defer buf.Release()
+	b := buf.Data[:cap(buf.Data)]
+	var n int64
+	for {
+		m, rerr := r.Read(b)
+		if m > 0 {
+			written, err := s.write(b[:m])
+			n += int64(written)
+			if err != nil {
+				return n, err
+			}
+		}
+		if rerr == io.EOF {
+			return n, nil
+		}
+		if rerr != nil {
+			return n, rerr
+		}
+	}
+}
+
+func (s *sendStream) write(p []byte) (int, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
@@ -219,7 +294,10 @@ func (s *sendStream) popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Fr
 }
 
 func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCount) (*wire.StreamFrame, bool /* has more data to send */) {
-	if s.canceledWrite || s.closeForShutdownErr != nil {
+	if s.closeForShutdownErr != nil {
+		return nil, false
+	}
+	if s.canceledWrite && len(s.retransmissionQueue) == 0 {
 		return nil, false
 	}
 
@@ -234,6 +312,9 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 			return f, true
 		}
 	}
+	if s.canceledWrite {
+		return nil, false
+	}
 
 	if len(s.dataForWriting) == 0 && s.nextFrame == nil {
 		if s.finishedWriting && !s.finSent {
@@ -292,7 +373,8 @@ func (s *sendStream) popNewStreamFrame(maxBytes, sendWindow protocol.ByteCount)
 		return nextFrame, s.nextFrame != nil || s.dataForWriting != nil
 	}
 
-	f := wire.GetStreamFrame()
+	// With large packets (see Config.MaxPacketSize), a STREAM frame might carry more data than fits into a pooled frame.
+	f := wire.GetStreamFrameWithSize(utils.Min(maxBytes, protocol.ByteCount(len(s.dataForWriting))))
 	f.Fin = false
 	f.StreamID = s.streamID
 	f.Offset = s.writeOffset
@@ -351,13 +433,16 @@ func (s *sendStream) getDataForWriting(f *wire.StreamFrame, maxBytes protocol.By
 }
 
 func (s *sendStream) frameAcked(f wire.Frame) {
-	f.(*wire.StreamFrame).PutBack()
+	sf := f.(*wire.StreamFrame)
+	dataLen := sf.DataLen()
+	sf.PutBack()
 
 	s.mutex.Lock()
-	if s.canceledWrite {
+	if s.canceledWrite && s.reliableSize == 0 {
 		s.mutex.Unlock()
 		return
 	}
+	s.bytesAcked += dataLen
 	s.numOutstandingFrames--
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
@@ -370,6 +455,20 @@ func (s *sendStream) frameAcked(f wire.Frame) {
 	}
 }
 
+func (s *sendStream) BufferedBytes() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.canceledWrite || s.closedForShutdown {
+		return 0
+	}
+	buffered := s.writeOffset - s.bytesAcked + protocol.ByteCount(len(s.dataForWriting))
+	if s.nextFrame != nil {
+		buffered += s.nextFrame.DataLen()
+	}
+	return uint64(buffered)
+}
+
 func (s *sendStream) isNewlyCompleted() bool {
 	completed := (s.finSent || s.canceledWrite) && s.numOutstandingFrames == 0 && len(s.retransmissionQueue) == 0
 	if completed && !s.completed {
@@ -383,15 +482,23 @@ func (s *sendStream) queueRetransmission(f wire.Frame) {
 	sf := f.(*wire.StreamFrame)
 	sf.DataLenPresent = true
 	s.mutex.Lock()
-	if s.canceledWrite {
+	if s.canceledWrite && s.reliableSize == 0 {
 		s.mutex.Unlock()
 		return
 	}
-	s.retransmissionQueue = append(s.retransmissionQueue, sf)
 	s.numOutstandingFrames--
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
 	}
+	if s.canceledWrite && !s.trimToReliableSize(sf) {
+		newlyCompleted := s.isNewlyCompleted()
+		s.mutex.Unlock()
+		if newlyCompleted {
+			s.sender.onStreamCompleted(s.streamID)
+		}
+		return
+	}
+	s.retransmissionQueue = append(s.retransmissionQueue, sf)
 	s.mutex.Unlock()
 
 	s.sender.onHasStreamData(s.streamID)
@@ -416,11 +523,19 @@ func (s *sendStream) Close() error {
 }
 
 func (s *sendStream) CancelWrite(errorCode StreamErrorCode) {
-	s.cancelWriteImpl(errorCode, fmt.Errorf("Write on stream %d canceled with error code %d", s.streamID, errorCode))
+	s.cancelWriteImpl(errorCode, 0, &StreamError{StreamID: s.streamID, ErrorCode: errorCode, Remote: false})
 }
 
-// must be called after locking the mutex
-func (s *sendStream) cancelWriteImpl(errorCode qerr.StreamErrorCode, writeErr error) {
+func (s *sendStream) CancelWriteAt(errorCode StreamErrorCode, reliableSize uint64) {
+	// If the peer doesn't support RESET_STREAM_AT, the stream is reset without any reliability guarantees.
+	// In that case, the stream must not retransmit any data after the reset, just like for CancelWrite.
+	if !s.sender.supportsResetStreamAt() {
+		reliableSize = 0
+	}
+	s.cancelWriteImpl(errorCode, protocol.ByteCount(reliableSize), &StreamError{StreamID: s.streamID, ErrorCode: errorCode, Remote: false})
+}
+
+func (s *sendStream) cancelWriteImpl(errorCode qerr.StreamErrorCode, reliableSize protocol.ByteCount, writeErr error) {
 	s.mutex.Lock()
 	if s.canceledWrite {
 		s.mutex.Unlock()
@@ -429,22 +544,77 @@ func (s *sendStream) cancelWriteImpl(errorCode qerr.StreamErrorCode, writeErr er
 	s.ctxCancel()
 	s.canceledWrite = true
 	s.cancelWriteErr = writeErr
-	s.numOutstandingFrames = 0
-	s.retransmissionQueue = nil
+	s.cancelWriteErrorCode = errorCode
+	s.reliableSize = utils.Min(reliableSize, s.writeOffset)
+	if s.reliableSize == 0 {
+		s.numOutstandingFrames = 0
+		s.retransmissionQueue = nil
+	} else {
+		retransmissionQueue := s.retransmissionQueue[:0]
+		for _, f := range s.retransmissionQueue {
+			if s.trimToReliableSize(f) {
+				retransmissionQueue = append(retransmissionQueue, f)
+			}
+		}
+		s.retransmissionQueue = retransmissionQueue
+	}
+	finalSize := s.writeOffset
+	reliableSize = s.reliableSize
+	hasRetransmissions := len(s.retransmissionQueue) > 0
 	newlyCompleted := s.isNewlyCompleted()
 	s.mutex.Unlock()
 
 	s.signalWrite()
-	s.sender.queueControlFrame(&wire.ResetStreamFrame{
-		StreamID:  s.streamID,
-		FinalSize: s.writeOffset,
-		ErrorCode: errorCode,
-	})
+	if reliableSize > 0 {
+		s.sender.queueControlFrame(&wire.ResetStreamAtFrame{
+			StreamID:     s.streamID,
+			FinalSize:    finalSize,
+			ReliableSize: reliableSize,
+			ErrorCode:    errorCode,
+		})
+	} else {
+		s.sender.queueControlFrame(&wire.ResetStreamFrame{
+			StreamID:  s.streamID,
+			FinalSize: finalSize,
+			ErrorCode: errorCode,
+		})
+	}
+	if hasRetransmissions {
+		s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+	}
 	if newlyCompleted {
 		s.sender.onStreamCompleted(s.streamID)
 	}
 }
 
+// trimToReliableSize cuts off all data beyond the reliable size.
+// It returns false if the frame doesn't contain any data that needs to be retransmitted.
+// must be called after locking the mutex
+func (s *sendStream) trimToReliableSize(f *wire.StreamFrame) bool {
+	if f.Offset >= s.reliableSize {
+		return false
+	}
+	if f.Offset+f.DataLen() > s.reliableSize {
+		f.Data = f.Data[:s.reliableSize-f.Offset]
+	}
+	f.Fin = false
+	return true
+}
+
+// sendCloseState returns the final size of the stream, and the error code if it was reset.
+// It is used for tracing, once the stream has completed.
+func (s *sendStream) sendCloseState() *logging.StreamCloseState {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state := &logging.StreamCloseState{FinalSize: s.writeOffset}
+	if s.canceledWrite {
+		errorCode := s.cancelWriteErrorCode
+		state.ErrorCode = &errorCode
+	}
+	return state
+}
+
 func (s *sendStream) updateSendWindow(limit protocol.ByteCount) {
 	s.mutex.Lock()
 	hasStreamData := s.dataForWriting != nil || s.nextFrame != nil
@@ -457,9 +627,10 @@ func (s *sendStream) updateSendWindow(limit protocol.ByteCount) {
 }
 
 func (s *sendStream) handleStopSendingFrame(frame *wire.StopSendingFrame) {
-	s.cancelWriteImpl(frame.ErrorCode, &StreamError{
+	s.cancelWriteImpl(frame.ErrorCode, 0, &StreamError{
 		StreamID:  s.streamID,
 		ErrorCode: frame.ErrorCode,
+		Remote:    true,
 	})
 }
 
@@ -480,6 +651,14 @@ func (s *sendStream) SetWriteDeadline(t time.Time) error {
 // The peer will NOT be informed about this: the stream is closed without sending a FIN or RST.
 func (s *sendStream) closeForShutdown(err error) {
 	s.mutex.Lock()
+	if err == Err0RTTRejected {
+		// All data written so far was either sent in 0-RTT packets, or is still waiting to be sent.
+		bytesWritten := s.writeOffset
+		if s.nextFrame != nil {
+			bytesWritten += s.nextFrame.DataLen()
+		}
+		err = &Stream0RTTRejectedError{StreamID: s.streamID, BytesWritten: uint64(bytesWritten)}
+	}
 	s.ctxCancel()
 	s.closedForShutdown = true
 	s.closeForShutdownErr = err
@@ -5,7 +5,10 @@ import (
 	"errors"
 	"io"
 	mrand "math/rand"
+	"net"
+	"os"
 	"runtime"
+	"testing/iotest"
 	"time"
 
 	"github.com/golang/mock/gomock"
@@ -13,6 +16,7 @@ import (
 	"github.com/fkwhite/quic-go/internal/mocks"
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/wire"
+	"github.com/fkwhite/quic-go/logging"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -299,6 +303,50 @@ var _ = Describe("Send Stream", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("bundles multiple buffers", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			n, err := str.WriteBuffers(net.Buffers{[]byte("foo"), []byte("bar"), nil, []byte("baz")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeEquivalentTo(9))
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(9))
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			f := frame.Frame.(*wire.StreamFrame)
+			Expect(f.Offset).To(BeZero())
+			Expect(f.Data).To(Equal([]byte("foobarbaz")))
+		})
+
+		It("writes the data read from an io.Reader", func() {
+			data := getData(5000)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				mockSender.EXPECT().onHasStreamData(streamID).AnyTimes()
+				n, err := str.ReadFrom(bytes.NewReader(data))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(BeEquivalentTo(5000))
+			}()
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+			var received []byte
+			Eventually(func() []byte {
+				if frame, _ := str.popStreamFrame(protocol.MaxByteCount); frame != nil {
+					received = append(received, frame.Frame.(*wire.StreamFrame).Data...)
+				}
+				return received
+			}).Should(Equal(data))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("returns the error from the io.Reader", func() {
+			testErr := errors.New("test error")
+			mockSender.EXPECT().onHasStreamData(streamID)
+			n, err := str.ReadFrom(io.MultiReader(bytes.NewReader([]byte("foobar")), iotest.ErrReader(testErr)))
+			Expect(err).To(MatchError(testErr))
+			Expect(n).To(BeEquivalentTo(6))
+		})
+
 		It("cancels the context when Close is called", func() {
 			mockSender.EXPECT().onHasStreamData(streamID)
 			Expect(str.Context().Done()).ToNot(BeClosed())
@@ -306,6 +354,25 @@ var _ = Describe("Send Stream", func() {
 			Expect(str.Context().Done()).To(BeClosed())
 		})
 
+		It("says how many bytes haven't been acknowledged yet", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.BufferedBytes()).To(BeZero())
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.BufferedBytes()).To(BeEquivalentTo(6))
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(2)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3)).Times(2)
+			frame1, _ := str.popStreamFrame(expectedFrameHeaderLen(0) + 3)
+			Expect(frame1).ToNot(BeNil())
+			frame2, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame2).ToNot(BeNil())
+			Expect(str.BufferedBytes()).To(BeEquivalentTo(6))
+			frame2.OnAcked(frame2.Frame)
+			Expect(str.BufferedBytes()).To(BeEquivalentTo(3))
+			frame1.OnAcked(frame1.Frame)
+			Expect(str.BufferedBytes()).To(BeZero())
+		})
+
 		Context("flow control blocking", func() {
 			It("queues a BLOCKED frame if the stream is flow control blocked", func() {
 				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(0))
@@ -384,6 +451,28 @@ var _ = Describe("Send Stream", func() {
 				Expect(time.Now()).To(BeTemporally("~", deadline, scaleDuration(20*time.Millisecond)))
 			})
 
+			It("returns an error for an empty Write after the deadline", func() {
+				str.SetWriteDeadline(time.Now().Add(-time.Second))
+				n, err := strWithTimeout.Write(nil)
+				Expect(err).To(MatchError(errDeadline))
+				Expect(errors.Is(err, os.ErrDeadlineExceeded)).To(BeTrue())
+				Expect(n).To(BeZero())
+			})
+
+			It("unblocks when a deadline is set during a blocked Write", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					_, err := str.Write(getData(5000))
+					Expect(errors.Is(err, os.ErrDeadlineExceeded)).To(BeTrue())
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				str.SetWriteDeadline(time.Now().Add(scaleDuration(20 * time.Millisecond)))
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("unblocks when the deadline is changed to the past", func() {
 				mockSender.EXPECT().onHasStreamData(streamID)
 				str.SetWriteDeadline(time.Now().Add(time.Hour))
@@ -641,6 +730,32 @@ var _ = Describe("Send Stream", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("reports how many bytes were written when 0-RTT is rejected", func() {
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(gomock.Any())
+				mockSender.EXPECT().onHasStreamData(streamID)
+				done := make(chan struct{})
+				var n int
+				var err error
+				go func() {
+					defer GinkgoRecover()
+					n, err = strWithTimeout.Write(getData(5000))
+					close(done)
+				}()
+				waitForWrite()
+				frame, _ := str.popStreamFrame(50)
+				Expect(frame).ToNot(BeNil())
+				dataLen := frame.Frame.(*wire.StreamFrame).DataLen()
+				str.closeForShutdown(Err0RTTRejected)
+				Eventually(done).Should(BeClosed())
+				Expect(n).To(BeEquivalentTo(dataLen))
+				Expect(err).To(MatchError(Err0RTTRejected))
+				var rejectedErr *Stream0RTTRejectedError
+				Expect(errors.As(err, &rejectedErr)).To(BeTrue())
+				Expect(rejectedErr.StreamID).To(Equal(streamID))
+				Expect(rejectedErr.BytesWritten).To(BeEquivalentTo(dataLen))
+			})
+
 			It("cancels the context", func() {
 				Expect(str.Context().Done()).ToNot(BeClosed())
 				str.closeForShutdown(testErr)
@@ -689,6 +804,19 @@ var _ = Describe("Send Stream", func() {
 				str.CancelWrite(9876)
 			})
 
+			It("reports the final size and the error code for tracing", func() {
+				Expect(str.sendCloseState()).To(Equal(&logging.StreamCloseState{}))
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.writeOffset = 1234
+				str.CancelWrite(9876)
+				errorCode := StreamErrorCode(9876)
+				Expect(str.sendCloseState()).To(Equal(&logging.StreamCloseState{
+					FinalSize: 1234,
+					ErrorCode: &errorCode,
+				}))
+			})
+
 			// This test is inherently racy, as it tests a concurrent call to Write() and CancelRead().
 			// A single successful run of this test therefore doesn't mean a lot,
 			// for reliable results it has to be run many times.
@@ -726,7 +854,7 @@ var _ = Describe("Send Stream", func() {
 					defer GinkgoRecover()
 					var err error
 					n, err = strWithTimeout.Write(getData(5000))
-					Expect(err).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+					Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234}))
 					close(writeReturned)
 				}()
 				waitForWrite()
@@ -770,7 +898,7 @@ var _ = Describe("Send Stream", func() {
 				go func() {
 					defer GinkgoRecover()
 					_, err := strWithTimeout.Write(getData(5000))
-					Expect(err).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+					Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234}))
 					close(writeReturned)
 				}()
 				waitForWrite()
@@ -818,7 +946,7 @@ var _ = Describe("Send Stream", func() {
 				mockSender.EXPECT().onStreamCompleted(gomock.Any())
 				str.CancelWrite(1234)
 				_, err := strWithTimeout.Write([]byte("foobar"))
-				Expect(err).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+				Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234}))
 			})
 
 			It("only cancels once", func() {
@@ -840,6 +968,121 @@ var _ = Describe("Send Stream", func() {
 			})
 		})
 
+		Context("canceling writing with a reliable size", func() {
+			BeforeEach(func() {
+				mockSender.EXPECT().supportsResetStreamAt().Return(true).AnyTimes()
+			})
+
+			It("queues a RESET_STREAM_AT frame, limiting the reliable size to the data sent", func() {
+				gomock.InOrder(
+					mockSender.EXPECT().queueControlFrame(&wire.ResetStreamAtFrame{
+						StreamID:     streamID,
+						FinalSize:    1234,
+						ReliableSize: 1234,
+						ErrorCode:    9876,
+					}),
+					mockSender.EXPECT().onStreamCompleted(streamID),
+				)
+				str.writeOffset = 1234
+				str.CancelWriteAt(9876, 2000)
+			})
+
+			It("queues a RESET_STREAM frame if the reliable size is 0", func() {
+				gomock.InOrder(
+					mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+						StreamID:  streamID,
+						FinalSize: 1234,
+						ErrorCode: 9876,
+					}),
+					mockSender.EXPECT().onStreamCompleted(streamID),
+				)
+				str.writeOffset = 1234
+				str.CancelWriteAt(9876, 0)
+			})
+
+			It("retransmits lost data up to the reliable size", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				f, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(f).ToNot(BeNil())
+				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamAtFrame{
+					StreamID:     streamID,
+					FinalSize:    6,
+					ReliableSize: 3,
+					ErrorCode:    1234,
+				})
+				str.CancelWriteAt(1234, 3)
+				_, err = strWithTimeout.Write([]byte("foobar"))
+				Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234}))
+				// lose the frame
+				mockSender.EXPECT().onHasStreamData(streamID)
+				f.OnLost(f.Frame)
+				retransmission, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(retransmission).ToNot(BeNil())
+				Expect(retransmission.Frame.(*wire.StreamFrame).Offset).To(BeZero())
+				Expect(retransmission.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foo")))
+				Expect(retransmission.Frame.(*wire.StreamFrame).Fin).To(BeFalse())
+				frame, hasMoreData := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).To(BeNil())
+				Expect(hasMoreData).To(BeFalse())
+				// acknowledge the retransmission
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				retransmission.OnAcked(retransmission.Frame)
+			})
+
+			It("doesn't retransmit data beyond the reliable size", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(2)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3)).Times(2)
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				f1, _ := str.popStreamFrame(expectedFrameHeaderLen(0) + 3)
+				Expect(f1).ToNot(BeNil())
+				Expect(f1.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foo")))
+				f2, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(f2).ToNot(BeNil())
+				Expect(f2.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("bar")))
+				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamAtFrame{
+					StreamID:     streamID,
+					FinalSize:    6,
+					ReliableSize: 3,
+					ErrorCode:    1234,
+				})
+				str.CancelWriteAt(1234, 3)
+				// don't EXPECT any calls to onHasStreamData
+				f2.OnLost(f2.Frame)
+				Expect(str.retransmissionQueue).To(BeEmpty())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				f1.OnAcked(f1.Frame)
+			})
+		})
+
+		It("resets the stream like CancelWrite, if the peer doesn't support RESET_STREAM_AT", func() {
+			mockSender.EXPECT().supportsResetStreamAt().Return(false)
+			mockSender.EXPECT().onHasStreamData(streamID)
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			f, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(f).ToNot(BeNil())
+			gomock.InOrder(
+				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					FinalSize: 6,
+					ErrorCode: 1234,
+				}),
+				mockSender.EXPECT().onStreamCompleted(streamID),
+			)
+			str.CancelWriteAt(1234, 3)
+			// don't EXPECT any calls to onHasStreamData
+			f.OnLost(f.Frame)
+			Expect(str.retransmissionQueue).To(BeEmpty())
+		})
+
 		Context("receiving STOP_SENDING frames", func() {
 			It("queues a RESET_STREAM frames, and copies the error code from the STOP_SENDING frame", func() {
 				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
@@ -862,9 +1105,10 @@ var _ = Describe("Send Stream", func() {
 				go func() {
 					defer GinkgoRecover()
 					_, err := str.Write(getData(5000))
-					Expect(err).To(MatchError(&StreamError{
+					Expect(err).To(Equal(&StreamError{
 						StreamID:  streamID,
-						ErrorCode: 1234,
+						ErrorCode: 123,
+						Remote:    true,
 					}))
 					close(done)
 				}()
@@ -884,9 +1128,10 @@ var _ = Describe("Send Stream", func() {
 					ErrorCode: 123,
 				})
 				_, err := str.Write([]byte("foobar"))
-				Expect(err).To(MatchError(&StreamError{
+				Expect(err).To(Equal(&StreamError{
 					StreamID:  streamID,
-					ErrorCode: 1234,
+					ErrorCode: 123,
+					Remote:    true,
 				}))
 			})
 		})
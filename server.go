@@ -42,6 +42,7 @@ type packetHandlerManager interface {
 	connRunner
 	SetServer(unknownPacketHandler)
 	CloseServer()
+	UseLargeBuffers()
 }
 
 type quicConn interface {
@@ -53,6 +54,7 @@ type quicConn interface {
 	run() error
 	destroy(error)
 	shutdown()
+	stopIssuingConnectionIDs()
 }
 
 // A Listener of QUIC
@@ -101,9 +103,21 @@ type baseServer struct {
 	closed      bool
 	running     chan struct{} // closed as soon as run() returns
 
+	// set when Shutdown() is called
+	draining     bool
+	drainingChan chan struct{} // closed when Shutdown() is called
+
+	conns      map[quicConn]struct{} // all connections that haven't been closed yet
+	connClosed chan struct{}         // cap: 1, signaled when a connection is closed
+
 	connQueue    chan quicConn
 	connQueueLen int32 // to be used as an atomic
 
+	handshakesMutex sync.Mutex
+	handshakesPerIP map[string]int // number of handshakes in progress, per source IP address
+
+	traceGSOOnce sync.Once
+
 	logger utils.Logger
 }
 
@@ -128,6 +142,7 @@ func ListenAddr(addr string, tlsConf *tls.Config, config *Config) (Listener, err
 }
 
 // ListenAddrEarly works like ListenAddr, but it returns connections before the handshake completes.
+// This allows the server to process 0-RTT data, see EarlyConnection for details.
 func ListenAddrEarly(addr string, tlsConf *tls.Config, config *Config) (EarlyListener, error) {
 	s, err := listenAddr(addr, tlsConf, config, true)
 	if err != nil {
@@ -169,6 +184,7 @@ func Listen(conn net.PacketConn, tlsConf *tls.Config, config *Config) (Listener,
 }
 
 // ListenEarly works like Listen, but it returns connections before the handshake completes.
+// This allows the server to process 0-RTT data, see EarlyConnection for details.
 func ListenEarly(conn net.PacketConn, tlsConf *tls.Config, config *Config) (EarlyListener, error) {
 	s, err := listen(conn, tlsConf, config, true)
 	if err != nil {
@@ -195,6 +211,9 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 	if err != nil {
 		return nil, err
 	}
+	if config.usesLargePackets() {
+		connHandler.UseLargeBuffers()
+	}
 	tokenGenerator, err := handshake.NewTokenGenerator(rand.Reader)
 	if err != nil {
 		return nil, err
@@ -212,6 +231,9 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 		connQueue:        make(chan quicConn),
 		errorChan:        make(chan struct{}),
 		running:          make(chan struct{}),
+		drainingChan:     make(chan struct{}),
+		conns:            make(map[quicConn]struct{}),
+		connClosed:       make(chan struct{}, 1),
 		receivedPackets:  make(chan *receivedPacket, protocol.MaxServerUnprocessedPackets),
 		newConn:          newConnection,
 		logger:           utils.DefaultLogger.WithPrefix("server"),
@@ -255,11 +277,110 @@ func (s *baseServer) accept(ctx context.Context) (quicConn, error) {
 	case conn := <-s.connQueue:
 		atomic.AddInt32(&s.connQueueLen, -1)
 		return conn, nil
+	case <-s.drainingChan:
+		return nil, ErrServerClosed
 	case <-s.errorChan:
 		return nil, s.serverError
 	}
 }
 
+// Shutdown gracefully shuts down the server.
+// It stops accepting new connections: Accept returns ErrServerClosed,
+// and new connection attempts are rejected with a CONNECTION_REFUSED error.
+// Existing connections are not interrupted, but they stop issuing new connection IDs to the peer.
+// Shutdown waits until all of them are closed.
+// If the context expires first, the remaining connections are closed with an application error,
+// and the context's error is returned.
+// Finally, the server is closed, as if Close had been called.
+func (s *baseServer) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	if !s.draining {
+		s.draining = true
+		close(s.drainingChan)
+		// Existing connections continue to use the connection IDs they already issued.
+		for conn := range s.conns {
+			conn.stopIssuingConnectionIDs()
+		}
+	}
+	s.mutex.Unlock()
+
+	for {
+		s.mutex.Lock()
+		numConns := len(s.conns)
+		s.mutex.Unlock()
+		if numConns == 0 {
+			return s.Close()
+		}
+		s.logger.Debugf("Shutting down. Waiting for %d connections to close.", numConns)
+
+		select {
+		case <-s.connClosed:
+		case <-ctx.Done():
+			s.closeConns()
+			s.Close()
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *baseServer) isDraining() bool {
+	select {
+	case <-s.drainingChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// addConn starts tracking a new connection.
+// It returns false if the server is already shutting down.
+func (s *baseServer) addConn(conn quicConn) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.draining || s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *baseServer) removeConn(conn quicConn) {
+	s.mutex.Lock()
+	delete(s.conns, conn)
+	s.mutex.Unlock()
+
+	select {
+	case s.connClosed <- struct{}{}:
+	default:
+	}
+}
+
+// closeConns closes all connections that are still open.
+// It blocks until the CONNECTION_CLOSE has been sent on all of them.
+func (s *baseServer) closeConns() {
+	s.mutex.Lock()
+	conns := make([]quicConn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.mutex.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(len(conns))
+	for _, conn := range conns {
+		go func(conn quicConn) {
+			defer wg.Done()
+			conn.CloseWithError(0, "server shutdown")
+		}(conn)
+	}
+	wg.Wait()
+}
+
 // Close the server
 func (s *baseServer) Close() error {
 	s.mutex.Lock()
@@ -462,19 +583,36 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 		return nil
 	}
 
-	if queueLen := atomic.LoadInt32(&s.connQueueLen); queueLen >= protocol.MaxAcceptQueueSize {
-		s.logger.Debugf("Rejecting new connection. Server currently busy. Accept queue length: %d (max %d)", queueLen, protocol.MaxAcceptQueueSize)
-		go func() {
-			defer p.buffer.Release()
-			if err := s.sendConnectionRefused(p.remoteAddr, hdr, p.info); err != nil {
-				s.logger.Debugf("Error rejecting connection: %s", err)
-			}
-		}()
+	if s.config.VerifyClient != nil && !s.config.VerifyClient(p.remoteAddr, clientAddrIsValid) {
+		s.logger.Debugf("Rejecting new connection from %s. Client verification failed.", p.remoteAddr)
+		s.refuseConnection(p, hdr, logging.PacketDropClientVerificationFailed)
+		return nil
+	}
+
+	if s.isDraining() {
+		s.logger.Debugf("Rejecting new connection. Server is shutting down.")
+		s.refuseConnection(p, hdr, logging.PacketDropServerShuttingDown)
+		return nil
+	}
+
+	if queueLen := atomic.LoadInt32(&s.connQueueLen); queueLen >= int32(s.config.MaxAcceptQueueSize) {
+		s.logger.Debugf("Rejecting new connection. Server currently busy. Accept queue length: %d (max %d)", queueLen, s.config.MaxAcceptQueueSize)
+		s.refuseConnection(p, hdr, logging.PacketDropAcceptQueueFull)
+		return nil
+	}
+
+	limitHandshakes := s.config.MaxHandshakesPerIP > 0
+	if limitHandshakes && !s.startHandshake(p.remoteAddr) {
+		s.logger.Debugf("Rejecting new connection from %s. Too many handshakes in progress (max %d).", p.remoteAddr, s.config.MaxHandshakesPerIP)
+		s.refuseConnection(p, hdr, logging.PacketDropDOSPrevention)
 		return nil
 	}
 
 	connID, err := s.config.ConnectionIDGenerator.GenerateConnectionID()
 	if err != nil {
+		if limitHandshakes {
+			s.finishHandshake(p.remoteAddr)
+		}
 		return err
 	}
 	s.logger.Debugf("Changing connection ID to %s.", connID)
@@ -494,8 +632,19 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 				connID,
 			)
 		}
+		disableGSO := s.config.DisableGSO || !supportsGSO(s.conn)
+		if tracer != nil {
+			// GSO is a property of the socket, so it's only traced for the first connection.
+			s.traceGSOOnce.Do(func() {
+				if disableGSO {
+					tracer.Debug("gso", "GSO disabled")
+				} else {
+					tracer.Debug("gso", "GSO enabled")
+				}
+			})
+		}
 		conn = s.newConn(
-			newSendConn(s.conn, p.remoteAddr, p.info),
+			newSendConn(s.conn, p.remoteAddr, p.info, disableGSO),
 			s.connHandler,
 			origDestConnID,
 			retrySrcConnID,
@@ -516,10 +665,37 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 		conn.handlePacket(p)
 		return conn
 	}); !added {
+		if limitHandshakes {
+			s.finishHandshake(p.remoteAddr)
+		}
 		return nil
 	}
-	go conn.run()
-	go s.handleNewConn(conn)
+	// Shutdown might have been called since we checked isDraining.
+	// Tracking the connection under the server mutex makes sure that Shutdown doesn't miss it.
+	tracked := s.addConn(conn)
+	go func() {
+		conn.run()
+		if tracked {
+			s.removeConn(conn)
+		}
+	}()
+	if !tracked {
+		// CloseWithError blocks until the CONNECTION_CLOSE was sent.
+		// Don't block the goroutine that handles incoming packets.
+		go conn.CloseWithError(0, "server shutdown")
+	} else {
+		go s.handleNewConn(conn)
+	}
+	if limitHandshakes {
+		go func() {
+			// wait until the handshake is complete (or fails)
+			select {
+			case <-conn.HandshakeComplete().Done():
+			case <-conn.Context().Done():
+			}
+			s.finishHandshake(p.remoteAddr)
+		}()
+	}
 	if conn == nil {
 		p.buffer.Release()
 		return nil
@@ -527,8 +703,44 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 	return nil
 }
 
+// startHandshake registers a new handshake from the IP address of addr.
+// It returns false if the limit of concurrent handshakes for this IP address is already reached.
+func (s *baseServer) startHandshake(addr net.Addr) bool {
+	ip := sourceIP(addr)
+	s.handshakesMutex.Lock()
+	defer s.handshakesMutex.Unlock()
+
+	if s.handshakesPerIP[ip] >= s.config.MaxHandshakesPerIP {
+		return false
+	}
+	if s.handshakesPerIP == nil {
+		s.handshakesPerIP = make(map[string]int)
+	}
+	s.handshakesPerIP[ip]++
+	return true
+}
+
+func (s *baseServer) finishHandshake(addr net.Addr) {
+	ip := sourceIP(addr)
+	s.handshakesMutex.Lock()
+	defer s.handshakesMutex.Unlock()
+
+	s.handshakesPerIP[ip]--
+	if s.handshakesPerIP[ip] <= 0 {
+		delete(s.handshakesPerIP, ip)
+	}
+}
+
+func sourceIP(addr net.Addr) string {
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		return udpAddr.IP.String()
+	}
+	return addr.String()
+}
+
 func (s *baseServer) handleNewConn(conn quicConn) {
 	connCtx := conn.Context()
+
 	if s.acceptEarlyConns {
 		// wait until the early connection is ready (or the handshake fails)
 		select {
@@ -549,6 +761,10 @@ func (s *baseServer) handleNewConn(conn quicConn) {
 	select {
 	case s.connQueue <- conn:
 		// blocks until the connection is accepted
+	case <-s.drainingChan:
+		atomic.AddInt32(&s.connQueueLen, -1)
+		// the server is shutting down, and won't accept this connection any more
+		conn.CloseWithError(0, "server shutdown")
 	case <-connCtx.Done():
 		atomic.AddInt32(&s.connQueueLen, -1)
 		// don't pass connections that were already closed to Accept()
@@ -623,6 +839,20 @@ func (s *baseServer) maybeSendInvalidToken(p *receivedPacket, hdr *wire.Header)
 	return s.sendError(p.remoteAddr, hdr, sealer, qerr.InvalidToken, p.info)
 }
 
+// refuseConnection refuses a new connection attempt by sending a CONNECTION_REFUSED error.
+// It takes ownership of the packet buffer.
+func (s *baseServer) refuseConnection(p *receivedPacket, hdr *wire.Header, reason logging.PacketDropReason) {
+	if s.config.Tracer != nil {
+		s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeInitial, p.Size(), reason)
+	}
+	go func() {
+		defer p.buffer.Release()
+		if err := s.sendConnectionRefused(p.remoteAddr, hdr, p.info); err != nil {
+			s.logger.Debugf("Error rejecting connection: %s", err)
+		}
+	}()
+}
+
 func (s *baseServer) sendConnectionRefused(remoteAddr net.Addr, hdr *wire.Header, info *packetInfo) error {
 	sealer, _ := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer, hdr.Version)
 	return s.sendError(remoteAddr, hdr, sealer, qerr.ConnectionRefused, info)
@@ -620,6 +620,102 @@ var _ = Describe("Server", func() {
 				Expect(createdConn).To(BeFalse())
 			})
 
+			It("rejects new connection attempts if the client verification fails", func() {
+				var verifiedAddr net.Addr
+				var verifiedWithToken bool
+				serv.config.VerifyClient = func(addr net.Addr, hasValidToken bool) bool {
+					verifiedAddr = addr
+					verifiedWithToken = hasValidToken
+					return false
+				}
+				p := getInitialWithRandomDestConnID()
+				hdr, _, _, err := wire.ParsePacket(p.data, 0)
+				Expect(err).ToNot(HaveOccurred())
+				tracer.EXPECT().DroppedPacket(p.remoteAddr, logging.PacketTypeInitial, p.Size(), logging.PacketDropClientVerificationFailed)
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				done := make(chan struct{})
+				conn.EXPECT().WriteTo(gomock.Any(), p.remoteAddr).DoAndReturn(func(b []byte, _ net.Addr) (int, error) {
+					defer close(done)
+					rejectHdr := parseHeader(b)
+					Expect(rejectHdr.Type).To(Equal(protocol.PacketTypeInitial))
+					Expect(rejectHdr.Version).To(Equal(hdr.Version))
+					Expect(rejectHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
+					Expect(rejectHdr.SrcConnectionID).To(Equal(hdr.DestConnectionID))
+					return len(b), nil
+				})
+				serv.handlePacket(p)
+				Eventually(done).Should(BeClosed())
+				Expect(verifiedAddr).To(Equal(p.remoteAddr))
+				Expect(verifiedWithToken).To(BeFalse())
+			})
+
+			It("limits the number of concurrent handshakes per source IP", func() {
+				serv.config.MaxHandshakesPerIP = 1
+				handshakeCtx, handshakeComplete := context.WithCancel(context.Background())
+				var counter int
+				serv.newConn = func(
+					_ sendConn,
+					runner connRunner,
+					_ protocol.ConnectionID,
+					_ *protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.StatelessResetToken,
+					_ *Config,
+					_ *tls.Config,
+					_ *handshake.TokenGenerator,
+					_ bool,
+					_ bool,
+					_ logging.ConnectionTracer,
+					_ uint64,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicConn {
+					counter++
+					conn := NewMockQuicConn(mockCtrl)
+					conn.EXPECT().handlePacket(gomock.Any())
+					conn.EXPECT().run().MaxTimes(1)
+					conn.EXPECT().Context().Return(context.Background()).AnyTimes()
+					conn.EXPECT().HandshakeComplete().Return(handshakeCtx).AnyTimes()
+					return conn
+				}
+				phm.EXPECT().AddWithConnID(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, _ protocol.ConnectionID, fn func() packetHandler) bool {
+					phm.EXPECT().GetStatelessResetToken(gomock.Any())
+					fn()
+					return true
+				}).Times(2)
+				tracer.EXPECT().TracerForConnection(gomock.Any(), protocol.PerspectiveServer, gomock.Any()).Times(2)
+
+				Expect(serv.handlePacketImpl(getInitialWithRandomDestConnID())).To(BeTrue())
+				Expect(counter).To(Equal(1))
+
+				// a second connection attempt from the same IP is rejected while the first handshake is in progress
+				p := getInitialWithRandomDestConnID()
+				tracer.EXPECT().DroppedPacket(p.remoteAddr, logging.PacketTypeInitial, p.Size(), logging.PacketDropDOSPrevention)
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				done := make(chan struct{})
+				conn.EXPECT().WriteTo(gomock.Any(), p.remoteAddr).DoAndReturn(func(b []byte, _ net.Addr) (int, error) {
+					defer close(done)
+					rejectHdr := parseHeader(b)
+					Expect(rejectHdr.Type).To(Equal(protocol.PacketTypeInitial))
+					return len(b), nil
+				})
+				Expect(serv.handlePacketImpl(p)).To(BeTrue())
+				Eventually(done).Should(BeClosed())
+				Expect(counter).To(Equal(1))
+
+				// once the handshake completes, new connection attempts are accepted again
+				handshakeComplete()
+				Eventually(func() int {
+					serv.handshakesMutex.Lock()
+					defer serv.handshakesMutex.Unlock()
+					return len(serv.handshakesPerIP)
+				}).Should(BeZero())
+				Expect(serv.handlePacketImpl(getInitialWithRandomDestConnID())).To(BeTrue())
+				Expect(counter).To(Equal(2))
+			})
+
 			It("rejects new connection attempts if the accept queue is full", func() {
 				serv.newConn = func(
 					_ sendConn,
@@ -672,6 +768,7 @@ var _ = Describe("Server", func() {
 				p := getInitialWithRandomDestConnID()
 				hdr, _, _, err := wire.ParsePacket(p.data, 0)
 				Expect(err).ToNot(HaveOccurred())
+				tracer.EXPECT().DroppedPacket(p.remoteAddr, logging.PacketTypeInitial, p.Size(), logging.PacketDropAcceptQueueFull)
 				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 				done := make(chan struct{})
 				conn.EXPECT().WriteTo(gomock.Any(), p.remoteAddr).DoAndReturn(func(b []byte, _ net.Addr) (int, error) {
@@ -687,6 +784,22 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("uses the configured accept queue size", func() {
+				serv.config.MaxAcceptQueueSize = 2
+				atomic.StoreInt32(&serv.connQueueLen, 2)
+				p := getInitialWithRandomDestConnID()
+				tracer.EXPECT().DroppedPacket(p.remoteAddr, logging.PacketTypeInitial, p.Size(), logging.PacketDropAcceptQueueFull)
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				done := make(chan struct{})
+				conn.EXPECT().WriteTo(gomock.Any(), p.remoteAddr).DoAndReturn(func(b []byte, _ net.Addr) (int, error) {
+					defer close(done)
+					Expect(parseHeader(b).Type).To(Equal(protocol.PacketTypeInitial))
+					return len(b), nil
+				})
+				Expect(serv.handlePacketImpl(p)).To(BeTrue())
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("doesn't accept new connections if they were closed in the mean time", func() {
 				p := getInitial(protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}))
 				ctx, cancel := context.WithCancel(context.Background())
@@ -1038,6 +1151,140 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 		})
+
+		Context("shutting down", func() {
+			// creates a new connection that completed the handshake, and accepts it
+			acceptConn := func(connCtx context.Context) *MockQuicConn {
+				conn := NewMockQuicConn(mockCtrl)
+				serv.newConn = func(
+					_ sendConn,
+					runner connRunner,
+					_ protocol.ConnectionID,
+					_ *protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.StatelessResetToken,
+					_ *Config,
+					_ *tls.Config,
+					_ *handshake.TokenGenerator,
+					_ bool,
+					_ bool,
+					_ logging.ConnectionTracer,
+					_ uint64,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicConn {
+					ctx, cancel := context.WithCancel(context.Background())
+					cancel()
+					conn.EXPECT().handlePacket(gomock.Any())
+					conn.EXPECT().HandshakeComplete().Return(ctx)
+					// run returns when the connection is closed
+					conn.EXPECT().run().Do(func() { <-connCtx.Done() })
+					conn.EXPECT().Context().Return(connCtx)
+					return conn
+				}
+				phm.EXPECT().AddWithConnID(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, _ protocol.ConnectionID, fn func() packetHandler) bool {
+					phm.EXPECT().GetStatelessResetToken(gomock.Any())
+					fn()
+					return true
+				})
+				tracer.EXPECT().TracerForConnection(gomock.Any(), protocol.PerspectiveServer, gomock.Any())
+				serv.handleInitialImpl(
+					&receivedPacket{buffer: getPacketBuffer()},
+					&wire.Header{DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8})},
+				)
+				c, err := serv.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(c).To(Equal(conn))
+				return conn
+			}
+
+			It("closes the server, if there are no active connections", func() {
+				phm.EXPECT().CloseServer()
+				Expect(serv.Shutdown(context.Background())).To(Succeed())
+				_, err := serv.Accept(context.Background())
+				Expect(err).To(MatchError(ErrServerClosed))
+			})
+
+			It("stops accepting connections, and waits for active connections to close", func() {
+				connCtx, connCancel := context.WithCancel(context.Background())
+				conn := acceptConn(connCtx)
+				conn.EXPECT().stopIssuingConnectionIDs()
+
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					Expect(serv.Shutdown(context.Background())).To(Succeed())
+				}()
+				Eventually(func() bool { return serv.isDraining() }).Should(BeTrue())
+				_, err := serv.Accept(context.Background())
+				Expect(err).To(MatchError(ErrServerClosed))
+				Consistently(done).ShouldNot(BeClosed())
+				phm.EXPECT().CloseServer()
+				connCancel()
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("stops issuing connection IDs on active connections", func() {
+				connCtx, connCancel := context.WithCancel(context.Background())
+				conn := acceptConn(connCtx)
+				stopped := make(chan struct{})
+				conn.EXPECT().stopIssuingConnectionIDs().Do(func() { close(stopped) })
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					Expect(serv.Shutdown(context.Background())).To(Succeed())
+				}()
+				Eventually(stopped).Should(BeClosed())
+				phm.EXPECT().CloseServer()
+				connCancel()
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("closes active connections when the context expires", func() {
+				connCtx, connCancel := context.WithCancel(context.Background())
+				conn := acceptConn(connCtx)
+				conn.EXPECT().stopIssuingConnectionIDs()
+				conn.EXPECT().CloseWithError(ApplicationErrorCode(0), "server shutdown").Do(func(ApplicationErrorCode, string) { connCancel() })
+				phm.EXPECT().CloseServer()
+				ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(50*time.Millisecond))
+				defer cancel()
+				Expect(serv.Shutdown(ctx)).To(MatchError(context.DeadlineExceeded))
+			})
+
+			It("rejects new connection attempts while shutting down", func() {
+				connCtx, connCancel := context.WithCancel(context.Background())
+				defer connCancel()
+				acceptConn(connCtx).EXPECT().stopIssuingConnectionIDs()
+				go func() {
+					defer GinkgoRecover()
+					serv.Shutdown(context.Background())
+				}()
+				Eventually(func() bool { return serv.isDraining() }).Should(BeTrue())
+
+				p := getInitialWithRandomDestConnID()
+				hdr, _, _, err := wire.ParsePacket(p.data, 0)
+				Expect(err).ToNot(HaveOccurred())
+				tracer.EXPECT().DroppedPacket(p.remoteAddr, logging.PacketTypeInitial, p.Size(), logging.PacketDropServerShuttingDown)
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				done := make(chan struct{})
+				conn.EXPECT().WriteTo(gomock.Any(), p.remoteAddr).DoAndReturn(func(b []byte, _ net.Addr) (int, error) {
+					defer close(done)
+					rejectHdr := parseHeader(b)
+					Expect(rejectHdr.Type).To(Equal(protocol.PacketTypeInitial))
+					Expect(rejectHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
+					Expect(rejectHdr.SrcConnectionID).To(Equal(hdr.DestConnectionID))
+					return len(b), nil
+				})
+				serv.handlePacket(p)
+				Eventually(done).Should(BeClosed())
+				phm.EXPECT().CloseServer()
+				connCancel()
+			})
+		})
 	})
 
 	Context("server accepting connections that haven't completed the handshake", func() {
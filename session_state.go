@@ -0,0 +1,23 @@
+package quic
+
+import (
+	"crypto/tls"
+
+	"github.com/fkwhite/quic-go/internal/qtls"
+)
+
+// MarshalClientSessionState serializes a session state that was stored in the tls.Config.ClientSessionCache
+// used for a QUIC connection.
+// Besides the TLS session ticket, the state contains the QUIC state needed to use 0-RTT when resuming the session:
+// the transport parameters of the server and the RTT of the connection.
+// Together with UnmarshalClientSessionState, this allows implementing a tls.ClientSessionCache
+// that persists session tickets across restarts.
+func MarshalClientSessionState(state *tls.ClientSessionState) ([]byte, error) {
+	return qtls.MarshalClientSessionState(state)
+}
+
+// UnmarshalClientSessionState restores a session state serialized by MarshalClientSessionState.
+// The state can be returned from the Get method of the tls.ClientSessionCache.
+func UnmarshalClientSessionState(data []byte) (*tls.ClientSessionState, error) {
+	return qtls.UnmarshalClientSessionState(data)
+}
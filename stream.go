@@ -10,6 +10,7 @@ import (
 	"github.com/fkwhite/quic-go/internal/flowcontrol"
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/wire"
+	"github.com/fkwhite/quic-go/logging"
 )
 
 type deadlineError struct{}
@@ -24,6 +25,8 @@ var errDeadline net.Error = &deadlineError{}
 // The streamSender is notified by the stream about various events.
 type streamSender interface {
 	queueControlFrame(wire.Frame)
+	// supportsResetStreamAt says if the peer supports the RESET_STREAM_AT frame
+	supportsResetStreamAt() bool
 	onHasStreamData(protocol.StreamID)
 	// must be called without holding the mutex that is acquired by closeForShutdown
 	onStreamCompleted(protocol.StreamID)
@@ -56,12 +59,16 @@ type streamI interface {
 	// for receiving
 	handleStreamFrame(*wire.StreamFrame) error
 	handleResetStreamFrame(*wire.ResetStreamFrame) error
+	handleResetStreamAtFrame(*wire.ResetStreamAtFrame) error
 	getWindowUpdate() protocol.ByteCount
 	// for sending
 	hasData() bool
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
 	updateSendWindow(protocol.ByteCount)
+	// for tracing
+	sendCloseState() *logging.StreamCloseState
+	receiveCloseState() *logging.StreamCloseState
 }
 
 var (
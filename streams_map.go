@@ -4,13 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net"
 	"sync"
 
 	"github.com/fkwhite/quic-go/internal/flowcontrol"
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/qerr"
 	"github.com/fkwhite/quic-go/internal/wire"
+	"github.com/fkwhite/quic-go/logging"
 )
 
 type streamError struct {
@@ -34,13 +34,6 @@ func convertStreamError(err error, stype protocol.StreamType, pers protocol.Pers
 	return fmt.Errorf(strError.Error(), ids...)
 }
 
-type streamOpenErr struct{ error }
-
-var _ net.Error = &streamOpenErr{}
-
-func (e streamOpenErr) Temporary() bool { return e.error == errTooManyOpenStreams }
-func (streamOpenErr) Timeout() bool     { return false }
-
 // errTooManyOpenStreams is used internally by the outgoing streams maps.
 var errTooManyOpenStreams = errors.New("too many open streams")
 
@@ -53,6 +46,7 @@ type streamsMap struct {
 
 	sender            streamSender
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController
+	tracer            logging.ConnectionTracer
 
 	mutex               sync.Mutex
 	outgoingBidiStreams *outgoingStreamsMap[streamI]
@@ -70,6 +64,7 @@ func newStreamsMap(
 	maxIncomingBidiStreams uint64,
 	maxIncomingUniStreams uint64,
 	perspective protocol.Perspective,
+	tracer logging.ConnectionTracer,
 	version protocol.VersionNumber,
 ) streamManager {
 	m := &streamsMap{
@@ -78,6 +73,7 @@ func newStreamsMap(
 		maxIncomingBidiStreams: maxIncomingBidiStreams,
 		maxIncomingUniStreams:  maxIncomingUniStreams,
 		sender:                 sender,
+		tracer:                 tracer,
 		version:                version,
 	}
 	m.initMaps()
@@ -89,6 +85,7 @@ func (m *streamsMap) initMaps() {
 		protocol.StreamTypeBidi,
 		func(num protocol.StreamNum) streamI {
 			id := num.StreamID(protocol.StreamTypeBidi, m.perspective)
+			m.traceOpenedStream(id)
 			return newStream(id, m.sender, m.newFlowController(id), m.version)
 		},
 		m.sender.queueControlFrame,
@@ -97,6 +94,7 @@ func (m *streamsMap) initMaps() {
 		protocol.StreamTypeBidi,
 		func(num protocol.StreamNum) streamI {
 			id := num.StreamID(protocol.StreamTypeBidi, m.perspective.Opposite())
+			m.traceOpenedStream(id)
 			return newStream(id, m.sender, m.newFlowController(id), m.version)
 		},
 		m.maxIncomingBidiStreams,
@@ -106,6 +104,7 @@ func (m *streamsMap) initMaps() {
 		protocol.StreamTypeUni,
 		func(num protocol.StreamNum) sendStreamI {
 			id := num.StreamID(protocol.StreamTypeUni, m.perspective)
+			m.traceOpenedStream(id)
 			return newSendStream(id, m.sender, m.newFlowController(id), m.version)
 		},
 		m.sender.queueControlFrame,
@@ -114,6 +113,7 @@ func (m *streamsMap) initMaps() {
 		protocol.StreamTypeUni,
 		func(num protocol.StreamNum) receiveStreamI {
 			id := num.StreamID(protocol.StreamTypeUni, m.perspective.Opposite())
+			m.traceOpenedStream(id)
 			return newReceiveStream(id, m.sender, m.newFlowController(id), m.version)
 		},
 		m.maxIncomingUniStreams,
@@ -193,7 +193,57 @@ func (m *streamsMap) AcceptUniStream(ctx context.Context) (ReceiveStream, error)
 	return str, convertStreamError(err, protocol.StreamTypeUni, m.perspective.Opposite())
 }
 
+func (m *streamsMap) traceOpenedStream(id protocol.StreamID) {
+	if m.tracer != nil {
+		m.tracer.OpenedStream(id)
+	}
+}
+
 func (m *streamsMap) DeleteStream(id protocol.StreamID) error {
+	var send, receive *logging.StreamCloseState
+	if m.tracer != nil {
+		send, receive = m.getCloseState(id)
+	}
+	if err := m.deleteStream(id); err != nil {
+		return err
+	}
+	if m.tracer != nil {
+		m.tracer.ClosedStream(id, send, receive)
+	}
+	return nil
+}
+
+// getCloseState gets the final sizes and error codes of the two directions of a stream.
+// For unidirectional streams, the direction that doesn't exist is nil.
+func (m *streamsMap) getCloseState(id protocol.StreamID) (send, receive *logging.StreamCloseState) {
+	num := id.StreamNum()
+	switch id.Type() {
+	case protocol.StreamTypeUni:
+		if id.InitiatedBy() == m.perspective {
+			if str, err := m.outgoingUniStreams.GetStream(num); err == nil && str != nil {
+				send = str.sendCloseState()
+			}
+			return send, nil
+		}
+		if str, ok := m.incomingUniStreams.GetStream(num); ok {
+			receive = str.receiveCloseState()
+		}
+		return nil, receive
+	case protocol.StreamTypeBidi:
+		var str streamI
+		if id.InitiatedBy() == m.perspective {
+			str, _ = m.outgoingBidiStreams.GetStream(num)
+		} else {
+			str, _ = m.incomingBidiStreams.GetStream(num)
+		}
+		if str != nil {
+			return str.sendCloseState(), str.receiveCloseState()
+		}
+	}
+	return nil, nil
+}
+
+func (m *streamsMap) deleteStream(id protocol.StreamID) error {
 	num := id.StreamNum()
 	switch id.Type() {
 	case protocol.StreamTypeUni:
@@ -294,6 +344,27 @@ func (m *streamsMap) UpdateLimits(p *wire.TransportParameters) {
 	m.outgoingUniStreams.SetMaxStream(p.MaxUniStreamNum)
 }
 
+// ResetAllStreams cancels reading and writing on all open streams.
+// The streams are collected first, since canceling a stream might complete it, which deletes it from the maps.
+func (m *streamsMap) ResetAllStreams(code StreamErrorCode) {
+	m.mutex.Lock()
+	bidiStreams := append(m.outgoingBidiStreams.Streams(), m.incomingBidiStreams.Streams()...)
+	sendStreams := m.outgoingUniStreams.Streams()
+	receiveStreams := m.incomingUniStreams.Streams()
+	m.mutex.Unlock()
+
+	for _, str := range bidiStreams {
+		str.CancelRead(code)
+		str.CancelWrite(code)
+	}
+	for _, str := range sendStreams {
+		str.CancelWrite(code)
+	}
+	for _, str := range receiveStreams {
+		str.CancelRead(code)
+	}
+}
+
 func (m *streamsMap) CloseWithError(err error) {
 	m.outgoingBidiStreams.CloseWithError(err)
 	m.outgoingUniStreams.CloseWithError(err)
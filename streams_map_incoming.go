@@ -138,6 +138,28 @@ func (m *incomingStreamsMap[T]) GetOrOpenStream(num protocol.StreamNum) (T, erro
 	return entry.stream, nil
 }
 
+// GetStream returns a stream that was already opened.
+// Contrary to GetOrOpenStream, it never opens new streams.
+func (m *incomingStreamsMap[T]) GetStream(num protocol.StreamNum) (T, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	entry, ok := m.streams[num]
+	return entry.stream, ok
+}
+
+// Streams returns all streams that haven't been deleted yet, including streams that were not yet accepted.
+func (m *incomingStreamsMap[T]) Streams() []T {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	streams := make([]T, 0, len(m.streams))
+	for _, entry := range m.streams {
+		streams = append(streams, entry.stream)
+	}
+	return streams
+}
+
 func (m *incomingStreamsMap[T]) DeleteStream(num protocol.StreamNum) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
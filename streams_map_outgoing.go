@@ -60,7 +60,7 @@ func (m *outgoingStreamsMap[T]) OpenStream() (T, error) {
 	// if there are OpenStreamSync calls waiting, return an error here
 	if len(m.openQueue) > 0 || m.nextStream > m.maxStream {
 		m.maybeSendBlockedFrame()
-		return *new(T), streamOpenErr{errTooManyOpenStreams}
+		return *new(T), m.streamLimitReachedError()
 	}
 	return m.openStream(), nil
 }
@@ -116,6 +116,17 @@ func (m *outgoingStreamsMap[T]) OpenStreamSync(ctx context.Context) (T, error) {
 	}
 }
 
+func (m *outgoingStreamsMap[T]) streamLimitReachedError() error {
+	var maxStreams uint64
+	if m.maxStream != protocol.InvalidStreamNum {
+		maxStreams = uint64(m.maxStream)
+	}
+	return &StreamLimitReachedError{
+		Unidirectional: m.streamType == protocol.StreamTypeUni,
+		MaxStreams:     maxStreams,
+	}
+}
+
 func (m *outgoingStreamsMap[T]) openStream() T {
 	s := m.newStream(m.nextStream)
 	m.streams[m.nextStream] = s
@@ -155,6 +166,18 @@ func (m *outgoingStreamsMap[T]) GetStream(num protocol.StreamNum) (T, error) {
 	return s, nil
 }
 
+// Streams returns all streams that haven't been deleted yet.
+func (m *outgoingStreamsMap[T]) Streams() []T {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	streams := make([]T, 0, len(m.streams))
+	for _, str := range m.streams {
+		streams = append(streams, str)
+	}
+	return streams
+}
+
 func (m *outgoingStreamsMap[T]) DeleteStream(num protocol.StreamNum) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -363,6 +363,22 @@ var _ = Describe("Streams Map (outgoing)", func() {
 			Expect(err.Error()).To(Equal(errTooManyOpenStreams.Error()))
 		})
 
+		It("says that the stream limit was reached", func() {
+			m.SetMaxStream(2)
+			for i := 0; i < 2; i++ {
+				_, err := m.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+			}
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			_, err := m.OpenStream()
+			var limitErr *StreamLimitReachedError
+			Expect(errors.As(err, &limitErr)).To(BeTrue())
+			Expect(limitErr.MaxStreams).To(BeEquivalentTo(2))
+			Expect(limitErr.Unidirectional).To(BeFalse())
+			Expect(limitErr.Temporary()).To(BeTrue())
+			Expect(limitErr.Timeout()).To(BeFalse())
+		})
+
 		It("only sends one STREAMS_BLOCKED frame for one stream ID", func() {
 			m.SetMaxStream(1)
 			mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
@@ -10,6 +10,7 @@ import (
 
 	"github.com/fkwhite/quic-go/internal/flowcontrol"
 	"github.com/fkwhite/quic-go/internal/mocks"
+	mocklogging "github.com/fkwhite/quic-go/internal/mocks/logging"
 	"github.com/fkwhite/quic-go/internal/protocol"
 	"github.com/fkwhite/quic-go/internal/qerr"
 	"github.com/fkwhite/quic-go/internal/wire"
@@ -88,7 +89,7 @@ var _ = Describe("Streams Map", func() {
 
 			BeforeEach(func() {
 				mockSender = NewMockStreamSender(mockCtrl)
-				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, perspective, protocol.VersionWhatever).(*streamsMap)
+				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, perspective, nil, protocol.VersionWhatever).(*streamsMap)
 			})
 
 			Context("opening", func() {
@@ -137,6 +138,49 @@ var _ = Describe("Streams Map", func() {
 				})
 			})
 
+			Context("tracing", func() {
+				var tracer *mocklogging.MockConnectionTracer
+
+				BeforeEach(func() {
+					tracer = mocklogging.NewMockConnectionTracer(mockCtrl)
+					m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, perspective, tracer, protocol.VersionWhatever).(*streamsMap)
+					mockSender.EXPECT().queueControlFrame(gomock.Any()).AnyTimes()
+					allowUnlimitedStreams()
+				})
+
+				It("traces opening and closing of outgoing streams", func() {
+					tracer.EXPECT().OpenedStream(ids.firstOutgoingBidiStream)
+					_, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					tracer.EXPECT().OpenedStream(ids.firstOutgoingUniStream)
+					_, err = m.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					tracer.EXPECT().ClosedStream(ids.firstOutgoingBidiStream, gomock.Not(gomock.Nil()), gomock.Not(gomock.Nil()))
+					Expect(m.DeleteStream(ids.firstOutgoingBidiStream)).To(Succeed())
+					tracer.EXPECT().ClosedStream(ids.firstOutgoingUniStream, gomock.Not(gomock.Nil()), gomock.Nil())
+					Expect(m.DeleteStream(ids.firstOutgoingUniStream)).To(Succeed())
+				})
+
+				It("traces opening and closing of incoming streams", func() {
+					// opening a stream implicitly opens all streams with lower stream numbers
+					tracer.EXPECT().OpenedStream(ids.firstIncomingBidiStream)
+					tracer.EXPECT().OpenedStream(ids.firstIncomingBidiStream + 4)
+					_, err := m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream + 4)
+					Expect(err).ToNot(HaveOccurred())
+					tracer.EXPECT().OpenedStream(ids.firstIncomingUniStream)
+					_, err = m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+					Expect(err).ToNot(HaveOccurred())
+					tracer.EXPECT().ClosedStream(ids.firstIncomingBidiStream, gomock.Not(gomock.Nil()), gomock.Not(gomock.Nil()))
+					Expect(m.DeleteStream(ids.firstIncomingBidiStream)).To(Succeed())
+					tracer.EXPECT().ClosedStream(ids.firstIncomingUniStream, gomock.Nil(), gomock.Not(gomock.Nil()))
+					Expect(m.DeleteStream(ids.firstIncomingUniStream)).To(Succeed())
+				})
+
+				It("doesn't trace the closing of unknown streams", func() {
+					Expect(m.DeleteStream(ids.firstOutgoingBidiStream)).ToNot(Succeed())
+				})
+			})
+
 			Context("deleting", func() {
 				BeforeEach(func() {
 					mockSender.EXPECT().queueControlFrame(gomock.Any()).AnyTimes()
@@ -456,6 +500,40 @@ var _ = Describe("Streams Map", func() {
 				})
 			})
 
+			It("resets all streams", func() {
+				var stopSending, resetStream []protocol.StreamID
+				mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+					switch f := f.(type) {
+					case *wire.StopSendingFrame:
+						Expect(f.ErrorCode).To(BeEquivalentTo(1337))
+						stopSending = append(stopSending, f.StreamID)
+					case *wire.ResetStreamFrame:
+						Expect(f.ErrorCode).To(BeEquivalentTo(1337))
+						resetStream = append(resetStream, f.StreamID)
+					}
+				}).AnyTimes()
+				mockSender.EXPECT().onStreamCompleted(gomock.Any()).AnyTimes()
+				allowUnlimitedStreams()
+				_, err := m.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+				Expect(err).ToNot(HaveOccurred())
+
+				m.ResetAllStreams(1337)
+				Expect(stopSending).To(ConsistOf(ids.firstOutgoingBidiStream, ids.firstIncomingBidiStream, ids.firstIncomingUniStream))
+				Expect(resetStream).To(ConsistOf(ids.firstOutgoingBidiStream, ids.firstIncomingBidiStream, ids.firstOutgoingUniStream))
+				// resetting streams that were already reset is a no-op
+				stopSending = nil
+				resetStream = nil
+				m.ResetAllStreams(42)
+				Expect(stopSending).To(BeEmpty())
+				Expect(resetStream).To(BeEmpty())
+			})
+
 			It("closes", func() {
 				testErr := errors.New("test error")
 				m.CloseWithError(testErr)
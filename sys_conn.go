@@ -12,6 +12,23 @@ import (
 // OOBCapablePacketConn is a connection that allows the reading of ECN bits from the IP header.
 // If the PacketConn passed to Dial or Listen satisfies this interface, quic-go will use it.
 // In this case, ReadMsgUDP() will be used instead of ReadFrom() to read packets.
+//
+// Besides *net.UDPConn, users can provide their own implementation, e.g. a wrapper around a UDP socket.
+// SyscallConn is only used to set socket options (and to read TX timestamps), packets are read and
+// written using ReadMsgUDP and WriteMsgUDP. If the implementation also has a
+// ReadBatch(ms []ipv4.Message, flags int) (int, error) or a WriteBatch(ms []ipv4.Message, flags int) (int, error)
+// method (as a golang.org/x/net/ipv4.PacketConn does), these are used to read or write multiple packets at once.
+// Otherwise, packets are read and written one by one, and sending in batches (sendmmsg, Linux only)
+// is only used for *net.UDPConns.
+//
+// When a PacketConn that doesn't satisfy this interface is used, the following features are not available:
+//   - reading of ECN bits
+//   - determining the local address a packet was received on (packet info), which is required
+//     to reply from the correct address when listening on an unspecified address (e.g. 0.0.0.0)
+//   - reading and writing of multiple packets with a single syscall, and UDP GRO and GSO
+//   - kernel TX timestamps
+//
+// Setting of the DF bit (required for Path MTU Discovery) only requires the SyscallConn method.
 type OOBCapablePacketConn interface {
 	net.PacketConn
 	SyscallConn() (syscall.RawConn, error)
@@ -54,15 +71,23 @@ func wrapConn(pc net.PacketConn) (rawConn, error) {
 // * when the OS doesn't support OOB.
 type basicConn struct {
 	net.PacketConn
+	// set when the application configured packets larger than protocol.MaxPacketBufferSize
+	largeBuffers utils.AtomicBool
 }
 
 var _ rawConn = &basicConn{}
 
 func (c *basicConn) ReadPacket() (*receivedPacket, error) {
-	buffer := getPacketBuffer()
-	// The packet size should not exceed protocol.MaxPacketBufferSize bytes
-	// If it does, we only read a truncated packet, which will then end up undecryptable
-	buffer.Data = buffer.Data[:protocol.MaxPacketBufferSize]
+	var buffer *packetBuffer
+	if c.largeBuffers.Get() {
+		buffer = getLargePacketBuffer()
+		buffer.Data = buffer.Data[:protocol.MaxLargePacketBufferSize]
+	} else {
+		buffer = getPacketBuffer()
+		// The packet size should not exceed protocol.MaxPacketBufferSize bytes
+		// If it does, we only read a truncated packet, which will then end up undecryptable
+		buffer.Data = buffer.Data[:protocol.MaxPacketBufferSize]
+	}
 	n, addr, err := c.PacketConn.ReadFrom(buffer.Data)
 	if err != nil {
 		return nil, err
@@ -75,6 +100,10 @@ func (c *basicConn) ReadPacket() (*receivedPacket, error) {
 	}, nil
 }
 
+func (c *basicConn) UseLargeBuffers() {
+	c.largeBuffers.Set(true)
+}
+
 func (c *basicConn) WritePacket(b []byte, addr net.Addr, _ []byte) (n int, err error) {
 	return c.PacketConn.WriteTo(b, addr)
 }
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package quic
+
+import (
+	"errors"
+	"net"
+)
+
+func connectUDP(*net.UDPConn, *net.UDPAddr) error {
+	return errors.New("connecting UDP sockets is not supported on this platform")
+}
+
+func disconnectUDP(*net.UDPConn) error {
+	// no-op, since the socket is never connected on unsupported platforms
+	return nil
+}
+
+func isConnRefusedErr(error) bool {
+	// connected UDP sockets are not used on unsupported platforms
+	return false
+}
@@ -0,0 +1,109 @@
+//go:build linux
+
+package quic
+
+import (
+	"errors"
+	"net"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// connectUDP connects a UDP socket to addr (see connect(2)).
+// The kernel then only delivers packets received from addr, and packets can be sent without
+// specifying the remote address, which saves a route lookup for every packet sent.
+func connectUDP(c *net.UDPConn, addr *net.UDPAddr) error {
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	if err := rawConn.Control(func(fd uintptr) {
+		var sa unix.Sockaddr
+		sa, serr = toSockaddr(int(fd), addr)
+		if serr != nil {
+			return
+		}
+		serr = unix.Connect(int(fd), sa)
+	}); err != nil {
+		return err
+	}
+	return serr
+}
+
+// disconnectUDP dissolves the association created by connectUDP,
+// such that packets can be sent to and received from any address again.
+func disconnectUDP(c *net.UDPConn) error {
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	if err := rawConn.Control(func(fd uintptr) {
+		local, err := unix.Getsockname(int(fd))
+		if err != nil {
+			serr = err
+			return
+		}
+		// Connecting to an AF_UNSPEC address disconnects the socket.
+		unspec := unix.RawSockaddr{Family: unix.AF_UNSPEC}
+		if _, _, errno := unix.Syscall(unix.SYS_CONNECT, fd, uintptr(unsafe.Pointer(&unspec)), unsafe.Sizeof(unspec)); errno != 0 {
+			serr = errno
+			return
+		}
+		// If the socket was bound to the unspecified address and port 0, the kernel also resets the local port.
+		// Bind to the previous port again, such that the peer doesn't see a change of our address.
+		after, err := unix.Getsockname(int(fd))
+		if err != nil {
+			serr = err
+			return
+		}
+		switch sa := local.(type) {
+		case *unix.SockaddrInet4:
+			if after.(*unix.SockaddrInet4).Port == 0 {
+				serr = unix.Bind(int(fd), &unix.SockaddrInet4{Port: sa.Port})
+			}
+		case *unix.SockaddrInet6:
+			if after.(*unix.SockaddrInet6).Port == 0 {
+				serr = unix.Bind(int(fd), &unix.SockaddrInet6{Port: sa.Port})
+			}
+		}
+	}); err != nil {
+		return err
+	}
+	return serr
+}
+
+// isConnRefusedErr says if err was caused by an ICMP port unreachable message.
+// The kernel only reports these on connected UDP sockets, and the packet read or written is simply lost.
+func isConnRefusedErr(err error) bool {
+	return errors.Is(err, unix.ECONNREFUSED)
+}
+
+// toSockaddr converts addr to a unix.Sockaddr of the address family of the socket.
+func toSockaddr(fd int, addr *net.UDPAddr) (unix.Sockaddr, error) {
+	local, err := unix.Getsockname(fd)
+	if err != nil {
+		return nil, err
+	}
+	switch local.(type) {
+	case *unix.SockaddrInet4:
+		ip := addr.IP.To4()
+		if ip == nil {
+			return nil, errors.New("cannot connect an IPv4 socket to an IPv6 address")
+		}
+		sa := &unix.SockaddrInet4{Port: addr.Port}
+		copy(sa.Addr[:], ip)
+		return sa, nil
+	case *unix.SockaddrInet6:
+		if addr.Zone != "" {
+			return nil, errors.New("connecting to an IPv6 address with a zone is not supported")
+		}
+		sa := &unix.SockaddrInet6{Port: addr.Port}
+		copy(sa.Addr[:], addr.IP.To16())
+		return sa, nil
+	default:
+		return nil, errors.New("unsupported address family")
+	}
+}
@@ -0,0 +1,184 @@
+//go:build linux
+
+package quic
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connected UDP sockets", func() {
+	var server1, server2, client *net.UDPConn
+
+	listen := func() *net.UDPConn {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		return conn
+	}
+
+	BeforeEach(func() {
+		server1 = listen()
+		server2 = listen()
+		var err error
+		client, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server1.Close()
+		server2.Close()
+		client.Close()
+	})
+
+	clientAddr := func() *net.UDPAddr {
+		return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: client.LocalAddr().(*net.UDPAddr).Port}
+	}
+
+	receive := func(conn *net.UDPConn) ([]byte, *net.UDPAddr, error) {
+		b := make([]byte, 100)
+		conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, addr, err := conn.ReadFromUDP(b)
+		return b[:n], addr, err
+	}
+
+	It("only receives packets from the address it is connected to", func() {
+		Expect(connectUDP(client, server1.LocalAddr().(*net.UDPAddr))).To(Succeed())
+		_, err := client.Write([]byte("foo"))
+		Expect(err).ToNot(HaveOccurred())
+		data, addr, err := receive(server1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foo")))
+		Expect(addr.Port).To(Equal(clientAddr().Port))
+
+		_, err = server2.WriteTo([]byte("bar"), clientAddr())
+		Expect(err).ToNot(HaveOccurred())
+		_, err = server1.WriteTo([]byte("baz"), clientAddr())
+		Expect(err).ToNot(HaveOccurred())
+		data, addr, err = receive(client)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("baz")))
+		Expect(addr.Port).To(Equal(server1.LocalAddr().(*net.UDPAddr).Port))
+	})
+
+	It("keeps the local port when disconnecting", func() {
+		port := client.LocalAddr().(*net.UDPAddr).Port
+		Expect(connectUDP(client, server1.LocalAddr().(*net.UDPAddr))).To(Succeed())
+		Expect(disconnectUDP(client)).To(Succeed())
+		_, err := client.WriteTo([]byte("foo"), server2.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		data, addr, err := receive(server2)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foo")))
+		Expect(addr.Port).To(Equal(port))
+		// packets from any address are received again
+		_, err = server2.WriteTo([]byte("bar"), clientAddr())
+		Expect(err).ToNot(HaveOccurred())
+		data, _, err = receive(client)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("bar")))
+	})
+
+	Context("sending", func() {
+		It("sends using the connected socket", func() {
+			c, err := newConnectedPconn(client, server1.LocalAddr().(*net.UDPAddr))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Write([]byte("foo"))).To(Succeed())
+			n, err := c.WriteBatch([][]byte{[]byte("bar"), []byte("baz")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(2))
+			for _, expected := range []string{"foo", "bar", "baz"} {
+				data, _, err := receive(server1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(data)).To(Equal(expected))
+			}
+		})
+
+		It("disconnects before sending to a different address", func() {
+			c, err := newConnectedPconn(client, server1.LocalAddr().(*net.UDPAddr))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.WritePacketTo([]byte("foo"), server2.LocalAddr())).To(Succeed())
+			data, _, err := receive(server2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foo")))
+			// the response from the other address is received
+			_, err = server2.WriteTo([]byte("bar"), clientAddr())
+			Expect(err).ToNot(HaveOccurred())
+			data, _, err = receive(client)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("bar")))
+			// packets to the original address are still sent
+			Expect(c.Write([]byte("baz"))).To(Succeed())
+			data, _, err = receive(server1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("baz")))
+		})
+
+		It("disconnects when the remote address changes", func() {
+			c, err := newConnectedPconn(client, server1.LocalAddr().(*net.UDPAddr))
+			Expect(err).ToNot(HaveOccurred())
+			c.SetRemoteAddr(server2.LocalAddr())
+			Expect(c.Write([]byte("foo"))).To(Succeed())
+			data, _, err := receive(server2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foo")))
+		})
+
+		It("ignores ICMP errors for packets sent to a closed port", func() {
+			c, err := newConnectedPconn(client, server1.LocalAddr().(*net.UDPAddr))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(server1.Close()).To(Succeed())
+			Expect(c.Write([]byte("foo"))).To(Succeed())
+			// the ICMP port unreachable message is reported on the next syscall
+			_, _, err = receive(client)
+			Expect(isConnRefusedErr(err)).To(BeTrue())
+			Expect(c.Write([]byte("bar"))).To(Succeed())
+			time.Sleep(10 * time.Millisecond)
+			Expect(c.Write([]byte("baz"))).To(Succeed())
+		})
+	})
+})
+
+func benchmarkWriteUDP(b *testing.B, connected bool) {
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer server.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			if _, _, err := server.ReadFrom(buf); err != nil {
+				return
+			}
+		}
+	}()
+	client, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer client.Close()
+
+	remoteAddr := server.LocalAddr().(*net.UDPAddr)
+	c := newSendPconn(client, remoteAddr)
+	if connected {
+		c, err = newConnectedPconn(client, remoteAddr)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	packet := make([]byte, 1200)
+	b.SetBytes(int64(len(packet)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := c.Write(packet); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteUDPUnconnected(b *testing.B) { benchmarkWriteUDP(b, false) }
+func BenchmarkWriteUDPConnected(b *testing.B)   { benchmarkWriteUDP(b, true) }
@@ -2,7 +2,14 @@
 
 package quic
 
-import "golang.org/x/sys/unix"
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+)
 
 const msgTypeIPTOS = unix.IP_RECVTOS
 
@@ -19,3 +26,24 @@ const (
 // ReadBatch only returns a single packet on OSX,
 // see https://godoc.org/golang.org/x/net/ipv4#PacketConn.ReadBatch.
 const batchSize = 1
+
+// WriteBatch would send the packets one by one, there's no sendmmsg on OSX.
+const batchWriteSupported = false
+
+// UDP GRO and GSO are Linux-only features.
+func enableGRO(int) error { return errors.New("GRO not supported on OSX") }
+
+func parseGROSegmentSize(unix.SocketControlMessage) (protocol.ByteCount, bool) { return 0, false }
+
+func isGSOSupported(int) bool { return false }
+
+func appendUDPSegmentSizeMsg(b []byte, _ uint16) []byte { return b }
+
+// Kernel TX timestamps are only supported on Linux.
+func enableTxTimestamps(int) error { return errors.New("TX timestamps not supported on OSX") }
+
+func appendTxTimestampMsg(b []byte) []byte { return b }
+
+func readTxTimestamp(int, []byte) (uint32, time.Time, bool, error) {
+	return 0, time.Time{}, false, errors.New("not supported")
+}
@@ -2,7 +2,15 @@
 
 package quic
 
-import "golang.org/x/sys/unix"
+import (
+	"encoding/binary"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+)
 
 const msgTypeIPTOS = unix.IP_TOS
 
@@ -17,3 +25,110 @@ const (
 )
 
 const batchSize = 8 // needs to smaller than MaxUint8 (otherwise the type of oobConn.readPos has to be changed)
+
+// sendmmsg is available on Linux, allowing us to send multiple packets with a single syscall
+const batchWriteSupported = true
+
+// UDP_SEGMENT and UDP_GRO, as defined in linux/udp.h.
+// They are not (yet) exported by golang.org/x/sys/unix.
+const (
+	udpSegment = 103
+	udpGRO     = 104
+)
+
+// enableGRO enables UDP Generic Receive Offload (available since Linux 5.0).
+// The kernel then coalesces multiple datagrams received from the same peer into a single buffer.
+func enableGRO(fd int) error {
+	return unix.SetsockoptInt(fd, unix.IPPROTO_UDP, udpGRO, 1)
+}
+
+// parseGROSegmentSize parses the segment size from a UDP_GRO control message.
+func parseGROSegmentSize(ctrlMsg unix.SocketControlMessage) (protocol.ByteCount, bool) {
+	if ctrlMsg.Header.Level != unix.IPPROTO_UDP || ctrlMsg.Header.Type != udpGRO || len(ctrlMsg.Data) < 4 {
+		return 0, false
+	}
+	// the segment size is passed as an int
+	return protocol.ByteCount(binary.LittleEndian.Uint32(ctrlMsg.Data)), true
+}
+
+// isGSOSupported checks if the kernel supports UDP Generic Segmentation Offload (available since Linux 4.18).
+func isGSOSupported(fd int) bool {
+	_, err := unix.GetsockoptInt(fd, unix.IPPROTO_UDP, udpSegment)
+	return err == nil
+}
+
+// appendUDPSegmentSizeMsg appends a UDP_SEGMENT control message, telling the kernel to split
+// the payload into datagrams of the given size.
+func appendUDPSegmentSizeMsg(b []byte, size uint16) []byte {
+	startLen := len(b)
+	const dataLen = 2 // payload is a uint16
+	b = append(b, make([]byte, unix.CmsgSpace(dataLen))...)
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&b[startLen]))
+	h.Level = unix.IPPROTO_UDP
+	h.Type = udpSegment
+	h.SetLen(unix.CmsgLen(dataLen))
+	*(*uint16)(unsafe.Pointer(&b[startLen+unix.CmsgSpace(0)])) = size
+	return b
+}
+
+// enableTxTimestamps enables reporting of software TX timestamps on the socket's error queue.
+// Timestamps are only generated for packets sent with the control message created by appendTxTimestampMsg.
+// Every timestamped packet is assigned an ID, counting up from 0, which is reported alongside the timestamp.
+func enableTxTimestamps(fd int) error {
+	return unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_TIMESTAMPING,
+		unix.SOF_TIMESTAMPING_SOFTWARE|unix.SOF_TIMESTAMPING_OPT_TSONLY|unix.SOF_TIMESTAMPING_OPT_ID)
+}
+
+// appendTxTimestampMsg appends a SO_TIMESTAMPING control message, requesting a software TX timestamp for this packet.
+func appendTxTimestampMsg(b []byte) []byte {
+	startLen := len(b)
+	const dataLen = 4 // payload is a uint32
+	b = append(b, make([]byte, unix.CmsgSpace(dataLen))...)
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&b[startLen]))
+	h.Level = unix.SOL_SOCKET
+	h.Type = unix.SO_TIMESTAMPING
+	h.SetLen(unix.CmsgLen(dataLen))
+	*(*uint32)(unsafe.Pointer(&b[startLen+unix.CmsgSpace(0)])) = unix.SOF_TIMESTAMPING_TX_SOFTWARE
+	return b
+}
+
+// readTxTimestamp reads a message from the socket's error queue, without blocking.
+// It returns the ID of the packet and its TX timestamp.
+// ok is false if the message didn't contain a TX timestamp.
+// It returns an error if the error queue is empty.
+func readTxTimestamp(fd int, oob []byte) (id uint32, t time.Time, ok bool, err error) {
+	_, oobn, _, _, err := unix.Recvmsg(fd, nil, oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+	if err != nil {
+		return 0, time.Time{}, false, err
+	}
+	ctrlMsgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return 0, time.Time{}, false, nil
+	}
+	var hasID, hasTimestamp bool
+	for _, ctrlMsg := range ctrlMsgs {
+		switch {
+		case ctrlMsg.Header.Level == unix.SOL_SOCKET && ctrlMsg.Header.Type == unix.SCM_TIMESTAMPING:
+			if len(ctrlMsg.Data) < int(unsafe.Sizeof(unix.ScmTimestamping{})) {
+				continue
+			}
+			// the software timestamp is the first of the three timestamps
+			ts := (*unix.ScmTimestamping)(unsafe.Pointer(&ctrlMsg.Data[0]))
+			t = time.Unix(ts.Ts[0].Unix())
+			hasTimestamp = true
+		case (ctrlMsg.Header.Level == unix.SOL_IP && ctrlMsg.Header.Type == unix.IP_RECVERR) ||
+			(ctrlMsg.Header.Level == unix.SOL_IPV6 && ctrlMsg.Header.Type == unix.IPV6_RECVERR):
+			if len(ctrlMsg.Data) < int(unsafe.Sizeof(unix.SockExtendedErr{})) {
+				continue
+			}
+			ee := (*unix.SockExtendedErr)(unsafe.Pointer(&ctrlMsg.Data[0]))
+			if ee.Origin != unix.SO_EE_ORIGIN_TIMESTAMPING {
+				continue
+			}
+			// with SOF_TIMESTAMPING_OPT_ID, the ID of the packet is passed in ee_data
+			id = ee.Data
+			hasID = true
+		}
+	}
+	return id, t, hasID && hasTimestamp, nil
+}
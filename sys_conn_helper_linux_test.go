@@ -0,0 +1,160 @@
+//go:build linux
+
+package quic
+
+import (
+	"net"
+	"time"
+	"unsafe"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/sys/unix"
+
+	"github.com/fkwhite/quic-go/internal/protocol"
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UDP GRO", func() {
+	appendGROMsg := func(b []byte, segmentSize uint32) []byte {
+		startLen := len(b)
+		b = append(b, make([]byte, unix.CmsgSpace(4))...)
+		h := (*unix.Cmsghdr)(unsafe.Pointer(&b[startLen]))
+		h.Level = unix.IPPROTO_UDP
+		h.Type = udpGRO
+		h.SetLen(unix.CmsgLen(4))
+		*(*uint32)(unsafe.Pointer(&b[startLen+unix.CmsgSpace(0)])) = segmentSize
+		return b
+	}
+
+	It("switches to large buffers once the kernel coalesced datagrams", func() {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+		oobConn, err := newConn(udpConn)
+		Expect(err).ToNot(HaveOccurred())
+		if !oobConn.groEnabled {
+			Skip("UDP GRO not supported by the kernel")
+		}
+		batchConn := NewMockBatchConn(mockCtrl)
+		oobConn.batchConn = batchConn
+
+		gomock.InOrder(
+			batchConn.EXPECT().ReadBatch(gomock.Any(), gomock.Any()).DoAndReturn(func(ms []ipv4.Message, _ int) (int, error) {
+				Expect(ms[0].Buffers[0]).To(HaveLen(int(protocol.MaxPacketBufferSize)))
+				// 2 complete datagrams, and a truncated third one
+				ms[0].N = 2*500 + 100
+				ms[0].NN = len(appendGROMsg(ms[0].OOB[:0], 500))
+				ms[0].Flags = unix.MSG_TRUNC
+				return 1, nil
+			}),
+			batchConn.EXPECT().ReadBatch(gomock.Any(), gomock.Any()).DoAndReturn(func(ms []ipv4.Message, _ int) (int, error) {
+				Expect(ms[0].Buffers[0]).To(HaveLen(int(protocol.MaxLargePacketBufferSize)))
+				// a single datagram, not coalesced
+				ms[0].N = 500
+				ms[0].NN = 0
+				ms[0].Flags = 0
+				return 1, nil
+			}),
+		)
+		p, err := oobConn.ReadPacket()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.data).To(HaveLen(2 * 500))
+		Expect(p.segmentSize).To(BeEquivalentTo(500))
+		Expect(oobConn.largeBuffers.Get()).To(BeTrue())
+		p, err = oobConn.ReadPacket()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.data).To(HaveLen(500))
+		Expect(p.segmentSize).To(BeZero())
+	})
+})
+
+var _ = Describe("Packet info on multi-homed hosts", func() {
+	// On Linux, the whole 127.0.0.0/8 block is assigned to the loopback interface.
+	// This allows testing with multiple local addresses.
+	localIPs := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv4(127, 0, 0, 2)}
+
+	readPacket := func(conn *oobConn) *receivedPacket {
+		p, err := conn.ReadPacket()
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		return p
+	}
+
+	It("replies from the local address that the packet was sent to", func() {
+		udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+		oobConn, err := newConn(udpConn)
+		Expect(err).ToNot(HaveOccurred())
+		port := udpConn.LocalAddr().(*net.UDPAddr).Port
+
+		for _, ip := range localIPs {
+			client, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer client.Close()
+			_, err = client.WriteTo([]byte("foobar"), &net.UDPAddr{IP: ip, Port: port})
+			Expect(err).ToNot(HaveOccurred())
+
+			p := readPacket(oobConn)
+			Expect(p.info).ToNot(BeNil())
+			Expect(p.info.addr.Equal(ip)).To(BeTrue())
+			conn := newSendConn(oobConn, p.remoteAddr, p.info, false)
+			Expect(conn.UsesPacketInfo()).To(BeTrue())
+			Expect(conn.LocalAddr().(*net.UDPAddr).IP.Equal(ip)).To(BeTrue())
+			Expect(conn.Write([]byte("reply"))).To(Succeed())
+
+			b := make([]byte, 100)
+			client.SetReadDeadline(time.Now().Add(time.Second))
+			n, addr, err := client.ReadFromUDP(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b[:n]).To(Equal([]byte("reply")))
+			Expect(addr.IP.Equal(ip)).To(BeTrue())
+			Expect(addr.Port).To(Equal(port))
+		}
+	})
+
+	It("switches the local address when the peer sends to a different address", func() {
+		udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+		oobConn, err := newConn(udpConn)
+		Expect(err).ToNot(HaveOccurred())
+		port := udpConn.LocalAddr().(*net.UDPAddr).Port
+		client, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer client.Close()
+
+		_, err = client.WriteTo([]byte("foo"), &net.UDPAddr{IP: localIPs[0], Port: port})
+		Expect(err).ToNot(HaveOccurred())
+		p := readPacket(oobConn)
+		conn := newSendConn(oobConn, p.remoteAddr, p.info, false)
+		_, err = client.WriteTo([]byte("bar"), &net.UDPAddr{IP: localIPs[1], Port: port})
+		Expect(err).ToNot(HaveOccurred())
+		conn.SetPacketInfo(readPacket(oobConn).info)
+		Expect(conn.Write([]byte("reply"))).To(Succeed())
+
+		client.SetReadDeadline(time.Now().Add(time.Second))
+		_, addr, err := client.ReadFromUDP(make([]byte, 100))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(addr.IP.Equal(localIPs[1])).To(BeTrue())
+	})
+
+	It("doesn't use packet info when listening on a specific address", func() {
+		udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: localIPs[1]})
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+		oobConn, err := newConn(udpConn)
+		Expect(err).ToNot(HaveOccurred())
+		client, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer client.Close()
+		_, err = client.WriteTo([]byte("foobar"), udpConn.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+
+		p := readPacket(oobConn)
+		Expect(p.info).To(BeNil())
+		Expect(newSendConn(oobConn, p.remoteAddr, p.info, false).UsesPacketInfo()).To(BeFalse())
+	})
+})
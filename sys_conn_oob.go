@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync"
 	"syscall"
 	"time"
 
@@ -19,8 +20,9 @@ import (
 )
 
 const (
-	ecnMask       = 0x3
-	oobBufferSize = 128
+	ecnMask = 0x3
+	// large enough to also hold a SCM_TIMESTAMPING message, once TX timestamps are enabled
+	oobBufferSize = 256
 )
 
 // Contrary to what the naming suggests, the ipv{4,6}.Message is not dependent on the IP version.
@@ -32,6 +34,10 @@ type batchConn interface {
 	ReadBatch(ms []ipv4.Message, flags int) (int, error)
 }
 
+type batchWriteConn interface {
+	WriteBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
 func inspectReadBuffer(c interface{}) (int, error) {
 	conn, ok := c.(interface {
 		SyscallConn() (syscall.RawConn, error)
@@ -55,7 +61,27 @@ func inspectReadBuffer(c interface{}) (int, error) {
 
 type oobConn struct {
 	OOBCapablePacketConn
-	batchConn batchConn
+	batchConn      batchConn
+	batchWriteConn batchWriteConn
+	// set when the kernel doesn't support sendmmsg
+	batchWriteDisabled utils.AtomicBool
+	// The messages used by WritePackets, reused across calls.
+	// WritePackets is called concurrently by all connections using this conn.
+	writeMessagesPool sync.Pool
+	// set when UDP GRO was enabled on the socket
+	groEnabled bool
+	// Set once the kernel coalesced multiple datagrams using GRO,
+	// or when the application configured packets larger than protocol.MaxPacketBufferSize.
+	// Until then, regular packet buffers are used for reading.
+	largeBuffers utils.AtomicBool
+	// set when the kernel supports UDP GSO
+	gsoSupported bool
+	// set when sending with GSO failed, e.g. because the NIC doesn't support it
+	gsoFailed utils.AtomicBool
+
+	// used to read TX timestamps from the socket's error queue
+	syscallConn  syscall.RawConn
+	txTimestamps txTimestamps
 
 	readPos uint8
 	// Packets received from the kernel, but not yet returned by ReadPacket().
@@ -63,7 +89,12 @@ type oobConn struct {
 	buffers  [batchSize]*packetBuffer
 }
 
-var _ rawConn = &oobConn{}
+var (
+	_ rawConn         = &oobConn{}
+	_ batchWriter     = &oobConn{}
+	_ gsoWriter       = &oobConn{}
+	_ timestampWriter = &oobConn{}
+)
 
 func newConn(c OOBCapablePacketConn) (*oobConn, error) {
 	rawConn, err := c.SyscallConn()
@@ -77,7 +108,8 @@ func newConn(c OOBCapablePacketConn) (*oobConn, error) {
 	// We don't know if this a IPv4-only, IPv6-only or a IPv4-and-IPv6 connection.
 	// Try enabling receiving of ECN and packet info for both IP versions.
 	// We expect at least one of those syscalls to succeed.
-	var errECNIPv4, errECNIPv6, errPIIPv4, errPIIPv6 error
+	var errECNIPv4, errECNIPv6, errPIIPv4, errPIIPv6, errGRO error
+	var gsoSupported bool
 	if err := rawConn.Control(func(fd uintptr) {
 		errECNIPv4 = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_RECVTOS, 1)
 		errECNIPv6 = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_RECVTCLASS, 1)
@@ -86,6 +118,8 @@ func newConn(c OOBCapablePacketConn) (*oobConn, error) {
 			errPIIPv4 = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, ipv4RECVPKTINFO, 1)
 			errPIIPv6 = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, ipv6RECVPKTINFO, 1)
 		}
+		errGRO = enableGRO(int(fd))
+		gsoSupported = isGSOSupported(int(fd))
 	}); err != nil {
 		return nil, err
 	}
@@ -111,15 +145,34 @@ func newConn(c OOBCapablePacketConn) (*oobConn, error) {
 			return nil, errors.New("activating packet info failed for both IPv4 and IPv6")
 		}
 	}
+	if errGRO == nil {
+		utils.DefaultLogger.Debugf("Activating UDP GRO.")
+	}
+	if gsoSupported {
+		utils.DefaultLogger.Debugf("Kernel supports UDP GSO.")
+	}
 
 	// Allows callers to pass in a connection that already satisfies batchConn interface
 	// to make use of the optimisation. Otherwise, ipv4.NewPacketConn would unwrap the file descriptor
 	// via SyscallConn(), and read it that way, which might not be what the caller wants.
+	// For that reason, ipv4.NewPacketConn is only used for *net.UDPConns. Other implementations
+	// of the OOBCapablePacketConn are read from (and written to) using ReadMsgUDP (and WriteMsgUDP).
+	_, isUDPConn := c.(*net.UDPConn)
 	var bc batchConn
+	var bwc batchWriteConn
 	if ibc, ok := c.(batchConn); ok {
 		bc = ibc
-	} else {
+	} else if isUDPConn {
 		bc = ipv4.NewPacketConn(c)
+	} else {
+		bc = &msgConn{OOBCapablePacketConn: c}
+	}
+	if ibwc, ok := c.(batchWriteConn); ok {
+		bwc = ibwc
+	} else if isUDPConn {
+		bwc = ipv4.NewPacketConn(c)
+	} else {
+		bwc = &msgConn{OOBCapablePacketConn: c}
 	}
 
 	msgs := make([]ipv4.Message, batchSize)
@@ -130,8 +183,12 @@ func newConn(c OOBCapablePacketConn) (*oobConn, error) {
 	oobConn := &oobConn{
 		OOBCapablePacketConn: c,
 		batchConn:            bc,
+		batchWriteConn:       bwc,
 		messages:             msgs,
 		readPos:              batchSize,
+		groEnabled:           errGRO == nil,
+		gsoSupported:         gsoSupported,
+		syscallConn:          rawConn,
 	}
 	for i := 0; i < batchSize; i++ {
 		oobConn.messages[i].OOB = make([]byte, oobBufferSize)
@@ -139,13 +196,61 @@ func newConn(c OOBCapablePacketConn) (*oobConn, error) {
 	return oobConn, nil
 }
 
+// A msgConn reads and writes one message at a time, using ReadMsgUDP and WriteMsgUDP.
+// It is used for OOBCapablePacketConns that are not a *net.UDPConn (e.g. wrappers around a UDP socket),
+// so that reads and writes go through the wrapper, and are not issued on the file descriptor directly.
+type msgConn struct {
+	OOBCapablePacketConn
+}
+
+var (
+	_ batchConn      = &msgConn{}
+	_ batchWriteConn = &msgConn{}
+)
+
+func (c *msgConn) ReadBatch(ms []ipv4.Message, _ int) (int, error) {
+	n, oobn, flags, addr, err := c.ReadMsgUDP(ms[0].Buffers[0], ms[0].OOB)
+	if err != nil {
+		return 0, err
+	}
+	ms[0].N = n
+	ms[0].NN = oobn
+	ms[0].Flags = flags
+	ms[0].Addr = addr
+	return 1, nil
+}
+
+func (c *msgConn) WriteBatch(ms []ipv4.Message, _ int) (int, error) {
+	for i, m := range ms {
+		if _, _, err := c.WriteMsgUDP(m.Buffers[0], m.OOB, m.Addr.(*net.UDPAddr)); err != nil {
+			return i, err
+		}
+	}
+	return len(ms), nil
+}
+
 func (c *oobConn) ReadPacket() (*receivedPacket, error) {
 	if len(c.messages) == int(c.readPos) { // all messages read. Read the next batch of messages.
 		c.messages = c.messages[:batchSize]
+		largeBuffers := c.largeBuffers.Get()
 		// replace buffers data buffers up to the packet that has been consumed during the last ReadBatch call
-		for i := uint8(0); i < c.readPos; i++ {
-			buffer := getPacketBuffer()
-			buffer.Data = buffer.Data[:protocol.MaxPacketBufferSize]
+		for i := uint8(0); i < batchSize; i++ {
+			if i >= c.readPos {
+				// This buffer was not consumed. It only needs to be replaced if we switched to large buffers.
+				if !largeBuffers || cap(c.buffers[i].Data) >= int(protocol.MaxLargePacketBufferSize) {
+					continue
+				}
+				c.buffers[i].Release()
+			}
+			var buffer *packetBuffer
+			if largeBuffers {
+				// with GRO, the kernel might return multiple datagrams in one buffer
+				buffer = getLargePacketBuffer()
+				buffer.Data = buffer.Data[:protocol.MaxLargePacketBufferSize]
+			} else {
+				buffer = getPacketBuffer()
+				buffer.Data = buffer.Data[:protocol.MaxPacketBufferSize]
+			}
 			c.buffers[i] = buffer
 			c.messages[i].Buffers[0] = c.buffers[i].Data
 		}
@@ -168,7 +273,12 @@ func (c *oobConn) ReadPacket() (*receivedPacket, error) {
 	var ecn protocol.ECN
 	var destIP net.IP
 	var ifIndex uint32
+	var segmentSize protocol.ByteCount
 	for _, ctrlMsg := range ctrlMsgs {
+		if size, ok := parseGROSegmentSize(ctrlMsg); ok {
+			segmentSize = size
+			continue
+		}
 		if ctrlMsg.Header.Level == unix.IPPROTO_IP {
 			switch ctrlMsg.Header.Type {
 			case msgTypeIPTOS:
@@ -216,14 +326,34 @@ func (c *oobConn) ReadPacket() (*receivedPacket, error) {
 			ifIndex: ifIndex,
 		}
 	}
-	return &receivedPacket{
+	p := &receivedPacket{
 		remoteAddr: msg.Addr,
 		rcvTime:    time.Now(),
 		data:       msg.Buffers[0][:msg.N],
 		ecn:        ecn,
 		info:       info,
 		buffer:     buffer,
-	}, nil
+	}
+	// The kernel only reports the segment size if it actually coalesced multiple datagrams.
+	if segmentSize > 0 {
+		if !c.largeBuffers.Get() {
+			// The coalesced datagrams might not all have fit into the buffer.
+			utils.DefaultLogger.Debugf("Received coalesced UDP datagrams. Using large receive buffers.")
+			c.largeBuffers.Set(true)
+		}
+		if segmentSize < p.Size() {
+			if msg.Flags&unix.MSG_TRUNC != 0 {
+				// drop the last datagram, it was truncated
+				p.data = p.data[:p.Size()/segmentSize*segmentSize]
+			}
+			p.segmentSize = segmentSize
+		}
+	}
+	return p, nil
+}
+
+func (c *oobConn) UseLargeBuffers() {
+	c.largeBuffers.Set(true)
 }
 
 func (c *oobConn) WritePacket(b []byte, addr net.Addr, oob []byte) (n int, err error) {
@@ -231,6 +361,231 @@ func (c *oobConn) WritePacket(b []byte, addr net.Addr, oob []byte) (n int, err e
 	return n, err
 }
 
+// noopOOB is a control message that doesn't change how a packet is sent:
+// A UDP_SEGMENT control message with a segment size of 0 disables GSO, which is the default.
+// Kernels that don't support GSO ignore it.
+var noopOOB = appendUDPSegmentSizeMsg(nil, 0)
+
+// WritePackets sends multiple packets to the same address.
+// On Linux, this uses the sendmmsg syscall, if the underlying conn is a *net.UDPConn or has a WriteBatch method.
+// On other platforms, on kernels that don't support sendmmsg, and for all other OOBCapablePacketConns,
+// the packets are sent one by one.
+func (c *oobConn) WritePackets(packets [][]byte, addr net.Addr, oob []byte) (int, error) {
+	if !batchWriteSupported || c.batchWriteDisabled.Get() {
+		return writePacketsIndividually(c, packets, addr, oob)
+	}
+	// golang.org/x/net reuses message headers across sendmmsg and recvmmsg calls,
+	// and doesn't reset the control message of messages that don't have one.
+	// The kernel would then use the control message received with a previous packet.
+	if len(oob) == 0 {
+		oob = noopOOB
+	}
+	msp, ok := c.writeMessagesPool.Get().(*[]ipv4.Message)
+	if !ok {
+		msp = new([]ipv4.Message)
+	}
+	defer c.writeMessagesPool.Put(msp)
+	ms := *msp
+	for len(ms) < len(packets) {
+		// preallocate the [][]byte
+		ms = append(ms, ipv4.Message{Buffers: make([][]byte, 1)})
+	}
+	*msp = ms
+	ms = ms[:len(packets)]
+	for i, p := range packets {
+		ms[i].Buffers[0] = p
+		ms[i].OOB = oob
+		ms[i].Addr = addr
+	}
+	// don't keep references to the packets after returning
+	defer func() {
+		for i := range ms {
+			ms[i].Buffers[0] = nil
+			ms[i].OOB = nil
+			ms[i].Addr = nil
+		}
+	}()
+	var sent int
+	for sent < len(ms) {
+		n, err := c.batchWriteConn.WriteBatch(ms[sent:], 0)
+		if err != nil {
+			if errors.Is(err, unix.ENOSYS) {
+				utils.DefaultLogger.Debugf("sendmmsg not supported by the kernel. Sending packets one by one.")
+				c.batchWriteDisabled.Set(true)
+				m, err := writePacketsIndividually(c, packets[sent:], addr, oob)
+				return sent + m, err
+			}
+			return sent + n, err
+		}
+		sent += n
+	}
+	return sent, nil
+}
+
+// WritePacketsWithTimestamps sends multiple packets to the same address, like WritePackets,
+// requesting a TX timestamp from the kernel for every packet.
+// TX timestamps are enabled on the first call.
+// The kernel reports the timestamps on the socket's error queue, which is read asynchronously.
+// onSent[i] is called once the timestamp for packets[i] was read.
+// If the kernel doesn't support TX timestamps, or doesn't report a timestamp in time,
+// it is called with the time the packet was passed to the kernel.
+func (c *oobConn) WritePacketsWithTimestamps(packets [][]byte, addr net.Addr, oob []byte, onSent []func(time.Time)) (int, error) {
+	c.txTimestamps.init(c.syscallConn)
+	if !c.txTimestamps.enabled.Get() {
+		return writeAndReportSendTime(c, packets, addr, oob, onSent)
+	}
+
+	// copy the oob, so we don't modify the slice owned by the caller
+	tsOOB := appendTxTimestampMsg(append(make([]byte, 0, len(oob)+unix.CmsgSpace(4)), oob...))
+	t := &c.txTimestamps
+	// The kernel assigns IDs to timestamped packets in the order they are sent.
+	// The mutex is held while sending, so that the IDs of these packets are known.
+	// It is only used for timestamped packets, all other packets are sent without acquiring it.
+	t.mutex.Lock()
+	n, err := c.WritePackets(packets, addr, tsOOB)
+	if err != nil && n == 0 && errors.Is(err, unix.EINVAL) {
+		t.mutex.Unlock()
+		// Kernels older than 4.13 don't support requesting timestamps using a control message.
+		utils.DefaultLogger.Debugf("Requesting TX timestamps failed (%s). Disabling TX timestamps.", err)
+		t.enabled.Set(false)
+		return writeAndReportSendTime(c, packets, addr, oob, onSent)
+	}
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		t.pending[t.nextID] = pendingTxTimestamp{onSent: onSent[i], sendTime: now}
+		t.nextID++
+	}
+	if n > 0 && !t.readScheduled {
+		t.readScheduled = true
+		t.timer.Reset(txTimestampReadInterval)
+	}
+	t.mutex.Unlock()
+	return n, err
+}
+
+// writeAndReportSendTime sends multiple packets, and reports the time they were passed to the kernel.
+func writeAndReportSendTime(c *oobConn, packets [][]byte, addr net.Addr, oob []byte, onSent []func(time.Time)) (int, error) {
+	n, err := c.WritePackets(packets, addr, oob)
+	reportSendTime(onSent[:n], time.Now())
+	return n, err
+}
+
+const (
+	// txTimestampReadInterval is the interval at which the error queue is read while TX timestamps are pending.
+	txTimestampReadInterval = time.Millisecond
+	// txTimestampTimeout is the time after which the time a packet was passed to the kernel is reported,
+	// if the kernel didn't report a TX timestamp.
+	txTimestampTimeout = 100 * time.Millisecond
+)
+
+type pendingTxTimestamp struct {
+	onSent   func(time.Time)
+	sendTime time.Time
+}
+
+// txTimestamps keeps track of packets that were sent with a request for a TX timestamp.
+type txTimestamps struct {
+	conn     syscall.RawConn
+	initOnce sync.Once
+	enabled  utils.AtomicBool
+
+	mutex         sync.Mutex
+	nextID        uint32
+	pending       map[uint32]pendingTxTimestamp
+	readScheduled bool
+	timer         *time.Timer
+}
+
+func (t *txTimestamps) init(conn syscall.RawConn) {
+	t.initOnce.Do(func() {
+		var err error
+		if cerr := conn.Control(func(fd uintptr) { err = enableTxTimestamps(int(fd)) }); cerr != nil {
+			err = cerr
+		}
+		if err != nil {
+			utils.DefaultLogger.Debugf("Enabling TX timestamps failed: %s", err)
+			return
+		}
+		t.conn = conn
+		t.pending = make(map[uint32]pendingTxTimestamp)
+		t.timer = time.AfterFunc(time.Hour, t.read)
+		t.timer.Stop()
+		t.enabled.Set(true)
+	})
+}
+
+// read reads all TX timestamps from the socket's error queue, without blocking.
+// It is called by a timer, as long as there are pending TX timestamps.
+func (t *txTimestamps) read() {
+	type report struct {
+		onSent func(time.Time)
+		time   time.Time
+	}
+	var reports []report
+
+	t.mutex.Lock()
+	oob := make([]byte, 128)
+	t.conn.Control(func(fd uintptr) {
+		for {
+			id, ts, ok, err := readTxTimestamp(int(fd), oob)
+			if err != nil { // the error queue is empty
+				return
+			}
+			if !ok {
+				continue
+			}
+			if p, ok := t.pending[id]; ok {
+				delete(t.pending, id)
+				reports = append(reports, report{onSent: p.onSent, time: ts})
+			}
+		}
+	})
+	now := time.Now()
+	for id, p := range t.pending {
+		if now.Sub(p.sendTime) > txTimestampTimeout {
+			delete(t.pending, id)
+			reports = append(reports, report{onSent: p.onSent, time: p.sendTime})
+		}
+	}
+	t.readScheduled = len(t.pending) > 0
+	if t.readScheduled {
+		t.timer.Reset(txTimestampReadInterval)
+	}
+	t.mutex.Unlock()
+
+	for _, r := range reports {
+		r.onSent(r.time)
+	}
+}
+
+// SupportsGSO says if packets can be sent using UDP GSO.
+func (c *oobConn) SupportsGSO() bool {
+	return c.gsoSupported && !c.gsoFailed.Get()
+}
+
+// WriteGSO sends multiple packets to the same address with a single sendmsg call, using UDP GSO.
+// All packets must have the same size, except for the last one, which may be shorter.
+// If the NIC doesn't support GSO, GSO is disabled and the packets are sent using WritePackets.
+func (c *oobConn) WriteGSO(packets [][]byte, addr net.Addr, oob []byte) (int, error) {
+	buffer := getLargePacketBuffer()
+	defer buffer.Release()
+	for _, p := range packets {
+		buffer.Data = append(buffer.Data, p...)
+	}
+	// copy the oob, so we don't modify the slice owned by the caller
+	gsoOOB := appendUDPSegmentSizeMsg(append(make([]byte, 0, len(oob)+unix.CmsgSpace(2)), oob...), uint16(len(packets[0])))
+	if _, _, err := c.OOBCapablePacketConn.WriteMsgUDP(buffer.Data, gsoOOB, addr.(*net.UDPAddr)); err != nil {
+		// The kernel returns EIO if the NIC doesn't support checksum offloading, which is required for GSO.
+		if errors.Is(err, unix.EIO) {
+			utils.DefaultLogger.Debugf("Sending with GSO failed (%s). Disabling GSO.", err)
+			c.gsoFailed.Set(true)
+			return c.WritePackets(packets, addr, oob)
+		}
+		return 0, err
+	}
+	return len(packets), nil
+}
+
 func (info *packetInfo) OOB() []byte {
 	if info == nil {
 		return nil
@@ -18,6 +18,22 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// wrappedUDPConn wraps a *net.UDPConn, counting the calls to ReadMsgUDP and WriteMsgUDP.
+type wrappedUDPConn struct {
+	*net.UDPConn
+	reads, writes int
+}
+
+func (c *wrappedUDPConn) ReadMsgUDP(b, oob []byte) (n, oobn, flags int, addr *net.UDPAddr, err error) {
+	c.reads++
+	return c.UDPConn.ReadMsgUDP(b, oob)
+}
+
+func (c *wrappedUDPConn) WriteMsgUDP(b, oob []byte, addr *net.UDPAddr) (n, oobn int, err error) {
+	c.writes++
+	return c.UDPConn.WriteMsgUDP(b, oob, addr)
+}
+
 var _ = Describe("OOB Conn Test", func() {
 	runServer := func(network, address string) (*net.UDPConn, <-chan *receivedPacket) {
 		addr, err := net.ResolveUDPAddr(network, address)
@@ -201,6 +217,46 @@ var _ = Describe("OOB Conn Test", func() {
 		})
 	})
 
+	Context("wrapped connections", func() {
+		It("reads and writes using ReadMsgUDP and WriteMsgUDP", func() {
+			udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer udpConn.Close()
+			wrapped := &wrappedUDPConn{UDPConn: udpConn}
+			oobConn, err := newConn(wrapped)
+			Expect(err).ToNot(HaveOccurred())
+
+			remoteConn, err := net.DialUDP("udp4", nil, udpConn.LocalAddr().(*net.UDPAddr))
+			Expect(err).ToNot(HaveOccurred())
+			defer remoteConn.Close()
+			rawConn, err := remoteConn.SyscallConn()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rawConn.Control(func(fd uintptr) {
+				Expect(unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TOS, 2)).To(Succeed())
+			})).To(Succeed())
+			_, err = remoteConn.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+
+			p, err := oobConn.ReadPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.data).To(Equal([]byte("foobar")))
+			Expect(p.ecn).To(Equal(protocol.ECT0))
+			Expect(wrapped.reads).To(Equal(1))
+
+			n, err := oobConn.WritePackets([][]byte{[]byte("foo"), []byte("bar")}, remoteConn.LocalAddr(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(2))
+			Expect(wrapped.writes).To(Equal(2))
+			b := make([]byte, 10)
+			for _, expected := range []string{"foo", "bar"} {
+				remoteConn.SetReadDeadline(time.Now().Add(time.Second))
+				n, err := remoteConn.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(b[:n])).To(Equal(expected))
+			}
+		})
+	})
+
 	Context("Batch Reading", func() {
 		var batchConn *MockBatchConn
 
@@ -215,6 +271,7 @@ var _ = Describe("OOB Conn Test", func() {
 				Expect(ms).To(HaveLen(batchSize))
 				for i := 0; i < numMsgRead; i++ {
 					Expect(ms[i].Buffers).To(HaveLen(1))
+					// large buffers are only used once the kernel coalesced datagrams using GRO
 					Expect(ms[i].Buffers[0]).To(HaveLen(int(protocol.MaxPacketBufferSize)))
 					data := []byte(fmt.Sprintf("message %d", counter))
 					counter++
@@ -238,5 +295,185 @@ var _ = Describe("OOB Conn Test", func() {
 				Expect(string(p.data)).To(Equal(fmt.Sprintf("message %d", i)))
 			}
 		})
+
+		It("switches to large buffers", func() {
+			batchConn.EXPECT().ReadBatch(gomock.Any(), gomock.Any()).DoAndReturn(func(ms []ipv4.Message, flags int) (int, error) {
+				Expect(ms).To(HaveLen(batchSize))
+				for i := range ms {
+					Expect(ms[i].Buffers[0]).To(HaveLen(int(protocol.MaxPacketBufferSize)))
+				}
+				ms[0].N = 6
+				return 1, nil
+			})
+			batchConn.EXPECT().ReadBatch(gomock.Any(), gomock.Any()).DoAndReturn(func(ms []ipv4.Message, flags int) (int, error) {
+				Expect(ms).To(HaveLen(batchSize))
+				// all buffers are replaced, not only the one that was consumed
+				for i := range ms {
+					Expect(ms[i].Buffers[0]).To(HaveLen(int(protocol.MaxLargePacketBufferSize)))
+				}
+				ms[0].N = 9000
+				return 1, nil
+			})
+
+			addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+			Expect(err).ToNot(HaveOccurred())
+			udpConn, err := net.ListenUDP("udp", addr)
+			Expect(err).ToNot(HaveOccurred())
+			oobConn, err := newConn(udpConn)
+			Expect(err).ToNot(HaveOccurred())
+			oobConn.batchConn = batchConn
+
+			p, err := oobConn.ReadPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.data).To(HaveLen(6))
+			oobConn.UseLargeBuffers()
+			p, err = oobConn.ReadPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.data).To(HaveLen(9000))
+		})
+	})
+
+	Context("Batch Writing", func() {
+		var batchConn *MockBatchWriteConn
+
+		BeforeEach(func() {
+			batchConn = NewMockBatchWriteConn(mockCtrl)
+		})
+
+		newOOBConn := func() (*oobConn, *net.UDPConn) {
+			addr, err := net.ResolveUDPAddr("udp4", "localhost:0")
+			Expect(err).ToNot(HaveOccurred())
+			udpConn, err := net.ListenUDP("udp4", addr)
+			Expect(err).ToNot(HaveOccurred())
+			oobConn, err := newConn(udpConn)
+			Expect(err).ToNot(HaveOccurred())
+			return oobConn, udpConn
+		}
+
+		It("writes multiple messages in one batch", func() {
+			if !batchWriteSupported {
+				Skip("sendmmsg is not supported on this platform")
+			}
+			oobConn, _ := newOOBConn()
+			oobConn.batchWriteConn = batchConn
+			remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
+			packets := [][]byte{[]byte("foo"), []byte("bar"), []byte("baz")}
+			gomock.InOrder(
+				batchConn.EXPECT().WriteBatch(gomock.Any(), 0).DoAndReturn(func(ms []ipv4.Message, _ int) (int, error) {
+					Expect(ms).To(HaveLen(3))
+					for i, m := range ms {
+						Expect(m.Buffers).To(Equal([][]byte{packets[i]}))
+						Expect(m.Addr).To(Equal(remoteAddr))
+						Expect(m.OOB).To(Equal([]byte("oob")))
+					}
+					return 2, nil
+				}),
+				// the kernel might not send all packets at once
+				batchConn.EXPECT().WriteBatch(gomock.Any(), 0).DoAndReturn(func(ms []ipv4.Message, _ int) (int, error) {
+					Expect(ms).To(HaveLen(1))
+					Expect(ms[0].Buffers).To(Equal([][]byte{[]byte("baz")}))
+					return 1, nil
+				}),
+			)
+			n, err := oobConn.WritePackets(packets, remoteAddr, []byte("oob"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(3))
+		})
+
+		It("sets a control message for packets sent without one", func() {
+			if !batchWriteSupported {
+				Skip("sendmmsg is not supported on this platform")
+			}
+			oobConn, _ := newOOBConn()
+			oobConn.batchWriteConn = batchConn
+			remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
+			batchConn.EXPECT().WriteBatch(gomock.Any(), 0).DoAndReturn(func(ms []ipv4.Message, _ int) (int, error) {
+				Expect(ms).To(HaveLen(2))
+				for _, m := range ms {
+					Expect(m.OOB).To(Equal(noopOOB))
+				}
+				return 2, nil
+			})
+			n, err := oobConn.WritePackets([][]byte{[]byte("foo"), []byte("bar")}, remoteAddr, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(2))
+		})
+
+		It("falls back to sending packets one by one if sendmmsg is not supported", func() {
+			if !batchWriteSupported {
+				Skip("sendmmsg is not supported on this platform")
+			}
+			oobConn, _ := newOOBConn()
+			oobConn.batchWriteConn = batchConn
+			remoteConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer remoteConn.Close()
+			batchConn.EXPECT().WriteBatch(gomock.Any(), 0).Return(0, &net.OpError{Op: "sendmmsg", Err: unix.ENOSYS})
+			n, err := oobConn.WritePackets([][]byte{[]byte("foo"), []byte("bar")}, remoteConn.LocalAddr(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(2))
+			Expect(oobConn.batchWriteDisabled.Get()).To(BeTrue())
+			b := make([]byte, 10)
+			for _, expected := range []string{"foo", "bar"} {
+				remoteConn.SetReadDeadline(time.Now().Add(time.Second))
+				n, _, err := remoteConn.ReadFrom(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(b[:n])).To(Equal(expected))
+			}
+			// don't EXPECT any more calls to WriteBatch
+			_, err = oobConn.WritePackets([][]byte{[]byte("foo")}, remoteConn.LocalAddr(), nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("sends packets with TX timestamps", func() {
+			oobConn, _ := newOOBConn()
+			remoteConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer remoteConn.Close()
+			timestamps := make(chan time.Time, 2)
+			onSent := func(t time.Time) { timestamps <- t }
+			start := time.Now()
+			n, err := oobConn.WritePacketsWithTimestamps(
+				[][]byte{[]byte("foo"), []byte("bar")},
+				remoteConn.LocalAddr(),
+				nil,
+				[]func(time.Time){onSent, onSent},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(2))
+			for _, data := range []string{"foo", "bar"} {
+				b := make([]byte, 10)
+				remoteConn.SetReadDeadline(time.Now().Add(time.Second))
+				n, _, err := remoteConn.ReadFrom(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(b[:n])).To(Equal(data))
+			}
+			for i := 0; i < 2; i++ {
+				var t time.Time
+				Eventually(timestamps).Should(Receive(&t))
+				Expect(t).To(BeTemporally(">=", start.Add(-time.Millisecond)))
+				Expect(t).To(BeTemporally("<=", time.Now()))
+			}
+		})
+
+		It("sends packets using GSO", func() {
+			oobConn, _ := newOOBConn()
+			if !oobConn.SupportsGSO() {
+				Skip("GSO is not supported on this platform")
+			}
+			remoteConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer remoteConn.Close()
+			n, err := oobConn.WriteGSO([][]byte{[]byte("foo"), []byte("bar"), []byte("b")}, remoteConn.LocalAddr(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(3))
+			b := make([]byte, 10)
+			for _, expected := range []string{"foo", "bar", "b"} {
+				remoteConn.SetReadDeadline(time.Now().Add(time.Second))
+				n, _, err := remoteConn.ReadFrom(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(b[:n])).To(Equal(expected))
+			}
+		})
 	})
 })
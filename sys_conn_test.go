@@ -30,4 +30,19 @@ var _ = Describe("Basic Conn Test", func() {
 		Expect(p.rcvTime).To(BeTemporally("~", time.Now(), scaleDuration(100*time.Millisecond)))
 		Expect(p.remoteAddr).To(Equal(addr))
 	})
+
+	It("reads a packet into a large buffer", func() {
+		c := NewMockPacketConn(mockCtrl)
+		c.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(func(b []byte) (int, net.Addr, error) {
+			Expect(b).To(HaveLen(int(protocol.MaxLargePacketBufferSize)))
+			return copy(b, make([]byte, 9000)), &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}, nil
+		})
+
+		conn, err := wrapConn(c)
+		Expect(err).ToNot(HaveOccurred())
+		conn.UseLargeBuffers()
+		p, err := conn.ReadPacket()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.data).To(HaveLen(9000))
+	})
 })
@@ -0,0 +1,205 @@
+// Package testutils provides helpers for testing applications built on top of quic-go.
+package testutils
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// maxReorderHoldTime is the maximum time that a packet is held back for reordering.
+// If no further packets are sent within this time, held back packets are sent anyway.
+const maxReorderHoldTime = 10 * time.Millisecond
+
+// sendQueueLen is the number of delayed packets that can be queued.
+// Once the queue is full, WriteTo blocks.
+const sendQueueLen = 1024
+
+// A Config configures the impairments applied by a PacketConn.
+type Config struct {
+	// Seed seeds the random number generator.
+	// Using the same seed (and the same sequence of sent packets) results in the same packets being
+	// dropped, duplicated and reordered.
+	Seed int64
+	// LossRate is the probability that a packet is dropped. It must be between 0 and 1.
+	LossRate float64
+	// DuplicateRate is the probability that a packet is sent twice. It must be between 0 and 1.
+	DuplicateRate float64
+	// ReorderDepth is the maximum number of packets that a packet is overtaken by.
+	// If zero, packets are not reordered.
+	ReorderDepth int
+	// Delay is the fixed delay added to every packet.
+	Delay time.Duration
+}
+
+type packet struct {
+	data []byte
+	addr net.Addr
+
+	sendTime time.Time
+	// the number of packets that still have to be sent before this packet is released
+	overtakenBy int
+}
+
+// A PacketConn wraps a net.PacketConn and drops, duplicates, reorders and delays
+// the packets sent on it, as configured by the Config.
+// Packets are only impaired in the send direction. To impair both directions,
+// wrap the connections of both endpoints.
+// It can be passed to quic.Listen and quic.Dial.
+// Note that ECN and other packet information (OOB data) is not available on a PacketConn.
+type PacketConn struct {
+	net.PacketConn
+
+	config *Config
+
+	mutex       sync.Mutex
+	closed      bool
+	rand        *rand.Rand
+	held        []*packet // packets held back for reordering
+	reorderTime *time.Timer
+
+	queue chan *packet // only used if a delay is configured
+}
+
+var _ net.PacketConn = &PacketConn{}
+
+// NewPacketConn wraps a net.PacketConn.
+func NewPacketConn(conn net.PacketConn, config *Config) *PacketConn {
+	c := &PacketConn{
+		PacketConn: conn,
+		config:     config,
+		rand:       rand.New(rand.NewSource(config.Seed)),
+	}
+	if config.Delay > 0 {
+		c.queue = make(chan *packet, sendQueueLen)
+		go c.runSendQueue()
+	}
+	return c
+}
+
+// WriteTo writes a packet.
+// It only returns an error if the connection was closed or if the packet was written without any delay.
+func (c *PacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	// Always draw the same amount of random numbers per packet, such that the decisions
+	// for one packet don't depend on the decisions made for the previous packets.
+	drop := c.rand.Float64() < c.config.LossRate
+	duplicate := c.rand.Float64() < c.config.DuplicateRate
+	var overtakenBy int
+	if c.config.ReorderDepth > 0 {
+		overtakenBy = c.rand.Intn(c.config.ReorderDepth + 1)
+	}
+	if drop {
+		return len(b), nil
+	}
+
+	data := make([]byte, len(b))
+	copy(data, b)
+	p := &packet{data: data, addr: addr, overtakenBy: overtakenBy}
+	n := 1
+	if duplicate {
+		n = 2
+	}
+	var err error
+	for i := 0; i < n; i++ {
+		if rerr := c.reorder(p); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// reorder sends all held back packets that are now overtaken by enough packets,
+// and then either sends p or holds it back.
+// It must be called with the mutex held.
+func (c *PacketConn) reorder(p *packet) error {
+	var err error
+	held := c.held[:0]
+	var release []*packet
+	for _, h := range c.held {
+		h.overtakenBy--
+		if h.overtakenBy <= 0 {
+			release = append(release, h)
+		} else {
+			held = append(held, h)
+		}
+	}
+	c.held = held
+	if p.overtakenBy == 0 {
+		err = c.send(p)
+	} else {
+		// copy the packet, so that duplicates can be held back independently
+		c.held = append(c.held, &packet{data: p.data, addr: p.addr, overtakenBy: p.overtakenBy})
+	}
+	for _, r := range release {
+		if serr := c.send(r); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	if len(c.held) > 0 {
+		if c.reorderTime == nil {
+			c.reorderTime = time.AfterFunc(maxReorderHoldTime, c.releaseHeldPackets)
+		} else {
+			c.reorderTime.Reset(maxReorderHoldTime)
+		}
+	}
+	return err
+}
+
+func (c *PacketConn) releaseHeldPackets() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return
+	}
+	for _, p := range c.held {
+		c.send(p)
+	}
+	c.held = c.held[:0]
+}
+
+// send sends a packet, or queues it if a delay is configured.
+// It must be called with the mutex held.
+func (c *PacketConn) send(p *packet) error {
+	if c.queue == nil {
+		_, err := c.PacketConn.WriteTo(p.data, p.addr)
+		return err
+	}
+	c.queue <- &packet{data: p.data, addr: p.addr, sendTime: time.Now().Add(c.config.Delay)}
+	return nil
+}
+
+func (c *PacketConn) runSendQueue() {
+	for p := range c.queue {
+		time.Sleep(time.Until(p.sendTime))
+		c.PacketConn.WriteTo(p.data, p.addr)
+	}
+}
+
+// Close closes the connection.
+// Packets that are held back or delayed are dropped.
+func (c *PacketConn) Close() error {
+	c.mutex.Lock()
+	if !c.closed {
+		c.closed = true
+		c.held = nil
+		if c.reorderTime != nil {
+			c.reorderTime.Stop()
+		}
+		if c.queue != nil {
+			close(c.queue)
+		}
+	}
+	c.mutex.Unlock()
+	return c.PacketConn.Close()
+}
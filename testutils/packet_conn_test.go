@@ -0,0 +1,121 @@
+package testutils
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordingConn struct {
+	net.PacketConn // nil, only used to satisfy the interface
+
+	mutex   sync.Mutex
+	written [][]byte
+}
+
+func (c *recordingConn) WriteTo(b []byte, _ net.Addr) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.written = append(c.written, b)
+	return len(b), nil
+}
+
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Written() []byte {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	b := make([]byte, 0, len(c.written))
+	for _, p := range c.written {
+		b = append(b, p[0])
+	}
+	return b
+}
+
+var _ = Describe("PacketConn", func() {
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+
+	send := func(config *Config, num int) *recordingConn {
+		rc := &recordingConn{}
+		conn := NewPacketConn(rc, config)
+		for i := 0; i < num; i++ {
+			n, err := conn.WriteTo([]byte{byte(i)}, addr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(1))
+		}
+		// wait for held back packets to be released
+		Eventually(func() int {
+			conn.mutex.Lock()
+			defer conn.mutex.Unlock()
+			return len(conn.held)
+		}).Should(BeZero())
+		return rc
+	}
+
+	It("passes through packets", func() {
+		rc := send(&Config{}, 10)
+		Expect(rc.Written()).To(Equal([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}))
+	})
+
+	It("drops packets", func() {
+		rc := send(&Config{LossRate: 0.5, Seed: 42}, 100)
+		Expect(len(rc.Written())).To(And(BeNumerically(">", 25), BeNumerically("<", 75)))
+		Expect(rc.Written()).To(Equal(send(&Config{LossRate: 0.5, Seed: 42}, 100).Written()))
+		Expect(rc.Written()).ToNot(Equal(send(&Config{LossRate: 0.5, Seed: 1337}, 100).Written()))
+	})
+
+	It("duplicates packets", func() {
+		rc := send(&Config{DuplicateRate: 0.5, Seed: 42}, 100)
+		Expect(len(rc.Written())).To(And(BeNumerically(">", 125), BeNumerically("<", 175)))
+		counts := make(map[byte]int)
+		for _, b := range rc.Written() {
+			counts[b]++
+		}
+		Expect(counts).To(HaveLen(100))
+	})
+
+	It("reorders packets", func() {
+		const reorderDepth = 3
+		written := send(&Config{ReorderDepth: reorderDepth, Seed: 42}, 100).Written()
+		Expect(written).To(HaveLen(100))
+		Expect(written).ToNot(Equal(send(&Config{}, 100).Written()))
+		for i, b := range written {
+			// a packet is overtaken by at most reorderDepth packets
+			Expect(i - int(b)).To(BeNumerically("<=", reorderDepth))
+		}
+		Expect(written).To(Equal(send(&Config{ReorderDepth: reorderDepth, Seed: 42}, 100).Written()))
+	})
+
+	It("releases held back packets if no more packets are sent", func() {
+		rc := &recordingConn{}
+		conn := NewPacketConn(rc, &Config{ReorderDepth: 10, Seed: 42})
+		for i := 0; i < 10; i++ {
+			_, err := conn.WriteTo([]byte{byte(i)}, addr)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(len(rc.Written())).To(BeNumerically("<", 10))
+		Eventually(func() int { return len(rc.Written()) }).Should(Equal(10))
+	})
+
+	It("delays packets", func() {
+		const delay = 50 * time.Millisecond
+		rc := &recordingConn{}
+		conn := NewPacketConn(rc, &Config{Delay: delay})
+		start := time.Now()
+		_, err := conn.WriteTo([]byte{0}, addr)
+		Expect(err).ToNot(HaveOccurred())
+		Consistently(func() int { return len(rc.Written()) }, delay/2).Should(BeZero())
+		Eventually(func() int { return len(rc.Written()) }).Should(Equal(1))
+		Expect(time.Since(start)).To(BeNumerically(">=", delay))
+	})
+
+	It("errors when writing after it was closed", func() {
+		conn := NewPacketConn(&recordingConn{}, &Config{Delay: time.Millisecond})
+		Expect(conn.Close()).To(Succeed())
+		_, err := conn.WriteTo([]byte{0}, addr)
+		Expect(err).To(MatchError(net.ErrClosed))
+	})
+})
@@ -0,0 +1,13 @@
+package testutils
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestTestUtils(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Test Utils Suite")
+}